@@ -7,12 +7,14 @@ mkdir -p ./generated
 
 protoc --go_out=./generated --go_opt=paths=source_relative \
     --go-grpc_out=./generated --go-grpc_opt=paths=source_relative \
-    login.proto
+    *.proto
 
 cd ..
 
 cd core 
 go build -o ../build/medicine-rag .
+go build -o ../build/medctl ./cmd/medctl
+go build -o ../build/ingest ./cmd/ingest
 
 cd ..
 
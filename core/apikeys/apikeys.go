@@ -0,0 +1,213 @@
+// Package apikeys issues and checks the API keys other clinic software uses to call the
+// public gRPC API. A key is "mrk_<id>_<secret>"; only a hash of the secret is stored.
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const keyPrefix = "mrk"
+
+// UserType marks the JWTs issued for API keys; their user id is UserID(keyID) and their
+// user type is TokenUserType(secretHash).
+const UserType = "api_key"
+
+const (
+	DefaultRequestsPerMinute = 60
+	MaxRequestsPerMinute     = 600
+	MaxKeysPerTenant         = 50
+	MaxNameLength            = 100
+)
+
+// Scopes grant access to whole gRPC services. Services missing here, such as key
+// management, notifications and the operator API, cannot be called with a key.
+var Scopes = map[string]Scope{
+	"agent":         {Description: "Ask the assistant questions", Services: []string{"/agent.Agent/"}},
+	"conversations": {Description: "Read, share and import conversations; attach case details", Services: []string{"/search.Conversation/"}},
+	"corpus":        {Description: "List corpus versions and watch for updates", Services: []string{"/search.Corpus/"}},
+	"interactions":  {Description: "Check remedy and drug interactions", Services: []string{"/search.Interactions/"}},
+	"feedback":      {Description: "Rate answers", Services: []string{"/search.Feedback/"}},
+	"search":        {Description: "Search the corpus directly, without the assistant", Services: []string{"/search.Search/"}},
+	"usage":         {Description: "Read token usage", Services: []string{"/search.Usage/"}},
+}
+
+type Scope struct {
+	Description string
+	Services    []string // full method name prefixes
+}
+
+// ScopeNames lists the scopes in a stable order.
+func ScopeNames() []string {
+	return slices.Sorted(maps.Keys(Scopes))
+}
+
+// ScopeForMethod returns the scope that grants a gRPC method, or "" if keys may not call it.
+func ScopeForMethod(fullMethod string) string {
+	for name, scope := range Scopes {
+		for _, service := range scope.Services {
+			if strings.HasPrefix(fullMethod, service) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// ValidateScopes checks requested scopes and returns them sorted without duplicates.
+func ValidateScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+
+	var valid []string
+	for _, scope := range scopes {
+		if _, ok := Scopes[scope]; !ok {
+			return nil, fmt.Errorf("unknown scope %q; expected one of %s", scope, strings.Join(ScopeNames(), ", "))
+		}
+		if !slices.Contains(valid, scope) {
+			valid = append(valid, scope)
+		}
+	}
+	slices.Sort(valid)
+	return valid, nil
+}
+
+// RequestsPerMinute applies the default and the ceiling to a requested rate limit.
+func RequestsPerMinute(requested int32) (int32, error) {
+	switch {
+	case requested < 0:
+		return 0, errors.New("the rate limit must not be negative")
+	case requested == 0:
+		return DefaultRequestsPerMinute, nil
+	case requested > MaxRequestsPerMinute:
+		return 0, fmt.Errorf("the rate limit is at most %d requests per minute", MaxRequestsPerMinute)
+	}
+	return requested, nil
+}
+
+// UserID is the user id API calls made with a key act as.
+func UserID(keyID string) string {
+	return "apikey_" + keyID
+}
+
+// KeyIDFromUserID returns the key behind an API key user id.
+func KeyIDFromUserID(userID string) (string, bool) {
+	return strings.CutPrefix(userID, "apikey_")
+}
+
+// TokenUserType binds a JWT to the secret it was exchanged for, so rotating a key also
+// invalidates the tokens issued for the old secret.
+func TokenUserType(secretHash string) string {
+	return UserType + ":" + secretHash[:min(12, len(secretHash))]
+}
+
+// IsTokenFor reports whether a JWT's user type was issued for the key's current secret.
+func IsTokenFor(userType, secretHash string) bool {
+	return userType == TokenUserType(secretHash)
+}
+
+// IsKeyToken reports whether a JWT's user type marks it as exchanged for an API key.
+func IsKeyToken(userType string) bool {
+	return strings.HasPrefix(userType, UserType+":")
+}
+
+// Generate creates a key. The key is shown to its creator once; only the id and the
+// secret's hash are stored.
+func Generate() (key, keyID, secretHash string, err error) {
+	id := make([]byte, 6)
+	secret := make([]byte, 24)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+
+	keyID = hex.EncodeToString(id)
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+	return keyPrefix + "_" + keyID + "_" + encoded, keyID, Hash(encoded), nil
+}
+
+// Rotate replaces the secret of an existing key.
+func Rotate(keyID string) (key, secretHash string, err error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+	return keyPrefix + "_" + keyID + "_" + encoded, Hash(encoded), nil
+}
+
+// Parse splits a key into its id and secret.
+func Parse(key string) (keyID, secret string, err error) {
+	parts := strings.SplitN(strings.TrimSpace(key), "_", 3)
+	if len(parts) != 3 || parts[0] != keyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", "", errors.New("malformed API key")
+	}
+	return parts[1], parts[2], nil
+}
+
+// Display is the part of a key that may be shown after creation.
+func Display(keyID string) string {
+	return keyPrefix + "_" + keyID + "_…"
+}
+
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Matches reports whether secret hashes to the stored hash, in constant time.
+func Matches(secretHash, secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(secretHash), []byte(Hash(secret))) == 1
+}
+
+// Limiter keeps a token bucket per key, refilled at the key's rate per minute and
+// holding up to a minute's worth of requests.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	limiter *rate.Limiter
+	perMin  int32
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{buckets: make(map[string]*bucket)}
+}
+
+// Allow takes one request from the key's bucket. A changed limit starts a full bucket.
+func (l *Limiter) Allow(keyID string, perMinute int32, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[keyID]
+	if !ok || b.perMin != perMinute {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(float64(perMinute)/60), int(perMinute)), perMin: perMinute}
+		b.limiter.AllowN(now, 0) // start the bucket's clock at now
+		l.buckets[keyID] = b
+	}
+	return b.limiter.AllowN(now, 1)
+}
+
+// Forget drops a key's bucket, e.g. once the key is revoked.
+func (l *Limiter) Forget(keyID string) {
+	l.mu.Lock()
+	delete(l.buckets, keyID)
+	l.mu.Unlock()
+}
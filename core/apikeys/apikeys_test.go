@@ -0,0 +1,87 @@
+package apikeys
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateAndParse(t *testing.T) {
+	key, keyID, secretHash, err := Generate()
+	require.NoError(t, err)
+	assert.Regexp(t, `^mrk_[0-9a-f]{12}_[A-Za-z0-9_-]{32}$`, key)
+
+	parsedID, secret, err := Parse(key)
+	require.NoError(t, err)
+	assert.Equal(t, keyID, parsedID)
+	assert.True(t, Matches(secretHash, secret))
+
+	rotated, rotatedHash, err := Rotate(keyID)
+	require.NoError(t, err)
+	_, rotatedSecret, err := Parse(rotated)
+	require.NoError(t, err)
+	assert.True(t, Matches(rotatedHash, rotatedSecret))
+	assert.False(t, Matches(secretHash, rotatedSecret), "rotating invalidates the old secret")
+
+	for _, malformed := range []string{"", "mrk_abc", "sk_abc_def", "mrk__secret"} {
+		_, _, err := Parse(malformed)
+		assert.Error(t, err, malformed)
+	}
+}
+
+func TestScopes(t *testing.T) {
+	assert.Equal(t, "agent", ScopeForMethod("/agent.Agent/Execute"))
+	assert.Equal(t, "conversations", ScopeForMethod("/search.Conversation/GetConversation"))
+	assert.Equal(t, "search", ScopeForMethod("/search.Search/Search"))
+	assert.Empty(t, ScopeForMethod("/search.ApiKeys/CreateApiKey"))
+	assert.Empty(t, ScopeForMethod("/search.Admin/ListActiveStreams"))
+
+	scopes, err := ValidateScopes([]string{"usage", "agent", "usage"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"agent", "usage"}, scopes)
+
+	_, err = ValidateScopes([]string{"admin"})
+	assert.ErrorContains(t, err, `unknown scope "admin"`)
+	_, err = ValidateScopes(nil)
+	assert.Error(t, err)
+}
+
+func TestRequestsPerMinute(t *testing.T) {
+	perMinute, err := RequestsPerMinute(0)
+	require.NoError(t, err)
+	assert.EqualValues(t, DefaultRequestsPerMinute, perMinute)
+
+	_, err = RequestsPerMinute(MaxRequestsPerMinute + 1)
+	assert.Error(t, err)
+}
+
+func TestLimiter(t *testing.T) {
+	limiter := NewLimiter()
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, limiter.Allow("k1", 3, now))
+	}
+	assert.False(t, limiter.Allow("k1", 3, now), "the burst is a minute's worth")
+	assert.True(t, limiter.Allow("k2", 3, now), "keys have their own buckets")
+
+	assert.True(t, limiter.Allow("k1", 3, now.Add(20*time.Second)), "refills at the per-minute rate")
+	assert.False(t, limiter.Allow("k1", 3, now.Add(20*time.Second)))
+
+	assert.True(t, limiter.Allow("k1", 6, now.Add(20*time.Second)), "a new limit starts a full bucket")
+}
+
+func TestTokenUserType(t *testing.T) {
+	_, _, oldHash, err := Generate()
+	require.NoError(t, err)
+	_, newHash, err := Rotate("k1")
+	require.NoError(t, err)
+
+	userType := TokenUserType(oldHash)
+	assert.True(t, IsKeyToken(userType))
+	assert.True(t, IsTokenFor(userType, oldHash))
+	assert.False(t, IsTokenFor(userType, newHash), "rotation invalidates earlier tokens")
+	assert.False(t, IsKeyToken("client"))
+}
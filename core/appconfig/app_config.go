@@ -19,4 +19,142 @@ type AppConfig struct {
 	OllamaMiniModel string `ini:"ollama_mini_model"`
 
 	TitleGenModel string `ini:"title_gen_model"`
+
+	// Models clients may request, as name=provider:model. See llmrouter.ParseModelSpec.
+	Models       []string `ini:"models" delim:","`
+	DefaultModel string   `ini:"default_model"`
+
+	// Registry names of the local models used by offline tenants.
+	OfflineModel        string `ini:"offline_model"`
+	OfflineMiniModel    string `ini:"offline_mini_model"`
+	OfflineToolSelector string `ini:"offline_tool_selector"`
+
+	// Embedding models tenants may choose, as name=provider:model@dimensions. See
+	// embedding.ParseSpec. Empty registers only jina-embeddings-v4.
+	Embedders       []string `ini:"embedders" delim:","`
+	DefaultEmbedder string   `ini:"default_embedder"`
+
+	// Registry name of the model a tenant whose embedder knows English only is switched
+	// to when it ingests a document in another language before it has any vectors. See
+	// ingest.Pipeline.Multilingual. Empty never switches.
+	MultilingualEmbedder string `ini:"multilingual_embedder"`
+
+	// Query embeddings held in memory, and days each is kept in the tenant's database.
+	// A zero size disables the cache; a zero TTL keeps them 30 days.
+	EmbeddingCacheSize    int `ini:"embedding_cache_size"`
+	EmbeddingCacheTTLDays int `ini:"embedding_cache_ttl_days"`
+
+	// Embedding client limits. Zero disables the rate limits and uses default batching.
+	EmbedRequestsPerMinute int `ini:"embed_requests_per_minute"`
+	EmbedTokensPerMinute   int `ini:"embed_tokens_per_minute"`
+	EmbedMaxBatch          int `ini:"embed_max_batch"`
+	EmbedBatchWindowMs     int `ini:"embed_batch_window_ms"`
+
+	// Per-provider call limits, as provider:value. See llmrouter.ParseCallPolicies.
+	ProviderTimeouts []string `ini:"provider_timeouts" delim:","`
+	ProviderRetries  []string `ini:"provider_retries" delim:","`
+
+	// Model each model fails over to when its provider errors or is down, as
+	// name=fallback. See llmrouter.ParseModelFallbacks.
+	ModelFallbacks []string `ini:"model_fallbacks" delim:","`
+
+	// Per-tenant cap on in-flight provider calls. See tenancy.Limits.
+	TenantLLMConcurrency   int `ini:"tenant_llm_concurrency"`
+	TenantEmbedConcurrency int `ini:"tenant_embed_concurrency"`
+
+	// Default monthly token quota per tenant; zero means unlimited. Tenants may override
+	// it in their tenant config.
+	MonthlyTokenQuota int64 `ini:"monthly_token_quota"`
+
+	// Per-execution limits. MaxIterations caps the tool-calling turns a client or agent
+	// config may ask for; the time and token budgets bound one answer, with zero meaning
+	// unlimited. Tenants may override each in their tenant config.
+	MaxIterations           int   `ini:"max_iterations"`
+	ExecutionTimeoutSeconds int   `ini:"execution_timeout_seconds"`
+	ExecutionTokenBudget    int64 `ini:"execution_token_budget"`
+
+	// Answer cache. A zero TTL disables it; the similarity is the minimum cosine
+	// similarity between question embeddings for a cached answer to be reused.
+	AnswerCacheTTLMinutes int     `ini:"answer_cache_ttl_minutes"`
+	AnswerCacheSimilarity float64 `ini:"answer_cache_similarity"`
+
+	// Search results replayed within a session for a query similar enough to an earlier
+	// one. A zero TTL disables it; sessions are forgotten that long after their last
+	// search, and the least recently used beyond the session count.
+	SessionCacheTTLMinutes int     `ini:"session_cache_ttl_minutes"`
+	SessionCacheSimilarity float64 `ini:"session_cache_similarity"`
+	SessionCacheSessions   int     `ini:"session_cache_sessions"`
+
+	// Tenants with at most this many chunk vectors are searched by exact in-process
+	// cosine scoring instead of the ANN index; zero always uses the ANN index. The
+	// vectors of up to ExactVectorScanTenants tenants, by default 50, are held in memory.
+	ExactVectorScanMaxChunks int `ini:"exact_vector_scan_max_chunks"`
+	ExactVectorScanTenants   int `ini:"exact_vector_scan_tenants"`
+
+	// Databases of the shared knowledge packs tenants may search. A pack a tenant config
+	// lists that isn't here is never searched; empty allows none.
+	KnowledgePacks []string `ini:"knowledge_packs" delim:","`
+
+	// Weights of the text (BM25) and vector rankings in hybrid search's reciprocal rank
+	// fusion. Tenants may override them in their tenant config.
+	TextSearchWeight   float64 `ini:"text_search_weight"`
+	VectorSearchWeight float64 `ini:"vector_search_weight"`
+
+	// Sections a search sends when the agent sets no limit (zero for all ranked), chunks
+	// ranked for the first page and for any page, and the least relevance, from 0 to 1, a
+	// section is sent with. Zero depths use the built-in 20 and 100. Tenants may override
+	// them in their tenant config; the agent may ask for fewer sections or a higher
+	// minimum score per search.
+	SearchTopK     int     `ini:"search_top_k"`
+	SearchDepth    int     `ini:"search_depth"`
+	MaxSearchDepth int     `ini:"max_search_depth"`
+	MinSearchScore float64 `ini:"min_search_score"`
+
+	// Time the text and vector searches get to answer; an engine that overruns it is
+	// left out and the results are marked partial. Zero waits for both.
+	SearchBudgetMs int `ini:"search_budget_ms"`
+
+	// Boost to the fused score of chunks tagged with every remedy, rubric and body
+	// system the query mentions; chunks tagged with some get a share. Zero disables it.
+	EntityBoost float64 `ini:"entity_boost"`
+
+	// Boost to the fused score of chunks from the newest edition among a search's
+	// candidates, older ones getting a share by age, and of chunks from a tenant's
+	// preferred sources. Tenants may override them in their tenant config; zero disables
+	// them.
+	RecencyBoost         float64 `ini:"recency_boost"`
+	PreferredSourceBoost float64 `ini:"preferred_source_boost"`
+
+	// Optional cross-encoder reranking of the best hybrid search hits: "jina", "ollama"
+	// or empty for none. The model defaults to Jina's multilingual reranker, or to
+	// ollama_mini_model. Reranking that overruns its budget keeps the fused order.
+	Reranker       string `ini:"reranker"`
+	RerankerModel  string `ini:"reranker_model"`
+	RerankTopK     int    `ini:"rerank_top_k"`
+	RerankBudgetMs int    `ini:"rerank_budget_ms"`
+
+	// Text around the matching windows of a search result: "windows" (the neighbouring
+	// windows whole, the default), "sentences" (search_context_sentences either side) or
+	// "paragraph" (the paragraphs the matches start and end in). See mcp.ContextMode.
+	SearchContext          string `ini:"search_context"`
+	SearchContextSentences int    `ini:"search_context_sentences"`
+
+	// What to do with answers stating dosages their sources lack: annotate, block or off.
+	// See guardrails.DosageMode.
+	GuardrailDosageMode string `ini:"guardrail_dosage_mode"`
+
+	// Default confidence below which the agent abstains instead of answering; zero
+	// disables abstention. Tenants may override it in their agent config.
+	AbstentionThreshold float64 `ini:"abstention_threshold"`
+
+	// How many searches picked in the same turn run at once; their results are merged
+	// and sections found by more than one are sent once. Zero or one runs them in turn.
+	ToolParallelism int `ini:"tool_parallelism"`
+
+	// How many follow-up questions are suggested after each answer; zero disables them.
+	FollowUpSuggestions int `ini:"follow_up_suggestions"`
+
+	// How many times a structured answer that fails schema validation is sent back to
+	// the model for repair.
+	StructuredOutputRepairAttempts int `ini:"structured_output_repair_attempts"`
 }
@@ -0,0 +1,286 @@
+// Package audit records what an agent run did, every model call and tool call with its
+// inputs, outputs, latency and tokens, so the run can be reviewed after the fact.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/llmrouter"
+	"github.com/ollama/ollama/api"
+)
+
+// Limits that keep a trace well inside Mongo's 16 MB document limit. Past MaxTraceBytes
+// model calls are still recorded, but without the messages they were sent.
+const (
+	MaxTextLength = 16 * 1024
+	MaxSteps      = 200
+	MaxTraceBytes = 8 * 1024 * 1024
+)
+
+const (
+	KindLLM  = "llm"
+	KindTool = "tool"
+)
+
+// Recorder collects the steps of one run. It is safe for concurrent use, as agent-boot
+// runs tools in parallel.
+type Recorder struct {
+	started time.Time
+
+	mu        sync.Mutex
+	steps     []db.TraceStep
+	bytes     int
+	truncated bool
+}
+
+func NewRecorder(started time.Time) *Recorder {
+	return &Recorder{started: started}
+}
+
+// Steps returns a copy of the steps recorded so far, in the order they started, and
+// whether anything was dropped or shortened. Steps still in flight are included as far
+// as they got.
+func (r *Recorder) Steps() ([]db.TraceStep, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]db.TraceStep(nil), r.steps...), r.truncated
+}
+
+// WrapLLM records every call made through client. name is the model's role in the agent.
+func (r *Recorder) WrapLLM(name, model string, client llm.LLMClient) llm.LLMClient {
+	return &recordedClient{LLMClient: client, recorder: r, name: name, model: model}
+}
+
+// WrapTool records every call of a tool handler and the results it produces.
+func (r *Recorder) WrapTool(name string, handler func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk) func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+	return func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+		arguments, _ := json.Marshal(params)
+		step := r.begin(db.TraceStep{Kind: KindTool, Name: name, Arguments: r.clip(string(arguments))})
+		started := time.Now()
+
+		in := handler(ctx, params)
+		out := make(chan *schema.ToolResultChunk)
+		go func() {
+			defer close(out)
+
+			var results []db.TraceResult
+			forward := true
+			for chunk := range in {
+				if chunk != nil {
+					results = append(results, r.result(chunk))
+				}
+				// once the agent stops listening, keep draining so the tool can finish
+				if forward {
+					select {
+					case out <- chunk:
+					case <-ctx.Done():
+						forward = false
+					}
+				}
+			}
+
+			r.end(step, func(s *db.TraceStep) {
+				s.DurationMs = time.Since(started).Milliseconds()
+				s.Results = results
+				if err := ctx.Err(); err != nil {
+					s.Error = err.Error()
+				}
+			})
+		}()
+		return out
+	}
+}
+
+func (r *Recorder) result(chunk *schema.ToolResultChunk) db.TraceResult {
+	var content []byte
+	for i, sentence := range chunk.Sentences {
+		if i > 0 {
+			content = append(content, '\n')
+		}
+		content = append(content, sentence...)
+	}
+	return db.TraceResult{
+		Title:       chunk.Title,
+		Attribution: chunk.Attribution,
+		Content:     r.clip(string(content)),
+		Error:       chunk.Error,
+	}
+}
+
+// begin appends a step and returns its index, or -1 once MaxSteps have been recorded.
+func (r *Recorder) begin(step db.TraceStep) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.steps) >= MaxSteps {
+		r.truncated = true
+		return -1
+	}
+	step.StartedMs = time.Since(r.started).Milliseconds()
+	r.steps = append(r.steps, step)
+	return len(r.steps) - 1
+}
+
+func (r *Recorder) end(index int, update func(*db.TraceStep)) {
+	if index < 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	update(&r.steps[index])
+	r.bytes += stepBytes(r.steps[index])
+}
+
+// messages copies a call's messages unless the trace has outgrown MaxTraceBytes.
+func (r *Recorder) messages(messages []llm.Message) []db.TraceMessage {
+	r.mu.Lock()
+	full := r.bytes >= MaxTraceBytes
+	if full {
+		r.truncated = true
+	}
+	r.mu.Unlock()
+	if full {
+		return nil
+	}
+
+	out := make([]db.TraceMessage, 0, len(messages))
+	for _, m := range messages {
+		out = append(out, db.TraceMessage{Role: m.Role, Content: r.clip(m.Content)})
+	}
+	return out
+}
+
+// clip shortens s to MaxTextLength bytes without splitting a character.
+func (r *Recorder) clip(s string) string {
+	if len(s) <= MaxTextLength {
+		return s
+	}
+
+	r.mu.Lock()
+	r.truncated = true
+	r.mu.Unlock()
+
+	cut := MaxTextLength
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
+
+func stepBytes(step db.TraceStep) int {
+	n := len(step.SystemPrompt) + len(step.Response) + len(step.Arguments)
+	for _, m := range step.Messages {
+		n += len(m.Content)
+	}
+	for _, call := range step.ToolCalls {
+		n += len(call.Arguments)
+	}
+	for _, result := range step.Results {
+		n += len(result.Content)
+	}
+	return n
+}
+
+type recordedClient struct {
+	llm.LLMClient
+	recorder *Recorder
+	name     string
+	model    string
+}
+
+func (c *recordedClient) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	call := c.begin(messages, opts)
+	var usage llmrouter.Usage
+	err := c.LLMClient.GenerateInference(llmrouter.WithCallUsage(ctx, &usage), messages, call.content(callback), opts...)
+	call.end(usage, err)
+	return err
+}
+
+func (c *recordedClient) GenerateInferenceWithTools(ctx context.Context, messages []llm.Message, contentCallback func(chunk string) error, toolCallback func(toolCalls []api.ToolCall) error, opts ...llm.LLMOption) error {
+	call := c.begin(messages, opts)
+	var usage llmrouter.Usage
+	err := c.LLMClient.GenerateInferenceWithTools(llmrouter.WithCallUsage(ctx, &usage), messages, call.content(contentCallback), call.tools(toolCallback), opts...)
+	call.end(usage, err)
+	return err
+}
+
+func (c *recordedClient) begin(messages []llm.Message, opts []llm.LLMOption) *recordedCall {
+	system, tools := llmrouter.DescribeOptions(opts)
+	return &recordedCall{
+		recorder: c.recorder,
+		started:  time.Now(),
+		index: c.recorder.begin(db.TraceStep{
+			Kind:         KindLLM,
+			Name:         c.name,
+			Model:        c.model,
+			SystemPrompt: c.recorder.clip(system),
+			Messages:     c.recorder.messages(messages),
+			OfferedTools: tools,
+		}),
+	}
+}
+
+// recordedCall collects the output of one model call. Streaming clients call back once
+// per delta, so the response is appended to rather than replaced.
+type recordedCall struct {
+	recorder *Recorder
+	index    int
+	started  time.Time
+
+	mu        sync.Mutex
+	response  []byte
+	toolCalls []db.TraceToolCall
+}
+
+func (c *recordedCall) content(callback func(chunk string) error) func(chunk string) error {
+	if callback == nil {
+		return nil
+	}
+	return func(chunk string) error {
+		c.mu.Lock()
+		c.response = append(c.response, chunk...)
+		c.mu.Unlock()
+		return callback(chunk)
+	}
+}
+
+func (c *recordedCall) tools(callback func(toolCalls []api.ToolCall) error) func(toolCalls []api.ToolCall) error {
+	if callback == nil {
+		return nil
+	}
+	return func(toolCalls []api.ToolCall) error {
+		c.mu.Lock()
+		for _, call := range toolCalls {
+			arguments, _ := json.Marshal(call.Function.Arguments)
+			c.toolCalls = append(c.toolCalls, db.TraceToolCall{Name: call.Function.Name, Arguments: c.recorder.clip(string(arguments))})
+		}
+		c.mu.Unlock()
+		return callback(toolCalls)
+	}
+}
+
+func (c *recordedCall) end(usage llmrouter.Usage, err error) {
+	c.mu.Lock()
+	response := c.recorder.clip(string(c.response))
+	toolCalls := c.toolCalls
+	c.mu.Unlock()
+
+	c.recorder.end(c.index, func(s *db.TraceStep) {
+		s.DurationMs = time.Since(c.started).Milliseconds()
+		s.Response = response
+		s.ToolCalls = toolCalls
+		s.PromptTokens = usage.PromptTokens
+		s.CompletionTokens = usage.CompletionTokens
+		s.TokensEstimated = usage.Estimated
+		if err != nil {
+			s.Error = err.Error()
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/medicine-rag/core/llmrouter"
+	"github.com/ollama/ollama/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type scriptedModel struct {
+	llm.LLMClient
+	reply     []string
+	toolCalls []api.ToolCall
+	err       error
+}
+
+func (m *scriptedModel) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	for _, chunk := range m.reply {
+		if err := callback(chunk); err != nil {
+			return err
+		}
+	}
+	return m.err
+}
+
+func (m *scriptedModel) GenerateInferenceWithTools(ctx context.Context, messages []llm.Message, contentCallback func(chunk string) error, toolCallback func(toolCalls []api.ToolCall) error, opts ...llm.LLMOption) error {
+	if err := toolCallback(m.toolCalls); err != nil {
+		return err
+	}
+	return m.GenerateInference(ctx, messages, contentCallback, opts...)
+}
+
+func TestRecordsModelCalls(t *testing.T) {
+	recorder := NewRecorder(time.Now())
+	meter := llmrouter.NewMeter()
+	model := &scriptedModel{
+		reply: []string{"Arnica ", "for bruising."},
+		toolCalls: []api.ToolCall{{Function: api.ToolCallFunction{
+			Name:      "medicine-rag",
+			Arguments: api.ToolCallFunctionArguments{"query": "bruising"},
+		}}},
+	}
+	client := recorder.WrapLLM("answer", "gpt-test", meter.Wrap(llmrouter.ModelSpec{Name: "gpt-test"}, model))
+
+	messages := []llm.Message{{Role: "user", Content: "What helps bruising?"}}
+	err := client.GenerateInferenceWithTools(t.Context(), messages, func(string) error { return nil }, func([]api.ToolCall) error { return nil },
+		llmrouter.WithSystemPrompt("You are a homeopathy assistant."))
+	require.NoError(t, err)
+
+	steps, truncated := recorder.Steps()
+	require.Len(t, steps, 1)
+	assert.False(t, truncated)
+
+	step := steps[0]
+	assert.Equal(t, KindLLM, step.Kind)
+	assert.Equal(t, "answer", step.Name)
+	assert.Equal(t, "gpt-test", step.Model)
+	assert.Equal(t, "You are a homeopathy assistant.", step.SystemPrompt)
+	require.Len(t, step.Messages, 1)
+	assert.Equal(t, "What helps bruising?", step.Messages[0].Content)
+	assert.Equal(t, "Arnica for bruising.", step.Response)
+	require.Len(t, step.ToolCalls, 1)
+	assert.Equal(t, "medicine-rag", step.ToolCalls[0].Name)
+	assert.JSONEq(t, `{"query":"bruising"}`, step.ToolCalls[0].Arguments)
+
+	// the trace carries the same per-call counts the meter totals
+	assert.Equal(t, meter.Total().PromptTokens, step.PromptTokens)
+	assert.Equal(t, meter.Total().CompletionTokens, step.CompletionTokens)
+	assert.True(t, step.TokensEstimated)
+}
+
+func TestRecordsModelErrors(t *testing.T) {
+	recorder := NewRecorder(time.Now())
+	client := recorder.WrapLLM("mini", "mini-test", &scriptedModel{err: errors.New("provider timed out")})
+
+	err := client.GenerateInference(t.Context(), nil, func(string) error { return nil })
+	require.Error(t, err)
+
+	steps, _ := recorder.Steps()
+	require.Len(t, steps, 1)
+	assert.Equal(t, "provider timed out", steps[0].Error)
+}
+
+func TestRecordsToolCalls(t *testing.T) {
+	recorder := NewRecorder(time.Now())
+	handler := recorder.WrapTool("repertory", func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+		out := make(chan *schema.ToolResultChunk, 2)
+		out <- &schema.ToolResultChunk{Title: "Fear of death", Sentences: []string{"Aconite (3)", "Arsenicum (2)"}}
+		out <- &schema.ToolResultChunk{Error: "rubric not found: xyz"}
+		close(out)
+		return out
+	})
+
+	var received int
+	for range handler(t.Context(), api.ToolCallFunctionArguments{"symptoms": []string{"fear of death", "xyz"}}) {
+		received++
+	}
+	assert.Equal(t, 2, received, "results reach the agent unchanged")
+
+	require.Eventually(t, func() bool {
+		steps, _ := recorder.Steps()
+		return len(steps) == 1 && len(steps[0].Results) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	steps, _ := recorder.Steps()
+	step := steps[0]
+	assert.Equal(t, KindTool, step.Kind)
+	assert.Equal(t, "repertory", step.Name)
+	assert.JSONEq(t, `{"symptoms":["fear of death","xyz"]}`, step.Arguments)
+	assert.Equal(t, "Fear of death", step.Results[0].Title)
+	assert.Equal(t, "Aconite (3)\nArsenicum (2)", step.Results[0].Content)
+	assert.Equal(t, "rubric not found: xyz", step.Results[1].Error)
+}
+
+func TestLimits(t *testing.T) {
+	recorder := NewRecorder(time.Now())
+	client := recorder.WrapLLM("answer", "gpt-test", &scriptedModel{reply: []string{strings.Repeat("é", MaxTextLength)}})
+
+	for i := 0; i < MaxSteps+1; i++ {
+		require.NoError(t, client.GenerateInference(t.Context(), nil, func(string) error { return nil }))
+	}
+
+	steps, truncated := recorder.Steps()
+	assert.True(t, truncated)
+	assert.Len(t, steps, MaxSteps)
+	assert.LessOrEqual(t, len(steps[0].Response), MaxTextLength+len("…"))
+	assert.True(t, strings.HasSuffix(steps[0].Response, "é…"), "texts are cut between characters")
+}
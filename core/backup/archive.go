@@ -0,0 +1,223 @@
+// Package backup writes a tenant's indexed corpus to a portable archive, and imports
+// one into a tenant of this or another deployment, for backups and for moving a corpus
+// between environments without chunking and embedding it again.
+//
+// An archive is gzip-compressed JSON Lines. The first line is its Header, with the
+// manifest of its documents. Each line after it is a document of one of Collections,
+// in canonical Extended JSON so BSON types and vectors round-trip exactly, or a figure
+// image from the tenant's bucket. The last line counts them, so a truncated archive is
+// refused.
+package backup
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	Format  = "medicine-rag-corpus"
+	Version = 1
+
+	// Figure images are counted under this key.
+	Images = "images"
+)
+
+// Collections of a tenant's corpus, in the order they are archived and imported.
+var Collections = []string{
+	db.IngestProgressModel{}.CollectionName(),
+	db.CorpusVersionModel{}.CollectionName(),
+	db.ChunkModel{}.CollectionName(),
+	db.ChunkAnnModel{}.CollectionName(),
+	db.TableRowModel{}.CollectionName(),
+	db.FigureModel{}.CollectionName(),
+	db.QuarantinedChunkModel{}.CollectionName(),
+	db.RubricModel{}.CollectionName(),
+}
+
+var (
+	ErrNotArchive = errors.New("not a corpus archive")
+	ErrTruncated  = errors.New("corpus archive is truncated")
+)
+
+// Header opens an archive.
+type Header struct {
+	Format        string     `json:"format"`
+	Version       int        `json:"version"`
+	Tenant        string     `json:"tenant"` // exported from
+	ExportedOn    int64      `json:"exportedOn"`
+	CorpusVersion int64      `json:"corpusVersion"` // latest at export
+	Embedder      Embedder   `json:"embedder"`      // of the vectors
+	Documents     []Document `json:"documents"`     // sorted by source URI
+}
+
+// Embedder is the embedding model of an archive's vectors. Its name is the exporting
+// deployment's; the model and size are what an importing deployment must have.
+type Embedder struct {
+	Name       string `json:"name"`
+	Model      string `json:"model"`
+	Dimensions int    `json:"dimensions"`
+}
+
+// Document is a source document of the archive, as its ingestion progress records it.
+type Document struct {
+	SourceURI    string   `json:"sourceUri"`
+	Checksum     string   `json:"checksum"`
+	Stage        string   `json:"stage"`
+	Chunks       int      `json:"chunks"`
+	Embedded     int      `json:"embedded"`
+	DuplicateOf  string   `json:"duplicateOf,omitempty"`
+	AccessGroups []string `json:"accessGroups,omitempty"`
+}
+
+// Counts are the documents of an archive by collection, and its figure images.
+type Counts map[string]int
+
+// Item is a line of an archive: a document of Collection, or a file of the tenant's
+// bucket at Path.
+type Item struct {
+	Collection string
+	Document   bson.D
+	Path       string
+	Data       []byte
+}
+
+// line is any line after the header.
+type line struct {
+	Collection string          `json:"collection,omitempty"`
+	Document   json.RawMessage `json:"document,omitempty"` // canonical Extended JSON
+	Path       string          `json:"path,omitempty"`
+	Data       []byte          `json:"data,omitempty"`
+
+	End    bool   `json:"end,omitempty"` // the last line
+	Counts Counts `json:"counts,omitempty"`
+}
+
+// Writer writes an archive.
+type Writer struct {
+	gz     *gzip.Writer
+	enc    *json.Encoder
+	counts Counts
+}
+
+// NewWriter starts an archive on w with header, stamped with the archive format.
+func NewWriter(w io.Writer, header Header) (*Writer, error) {
+	header.Format, header.Version = Format, Version
+	gz := gzip.NewWriter(w)
+	enc := json.NewEncoder(gz)
+	if err := enc.Encode(header); err != nil {
+		return nil, err
+	}
+	return &Writer{gz: gz, enc: enc, counts: Counts{}}, nil
+}
+
+// WriteDocument adds a document of one of Collections.
+func (w *Writer) WriteDocument(collection string, document bson.Raw) error {
+	if !slices.Contains(Collections, collection) {
+		return errors.New("collection " + collection + " is not part of a corpus")
+	}
+	data, err := bson.MarshalExtJSON(document, true, false)
+	if err != nil {
+		return err
+	}
+	if err := w.enc.Encode(line{Collection: collection, Document: data}); err != nil {
+		return err
+	}
+	w.counts[collection]++
+	return nil
+}
+
+// WriteFile adds a figure image stored at path in the tenant's bucket.
+func (w *Writer) WriteFile(path string, data []byte) error {
+	if err := w.enc.Encode(line{Path: path, Data: data}); err != nil {
+		return err
+	}
+	w.counts[Images]++
+	return nil
+}
+
+// Close ends the archive with its counts, and returns them. It does not close the
+// underlying writer.
+func (w *Writer) Close() (Counts, error) {
+	if err := w.enc.Encode(line{End: true, Counts: w.counts}); err != nil {
+		return nil, err
+	}
+	return w.counts, w.gz.Close()
+}
+
+// Reader reads an archive.
+type Reader struct {
+	Header Header
+
+	gz     *gzip.Reader
+	dec    *json.Decoder
+	counts Counts
+	done   bool
+}
+
+// NewReader reads an archive's header from r.
+func NewReader(r io.Reader) (*Reader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, ErrNotArchive
+	}
+	reader := &Reader{gz: gz, dec: json.NewDecoder(gz), counts: Counts{}}
+	if err := reader.dec.Decode(&reader.Header); err != nil || reader.Header.Format != Format {
+		return nil, ErrNotArchive
+	}
+	if reader.Header.Version > Version {
+		return nil, errors.New("corpus archive version " + strconv.Itoa(reader.Header.Version) + " is newer than this deployment reads")
+	}
+	return reader, nil
+}
+
+// Next returns the archive's next item, and io.EOF after the last one once its counts
+// are checked. An archive that ends early fails with ErrTruncated.
+func (r *Reader) Next() (Item, error) {
+	if r.done {
+		return Item{}, io.EOF
+	}
+
+	var next line
+	if err := r.dec.Decode(&next); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return Item{}, ErrTruncated
+		}
+		return Item{}, errors.New("failed to read corpus archive: " + err.Error())
+	}
+
+	switch {
+	case next.End:
+		r.done = true
+		for _, key := range append(slices.Clone(Collections), Images) {
+			if next.Counts[key] != r.counts[key] {
+				return Item{}, errors.New("corpus archive has " + strconv.Itoa(r.counts[key]) + " " + key + " but should have " + strconv.Itoa(next.Counts[key]))
+			}
+		}
+		return Item{}, io.EOF
+	case next.Path != "":
+		r.counts[Images]++
+		return Item{Path: next.Path, Data: next.Data}, nil
+	case slices.Contains(Collections, next.Collection):
+		var document bson.D
+		if err := bson.UnmarshalExtJSON(next.Document, true, &document); err != nil {
+			return Item{}, errors.New("failed to read " + next.Collection + " document: " + err.Error())
+		}
+		r.counts[next.Collection]++
+		return Item{Collection: next.Collection, Document: document}, nil
+	default:
+		// never write to collections outside the corpus, such as logins
+		return Item{}, errors.New("collection " + strconv.Quote(next.Collection) + " is not part of a corpus")
+	}
+}
+
+// Counts are the items read so far.
+func (r *Reader) Counts() Counts {
+	return r.counts
+}
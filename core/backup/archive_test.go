@@ -0,0 +1,128 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func marshal(t *testing.T, model any) bson.Raw {
+	data, err := bson.Marshal(model)
+	require.NoError(t, err)
+	return data
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	chunk := db.ChunkModel{ChunkID: "c1", SourceURI: "books/boericke.pdf", Title: "Aconite", Sentences: []string{"Sudden fear of death."}, CorpusVersion: 3, RetiredVersion: 5}
+	vector := db.ChunkAnnModel{ChunkID: "c1", Embedding: bson.NewVector([]float32{0.25, -1, 3.5}), Model: "voyage-3.5", Dimensions: 3}
+	figure := db.FigureModel{FigureID: "f1", SourceURI: "books/boericke.pdf", Label: "Fig. 1", ImagePath: "figures/f1.png"}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Header{Tenant: "clinic", CorpusVersion: 5, Embedder: Embedder{Name: "voyage", Model: "voyage-3.5", Dimensions: 3},
+		Documents: []Document{{SourceURI: "books/boericke.pdf", Checksum: "abc", Stage: db.IngestStageEmbedded, Chunks: 1, Embedded: 1}}})
+	require.NoError(t, err)
+	require.NoError(t, w.WriteDocument(db.ChunkModel{}.CollectionName(), marshal(t, chunk)))
+	require.NoError(t, w.WriteDocument(db.ChunkAnnModel{}.CollectionName(), marshal(t, vector)))
+	require.NoError(t, w.WriteDocument(db.FigureModel{}.CollectionName(), marshal(t, figure)))
+	require.NoError(t, w.WriteFile("figures/f1.png", []byte{0x89, 'P', 'N', 'G'}))
+	assert.Error(t, w.WriteDocument("logins", marshal(t, chunk)), "only corpus collections are archived")
+	counts, err := w.Close()
+	require.NoError(t, err)
+	assert.Equal(t, Counts{"chunks": 1, "chunk_ann_index": 1, "figures": 1, Images: 1}, counts)
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, Format, r.Header.Format)
+	assert.Equal(t, "clinic", r.Header.Tenant)
+	assert.Equal(t, int64(5), r.Header.CorpusVersion)
+	assert.Equal(t, "voyage-3.5", r.Header.Embedder.Model)
+	require.Len(t, r.Header.Documents, 1)
+
+	var items []Item
+	for {
+		item, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		items = append(items, item)
+	}
+	require.Len(t, items, 4)
+	assert.Equal(t, counts, r.Counts())
+
+	var gotChunk db.ChunkModel
+	require.NoError(t, unmarshal(items[0].Document, &gotChunk))
+	assert.Equal(t, chunk, gotChunk)
+	var gotVector db.ChunkAnnModel
+	require.NoError(t, unmarshal(items[1].Document, &gotVector))
+	assert.Equal(t, vector, gotVector, "vectors round-trip exactly")
+	assert.Equal(t, "figures/f1.png", items[3].Path)
+	assert.Equal(t, []byte{0x89, 'P', 'N', 'G'}, items[3].Data)
+
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func unmarshal(document bson.D, model any) error {
+	data, err := bson.Marshal(document)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, model)
+}
+
+// archive writes raw lines after a header, as a damaged or hand-made archive would have.
+func archive(t *testing.T, lines ...any) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+	require.NoError(t, enc.Encode(Header{Format: Format, Version: Version, Tenant: "clinic"}))
+	for _, line := range lines {
+		require.NoError(t, enc.Encode(line))
+	}
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func readAll(t *testing.T, data []byte) error {
+	r, err := NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	for {
+		if _, err := r.Next(); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func TestArchiveDamaged(t *testing.T) {
+	chunk := map[string]any{"collection": "chunks", "document": json.RawMessage(`{"_id": "c1"}`)}
+
+	assert.NoError(t, readAll(t, archive(t, chunk, map[string]any{"end": true, "counts": Counts{"chunks": 1}})))
+	assert.ErrorIs(t, readAll(t, archive(t, chunk)), ErrTruncated, "no last line")
+	assert.ErrorContains(t, readAll(t, archive(t, chunk, map[string]any{"end": true, "counts": Counts{"chunks": 2}})),
+		"has 1 chunks but should have 2")
+	assert.ErrorContains(t, readAll(t, archive(t, map[string]any{"collection": "logins", "document": json.RawMessage(`{"_id": "a"}`)})),
+		`collection "logins" is not part of a corpus`)
+
+	whole := archive(t, chunk, map[string]any{"end": true, "counts": Counts{"chunks": 1}})
+	_, err := NewReader(bytes.NewReader([]byte(`{"format": "medicine-rag-corpus"}`)))
+	assert.ErrorIs(t, err, ErrNotArchive, "not gzipped")
+	assert.Error(t, readAll(t, whole[:len(whole)-12]), "cut mid-stream")
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	require.NoError(t, json.NewEncoder(gz).Encode(Header{Format: Format, Version: Version + 1}))
+	require.NoError(t, gz.Close())
+	_, err = NewReader(&buf)
+	assert.ErrorContains(t, err, "is newer than this deployment reads")
+}
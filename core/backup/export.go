@@ -0,0 +1,102 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Storage is the tenant's bucket, which figure images are read from and written to.
+type Storage interface {
+	UploadBuffer(ctx context.Context, bucketName, path string, fileData []byte) (string, error)
+	DownloadFile(ctx context.Context, bucketName, path string) (string, error)
+}
+
+// Export writes the tenant's indexed corpus to w: every chunk of every corpus version,
+// their vectors of embedder, table rows, figures and their images, quarantined chunks
+// and their reviews, repertory rubrics, corpus versions and ingestion progress. It
+// reads the collections one after another, so a document ingested meanwhile may be in
+// some and not others; export while nothing is being ingested.
+func Export(ctx context.Context, mongo odm.MongoClient, storage Storage, tenant string, embedder Embedder, w io.Writer) (Header, Counts, error) {
+	header := Header{Tenant: tenant, ExportedOn: time.Now().Unix(), Embedder: embedder}
+
+	progress, err := async.Await(odm.CollectionOf[db.IngestProgressModel](mongo, tenant).
+		Find(ctx, bson.M{}, bson.D{{Key: "sourceUri", Value: 1}}, 0, 0))
+	if err != nil {
+		return header, nil, errors.New("failed to load ingestion progress: " + err.Error())
+	}
+	for _, document := range progress {
+		header.Documents = append(header.Documents, Document{
+			SourceURI:    document.SourceURI,
+			Checksum:     document.Checksum,
+			Stage:        document.Stage,
+			Chunks:       document.Chunks,
+			Embedded:     document.Embedded,
+			DuplicateOf:  document.DuplicateOf,
+			AccessGroups: document.AccessGroups,
+		})
+	}
+	if header.CorpusVersion, err = db.CurrentCorpusVersion(ctx, mongo, tenant); err != nil {
+		return header, nil, errors.New("failed to load corpus version: " + err.Error())
+	}
+
+	archive, err := NewWriter(w, header)
+	if err != nil {
+		return header, nil, errors.New("failed to write corpus archive: " + err.Error())
+	}
+	var images []string
+	for _, collection := range Collections {
+		cursor, err := mongo.Database(tenant).Collection(collection).
+			Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+		if err != nil {
+			return header, nil, errors.New("failed to read " + collection + ": " + err.Error())
+		}
+		for cursor.Next(ctx) {
+			if err := archive.WriteDocument(collection, cursor.Current); err != nil {
+				cursor.Close(ctx)
+				return header, nil, errors.New("failed to write corpus archive: " + err.Error())
+			}
+			if path, ok := cursor.Current.Lookup("imagePath").StringValueOK(); ok && collection == (db.FigureModel{}).CollectionName() {
+				images = append(images, path)
+			}
+		}
+		err = cursor.Err()
+		cursor.Close(ctx)
+		if err != nil {
+			return header, nil, errors.New("failed to read " + collection + ": " + err.Error())
+		}
+	}
+
+	for _, path := range images {
+		data, err := download(ctx, storage, tenant, path)
+		if err != nil {
+			return header, nil, errors.New("failed to read figure image " + path + ": " + err.Error())
+		}
+		if err := archive.WriteFile(path, data); err != nil {
+			return header, nil, errors.New("failed to write corpus archive: " + err.Error())
+		}
+	}
+
+	counts, err := archive.Close()
+	if err != nil {
+		return header, nil, errors.New("failed to write corpus archive: " + err.Error())
+	}
+	return header, counts, nil
+}
+
+func download(ctx context.Context, storage Storage, tenant, path string) ([]byte, error) {
+	filePath, err := storage.DownloadFile(ctx, tenant, path)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(filePath)
+	return os.ReadFile(filePath)
+}
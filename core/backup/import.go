@@ -0,0 +1,165 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+)
+
+const importBatchSize = 500
+
+// ErrCorpusNotEmpty is returned for importing into a tenant that has chunks, without
+// replacing its corpus.
+var ErrCorpusNotEmpty = errors.New("tenant already has a corpus")
+
+// Report is what Import did.
+type Report struct {
+	Imported      Counts
+	Cleared       int   // documents of the tenant's corpus deleted first
+	CorpusVersion int64 // recording the import
+}
+
+// Import loads an archive into the tenant's corpus, as it was exported: chunks keep
+// their IDs and corpus versions, and figure images are stored at their paths in the
+// tenant's bucket. A tenant with chunks is refused unless replace is set, which first
+// deletes every document of Collections; vectors go first, as DeleteDocument does.
+//
+// The tenant's vectors must be of the archive's embedding model; switching the tenant
+// to it is the caller's. Documents are upserted, so an import that fails part way is
+// finished by running it again. The import is recorded as a corpus version after the
+// archive's, and the tenant's cached answers are dropped.
+func Import(ctx context.Context, client odm.MongoClient, storage Storage, tenant string, archive *Reader, replace bool) (Report, error) {
+	report := Report{}
+	database := client.Database(tenant)
+
+	if replace {
+		for _, collection := range slices.Backward(Collections) {
+			result, err := database.Collection(collection).DeleteMany(ctx, bson.M{})
+			if err != nil {
+				return report, errors.New("failed to clear " + collection + ": " + err.Error())
+			}
+			report.Cleared += int(result.DeletedCount)
+		}
+	} else {
+		chunks, err := database.Collection(db.ChunkModel{}.CollectionName()).
+			CountDocuments(ctx, bson.M{}, options.Count().SetLimit(1))
+		if err != nil {
+			return report, errors.New("failed to count chunks: " + err.Error())
+		}
+		if chunks > 0 {
+			return report, ErrCorpusNotEmpty
+		}
+	}
+
+	var (
+		collection string
+		batch      []mongo.WriteModel
+		images     = map[string]bool{} // of the figures imported, the only files written
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if _, err := database.Collection(collection).BulkWrite(ctx, batch, options.BulkWrite().SetOrdered(false)); err != nil {
+			return errors.New("failed to import " + collection + ": " + err.Error())
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		item, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+
+		if item.Path != "" {
+			if !images[item.Path] {
+				return report, errors.New("corpus archive has a file no figure links to: " + item.Path)
+			}
+			if _, err := storage.UploadBuffer(ctx, tenant, item.Path, item.Data); err != nil {
+				return report, errors.New("failed to store figure image " + item.Path + ": " + err.Error())
+			}
+			continue
+		}
+
+		if item.Collection != collection || len(batch) >= importBatchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+			collection = item.Collection
+		}
+		id, ok := documentID(item.Document)
+		if !ok {
+			return report, errors.New("corpus archive has a " + collection + " document without an _id")
+		}
+		batch = append(batch, mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": id}).SetReplacement(item.Document).SetUpsert(true))
+		if collection == (db.FigureModel{}).CollectionName() {
+			if path, ok := documentField(item.Document, "imagePath").(string); ok && path != "" {
+				images[path] = true
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return report, err
+	}
+	report.Imported = archive.Counts()
+
+	// versions allocated from now on follow the archive's
+	if err := db.RaiseCorpusVersion(ctx, client, tenant, archive.Header.CorpusVersion); err != nil {
+		return report, errors.New("failed to raise corpus version: " + err.Error())
+	}
+	live, err := database.Collection(db.ChunkModel{}.CollectionName()).CountDocuments(ctx, db.LiveChunksFilter())
+	if err != nil {
+		return report, errors.New("failed to count live chunks: " + err.Error())
+	}
+	version, err := db.NextCorpusVersion(ctx, client, tenant)
+	if err != nil {
+		return report, errors.New("failed to allocate corpus version: " + err.Error())
+	}
+	corpusVersion := db.NewCorpusVersionModel(version)
+	corpusVersion.AddedChunks = int(live)
+	corpusVersion.CreatedOn = time.Now().Unix()
+	if _, err := async.Await(odm.CollectionOf[db.CorpusVersionModel](client, tenant).Save(ctx, *corpusVersion)); err != nil {
+		return report, errors.New("failed to record corpus version: " + err.Error())
+	}
+	report.CorpusVersion = version
+
+	if err := db.InvalidateAnswerCache(ctx, client, tenant); err != nil {
+		// Entries are keyed by corpus version as well, so stale ones are never served.
+		logger.Error("Failed to invalidate answer cache", zap.String("tenant", tenant), zap.Error(err))
+	}
+
+	logger.Info("Corpus imported",
+		zap.String("tenant", tenant), zap.String("from", archive.Header.Tenant), zap.Int64("version", version),
+		zap.Int("documents", len(archive.Header.Documents)), zap.Any("imported", report.Imported), zap.Int("cleared", report.Cleared))
+	return report, nil
+}
+
+func documentID(document bson.D) (any, bool) {
+	id := documentField(document, "_id")
+	return id, id != nil
+}
+
+func documentField(document bson.D, key string) any {
+	for _, element := range document {
+		if element.Key == key {
+			return element.Value
+		}
+	}
+	return nil
+}
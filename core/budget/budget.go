@@ -0,0 +1,280 @@
+// Package budget bounds the wall-clock time and tokens one agent execution may spend.
+// Tool selection and tool calls stop once they have used their share of the budget, so
+// the answer is written from what was gathered; the answer itself is cut off when the
+// whole budget is spent. Either way the client is told with a "budget_exhausted" event
+// and still gets the (partial) answer.
+package budget
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/SaiNageswarS/agent-boot/agentboot"
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/ollama/ollama/api"
+)
+
+// Code is the error code of the stream event sent when a budget ran out.
+const Code = "budget_exhausted"
+
+const (
+	ReasonTime   = "time"
+	ReasonTokens = "tokens"
+
+	StageTools  = "tools"
+	StageAnswer = "answer"
+)
+
+// toolShare is the part of each budget that tool selection and tool calls may use,
+// leaving the rest for the answer.
+const toolShare = 2.0 / 3
+
+// Budget tracks one execution. A zero duration or token limit is unlimited.
+type Budget struct {
+	started  time.Time
+	duration time.Duration
+	tokens   int64
+	used     func() int64 // tokens used by completed model calls
+
+	mu     sync.Mutex
+	reason string
+	stage  string
+}
+
+// New starts a budget. used reports the tokens the execution's completed model calls
+// have used, typically from its llmrouter.Meter.
+func New(started time.Time, duration time.Duration, tokens int64, used func() int64) *Budget {
+	return &Budget{started: started, duration: duration, tokens: tokens, used: used}
+}
+
+// Exhausted returns why and in which stage the budget ran out, or "" if it has not.
+func (b *Budget) Exhausted() (reason, stage string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.reason, b.stage
+}
+
+// exhaust records the first reason the budget ran out in each stage. An answer that was
+// cut off is reported over tools that were skipped.
+func (b *Budget) exhaust(reason, stage string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.reason == "" || (stage == StageAnswer && b.stage != StageAnswer) {
+		b.reason, b.stage = reason, stage
+	}
+}
+
+func (b *Budget) exhausted() bool {
+	reason, _ := b.Exhausted()
+	return reason != ""
+}
+
+// toolsOver reports whether the tool stage has used its share, recording why.
+func (b *Budget) toolsOver(now time.Time) bool {
+	if b.exhausted() {
+		return true
+	}
+	if b.duration > 0 && now.Sub(b.started) >= time.Duration(float64(b.duration)*toolShare) {
+		b.exhaust(ReasonTime, StageTools)
+	} else if b.tokens > 0 && float64(b.used()) >= float64(b.tokens)*toolShare {
+		b.exhaust(ReasonTokens, StageTools)
+	}
+	return b.exhausted()
+}
+
+func (b *Budget) toolDeadline() (time.Time, bool) {
+	return b.started.Add(time.Duration(float64(b.duration) * toolShare)), b.duration > 0
+}
+
+// ToolSelector skips tool selection once the tool stage is over budget, and cuts off a
+// selection still running when its time is up. Either way no tools are selected and the
+// agent moves on to the answer.
+func (b *Budget) ToolSelector(client llm.LLMClient) llm.LLMClient {
+	return &selectorClient{LLMClient: client, budget: b}
+}
+
+type selectorClient struct {
+	llm.LLMClient
+	budget *Budget
+}
+
+func (c *selectorClient) GenerateInferenceWithTools(ctx context.Context, messages []llm.Message, contentCallback func(chunk string) error, toolCallback func(toolCalls []api.ToolCall) error, opts ...llm.LLMOption) error {
+	if c.budget.toolsOver(time.Now()) {
+		return nil
+	}
+	if deadline, ok := c.budget.toolDeadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	var calls []api.ToolCall
+	err := c.LLMClient.GenerateInferenceWithTools(ctx, messages, contentCallback, func(toolCalls []api.ToolCall) error {
+		calls = append(calls, toolCalls...)
+		return nil
+	}, opts...)
+	// tools picked by a selection that overran are not run
+	if c.budget.toolsOver(time.Now()) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(calls) > 0 && toolCallback != nil {
+		return toolCallback(calls)
+	}
+	return nil
+}
+
+// Tool skips a tool call once the tool stage is over budget and cuts off one still
+// running when its time is up.
+func (b *Budget) Tool(handler func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk) func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+	return func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+		if b.toolsOver(time.Now()) {
+			closed := make(chan *schema.ToolResultChunk)
+			close(closed)
+			return closed
+		}
+
+		deadline, ok := b.toolDeadline()
+		if !ok {
+			return handler(ctx, params)
+		}
+
+		ctx, cancel := context.WithDeadline(ctx, deadline)
+		in := handler(ctx, params)
+		out := make(chan *schema.ToolResultChunk)
+		go func() {
+			defer close(out)
+			defer cancel()
+			for chunk := range in {
+				select {
+				case out <- chunk:
+				case <-ctx.Done():
+				}
+			}
+			b.toolsOver(time.Now())
+		}()
+		return out
+	}
+}
+
+// Answer cuts the answer off when the whole budget is spent, keeping what was streamed,
+// and reports no error so the agent completes with it. Tokens are estimated from the
+// streamed text while the answer is written, since the provider's counts come at the end.
+func (b *Budget) Answer(client llm.LLMClient) llm.LLMClient {
+	return &answerClient{LLMClient: client, budget: b}
+}
+
+type answerClient struct {
+	llm.LLMClient
+	budget *Budget
+}
+
+func (c *answerClient) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	// once everything is spent the model is not called at all, which leaves an empty
+	// answer, or an unrepaired one for a structured-output repair
+	b := c.budget
+	if b.duration > 0 && time.Since(b.started) >= b.duration {
+		b.exhaust(ReasonTime, StageAnswer)
+		return nil
+	}
+	if b.tokens > 0 && b.used() >= b.tokens {
+		b.exhaust(ReasonTokens, StageAnswer)
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if b.duration > 0 {
+		var cancelDeadline context.CancelFunc
+		ctx, cancelDeadline = context.WithDeadline(ctx, b.started.Add(b.duration))
+		defer cancelDeadline()
+	}
+
+	var (
+		mu       sync.Mutex
+		streamed int
+		cut      bool
+	)
+	err := c.LLMClient.GenerateInference(ctx, messages, func(chunk string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if cut {
+			return nil
+		}
+		streamed += len(chunk)
+		if b.tokens > 0 && b.used()+estimateTokens(streamed) > b.tokens {
+			cut = true
+			b.exhaust(ReasonTokens, StageAnswer)
+			cancel()
+			return nil
+		}
+		return callback(chunk)
+	}, opts...)
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		b.exhaust(ReasonTime, StageAnswer)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if cut || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil
+	}
+	return err
+}
+
+// Reporter sends the budget_exhausted event ahead of the completion and marks the
+// completion with why and where the budget ran out.
+func (b *Budget) Reporter(inner agentboot.ProgressReporter) agentboot.ProgressReporter {
+	return &reporter{ProgressReporter: inner, budget: b}
+}
+
+type reporter struct {
+	agentboot.ProgressReporter
+	budget *Budget
+}
+
+func (r *reporter) Send(event *schema.AgentStreamChunk) error {
+	complete, ok := event.ChunkType.(*schema.AgentStreamChunk_Complete)
+	if !ok || complete.Complete == nil {
+		return r.ProgressReporter.Send(event)
+	}
+
+	reason, stage := r.budget.Exhausted()
+	if reason == "" {
+		return r.ProgressReporter.Send(event)
+	}
+
+	if err := r.ProgressReporter.Send(agentboot.NewStreamError(Message(reason, stage), Code)); err != nil {
+		return err
+	}
+	if complete.Complete.Metadata == nil {
+		complete.Complete.Metadata = make(map[string]string)
+	}
+	complete.Complete.Metadata["budgetExhausted"] = reason + ":" + stage
+	return r.ProgressReporter.Send(event)
+}
+
+// Message tells the user what running out of budget did to the answer.
+func Message(reason, stage string) string {
+	limit := "the time allowed for one question"
+	if reason == ReasonTokens {
+		limit = "the token budget for one question"
+	}
+	if stage == StageTools {
+		return "Not every source could be checked within " + limit + ". The answer is based on what was found."
+	}
+	return "This answer was cut short because it reached " + limit + "."
+}
+
+// estimateTokens approximates four bytes per token, as llmrouter's meter does.
+func estimateTokens(n int) int64 {
+	if n == 0 {
+		return 0
+	}
+	return int64(n/4 + 1)
+}
@@ -0,0 +1,174 @@
+package budget
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/ollama/ollama/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeModel struct {
+	llm.LLMClient
+	chunks    []string
+	toolCalls []api.ToolCall
+	delay     time.Duration
+	calls     int
+}
+
+func (m *fakeModel) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	m.calls++
+	for _, chunk := range m.chunks {
+		select {
+		case <-time.After(m.delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if err := callback(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *fakeModel) GenerateInferenceWithTools(ctx context.Context, messages []llm.Message, contentCallback func(chunk string) error, toolCallback func(toolCalls []api.ToolCall) error, opts ...llm.LLMOption) error {
+	m.calls++
+	select {
+	case <-time.After(m.delay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return toolCallback(m.toolCalls)
+}
+
+type recordingReporter struct {
+	events []*schema.AgentStreamChunk
+}
+
+func (r *recordingReporter) Send(event *schema.AgentStreamChunk) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func selectTools(t *testing.T, client llm.LLMClient) []api.ToolCall {
+	var selected []api.ToolCall
+	err := client.GenerateInferenceWithTools(t.Context(), nil, func(string) error { return nil }, func(calls []api.ToolCall) error {
+		selected = append(selected, calls...)
+		return nil
+	})
+	require.NoError(t, err)
+	return selected
+}
+
+func TestUnlimitedBudgetPassesThrough(t *testing.T) {
+	b := New(time.Now(), 0, 0, func() int64 { return 1_000_000 })
+	model := &fakeModel{chunks: []string{"Arnica."}, toolCalls: []api.ToolCall{{Function: api.ToolCallFunction{Name: "medicine-rag"}}}}
+
+	assert.Len(t, selectTools(t, b.ToolSelector(model)), 1)
+
+	var answer strings.Builder
+	require.NoError(t, b.Answer(model).GenerateInference(t.Context(), nil, func(chunk string) error {
+		answer.WriteString(chunk)
+		return nil
+	}))
+	assert.Equal(t, "Arnica.", answer.String())
+
+	reason, _ := b.Exhausted()
+	assert.Empty(t, reason)
+}
+
+func TestToolStageStopsAtItsShareOfTokens(t *testing.T) {
+	used := int64(0)
+	b := New(time.Now(), 0, 300, func() int64 { return used })
+	model := &fakeModel{toolCalls: []api.ToolCall{{Function: api.ToolCallFunction{Name: "medicine-rag"}}}}
+	selector := b.ToolSelector(model)
+
+	assert.Len(t, selectTools(t, selector), 1)
+
+	used = 200 // two thirds of the budget
+	assert.Empty(t, selectTools(t, selector))
+	assert.Equal(t, 1, model.calls, "the selector is not called once the tool stage is over")
+
+	tool := b.Tool(func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+		t.Fatal("tool should not run")
+		return nil
+	})
+	_, open := <-tool(t.Context(), nil)
+	assert.False(t, open)
+
+	reason, stage := b.Exhausted()
+	assert.Equal(t, ReasonTokens, reason)
+	assert.Equal(t, StageTools, stage)
+}
+
+func TestToolSelectionCutOffByTime(t *testing.T) {
+	b := New(time.Now(), 60*time.Millisecond, 0, func() int64 { return 0 })
+	model := &fakeModel{delay: time.Second, toolCalls: []api.ToolCall{{Function: api.ToolCallFunction{Name: "medicine-rag"}}}}
+
+	assert.Empty(t, selectTools(t, b.ToolSelector(model)), "a selection that overruns picks no tools")
+	reason, stage := b.Exhausted()
+	assert.Equal(t, ReasonTime, reason)
+	assert.Equal(t, StageTools, stage)
+}
+
+func TestAnswerCutOffByTokens(t *testing.T) {
+	b := New(time.Now(), 0, 10, func() int64 { return 0 })
+	model := &fakeModel{chunks: []string{"Arnica montana ", "is used for ", "bruising and ", "muscle soreness."}}
+
+	var answer strings.Builder
+	err := b.Answer(model).GenerateInference(t.Context(), nil, func(chunk string) error {
+		answer.WriteString(chunk)
+		return nil
+	})
+	require.NoError(t, err, "a cut answer is not an error")
+	assert.Equal(t, "Arnica montana is used for ", answer.String())
+
+	reason, stage := b.Exhausted()
+	assert.Equal(t, ReasonTokens, reason)
+	assert.Equal(t, StageAnswer, stage)
+}
+
+func TestAnswerCutOffByTime(t *testing.T) {
+	b := New(time.Now(), 100*time.Millisecond, 0, func() int64 { return 0 })
+	model := &fakeModel{delay: 40 * time.Millisecond, chunks: []string{"a", "b", "c", "d", "e"}}
+
+	var answer strings.Builder
+	err := b.Answer(model).GenerateInference(t.Context(), nil, func(chunk string) error {
+		answer.WriteString(chunk)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, answer.String())
+	assert.Less(t, len(answer.String()), 5)
+
+	reason, stage := b.Exhausted()
+	assert.Equal(t, ReasonTime, reason)
+	assert.Equal(t, StageAnswer, stage)
+}
+
+func TestReporterAnnouncesExhaustedBudget(t *testing.T) {
+	b := New(time.Now(), 0, 10, func() int64 { return 0 })
+	inner := &recordingReporter{}
+	reporter := b.Reporter(inner)
+
+	require.NoError(t, reporter.Send(&schema.AgentStreamChunk{ChunkType: &schema.AgentStreamChunk_Complete{Complete: &schema.StreamComplete{Answer: "ok"}}}))
+	require.Len(t, inner.events, 1, "nothing is added while within budget")
+
+	b.exhaust(ReasonTokens, StageAnswer)
+	require.NoError(t, reporter.Send(&schema.AgentStreamChunk{ChunkType: &schema.AgentStreamChunk_Complete{Complete: &schema.StreamComplete{Answer: "Arnica"}}}))
+	require.Len(t, inner.events, 3)
+
+	streamErr := inner.events[1].GetError()
+	require.NotNil(t, streamErr)
+	assert.Equal(t, Code, streamErr.ErrorCode)
+	assert.Contains(t, streamErr.ErrorMessage, "cut short")
+
+	complete := inner.events[2].GetComplete()
+	assert.Equal(t, "Arnica", complete.Answer, "the partial answer still completes")
+	assert.Equal(t, "tokens:answer", complete.Metadata["budgetExhausted"])
+}
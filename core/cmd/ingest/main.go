@@ -0,0 +1,228 @@
+// ingest loads a directory, an S3, Google Cloud Storage or Azure Blob Storage prefix,
+// or the web pages under a list of URLs, of PDF, EPUB, DOCX, HTML and markdown files
+// into a tenant: each document is chunked, published as a corpus version and embedded
+// with the tenant's embedder.
+// Progress is kept per document, so an interrupted run is resumed by running it again,
+// and objects whose ETag has not changed since they were ingested are not downloaded.
+//
+//	ingest -tenant healthcare -dir ./books
+//	ingest -tenant healthcare -container library -prefix materia-medica/
+//	ingest -tenant healthcare -s3 library -region eu-west-1 -prefix materia-medica/
+//	ingest -tenant healthcare -web https://clinic.example/protocols/
+//	ingest -tenant healthcare -source library   # configured in the tenant's config
+//	ingest -tenant healthcare -dir ./notes -access-groups practice-internal
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/cloud"
+	"github.com/SaiNageswarS/go-api-boot/config"
+	"github.com/SaiNageswarS/go-api-boot/dotenv"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
+	"github.com/SaiNageswarS/medicine-rag/core/events"
+	"github.com/SaiNageswarS/medicine-rag/core/ingest"
+	"github.com/SaiNageswarS/medicine-rag/core/tenancy"
+	"go.uber.org/zap"
+)
+
+func main() {
+	dotenv.LoadEnv()
+
+	flags := flag.NewFlagSet("ingest", flag.ExitOnError)
+	configPath := flags.String("config", "config.ini", "path to the deployment's config.ini")
+	tenant := flags.String("tenant", "", "tenant (database) to ingest into")
+	dir := flags.String("dir", "", "local directory of documents")
+	container := flags.String("container", "", "Azure Blob Storage container of documents, in the configured storage account")
+	s3Bucket := flags.String("s3", "", "S3 bucket of documents")
+	region := flags.String("region", "", "AWS region of -s3; defaults to the AWS config's")
+	gcsBucket := flags.String("gcs", "", "Google Cloud Storage bucket of documents")
+	prefix := flags.String("prefix", "", "object name prefix within -container, -s3 or -gcs")
+	web := flags.String("web", "", "comma-separated URLs to crawl; the pages under them are ingested")
+	maxPages := flags.Int("max-pages", ingest.DefaultCrawlPages, "URLs -web visits at most")
+	sourceName := flags.String("source", "", "source configured in the tenant's config, by name")
+	batchSize := flags.Int("batch", ingest.DefaultEmbedBatchSize, "chunks embedded per request")
+	workers := flags.Int("workers", ingest.DefaultEmbedWorkers, "embedding requests in flight at once")
+	force := flags.Bool("force", false, "re-chunk documents that have not changed since they were ingested")
+	allowDuplicates := flags.Bool("allow-duplicates", false, "ingest documents that copy ones already ingested from other sources")
+	accessGroups := flags.String("access-groups", "", "comma-separated groups whose users alone may retrieve the documents; a -source's configured groups by default")
+	documentType := flags.String("type", "", "chunk every document as narrative, repertory or case-journal; detected per document by default")
+	initTenant := flags.Bool("init", false, "create the tenant's collections and indexes first")
+	flags.Parse(os.Args[1:])
+
+	var source db.SourceConfig
+	sources := 0
+	for _, flagged := range []struct {
+		value  string
+		config db.SourceConfig
+	}{
+		{*dir, db.SourceConfig{Kind: db.SourceDir, Path: *dir}},
+		{*container, db.SourceConfig{Kind: db.SourceAzure, Bucket: *container, Prefix: *prefix}},
+		{*s3Bucket, db.SourceConfig{Kind: db.SourceS3, Bucket: *s3Bucket, Prefix: *prefix, Region: *region}},
+		{*gcsBucket, db.SourceConfig{Kind: db.SourceGCS, Bucket: *gcsBucket, Prefix: *prefix}},
+		{*web, db.SourceConfig{Kind: db.SourceWeb, URLs: strings.Split(*web, ","), MaxPages: *maxPages}},
+		{*sourceName, db.SourceConfig{Name: *sourceName}},
+	} {
+		if flagged.value != "" {
+			source = flagged.config
+			sources++
+		}
+	}
+	if *tenant == "" || sources != 1 {
+		fmt.Fprintln(os.Stderr, "usage: ingest -tenant <tenant> (-dir <directory> | -container <container> | -s3 <bucket> | -gcs <bucket> | -web <urls> | -source <name>) [-prefix <prefix>] [flags]")
+		flags.PrintDefaults()
+		os.Exit(2)
+	}
+	if *accessGroups != "" {
+		source.AccessGroups = strings.Split(*accessGroups, ",")
+	}
+
+	if *documentType != "" && !ingest.IsDocumentType(*documentType) {
+		fmt.Fprintln(os.Stderr, "-type must be narrative, repertory or case-journal")
+		os.Exit(2)
+	}
+
+	ccfg := &appconfig.AppConfig{}
+	if err := config.LoadConfig(*configPath, ccfg); err != nil {
+		logger.Fatal("Failed to load config", zap.Error(err))
+	}
+
+	ctx := getCancellableContext()
+	if err := run(ctx, ccfg, *tenant, source, *batchSize, *workers, *force, *allowDuplicates, *documentType, *initTenant); err != nil {
+		logger.Fatal("Ingestion failed", zap.String("tenant", *tenant), zap.Error(err))
+	}
+}
+
+// run ingests the source sourceConfig sets, or the tenant's source named in it when it
+// has no kind. Access groups set in sourceConfig replace the named source's.
+func run(ctx context.Context, ccfg *appconfig.AppConfig, tenant string, sourceConfig db.SourceConfig, batchSize, workers int, force, allowDuplicates bool, documentType string, initTenant bool) error {
+	mongo := odm.ProvideMongoClient()
+	defer mongo.Disconnect(context.Background())
+
+	if sourceConfig.Kind == "" {
+		configured, err := ingest.TenantSource(ctx, mongo, tenant, sourceConfig.Name)
+		if err != nil {
+			return errors.New("source " + sourceConfig.Name + ": " + err.Error())
+		}
+		if len(sourceConfig.AccessGroups) > 0 {
+			configured.AccessGroups = sourceConfig.AccessGroups
+		}
+		sourceConfig = configured
+	}
+	source, err := ingest.OpenSource(ctx, sourceConfig, ccfg.AzureStorageAccount)
+	if err != nil {
+		return errors.New("failed to open source: " + err.Error())
+	}
+
+	name, err := ingest.TenantEmbedderName(ctx, mongo, tenant)
+	if err != nil {
+		return errors.New("failed to load tenant config: " + err.Error())
+	}
+	embedders, limits := embedding.ProvideRegistry(ccfg), tenancy.ProvideLimits(ccfg)
+	spec, embedder, err := embedders.Resolve(name)
+	if err != nil {
+		return errors.New("failed to resolve tenant embedder: " + err.Error())
+	}
+	embedder = limits.Embedder(tenant, embedder)
+
+	if initTenant {
+		if err := db.InitSearchCoreDB(ctx, mongo, tenant, spec.Dimensions); err != nil {
+			return errors.New("failed to initialize tenant database: " + err.Error())
+		}
+	}
+
+	pipeline := ingest.NewPipeline(mongo, spec, embedder)
+	pipeline.BatchSize = batchSize
+	pipeline.Workers = workers
+	pipeline.Force = force
+	pipeline.AllowDuplicates = allowDuplicates
+	pipeline.DocumentType = documentType
+	pipeline.AccessGroups = sourceConfig.AccessGroups
+	if multilingual := embedders.MultilingualName(); multilingual != "" {
+		if multilingualSpec, multilingualEmbedder, err := embedders.Resolve(multilingual); err == nil {
+			pipeline.Multilingual = &ingest.NamedEmbedder{Name: multilingual, Spec: multilingualSpec, Embedder: limits.Embedder(tenant, multilingualEmbedder)}
+		} else {
+			logger.Error("Multilingual embedder unavailable", zap.String("embedder", multilingual), zap.Error(err))
+		}
+	}
+	if ccfg.AzureStorageAccount != "" {
+		// without a storage account, figures keep only their captions
+		pipeline.Storage = cloud.ProvideAzure(&ccfg.BootConfig)
+	}
+
+	// the tenant's event sinks are told of the run as of a sync's
+	dispatcher := events.ProvideDispatcher(mongo)
+	defer dispatcher.Wait()
+	job := events.Job{Kind: events.JobCLI, Source: sourceConfig.Name, Status: db.SyncRunRunning}
+	if job.Source == "" {
+		job.Source = source.URI("")
+	}
+	job.ID, _ = odm.HashedKey(tenant, job.Source, strconv.FormatInt(time.Now().UnixNano(), 10))
+	dispatcher.Emit(ctx, events.New(events.JobStarted, tenant, job))
+	pipeline.Indexed = dispatcher.IndexedHook(tenant, job)
+
+	report, err := pipeline.Run(ctx, tenant, source)
+	job.Status, job.Ingested, job.Failed, job.Chunks, job.Embedded = db.SyncRunDone, report.Ingested+report.Resumed, report.Failed, report.Chunks, report.Embedded
+	eventType := events.JobCompleted
+	if err != nil {
+		job.Status, job.Error, eventType = db.SyncRunFailed, err.Error(), events.JobFailed
+	}
+	dispatcher.Emit(ctx, events.New(eventType, tenant, job))
+
+	logger.Info("Ingestion finished",
+		zap.String("tenant", tenant),
+		zap.Int("ingested", report.Ingested),
+		zap.Int("resumed", report.Resumed),
+		zap.Int("unchanged", report.Unchanged),
+		zap.Int("duplicates", report.Duplicates),
+		zap.Int("unsupported", report.Unsupported),
+		zap.Int("failed", report.Failed),
+		zap.Int("chunks", report.Chunks),
+		zap.Int("tableRows", report.TableRows),
+		zap.Int("figures", report.Figures),
+		zap.Int("quarantined", report.Quarantined),
+		zap.Int("redacted", report.Redacted),
+		zap.Int("embedded", report.Embedded))
+	for sourceUri, pages := range report.Flagged {
+		logger.Info("Scanned pages flagged for review", zap.String("sourceUri", sourceUri), zap.Ints("pages", pages))
+	}
+	for sourceUri, original := range report.DuplicateOf {
+		logger.Info("Duplicate document skipped", zap.String("sourceUri", sourceUri), zap.String("duplicateOf", original))
+	}
+	if err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d documents failed; run again to retry them", report.Failed)
+	}
+	return nil
+}
+
+// getCancellableContext stops ingestion on SIGINT or SIGTERM. Documents finished so far
+// keep their progress.
+func getCancellableContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-sig
+		cancel()
+	}()
+
+	return ctx
+}
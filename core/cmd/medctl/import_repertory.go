@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/repertory"
+	"go.uber.org/zap"
+)
+
+// importRepertory reads a repertory file and replaces the tenant's rubrics of that
+// repertory with its rubrics. It is idempotent, so a corrected file is imported by
+// running it again.
+func importRepertory(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("import-repertory", flag.ExitOnError)
+	tenant := flags.String("tenant", "", "tenant (database) to import into")
+	name := flags.String("repertory", "", "the repertory's name, cited in answers, e.g. Kent")
+	file := flags.String("file", "", "repertory file")
+	format := flags.String("format", "", "text, csv, tsv or json; by the file's extension by default")
+	maxGrade := flags.Int("max-grade", repertory.MaxGrade, "highest grade the file's numbers use; scaled to 1-3")
+	initTenant := flags.Bool("init", false, "create the repertory collection and its indexes first")
+	flags.Parse(args)
+
+	if *tenant == "" || *name == "" || *file == "" {
+		fmt.Fprintln(os.Stderr, "usage: medctl import-repertory -tenant <tenant> -repertory <name> -file <file> [flags]")
+		flags.PrintDefaults()
+		os.Exit(2)
+	}
+	if *format == "" {
+		*format = repertory.FormatOf(*file)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	rubrics, err := repertory.Parse(f, repertory.Options{Repertory: *name, Format: *format, MaxGrade: *maxGrade})
+	if err != nil {
+		return errors.New("failed to read " + *file + ": " + err.Error())
+	}
+
+	mongo := odm.ProvideMongoClient()
+	defer mongo.Disconnect(ctx)
+
+	if *initTenant {
+		if err := odm.EnsureIndexes[db.RubricModel](ctx, mongo, *tenant); err != nil {
+			return errors.New("failed to create repertory indexes: " + err.Error())
+		}
+	}
+
+	report, err := repertory.Import(ctx, mongo, *tenant, *name, rubrics)
+	if err != nil {
+		return err
+	}
+	if len(report.Unknown) > 0 {
+		logger.Info("Remedies not in the remedy dictionary are named as written",
+			zap.Strings("remedies", report.Unknown))
+	}
+	logger.Info("Repertory imported",
+		zap.String("tenant", *tenant),
+		zap.String("repertory", *name),
+		zap.Int("rubrics", report.Rubrics),
+		zap.Int("remedies", report.Remedies),
+		zap.Int("removed", report.Removed),
+		zap.Int("unknownRemedies", len(report.Unknown)))
+	return nil
+}
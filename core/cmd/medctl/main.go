@@ -0,0 +1,36 @@
+// medctl is the operator CLI for medicine-rag deployments.
+//
+//	medctl seed-demo [-tenant demo] [-password demo1234] [-embed]
+//	medctl import-repertory -tenant healthcare -repertory Kent -file kent.txt [-format text] [-max-grade 3] [-init]
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/SaiNageswarS/go-api-boot/dotenv"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"go.uber.org/zap"
+)
+
+var commands = map[string]func(ctx context.Context, args []string) error{
+	"seed-demo":        seedDemo,
+	"import-repertory": importRepertory,
+}
+
+func main() {
+	dotenv.LoadEnv()
+
+	if len(os.Args) < 2 || commands[os.Args[1]] == nil {
+		fmt.Fprintln(os.Stderr, "usage: medctl <command> [flags]")
+		fmt.Fprintln(os.Stderr, "commands:")
+		fmt.Fprintln(os.Stderr, "  seed-demo          provision a sandbox tenant with a demo corpus, users and conversations")
+		fmt.Fprintln(os.Stderr, "  import-repertory   import a repertory's rubrics and graded remedies from a text, CSV, TSV or JSON file")
+		os.Exit(2)
+	}
+
+	if err := commands[os.Args[1]](context.Background(), os.Args[2:]); err != nil {
+		logger.Fatal("Command failed", zap.String("command", os.Args[1]), zap.Error(err))
+	}
+}
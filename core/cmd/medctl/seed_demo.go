@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/go-api-boot/config"
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
+	"github.com/SaiNageswarS/medicine-rag/core/entities"
+	"github.com/SaiNageswarS/medicine-rag/core/tenancy"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+//go:embed demo/corpus.json
+var demoCorpusJson []byte
+
+type demoCorpus struct {
+	Title           string `json:"title"`
+	SourceURI       string `json:"sourceUri"`
+	Book            string `json:"book"`
+	Author          string `json:"author"`
+	PublicationYear int    `json:"publicationYear"`
+	Sections        []struct {
+		Heading string   `json:"heading"`
+		Tags    []string `json:"tags"`
+		Text    string   `json:"text"`
+	} `json:"sections"`
+	PortalPages   []db.PortalPageModel `json:"portalPages"`
+	Conversations []struct {
+		User     string        `json:"user"`
+		Messages []llm.Message `json:"messages"`
+	} `json:"conversations"`
+}
+
+var demoUsers = []string{"demo@medicine-rag.local", "physician@medicine-rag.local"}
+
+// seedDemo provisions a sandbox tenant: indexes, a small public-domain corpus, demo
+// users, tenant features, portal pages and a couple of canned conversations. It is
+// idempotent, so re-running it resets the demo content to its shipped state.
+func seedDemo(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("seed-demo", flag.ExitOnError)
+	tenant := flags.String("tenant", "demo", "tenant (database) to provision")
+	password := flags.String("password", "demo1234", "password for the demo users")
+	withEmbeddings := flags.Bool("embed", os.Getenv("JINA_AI_API_KEY") != "", "embed the corpus for vector search (needs JINA_AI_API_KEY)")
+	configPath := flags.String("config", "config.ini", "path to the deployment's config.ini, for the embedding rate limits")
+	flags.Parse(args)
+
+	var corpus demoCorpus
+	if err := json.Unmarshal(demoCorpusJson, &corpus); err != nil {
+		return errors.New("failed to parse demo corpus: " + err.Error())
+	}
+
+	mongo := odm.ProvideMongoClient()
+	defer mongo.Disconnect(ctx)
+
+	if err := db.InitSearchCoreDB(ctx, mongo, *tenant, embedding.Legacy.Dimensions); err != nil {
+		return errors.New("failed to initialize tenant database: " + err.Error())
+	}
+
+	if err := seedDemoUsers(ctx, mongo, *tenant, *password); err != nil {
+		return err
+	}
+
+	chunks, err := seedDemoChunks(ctx, mongo, *tenant, corpus)
+	if err != nil {
+		return err
+	}
+
+	if *withEmbeddings {
+		ccfg := &appconfig.AppConfig{}
+		if err := config.LoadConfig(*configPath, ccfg); err != nil {
+			return errors.New("failed to load config: " + err.Error())
+		}
+		// the same batched, rate-limited client ingestion embeds with
+		embedder := tenancy.ProvideLimits(ccfg).Embedder(*tenant, embedding.NewJinaClient(os.Getenv("JINA_AI_API_KEY"), ccfg))
+		if err := seedDemoEmbeddings(ctx, mongo, embedder, *tenant, chunks); err != nil {
+			return err
+		}
+	} else {
+		logger.Info("Skipping embeddings; only lexical search will find demo content")
+	}
+
+	if err := seedDemoPortal(ctx, mongo, *tenant, corpus); err != nil {
+		return err
+	}
+
+	if err := seedDemoConversations(ctx, mongo, *tenant, corpus); err != nil {
+		return err
+	}
+
+	logger.Info("Demo tenant ready",
+		zap.String("tenant", *tenant),
+		zap.Strings("users", demoUsers),
+		zap.Int("chunks", len(chunks)))
+	return nil
+}
+
+func seedDemoUsers(ctx context.Context, mongo odm.MongoClient, tenant, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("failed to hash password: " + err.Error())
+	}
+
+	for _, email := range demoUsers {
+		login := db.NewLoginModel(email)
+		login.HashedPassword = string(hashedPassword)
+		login.CreatedOn = time.Now().Unix()
+
+		if _, err := async.Await(odm.CollectionOf[db.LoginModel](mongo, tenant).Save(ctx, *login)); err != nil {
+			return errors.New("failed to save demo user: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// Demo chunks are written in one corpus version, one chunk per section, the same shape
+// the markdown chunking workflow produces. Seeding a tenant that already holds the same
+// demo chunks publishes no new version, so its cached answers are kept.
+func seedDemoChunks(ctx context.Context, mongo odm.MongoClient, tenant string, corpus demoCorpus) ([]db.ChunkModel, error) {
+	chunks := make([]db.ChunkModel, 0, len(corpus.Sections))
+	for idx, section := range corpus.Sections {
+		secHash, _ := odm.HashedKey(corpus.SourceURI, section.Heading)
+		chunks = append(chunks, db.ChunkModel{
+			ChunkID:         secHash,
+			Title:           section.Heading,
+			SectionPath:     corpus.Title + " | " + section.Heading,
+			SectionIndex:    idx + 1,
+			SectionID:       secHash,
+			SourceURI:       corpus.SourceURI,
+			Book:            corpus.Book,
+			Author:          corpus.Author,
+			PublicationYear: corpus.PublicationYear,
+			Tags:            section.Tags,
+			Sentences:       []string{section.Text},
+			Entities:        entities.ChunkTags([]string{section.Heading}, section.Text),
+		})
+	}
+	for i := range chunks {
+		if i > 0 {
+			chunks[i].PrevChunkID = chunks[i-1].ChunkID
+		}
+		if i < len(chunks)-1 {
+			chunks[i].NextChunkID = chunks[i+1].ChunkID
+		}
+	}
+
+	chunkRepo := odm.CollectionOf[db.ChunkModel](mongo, tenant)
+	existing, err := async.Await(chunkRepo.Find(ctx, bson.M{"$and": bson.A{
+		bson.M{"sourceUri": corpus.SourceURI},
+		db.LiveChunksFilter(),
+	}}, nil, 0, 0))
+	if err != nil {
+		return nil, errors.New("failed to load existing demo chunks: " + err.Error())
+	}
+	if sameDemoChunks(existing, chunks) {
+		logger.Info("Demo chunks unchanged, keeping their corpus version", zap.Int64("version", existing[0].CorpusVersion))
+		return existing, nil
+	}
+
+	version, err := db.NextCorpusVersion(ctx, mongo, tenant)
+	if err != nil {
+		return nil, errors.New("failed to allocate corpus version: " + err.Error())
+	}
+	for i := range chunks {
+		chunks[i].CorpusVersion = version
+		if _, err := async.Await(chunkRepo.Save(ctx, chunks[i])); err != nil {
+			return nil, errors.New("failed to save demo chunk: " + err.Error())
+		}
+	}
+
+	corpusVersion := db.NewCorpusVersionModel(version)
+	corpusVersion.SourceURI = corpus.SourceURI
+	corpusVersion.AddedChunks = len(chunks)
+	corpusVersion.CreatedOn = time.Now().Unix()
+
+	if _, err := async.Await(odm.CollectionOf[db.CorpusVersionModel](mongo, tenant).Save(ctx, *corpusVersion)); err != nil {
+		return nil, errors.New("failed to record corpus version: " + err.Error())
+	}
+	if err := db.InvalidateAnswerCache(ctx, mongo, tenant); err != nil {
+		return nil, errors.New("failed to invalidate answer cache: " + err.Error())
+	}
+	return chunks, nil
+}
+
+// sameDemoChunks reports whether the live demo chunks are the ones about to be seeded.
+func sameDemoChunks(existing, chunks []db.ChunkModel) bool {
+	if len(existing) == 0 || len(existing) != len(chunks) {
+		return false
+	}
+
+	stored := make(map[string]db.ChunkModel, len(existing))
+	for _, chunk := range existing {
+		stored[chunk.ChunkID] = chunk
+	}
+	for _, chunk := range chunks {
+		old, ok := stored[chunk.ChunkID]
+		if !ok || old.Title != chunk.Title || old.SectionPath != chunk.SectionPath || old.SectionIndex != chunk.SectionIndex ||
+			old.Book != chunk.Book || old.Author != chunk.Author || old.PublicationYear != chunk.PublicationYear ||
+			old.PrevChunkID != chunk.PrevChunkID || old.NextChunkID != chunk.NextChunkID ||
+			!slices.Equal(old.Tags, chunk.Tags) || !slices.Equal(old.Sentences, chunk.Sentences) || !slices.Equal(old.Entities, chunk.Entities) {
+			return false
+		}
+	}
+	return true
+}
+
+func seedDemoEmbeddings(ctx context.Context, mongo odm.MongoClient, embedder embed.Embedder, tenant string, chunks []db.ChunkModel) error {
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.SectionPath + "\n" + chunk.Sentences[0]
+	}
+	embeddings, err := embedding.EmbedBatch(ctx, embedder, texts, embedding.WithTask("retrieval.passage"), embedding.WithModel(embedding.Legacy.Model))
+	if err != nil {
+		return errors.New("failed to embed demo chunks: " + err.Error())
+	}
+
+	for i, chunk := range chunks {
+		chunkAnn := db.ChunkAnnModel{ChunkID: chunk.ChunkID, Embedding: bson.NewVector(embeddings[i]), Model: embedding.Legacy.Model, Dimensions: len(embeddings[i])}
+		if _, err := async.Await(odm.CollectionOf[db.ChunkAnnModel](mongo, tenant).Save(ctx, chunkAnn)); err != nil {
+			return errors.New("failed to save demo embedding: " + err.Error())
+		}
+	}
+	return nil
+}
+
+func seedDemoPortal(ctx context.Context, mongo odm.MongoClient, tenant string, corpus demoCorpus) error {
+	tenantConfig := db.TenantConfigModel{
+		ID:                  db.TenantConfigID,
+		ShareLinksEnabled:   true,
+		PublicPortalEnabled: true,
+		PublicPortalTitle:   "Medicine RAG Demo",
+		PublicTags:          []string{"public"},
+	}
+	if _, err := async.Await(odm.CollectionOf[db.TenantConfigModel](mongo, tenant).Save(ctx, tenantConfig)); err != nil {
+		return errors.New("failed to save tenant config: " + err.Error())
+	}
+
+	for _, page := range corpus.PortalPages {
+		page.Published = true
+		page.UpdatedOn = time.Now().Unix()
+
+		if _, err := async.Await(odm.CollectionOf[db.PortalPageModel](mongo, tenant).Save(ctx, page)); err != nil {
+			return errors.New("failed to save portal page: " + err.Error())
+		}
+	}
+	return nil
+}
+
+func seedDemoConversations(ctx context.Context, mongo odm.MongoClient, tenant string, corpus demoCorpus) error {
+	for idx, conversation := range corpus.Conversations {
+		sessionId, _ := odm.HashedKey("demo-conversation", conversation.User, conversation.Messages[0].Content)
+		now := time.Now().Add(-time.Duration(len(corpus.Conversations)-idx) * time.Hour).Unix()
+
+		model := db.ConversationModel{
+			SessionID: sessionId,
+			UserID:    db.NewLoginModel(conversation.User).Id(),
+			Messages:  conversation.Messages,
+			CreatedOn: now,
+			UpdatedOn: now,
+		}
+		if _, err := async.Await(odm.CollectionOf[db.ConversationModel](mongo, tenant).Save(ctx, model)); err != nil {
+			return errors.New("failed to save demo conversation: " + err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+// Package compaction keeps long consultations in context. agent-boot's conversation
+// manager only keeps the last few turns; the turns it trims are folded into a rolling
+// summary on the conversation document, and the summary is handed back to the agent at
+// the start of the conversation whenever the session is loaded.
+package compaction
+
+import (
+	"context"
+	"hash/fnv"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/agent-boot/memory"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/llmrouter"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxSummaryLength bounds the stored summary, in characters.
+	MaxSummaryLength = 4000
+
+	// maxPending bounds the trimmed turns waiting to be summarized, should summarizing
+	// keep failing. The oldest are dropped first.
+	maxPending = 40
+
+	// each trimmed message contributes at most this many characters to the summary prompt
+	maxMessageLength = 2000
+
+	summarizeTimeout = 30 * time.Second
+)
+
+const recapHeader = "Summary of the earlier part of this consultation, for context only:"
+
+const summarySystemPrompt = "You maintain a running summary of a consultation between a user and a homeopathy assistant. " +
+	"Merge the earlier turns into the existing summary. Keep the patient's details, symptoms and history, " +
+	"the remedies, potencies and doses discussed, the advice given and any open questions. " +
+	"Drop greetings and repetition. Write plain prose of at most 200 words and reply with the summary only."
+
+// Collection wraps the conversation collection handed to agent-boot. Loading a session
+// prepends the summary of its trimmed turns; saving records the turns agent-boot trimmed
+// and summarizes them in the background with the given model.
+type Collection struct {
+	odm.OdmCollectionInterface[memory.Conversation]
+	summaries odm.OdmCollectionInterface[db.ConversationSummaryModel]
+	model     llm.LLMClient
+
+	mu     sync.Mutex
+	loaded map[string][]llm.Message // messages as stored, by session
+	recaps map[string]string        // recap message prepended on load, by session
+
+	pending sync.WaitGroup
+}
+
+func NewCollection(conversations odm.OdmCollectionInterface[memory.Conversation], summaries odm.OdmCollectionInterface[db.ConversationSummaryModel], model llm.LLMClient) *Collection {
+	return &Collection{
+		OdmCollectionInterface: conversations,
+		summaries:              summaries,
+		model:                  model,
+		loaded:                 make(map[string][]llm.Message),
+		recaps:                 make(map[string]string),
+	}
+}
+
+func (c *Collection) FindOneByID(ctx context.Context, id string) <-chan async.Result[*memory.Conversation] {
+	return async.Go(func() (*memory.Conversation, error) {
+		conversation, err := async.Await(c.OdmCollectionInterface.FindOneByID(ctx, id))
+		if err != nil {
+			return nil, err
+		}
+
+		var recap string
+		if state, err := async.Await(c.summaries.FindOneByID(ctx, id)); err == nil {
+			recap = Recap(state.Summary, state.Pending)
+		}
+
+		c.mu.Lock()
+		c.loaded[id] = slices.Clone(conversation.Messages)
+		c.recaps[id] = recap
+		c.mu.Unlock()
+
+		if recap != "" {
+			// marked as a tool result so that agent-boot does not count it as a user turn
+			conversation.Messages = append([]llm.Message{{Role: "user", Content: recap, IsToolResult: true}}, conversation.Messages...)
+		}
+		return conversation, nil
+	})
+}
+
+func (c *Collection) Save(ctx context.Context, conversation memory.Conversation) <-chan async.Result[struct{}] {
+	return async.Go(func() (struct{}, error) {
+		c.mu.Lock()
+		previous, wasLoaded := c.loaded[conversation.ID]
+		recap := c.recaps[conversation.ID]
+		c.mu.Unlock()
+
+		conversation.Messages = slices.DeleteFunc(slices.Clone(conversation.Messages), func(message llm.Message) bool {
+			return recap != "" && message.IsToolResult && message.Content == recap
+		})
+		if _, err := async.Await(c.OdmCollectionInterface.Save(ctx, conversation)); err != nil {
+			return struct{}{}, err
+		}
+
+		c.mu.Lock()
+		c.loaded[conversation.ID] = slices.Clone(conversation.Messages)
+		c.mu.Unlock()
+
+		if wasLoaded {
+			if trimmed := Trimmed(previous, conversation.Messages); len(trimmed) > 0 {
+				c.queue(ctx, conversation.ID, trimmed)
+			}
+		}
+		return struct{}{}, nil
+	})
+}
+
+// Wait blocks until the summaries started by this collection have been written.
+func (c *Collection) Wait() {
+	c.pending.Wait()
+}
+
+// queue records trimmed turns on the conversation and starts summarizing them. The turns
+// are recorded before Save returns, so a session loaded before the summary is ready
+// still sees them.
+func (c *Collection) queue(ctx context.Context, id string, trimmed []llm.Message) {
+	trimmed = slices.DeleteFunc(trimmed, func(message llm.Message) bool { return message.IsToolResult })
+	if len(trimmed) == 0 {
+		return
+	}
+
+	lock := sessionLock(id)
+	lock.Lock()
+	state := c.state(ctx, id)
+	state.Pending = append(state.Pending, trimmed...)
+	if overflow := len(state.Pending) - maxPending; overflow > 0 {
+		state.Pending = state.Pending[overflow:]
+	}
+	_, err := async.Await(c.summaries.Save(ctx, state))
+	lock.Unlock()
+
+	if err != nil {
+		logger.Error("Failed to record trimmed turns", zap.String("sessionId", id), zap.Error(err))
+		return
+	}
+
+	c.pending.Add(1)
+	go func() {
+		defer c.pending.Done()
+		c.fold(context.WithoutCancel(ctx), id)
+	}()
+}
+
+// fold merges the pending turns into the summary. Turns queued while the model runs stay
+// pending for the next fold.
+func (c *Collection) fold(ctx context.Context, id string) {
+	ctx, cancel := context.WithTimeout(ctx, summarizeTimeout)
+	defer cancel()
+
+	state := c.state(ctx, id)
+	if len(state.Pending) == 0 {
+		return
+	}
+
+	summary, err := Summarize(ctx, c.model, state.Summary, state.Pending)
+	if err != nil {
+		logger.Error("Failed to summarize conversation", zap.String("sessionId", id), zap.Error(err))
+		return
+	}
+
+	lock := sessionLock(id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	latest := c.state(ctx, id)
+	if latest.Summary != state.Summary {
+		return // another fold got there first; its summary already covers these turns
+	}
+	folded := len(state.Pending) - overlap(state.Pending, latest.Pending)
+	latest.Summary = summary
+	latest.Pending = latest.Pending[min(folded, len(latest.Pending)):]
+	latest.SummarizedOn = time.Now().Unix()
+	if _, err := async.Await(c.summaries.Save(ctx, latest)); err != nil {
+		logger.Error("Failed to save conversation summary", zap.String("sessionId", id), zap.Error(err))
+	}
+}
+
+func (c *Collection) state(ctx context.Context, id string) db.ConversationSummaryModel {
+	state, err := async.Await(c.summaries.FindOneByID(ctx, id))
+	if err != nil || state == nil {
+		return db.ConversationSummaryModel{SessionID: id, Pending: []llm.Message{}}
+	}
+	state.SessionID = id
+	return *state
+}
+
+// Recap is the message handed to the agent ahead of a session's remaining turns, or ""
+// when nothing has been trimmed yet.
+func Recap(summary string, pending []llm.Message) string {
+	summary = strings.TrimSpace(summary)
+	if summary == "" && len(pending) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(recapHeader)
+	if summary != "" {
+		sb.WriteString("\n\n")
+		sb.WriteString(summary)
+	}
+	if len(pending) > 0 {
+		if summary != "" {
+			sb.WriteString("\n\nLater turns, not yet summarized:")
+		}
+		sb.WriteString("\n\n")
+		sb.WriteString(transcript(pending))
+	}
+	return sb.String()
+}
+
+// Summarize folds trimmed turns into the previous summary with the given model.
+func Summarize(ctx context.Context, model llm.LLMClient, previous string, turns []llm.Message) (string, error) {
+	var prompt strings.Builder
+	if previous = strings.TrimSpace(previous); previous != "" {
+		prompt.WriteString("Existing summary:\n")
+		prompt.WriteString(previous)
+		prompt.WriteString("\n\n")
+	}
+	prompt.WriteString("Earlier turns:\n")
+	prompt.WriteString(transcript(turns))
+	prompt.WriteString("\n\nWrite the updated summary.")
+
+	var summary strings.Builder
+	err := model.GenerateInference(ctx,
+		[]llm.Message{{Role: "user", Content: prompt.String()}},
+		func(chunk string) error {
+			summary.WriteString(chunk)
+			return nil
+		},
+		llmrouter.WithSystemPrompt(summarySystemPrompt),
+		llmrouter.WithTemperature(0),
+	)
+	if err != nil {
+		return "", err
+	}
+	return truncate(strings.TrimSpace(summary.String()), MaxSummaryLength), nil
+}
+
+// Trimmed returns the messages of previous that are missing from the front of current:
+// the turns agent-boot trimmed when it saved the session.
+func Trimmed(previous, current []llm.Message) []llm.Message {
+	return previous[:overlap(previous, current)]
+}
+
+// overlap returns the smallest k for which previous[k:] is a prefix of current.
+func overlap(previous, current []llm.Message) int {
+	for k := range previous {
+		rest := previous[k:]
+		if len(rest) <= len(current) && slices.Equal(rest, current[:len(rest)]) {
+			return k
+		}
+	}
+	return len(previous)
+}
+
+func transcript(messages []llm.Message) string {
+	lines := make([]string, 0, len(messages))
+	for _, message := range messages {
+		speaker := "User"
+		if message.Role == "assistant" {
+			speaker = "Assistant"
+		}
+		lines = append(lines, speaker+": "+truncate(strings.TrimSpace(message.Content), maxMessageLength))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func truncate(s string, length int) string {
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+	return string(runes[:length]) + "…"
+}
+
+// Summaries are read, extended and written back; saves and folds of the same session
+// take the same lock so neither overwrites the other's pending turns.
+var sessionLocks [64]sync.Mutex
+
+func sessionLock(id string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return &sessionLocks[h.Sum32()%uint32(len(sessionLocks))]
+}
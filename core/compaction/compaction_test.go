@@ -0,0 +1,104 @@
+package compaction
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/agent-boot/memory"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type summarizingModel struct {
+	llm.LLMClient
+	replies []string
+	prompts []string
+}
+
+func (m *summarizingModel) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	m.prompts = append(m.prompts, messages[len(messages)-1].Content)
+	reply := m.replies[0]
+	m.replies = m.replies[1:]
+	return callback(reply)
+}
+
+func TestTrimmed(t *testing.T) {
+	a := llm.Message{Role: "user", Content: "a"}
+	b := llm.Message{Role: "assistant", Content: "b"}
+	c := llm.Message{Role: "user", Content: "c"}
+	d := llm.Message{Role: "assistant", Content: "d"}
+
+	assert.Empty(t, Trimmed(nil, []llm.Message{a, b}))
+	assert.Empty(t, Trimmed([]llm.Message{a, b}, []llm.Message{a, b, c, d}))
+	assert.Equal(t, []llm.Message{a, b}, Trimmed([]llm.Message{a, b, c, d}, []llm.Message{c, d, a, b}))
+	assert.Equal(t, []llm.Message{a, b}, Trimmed([]llm.Message{a, b}, []llm.Message{c, d}))
+}
+
+func TestCollection(t *testing.T) {
+	ctx := t.Context()
+
+	seeded := memory.Conversation{ID: "s1"}
+	for i := 1; i <= 5; i++ {
+		seeded.AddUserMessage(fmt.Sprintf("question %d", i))
+		seeded.AddToolResult(fmt.Sprintf("tool result %d", i))
+		seeded.AddAssistantMessage(fmt.Sprintf("answer %d", i))
+	}
+	conversations := odmtest.NewCollection(seeded)
+	summaries := odmtest.NewCollection[db.ConversationSummaryModel]()
+	model := &summarizingModel{replies: []string{"Patient asked about question 1.", "Patient asked about questions 1 and 2."}}
+
+	repo := NewCollection(conversations, summaries, model)
+	manager := memory.NewConversationManager(repo, 5)
+
+	conversation := manager.LoadSession(ctx, "s1")
+	require.Len(t, conversation.Messages, 15, "no recap before anything is trimmed")
+	conversation.AddUserMessage("question 6")
+	conversation.AddAssistantMessage("answer 6")
+	require.NoError(t, manager.SaveSession(ctx, conversation))
+	repo.Wait()
+
+	require.Len(t, model.prompts, 1)
+	assert.Contains(t, model.prompts[0], "User: question 1\nAssistant: answer 1")
+	assert.NotContains(t, model.prompts[0], "tool result")
+	state, err := async.Await(summaries.FindOneByID(ctx, "s1"))
+	require.NoError(t, err)
+	assert.Equal(t, "Patient asked about question 1.", state.Summary)
+	assert.Empty(t, state.Pending)
+
+	// a fresh request sees the summary ahead of the kept turns
+	repo = NewCollection(conversations, summaries, model)
+	manager = memory.NewConversationManager(repo, 5)
+	conversation = manager.LoadSession(ctx, "s1")
+	require.Len(t, conversation.Messages, 15)
+	assert.True(t, conversation.Messages[0].IsToolResult)
+	assert.Contains(t, conversation.Messages[0].Content, "Patient asked about question 1.")
+	assert.Equal(t, "question 2", conversation.Messages[1].Content)
+
+	conversation.AddUserMessage("question 7")
+	conversation.AddAssistantMessage("answer 7")
+	require.NoError(t, manager.SaveSession(ctx, conversation))
+	repo.Wait()
+
+	stored, err := async.Await(conversations.FindOneByID(ctx, "s1"))
+	require.NoError(t, err)
+	assert.Equal(t, "question 3", stored.Messages[0].Content, "the recap is never stored")
+
+	require.Len(t, model.prompts, 2)
+	assert.Contains(t, model.prompts[1], "Existing summary:\nPatient asked about question 1.")
+	assert.Contains(t, model.prompts[1], "User: question 2")
+	state, err = async.Await(summaries.FindOneByID(ctx, "s1"))
+	require.NoError(t, err)
+	assert.Equal(t, "Patient asked about questions 1 and 2.", state.Summary)
+}
+
+func TestRecap(t *testing.T) {
+	assert.Empty(t, Recap(" ", nil))
+
+	recap := Recap("Fever since Monday.", []llm.Message{{Role: "user", Content: "Is Belladonna suitable?"}})
+	assert.Contains(t, recap, "Fever since Monday.\n\nLater turns, not yet summarized:\n\nUser: Is Belladonna suitable?")
+}
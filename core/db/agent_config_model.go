@@ -0,0 +1,65 @@
+package db
+
+const AgentConfigID = "agent"
+
+const (
+	DefaultAgentSystemPrompt = "You are an assistant for Qualified Homeopathic Physicians. You are provided with medicine-rag tool to query medical knowledge database. Use ONLY INFORMATION from medicine-rag to answer the User Query."
+	DefaultAgentMaxTurns     = 5
+)
+
+// AgentConfigModel describes how the answering agent is assembled for a tenant.
+// Model fields name entries of the model registry (see llmrouter.Registry).
+// There is a single document per tenant database; missing fields fall back to defaults.
+type AgentConfigModel struct {
+	ID                string             `bson:"_id"`
+	MiniModel         string             `bson:"miniModel,omitempty"`
+	BigModel          string             `bson:"bigModel,omitempty"`
+	ToolSelectorModel string             `bson:"toolSelectorModel,omitempty"`
+	SystemPrompt      string             `bson:"systemPrompt,omitempty"`
+	MaxTurns          int                `bson:"maxTurns,omitempty"`
+	Tools             []string           `bson:"tools,omitempty"`
+	CitationMode      string             `bson:"citationMode,omitempty"`  // off, flag or drop; see guardrails.CitationMode
+	StopSequences     []string           `bson:"stopSequences,omitempty"` // the answer is cut at the first of these
+	ContentRules      []ContentRuleModel `bson:"contentRules,omitempty"`
+
+	// Regulatory disclaimers added to answers, chosen by the answer's category and the
+	// tenant config's jurisdiction; see guardrails.Disclaimer.
+	Disclaimers []DisclaimerModel `bson:"disclaimers,omitempty"`
+
+	// Answers scoring below this confidence are replaced by an abstention listing the
+	// closest sources. Zero uses the deployment's abstention_threshold; a negative value
+	// disables abstention. See guardrails.AssessConfidence.
+	AbstentionThreshold float64 `bson:"abstentionThreshold,omitempty"`
+
+	// A tenant's own instructions, appended to SystemPrompt, which the tenant cannot
+	// change. {{name}} placeholders are filled from PromptVariables; see
+	// prompts.RenderPromptTemplate.
+	PromptTemplate  string            `bson:"promptTemplate,omitempty"`
+	PromptVariables map[string]string `bson:"promptVariables,omitempty"`
+
+	UpdatedOn int64 `bson:"updatedOn,omitempty"`
+}
+
+func (m AgentConfigModel) Id() string { return AgentConfigID }
+
+func (m AgentConfigModel) CollectionName() string { return "agent_config" }
+
+// ContentRuleModel bans answer text matching Pattern, a case-insensitive regular
+// expression. Action is redact (the default) or regenerate; see guardrails.ContentRule.
+type ContentRuleModel struct {
+	Name        string `bson:"name"`
+	Pattern     string `bson:"pattern"`
+	Action      string `bson:"action,omitempty"`
+	Replacement string `bson:"replacement,omitempty"`
+}
+
+// DisclaimerModel is added to answers of Category, dosage or general, or to every
+// answer when empty. Jurisdictions limit it to tenants in one of them. Position is
+// append (the default) or prepend.
+type DisclaimerModel struct {
+	Name          string   `bson:"name"`
+	Text          string   `bson:"text"`
+	Category      string   `bson:"category,omitempty"`
+	Jurisdictions []string `bson:"jurisdictions,omitempty"`
+	Position      string   `bson:"position,omitempty"`
+}
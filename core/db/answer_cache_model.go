@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// AnswerCacheModel is a generated answer that can be served again for a near-identical
+// question. An entry only matches with the same agent config (ConfigHash) and model at
+// the same corpus version, for a user in the same access groups, and when the question
+// embeddings are close enough.
+type AnswerCacheModel struct {
+	ID                string      `bson:"_id"`
+	ConfigHash        string      `bson:"configHash"` // hash of the agent config and system prompt
+	Question          string      `bson:"question"`   // normalized
+	QuestionEmbedding bson.Vector `bson:"questionEmbedding"`
+	Model             string      `bson:"model"`
+	CorpusVersion     int64       `bson:"corpusVersion"`
+	AccessGroups      []string    `bson:"accessGroups,omitempty"` // of the user it was answered for
+	Answer            string      `bson:"answer"`
+	Hits              int64       `bson:"hits"`
+	CreatedOn         int64       `bson:"createdOn"`
+	ExpiresAt         time.Time   `bson:"expiresAt"` // removed by the TTL index
+}
+
+func (m AnswerCacheModel) Id() string { return m.ID }
+
+func (m AnswerCacheModel) CollectionName() string { return "answer_cache" }
+
+func (m AnswerCacheModel) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "configHash", Value: 1}, {Key: "model", Value: 1}, {Key: "corpusVersion", Value: 1}}},
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}
+}
+
+// InvalidateAnswerCache drops every cached answer of the tenant. Called whenever the
+// corpus changes, since cached answers may cite chunks that were replaced.
+func InvalidateAnswerCache(ctx context.Context, client odm.MongoClient, tenant string) error {
+	_, err := client.Database(tenant).Collection(AnswerCacheModel{}.CollectionName()).DeleteMany(ctx, bson.M{})
+	return err
+}
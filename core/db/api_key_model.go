@@ -0,0 +1,44 @@
+package db
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ApiKeyModel is a tenant's API key. The key itself is never stored, only a hash of its
+// secret; see apikeys.Generate.
+type ApiKeyModel struct {
+	KeyID             string   `bson:"_id"`
+	Name              string   `bson:"name"`
+	SecretHash        string   `bson:"secretHash"`
+	Scopes            []string `bson:"scopes"`
+	RequestsPerMinute int32    `bson:"requestsPerMinute"`
+	CreatedBy         string   `bson:"createdBy"`
+	CreatedOn         int64    `bson:"createdOn"`
+	RotatedOn         int64    `bson:"rotatedOn,omitempty"`
+	RevokedOn         int64    `bson:"revokedOn,omitempty"`
+	LastUsedOn        int64    `bson:"lastUsedOn,omitempty"`
+}
+
+func (m ApiKeyModel) Id() string { return m.KeyID }
+
+func (m ApiKeyModel) CollectionName() string { return "api_keys" }
+
+// ApiKeyUsageModel counts one key's requests on one UTC day.
+type ApiKeyUsageModel struct {
+	ID       string `bson:"_id"` // keyId:day
+	KeyID    string `bson:"keyId"`
+	Day      string `bson:"day"` // 2006-01-02
+	Requests int64  `bson:"requests"`
+	Rejected int64  `bson:"rejected"` // over the rate limit or outside the key's scopes
+}
+
+func (m ApiKeyUsageModel) Id() string { return m.ID }
+
+func (m ApiKeyUsageModel) CollectionName() string { return "api_key_usage" }
+
+func (m ApiKeyUsageModel) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "keyId", Value: 1}, {Key: "day", Value: -1}}},
+	}
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Audited actions.
+const (
+	AuditDocumentDeleted = "document.deleted"
+)
+
+// AuditEventModel records an admin action on the tenant's data: who took it, on what,
+// and what it changed. Events are only ever added.
+type AuditEventModel struct {
+	EventID   string            `bson:"_id"`
+	Action    string            `bson:"action"`
+	UserID    string            `bson:"userId"`
+	Target    string            `bson:"target"` // such as the source URI of a deleted document
+	Details   map[string]string `bson:"details,omitempty"`
+	CreatedOn int64             `bson:"createdOn"`
+}
+
+func (m AuditEventModel) Id() string { return m.EventID }
+
+func (m AuditEventModel) CollectionName() string { return "audit_events" }
+
+func (m AuditEventModel) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "createdOn", Value: -1}}},
+		{Keys: bson.D{{Key: "target", Value: 1}, {Key: "createdOn", Value: -1}}},
+	}
+}
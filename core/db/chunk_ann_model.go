@@ -1,34 +1,75 @@
 package db
 
 import (
+	"context"
+
 	"github.com/SaiNageswarS/go-api-boot/odm"
 	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
 const VectorIndexName = "chunkEmbeddingIndex"
 const VectorPath = "embedding"
 
-const EmbeddingDimensions = 2048 // jina ai 4
-
 type ChunkAnnModel struct {
 	ChunkID   string      `json:"chunkId" bson:"_id"` // Unique
 	Embedding bson.Vector `json:"-" bson:"embedding"` // Embedding vector for the chunk, not serialized in JSON
+
+	// Embedding model and vector size, so vectors of different models never share the
+	// tenant's index. Empty on vectors stored before they were recorded, which are all
+	// jina-embeddings-v4's 2048.
+	Model      string `json:"model,omitempty" bson:"model,omitempty"`
+	Dimensions int    `json:"dimensions,omitempty" bson:"dimensions,omitempty"`
+
+	// Set while the vector's chunk is retired; see LiveVectorsFilter.
+	Retired bool `json:"retired,omitempty" bson:"retired,omitempty"`
 }
 
 func (m ChunkAnnModel) Id() string { return m.ChunkID }
 
 func (m ChunkAnnModel) CollectionName() string { return "chunk_ann_index" }
 
-// Indexes
-func (m ChunkAnnModel) VectorIndexSpecs() []odm.VectorIndexSpec {
-	return []odm.VectorIndexSpec{
-		{
-			Name:          VectorIndexName,
-			Path:          VectorPath,
-			Type:          "vector",
-			NumDimensions: EmbeddingDimensions,
-			Similarity:    "cosine",
-			Quantization:  "scalar",
-		},
+// VectorIndexSpec is the vector index for the tenant's embedding model. Its size is
+// fixed when the index is created, so it isn't one of the model's static indexes.
+func (m ChunkAnnModel) VectorIndexSpec(dimensions int) odm.VectorIndexSpec {
+	return odm.VectorIndexSpec{
+		Name:          VectorIndexName,
+		Path:          VectorPath,
+		Type:          "vector",
+		NumDimensions: dimensions,
+		Similarity:    "cosine",
+		Quantization:  "scalar",
+	}
+}
+
+// VectorIndexModel is the vector index of VectorIndexSpec, which also indexes the
+// retired flag for LiveVectorsFilter.
+func (m ChunkAnnModel) VectorIndexModel(dimensions int) mongo.SearchIndexModel {
+	spec := m.VectorIndexSpec(dimensions)
+	model := spec.Model()
+	model.Definition = bson.M{"fields": bson.A{spec, bson.M{"type": "filter", "path": "retired"}}}
+	return model
+}
+
+// ResizeVectorIndex redefines the tenant's vector index for vectors of dimensions, or
+// creates it when the tenant has none. The index is rebuilt in the background, so a
+// change of size is only for a tenant without vectors, whose model is changing.
+func ResizeVectorIndex(ctx context.Context, mongo odm.MongoClient, tenant string, dimensions int) error {
+	model := ChunkAnnModel{}.VectorIndexModel(dimensions)
+	indexes := mongo.Database(tenant).Collection(ChunkAnnModel{}.CollectionName()).SearchIndexes()
+
+	cursor, err := indexes.List(ctx, options.SearchIndexes().SetName(VectorIndexName))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	if cursor.Next(ctx) {
+		return indexes.UpdateOne(ctx, VectorIndexName, model.Definition)
+	}
+	if err := cursor.Err(); err != nil {
+		return err
 	}
+	_, err = indexes.CreateOne(ctx, model)
+	return err
 }
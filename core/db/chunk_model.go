@@ -1,7 +1,11 @@
 package db
 
 import (
+	"slices"
+	"strings"
+
 	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 const TextSearchIndexName = "chunkIndex"
@@ -9,19 +13,70 @@ const TextSearchIndexName = "chunkIndex"
 var TextSearchPaths = []string{"sentences", "sectionPath", "tags", "title"}
 
 type ChunkModel struct {
-	ChunkID      string            `json:"chunkId" bson:"_id"`
-	Title        string            `json:"title" bson:"title"` // Title of the document, e.g., "Introduction to AI"
-	SectionPath  string            `json:"sectionPath" bson:"sectionPath"`
-	SectionIndex int               `json:"sectionIndex" bson:"sectionIndex"` // Index of the section in the path
-	SourceURI    string            `json:"sourceUri" bson:"sourceUri"`       // e.g., "file://path/to/file.pdf"
-	Tags         []string          `json:"tags" bson:"tags"`                 // Tags associated with the chunk
-	Abbrevations map[string]string `json:"abbrevations" bson:"abbrevations"` // Abbreviations used in the chunk
-	Sentences    []string          `json:"sentences" bson:"sentences"`       // Sentences in the chunk, used for text search
-	PrevChunkID  string            `json:"prevChunkId" bson:"prevChunkId"`   // ID of the previous chunk in the sequence
-	NextChunkID  string            `json:"nextChunkId" bson:"nextChunkId"`
-	SectionID    string            `bson:"sectionId" json:"sectionId"`     // stable hash for the *section* (same for all windows of that section)
-	WindowIndex  int               `bson:"windowIndex" json:"windowIndex"` // 0-based window order *within* section
-	IsAnchor     bool              `bson:"-" json:"-"`
+	ChunkID         string            `json:"chunkId" bson:"_id"`
+	Title           string            `json:"title" bson:"title"` // Title of the document, e.g., "Introduction to AI"
+	SectionPath     string            `json:"sectionPath" bson:"sectionPath"`
+	SectionIndex    int               `json:"sectionIndex" bson:"sectionIndex"`                           // Index of the section in the path
+	SourceURI       string            `json:"sourceUri" bson:"sourceUri"`                                 // e.g., "file://path/to/file.pdf"
+	Book            string            `json:"book,omitempty" bson:"book,omitempty"`                       // Source book, from the markdown front matter
+	Author          string            `json:"author,omitempty" bson:"author,omitempty"`                   // Author of the source book
+	PublicationYear int               `json:"publicationYear,omitempty" bson:"publicationYear,omitempty"` // Year the source book was published
+	Chapter         string            `json:"chapter,omitempty" bson:"chapter,omitempty"`                 // Top heading the chunk's section is under
+	Section         string            `json:"section,omitempty" bson:"section,omitempty"`                 // Deepest heading of the section, when below the chapter
+	PageStart       int               `json:"pageStart,omitempty" bson:"pageStart,omitempty"`             // First page of the source PDF the chunk is on
+	PageEnd         int               `json:"pageEnd,omitempty" bson:"pageEnd,omitempty"`                 // Last page of the source PDF the chunk is on
+	OcrConfidence   float64           `json:"ocrConfidence,omitempty" bson:"ocrConfidence,omitempty"`     // Lowest OCR confidence of the scanned pages the chunk is on
+	NeedsReview     bool              `json:"needsReview,omitempty" bson:"needsReview,omitempty"`         // Read from a page OCR was unsure of
+	Tags            []string          `json:"tags" bson:"tags"`                                           // Tags associated with the chunk
+	Abbrevations    map[string]string `json:"abbrevations" bson:"abbrevations"`                           // Abbreviations used in the chunk
+	Entities        []string          `json:"entities,omitempty" bson:"entities,omitempty"`               // Remedies, rubrics and body systems the chunk is about, e.g. "remedy:aconitum napellus"
+	Language        string            `json:"language,omitempty" bson:"language,omitempty"`               // Base language of the chunk's text, e.g. "de"; empty when it can't be told
+	Quality         float64           `json:"quality,omitempty" bson:"quality,omitempty"`                 // Text quality from 0 to 1, as scored at ingest; see ingest.ScoreChunks
+	QualityIssues   []string          `json:"qualityIssues,omitempty" bson:"qualityIssues,omitempty"`     // What lowered the quality, e.g. "ocr_garbage"
+	Sentences       []string          `json:"sentences" bson:"sentences"`                                 // Sentences in the chunk, used for text search
+	Paragraphs      []int             `json:"paragraphs,omitempty" bson:"paragraphs,omitempty"`           // Paragraph of each sentence within the section
+	Links           []ChunkLink       `json:"links,omitempty" bson:"links,omitempty"`                     // Sections of the same source the chunk's section refers to or shares a chapter with
+	Figures         []FigureRef       `json:"figures,omitempty" bson:"figures,omitempty"`                 // Figures the chunk captions or refers to
+	Chunking        *ChunkingParams   `json:"chunking,omitempty" bson:"chunking,omitempty"`               // How the chunk was cut, to reproduce it
+	AccessGroups    []string          `json:"accessGroups,omitempty" bson:"accessGroups,omitempty"`       // Groups whose users may retrieve the chunk; empty for the whole tenant
+	PrevChunkID     string            `json:"prevChunkId" bson:"prevChunkId"`                             // ID of the previous chunk in the sequence
+	NextChunkID     string            `json:"nextChunkId" bson:"nextChunkId"`
+	SectionID       string            `bson:"sectionId" json:"sectionId"`               // stable hash for the *section* (same for all windows of that section)
+	WindowIndex     int               `bson:"windowIndex" json:"windowIndex"`           // 0-based window order *within* section
+	CorpusVersion   int64             `bson:"corpusVersion" json:"corpusVersion"`       // corpus version that first added this chunk
+	RetiredVersion  int64             `bson:"retiredVersion" json:"retiredVersion"`     // corpus version that replaced it; 0 while live
+	Lapses          []ChunkLapse      `bson:"lapses,omitempty" json:"lapses,omitempty"` // spans it was retired for before a later version added it back
+	IsAnchor        bool              `bson:"-" json:"-"`
+}
+
+// ChunkLapse is a span of corpus versions a chunk was retired for, from the version that
+// replaced it to the one that added it back; see ChunksAtVersionFilter.
+type ChunkLapse struct {
+	RetiredVersion  int64 `json:"retiredVersion" bson:"retiredVersion"`
+	RestoredVersion int64 `json:"restoredVersion" bson:"restoredVersion"`
+}
+
+// ChunkingParams are the strategy and sizes a chunk was cut with; see ingest.Chunker.
+type ChunkingParams struct {
+	DocumentType  string `json:"documentType" bson:"documentType"` // "narrative", "repertory" or "case-journal"
+	Strategy      string `json:"strategy" bson:"strategy"`         // "fixed", "heading" or "semantic"
+	MaxTokens     int    `json:"maxTokens" bson:"maxTokens"`
+	OverlapTokens int    `json:"overlapTokens" bson:"overlapTokens"`
+}
+
+// ChunkLink points from a chunk's section to a related section of the same source.
+type ChunkLink struct {
+	SectionID string `json:"sectionId" bson:"sectionId"`
+	Kind      string `json:"kind" bson:"kind"`                     // "reference", "compare" or "chapter"; see package references
+	Text      string `json:"text,omitempty" bson:"text,omitempty"` // the mention that made the link, e.g. "Compare Gels."
+}
+
+// FigureRef points from a chunk to a figure its text captions or refers to; see
+// FigureModel.
+type FigureRef struct {
+	FigureID string `json:"figureId" bson:"figureId"`
+	Label    string `json:"label" bson:"label"` // e.g. "Fig. 3", as the caption numbers the figure
+	Caption  string `json:"caption" bson:"caption"`
 }
 
 func (m ChunkModel) Id() string { return m.ChunkID }
@@ -37,3 +92,27 @@ func (m ChunkModel) TermSearchIndexSpecs() []odm.TermSearchIndexSpec {
 		},
 	}
 }
+
+// AccessFilter matches the chunks and table rows a user in groups may read: those of
+// documents without access groups, which the whole tenant may, and those of documents
+// sharing one of groups.
+func AccessFilter(groups []string) bson.M {
+	untagged := bson.M{"accessGroups": bson.M{"$exists": false}}
+	if len(groups) == 0 {
+		return untagged
+	}
+	return bson.M{"$or": bson.A{untagged, bson.M{"accessGroups": bson.M{"$in": groups}}}}
+}
+
+// NormalizeAccessGroups lowercases and trims group names, and sorts them without blanks
+// or repeats, as they are stored and compared.
+func NormalizeAccessGroups(groups []string) []string {
+	var normalized []string
+	for _, group := range groups {
+		if group = strings.ToLower(strings.TrimSpace(group)); group != "" && !slices.Contains(normalized, group) {
+			normalized = append(normalized, group)
+		}
+	}
+	slices.Sort(normalized)
+	return normalized
+}
@@ -0,0 +1,30 @@
+package db
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ContentViolationModel records an answer that matched one of the tenant's banned-content
+// rules, as the model generated it, so editors can review what was regenerated or redacted.
+type ContentViolationModel struct {
+	ViolationID string `bson:"_id"`
+	SessionID   string `bson:"sessionId,omitempty"`
+	UserID      string `bson:"userId"`
+	Rule        string `bson:"rule"`
+	Match       string `bson:"match"`
+	Action      string `bson:"action"` // redact or regenerate
+	Question    string `bson:"question,omitempty"`
+	CreatedOn   int64  `bson:"createdOn"`
+}
+
+func (m ContentViolationModel) Id() string { return m.ViolationID }
+
+func (m ContentViolationModel) CollectionName() string { return "content_violations" }
+
+func (m ContentViolationModel) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "createdOn", Value: -1}}},
+		{Keys: bson.D{{Key: "rule", Value: 1}, {Key: "createdOn", Value: -1}}},
+	}
+}
@@ -0,0 +1,44 @@
+package db
+
+import "github.com/SaiNageswarS/agent-boot/llm"
+
+// ConversationModel is medicine-rag's view over agent-boot's "conversations" collection.
+// agent-boot only $sets _id and messages on save, so the extra fields owned here survive
+// its writes. Every field is omitempty so that partial saves from this side never clobber
+// the messages written by the agent.
+type ConversationModel struct {
+	SessionID string        `bson:"_id"`
+	UserID    string        `bson:"userId,omitempty"`
+	Messages  []llm.Message `bson:"messages,omitempty"`
+	CreatedOn int64         `bson:"createdOn,omitempty"`
+	UpdatedOn int64         `bson:"updatedOn,omitempty"`
+
+	// Named after the first question and answer for the history list; see titles.Generate.
+	Title string `bson:"title,omitempty"`
+
+	// One entry per answer, appended with $push so saves from either side never drop entries.
+	AnswerProvenance []AnswerProvenance `bson:"answerProvenance,omitempty"`
+
+	// Patient details the physician attached to the session; see prompts.CaseContextPrompt.
+	CaseContext *CaseContext `bson:"caseContext,omitempty"`
+}
+
+// CaseContext describes the patient a consultation is about. Zero values are unset.
+type CaseContext struct {
+	AgeYears        int32    `bson:"ageYears,omitempty"`
+	Sex             string   `bson:"sex,omitempty"` // female, male or other
+	ChiefComplaints []string `bson:"chiefComplaints,omitempty"`
+	History         string   `bson:"history,omitempty"`
+	Medications     []string `bson:"medications,omitempty"`
+	UpdatedOn       int64    `bson:"updatedOn,omitempty"`
+}
+
+// AnswerProvenance ties an answer to the corpus version it was retrieved from.
+type AnswerProvenance struct {
+	CorpusVersion int64 `bson:"corpusVersion"`
+	AnsweredOn    int64 `bson:"answeredOn"`
+}
+
+func (m ConversationModel) Id() string { return m.SessionID }
+
+func (m ConversationModel) CollectionName() string { return "conversations" }
@@ -0,0 +1,20 @@
+package db
+
+import "github.com/SaiNageswarS/agent-boot/llm"
+
+// ConversationSummaryModel is the rolling summary kept on a conversation document for the
+// turns trimmed from its messages. Neither field is omitempty: both are written together,
+// so saving an empty pending list clears it.
+type ConversationSummaryModel struct {
+	SessionID string `bson:"_id"`
+	Summary   string `bson:"summary"`
+
+	// Trimmed turns not yet folded into Summary.
+	Pending []llm.Message `bson:"pendingSummary"`
+
+	SummarizedOn int64 `bson:"summarizedOn,omitempty"`
+}
+
+func (m ConversationSummaryModel) Id() string { return m.SessionID }
+
+func (m ConversationSummaryModel) CollectionName() string { return "conversations" }
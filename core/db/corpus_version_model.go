@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const corpusVersionCounterID = "corpusVersion"
+
+/*
+CorpusVersionModel records one change to the searchable corpus.
+
+Every ingestion that saves chunks allocates the next version N. Chunks added by it
+carry corpusVersion = N and chunks it replaced get retiredVersion = N, so the corpus
+as it stood at any version can be rebuilt from the chunks collection alone.
+Chunks saved before versioning have no corpusVersion and count as version 0.
+
+Deleting a source document also allocates a version, recorded with Deleted set and
+the chunks that were live as retired. The document's chunks are removed outright, so
+earlier versions no longer list them.
+
+Importing a corpus archive allocates a version too, after every version the archive
+has, recorded without a source and with the live chunks imported as added.
+*/
+type CorpusVersionModel struct {
+	ID            string `bson:"_id"`
+	Version       int64  `bson:"version"`
+	SourceURI     string `bson:"sourceUri"`
+	AddedChunks   int    `bson:"addedChunks"`
+	RetiredChunks int    `bson:"retiredChunks"`
+	Deleted       bool   `bson:"deleted,omitempty"` // the source was deleted
+	CreatedOn     int64  `bson:"createdOn"`
+}
+
+func NewCorpusVersionModel(version int64) *CorpusVersionModel {
+	return &CorpusVersionModel{
+		ID:      strconv.FormatInt(version, 10),
+		Version: version,
+	}
+}
+
+func (m CorpusVersionModel) Id() string { return m.ID }
+
+func (m CorpusVersionModel) CollectionName() string { return "corpus_versions" }
+
+func (m CorpusVersionModel) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "version", Value: -1}}},
+	}
+}
+
+// NextCorpusVersion atomically allocates the next corpus version for the tenant.
+func NextCorpusVersion(ctx context.Context, client odm.MongoClient, tenant string) (int64, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+
+	err := client.Database(tenant).Collection("counters").FindOneAndUpdate(ctx,
+		bson.M{"_id": corpusVersionCounterID},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+
+	return counter.Seq, err
+}
+
+// RaiseCorpusVersion makes the tenant's next corpus version come after version, as
+// when chunks of that version are imported.
+func RaiseCorpusVersion(ctx context.Context, client odm.MongoClient, tenant string, version int64) error {
+	_, err := client.Database(tenant).Collection("counters").UpdateOne(ctx,
+		bson.M{"_id": corpusVersionCounterID},
+		bson.M{"$max": bson.M{"seq": version}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	return err
+}
+
+// CurrentCorpusVersion returns the latest published version, or 0 if none has been recorded.
+func CurrentCorpusVersion(ctx context.Context, client odm.MongoClient, tenant string) (int64, error) {
+	var latest CorpusVersionModel
+	err := client.Database(tenant).Collection(latest.CollectionName()).FindOne(ctx,
+		bson.M{},
+		options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}}),
+	).Decode(&latest)
+
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	return latest.Version, err
+}
+
+// LiveChunksFilter matches chunks that have not been retired.
+func LiveChunksFilter() bson.M {
+	return bson.M{"retiredVersion": bson.M{"$not": bson.M{"$gt": 0}}}
+}
+
+// LiveVectorsFilter matches the vectors of chunks that have not been retired. Vector
+// search filters on it before ranking, so retired chunks never take a hit's place.
+func LiveVectorsFilter() bson.M {
+	return bson.M{"retired": bson.M{"$ne": true}}
+}
+
+// ChunksAtVersionFilter matches chunks that were searchable at the given corpus version.
+func ChunksAtVersionFilter(version int64) bson.M {
+	return bson.M{"$and": bson.A{
+		bson.M{"$or": bson.A{
+			bson.M{"corpusVersion": bson.M{"$lte": version}},
+			bson.M{"corpusVersion": bson.M{"$exists": false}},
+		}},
+		bson.M{"$or": bson.A{
+			LiveChunksFilter(),
+			bson.M{"retiredVersion": bson.M{"$gt": version}},
+		}},
+		bson.M{"lapses": bson.M{"$not": bson.M{"$elemMatch": bson.M{
+			"retiredVersion":  bson.M{"$lte": version},
+			"restoredVersion": bson.M{"$gt": version},
+		}}}},
+	}}
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ExecutionTraceModel is the audit record of one Execute call: every model call and
+// tool call the agent made, in the order they started. Its id is the run id that the
+// completion metadata carries as traceId.
+type ExecutionTraceModel struct {
+	TraceID       string      `bson:"_id"`
+	SessionID     string      `bson:"sessionId,omitempty"`
+	UserID        string      `bson:"userId"`
+	Question      string      `bson:"question"`
+	Model         string      `bson:"model,omitempty"`
+	CorpusVersion int64       `bson:"corpusVersion,omitempty"`
+	Outcome       string      `bson:"outcome"` // completed, failed, terminated or cached
+	Answer        string      `bson:"answer,omitempty"`
+	Error         string      `bson:"error,omitempty"`
+	Steps         []TraceStep `bson:"steps"`
+	// Set when steps were dropped or texts shortened to keep the document within limits.
+	Truncated        bool  `bson:"truncated,omitempty"`
+	LatencyMs        int64 `bson:"latencyMs"`
+	PromptTokens     int64 `bson:"promptTokens"`
+	CompletionTokens int64 `bson:"completionTokens"`
+	StartedOn        int64 `bson:"startedOn"`
+	CreatedOn        int64 `bson:"createdOn"`
+}
+
+// TraceStep is one model call (kind "llm") or tool call (kind "tool").
+type TraceStep struct {
+	Kind string `bson:"kind"`
+	// The model's role in the agent (answer, mini or toolSelector), or the tool's name.
+	Name       string `bson:"name"`
+	Model      string `bson:"model,omitempty"`
+	StartedMs  int64  `bson:"startedMs"` // since the run started
+	DurationMs int64  `bson:"durationMs"`
+	Error      string `bson:"error,omitempty"`
+
+	// model calls
+	SystemPrompt     string          `bson:"systemPrompt,omitempty"`
+	Messages         []TraceMessage  `bson:"messages,omitempty"`
+	OfferedTools     []string        `bson:"offeredTools,omitempty"`
+	Response         string          `bson:"response,omitempty"`
+	ToolCalls        []TraceToolCall `bson:"toolCalls,omitempty"`
+	PromptTokens     int64           `bson:"promptTokens,omitempty"`
+	CompletionTokens int64           `bson:"completionTokens,omitempty"`
+	TokensEstimated  bool            `bson:"tokensEstimated,omitempty"`
+
+	// tool calls
+	Arguments string        `bson:"arguments,omitempty"` // JSON
+	Results   []TraceResult `bson:"results,omitempty"`
+}
+
+type TraceMessage struct {
+	Role    string `bson:"role"`
+	Content string `bson:"content"`
+}
+
+type TraceToolCall struct {
+	Name      string `bson:"name"`
+	Arguments string `bson:"arguments"` // JSON
+}
+
+type TraceResult struct {
+	Title       string `bson:"title,omitempty"`
+	Attribution string `bson:"attribution,omitempty"`
+	Content     string `bson:"content"`
+	Error       string `bson:"error,omitempty"`
+}
+
+func (m ExecutionTraceModel) Id() string { return m.TraceID }
+
+func (m ExecutionTraceModel) CollectionName() string { return "execution_traces" }
+
+func (m ExecutionTraceModel) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "sessionId", Value: 1}, {Key: "createdOn", Value: -1}}},
+		{Keys: bson.D{{Key: "createdOn", Value: -1}}},
+	}
+}
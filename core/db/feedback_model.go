@@ -0,0 +1,41 @@
+package db
+
+import (
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// FeedbackModel is a user's rating of a single answer. It is keyed by session and message,
+// so re-rating the same answer replaces the earlier feedback instead of adding a new row.
+type FeedbackModel struct {
+	FeedbackID string `bson:"_id"`
+	SessionID  string `bson:"sessionId"`
+	MessageID  string `bson:"messageId"`
+	UserID     string `bson:"userId"`
+	Rating     string `bson:"rating"` // "up" or "down"
+	Comment    string `bson:"comment,omitempty"`
+	Question   string `bson:"question,omitempty"`
+	Answer     string `bson:"answer,omitempty"`
+	CreatedOn  int64  `bson:"createdOn"`
+}
+
+func NewFeedbackModel(sessionId, messageId string) *FeedbackModel {
+	feedbackId, _ := odm.HashedKey(sessionId, messageId)
+	return &FeedbackModel{
+		FeedbackID: feedbackId,
+		SessionID:  sessionId,
+		MessageID:  messageId,
+	}
+}
+
+func (m FeedbackModel) Id() string { return m.FeedbackID }
+
+func (m FeedbackModel) CollectionName() string { return "feedback" }
+
+func (m FeedbackModel) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "sessionId", Value: 1}}},
+		{Keys: bson.D{{Key: "rating", Value: 1}, {Key: "createdOn", Value: -1}}},
+	}
+}
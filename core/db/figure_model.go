@@ -0,0 +1,40 @@
+package db
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// FigureModel is a captioned figure of an ingested document, such as an anatomical
+// plate or a diagram. Its caption is chunked as text with the rest of the document,
+// and the chunks that caption or refer to the figure link to it; see FigureRef. Its
+// image, when one could be cut out of the page, is kept in the tenant's storage bucket.
+type FigureModel struct {
+	FigureID  string `json:"figureId" bson:"_id"`
+	SourceURI string `json:"sourceUri" bson:"sourceUri"`
+	Label     string `json:"label" bson:"label"` // e.g. "Fig. 3", as the caption numbers the figure
+	Caption   string `json:"caption" bson:"caption"`
+	Page      int    `json:"page,omitempty" bson:"page,omitempty"` // of the source PDF
+	// Path of the PNG image in the tenant's bucket; empty for a figure without one.
+	ImagePath string `json:"imagePath,omitempty" bson:"imagePath,omitempty"`
+
+	SectionID string `json:"sectionId" bson:"sectionId"`
+	ChunkID   string `json:"chunkId" bson:"chunkId"` // the window the caption is in
+	Title     string `json:"title" bson:"title"`     // of the section
+	Book      string `json:"book,omitempty" bson:"book,omitempty"`
+	Author    string `json:"author,omitempty" bson:"author,omitempty"`
+
+	// Groups whose users may see the figure, as for the document's chunks.
+	AccessGroups []string `json:"accessGroups,omitempty" bson:"accessGroups,omitempty"`
+}
+
+func (m FigureModel) Id() string { return m.FigureID }
+
+func (m FigureModel) CollectionName() string { return "figures" }
+
+// Indexes
+func (m FigureModel) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "sourceUri", Value: 1}}},
+	}
+}
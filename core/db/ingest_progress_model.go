@@ -0,0 +1,59 @@
+package db
+
+import (
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Stages of a document's ingestion, in order.
+const (
+	IngestStageChunked  = "chunked"  // its chunks are published
+	IngestStageEmbedded = "embedded" // every chunk has a vector
+)
+
+// IngestProgressModel records how far bulk ingestion got with one source document, so
+// an interrupted run picks up where it stopped. A document whose content changed since
+// is ingested again from the start.
+type IngestProgressModel struct {
+	ID        string `bson:"_id"` // hash of the source URI
+	SourceURI string `bson:"sourceUri"`
+	Checksum  string `bson:"checksum"`       // SHA-256 of the document's bytes
+	ETag      string `bson:"etag,omitempty"` // object store version, once fully ingested
+	Stage     string `bson:"stage"`
+	Chunks    int    `bson:"chunks"`
+	Embedded  int    `bson:"embedded"`        // chunks with a vector, saved after each batch
+	Error     string `bson:"error,omitempty"` // why the last run failed on it
+	UpdatedOn int64  `bson:"updatedOn"`
+
+	// Scanned pages whose OCR confidence is low enough for someone to check them.
+	FlaggedPages []int `bson:"flaggedPages,omitempty"`
+
+	// SimHash of the document's text, and its bands, to find copies of it in other
+	// formats; see ingest.Fingerprint.
+	Fingerprint      string   `bson:"fingerprint,omitempty"`
+	FingerprintBands []string `bson:"fingerprintBands,omitempty"`
+	// The source this document is a copy of. It has no chunks of its own; the
+	// original's stand for it.
+	DuplicateOf string `bson:"duplicateOf,omitempty"`
+
+	// Access groups the document's chunks were published for; see AccessFilter.
+	AccessGroups []string `bson:"accessGroups,omitempty"`
+}
+
+func NewIngestProgressModel(sourceUri string) *IngestProgressModel {
+	id, _ := odm.HashedKey(sourceUri)
+	return &IngestProgressModel{ID: id, SourceURI: sourceUri}
+}
+
+func (m IngestProgressModel) Id() string { return m.ID }
+
+func (m IngestProgressModel) CollectionName() string { return "ingest_progress" }
+
+func (m IngestProgressModel) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "checksum", Value: 1}}},
+		{Keys: bson.D{{Key: "fingerprintBands", Value: 1}}},
+		{Keys: bson.D{{Key: "duplicateOf", Value: 1}}},
+	}
+}
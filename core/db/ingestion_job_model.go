@@ -0,0 +1,60 @@
+package db
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Statuses of an ingestion job. A job moves through them in order until it is done,
+// or stops at failed with the error it failed on.
+const (
+	IngestionJobQueued    = "queued"
+	IngestionJobParsing   = "parsing"   // converting the document to markdown
+	IngestionJobChunking  = "chunking"  // splitting it into windows
+	IngestionJobIndexing  = "indexing"  // publishing its chunks as a corpus version
+	IngestionJobEmbedding = "embedding" // computing the vectors of its new chunks
+	IngestionJobDone      = "done"
+	IngestionJobFailed    = "failed"
+)
+
+// IngestionJobModel tracks one document submitted through the Ingestion API.
+type IngestionJobModel struct {
+	JobID       string `bson:"_id"`
+	SourceURI   string `bson:"sourceUri"`
+	FileName    string `bson:"fileName"`
+	StoragePath string `bson:"storagePath"` // in the tenant's bucket
+	// Chunks the document as this type; empty detects it. See ingest.DocumentType.
+	DocumentType string `bson:"documentType,omitempty"`
+	Status       string `bson:"status"`
+	Error        string `bson:"error,omitempty"`
+	Chunks       int    `bson:"chunks"`   // chunks published
+	Embedded     int    `bson:"embedded"` // chunk vectors saved
+	// Low-quality chunks held out of search for review; see QuarantinedChunkModel.
+	Quarantined int `bson:"quarantined,omitempty"`
+	// Patient identifiers taken out before chunking; see ingest.Deidentifier.
+	Redacted int `bson:"redacted,omitempty"`
+	// Scanned pages whose OCR confidence is low enough for someone to check them.
+	FlaggedPages []int  `bson:"flaggedPages,omitempty"`
+	CreatedBy    string `bson:"createdBy"`
+	CreatedOn    int64  `bson:"createdOn"`
+	UpdatedOn    int64  `bson:"updatedOn"`
+
+	// Ingests the document even when it copies one from another source.
+	AllowDuplicate bool `bson:"allowDuplicate,omitempty"`
+	// The source the document was found to copy, whose chunks stand for it; the job is
+	// done without chunks of its own.
+	DuplicateOf string `bson:"duplicateOf,omitempty"`
+	// Only users in one of these groups may retrieve the document; empty is everyone.
+	AccessGroups []string `bson:"accessGroups,omitempty"`
+}
+
+func (m IngestionJobModel) Id() string { return m.JobID }
+
+func (m IngestionJobModel) CollectionName() string { return "ingestion_jobs" }
+
+func (m IngestionJobModel) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "createdOn", Value: -1}}},
+		{Keys: bson.D{{Key: "createdOn", Value: -1}}},
+	}
+}
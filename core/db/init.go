@@ -4,9 +4,14 @@ import (
 	"context"
 
 	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
-func InitSearchCoreDB(ctx context.Context, mongo odm.MongoClient, tenant string) error {
+// InitSearchCoreDB creates the tenant's collections and indexes. The vector index holds
+// vectorDimensions-sized vectors, those of the tenant's embedding model. Run on an
+// existing tenant, it redefines the vector index with the fields searches filter on,
+// and flags the vectors of retired chunks, which were stored before they were flagged.
+func InitSearchCoreDB(ctx context.Context, mongo odm.MongoClient, tenant string, vectorDimensions int) error {
 	err := odm.EnsureIndexes[LoginModel](ctx, mongo, tenant)
 	if err != nil {
 		return err
@@ -22,10 +27,126 @@ func InitSearchCoreDB(ctx context.Context, mongo odm.MongoClient, tenant string)
 		return err
 	}
 
+	err = ResizeVectorIndex(ctx, mongo, tenant, vectorDimensions)
+	if err != nil {
+		return err
+	}
+
+	err = flagRetiredVectors(ctx, mongo, tenant)
+	if err != nil {
+		return err
+	}
+
 	err = odm.EnsureIndexes[AgentModel](ctx, mongo, tenant)
 	if err != nil {
 		return err
 	}
 
+	err = odm.EnsureIndexes[FeedbackModel](ctx, mongo, tenant)
+	if err != nil {
+		return err
+	}
+
+	err = odm.EnsureIndexes[PortalPageModel](ctx, mongo, tenant)
+	if err != nil {
+		return err
+	}
+
+	err = odm.EnsureIndexes[CorpusVersionModel](ctx, mongo, tenant)
+	if err != nil {
+		return err
+	}
+
+	err = odm.EnsureIndexes[IngestProgressModel](ctx, mongo, tenant)
+	if err != nil {
+		return err
+	}
+
+	err = odm.EnsureIndexes[UsageModel](ctx, mongo, tenant)
+	if err != nil {
+		return err
+	}
+
+	err = odm.EnsureIndexes[AnswerCacheModel](ctx, mongo, tenant)
+	if err != nil {
+		return err
+	}
+
+	err = odm.EnsureIndexes[QueryEmbeddingModel](ctx, mongo, tenant)
+	if err != nil {
+		return err
+	}
+
+	err = odm.EnsureIndexes[RubricModel](ctx, mongo, tenant)
+	if err != nil {
+		return err
+	}
+
+	err = odm.EnsureIndexes[ContentViolationModel](ctx, mongo, tenant)
+	if err != nil {
+		return err
+	}
+
+	err = odm.EnsureIndexes[InteractionModel](ctx, mongo, tenant)
+	if err != nil {
+		return err
+	}
+
+	err = odm.EnsureIndexes[ApiKeyUsageModel](ctx, mongo, tenant)
+	if err != nil {
+		return err
+	}
+
+	err = odm.EnsureIndexes[ExecutionTraceModel](ctx, mongo, tenant)
+	if err != nil {
+		return err
+	}
+
+	err = odm.EnsureIndexes[IngestionJobModel](ctx, mongo, tenant)
+	if err != nil {
+		return err
+	}
+
+	err = odm.EnsureIndexes[TableRowModel](ctx, mongo, tenant)
+	if err != nil {
+		return err
+	}
+
+	err = odm.EnsureIndexes[FigureModel](ctx, mongo, tenant)
+	if err != nil {
+		return err
+	}
+
+	err = odm.EnsureIndexes[AuditEventModel](ctx, mongo, tenant)
+	if err != nil {
+		return err
+	}
+
+	err = odm.EnsureIndexes[SyncRunModel](ctx, mongo, tenant)
+	if err != nil {
+		return err
+	}
+
+	err = odm.EnsureIndexes[QuarantinedChunkModel](ctx, mongo, tenant)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// flagRetiredVectors sets the retired flag on the vectors of the tenant's retired chunks.
+func flagRetiredVectors(ctx context.Context, mongo odm.MongoClient, tenant string) error {
+	var retired []string
+	err := mongo.Database(tenant).Collection(ChunkModel{}.CollectionName()).
+		Distinct(ctx, "_id", bson.M{"retiredVersion": bson.M{"$gt": 0}}).Decode(&retired)
+	if err != nil || len(retired) == 0 {
+		return err
+	}
+
+	_, err = mongo.Database(tenant).Collection(ChunkAnnModel{}.CollectionName()).UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": retired}},
+		bson.M{"$set": bson.M{"retired": true}},
+	)
+	return err
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Interaction kinds.
+const (
+	// InteractionAntidote: the first substance antidotes (cancels the action of) the second.
+	InteractionAntidote = "antidote"
+	// InteractionIncompatible: the two should not be given together or in sequence.
+	InteractionIncompatible = "incompatible"
+	// InteractionCaution: they may be combined with care, e.g. with spacing between doses.
+	InteractionCaution = "caution"
+)
+
+// InteractionModel is a known interaction between two substances, each a remedy or a
+// conventional drug. For antidotes the order matters: Substances[0] antidotes
+// Substances[1].
+type InteractionModel struct {
+	InteractionID string                  `bson:"_id"`
+	Kind          string                  `bson:"kind"`
+	Substances    [2]InteractionSubstance `bson:"substances"`
+	Note          string                  `bson:"note,omitempty"`   // e.g. "Strong coffee antidotes the action of Nux vomica."
+	Source        string                  `bson:"source,omitempty"` // reference the interaction was taken from
+	Keys          []string                `bson:"keys"`             // InteractionKey of every name and alias, for lookup
+}
+
+type InteractionSubstance struct {
+	Name    string   `bson:"name"`
+	Type    string   `bson:"type"` // remedy or drug
+	Aliases []string `bson:"aliases,omitempty"`
+}
+
+// NewInteractionModel derives the ID and lookup keys, so loading the same interaction
+// twice updates it instead of adding a duplicate.
+func NewInteractionModel(kind string, first, second InteractionSubstance) *InteractionModel {
+	model := &InteractionModel{Kind: kind, Substances: [2]InteractionSubstance{first, second}}
+
+	names := []string{InteractionKey(first.Name), InteractionKey(second.Name)}
+	if kind != InteractionAntidote {
+		sort.Strings(names) // symmetric kinds are the same record either way round
+	}
+	model.InteractionID, _ = odm.HashedKey(append([]string{kind}, names...)...)
+
+	for _, substance := range model.Substances {
+		model.Keys = append(model.Keys, substance.Keys()...)
+	}
+	return model
+}
+
+// Keys are the lookup keys of the substance's name and aliases.
+func (s InteractionSubstance) Keys() []string {
+	keys := []string{InteractionKey(s.Name)}
+	for _, alias := range s.Aliases {
+		keys = append(keys, InteractionKey(alias))
+	}
+	return keys
+}
+
+// InteractionKey normalizes a substance name for lookup: "Nux-Vomica " becomes
+// "nux vomica".
+func InteractionKey(name string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+func (m InteractionModel) Id() string { return m.InteractionID }
+
+func (m InteractionModel) CollectionName() string { return "interactions" }
+
+func (m InteractionModel) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "keys", Value: 1}}},
+	}
+}
@@ -7,8 +7,20 @@ type LoginModel struct {
 	EmailId        string `bson:"email"`
 	HashedPassword string `bson:"password"`
 	CreatedOn      int64  `bson:"createdOn"`
+
+	// Narrows the tenant's allowed models for this user; empty inherits the tenant's list.
+	AllowedModels []string `bson:"allowedModels,omitempty"`
+
+	// RoleAdmin lets the user manage the tenant's API keys. Set by operators.
+	Role string `bson:"role,omitempty"`
+
+	// Lets the user retrieve documents ingested for these access groups, besides the
+	// tenant's untagged ones. Set by operators.
+	AccessGroups []string `bson:"accessGroups,omitempty"`
 }
 
+const RoleAdmin = "admin"
+
 func NewLoginModel(emailId string) *LoginModel {
 	userId, _ := odm.HashedKey(emailId)
 	return &LoginModel{
@@ -0,0 +1,15 @@
+package db
+
+// NotificationPreferencesModel is a user's choice of how they are told about each kind of
+// event. Events missing from Channels use the defaults in package notifications.
+type NotificationPreferencesModel struct {
+	UserID string `bson:"_id"`
+	// Event name to the channels it is delivered on; an empty list turns the event off.
+	Channels        map[string][]string `bson:"channels,omitempty"`
+	DigestFrequency string              `bson:"digestFrequency,omitempty"` // off, daily or weekly
+	UpdatedOn       int64               `bson:"updatedOn,omitempty"`
+}
+
+func (m NotificationPreferencesModel) Id() string { return m.UserID }
+
+func (m NotificationPreferencesModel) CollectionName() string { return "notification_preferences" }
@@ -0,0 +1,28 @@
+package db
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// PortalPageModel is a curated remedy page published on the tenant's public portal.
+// Body is markdown; pages stay hidden until Published is set.
+type PortalPageModel struct {
+	Slug      string   `bson:"_id"`
+	Title     string   `bson:"title"`
+	Summary   string   `bson:"summary"`
+	Body      string   `bson:"body"`
+	Tags      []string `bson:"tags,omitempty"`
+	Published bool     `bson:"published"`
+	UpdatedOn int64    `bson:"updatedOn"`
+}
+
+func (m PortalPageModel) Id() string { return m.Slug }
+
+func (m PortalPageModel) CollectionName() string { return "portal_pages" }
+
+func (m PortalPageModel) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "published", Value: 1}, {Key: "title", Value: 1}}},
+	}
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Review statuses of a quarantined chunk.
+const (
+	QuarantinePending  = "pending"
+	QuarantineApproved = "approved" // published despite its quality
+	QuarantineRejected = "rejected" // kept out of search, also when its document is ingested again
+)
+
+// QuarantinedChunkModel holds a chunk whose quality scored below its tenant's minimum at
+// ingest. The chunk is kept out of search until a tenant admin approves it. It keeps its
+// chunk ID, which only changes with its section's text, so the review holds when the
+// document is ingested again.
+type QuarantinedChunkModel struct {
+	ChunkID    string     `bson:"_id"`
+	SourceURI  string     `bson:"sourceUri"`
+	Chunk      ChunkModel `bson:"chunk"` // with its Quality and QualityIssues
+	Status     string     `bson:"status"`
+	ReviewedBy string     `bson:"reviewedBy,omitempty"`
+	ReviewedOn int64      `bson:"reviewedOn,omitempty"`
+	CreatedOn  int64      `bson:"createdOn"`
+}
+
+func (m QuarantinedChunkModel) Id() string { return m.ChunkID }
+
+func (m QuarantinedChunkModel) CollectionName() string { return "quarantined_chunks" }
+
+func (m QuarantinedChunkModel) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "createdOn", Value: -1}}},
+		{Keys: bson.D{{Key: "sourceUri", Value: 1}}},
+	}
+}
@@ -0,0 +1,30 @@
+package db
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// QueryEmbeddingModel is a search query's embedding, kept so a repeated query isn't sent
+// to the embedding API again. It is keyed by the embedding model and the normalized
+// query, since vectors of different models aren't interchangeable.
+type QueryEmbeddingModel struct {
+	ID        string      `bson:"_id"` // hash of model and query
+	Model     string      `bson:"model"`
+	Query     string      `bson:"query"` // normalized
+	Embedding bson.Vector `bson:"embedding"`
+	ExpiresAt time.Time   `bson:"expiresAt"` // removed by the TTL index
+}
+
+func (m QueryEmbeddingModel) Id() string { return m.ID }
+
+func (m QueryEmbeddingModel) CollectionName() string { return "query_embeddings" }
+
+func (m QueryEmbeddingModel) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "expiresAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	}
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const RepertorySearchIndexName = "rubricIndex"
+
+var RepertorySearchPaths = []string{"rubric", "synonyms"}
+
+// RubricModel is one rubric of a repertory, as repertory.Import saves it: a symptom,
+// written as the path of headings leading to it ("Mind; Fear; sudden"), with the
+// remedies listed under it.
+type RubricModel struct {
+	RubricID   string         `json:"rubricId" bson:"_id"`
+	Repertory  string         `json:"repertory" bson:"repertory"`                   // e.g. "Kent"
+	Chapter    string         `json:"chapter" bson:"chapter"`                       // e.g. "Mind"
+	Rubric     string         `json:"rubric" bson:"rubric"`                         // full path, e.g. "Mind; Fear; sudden"
+	Synonyms   []string       `json:"synonyms,omitempty" bson:"synonyms,omitempty"` // plain-language phrasings of the symptom
+	Remedies   []RubricRemedy `json:"remedies" bson:"remedies"`                     // highest grade first, once imported
+	ImportedOn int64          `json:"importedOn,omitempty" bson:"importedOn,omitempty"`
+}
+
+type RubricRemedy struct {
+	Name         string `json:"name" bson:"name"`                                     // e.g. "Aconitum napellus"
+	Abbreviation string `json:"abbreviation,omitempty" bson:"abbreviation,omitempty"` // e.g. "Acon."
+	Grade        int    `json:"grade" bson:"grade"`                                   // 1 (plain) to 3 (bold)
+}
+
+func (m RubricModel) Id() string { return m.RubricID }
+
+func (m RubricModel) CollectionName() string { return "repertory" }
+
+// Indexes
+func (m RubricModel) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "repertory", Value: 1}, {Key: "chapter", Value: 1}}},
+		// the rubrics listing a remedy, strongest first
+		{Keys: bson.D{{Key: "remedies.name", Value: 1}, {Key: "remedies.grade", Value: -1}}},
+	}
+}
+
+func (m RubricModel) TermSearchIndexSpecs() []odm.TermSearchIndexSpec {
+	return []odm.TermSearchIndexSpec{
+		{
+			Name:  RepertorySearchIndexName,
+			Paths: RepertorySearchPaths,
+		},
+	}
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// How a sync run was started.
+const (
+	SyncTriggerSchedule = "schedule"
+	SyncTriggerManual   = "manual"
+)
+
+// Statuses of a sync run.
+const (
+	SyncRunRunning = "running"
+	SyncRunDone    = "done"
+	SyncRunFailed  = "failed" // the source could not be listed; see Error
+)
+
+// SyncRunModel records one re-scan of a tenant's configured source, which ingests the
+// documents that are new or changed since the last. Scheduled runs get the same id on
+// every instance, so only the instance that saves the run first performs it.
+type SyncRunModel struct {
+	RunID        string `bson:"_id"`
+	Source       string `bson:"source"` // name in the tenant's config
+	Kind         string `bson:"kind"`
+	Trigger      string `bson:"trigger"`
+	ScheduledFor int64  `bson:"scheduledFor,omitempty"`
+	Status       string `bson:"status"`
+	Error        string `bson:"error,omitempty"`
+	StartedOn    int64  `bson:"startedOn"`
+	FinishedOn   int64  `bson:"finishedOn,omitempty"`
+
+	// as in ingest.Report
+	Ingested    int `bson:"ingested"`
+	Resumed     int `bson:"resumed"`
+	Unchanged   int `bson:"unchanged"`
+	Duplicates  int `bson:"duplicates"`
+	Unsupported int `bson:"unsupported"`
+	Failed      int `bson:"failed"` // documents that failed; the run still finished
+	Chunks      int `bson:"chunks"`
+	Embedded    int `bson:"embedded"`
+	Quarantined int `bson:"quarantined,omitempty"`
+	Redacted    int `bson:"redacted,omitempty"`
+}
+
+func (m SyncRunModel) Id() string { return m.RunID }
+
+func (m SyncRunModel) CollectionName() string { return "sync_runs" }
+
+func (m SyncRunModel) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "startedOn", Value: -1}}},
+		{Keys: bson.D{{Key: "source", Value: 1}, {Key: "startedOn", Value: -1}}},
+	}
+}
@@ -0,0 +1,20 @@
+package db
+
+// SynonymModel is a group of interchangeable search terms, e.g. a remedy's Latin name,
+// its common name and its repertory abbreviations: "Arsenicum album", "Arsenic",
+// "Ars.". A tenant's groups extend the built-in dictionary that search queries are
+// expanded with.
+type SynonymModel struct {
+	SynonymID string   `json:"synonymId" bson:"_id"`
+	Terms     []string `json:"terms" bson:"terms"`
+}
+
+// SynonymKey normalizes a term for lookup the way substance names are: "Nat-m." becomes
+// "nat m".
+func SynonymKey(term string) string {
+	return InteractionKey(term)
+}
+
+func (m SynonymModel) Id() string { return m.SynonymID }
+
+func (m SynonymModel) CollectionName() string { return "synonyms" }
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const TableSearchIndexName = "tableRowIndex"
+
+var TableSearchPaths = []string{"text", "title", "sectionPath"}
+
+// TableRowModel is one data row of a table in an ingested document, such as a dosage
+// table or a repertory page, kept with its column headers so it can be looked up and
+// returned whole rather than as the flattened sentence chunks hold.
+type TableRowModel struct {
+	RowID    string   `json:"rowId" bson:"_id"` // {tableId}_{rowIndex}
+	TableID  string   `json:"tableId" bson:"tableId"`
+	RowIndex int      `json:"rowIndex" bson:"rowIndex"` // among the table's data rows, from 0
+	Header   []string `json:"header" bson:"header"`
+	Cells    []string `json:"cells" bson:"cells"`
+	Text     string   `json:"text" bson:"text"` // the row as chunked: "Remedy: Aconite; Potency: 30C"
+
+	SourceURI   string `json:"sourceUri" bson:"sourceUri"`
+	SectionID   string `json:"sectionId" bson:"sectionId"`
+	ChunkID     string `json:"chunkId" bson:"chunkId"` // the first window the row is in
+	SectionPath string `json:"sectionPath" bson:"sectionPath"`
+	Title       string `json:"title" bson:"title"` // of the section
+	Book        string `json:"book,omitempty" bson:"book,omitempty"`
+	Author      string `json:"author,omitempty" bson:"author,omitempty"`
+
+	// Groups whose users may look the row up, as for the document's chunks.
+	AccessGroups []string `json:"accessGroups,omitempty" bson:"accessGroups,omitempty"`
+}
+
+func (m TableRowModel) Id() string { return m.RowID }
+
+func (m TableRowModel) CollectionName() string { return "table_rows" }
+
+// Indexes
+func (m TableRowModel) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "sourceUri", Value: 1}}},
+		{Keys: bson.D{{Key: "tableId", Value: 1}, {Key: "rowIndex", Value: 1}}},
+	}
+}
+
+func (m TableRowModel) TermSearchIndexSpecs() []odm.TermSearchIndexSpec {
+	return []odm.TermSearchIndexSpec{
+		{
+			Name:  TableSearchIndexName,
+			Paths: TableSearchPaths,
+		},
+	}
+}
@@ -0,0 +1,235 @@
+package db
+
+const TenantConfigID = "tenant"
+
+// TenantConfigModel holds tenant-level feature switches.
+// There is a single document per tenant database.
+type TenantConfigModel struct {
+	ID                string `bson:"_id"`
+	ShareLinksEnabled bool   `bson:"shareLinksEnabled"`
+
+	// Public portal. Only chunks carrying one of PublicTags are searchable from the portal;
+	// an empty list exposes no corpus content at all.
+	PublicPortalEnabled bool     `bson:"publicPortalEnabled"`
+	PublicPortalTitle   string   `bson:"publicPortalTitle,omitempty"`
+	PublicTags          []string `bson:"publicTags,omitempty"`
+
+	// Offline tenants answer with local models only and never send query text to hosted
+	// APIs, so search runs without query embeddings.
+	OfflineMode bool `bson:"offlineMode"`
+
+	// Model names this tenant may request; empty allows every registered model.
+	AllowedModels []string `bson:"allowedModels,omitempty"`
+
+	// Tokens this tenant may use per calendar month across all models. Zero uses the
+	// deployment's monthly_token_quota; a negative value means unlimited.
+	MonthlyTokenQuota int64 `bson:"monthlyTokenQuota,omitempty"`
+
+	// Per-execution limits. Zero uses the deployment's max_iterations,
+	// execution_timeout_seconds and execution_token_budget; a negative budget means
+	// unlimited.
+	MaxIterations           int   `bson:"maxIterations,omitempty"`
+	ExecutionTimeoutSeconds int   `bson:"executionTimeoutSeconds,omitempty"`
+	ExecutionTokenBudget    int64 `bson:"executionTokenBudget,omitempty"`
+
+	// Weights of text and vector rankings in hybrid search; see mcp.FusionWeights. Zero
+	// uses the deployment's text_search_weight and vector_search_weight; a negative
+	// value leaves that engine out.
+	TextSearchWeight   float64 `bson:"textSearchWeight,omitempty"`
+	VectorSearchWeight float64 `bson:"vectorSearchWeight,omitempty"`
+
+	// How much a search ranks and sends; see mcp.SearchLimits. Zero uses the deployment's
+	// search_top_k, search_depth, max_search_depth and min_search_score; a negative top-K
+	// or minimum score sends all sections ranked.
+	SearchTopK     int     `bson:"searchTopK,omitempty"`
+	SearchDepth    int     `bson:"searchDepth,omitempty"`
+	MaxSearchDepth int     `bson:"maxSearchDepth,omitempty"`
+	MinSearchScore float64 `bson:"minSearchScore,omitempty"`
+
+	// Boosts to the ranking of chunks from newer editions and from the preferred documents,
+	// by source URI, and authors; see mcp.SourceBoost. A zero boost uses the deployment's
+	// recency_boost or preferred_source_boost; a negative one turns it off.
+	RecencyBoost         float64  `bson:"recencyBoost,omitempty"`
+	PreferredSourceBoost float64  `bson:"preferredSourceBoost,omitempty"`
+	PreferredDocuments   []string `bson:"preferredDocuments,omitempty"`
+	PreferredAuthors     []string `bson:"preferredAuthors,omitempty"`
+
+	// Registry name of the embedding model for this tenant's vectors; empty uses the
+	// deployment's default_embedder. Vectors are only comparable to those of the same
+	// model, so changing it means deleting the tenant's vectors and embedding them again.
+	Embedder string `bson:"embedder,omitempty"`
+
+	// How ingestion chunks this tenant's documents; see ingest.NewChunker.
+	Chunking ChunkingConfig `bson:"chunking,omitempty"`
+
+	// Chunks scoring below this quality at ingest are quarantined for review instead of
+	// published; see ingest.ScoreChunks. Zero uses ingest.DefaultMinChunkQuality; a
+	// negative value quarantines nothing.
+	MinChunkQuality float64 `bson:"minChunkQuality,omitempty"`
+
+	// How patients' names, phone numbers, addresses and other identifiers are taken out
+	// of clinic case notes before they are chunked; see ingest.Deidentifier.
+	Deidentification DeidentificationConfig `bson:"deidentification,omitempty"`
+
+	// Buckets and folders of documents ingested into this tenant by name, and re-scanned
+	// on their schedules; see ingest.OpenSource and services.SourceScheduler.
+	Sources []SourceConfig `bson:"sources,omitempty"`
+
+	// Databases of shared knowledge packs, such as a centrally maintained classic materia
+	// medica corpus, searched alongside this tenant's own chunks; see
+	// mcp.WithKnowledgePacks.
+	KnowledgePacks []string `bson:"knowledgePacks,omitempty"`
+
+	// Sources this tenant's searches never return: documents by source URI, and authors
+	// by whole name ignoring case. Managed through the Admin API; see mcp.WithExclusions.
+	ExcludedDocuments   []string `bson:"excludedDocuments,omitempty"`
+	ExcludedAuthors     []string `bson:"excludedAuthors,omitempty"`
+	ExclusionsUpdatedOn int64    `bson:"exclusionsUpdatedOn,omitempty"`
+
+	// Also searches the vector index with the embedding of a passage the mini model
+	// writes to answer each query; see mcp.WithHyDE.
+	HyDE bool `bson:"hyde,omitempty"`
+
+	// Splits multi-part questions into sub-queries the mini model writes, and searches
+	// each alongside the whole query; see mcp.WithDecomposition.
+	Decomposition bool `bson:"decomposition,omitempty"`
+
+	// Sends the figures each search result captions or mentions with it, so the chat
+	// can show them; see mcp.WithFigures.
+	FigureReferences bool `bson:"figureReferences,omitempty"`
+
+	// Answers a search from lexical hits alone when they are convincing, skipping the
+	// query embedding and vector search; see mcp.WithProgressiveRetrieval.
+	ProgressiveRetrieval bool `bson:"progressiveRetrieval,omitempty"`
+
+	// Sends the agent the top lexical sections before vector search finishes, so their
+	// summary overlaps with it; see mcp.WithSpeculativeResults.
+	SpeculativeResults bool `bson:"speculativeResults,omitempty"`
+
+	// Locale (BCP 47, e.g. "de-DE") and IANA time zone used to format dates, doses and
+	// numbers in exports such as shared transcripts. Empty means en-US and UTC.
+	Locale   string `bson:"locale,omitempty"`
+	TimeZone string `bson:"timeZone,omitempty"`
+
+	// Regulatory jurisdiction, e.g. "US", "IN" or "EU", selecting which of the agent
+	// config's disclaimers apply. Empty applies only those without jurisdictions.
+	Jurisdiction string `bson:"jurisdiction,omitempty"`
+
+	// Stops chat clients from being told when newly indexed documents become searchable.
+	DisableCorpusUpdateNotifications bool `bson:"disableCorpusUpdateNotifications"`
+
+	// Webhooks and message queues told when ingestion jobs start, finish or fail and when
+	// documents are indexed, so external systems can act on new knowledge; see
+	// events.Dispatcher.
+	EventSinks []EventSinkConfig `bson:"eventSinks,omitempty"`
+}
+
+// ChunkingConfig picks the chunking strategy for each document type and sets the
+// chunk sizes. Anything left empty uses the defaults.
+type ChunkingConfig struct {
+	Strategies    map[string]string `bson:"strategies,omitempty"` // by document type, e.g. {"case-journal": "semantic"}
+	MaxTokens     int               `bson:"maxTokens,omitempty"`  // most estimated tokens in a chunk
+	OverlapTokens int               `bson:"overlapTokens,omitempty"`
+}
+
+// Documents a tenant's de-identification applies to.
+const (
+	DeidentifyCaseJournals = "case-journal" // documents chunked as case journals; the default
+	DeidentifyAll          = "all"
+	DeidentifyOff          = "off"
+)
+
+// Redaction policies for a kind of identifier.
+const (
+	RedactionMask      = "redact"       // replaced by its kind, e.g. [NAME]; the default
+	RedactionPseudonym = "pseudonymize" // replaced by its kind and a number, the same for each mention in a document, e.g. [NAME-2]
+	RedactionKeep      = "keep"
+)
+
+// DeidentificationConfig says which documents are de-identified at ingest, and what
+// is done with each kind of identifier found in them.
+type DeidentificationConfig struct {
+	Documents string `bson:"documents,omitempty"` // case-journal, all or off
+	// Policies by kind of identifier: name, phone, email, address, id, or the kind of a
+	// rule. Kinds left out are redacted.
+	Policies map[string]string `bson:"policies,omitempty"`
+	// Words never taken for a name, such as the clinic's name or remedies the remedy
+	// dictionary lacks. Matched ignoring case.
+	AllowedNames []string `bson:"allowedNames,omitempty"`
+	// Further identifiers, such as the clinic's own file numbers.
+	Rules []DeidentificationRule `bson:"rules,omitempty"`
+}
+
+// DeidentificationRule finds identifiers matching Pattern, a case-insensitive regular
+// expression, as identifiers of Kind, "id" when empty.
+type DeidentificationRule struct {
+	Kind    string `bson:"kind,omitempty"`
+	Pattern string `bson:"pattern"`
+}
+
+// Kinds of document source.
+const (
+	SourceS3    = "s3"
+	SourceGCS   = "gcs"
+	SourceAzure = "azure" // Azure Blob Storage
+	SourceDir   = "dir"   // a directory on the ingesting host
+	SourceWeb   = "web"   // pages crawled under an allowlist of URLs
+)
+
+// SourceConfig points ingestion at a prefix of an object store bucket, a directory, or
+// the web pages under an allowlist of URLs.
+type SourceConfig struct {
+	Name   string `bson:"name"`
+	Kind   string `bson:"kind"`
+	Bucket string `bson:"bucket,omitempty"` // bucket, or container for azure
+	Prefix string `bson:"prefix,omitempty"`
+	Path   string `bson:"path,omitempty"` // directory, for dir
+
+	Region   string `bson:"region,omitempty"`   // s3; empty uses the AWS config's
+	Endpoint string `bson:"endpoint,omitempty"` // s3-compatible stores, such as MinIO
+	Account  string `bson:"account,omitempty"`  // azure storage account; empty uses the deployment's
+
+	URLs     []string `bson:"urls,omitempty"`     // web; crawled from, and the pages under them
+	MaxPages int      `bson:"maxPages,omitempty"` // web; URLs visited per sync, 200 when unset
+
+	// Cron schedule for re-scanning the source, e.g. "0 3 * * *", in UTC unless it
+	// starts with CRON_TZ=; empty only syncs it on demand.
+	Schedule string `bson:"schedule,omitempty"`
+
+	// Only users in one of these access groups may retrieve the source's documents;
+	// empty is everyone in the tenant.
+	AccessGroups []string `bson:"accessGroups,omitempty"`
+}
+
+// Kinds of event sink.
+const (
+	EventSinkWebhook = "webhook"
+	EventSinkPubSub  = "pubsub" // a Google Cloud Pub/Sub topic
+)
+
+// EventSinkConfig is where a tenant's ingestion events are sent.
+type EventSinkConfig struct {
+	Kind string `bson:"kind"`
+	URL  string `bson:"url,omitempty"` // webhook; events are POSTed to it as JSON
+	// webhook; when set, each body is signed with HMAC-SHA256 in the X-Webhook-Signature
+	// header, so the receiver can check it came from this deployment
+	Secret string `bson:"secret,omitempty"`
+	Topic  string `bson:"topic,omitempty"` // pubsub; projects/PROJECT/topics/TOPIC
+
+	// Event types sent, e.g. "document.indexed"; empty sends every type.
+	Events []string `bson:"events,omitempty"`
+}
+
+// Source returns the tenant's source configured as name.
+func (m TenantConfigModel) Source(name string) (SourceConfig, bool) {
+	for _, source := range m.Sources {
+		if source.Name == name {
+			return source, true
+		}
+	}
+	return SourceConfig{}, false
+}
+
+func (m TenantConfigModel) Id() string { return TenantConfigID }
+
+func (m TenantConfigModel) CollectionName() string { return "tenant_config" }
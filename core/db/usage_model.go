@@ -0,0 +1,54 @@
+package db
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// UsageModel is the token usage of one model during one agent execution.
+type UsageModel struct {
+	UsageID          string `bson:"_id"`
+	ExecutionID      string `bson:"executionId"`
+	SessionID        string `bson:"sessionId,omitempty"`
+	UserID           string `bson:"userId"`
+	Month            string `bson:"month"` // UsageMonth of CreatedOn
+	Provider         string `bson:"provider"`
+	Model            string `bson:"model"`
+	PromptTokens     int64  `bson:"promptTokens"`
+	CompletionTokens int64  `bson:"completionTokens"`
+	Estimated        bool   `bson:"estimated"` // counted from text length, not reported by the provider
+	CreatedOn        int64  `bson:"createdOn"`
+}
+
+func (m UsageModel) Id() string { return m.UsageID }
+
+func (m UsageModel) CollectionName() string { return "usage" }
+
+func (m UsageModel) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{Keys: bson.D{{Key: "month", Value: 1}, {Key: "createdOn", Value: -1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "month", Value: 1}}},
+	}
+}
+
+// UsageTotalModel is a tenant's running token total for one calendar month. Quota
+// checks read this single document instead of summing the usage records.
+type UsageTotalModel struct {
+	Month            string `bson:"_id"`
+	PromptTokens     int64  `bson:"promptTokens"`
+	CompletionTokens int64  `bson:"completionTokens"`
+	UpdatedOn        int64  `bson:"updatedOn"`
+}
+
+func (m UsageTotalModel) Id() string { return m.Month }
+
+func (m UsageTotalModel) CollectionName() string { return "usage_totals" }
+
+func (m UsageTotalModel) TotalTokens() int64 { return m.PromptTokens + m.CompletionTokens }
+
+// UsageMonth names the calendar month (UTC) that usage at t is billed to, e.g. "2025-07".
+func UsageMonth(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
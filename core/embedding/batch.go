@@ -0,0 +1,64 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+)
+
+// BatchEmbedder embeds many texts in one provider request. Bulk ingestion uses it
+// where the embedder has it, instead of a request per text.
+type BatchEmbedder interface {
+	GetEmbeddings(ctx context.Context, texts []string, opts ...embed.EmbedOption) ([][]float32, error)
+}
+
+// RateLimitError is returned when the provider rejected a request for its rate limit.
+// RetryAfter is how long the provider asked callers to wait.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("embedding rate limited, retry after %s", e.RetryAfter)
+}
+
+// IsRateLimited reports whether err is a provider's rate limit, and how long it asked
+// callers to wait.
+func IsRateLimited(err error) (time.Duration, bool) {
+	var rateLimited *RateLimitError
+	if errors.As(err, &rateLimited) {
+		return rateLimited.RetryAfter, true
+	}
+	return 0, false
+}
+
+// EmbedBatch embeds texts in one request when embedder is a BatchEmbedder, and
+// otherwise sends a request per text, all at once, and waits for every one.
+func EmbedBatch(ctx context.Context, embedder embed.Embedder, texts []string, opts ...embed.EmbedOption) ([][]float32, error) {
+	if batcher, ok := embedder.(BatchEmbedder); ok {
+		return batcher.GetEmbeddings(ctx, texts, opts...)
+	}
+
+	results := make([]<-chan async.Result[[]float32], len(texts))
+	for i, text := range texts {
+		results[i] = embedder.GetEmbedding(ctx, text, opts...)
+	}
+
+	var firstErr error
+	embeddings := make([][]float32, len(texts))
+	for i, result := range results {
+		embedding, err := async.Await(result)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		embeddings[i] = embedding
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return embeddings, nil
+}
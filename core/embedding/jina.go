@@ -0,0 +1,351 @@
+// Package embedding provides the embedding clients used by core.
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+const (
+	jinaEmbeddingsURL = "https://api.jina.ai/v1/embeddings"
+	jinaDefaultModel  = "jina-embeddings-v4"
+	jinaDefaultTask   = "retrieval.passage"
+
+	defaultMaxBatch    = 64
+	defaultBatchWindow = 20 * time.Millisecond
+	maxRateLimitRetry  = 5
+)
+
+// JinaClient is a Jina AI embedder that coalesces concurrent GetEmbedding calls into
+// batched requests and paces them under the account's request and token limits, so
+// large ingestions queue locally instead of triggering 429 storms.
+//
+// Batches are adaptive: a 429 halves the batch size, the rejected batch is retried in
+// batches of the new size, and successful batches grow it back toward the configured
+// maximum.
+type JinaClient struct {
+	apiKey     string
+	httpClient *http.Client
+	url        string
+
+	requests *rate.Limiter // nil when unlimited
+	tokens   *rate.Limiter
+
+	maxBatch    int
+	batchWindow time.Duration
+
+	mu      sync.Mutex
+	batch   int // current adaptive batch size
+	queues  map[batchKey][]*pending
+	flushes map[batchKey]*time.Timer
+}
+
+type batchKey struct {
+	model string
+	task  string
+}
+
+type pending struct {
+	ctx    context.Context
+	text   string
+	result chan async.Result[[]float32]
+}
+
+func NewJinaClient(apiKey string, ccfg *appconfig.AppConfig) *JinaClient {
+	c := &JinaClient{
+		apiKey:      apiKey,
+		httpClient:  &http.Client{Timeout: 60 * time.Second},
+		url:         jinaEmbeddingsURL,
+		maxBatch:    ccfg.EmbedMaxBatch,
+		batchWindow: time.Duration(ccfg.EmbedBatchWindowMs) * time.Millisecond,
+		queues:      make(map[batchKey][]*pending),
+		flushes:     make(map[batchKey]*time.Timer),
+	}
+	if c.maxBatch <= 0 {
+		c.maxBatch = defaultMaxBatch
+	}
+	if c.batchWindow <= 0 {
+		c.batchWindow = defaultBatchWindow
+	}
+	c.batch = c.maxBatch
+
+	if rpm := ccfg.EmbedRequestsPerMinute; rpm > 0 {
+		c.requests = rate.NewLimiter(rate.Limit(float64(rpm)/60), max(1, rpm/60))
+	}
+	if tpm := ccfg.EmbedTokensPerMinute; tpm > 0 {
+		// The burst must fit the largest single batch, or WaitN could never succeed.
+		c.tokens = rate.NewLimiter(rate.Limit(float64(tpm)/60), tpm)
+	}
+	return c
+}
+
+func (c *JinaClient) GetEmbedding(ctx context.Context, text string, opts ...embed.EmbedOption) <-chan async.Result[[]float32] {
+	var key batchKey
+	key.model, key.task = resolveOptions(opts, jinaDefaultModel, jinaDefaultTask)
+
+	p := &pending{ctx: ctx, text: text, result: make(chan async.Result[[]float32], 1)}
+	c.enqueue(key, p)
+	return p.result
+}
+
+// GetEmbeddings embeds texts in one request, bypassing the queue, under the same rate
+// limits and retries as queued batches.
+func (c *JinaClient) GetEmbeddings(ctx context.Context, texts []string, opts ...embed.EmbedOption) ([][]float32, error) {
+	var key batchKey
+	key.model, key.task = resolveOptions(opts, jinaDefaultModel, jinaDefaultTask)
+	return c.embedWithRetry(ctx, key, texts)
+}
+
+func (c *JinaClient) enqueue(key batchKey, p *pending) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.queues[key] = append(c.queues[key], p)
+	queueDepth.Inc()
+
+	if len(c.queues[key]) >= c.batch {
+		c.flushLocked(key)
+		return
+	}
+	if _, scheduled := c.flushes[key]; !scheduled {
+		c.flushes[key] = time.AfterFunc(c.batchWindow, func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.flushLocked(key)
+		})
+	}
+}
+
+// flushLocked hands the queued inputs for key to a sender, one batch at a time.
+func (c *JinaClient) flushLocked(key batchKey) {
+	if timer, ok := c.flushes[key]; ok {
+		timer.Stop()
+		delete(c.flushes, key)
+	}
+
+	queue := c.queues[key]
+	delete(c.queues, key)
+	for len(queue) > 0 {
+		n := min(len(queue), c.batch)
+		go c.send(key, queue[:n])
+		queue = queue[n:]
+	}
+}
+
+func (c *JinaClient) send(key batchKey, batch []*pending) {
+	queueDepth.Sub(float64(len(batch)))
+
+	// Callers that gave up while queued are dropped before paying for them.
+	live := batch[:0]
+	for _, p := range batch {
+		if err := p.ctx.Err(); err != nil {
+			p.result <- async.Result[[]float32]{Err: err}
+			continue
+		}
+		live = append(live, p)
+	}
+	if len(live) == 0 {
+		return
+	}
+
+	// The batch is cancelled only when every caller in it has gone away.
+	ctx, cancel := mergedContext(live)
+	defer cancel()
+
+	inputs := make([]string, len(live))
+	for i, p := range live {
+		inputs[i] = p.text
+	}
+	embeddings, err := c.embedWithRetry(ctx, key, inputs)
+	for i, p := range live {
+		if err != nil {
+			p.result <- async.Result[[]float32]{Err: err}
+			continue
+		}
+		p.result <- async.Result[[]float32]{Data: embeddings[i]}
+	}
+}
+
+// embedWithRetry returns a RateLimitError once the server keeps rate limiting it past
+// maxRateLimitRetry retries.
+func (c *JinaClient) embedWithRetry(ctx context.Context, key batchKey, inputs []string) ([][]float32, error) {
+	tokens := 0
+	for _, text := range inputs {
+		tokens += estimateTokens(text)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := c.wait(ctx, tokens); err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		embeddings, retryAfter, err := c.post(ctx, key, inputs)
+		requestSeconds.Observe(time.Since(start).Seconds())
+
+		switch {
+		case err == nil:
+			requestsTotal.WithLabelValues("ok").Inc()
+			batchSize.Observe(float64(len(inputs)))
+			c.adapt(true)
+			return embeddings, nil
+
+		case retryAfter > 0 && attempt < maxRateLimitRetry:
+			requestsTotal.WithLabelValues("rate_limited").Inc()
+			c.adapt(false)
+			logger.Info("Embedding rate limited, backing off", zap.Duration("retryAfter", retryAfter), zap.Int("batch", len(inputs)))
+
+			select {
+			case <-time.After(retryAfter):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if size := c.batchSize(); len(inputs) > size {
+				return c.embedSplit(ctx, key, inputs, size)
+			}
+
+		case retryAfter > 0:
+			requestsTotal.WithLabelValues("rate_limited").Inc()
+			c.adapt(false)
+			return nil, &RateLimitError{RetryAfter: retryAfter}
+
+		default:
+			requestsTotal.WithLabelValues("error").Inc()
+			return nil, err
+		}
+	}
+}
+
+// embedSplit retries a rate-limited batch as batches of the reduced size, in order.
+func (c *JinaClient) embedSplit(ctx context.Context, key batchKey, inputs []string, size int) ([][]float32, error) {
+	embeddings := make([][]float32, 0, len(inputs))
+	for start := 0; start < len(inputs); start += size {
+		part, err := c.embedWithRetry(ctx, key, inputs[start:min(start+size, len(inputs))])
+		if err != nil {
+			return nil, err
+		}
+		embeddings = append(embeddings, part...)
+	}
+	return embeddings, nil
+}
+
+func (c *JinaClient) batchSize() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.batch
+}
+
+// wait blocks until both the request and the token budget allow one more call.
+func (c *JinaClient) wait(ctx context.Context, tokens int) error {
+	start := time.Now()
+	defer func() { limiterWaitSeconds.Observe(time.Since(start).Seconds()) }()
+
+	if c.requests != nil {
+		if err := c.requests.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	if c.tokens != nil {
+		return c.tokens.WaitN(ctx, min(tokens, c.tokens.Burst()))
+	}
+	return nil
+}
+
+// adapt halves the batch size after a 429 and grows it by a quarter after each success.
+func (c *JinaClient) adapt(ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ok {
+		c.batch = min(c.maxBatch, c.batch+max(1, c.batch/4))
+	} else {
+		c.batch = max(1, c.batch/2)
+	}
+	adaptiveBatch.Set(float64(c.batch))
+}
+
+// post returns retryAfter > 0 when the server rate limited the request.
+func (c *JinaClient) post(ctx context.Context, key batchKey, inputs []string) ([][]float32, time.Duration, error) {
+	body, err := json.Marshal(jinaRequest{Model: key.model, Task: key.task, Input: inputs})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, retryAfter(resp.Header.Get("Retry-After")), fmt.Errorf("failed to get embedding: %s", resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("failed to get embedding: %s", resp.Status)
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, 0, err
+	}
+	if len(result.Data) != len(inputs) {
+		return nil, 0, errors.New("embedding count does not match input count")
+	}
+
+	embeddings := make([][]float32, len(inputs))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(inputs) || len(d.Embedding) == 0 {
+			return nil, 0, errors.New("no embedding data found")
+		}
+		embeddings[d.Index] = d.Embedding
+	}
+	return embeddings, 0, nil
+}
+
+type jinaRequest struct {
+	Model string   `json:"model"`
+	Task  string   `json:"task"`
+	Input []string `json:"input"`
+}
+
+func retryAfter(header string) time.Duration {
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil && time.Until(at) > 0 {
+		return time.Until(at)
+	}
+	return 2 * time.Second
+}
+
+// estimateTokens approximates tokens as four bytes each, which is conservative for the
+// English and Latin remedy names in the corpus.
+func estimateTokens(text string) int {
+	return len(text)/4 + 1
+}
@@ -0,0 +1,117 @@
+package embedding
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jinaServer embeds each input as a vector holding its length, and records the inputs
+// of every request. Requests that limit returns true for are answered with a 429.
+type jinaServer struct {
+	mu       sync.Mutex
+	requests []jinaRequest
+	limit    func(request int, req jinaRequest) bool
+}
+
+func (s *jinaServer) start(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req jinaRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		s.mu.Lock()
+		s.requests = append(s.requests, req)
+		limited := s.limit != nil && s.limit(len(s.requests), req)
+		s.mu.Unlock()
+
+		if limited {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		type datum struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}
+		var result struct {
+			Data []datum `json:"data"`
+		}
+		for i, input := range req.Input {
+			result.Data = append(result.Data, datum{Index: i, Embedding: []float32{float32(len(input))}})
+		}
+		json.NewEncoder(w).Encode(result)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (s *jinaServer) batchSizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sizes := make([]int, len(s.requests))
+	for i, req := range s.requests {
+		sizes[i] = len(req.Input)
+	}
+	return sizes
+}
+
+func newTestJinaClient(srv *httptest.Server, maxBatch int) *JinaClient {
+	c := NewJinaClient("test", &appconfig.AppConfig{EmbedMaxBatch: maxBatch, EmbedBatchWindowMs: 50})
+	c.url = srv.URL
+	c.httpClient = srv.Client()
+	return c
+}
+
+func TestJinaClientBatchesConcurrentCalls(t *testing.T) {
+	server := &jinaServer{}
+	client := newTestJinaClient(server.start(t), 4)
+
+	texts := []string{"a", "bb", "ccc", "dddd", "eeeee", "ffffff"}
+	results := make([]<-chan async.Result[[]float32], len(texts))
+	for i, text := range texts {
+		results[i] = client.GetEmbedding(t.Context(), text, WithTask("retrieval.query"))
+	}
+	for i, result := range results {
+		embedding, err := async.Await(result)
+		require.NoError(t, err)
+		assert.Equal(t, []float32{float32(len(texts[i]))}, embedding)
+	}
+
+	assert.ElementsMatch(t, []int{4, 2}, server.batchSizes(), "calls are sent in batches of at most four")
+	for _, req := range server.requests {
+		assert.Equal(t, "retrieval.query", req.Task)
+		assert.Equal(t, jinaDefaultModel, req.Model)
+	}
+}
+
+func TestJinaClientSplitsRateLimitedBatch(t *testing.T) {
+	server := &jinaServer{limit: func(request int, _ jinaRequest) bool { return request == 1 }}
+	client := newTestJinaClient(server.start(t), 4)
+
+	embeddings, err := client.GetEmbeddings(t.Context(), []string{"a", "bb", "ccc", "dddd"}, WithModel("jina-embeddings-v3"))
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1}, {2}, {3}, {4}}, embeddings, "in input order")
+
+	assert.Equal(t, []int{4, 2, 2}, server.batchSizes(), "the rejected batch is retried in halves")
+	assert.Equal(t, "jina-embeddings-v3", server.requests[2].Model)
+}
+
+func TestResolveOptions(t *testing.T) {
+	model, task := resolveOptions([]embed.EmbedOption{WithModel("voyage-3.5"), WithTask("retrieval.query")}, "default", "retrieval.passage")
+	assert.Equal(t, "voyage-3.5", model)
+	assert.Equal(t, "retrieval.query", task)
+
+	model, task = resolveOptions([]embed.EmbedOption{embed.WithKeepAlive(0)}, "default", "retrieval.passage")
+	assert.Equal(t, "default", model, "options this package can't read leave the defaults")
+	assert.Equal(t, "retrieval.passage", task)
+}
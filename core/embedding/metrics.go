@@ -0,0 +1,42 @@
+package embedding
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Exposed on go-api-boot's /metrics endpoint through the default registry.
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "embedding_requests_total",
+		Help: "Embedding API requests by outcome (ok, rate_limited, error).",
+	}, []string{"status"})
+
+	requestSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "embedding_request_seconds",
+		Help:    "Latency of embedding API requests.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	batchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "embedding_batch_size",
+		Help:    "Inputs per successful embedding request.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 8),
+	})
+
+	adaptiveBatch = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "embedding_adaptive_batch_size",
+		Help: "Current batch size limit after rate-limit adaptation.",
+	})
+
+	queueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "embedding_queue_depth",
+		Help: "Inputs waiting to be batched.",
+	})
+
+	limiterWaitSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "embedding_limiter_wait_seconds",
+		Help:    "Time spent waiting for the client-side request/token budget.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+	})
+)
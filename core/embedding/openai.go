@@ -0,0 +1,120 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+)
+
+const (
+	openAIEmbeddingsURL = "https://api.openai.com/v1/embeddings"
+	openAIDefaultModel  = "text-embedding-3-small"
+)
+
+// OpenAIClient embeds with the OpenAI embeddings API, one request per text, or per
+// batch through GetEmbeddings. OpenAI models take no task, so queries and passages
+// are embedded alike.
+type OpenAIClient struct {
+	apiKey     string
+	httpClient *http.Client
+	url        string
+}
+
+func NewOpenAIClient(apiKey string) *OpenAIClient {
+	return &OpenAIClient{
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+		url:        openAIEmbeddingsURL,
+	}
+}
+
+func (c *OpenAIClient) GetEmbedding(ctx context.Context, text string, opts ...embed.EmbedOption) <-chan async.Result[[]float32] {
+	return async.Go(func() ([]float32, error) {
+		embeddings, err := c.GetEmbeddings(ctx, []string{text}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return embeddings[0], nil
+	})
+}
+
+func (c *OpenAIClient) GetEmbeddings(ctx context.Context, texts []string, opts ...embed.EmbedOption) ([][]float32, error) {
+	model, _ := resolveOptions(opts, openAIDefaultModel, "")
+	return postEmbedding(ctx, c.httpClient, c.url, c.apiKey, openAIRequest{Model: model, Input: texts}, len(texts))
+}
+
+type openAIRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// postEmbedding sends an embeddings request for inputs texts to an API answering in
+// OpenAI's response format, which Voyage shares, and returns the vectors in input
+// order. A 429 is returned as a RateLimitError.
+func postEmbedding(ctx context.Context, httpClient *http.Client, url, apiKey string, request any, inputs int) ([][]float32, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	requestSeconds.Observe(time.Since(start).Seconds())
+	if err != nil {
+		requestsTotal.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests:
+		requestsTotal.WithLabelValues("rate_limited").Inc()
+		return nil, &RateLimitError{RetryAfter: retryAfter(resp.Header.Get("Retry-After"))}
+	default:
+		requestsTotal.WithLabelValues("error").Inc()
+		return nil, fmt.Errorf("failed to get embedding: %s", resp.Status)
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		requestsTotal.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	if len(result.Data) != inputs {
+		requestsTotal.WithLabelValues("error").Inc()
+		return nil, errors.New("embedding count does not match input count")
+	}
+
+	embeddings := make([][]float32, inputs)
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= inputs || len(d.Embedding) == 0 {
+			requestsTotal.WithLabelValues("error").Inc()
+			return nil, errors.New("no embedding data found")
+		}
+		embeddings[d.Index] = d.Embedding
+	}
+
+	requestsTotal.WithLabelValues("ok").Inc()
+	batchSize.Observe(float64(inputs))
+	return embeddings, nil
+}
@@ -0,0 +1,74 @@
+package embedding
+
+import (
+	"context"
+	"sync"
+
+	"github.com/SaiNageswarS/go-api-boot/embed"
+)
+
+// options is what a call's embed options set. go-api-boot's options set fields of an
+// unexported struct, so WithModel and WithTask record their values here as they apply.
+type options struct {
+	model string
+	task  string
+}
+
+// captures maps the settings that resolveOptions is applying an option to onto the
+// options it is recorded in.
+var captures sync.Map
+
+// capture wraps a go-api-boot option so it also records its value when resolved here.
+// go-api-boot's own embedders see the option unchanged.
+func capture[S any](opt func(*S), record func(*options)) func(*S) {
+	return func(s *S) {
+		opt(s)
+		if o, ok := captures.Load(s); ok {
+			record(o.(*options))
+		}
+	}
+}
+
+// WithModel is embed.WithModel, readable by this package's embedders.
+func WithModel(name string) embed.EmbedOption {
+	return capture(embed.WithModel(name), func(o *options) { o.model = name })
+}
+
+// WithTask is embed.WithTask, readable by this package's embedders.
+func WithTask(name string) embed.EmbedOption {
+	return capture(embed.WithTask(name), func(o *options) { o.task = name })
+}
+
+// resolveOptions returns the model and task opts set, or the defaults given. Options
+// made with go-api-boot's own constructors set nothing here.
+func resolveOptions(opts []embed.EmbedOption, model, task string) (string, string) {
+	o := &options{model: model, task: task}
+	for _, opt := range opts {
+		applyOption(opt, o)
+	}
+	return o.model, o.task
+}
+
+// applyOption runs opt on fresh settings of go-api-boot's type, recording into o.
+func applyOption[S any](opt func(*S), o *options) {
+	s := new(S)
+	captures.Store(s, o)
+	defer captures.Delete(s)
+	opt(s)
+}
+
+// mergedContext stays alive while any of the callers in a batch is still waiting.
+func mergedContext(batch []*pending) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for _, p := range batch {
+			select {
+			case <-p.ctx.Done():
+			case <-ctx.Done():
+				return
+			}
+		}
+		cancel()
+	}()
+	return ctx, cancel
+}
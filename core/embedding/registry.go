@@ -0,0 +1,249 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"go.uber.org/zap"
+)
+
+// Supported providers and the environment variable each one needs.
+var providerEnv = map[string]string{
+	"jina":   "JINA_AI_API_KEY",
+	"ollama": "OLLAMA_HOST",
+	"openai": "OPENAI_API_KEY",
+	"voyage": "VOYAGE_API_KEY",
+}
+
+// Legacy is the embedding model of deployments without an `embedders` setting, and of
+// vectors stored before the model was recorded alongside them.
+var Legacy = Spec{Name: "jina", Provider: "jina", Model: jinaDefaultModel, Dimensions: 2048}
+
+// multilingualModels are the model families that embed text of every language they
+// know into one space, so a query in one language finds passages in another, by model
+// id prefix. Other models are taken to know English only.
+var multilingualModels = []string{
+	"jina-embeddings-v3", "jina-embeddings-v4",
+	"text-embedding-3-",
+	"voyage-3", "voyage-multilingual-",
+	"bge-m3", "multilingual-e5", "nomic-embed-text-v2",
+}
+
+// Spec is one entry of the `embedders` config list, written as
+// name=provider:model@dimensions, e.g. openai-large=openai:text-embedding-3-large@3072.
+type Spec struct {
+	Name       string
+	Provider   string
+	Model      string
+	Dimensions int
+}
+
+// Registry maps the embedder names tenants may choose to embedding clients. Clients are
+// built on first use, so a provider without credentials only disables its own models.
+//
+// Vectors of different models are not comparable, even at the same size, so a tenant
+// whose embedder is unavailable gets an error rather than another embedder.
+type Registry struct {
+	specs       map[string]Spec
+	defaultName string
+	ccfg        *appconfig.AppConfig
+
+	mu      sync.Mutex
+	clients map[string]embed.Embedder // per provider
+}
+
+// Multilingual reports whether the spec's model embeds other languages than English
+// comparably; see multilingualModels.
+func (s Spec) Multilingual() bool {
+	return slices.ContainsFunc(multilingualModels, func(prefix string) bool {
+		return strings.HasPrefix(s.Model, prefix)
+	})
+}
+
+func ProvideRegistry(ccfg *appconfig.AppConfig) *Registry {
+	r := &Registry{
+		specs:       make(map[string]Spec),
+		defaultName: ccfg.DefaultEmbedder,
+		ccfg:        ccfg,
+		clients:     make(map[string]embed.Embedder),
+	}
+
+	for _, entry := range ccfg.Embedders {
+		spec, err := ParseSpec(entry)
+		if err != nil {
+			logger.Error("Ignoring invalid embedder entry", zap.String("entry", entry), zap.Error(err))
+			continue
+		}
+		r.specs[spec.Name] = spec
+	}
+
+	if len(r.specs) == 0 {
+		r.specs[Legacy.Name] = Legacy
+	}
+	if r.defaultName == "" {
+		r.defaultName = Legacy.Name
+	}
+	if _, ok := r.specs[r.defaultName]; !ok {
+		logger.Error("Default embedder is not registered", zap.String("defaultEmbedder", r.defaultName))
+	}
+	if name := ccfg.MultilingualEmbedder; name != "" && r.MultilingualName() == "" {
+		logger.Error("Multilingual embedder is not a registered multilingual model", zap.String("multilingualEmbedder", name))
+	}
+	return r
+}
+
+func ParseSpec(entry string) (Spec, error) {
+	name, target, ok := strings.Cut(strings.TrimSpace(entry), "=")
+	if !ok {
+		return Spec{}, fmt.Errorf("expected name=provider:model@dimensions")
+	}
+	provider, model, ok := strings.Cut(target, ":") // model ids may contain ':' themselves
+	if !ok {
+		return Spec{}, fmt.Errorf("expected name=provider:model@dimensions")
+	}
+	provider = strings.TrimSpace(provider)
+	at := strings.LastIndex(model, "@")
+	if at < 0 {
+		return Spec{}, fmt.Errorf("expected name=provider:model@dimensions")
+	}
+	dimensions, err := strconv.Atoi(strings.TrimSpace(model[at+1:]))
+	if err != nil || dimensions <= 0 {
+		return Spec{}, fmt.Errorf("invalid dimensions %q", model[at+1:])
+	}
+	model = strings.TrimSpace(model[:at])
+	if name == "" || model == "" {
+		return Spec{}, fmt.Errorf("expected name=provider:model@dimensions")
+	}
+	if _, known := providerEnv[provider]; !known {
+		return Spec{}, fmt.Errorf("unknown provider %q", provider)
+	}
+
+	return Spec{Name: strings.TrimSpace(name), Provider: provider, Model: model, Dimensions: dimensions}, nil
+}
+
+// Spec returns the spec registered as name, or the default embedder's for an empty name.
+func (r *Registry) Spec(name string) (Spec, error) {
+	if name == "" {
+		name = r.defaultName
+	}
+	spec, ok := r.specs[name]
+	if !ok {
+		return Spec{}, fmt.Errorf("embedder %q is not registered", name)
+	}
+	return spec, nil
+}
+
+// Find returns a registered spec of model with vectors of dimensions, the default
+// embedder's when it is one, as for vectors embedded by another deployment.
+func (r *Registry) Find(model string, dimensions int) (Spec, bool) {
+	if spec, ok := r.specs[r.defaultName]; ok && spec.Model == model && spec.Dimensions == dimensions {
+		return spec, true
+	}
+	names := make([]string, 0, len(r.specs))
+	for name := range r.specs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if spec := r.specs[name]; spec.Model == model && spec.Dimensions == dimensions {
+			return spec, true
+		}
+	}
+	return Spec{}, false
+}
+
+// MultilingualName is the registry name of the deployment's multilingual_embedder,
+// which tenants whose embedder knows English only are switched to for documents in
+// other languages; empty when it is unset, not registered or not multilingual.
+func (r *Registry) MultilingualName() string {
+	spec, ok := r.specs[r.ccfg.MultilingualEmbedder]
+	if !ok || !spec.Multilingual() {
+		return ""
+	}
+	return spec.Name
+}
+
+// Resolve returns the embedder registered as name, or the default embedder for an
+// empty name. The embedder embeds with the spec's model and fails on vectors of any
+// other size.
+func (r *Registry) Resolve(name string) (Spec, embed.Embedder, error) {
+	spec, err := r.Spec(name)
+	if err != nil {
+		return Spec{}, nil, err
+	}
+
+	client, err := r.client(spec.Provider)
+	if err != nil {
+		return spec, nil, err
+	}
+	return spec, &specEmbedder{spec: spec, embedder: client}, nil
+}
+
+func (r *Registry) client(provider string) (embed.Embedder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if client, ok := r.clients[provider]; ok {
+		return client, nil
+	}
+
+	env := providerEnv[provider]
+	if os.Getenv(env) == "" {
+		return nil, fmt.Errorf("%s environment variable is not set", env)
+	}
+
+	var client embed.Embedder
+	switch provider {
+	case "jina":
+		client = NewJinaClient(os.Getenv(env), r.ccfg)
+	case "ollama":
+		client = embed.ProvideOllamaEmbeddingClient()
+	case "openai":
+		client = NewOpenAIClient(os.Getenv(env))
+	case "voyage":
+		client = NewVoyageClient(os.Getenv(env))
+	}
+	r.clients[provider] = client
+	return client, nil
+}
+
+// specEmbedder pins an embedding client to one spec.
+type specEmbedder struct {
+	spec     Spec
+	embedder embed.Embedder
+}
+
+func (e *specEmbedder) GetEmbedding(ctx context.Context, text string, opts ...embed.EmbedOption) <-chan async.Result[[]float32] {
+	return async.Go(func() ([]float32, error) {
+		embedding, err := async.Await(e.embedder.GetEmbedding(ctx, text, append(opts, WithModel(e.spec.Model))...))
+		if err != nil {
+			return nil, err
+		}
+		if len(embedding) != e.spec.Dimensions {
+			return nil, fmt.Errorf("embedder %q returned %d dimensions, expected %d", e.spec.Name, len(embedding), e.spec.Dimensions)
+		}
+		return embedding, nil
+	})
+}
+
+func (e *specEmbedder) GetEmbeddings(ctx context.Context, texts []string, opts ...embed.EmbedOption) ([][]float32, error) {
+	embeddings, err := EmbedBatch(ctx, e.embedder, texts, append(opts, WithModel(e.spec.Model))...)
+	if err != nil {
+		return nil, err
+	}
+	for _, embedding := range embeddings {
+		if len(embedding) != e.spec.Dimensions {
+			return nil, fmt.Errorf("embedder %q returned %d dimensions, expected %d", e.spec.Name, len(embedding), e.spec.Dimensions)
+		}
+	}
+	return embeddings, nil
+}
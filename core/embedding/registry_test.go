@@ -0,0 +1,165 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSpec(t *testing.T) {
+	spec, err := ParseSpec(" nomic = ollama:nomic-embed-text:v1.5@768 ")
+	require.NoError(t, err)
+	assert.Equal(t, Spec{Name: "nomic", Provider: "ollama", Model: "nomic-embed-text:v1.5", Dimensions: 768}, spec)
+
+	for _, entry := range []string{
+		"jina:jina-embeddings-v4@2048",
+		"jina=jina-embeddings-v4@2048",
+		"jina=jina:jina-embeddings-v4",
+		"jina=jina:jina-embeddings-v4@0",
+		"jina=jina:@2048",
+		"cohere=cohere:embed-v4@1024",
+	} {
+		_, err := ParseSpec(entry)
+		assert.Error(t, err, entry)
+	}
+}
+
+func TestRegistryDefaults(t *testing.T) {
+	r := ProvideRegistry(&appconfig.AppConfig{})
+	spec, err := r.Spec("")
+	require.NoError(t, err)
+	assert.Equal(t, Legacy, spec, "deployments without embedders keep jina-embeddings-v4")
+
+	r = ProvideRegistry(&appconfig.AppConfig{Embedders: []string{"jina=jina:jina-embeddings-v4@2048", "voyage=voyage:voyage-3.5@1024"}, DefaultEmbedder: "voyage"})
+	spec, err = r.Spec("")
+	require.NoError(t, err)
+	assert.Equal(t, "voyage-3.5", spec.Model)
+
+	_, _, err = r.Resolve("openai")
+	assert.ErrorContains(t, err, "not registered")
+
+	t.Setenv("VOYAGE_API_KEY", "")
+	_, _, err = r.Resolve("voyage")
+	assert.ErrorContains(t, err, "VOYAGE_API_KEY", "no other embedder stands in")
+}
+
+func TestRegistryFind(t *testing.T) {
+	r := ProvideRegistry(&appconfig.AppConfig{Embedders: []string{
+		"voyage-b=voyage:voyage-3.5@1024", "voyage-a=voyage:voyage-3.5@1024", "large=openai:text-embedding-3-large@3072",
+	}, DefaultEmbedder: "large"})
+
+	spec, ok := r.Find("voyage-3.5", 1024)
+	assert.True(t, ok)
+	assert.Equal(t, "voyage-a", spec.Name, "the first name of a model registered twice")
+	spec, ok = r.Find("text-embedding-3-large", 3072)
+	assert.True(t, ok)
+	assert.Equal(t, "large", spec.Name)
+
+	_, ok = r.Find("voyage-3.5", 512)
+	assert.False(t, ok, "vectors of another size")
+}
+
+func TestMultilingual(t *testing.T) {
+	assert.True(t, Legacy.Multilingual())
+	assert.True(t, Spec{Model: "voyage-3.5"}.Multilingual())
+	assert.False(t, Spec{Model: "nomic-embed-text"}.Multilingual())
+
+	embedders := []string{"jina=jina:jina-embeddings-v4@2048", "nomic=ollama:nomic-embed-text@768"}
+	assert.Equal(t, "jina", ProvideRegistry(&appconfig.AppConfig{Embedders: embedders, MultilingualEmbedder: "jina"}).MultilingualName())
+	assert.Empty(t, ProvideRegistry(&appconfig.AppConfig{Embedders: embedders, MultilingualEmbedder: "nomic"}).MultilingualName(), "an English model")
+	assert.Empty(t, ProvideRegistry(&appconfig.AppConfig{Embedders: embedders}).MultilingualName())
+}
+
+type modelEcho struct{ dimensions int }
+
+// GetEmbedding returns a vector of the fake's size whose first value is the model's
+// name length, to show which model was asked for.
+func (e modelEcho) GetEmbedding(ctx context.Context, text string, opts ...embed.EmbedOption) <-chan async.Result[[]float32] {
+	return async.Go(func() ([]float32, error) {
+		model, _ := resolveOptions(opts, "", "")
+		vector := make([]float32, e.dimensions)
+		vector[0] = float32(len(model))
+		return vector, nil
+	})
+}
+
+func TestSpecEmbedder(t *testing.T) {
+	spec := Spec{Name: "voyage", Provider: "voyage", Model: "voyage-3.5", Dimensions: 4}
+
+	embedding, err := async.Await((&specEmbedder{spec: spec, embedder: modelEcho{dimensions: 4}}).GetEmbedding(t.Context(), "fear of death"))
+	require.NoError(t, err)
+	assert.Equal(t, float32(len("voyage-3.5")), embedding[0], "the spec's model is used")
+
+	_, err = async.Await((&specEmbedder{spec: spec, embedder: modelEcho{dimensions: 8}}).GetEmbedding(t.Context(), "fear of death"))
+	assert.ErrorContains(t, err, "returned 8 dimensions, expected 4")
+}
+
+func TestVoyageClient(t *testing.T) {
+	var request voyageRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Write([]byte(`{"data":[{"embedding":[0.1,0.2,0.3],"index":0}]}`))
+	}))
+	defer server.Close()
+
+	client := NewVoyageClient("key")
+	client.url = server.URL
+
+	embedding, err := async.Await(client.GetEmbedding(t.Context(), "fear of death", WithTask("retrieval.query"), WithModel("voyage-3.5-lite")))
+	require.NoError(t, err)
+	assert.Equal(t, []float32{0.1, 0.2, 0.3}, embedding)
+	assert.Equal(t, voyageRequest{Model: "voyage-3.5-lite", Input: []string{"fear of death"}, InputType: "query"}, request)
+}
+
+func TestOpenAIClientError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := NewOpenAIClient("key")
+	client.url = server.URL
+
+	_, err := async.Await(client.GetEmbedding(t.Context(), "fear of death"))
+	assert.ErrorContains(t, err, "401")
+}
+
+func TestOpenAIClientBatch(t *testing.T) {
+	limited := true
+	var request openAIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if limited {
+			limited = false
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Write([]byte(`{"data":[{"embedding":[0.2],"index":1},{"embedding":[0.1],"index":0}]}`))
+	}))
+	defer server.Close()
+
+	client := NewOpenAIClient("key")
+	client.url = server.URL
+
+	_, err := client.GetEmbeddings(t.Context(), []string{"fear", "anxiety"})
+	retryAfter, limitedErr := IsRateLimited(err)
+	require.True(t, limitedErr, "a 429 is a RateLimitError")
+	assert.Equal(t, 3*time.Second, retryAfter)
+
+	spec := Spec{Name: "openai", Provider: "openai", Model: "text-embedding-3-small", Dimensions: 1}
+	embeddings, err := EmbedBatch(t.Context(), &specEmbedder{spec: spec, embedder: client}, []string{"fear", "anxiety"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{0.1}, {0.2}}, embeddings, "vectors are in input order")
+	assert.Equal(t, []string{"fear", "anxiety"}, request.Input, "one request for the batch")
+}
@@ -0,0 +1,59 @@
+package embedding
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+)
+
+const (
+	voyageEmbeddingsURL = "https://api.voyageai.com/v1/embeddings"
+	voyageDefaultModel  = "voyage-3.5"
+)
+
+// voyageInputTypes maps the Jina tasks callers pass to Voyage's input types.
+var voyageInputTypes = map[string]string{
+	"retrieval.query":   "query",
+	"retrieval.passage": "document",
+}
+
+// VoyageClient embeds with the Voyage AI embeddings API, one request per text, or per
+// batch through GetEmbeddings.
+type VoyageClient struct {
+	apiKey     string
+	httpClient *http.Client
+	url        string
+}
+
+func NewVoyageClient(apiKey string) *VoyageClient {
+	return &VoyageClient{
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+		url:        voyageEmbeddingsURL,
+	}
+}
+
+func (c *VoyageClient) GetEmbedding(ctx context.Context, text string, opts ...embed.EmbedOption) <-chan async.Result[[]float32] {
+	return async.Go(func() ([]float32, error) {
+		embeddings, err := c.GetEmbeddings(ctx, []string{text}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return embeddings[0], nil
+	})
+}
+
+func (c *VoyageClient) GetEmbeddings(ctx context.Context, texts []string, opts ...embed.EmbedOption) ([][]float32, error) {
+	model, task := resolveOptions(opts, voyageDefaultModel, "")
+	request := voyageRequest{Model: model, Input: texts, InputType: voyageInputTypes[task]}
+	return postEmbedding(ctx, c.httpClient, c.url, c.apiKey, request, len(texts))
+}
+
+type voyageRequest struct {
+	Model     string   `json:"model"`
+	Input     []string `json:"input"`
+	InputType string   `json:"input_type,omitempty"`
+}
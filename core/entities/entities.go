@@ -0,0 +1,342 @@
+// Package entities recognizes the remedies, rubrics and body systems a text is about,
+// with a dictionary of their names and a few rules for how repertories and materia
+// medicas write them. Chunks are tagged at ingestion, and search boosts the chunks
+// tagged with the entities a question mentions.
+package entities
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+)
+
+// Entity kinds.
+const (
+	KindRemedy = "remedy"
+	KindRubric = "rubric"
+	KindSystem = "system"
+)
+
+// a rubric or body system must be mentioned this many times in a chunk's text to tag
+// it; once is enough in its headings, and for a remedy
+const minBodyMentions = 2
+
+// RemedyNames are the remedies recognized by name: the Latin name, which tags the
+// remedy, then its common names and the abbreviations repertories and older materia
+// medicas use. Search queries are expanded with them as synonyms.
+var RemedyNames = [][]string{
+	{"Aconitum napellus", "Aconite", "Acon."},
+	{"Apis mellifica", "Apis mel.", "Apis"},
+	{"Argentum nitricum", "Arg-n.", "Arg. nit."},
+	{"Arnica montana", "Arnica", "Arn."},
+	{"Arsenicum album", "Arsenic", "Ars.", "Ars-alb.", "Ars. alb."},
+	{"Belladonna", "Bell."},
+	{"Bryonia alba", "Bryonia", "Bry."},
+	{"Calcarea carbonica", "Calc.", "Calc-c.", "Calc. carb."},
+	{"Calcarea phosphorica", "Calc-p.", "Calc. phos."},
+	{"Carbo vegetabilis", "Carb-v.", "Carbo veg."},
+	{"Causticum", "Caust."},
+	{"Chamomilla", "Cham."},
+	{"Gelsemium sempervirens", "Gelsemium", "Gels."},
+	{"Hepar sulphuris calcareum", "Hepar sulph.", "Hep."},
+	{"Hypericum perforatum", "Hypericum", "Hyper."},
+	{"Ignatia amara", "Ignatia", "Ign."},
+	{"Kali bichromicum", "Kali-bi.", "Kali bich."},
+	{"Lachesis muta", "Lachesis", "Lach."},
+	{"Lycopodium clavatum", "Lycopodium", "Lyc."},
+	{"Mercurius solubilis", "Mercurius", "Merc.", "Merc-s.", "Merc. sol."},
+	{"Natrum muriaticum", "Natrum mur.", "Nat-m.", "Nat. mur."},
+	{"Nux vomica", "Nux-v.", "Nux vom."},
+	{"Phosphorus", "Phos."},
+	{"Pulsatilla nigricans", "Pulsatilla", "Puls."},
+	{"Rhus toxicodendron", "Rhus tox.", "Rhus-t.", "Poison ivy"},
+	{"Ruta graveolens", "Ruta", "Ruta g."},
+	{"Sepia officinalis", "Sepia", "Sep."},
+	{"Silicea", "Silica", "Sil."},
+	{"Staphysagria", "Staph."},
+	{"Sulphur", "Sulph.", "Sulfur"},
+	{"Thuja occidentalis", "Thuja", "Thuj."},
+}
+
+// rubrics are common repertory rubrics, by the words that mention them.
+var rubrics = []pattern{
+	{"fear", `fears?|fearful|afraid|fright\w*|dread\w*|phobi\w*`},
+	{"anxiety", `anxi\w*|anguish`},
+	{"grief", `grie(?:f|ve|ving)|bereave\w*|mourn\w*`},
+	{"anger", `anger|angry|irritab\w*|rage|vexation`},
+	{"weeping", `weep\w*|tearful|crying`},
+	{"restlessness", `restless\w*`},
+	{"delusions", `delusions?`},
+	{"vertigo", `vertigo|dizz\w*|giddi\w*`},
+	{"headache", `headaches?|cephalalgia`},
+	{"cough", `cough\w*`},
+	{"nausea", `nause\w*`},
+	{"vomiting", `vomit\w*`},
+	{"diarrhoea", `diarrh\w*`},
+	{"constipation", `constipat\w*`},
+	{"thirst", `thirst\w*`},
+	{"fever", `fevers?|feverish|febrile`},
+	{"chill", `chill\w*|shiver\w*`},
+	{"perspiration", `perspir\w*|sweat\w*`},
+	{"sleeplessness", `sleepless\w*|insomnia`},
+	{"burning", `burning`},
+	{"itching", `itch\w*|prurit\w*`},
+	{"haemorrhage", `bleed\w*|h(?:a)?emorrhag\w*`},
+	{"swelling", `swell\w*|swollen|(?:o)?edema\w*`},
+	{"cramps", `cramp\w*|spasm\w*`},
+	{"weakness", `weak\w*|exhaust\w*|prostrat\w*`},
+}
+
+// systems are the body systems repertory chapters are arranged by, by the words that
+// mention them.
+var systems = []pattern{
+	{"mind", `mind|mental\w*|emotion\w*|psych\w*`},
+	{"head", `head|heads|scalp|headaches?`},
+	{"eyes", `eyes?|eyelids?|vision|conjunctiv\w*`},
+	{"ears", `ears?|hearing|otitis`},
+	{"nose", `nose|nasal|coryza|sneez\w*`},
+	{"face", `face|facial`},
+	{"mouth", `mouth|tongue|teeth|tooth\w*|gums`},
+	{"throat", `throat|tonsil\w*|pharyn\w*|laryn\w*`},
+	{"stomach", `stomach|gastric|nause\w*|vomit\w*|appetite|dyspeps\w*`},
+	{"abdomen", `abdom\w*|colic\w*|liver|bowels?`},
+	{"rectum", `rectum|rectal|anus|stools?|diarrh\w*|constipat\w*|h(?:a)?emorrhoids`},
+	{"urinary", `urin\w*|bladder|kidneys?|renal`},
+	{"genitalia", `genital\w*|uter\w*|ovar\w*|menstru\w*|menses|testic\w*`},
+	{"respiratory", `respirat\w*|breath\w*|lungs?|chest|cough\w*|asthma\w*`},
+	{"heart", `heart|cardiac|palpitat\w*|pulse`},
+	{"back", `backache|spine|spinal|lumbar|sacr(?:al|um)`},
+	{"extremities", `extremit\w*|limbs?|joints?|knees?|hands?|feet|foot`},
+	{"skin", `skin|eruptions?|rash\w*|eczema|urticaria`},
+	{"sleep", `sleep\w*|insomnia|dreams?`},
+	{"fever", `fevers?|feverish|febrile`},
+}
+
+type pattern struct {
+	name  string
+	words string // alternatives of a regular expression matching whole words
+}
+
+// Entity is a remedy, rubric or body system a text mentions.
+type Entity struct {
+	Kind string
+	Name string // lower case; a remedy's Latin name, e.g. "aconitum napellus"
+}
+
+// Tag is how the entity is stored on a chunk, e.g. "remedy:aconitum napellus".
+func (e Entity) Tag() string { return e.Kind + ":" + e.Name }
+
+// ParseTag reads a tag written by Tag.
+func ParseTag(tag string) (Entity, bool) {
+	kind, name, ok := strings.Cut(tag, ":")
+	if !ok || name == "" {
+		return Entity{}, false
+	}
+	return Entity{Kind: kind, Name: name}, true
+}
+
+// Recognize returns the entities text mentions, in the order they are first mentioned.
+func Recognize(text string) []Entity {
+	_, order := recognizer.count(text)
+	return order
+}
+
+// RemedyName returns the Latin name, as RemedyNames writes it, of the remedy one of
+// whose names is name, ignoring case and punctuation, so "NAT-M." is Natrum muriaticum.
+func RemedyName(name string) (string, bool) {
+	remedy, ok := recognizer.remedies[db.SynonymKey(name)]
+	if !ok {
+		return "", false
+	}
+	return recognizer.latin[remedy.remedy], true
+}
+
+// Mention is where a text mentions an entity: byte offsets, end exclusive.
+type Mention struct {
+	Entity
+	Start, End int
+}
+
+// Mentions returns every mention of an entity in text, by where it starts; of mentions
+// starting together, the longer comes first.
+func Mentions(text string) []Mention {
+	var mentions []Mention
+	recognizer.findRemedies(text, func(entity Entity, start, end int) {
+		mentions = append(mentions, Mention{Entity: entity, Start: start, End: end})
+	})
+	find := func(kind string, patterns []pattern, compiled []*regexp.Regexp) {
+		for i, re := range compiled {
+			for _, span := range re.FindAllStringIndex(text, -1) {
+				mentions = append(mentions, Mention{Entity: Entity{Kind: kind, Name: patterns[i].name}, Start: span[0], End: span[1]})
+			}
+		}
+	}
+	find(KindRubric, rubrics, recognizer.rubrics)
+	find(KindSystem, systems, recognizer.systems)
+
+	slices.SortStableFunc(mentions, func(a, b Mention) int {
+		return cmp.Or(cmp.Compare(a.Start, b.Start), cmp.Compare(b.End, a.End))
+	})
+	return mentions
+}
+
+// ChunkTags are the tags of the entities a chunk is about: those its headings mention,
+// and those its text mentions often enough. A remedy is tagged on its first mention,
+// while a rubric or body system must come up at least twice in the text, since nearly
+// every materia medica entry touches on most of them in passing.
+func ChunkTags(headings []string, text string) []string {
+	var entities []Entity
+	for _, heading := range headings {
+		entities = append(entities, Recognize(heading)...)
+	}
+
+	counts, order := recognizer.count(text)
+	for _, entity := range order {
+		if entity.Kind == KindRemedy || counts[entity] >= minBodyMentions {
+			entities = append(entities, entity)
+		}
+	}
+
+	var tags []string
+	for _, entity := range entities {
+		if tag := entity.Tag(); !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Matches reports how many of want a chunk's tags include.
+func Matches(tags []string, want []Entity) int {
+	matched := 0
+	for _, entity := range want {
+		if slices.Contains(tags, entity.Tag()) {
+			matched++
+		}
+	}
+	return matched
+}
+
+var recognizer = newRecognizer()
+
+type remedyName struct {
+	remedy       string // the remedy's key
+	abbreviation bool   // written with a trailing full stop, as "Nat. mur."
+}
+
+type dictionary struct {
+	remedies  map[string]remedyName // name key → remedy
+	latin     map[string]string     // remedy key → Latin name
+	maxLength int                   // most words in a name key
+
+	rubrics []*regexp.Regexp
+	systems []*regexp.Regexp
+}
+
+func newRecognizer() *dictionary {
+	d := &dictionary{remedies: make(map[string]remedyName), latin: make(map[string]string)}
+	for _, names := range RemedyNames {
+		remedy := db.SynonymKey(names[0])
+		d.latin[remedy] = names[0]
+		for _, name := range names {
+			key := db.SynonymKey(name)
+			d.remedies[key] = remedyName{remedy: remedy, abbreviation: strings.HasSuffix(name, ".")}
+			d.maxLength = max(d.maxLength, strings.Count(key, " ")+1)
+		}
+	}
+	for _, p := range rubrics {
+		d.rubrics = append(d.rubrics, regexp.MustCompile(`(?i)\b(?:`+p.words+`)\b`))
+	}
+	for _, p := range systems {
+		d.systems = append(d.systems, regexp.MustCompile(`(?i)\b(?:`+p.words+`)\b`))
+	}
+	return d
+}
+
+var wordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// repertoryRubric is a rubric written as its path in a repertory, "Mind; Fear; death, of":
+// the chapter names the body system and the next heading the rubric. Written this way
+// they count as mentioned twice, so a repertory excerpt is tagged with its rubrics.
+var repertoryRubric = regexp.MustCompile(`\b(\p{L}+(?: \p{L}+)?)\s*;\s*(\p{L}+(?: \p{L}+)?)`)
+
+// count returns how often text mentions each entity, and the entities in the order they
+// are first mentioned.
+func (d *dictionary) count(text string) (map[Entity]int, []Entity) {
+	counts := make(map[Entity]int)
+	var order []Entity
+	add := func(entity Entity, n int) {
+		if n == 0 {
+			return
+		}
+		if counts[entity] == 0 {
+			order = append(order, entity)
+		}
+		counts[entity] += n
+	}
+
+	d.findRemedies(text, func(entity Entity, _, _ int) { add(entity, 1) })
+
+	for _, match := range repertoryRubric.FindAllStringSubmatch(text, -1) {
+		chapter, rubric := strings.ToLower(match[1]), strings.ToLower(match[2])
+		if i := slices.IndexFunc(systems, func(p pattern) bool { return p.name == chapter }); i >= 0 {
+			add(Entity{Kind: KindSystem, Name: systems[i].name}, 1)
+			if name, ok := matchPattern(rubrics, d.rubrics, rubric); ok {
+				add(Entity{Kind: KindRubric, Name: name}, 1)
+			}
+		}
+	}
+
+	for i, p := range rubrics {
+		add(Entity{Kind: KindRubric, Name: p.name}, len(d.rubrics[i].FindAllStringIndex(text, -1)))
+	}
+	for i, p := range systems {
+		add(Entity{Kind: KindSystem, Name: p.name}, len(d.systems[i].FindAllStringIndex(text, -1)))
+	}
+	return counts, order
+}
+
+// findRemedies matches remedy names longest first, so "Arsenicum album" is one mention,
+// and calls found with the byte offsets of each. An abbreviation only counts capitalized
+// and followed by its full stop, so "Bell." is Belladonna but "a bell" is not.
+func (d *dictionary) findRemedies(text string, found func(entity Entity, start, end int)) {
+	spans := wordPattern.FindAllStringIndex(text, -1)
+	for i := 0; i < len(spans); {
+		n := min(d.maxLength, len(spans)-i)
+		for ; n > 0; n-- {
+			words := make([]string, n)
+			for j := range words {
+				words[j] = strings.ToLower(text[spans[i+j][0]:spans[i+j][1]])
+			}
+			name, ok := d.remedies[strings.Join(words, " ")]
+			if ok && (!name.abbreviation || abbreviated(text, spans[i], spans[i+n-1])) {
+				end := spans[i+n-1][1]
+				if name.abbreviation {
+					end++ // the full stop
+				}
+				found(Entity{Kind: KindRemedy, Name: name.remedy}, spans[i][0], end)
+				break
+			}
+		}
+		i += max(n, 1)
+	}
+}
+
+// abbreviated reports whether the words from first to last are capitalized and followed
+// by a full stop.
+func abbreviated(text string, first, last []int) bool {
+	return unicode.IsUpper(rune(text[first[0]])) && last[1] < len(text) && text[last[1]] == '.'
+}
+
+func matchPattern(patterns []pattern, compiled []*regexp.Regexp, text string) (string, bool) {
+	for i, re := range compiled {
+		if re.MatchString(text) {
+			return patterns[i].name, true
+		}
+	}
+	return "", false
+}
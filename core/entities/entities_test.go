@@ -0,0 +1,83 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tags(entities []Entity) []string {
+	var tags []string
+	for _, entity := range entities {
+		tags = append(tags, entity.Tag())
+	}
+	return tags
+}
+
+func TestRecognize(t *testing.T) {
+	assert.Equal(t,
+		[]string{"remedy:aconitum napellus", "remedy:arsenicum album", "rubric:fear", "rubric:restlessness", "system:mind"},
+		tags(Recognize("Aconite or Arsenicum album for sudden fear of death with a restless mind?")),
+		"Arsenicum album is one remedy, not Arsenic")
+
+	assert.Equal(t,
+		[]string{"remedy:natrum muriaticum", "remedy:belladonna"},
+		tags(Recognize("Nat. mur. follows Bell. well")),
+		"abbreviations are recognized with their full stop")
+	assert.Empty(t, Recognize("ring the bell, nat mur"), "but not as plain words")
+
+	assert.Empty(t, Recognize("What is the dose?"))
+}
+
+func TestRepertoryRubric(t *testing.T) {
+	assert.Equal(t,
+		[]string{"system:mind", "rubric:fear"},
+		tags(Recognize("Mind; Fear; death, of")))
+}
+
+func TestChunkTags(t *testing.T) {
+	text := "Great fear and anxiety. Fears death, predicts the hour. Aggravated in a warm room. Thirst for cold water. Compare Gels."
+	assert.Equal(t,
+		[]string{"remedy:aconitum napellus", "system:mind", "remedy:gelsemium sempervirens", "rubric:fear"},
+		ChunkTags([]string{"Aconitum Napellus", "Mind"}, text),
+		"anxiety and thirst are mentioned once, in passing")
+}
+
+func TestParseTag(t *testing.T) {
+	entity, ok := ParseTag("remedy:nux vomica")
+	assert.True(t, ok)
+	assert.Equal(t, Entity{Kind: KindRemedy, Name: "nux vomica"}, entity)
+
+	_, ok = ParseTag("nux vomica")
+	assert.False(t, ok)
+}
+
+func TestMatches(t *testing.T) {
+	chunk := []string{"remedy:aconitum napellus", "rubric:fear"}
+	assert.Equal(t, 2, Matches(chunk, Recognize("Aconite fear")))
+	assert.Equal(t, 1, Matches(chunk, Recognize("Aconite grief")))
+	assert.Zero(t, Matches(nil, Recognize("Aconite fear")))
+}
+
+func TestMentions(t *testing.T) {
+	text := "Sudden fear; compare Nat. mur. and Arsenicum album."
+	var found []string
+	for _, mention := range Mentions(text) {
+		found = append(found, mention.Tag()+"="+text[mention.Start:mention.End])
+	}
+	assert.Equal(t, []string{"rubric:fear=fear", "remedy:natrum muriaticum=Nat. mur.", "remedy:arsenicum album=Arsenicum album"}, found)
+
+	assert.Empty(t, Mentions("ring the bell"))
+}
+
+func TestRemedyName(t *testing.T) {
+	name, ok := RemedyName("NAT-M.")
+	assert.True(t, ok)
+	assert.Equal(t, "Natrum muriaticum", name)
+
+	name, _ = RemedyName("aconite")
+	assert.Equal(t, "Aconitum napellus", name)
+
+	_, ok = RemedyName("Zinc.")
+	assert.False(t, ok)
+}
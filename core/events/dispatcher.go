@@ -0,0 +1,239 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"go.uber.org/zap"
+	"google.golang.org/api/option"
+	"google.golang.org/api/pubsub/v1"
+)
+
+const (
+	deliveryAttempts = 3
+	deliveryTimeout  = 10 * time.Second
+
+	// Headers of webhook requests.
+	EventHeader     = "X-Webhook-Event"     // the event's type
+	IDHeader        = "X-Webhook-Id"        // the event's ID, the same on every retry
+	SignatureHeader = "X-Webhook-Signature" // "sha256=" and the hex HMAC of the body
+)
+
+// retryDelay is the wait before the second attempt at a delivery, doubled before each
+// one after.
+var retryDelay = time.Second
+
+var pubsubTopic = regexp.MustCompile(`^projects/[^/]+/topics/[^/]+$`)
+
+// Dispatcher sends events to the sinks in tenants' configs. Pub/Sub topics are
+// published to with Application Default Credentials, as GCS sources are read.
+type Dispatcher struct {
+	mongo   odm.MongoClient
+	client  *http.Client
+	pending sync.WaitGroup
+
+	pubsubOpts []option.ClientOption
+	mu         sync.Mutex
+	pubsub     *pubsub.Service // created on the first publish
+}
+
+func ProvideDispatcher(mongo odm.MongoClient) *Dispatcher {
+	return &Dispatcher{
+		mongo:  mongo,
+		client: &http.Client{Timeout: deliveryTimeout},
+	}
+}
+
+// Emit sends event to the sinks of its tenant that take its type. It returns at once
+// and delivers in the background, so a slow or failing sink never holds up ingestion;
+// a delivery that still fails after retries is logged.
+func (d *Dispatcher) Emit(ctx context.Context, event Event) {
+	d.pending.Add(1)
+	go func() {
+		defer d.pending.Done()
+
+		ctx := context.WithoutCancel(ctx)
+		sinks, err := d.tenantSinks(ctx, event.Tenant)
+		if err != nil {
+			logger.Error("Failed to load event sinks", zap.String("tenant", event.Tenant), zap.String("event", event.Type), zap.Error(err))
+			return
+		}
+		for _, err := range d.Send(ctx, sinks, event) {
+			logger.Error("Failed to deliver event", zap.String("tenant", event.Tenant), zap.String("event", event.Type),
+				zap.String("eventId", event.ID), zap.Error(err))
+		}
+	}()
+}
+
+// Wait blocks until every event emitted so far is delivered or given up on.
+func (d *Dispatcher) Wait() {
+	d.pending.Wait()
+}
+
+// Send delivers event to each of sinks that takes its type, retrying failures, and
+// returns an error for each sink it could not deliver to.
+func (d *Dispatcher) Send(ctx context.Context, sinks []db.EventSinkConfig, event Event) []error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return []error{errors.New("failed to encode event: " + err.Error())}
+	}
+
+	var failed []error
+	for _, sink := range sinks {
+		if len(sink.Events) > 0 && !slices.Contains(sink.Events, event.Type) {
+			continue
+		}
+		if err := d.deliver(ctx, sink, event, body); err != nil {
+			failed = append(failed, errors.New(sinkName(sink)+": "+err.Error()))
+		}
+	}
+	return failed
+}
+
+// deliver sends body to sink, retrying errors that may pass.
+func (d *Dispatcher) deliver(ctx context.Context, sink db.EventSinkConfig, event Event, body []byte) error {
+	var send func(context.Context) (retry bool, err error)
+	switch sink.Kind {
+	case db.EventSinkWebhook:
+		if u, err := url.Parse(sink.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("url must be an http or https URL")
+		}
+		send = func(ctx context.Context) (bool, error) { return d.postWebhook(ctx, sink, event, body) }
+	case db.EventSinkPubSub:
+		if !pubsubTopic.MatchString(sink.Topic) {
+			return errors.New("topic must be projects/PROJECT/topics/TOPIC")
+		}
+		send = func(ctx context.Context) (bool, error) { return true, d.publish(ctx, sink.Topic, event, body) }
+	default:
+		return errors.New("unknown kind " + strconv.Quote(sink.Kind))
+	}
+
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+		retry, err := send(attemptCtx)
+		cancel()
+		if err == nil || !retry || attempt == deliveryAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// postWebhook posts body to the sink's URL. Client errors other than timeouts and rate
+// limits won't pass on retry.
+func (d *Dispatcher) postWebhook(ctx context.Context, sink db.EventSinkConfig, event Event, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sink.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event.Type)
+	req.Header.Set(IDHeader, event.ID)
+	if sink.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(sink.Secret, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+	return retry, errors.New("webhook responded " + resp.Status)
+}
+
+// publish sends body to the Pub/Sub topic, with the event's type, tenant and ID as
+// message attributes subscriptions can filter on.
+func (d *Dispatcher) publish(ctx context.Context, topic string, event Event, body []byte) error {
+	service, err := d.pubsubService(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = service.Projects.Topics.Publish(topic, &pubsub.PublishRequest{
+		Messages: []*pubsub.PubsubMessage{{
+			Data:       base64.StdEncoding.EncodeToString(body),
+			Attributes: map[string]string{"type": event.Type, "tenant": event.Tenant, "id": event.ID},
+		}},
+	}).Context(ctx).Do()
+	return err
+}
+
+func (d *Dispatcher) pubsubService(ctx context.Context) (*pubsub.Service, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.pubsub == nil {
+		// the service outlives the event that creates it
+		service, err := pubsub.NewService(context.WithoutCancel(ctx), d.pubsubOpts...)
+		if err != nil {
+			return nil, errors.New("failed to create Pub/Sub client: " + err.Error())
+		}
+		d.pubsub = service
+	}
+	return d.pubsub, nil
+}
+
+// tenantSinks reads the event sinks in the tenant's config.
+func (d *Dispatcher) tenantSinks(ctx context.Context, tenant string) ([]db.EventSinkConfig, error) {
+	repo := odm.CollectionOf[db.TenantConfigModel](d.mongo, tenant)
+	exists, err := async.Await(repo.Exists(ctx, db.TenantConfigID))
+	if err != nil || !exists {
+		return nil, err
+	}
+	config, err := async.Await(repo.FindOneByID(ctx, db.TenantConfigID))
+	if err != nil {
+		return nil, err
+	}
+	return config.EventSinks, nil
+}
+
+// Sign returns the X-Webhook-Signature of body for secret, which receivers compare to
+// the header to check a request is genuine.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// sinkName identifies a sink in errors without its secret.
+func sinkName(sink db.EventSinkConfig) string {
+	switch sink.Kind {
+	case db.EventSinkWebhook:
+		if u, err := url.Parse(sink.URL); err == nil {
+			return "webhook " + u.Scheme + "://" + u.Host
+		}
+		return "webhook"
+	case db.EventSinkPubSub:
+		return "pubsub " + sink.Topic
+	}
+	return sink.Kind
+}
@@ -0,0 +1,120 @@
+package events
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+func TestSendWebhook(t *testing.T) {
+	retryDelay = time.Millisecond
+
+	var mu sync.Mutex
+	received := map[string][]Event{}
+	failures := map[string]int{"/flaky": 1, "/down": 10}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/signed" {
+			assert.Equal(t, Sign("s3cret", body), r.Header.Get(SignatureHeader))
+		} else {
+			assert.Empty(t, r.Header.Get(SignatureHeader))
+		}
+		switch {
+		case r.URL.Path == "/rejects":
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		case failures[r.URL.Path] > 0:
+			failures[r.URL.Path]--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var event Event
+		require.NoError(t, json.Unmarshal(body, &event))
+		assert.Equal(t, event.Type, r.Header.Get(EventHeader))
+		assert.Equal(t, event.ID, r.Header.Get(IDHeader))
+		received[r.URL.Path] = append(received[r.URL.Path], event)
+	}))
+	defer server.Close()
+
+	d := ProvideDispatcher(nil)
+	sinks := []db.EventSinkConfig{
+		{Kind: db.EventSinkWebhook, URL: server.URL + "/signed", Secret: "s3cret"},
+		{Kind: db.EventSinkWebhook, URL: server.URL + "/indexed", Events: []string{DocumentIndexed}},
+		{Kind: db.EventSinkWebhook, URL: server.URL + "/flaky"},
+		{Kind: db.EventSinkWebhook, URL: server.URL + "/down"},
+		{Kind: db.EventSinkWebhook, URL: server.URL + "/rejects"},
+		{Kind: db.EventSinkWebhook, URL: "ftp://example.org/events"},
+		{Kind: "sqs"},
+	}
+
+	job := Job{ID: "job-1", Kind: JobIngestion, Source: "uploads/kent.pdf", Status: db.IngestionJobQueued}
+	failed := d.Send(context.Background(), sinks, New(JobStarted, "healthcare", job))
+	require.Len(t, failed, 4)
+	assert.ErrorContains(t, failed[0], "webhook "+server.URL+": webhook responded 503")
+	assert.ErrorContains(t, failed[1], "responded 400")
+	assert.ErrorContains(t, failed[2], "http or https")
+	assert.EqualError(t, failed[3], `sqs: unknown kind "sqs"`)
+
+	indexed := New(DocumentIndexed, "healthcare", job)
+	indexed.Document = &Document{SourceURI: "uploads/kent.pdf", Chunks: 12, Embedded: 12}
+	d.Send(context.Background(), sinks[:2], indexed)
+
+	assert.Len(t, received["/signed"], 2)
+	require.Len(t, received["/indexed"], 1, "takes only document.indexed")
+	assert.Equal(t, "uploads/kent.pdf", received["/indexed"][0].Document.SourceURI)
+	assert.Equal(t, "healthcare", received["/indexed"][0].Tenant)
+	require.Len(t, received["/flaky"], 1, "retried after a server error")
+	assert.Equal(t, job, received["/flaky"][0].Job)
+	assert.Equal(t, 10-deliveryAttempts, failures["/down"], "gives up after the last attempt")
+}
+
+func TestSendPubSub(t *testing.T) {
+	var published struct {
+		Messages []struct {
+			Data       string            `json:"data"`
+			Attributes map[string]string `json:"attributes"`
+		} `json:"messages"`
+	}
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&published))
+		w.Write([]byte(`{"messageIds": ["1"]}`))
+	}))
+	defer server.Close()
+
+	d := ProvideDispatcher(nil)
+	d.pubsubOpts = []option.ClientOption{option.WithEndpoint(server.URL), option.WithoutAuthentication()}
+	event := New(JobCompleted, "healthcare", Job{ID: "run-1", Kind: JobSync, Source: "library", Status: db.SyncRunDone, Ingested: 3})
+
+	failed := d.Send(context.Background(), []db.EventSinkConfig{
+		{Kind: db.EventSinkPubSub, Topic: "projects/clinic/topics/ingestion"},
+		{Kind: db.EventSinkPubSub, Topic: "ingestion"},
+	}, event)
+	require.Len(t, failed, 1)
+	assert.ErrorContains(t, failed[0], "projects/PROJECT/topics/TOPIC")
+
+	assert.Equal(t, "/v1/projects/clinic/topics/ingestion:publish", path)
+	require.Len(t, published.Messages, 1)
+	assert.Equal(t, map[string]string{"type": JobCompleted, "tenant": "healthcare", "id": event.ID}, published.Messages[0].Attributes)
+	data, err := base64.StdEncoding.DecodeString(published.Messages[0].Data)
+	require.NoError(t, err)
+	var sent Event
+	require.NoError(t, json.Unmarshal(data, &sent))
+	assert.Equal(t, event, sent)
+}
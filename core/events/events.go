@@ -0,0 +1,93 @@
+// Package events tells external systems about ingestion as it happens: jobs starting,
+// finishing and failing, and documents being indexed. Events go to the webhooks and
+// Pub/Sub topics in each tenant's config, so downstream workflows can start as soon as
+// new knowledge becomes searchable.
+package events
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/medicine-rag/core/ingest"
+)
+
+// Event types.
+const (
+	JobStarted      = "job.started"
+	JobCompleted    = "job.completed"
+	JobFailed       = "job.failed"
+	DocumentIndexed = "document.indexed" // its chunks are published and embedded
+)
+
+// Types lists the event types, in the order a job emits them.
+var Types = []string{JobStarted, DocumentIndexed, JobCompleted, JobFailed}
+
+// Kinds of job.
+const (
+	JobIngestion = "ingestion" // a document submitted through the Ingestion API
+	JobSync      = "sync"      // a re-scan of a source in the tenant's config
+	JobCLI       = "cli"       // a run of the ingest command
+)
+
+// Event is the JSON body sent to sinks.
+type Event struct {
+	ID       string    `json:"id"`
+	Type     string    `json:"type"`
+	Tenant   string    `json:"tenant"`
+	Time     int64     `json:"time"` // unix milliseconds; events may arrive out of order
+	Job      Job       `json:"job"`
+	Document *Document `json:"document,omitempty"` // document.indexed only
+}
+
+// Job is the job an event belongs to. Counts are set once it is done.
+type Job struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind"`
+	Source string `json:"source"` // source URI of an ingestion job, source name of a sync
+	Status string `json:"status"` // as db.IngestionJobModel's or db.SyncRunModel's
+	Error  string `json:"error,omitempty"`
+
+	DuplicateOf string `json:"duplicateOf,omitempty"` // the source an ingestion job's document copies
+
+	Ingested int `json:"ingested,omitempty"` // documents, for syncs and CLI runs
+	Failed   int `json:"failed,omitempty"`   // documents, for syncs and CLI runs
+	Chunks   int `json:"chunks,omitempty"`
+	Embedded int `json:"embedded,omitempty"`
+}
+
+// Document is a document that became searchable.
+type Document struct {
+	SourceURI string `json:"sourceUri"`
+	Chunks    int    `json:"chunks"`   // published by this job
+	Embedded  int    `json:"embedded"` // vectors saved by this job
+	TableRows int    `json:"tableRows,omitempty"`
+	Figures   int    `json:"figures,omitempty"`
+	// Quarantined chunks are held back for review; see ingest.ScoreChunks.
+	Quarantined int `json:"quarantined,omitempty"`
+}
+
+// New returns an event of the type for the job, with a unique ID.
+func New(eventType, tenant string, job Job) Event {
+	now := time.Now()
+	id, _ := odm.HashedKey(tenant, eventType, job.Kind, job.ID, strconv.FormatInt(now.UnixNano(), 10))
+	return Event{ID: id, Type: eventType, Tenant: tenant, Time: now.UnixMilli(), Job: job}
+}
+
+// IndexedHook returns an ingest.Pipeline Indexed hook that emits a document.indexed
+// event of the job for each document.
+func (d *Dispatcher) IndexedHook(tenant string, job Job) func(ctx context.Context, sourceUri string, document ingest.Report) {
+	return func(ctx context.Context, sourceUri string, document ingest.Report) {
+		event := New(DocumentIndexed, tenant, job)
+		event.Document = &Document{
+			SourceURI:   sourceUri,
+			Chunks:      document.Chunks,
+			Embedded:    document.Embedded,
+			TableRows:   document.TableRows,
+			Figures:     document.Figures,
+			Quarantined: document.Quarantined,
+		}
+		d.Emit(ctx, event)
+	}
+}
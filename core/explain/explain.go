@@ -0,0 +1,170 @@
+// Package explain runs a turn as a dry run for debugging retrieval. Tools are selected
+// and run as usual, but the big model is replaced by a Plan that writes out the tool
+// calls, their queries and the sources they retrieved instead of an answer.
+package explain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/medicine-rag/core/fanout"
+	"github.com/ollama/ollama/api"
+)
+
+// MetadataKey is the GenerateAnswerRequest metadata entry that asks for a dry run.
+const MetadataKey = "explain"
+
+// ModelName stands in for the answer model in traces and completion metadata.
+const ModelName = "explain"
+
+// Requested reports whether metadata asks for a dry run.
+func Requested(metadata map[string]string) bool {
+	requested, _ := strconv.ParseBool(metadata[MetadataKey])
+	return requested
+}
+
+// Source is a result a tool call retrieved.
+type Source struct {
+	Title       string
+	Attribution string
+}
+
+// Call is one tool call and what it retrieved.
+type Call struct {
+	Tool      string
+	Arguments api.ToolCallFunctionArguments
+	Sources   []Source
+	Errors    []string
+}
+
+// Plan records the tool calls of a turn in the order they start.
+type Plan struct {
+	mu    sync.Mutex
+	calls []*Call
+}
+
+func NewPlan() *Plan {
+	return &Plan{}
+}
+
+// Tool records each call of handler and the results it produces. Wrapped inside
+// fanout.Tool, each query of a folded call is recorded on its own.
+func (p *Plan) Tool(name string, handler fanout.Handler) fanout.Handler {
+	return func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+		call := &Call{Tool: name, Arguments: params}
+		p.mu.Lock()
+		p.calls = append(p.calls, call)
+		p.mu.Unlock()
+
+		in := handler(ctx, params)
+		out := make(chan *schema.ToolResultChunk)
+		go func() {
+			defer close(out)
+			for chunk := range in {
+				if chunk != nil {
+					p.mu.Lock()
+					if chunk.Error != "" {
+						call.Errors = append(call.Errors, chunk.Error)
+					} else {
+						call.Sources = append(call.Sources, Source{Title: chunk.Title, Attribution: chunk.Attribution})
+					}
+					p.mu.Unlock()
+				}
+				select {
+				case out <- chunk:
+				case <-ctx.Done():
+				}
+			}
+		}()
+		return out
+	}
+}
+
+// Calls returns the recorded calls.
+func (p *Plan) Calls() []Call {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	calls := make([]Call, len(p.calls))
+	for i, call := range p.calls {
+		calls[i] = *call
+		calls[i].Sources = slices.Clone(call.Sources)
+		calls[i].Errors = slices.Clone(call.Errors)
+	}
+	return calls
+}
+
+// Markdown describes the recorded calls for the chat window.
+func (p *Plan) Markdown() string {
+	calls := p.Calls()
+
+	var b strings.Builder
+	b.WriteString("**Dry run:** no answer was generated.\n\n")
+	if len(calls) == 0 {
+		b.WriteString("No tools were selected for this question.")
+		return b.String()
+	}
+
+	b.WriteString("### Tool calls\n")
+	for i, call := range calls {
+		fmt.Fprintf(&b, "\n%d. `%s` %s: %s\n", i+1, call.Tool, formatArguments(call.Arguments), pluralize(len(call.Sources), "result"))
+		for _, source := range call.Sources {
+			b.WriteString("   - " + source.Title)
+			if source.Attribution != "" {
+				b.WriteString(" (" + source.Attribution + ")")
+			}
+			b.WriteString("\n")
+		}
+		for _, err := range call.Errors {
+			b.WriteString("   - error: " + err + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Model takes the big model's place: instead of answering, it writes the plan.
+func (p *Plan) Model() llm.LLMClient {
+	return &planClient{plan: p}
+}
+
+type planClient struct {
+	plan *Plan
+}
+
+func (c *planClient) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	return callback(c.plan.Markdown())
+}
+
+func (c *planClient) GenerateInferenceWithTools(ctx context.Context, messages []llm.Message, contentCallback func(chunk string) error, toolCallback func(toolCalls []api.ToolCall) error, opts ...llm.LLMOption) error {
+	return errors.New("a dry run does not call tools")
+}
+
+func (c *planClient) Capabilities() llm.Capability { return 0 }
+
+func (c *planClient) GetModel() string { return ModelName }
+
+// formatArguments lists a call's arguments by name, as query="..." or remedies=[...].
+func formatArguments(args api.ToolCallFunctionArguments) string {
+	parts := make([]string, 0, len(args))
+	for _, name := range slices.Sorted(maps.Keys(args)) {
+		value, _ := json.Marshal(args[name])
+		parts = append(parts, name+"="+string(value))
+	}
+	return strings.Join(parts, " ")
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
+}
@@ -0,0 +1,69 @@
+package explain
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/medicine-rag/core/fanout"
+	"github.com/ollama/ollama/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func results(chunks ...*schema.ToolResultChunk) fanout.Handler {
+	return func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+		out := make(chan *schema.ToolResultChunk, len(chunks))
+		for _, chunk := range chunks {
+			out <- chunk
+		}
+		close(out)
+		return out
+	}
+}
+
+func drain(ch <-chan *schema.ToolResultChunk) int {
+	n := 0
+	for range ch {
+		n++
+	}
+	return n
+}
+
+func TestRequested(t *testing.T) {
+	assert.True(t, Requested(map[string]string{MetadataKey: "true"}))
+	assert.False(t, Requested(map[string]string{MetadataKey: "no"}))
+	assert.False(t, Requested(nil))
+}
+
+func TestPlan(t *testing.T) {
+	plan := NewPlan()
+	search := plan.Tool("search", results(
+		&schema.ToolResultChunk{Title: "Arnica", Attribution: "boericke.pdf"},
+		&schema.ToolResultChunk{Title: "Bellis perennis"},
+	))
+	profile := plan.Tool("remedy_profile", results(&schema.ToolResultChunk{Error: "no such remedy"}))
+
+	assert.Equal(t, 2, drain(search(t.Context(), api.ToolCallFunctionArguments{"query": "bruising"})), "results pass through")
+	drain(profile(t.Context(), api.ToolCallFunctionArguments{"remedies": []string{"Arnicaa"}}))
+
+	calls := plan.Calls()
+	require.Len(t, calls, 2)
+	assert.Equal(t, []Source{{Title: "Arnica", Attribution: "boericke.pdf"}, {Title: "Bellis perennis"}}, calls[0].Sources)
+	assert.Equal(t, []string{"no such remedy"}, calls[1].Errors)
+
+	var answer strings.Builder
+	require.NoError(t, plan.Model().GenerateInference(t.Context(), nil, func(chunk string) error {
+		answer.WriteString(chunk)
+		return nil
+	}))
+	assert.Contains(t, answer.String(), "1. `search` query=\"bruising\": 2 results")
+	assert.Contains(t, answer.String(), "   - Arnica (boericke.pdf)")
+	assert.Contains(t, answer.String(), "2. `remedy_profile` remedies=[\"Arnicaa\"]: 0 results")
+	assert.Contains(t, answer.String(), "   - error: no such remedy")
+}
+
+func TestPlanWithoutTools(t *testing.T) {
+	assert.Contains(t, NewPlan().Markdown(), "No tools were selected")
+}
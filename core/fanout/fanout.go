@@ -0,0 +1,153 @@
+// Package fanout runs the queries of one tool selection concurrently. agent-boot runs
+// the tool calls of a turn one after another, so Selector folds several calls to the
+// same tool into a single call carrying all their arguments, and Tool runs them side by
+// side and merges their results, dropping sections more than one query found, before
+// agent-boot summarizes them.
+package fanout
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/ollama/ollama/api"
+)
+
+// CallsParam holds the arguments of each folded call.
+const CallsParam = "calls"
+
+type Handler = func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk
+
+// Selector folds the calls to each of tools that a selection issues more than once into
+// one call, dropping repeats of the same arguments. Other tools' calls pass unchanged.
+func Selector(client llm.LLMClient, tools ...string) llm.LLMClient {
+	return &selectorClient{LLMClient: client, tools: tools}
+}
+
+type selectorClient struct {
+	llm.LLMClient
+	tools []string
+}
+
+func (c *selectorClient) GenerateInferenceWithTools(ctx context.Context, messages []llm.Message, contentCallback func(chunk string) error, toolCallback func(toolCalls []api.ToolCall) error, opts ...llm.LLMOption) error {
+	var calls []api.ToolCall
+	err := c.LLMClient.GenerateInferenceWithTools(ctx, messages, contentCallback, func(toolCalls []api.ToolCall) error {
+		calls = append(calls, toolCalls...)
+		return nil
+	}, opts...)
+	if err != nil {
+		return err
+	}
+	if len(calls) > 0 && toolCallback != nil {
+		return toolCallback(Fold(calls, c.tools...))
+	}
+	return nil
+}
+
+// Fold merges the calls to each of tools into one call at the position of the first,
+// keeping a tool called once as it is.
+func Fold(calls []api.ToolCall, tools ...string) []api.ToolCall {
+	folded := make([]api.ToolCall, 0, len(calls))
+	grouped := make(map[string][]api.ToolCallFunctionArguments)
+	position := make(map[string]int)
+	seen := make(map[string]bool)
+	for _, call := range calls {
+		name := call.Function.Name
+		if !slices.Contains(tools, name) {
+			folded = append(folded, call)
+			continue
+		}
+
+		key, _ := json.Marshal(call.Function.Arguments)
+		if seen[name+string(key)] {
+			continue
+		}
+		seen[name+string(key)] = true
+
+		if _, ok := position[name]; !ok {
+			position[name] = len(folded)
+			folded = append(folded, call)
+		}
+		grouped[name] = append(grouped[name], call.Function.Arguments)
+	}
+
+	for name, args := range grouped {
+		if len(args) > 1 {
+			folded[position[name]].Function.Arguments = api.ToolCallFunctionArguments{CallsParam: args}
+		}
+	}
+	return folded
+}
+
+// Tool runs a folded call's queries through handler, at most limit at a time, and sends
+// their results in query order, each section once. A call that was not folded is passed
+// to handler as it is.
+func Tool(handler Handler, limit int) Handler {
+	return func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+		calls, ok := params[CallsParam].([]api.ToolCallFunctionArguments)
+		if !ok {
+			return handler(ctx, params)
+		}
+
+		// every query's results are collected as they arrive, so no query waits on
+		// the ones before it to be read
+		results := make([][]*schema.ToolResultChunk, len(calls))
+		done := make([]chan struct{}, len(calls))
+		slots := make(chan struct{}, max(limit, 1))
+		for i, call := range calls {
+			done[i] = make(chan struct{})
+			go func() {
+				defer close(done[i])
+				select {
+				case slots <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				defer func() { <-slots }()
+
+				for chunk := range handler(ctx, call) {
+					results[i] = append(results[i], chunk)
+				}
+			}()
+		}
+
+		out := make(chan *schema.ToolResultChunk)
+		go func() {
+			defer close(out)
+
+			seen := make(map[string]bool)
+			for i := range calls {
+				<-done[i]
+				for _, chunk := range results[i] {
+					if key := Key(chunk); key != "" {
+						if seen[key] {
+							continue
+						}
+						seen[key] = true
+					}
+
+					select {
+					case out <- chunk:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+		return out
+	}
+}
+
+// Key identifies the section or record a result carries, or is "" for results that
+// are never merged, such as errors.
+func Key(chunk *schema.ToolResultChunk) string {
+	if chunk.Error != "" {
+		return ""
+	}
+	if id := chunk.Metadata["sectionId"]; id != "" {
+		return id
+	}
+	return chunk.Id
+}
@@ -0,0 +1,140 @@
+package fanout
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/ollama/ollama/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func call(name string, args api.ToolCallFunctionArguments) api.ToolCall {
+	return api.ToolCall{Function: api.ToolCallFunction{Name: name, Arguments: args}}
+}
+
+type selectingModel struct {
+	llm.LLMClient
+	calls []api.ToolCall
+}
+
+func (m *selectingModel) GenerateInferenceWithTools(ctx context.Context, messages []llm.Message, contentCallback func(chunk string) error, toolCallback func(toolCalls []api.ToolCall) error, opts ...llm.LLMOption) error {
+	// calls may arrive over several callbacks
+	for _, c := range m.calls {
+		if err := toolCallback([]api.ToolCall{c}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestFold(t *testing.T) {
+	calls := []api.ToolCall{
+		call("search", api.ToolCallFunctionArguments{"query": "arnica bruising"}),
+		call("calculator", api.ToolCallFunctionArguments{"potencies": []string{"30C"}}),
+		call("search", api.ToolCallFunctionArguments{"query": "bellis perennis"}),
+		call("search", api.ToolCallFunctionArguments{"query": "arnica bruising"}),
+	}
+
+	folded := Fold(calls, "search")
+	require.Len(t, folded, 2)
+	assert.Equal(t, "search", folded[0].Function.Name)
+	assert.Equal(t, []api.ToolCallFunctionArguments{
+		{"query": "arnica bruising"},
+		{"query": "bellis perennis"},
+	}, folded[0].Function.Arguments[CallsParam], "repeated queries are dropped")
+	assert.Equal(t, calls[1], folded[1])
+
+	single := Fold(calls[:2], "search")
+	assert.Equal(t, calls[:2], single, "a tool called once is left as it is")
+}
+
+func TestSelector(t *testing.T) {
+	model := &selectingModel{calls: []api.ToolCall{
+		call("search", api.ToolCallFunctionArguments{"query": "a"}),
+		call("search", api.ToolCallFunctionArguments{"query": "b"}),
+	}}
+
+	var selected []api.ToolCall
+	err := Selector(model, "search").GenerateInferenceWithTools(t.Context(), nil, nil, func(toolCalls []api.ToolCall) error {
+		selected = append(selected, toolCalls...)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, selected, 1)
+	assert.Len(t, selected[0].Function.Arguments[CallsParam], 2)
+}
+
+func results(chunks ...*schema.ToolResultChunk) <-chan *schema.ToolResultChunk {
+	out := make(chan *schema.ToolResultChunk, len(chunks))
+	for _, chunk := range chunks {
+		out <- chunk
+	}
+	close(out)
+	return out
+}
+
+func section(id string) *schema.ToolResultChunk {
+	return &schema.ToolResultChunk{Title: id, Metadata: map[string]string{"sectionId": id}}
+}
+
+func TestToolMergesInQueryOrder(t *testing.T) {
+	handler := func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+		switch params["query"] {
+		case "a":
+			// the first query finishes last
+			time.Sleep(20 * time.Millisecond)
+			return results(section("s1"), section("s2"))
+		default:
+			return results(section("s2"), section("s3"), &schema.ToolResultChunk{Error: "failed"}, &schema.ToolResultChunk{Error: "failed"})
+		}
+	}
+
+	var got []string
+	for chunk := range Tool(handler, 2)(t.Context(), api.ToolCallFunctionArguments{
+		CallsParam: []api.ToolCallFunctionArguments{{"query": "a"}, {"query": "b"}},
+	}) {
+		got = append(got, chunk.Title+chunk.Error)
+	}
+	assert.Equal(t, []string{"s1", "s2", "s3", "failed", "failed"}, got, "sections are sent once, errors always")
+}
+
+func TestToolBoundsParallelism(t *testing.T) {
+	var running, peak atomic.Int32
+	handler := func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+		now := running.Add(1)
+		for {
+			old := peak.Load()
+			if now <= old || peak.CompareAndSwap(old, now) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+		return results(section(params["query"].(string)))
+	}
+
+	var calls []api.ToolCallFunctionArguments
+	for _, query := range []string{"a", "b", "c", "d", "e", "f"} {
+		calls = append(calls, api.ToolCallFunctionArguments{"query": query})
+	}
+	count := 0
+	for range Tool(handler, 2)(t.Context(), api.ToolCallFunctionArguments{CallsParam: calls}) {
+		count++
+	}
+	assert.Equal(t, 6, count)
+	assert.Equal(t, int32(2), peak.Load())
+}
+
+func TestToolPassesSingleCalls(t *testing.T) {
+	handler := func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+		return results(section(params["query"].(string)))
+	}
+
+	chunk := <-Tool(handler, 4)(t.Context(), api.ToolCallFunctionArguments{"query": "a"})
+	assert.Equal(t, "a", chunk.Title)
+}
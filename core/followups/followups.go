@@ -0,0 +1,134 @@
+// Package followups suggests questions a user could ask next, grounded in the sources
+// the answer was generated from, so every suggestion is one the knowledge base can answer.
+package followups
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/SaiNageswarS/agent-boot/agentboot"
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/medicine-rag/core/guardrails"
+	"github.com/SaiNageswarS/medicine-rag/core/llmrouter"
+)
+
+// ToolName marks the tool result chunk that carries the suggestions. agent-boot's stream
+// has no chunk type of its own for them, so they travel as a result of this pseudo-tool,
+// sent after the StreamComplete.
+const ToolName = "follow_up_questions"
+
+const (
+	// MaxQuestionLength bounds each suggestion, in characters.
+	MaxQuestionLength = 150
+
+	// the prompt holds at most this many sources, each cut to maxSourceLength characters
+	maxSources      = 6
+	maxSourceLength = 1500
+	// the answer is cut to this many characters in the prompt
+	maxAnswerLength = 3000
+
+	suggestTimeout = 20 * time.Second
+)
+
+const suggestSystemPrompt = "You suggest follow-up questions for a physician using a homeopathy knowledge base. " +
+	"Each question must be answerable from the given sources, must not repeat the original question, " +
+	"and must be short and self-contained. Reply with one question per line and nothing else."
+
+// Suggest asks model for up to count follow-up questions to question and its answer,
+// grounded in sources. It returns none when there are no sources to ground them in.
+func Suggest(ctx context.Context, model llm.LLMClient, question, answer string, sources []guardrails.Source, count int) ([]string, error) {
+	if count <= 0 || len(sources) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, suggestTimeout)
+	defer cancel()
+
+	var prompt strings.Builder
+	prompt.WriteString("Sources:\n")
+	seen := make(map[string]bool)
+	for _, source := range sources {
+		if len(seen) == maxSources {
+			break
+		}
+		if seen[source.ID] {
+			continue
+		}
+		seen[source.ID] = true
+		prompt.WriteString("\n### " + source.Title + "\n")
+		prompt.WriteString(truncate(strings.Join(source.Sentences, " "), maxSourceLength) + "\n")
+	}
+	prompt.WriteString("\nOriginal question: " + question + "\n")
+	prompt.WriteString("\nAnswer given:\n" + truncate(answer, maxAnswerLength) + "\n")
+	prompt.WriteString("\nSuggest " + strconv.Itoa(count) + " follow-up questions.")
+
+	var reply strings.Builder
+	err := model.GenerateInference(ctx,
+		[]llm.Message{{Role: "user", Content: prompt.String()}},
+		func(chunk string) error {
+			reply.WriteString(chunk)
+			return nil
+		},
+		llmrouter.WithSystemPrompt(suggestSystemPrompt),
+		llmrouter.WithTemperature(0.3),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(reply.String(), question, count), nil
+}
+
+var listMarker = regexp.MustCompile(`^\s*(?:[-*•]\s*|\d+[.)]\s*|Q\d*[:.]\s*)`)
+
+// Parse reads one question per line, dropping list markers, blank lines, lines that are
+// not questions and repeats of the original question.
+func Parse(reply, question string, count int) []string {
+	var questions []string
+	seen := map[string]bool{normalize(question): true}
+	for _, line := range strings.Split(reply, "\n") {
+		line = strings.Trim(strings.TrimSpace(listMarker.ReplaceAllString(line, "")), `"*`)
+		if !strings.HasSuffix(line, "?") && !strings.HasSuffix(line, "？") && !strings.HasSuffix(line, "؟") {
+			continue
+		}
+		if utf8.RuneCountInString(line) > MaxQuestionLength {
+			continue
+		}
+		if key := normalize(line); !seen[key] {
+			seen[key] = true
+			questions = append(questions, line)
+		}
+		if len(questions) == count {
+			break
+		}
+	}
+	return questions
+}
+
+// Chunk is the stream chunk carrying the suggestions.
+func Chunk(questions []string) *schema.AgentStreamChunk {
+	return agentboot.NewToolExecutionResult(ToolName, &schema.ToolResultChunk{
+		ToolName:  ToolName,
+		Title:     "Suggested follow-up questions",
+		Sentences: questions,
+	})
+}
+
+func normalize(question string) string {
+	return strings.ToLower(strings.Join(strings.FieldsFunc(question, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " "))
+}
+
+func truncate(s string, length int) string {
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+	return string(runes[:length]) + "…"
+}
@@ -0,0 +1,69 @@
+package followups
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/medicine-rag/core/guardrails"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type suggestingModel struct {
+	llm.LLMClient
+	reply  string
+	prompt string
+	calls  int
+}
+
+func (m *suggestingModel) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	m.calls++
+	m.prompt = messages[len(messages)-1].Content
+	return callback(m.reply)
+}
+
+func TestParse(t *testing.T) {
+	reply := "Here are some questions:\n" +
+		"1. Which potency of Arnica suits a fresh bruise?\n" +
+		"- **How often should Arnica be repeated?**\n" +
+		"\n" +
+		"3) Which remedy helps bruising after injury?\n" +
+		"Q4: Does Arnica help muscle soreness after exercise?\n" +
+		"5. How often should Arnica be repeated?\n"
+
+	assert.Equal(t, []string{
+		"Which potency of Arnica suits a fresh bruise?",
+		"How often should Arnica be repeated?",
+		"Does Arnica help muscle soreness after exercise?",
+	}, Parse(reply, "Which remedy helps bruising after injury", 3), "the original question and repeats are dropped")
+
+	assert.Len(t, Parse(reply, "", 1), 1)
+}
+
+func TestSuggest(t *testing.T) {
+	sources := []guardrails.Source{
+		{ID: "arnica", Title: "Arnica", Sentences: []string{"Arnica helps bruising and soreness after injury."}},
+		{ID: "arnica", Title: "Arnica", Sentences: []string{"Arnica helps bruising and soreness after injury."}},
+	}
+	model := &suggestingModel{reply: "Which potency of Arnica suits a fresh bruise?\nHow often should Arnica be repeated?"}
+
+	questions, err := Suggest(t.Context(), model, "What helps bruising?", "Arnica.", sources, 3)
+	require.NoError(t, err)
+	assert.Len(t, questions, 2)
+	assert.Contains(t, model.prompt, "### Arnica\nArnica helps bruising")
+	assert.Equal(t, 1, strings.Count(model.prompt, "### Arnica"), "each source is listed once")
+
+	questions, err = Suggest(t.Context(), model, "Make it shorter", "Arnica.", nil, 3)
+	require.NoError(t, err)
+	assert.Empty(t, questions)
+	assert.Equal(t, 1, model.calls, "nothing to ground suggestions in")
+}
+
+func TestChunk(t *testing.T) {
+	result := Chunk([]string{"How often should Arnica be repeated?"}).GetToolResultChunk()
+	require.NotNil(t, result)
+	assert.Equal(t, ToolName, result.ToolName)
+	assert.Equal(t, []string{"How often should Arnica be repeated?"}, result.Sentences)
+}
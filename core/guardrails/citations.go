@@ -0,0 +1,251 @@
+package guardrails
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/SaiNageswarS/agent-boot/schema"
+)
+
+// CitationMode decides what happens to answer sentences that none of the retrieved
+// tool results support.
+type CitationMode string
+
+const (
+	// CitationOff streams the answer as generated.
+	CitationOff CitationMode = "off"
+	// CitationFlag keeps unsupported sentences and marks them in the answer.
+	CitationFlag CitationMode = "flag"
+	// CitationDrop removes unsupported sentences from the answer.
+	CitationDrop CitationMode = "drop"
+)
+
+// ParseCitationMode falls back to CitationOff for empty or unknown values.
+func ParseCitationMode(value string) CitationMode {
+	switch mode := CitationMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case CitationFlag, CitationDrop:
+		return mode
+	default:
+		return CitationOff
+	}
+}
+
+// CitationInstruction is added to the system prompt in strict citation modes.
+const CitationInstruction = "Every sentence of your answer must be supported by the search results. " +
+	"Do not add facts, remedies, potencies or advice that the search results do not contain; " +
+	"if they do not answer the question, say so."
+
+const unsupportedMarker = " _(not found in the sources)_"
+
+const (
+	// share of a sentence's content words a single source must contain to support it
+	minSupport = 0.6
+	// sentences with fewer content words are connective text and are not checked
+	minClaimWords = 4
+	// at most this many sources are attributed to a sentence
+	maxAttributions = 3
+)
+
+// Source is a retrieved tool result an answer may be attributed to.
+type Source struct {
+	ID          string
+	Title       string
+	Attribution string
+	Sentences   []string
+}
+
+// SourceFromToolResult identifies a tool result by the section it came from. agent-boot
+// drops the result ID when it summarizes a result but keeps its metadata.
+func SourceFromToolResult(result *schema.ToolResultChunk) Source {
+	id := result.Metadata["sectionId"]
+	for _, fallback := range []string{result.Id, result.Attribution, result.Title} {
+		if id == "" {
+			id = fallback
+		}
+	}
+	return Source{ID: id, Title: result.Title, Attribution: result.Attribution, Sentences: result.Sentences}
+}
+
+func (s Source) text() string {
+	return s.Title + "\n" + strings.Join(s.Sentences, "\n")
+}
+
+// Citation attributes one checked sentence of an answer to the sources supporting it.
+type Citation struct {
+	Sentence  string   `json:"sentence"`
+	SourceIDs []string `json:"sourceIds"`
+	Supported bool     `json:"supported"`
+}
+
+// EnforceCitations checks every sentence of answer against sources. Unsupported
+// sentences are flagged or dropped according to mode; the citations list every checked
+// sentence as it appeared in the original answer.
+func EnforceCitations(answer string, sources []Source, mode CitationMode) (string, []Citation) {
+	vocabularies := make([]map[string]bool, len(sources))
+	for i, source := range sources {
+		vocabularies[i] = wordSet(contentWords(source.text()))
+	}
+
+	var (
+		citations []Citation
+		lines     []string
+		inCode    bool
+	)
+	for _, line := range strings.Split(answer, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+		}
+		if inCode || trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "```") {
+			lines = append(lines, line)
+			continue
+		}
+
+		prefix, body := splitLinePrefix(line)
+		var kept []string
+		for _, sentence := range splitSentences(body) {
+			words := contentWords(sentence)
+			if len(words) < minClaimWords {
+				kept = append(kept, sentence)
+				continue
+			}
+
+			citation := attribute(strings.TrimSpace(sentence), words, sources, vocabularies)
+			citations = append(citations, citation)
+			switch {
+			case citation.Supported || mode == CitationOff:
+				kept = append(kept, sentence)
+			case mode == CitationFlag:
+				kept = append(kept, strings.TrimRight(sentence, " ")+unsupportedMarker+trailingSpace(sentence))
+			}
+		}
+
+		if len(kept) == 0 {
+			continue // every sentence on the line was dropped
+		}
+		lines = append(lines, prefix+strings.TrimRight(strings.Join(kept, ""), " "))
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n")), citations
+}
+
+func attribute(sentence string, words []string, sources []Source, vocabularies []map[string]bool) Citation {
+	type scored struct {
+		id      string
+		support float64
+	}
+
+	distinct := wordSet(words)
+	var supporting []scored
+	for i, vocabulary := range vocabularies {
+		found := 0
+		for word := range distinct {
+			if vocabulary[word] {
+				found++
+			}
+		}
+		if support := float64(found) / float64(len(distinct)); support >= minSupport {
+			supporting = append(supporting, scored{sources[i].ID, support})
+		}
+	}
+	slices.SortStableFunc(supporting, func(a, b scored) int {
+		switch {
+		case a.support > b.support:
+			return -1
+		case a.support < b.support:
+			return 1
+		}
+		return 0
+	})
+
+	citation := Citation{Sentence: sentence, SourceIDs: []string{}, Supported: len(supporting) > 0}
+	for _, s := range supporting {
+		if len(citation.SourceIDs) == maxAttributions {
+			break
+		}
+		if !slices.Contains(citation.SourceIDs, s.id) {
+			citation.SourceIDs = append(citation.SourceIDs, s.id)
+		}
+	}
+	return citation
+}
+
+// list markers and block quotes, kept in place when sentences are dropped
+var linePrefix = regexp.MustCompile(`^\s*(?:>\s*)*(?:[-*+]\s+|\d+[.)]\s+)?`)
+
+func splitLinePrefix(line string) (string, string) {
+	prefix := linePrefix.FindString(line)
+	return prefix, line[len(prefix):]
+}
+
+// splitSentences splits after terminal punctuation followed by a space and a capital
+// letter, so decimals ("0.5 ml") and most abbreviations stay intact. Each sentence keeps
+// its trailing whitespace so the line can be rejoined unchanged.
+func splitSentences(text string) []string {
+	var (
+		sentences []string
+		start     int
+	)
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if !strings.ContainsRune(".!?", runes[i]) {
+			continue
+		}
+		j := i + 1
+		for j < len(runes) && strings.ContainsRune(`.!?"')*_`, runes[j]) {
+			j++
+		}
+		k := j
+		for k < len(runes) && unicode.IsSpace(runes[k]) {
+			k++
+		}
+		if k == j || k == len(runes) || !(unicode.IsUpper(runes[k]) || strings.ContainsRune("*_[", runes[k])) {
+			continue
+		}
+		sentences = append(sentences, string(runes[start:k]))
+		start, i = k, k-1
+	}
+	if start < len(runes) {
+		sentences = append(sentences, string(runes[start:]))
+	}
+	return sentences
+}
+
+func trailingSpace(s string) string {
+	return s[len(strings.TrimRight(s, " ")):]
+}
+
+var stopWords = wordSet(strings.Fields(`the and for are but not you your with this that these those from
+	have has had was were been being can could should would will may might must shall into onto than then
+	them they their there here what which who whom whose when where why how all any both each few more most
+	other some such only own same very also just over under again further once about above below between
+	during before after its it's our out off too per via use used using like well does did doing`))
+
+// contentWords lowercases text and returns its words of three or more characters, and
+// all numbers, minus stop words. A trailing plural "s" is dropped.
+func contentWords(text string) []string {
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		isNumber := strings.IndexFunc(word, func(r rune) bool { return !unicode.IsDigit(r) }) < 0
+		if (len(word) < 3 && !isNumber) || stopWords[word] {
+			continue
+		}
+		if len(word) > 4 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") {
+			word = word[:len(word)-1]
+		}
+		words = append(words, word)
+	}
+	return words
+}
+
+func wordSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
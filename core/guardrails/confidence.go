@@ -0,0 +1,150 @@
+package guardrails
+
+import (
+	"slices"
+	"strings"
+)
+
+const (
+	// weight of answer support in the confidence score; the rest is retrieval coverage
+	supportWeight = 0.6
+	// at most this many of the closest sources are listed when the agent abstains
+	maxClosestSources = 3
+)
+
+const AbstentionNotice = "**Insufficient evidence in the knowledge base** to answer this question reliably."
+
+// Confidence is how well an answer is backed by the sources retrieved for it. Search
+// returns sections ranked by fusing lexical and vector ranks, which carry no absolute
+// relevance, so both parts are measured on the text itself.
+type Confidence struct {
+	// Score combines Support and Coverage, from 0 to 1.
+	Score float64
+	// Coverage is the share of the question's content words found in the sources.
+	Coverage float64
+	// Support is the share of the answer's claims that a single source supports, as
+	// citation enforcement attributes them.
+	Support float64
+	// Closest are the sources sharing the most words with the question, best first.
+	Closest []Source
+}
+
+// AssessConfidence scores answer against the sources it was generated from. It returns
+// false when nothing was retrieved, as for a follow-up answered from the conversation,
+// since there is no evidence to weigh then.
+func AssessConfidence(question, answer string, sources []Source) (Confidence, bool) {
+	if len(sources) == 0 {
+		return Confidence{}, false
+	}
+
+	vocabularies := make([]map[string]bool, len(sources))
+	corpus := make(map[string]bool)
+	for i, source := range sources {
+		vocabularies[i] = wordSet(contentWords(source.text()))
+		for word := range vocabularies[i] {
+			corpus[word] = true
+		}
+	}
+
+	questionWords := wordSet(contentWords(question))
+	coverage := 1.0
+	if len(questionWords) > 0 {
+		coverage = overlap(questionWords, corpus)
+	}
+
+	claims, supported := 0, 0
+	for _, line := range strings.Split(answer, "\n") {
+		_, body := splitLinePrefix(strings.TrimSpace(line))
+		for _, sentence := range splitSentences(body) {
+			words := contentWords(sentence)
+			if len(words) < minClaimWords {
+				continue
+			}
+			claims++
+			if attribute(sentence, words, sources, vocabularies).Supported {
+				supported++
+			}
+		}
+	}
+	support := 1.0
+	if claims > 0 {
+		support = float64(supported) / float64(claims)
+	}
+
+	return Confidence{
+		Score:    supportWeight*support + (1-supportWeight)*coverage,
+		Coverage: coverage,
+		Support:  support,
+		Closest:  closestSources(questionWords, sources, vocabularies),
+	}, true
+}
+
+// overlap is the share of words found in vocabulary.
+func overlap(words, vocabulary map[string]bool) float64 {
+	found := 0
+	for word := range words {
+		if vocabulary[word] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(words))
+}
+
+// closestSources keeps the retrieval order between sources that match the question
+// equally well, and lists each section once.
+func closestSources(questionWords map[string]bool, sources []Source, vocabularies []map[string]bool) []Source {
+	type scored struct {
+		source  Source
+		overlap float64
+	}
+
+	var candidates []scored
+	for i, source := range sources {
+		if slices.ContainsFunc(candidates, func(c scored) bool { return c.source.ID == source.ID }) {
+			continue
+		}
+		score := 0.0
+		if len(questionWords) > 0 {
+			score = overlap(questionWords, vocabularies[i])
+		}
+		candidates = append(candidates, scored{source, score})
+	}
+	slices.SortStableFunc(candidates, func(a, b scored) int {
+		switch {
+		case a.overlap > b.overlap:
+			return -1
+		case a.overlap < b.overlap:
+			return 1
+		}
+		return 0
+	})
+
+	closest := make([]Source, 0, maxClosestSources)
+	for _, c := range candidates[:min(len(candidates), maxClosestSources)] {
+		closest = append(closest, c.source)
+	}
+	return closest
+}
+
+// AbstentionAnswer replaces an answer the sources do not support, pointing the reader
+// to the closest sources instead.
+func AbstentionAnswer(closest []Source) string {
+	var b strings.Builder
+	b.WriteString(AbstentionNotice)
+	if len(closest) == 0 {
+		return b.String()
+	}
+
+	b.WriteString("\n\nThe closest sources found were:\n")
+	for _, source := range closest {
+		title := source.Title
+		if title == "" {
+			title = source.ID
+		}
+		b.WriteString("\n- " + title)
+		if source.Attribution != "" {
+			b.WriteString(" (" + source.Attribution + ")")
+		}
+	}
+	return b.String()
+}
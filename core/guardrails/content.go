@@ -0,0 +1,267 @@
+package guardrails
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/SaiNageswarS/agent-boot/agentboot"
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/medicine-rag/core/llmrouter"
+	"go.uber.org/zap"
+)
+
+// ContentAction decides what happens to a span of the answer that matches a tenant's
+// banned-content rule.
+type ContentAction string
+
+const (
+	// ContentRedact replaces the span with the rule's replacement text.
+	ContentRedact ContentAction = "redact"
+	// ContentRegenerate has the model rewrite the answer without the span. Spans the
+	// rewrite still contains are redacted.
+	ContentRegenerate ContentAction = "regenerate"
+)
+
+// ParseContentAction falls back to ContentRedact for empty or unknown values.
+func ParseContentAction(value string) ContentAction {
+	if ContentAction(strings.ToLower(strings.TrimSpace(value))) == ContentRegenerate {
+		return ContentRegenerate
+	}
+	return ContentRedact
+}
+
+const defaultRedaction = "[removed]"
+
+// ContentRule bans answer text matching Pattern, a regular expression matched without
+// regard to case, e.g. a brand name or `cures? (all|every)`.
+type ContentRule struct {
+	Name        string
+	Pattern     string
+	Action      ContentAction
+	Replacement string // used when redacting; defaults to "[removed]"
+}
+
+// ContentViolation is one match of a rule in a generated answer.
+type ContentViolation struct {
+	Rule   string        `json:"rule"`
+	Match  string        `json:"match"`
+	Action ContentAction `json:"action"`
+}
+
+// ContentPolicy is a tenant's stop sequences and banned-content rules.
+type ContentPolicy struct {
+	stopSequences []string
+	rules         []compiledRule
+}
+
+type compiledRule struct {
+	ContentRule
+	re *regexp.Regexp
+}
+
+// NewContentPolicy compiles the rules. A rule that does not compile is reported and
+// left out, so one bad pattern does not disable the others.
+func NewContentPolicy(stopSequences []string, rules []ContentRule) (*ContentPolicy, []error) {
+	policy := &ContentPolicy{}
+	for _, stop := range stopSequences {
+		if stop != "" {
+			policy.stopSequences = append(policy.stopSequences, stop)
+		}
+	}
+
+	var errs []error
+	for _, rule := range rules {
+		re, err := regexp.Compile("(?i)" + rule.Pattern)
+		if err != nil || rule.Pattern == "" {
+			errs = append(errs, fmt.Errorf("content rule %q: invalid pattern %q", rule.Name, rule.Pattern))
+			continue
+		}
+		if rule.Name == "" {
+			rule.Name = rule.Pattern
+		}
+		if rule.Replacement == "" {
+			rule.Replacement = defaultRedaction
+		}
+		rule.Action = ParseContentAction(string(rule.Action))
+		policy.rules = append(policy.rules, compiledRule{ContentRule: rule, re: re})
+	}
+	return policy, errs
+}
+
+// Empty reports whether the policy leaves every answer as generated.
+func (p *ContentPolicy) Empty() bool {
+	return p == nil || (len(p.stopSequences) == 0 && len(p.rules) == 0)
+}
+
+// Truncate cuts the answer at the earliest stop sequence. The model is not told about
+// stop sequences, so anything it writes after one is discarded here.
+func (p *ContentPolicy) Truncate(answer string) (string, bool) {
+	cut := -1
+	for _, stop := range p.stopSequences {
+		if i := strings.Index(answer, stop); i >= 0 && (cut < 0 || i < cut) {
+			cut = i
+		}
+	}
+	if cut < 0 {
+		return answer, false
+	}
+	return strings.TrimRight(answer[:cut], " \t\n"), true
+}
+
+// Violations lists every match of every rule in the answer.
+func (p *ContentPolicy) Violations(answer string) []ContentViolation {
+	var violations []ContentViolation
+	for _, rule := range p.rules {
+		for _, match := range rule.re.FindAllString(answer, -1) {
+			violations = append(violations, ContentViolation{Rule: rule.Name, Match: match, Action: rule.Action})
+		}
+	}
+	return violations
+}
+
+// Allows reports whether the policy leaves the answer unchanged, e.g. for an answer
+// cached before the rules were edited.
+func (p *ContentPolicy) Allows(answer string) bool {
+	if p.Empty() {
+		return true
+	}
+	_, stopped := p.Truncate(answer)
+	return !stopped && len(p.Violations(answer)) == 0
+}
+
+// Redact replaces every match of every rule.
+func (p *ContentPolicy) Redact(answer string) string {
+	for _, rule := range p.rules {
+		answer = rule.re.ReplaceAllLiteralString(answer, rule.Replacement)
+	}
+	return answer
+}
+
+const regenerateSystemPrompt = "You revise answers so that they comply with a publisher's content rules. " +
+	"Rewrite only what the rules forbid and keep everything else, including formatting, unchanged. Reply with the revised answer only."
+
+// ContentFilter enforces a ContentPolicy on an agent's stream. It holds the answer back
+// until it is complete, applies the stop sequences, and then regenerates or redacts
+// banned spans before the client sees them.
+type ContentFilter struct {
+	agentboot.ProgressReporter
+	ctx          context.Context
+	policy       *ContentPolicy
+	model        llm.LLMClient
+	onViolations func([]ContentViolation)
+
+	mu     sync.Mutex
+	answer strings.Builder
+}
+
+// NewContentFilter reports the violations found in each generated answer to
+// onViolations, before any regeneration, so they can be kept for review.
+func NewContentFilter(ctx context.Context, inner agentboot.ProgressReporter, policy *ContentPolicy, model llm.LLMClient, onViolations func([]ContentViolation)) *ContentFilter {
+	return &ContentFilter{
+		ProgressReporter: inner,
+		ctx:              ctx,
+		policy:           policy,
+		model:            model,
+		onViolations:     onViolations,
+	}
+}
+
+func (f *ContentFilter) Send(event *schema.AgentStreamChunk) error {
+	switch chunk := event.ChunkType.(type) {
+	case *schema.AgentStreamChunk_Answer:
+		if chunk.Answer != nil {
+			f.mu.Lock()
+			f.answer.WriteString(chunk.Answer.Content)
+			f.mu.Unlock()
+		}
+		return nil // sent once the answer has been filtered
+
+	case *schema.AgentStreamChunk_Complete:
+		if chunk.Complete != nil {
+			return f.complete(chunk.Complete)
+		}
+	}
+
+	return f.ProgressReporter.Send(event)
+}
+
+func (f *ContentFilter) complete(complete *schema.StreamComplete) error {
+	f.mu.Lock()
+	answer := complete.Answer
+	if answer == "" {
+		answer = f.answer.String()
+	}
+	f.mu.Unlock()
+
+	if complete.Metadata == nil {
+		complete.Metadata = make(map[string]string)
+	}
+
+	answer, stopped := f.policy.Truncate(answer)
+	if stopped {
+		complete.Metadata["stopSequence"] = "true"
+	}
+
+	violations := f.policy.Violations(answer)
+	if len(violations) > 0 {
+		if f.onViolations != nil {
+			f.onViolations(violations)
+		}
+		complete.Metadata["contentViolations"] = strconv.Itoa(len(violations))
+
+		if f.model != nil && needsRegeneration(violations) {
+			regenerated, err := f.regenerate(answer, violations)
+			if err != nil {
+				logger.Error("Failed to regenerate answer without banned content", zap.Error(err))
+			} else if regenerated = strings.TrimSpace(regenerated); regenerated != "" {
+				answer, _ = f.policy.Truncate(regenerated)
+				complete.Metadata["contentRegenerated"] = "true"
+			}
+		}
+		answer = f.policy.Redact(answer)
+	}
+
+	complete.Answer = answer
+	if answer != "" {
+		if err := f.ProgressReporter.Send(agentboot.NewAnswerChunk(&schema.AnswerChunk{Content: answer})); err != nil {
+			return err
+		}
+	}
+	return f.ProgressReporter.Send(agentboot.NewStreamComplete(complete))
+}
+
+func needsRegeneration(violations []ContentViolation) bool {
+	for _, violation := range violations {
+		if violation.Action == ContentRegenerate {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *ContentFilter) regenerate(answer string, violations []ContentViolation) (string, error) {
+	forbidden := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		forbidden = append(forbidden, fmt.Sprintf("%q (rule: %s)", violation.Match, violation.Rule))
+	}
+	prompt := fmt.Sprintf("Answer:\n%s\n\nThe answer must not contain:\n- %s\n\nReturn the revised answer.",
+		answer, strings.Join(forbidden, "\n- "))
+
+	var regenerated strings.Builder
+	err := f.model.GenerateInference(f.ctx,
+		[]llm.Message{{Role: "user", Content: prompt}},
+		func(chunk string) error {
+			regenerated.WriteString(chunk)
+			return nil
+		},
+		llmrouter.WithSystemPrompt(regenerateSystemPrompt),
+		llmrouter.WithTemperature(0),
+	)
+	return regenerated.String(), err
+}
@@ -0,0 +1,112 @@
+package guardrails
+
+import (
+	"slices"
+	"strings"
+)
+
+// AnswerCategory is the kind of advice an answer gives, which decides the disclaimers
+// it needs.
+type AnswerCategory string
+
+const (
+	// CategoryDosage is an answer stating doses or potencies.
+	CategoryDosage AnswerCategory = "dosage"
+	// CategoryGeneral is any other answer.
+	CategoryGeneral AnswerCategory = "general"
+)
+
+// Categorize puts an answer mentioning any dose or potency in CategoryDosage.
+func Categorize(answer string) AnswerCategory {
+	if len(FindDosages(answer)) > 0 {
+		return CategoryDosage
+	}
+	return CategoryGeneral
+}
+
+// DisclaimerPosition places a disclaimer before or after the answer.
+type DisclaimerPosition string
+
+const (
+	DisclaimerAppend  DisclaimerPosition = "append"
+	DisclaimerPrepend DisclaimerPosition = "prepend"
+)
+
+// Disclaimer is regulatory text added to answers. An empty Category applies to every
+// answer, and empty Jurisdictions to every jurisdiction.
+type Disclaimer struct {
+	Name          string
+	Text          string
+	Category      AnswerCategory
+	Jurisdictions []string
+	Position      DisclaimerPosition
+}
+
+// DisclaimerPolicy holds the disclaimers that apply in one jurisdiction.
+type DisclaimerPolicy struct {
+	disclaimers []Disclaimer
+}
+
+// NewDisclaimerPolicy keeps the disclaimers for jurisdiction, compared without regard
+// to case, in the order given. Disclaimers without text are dropped, and unknown
+// positions append.
+func NewDisclaimerPolicy(jurisdiction string, disclaimers []Disclaimer) *DisclaimerPolicy {
+	p := &DisclaimerPolicy{}
+	for _, disclaimer := range disclaimers {
+		disclaimer.Text = strings.TrimSpace(disclaimer.Text)
+		if disclaimer.Text == "" {
+			continue
+		}
+		if len(disclaimer.Jurisdictions) > 0 && !slices.ContainsFunc(disclaimer.Jurisdictions, func(j string) bool {
+			return strings.EqualFold(strings.TrimSpace(j), strings.TrimSpace(jurisdiction))
+		}) {
+			continue
+		}
+		if disclaimer.Position != DisclaimerPrepend {
+			disclaimer.Position = DisclaimerAppend
+		}
+		p.disclaimers = append(p.disclaimers, disclaimer)
+	}
+	return p
+}
+
+func (p *DisclaimerPolicy) Empty() bool {
+	return p == nil || len(p.disclaimers) == 0
+}
+
+// For returns the disclaimers an answer of category needs.
+func (p *DisclaimerPolicy) For(category AnswerCategory) []Disclaimer {
+	if p == nil {
+		return nil
+	}
+	var applied []Disclaimer
+	for _, disclaimer := range p.disclaimers {
+		if disclaimer.Category == "" || disclaimer.Category == category {
+			applied = append(applied, disclaimer)
+		}
+	}
+	return applied
+}
+
+// AddDisclaimers places each disclaimer around answer as a quoted paragraph.
+func AddDisclaimers(answer string, disclaimers []Disclaimer) string {
+	var before, after []string
+	for _, disclaimer := range disclaimers {
+		quoted := "> " + strings.ReplaceAll(disclaimer.Text, "\n", "\n> ")
+		if disclaimer.Position == DisclaimerPrepend {
+			before = append(before, quoted)
+		} else {
+			after = append(after, quoted)
+		}
+	}
+	return strings.Join(slices.Concat(before, []string{answer}, after), "\n\n")
+}
+
+// disclaimerNames lists the disclaimers for the completion metadata.
+func disclaimerNames(disclaimers []Disclaimer) string {
+	names := make([]string, len(disclaimers))
+	for i, disclaimer := range disclaimers {
+		names[i] = disclaimer.Name
+	}
+	return strings.Join(names, ",")
+}
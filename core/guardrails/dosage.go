@@ -0,0 +1,115 @@
+package guardrails
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/SaiNageswarS/medicine-rag/core/entities"
+)
+
+// Doses such as "500 mg", "10 drops" or "2 tablets", and homeopathic potencies such as
+// "30C", "200 CH", "6X", "1M", "LM1" and "0/3". The forms in the group, "30 C", "6X" and
+// "6D", are also temperatures, times and days, and count only after a remedy.
+var dosagePattern = regexp.MustCompile(`(?i)\b(?:` +
+	`\d+(?:\.\d+)?\s?(?:mg|mcg|µg|ug|gm|g|ml|iu|units?|drops?|tablets?|tabs?|pills?|globules?|pellets?|capsules?)` +
+	`|\d+\s?(?:ch|ck)|\d+c` +
+	`|\d{1,2}m` +
+	`|lm\s?\d+` +
+	`|0/\d+` +
+	`|(\d+\sc|\d+(?:x|d))` + // "2 x daily" is a frequency, not a potency
+	`)\b`)
+
+// contextToken is a word or number, with the dots and hyphens of abbreviated remedies.
+var contextToken = regexp.MustCompile(`[\p{L}\p{N}][\p{L}\p{N}.'-]*`)
+
+var unitAliases = map[string]string{
+	"µg": "mcg", "ug": "mcg", "gm": "g",
+	"unit": "units", "drop": "drops",
+	"tablet": "tablets", "tab": "tablets", "tabs": "tablets",
+	"pill": "pills", "globule": "globules", "pellet": "pellets", "capsule": "capsules",
+	"ch": "c", "ck": "c", "d": "x",
+}
+
+// FindDosages returns the distinct doses and potencies mentioned in text, normalized
+// so that "200 CH" and "200c" compare equal.
+func FindDosages(text string) []string {
+	var found []string
+	for _, match := range dosagePattern.FindAllStringSubmatchIndex(text, -1) {
+		if match[2] >= 0 && !followsRemedy(text[:match[0]]) {
+			continue
+		}
+		dosage := normalizeDosage(text[match[0]:match[1]])
+		if !slices.Contains(found, dosage) {
+			found = append(found, dosage)
+		}
+	}
+	return found
+}
+
+// UnsupportedDosages returns the doses and potencies in answer that appear in none of
+// the sources the answer was generated from.
+func UnsupportedDosages(answer string, sources []string) []string {
+	supported := make(map[string]bool)
+	for _, source := range sources {
+		for _, dosage := range FindDosages(source) {
+			supported[dosage] = true
+		}
+	}
+
+	var unsupported []string
+	for _, dosage := range FindDosages(answer) {
+		if !supported[dosage] {
+			unsupported = append(unsupported, dosage)
+		}
+	}
+	return unsupported
+}
+
+// followsRemedy reports whether one of the last three words of before names a remedy,
+// or says "potency": a known remedy, or a capitalized word inside a sentence.
+func followsRemedy(before string) bool {
+	tokens := contextToken.FindAllStringIndex(before, -1)
+	tokens = tokens[max(0, len(tokens)-3):]
+	for i, span := range tokens {
+		word := before[span[0]:span[1]]
+		if _, ok := entities.RemedyName(word); ok {
+			return true
+		}
+		if i > 0 {
+			if _, ok := entities.RemedyName(before[tokens[i-1][0]:span[1]]); ok {
+				return true
+			}
+		}
+		if strings.HasPrefix(strings.ToLower(word), "potenc") {
+			return true
+		}
+
+		first, _ := utf8.DecodeRuneInString(word)
+		sentence := strings.TrimRight(before[:span[0]], " \t")
+		if unicode.IsUpper(first) && word != "I" && sentence != "" && !strings.ContainsAny(sentence[len(sentence)-1:], ".!?:\n") {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeDosage(match string) string {
+	match = strings.ToLower(strings.Join(strings.Fields(match), ""))
+
+	i := 0
+	for i < len(match) && (match[i] >= '0' && match[i] <= '9' || match[i] == '.' || match[i] == '/') {
+		i++
+	}
+	if i == 0 { // "lm1": the unit comes first
+		return match
+	}
+
+	amount, unit := match[:i], match[i:]
+	if alias, ok := unitAliases[unit]; ok {
+		unit = alias
+	}
+	return amount + unit
+}
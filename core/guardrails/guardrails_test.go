@@ -0,0 +1,341 @@
+package guardrails
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SaiNageswarS/agent-boot/agentboot"
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssessQuestion(t *testing.T) {
+	emergency := AssessQuestion("My father has crushing chest pain and slurred speech")
+	assert.True(t, emergency.Emergency)
+	assert.Equal(t, []string{"chest pain", "stroke signs"}, emergency.Signals)
+
+	routine := AssessQuestion("Which remedy suits anxiety after midnight?")
+	assert.False(t, routine.Emergency)
+	assert.Empty(t, routine.Signals)
+
+	for _, question := range []string{
+		"Is Rhus tox the same as poison ivy?",
+		"Which remedy helps a rash from poison oak?",
+		"Is a fitting remedy for grief Ignatia?",
+		"What helps headache after heat stroke?",
+		"Remedies for sunstroke with a throbbing head",
+	} {
+		assert.False(t, AssessQuestion(question).Emergency, question)
+	}
+	assert.True(t, AssessQuestion("He touched poison ivy and then swallowed poison").Emergency)
+	assert.True(t, AssessQuestion("My son is fitting and won't wake").Emergency)
+}
+
+func TestUnsupportedDosages(t *testing.T) {
+	sources := []string{"Aconite 30C every hour in acute fear.", "Give 5 drops of the mother tincture."}
+
+	assert.Empty(t, UnsupportedDosages("Take Aconite 30 c, or 5 drops of the tincture.", sources))
+	assert.Equal(t, []string{"200c", "500mg"}, UnsupportedDosages("Aconite 200CH twice, or 500 mg of Arnica.", sources))
+	assert.Empty(t, UnsupportedDosages("Repeat 2 x daily for 3 days.", sources), "frequencies are not potencies")
+	assert.Empty(t, UnsupportedDosages("With a fever of 39 C, give Aconite 30C.", sources), "temperatures are not potencies")
+	assert.Empty(t, UnsupportedDosages("Repeat for 7d, then stop.", sources), "days are not potencies")
+	assert.Empty(t, UnsupportedDosages("Take it 2x daily.", sources), "times are not potencies")
+}
+
+func TestFindDosagesAfterRemedy(t *testing.T) {
+	assert.Equal(t, []string{"6x"}, FindDosages("Give Ferrum phos 6X in the first stage of fever."))
+	assert.Equal(t, []string{"12x"}, FindDosages("Nat. mur. 12X suits grief."))
+	assert.Equal(t, []string{"6x"}, FindDosages("In Germany the potency 6D is written for 6X."))
+	assert.Equal(t, []string{"30c"}, FindDosages("Aconite 30 c after a fright."))
+	assert.Empty(t, FindDosages("Her temperature rose to 39 C overnight."))
+}
+
+type recordingReporter struct {
+	events []*schema.AgentStreamChunk
+}
+
+func (r *recordingReporter) Send(event *schema.AgentStreamChunk) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestReporter(t *testing.T) {
+	source := agentboot.NewToolExecutionResult("medicine-rag", &schema.ToolResultChunk{Title: "Aconite", Sentences: []string{"Aconite 30C in sudden fear."}})
+	answer := "Aconite 30C, then 1M if needed."
+
+	t.Run("Annotate", func(t *testing.T) {
+		inner := &recordingReporter{}
+		guard := NewReporter(inner, Assessment{}, DosageAnnotate)
+
+		require.NoError(t, guard.Send(source))
+		require.NoError(t, guard.Send(agentboot.NewAnswerChunk(&schema.AnswerChunk{Content: answer})))
+		require.NoError(t, guard.Send(agentboot.NewStreamComplete(&schema.StreamComplete{Answer: answer})))
+
+		require.Len(t, inner.events, 3)
+		complete := inner.events[2].GetComplete()
+		assert.Contains(t, complete.Answer, "**Unverified dosage:** 1m")
+		assert.Equal(t, "1m", complete.Metadata["unsupportedDosages"])
+		assert.Equal(t, complete.Answer, guard.FinalAnswer())
+	})
+
+	t.Run("BlockWithEmergency", func(t *testing.T) {
+		inner := &recordingReporter{}
+		guard := NewReporter(inner, Assessment{Emergency: true}, DosageBlock)
+
+		require.NoError(t, guard.Begin())
+		require.NoError(t, guard.Send(source))
+		require.NoError(t, guard.Send(agentboot.NewAnswerChunk(&schema.AnswerChunk{Content: answer})))
+		require.NoError(t, guard.Send(agentboot.NewStreamComplete(&schema.StreamComplete{Answer: answer})))
+
+		// notice, tool result, checked answer, complete: the raw answer chunk is held back.
+		require.Len(t, inner.events, 4)
+		assert.Equal(t, EmergencyNotice, inner.events[0].GetAnswer().Content)
+		complete := inner.events[3].GetComplete()
+		assert.NotContains(t, complete.Answer, "1M")
+		assert.Contains(t, complete.Answer, EmergencyNotice)
+		assert.Equal(t, "emergency", complete.Metadata["safety"])
+		assert.Equal(t, complete.Answer, inner.events[2].GetAnswer().Content)
+	})
+}
+
+func TestEnforceCitations(t *testing.T) {
+	sources := []Source{
+		{ID: "aconite", Title: "Aconite", Sentences: []string{"Aconite suits sudden fear with restlessness after a shock.", "Symptoms are worse at midnight."}},
+		{ID: "arnica", Title: "Arnica", Sentences: []string{"Arnica helps bruising and soreness after injury."}},
+	}
+	answer := "## Remedies\n" +
+		"- Aconite suits sudden fear and restlessness after shock. Arnica cures diabetes in elderly patients quickly.\n" +
+		"- Arnica helps bruising and soreness after an injury.\n" +
+		"Hope this helps."
+
+	t.Run("Flag", func(t *testing.T) {
+		flagged, citations := EnforceCitations(answer, sources, CitationFlag)
+
+		assert.Equal(t, "## Remedies\n"+
+			"- Aconite suits sudden fear and restlessness after shock. Arnica cures diabetes in elderly patients quickly."+unsupportedMarker+"\n"+
+			"- Arnica helps bruising and soreness after an injury.\n"+
+			"Hope this helps.", flagged)
+
+		require.Len(t, citations, 3)
+		assert.Equal(t, []string{"aconite"}, citations[0].SourceIDs)
+		assert.False(t, citations[1].Supported)
+		assert.Empty(t, citations[1].SourceIDs)
+		assert.Equal(t, []string{"arnica"}, citations[2].SourceIDs)
+	})
+
+	t.Run("Drop", func(t *testing.T) {
+		dropped, _ := EnforceCitations(answer, sources, CitationDrop)
+
+		assert.Equal(t, "## Remedies\n"+
+			"- Aconite suits sudden fear and restlessness after shock.\n"+
+			"- Arnica helps bruising and soreness after an injury.\n"+
+			"Hope this helps.", dropped)
+	})
+
+	t.Run("DecimalsAreNotSentenceBreaks", func(t *testing.T) {
+		assert.Equal(t, []string{"Give 0.5 ml twice. ", "Stop if worse."}, splitSentences("Give 0.5 ml twice. Stop if worse."))
+	})
+}
+
+func TestReporterCitations(t *testing.T) {
+	inner := &recordingReporter{}
+	guard := NewReporter(inner, Assessment{}, DosageOff).WithCitations(CitationDrop)
+
+	source := &schema.ToolResultChunk{
+		Title:     "Aconite",
+		Sentences: []string{"Aconite suits sudden fear with restlessness after a shock."},
+		Metadata:  map[string]string{"sectionId": "sec-1"},
+	}
+	answer := "Aconite suits sudden fear with restlessness. Belladonna treats broken bones in adults."
+
+	require.NoError(t, guard.Send(agentboot.NewToolExecutionResult("medicine-rag", source)))
+	require.NoError(t, guard.Send(agentboot.NewAnswerChunk(&schema.AnswerChunk{Content: answer})))
+	require.NoError(t, guard.Send(agentboot.NewStreamComplete(&schema.StreamComplete{Answer: answer})))
+
+	// tool result, checked answer, complete: the raw answer chunk is held back.
+	require.Len(t, inner.events, 3)
+	complete := inner.events[2].GetComplete()
+	assert.Equal(t, "Aconite suits sudden fear with restlessness.", complete.Answer)
+	assert.Equal(t, complete.Answer, inner.events[1].GetAnswer().Content)
+	assert.Equal(t, "1", complete.Metadata["unsupportedSentences"])
+	assert.Contains(t, complete.Metadata["citations"], `"sourceIds":["sec-1"]`)
+}
+
+func TestReporterPreservesStructuredAnswers(t *testing.T) {
+	inner := &recordingReporter{}
+	guard := NewReporter(inner, Assessment{Emergency: true}, DosageAnnotate).WithCitations(CitationDrop).PreserveAnswer()
+	answer := `{"remedy": "Aconite", "potency": "1M"}`
+
+	require.NoError(t, guard.Begin())
+	require.NoError(t, guard.Send(agentboot.NewAnswerChunk(&schema.AnswerChunk{Content: answer})))
+	require.NoError(t, guard.Send(agentboot.NewStreamComplete(&schema.StreamComplete{Answer: answer})))
+
+	// no notice ahead of the answer, which streams as generated
+	require.Len(t, inner.events, 2)
+	complete := inner.events[1].GetComplete()
+	assert.Equal(t, answer, complete.Answer)
+	assert.Equal(t, EmergencyNotice, complete.Metadata["safetyNotice"])
+	assert.Equal(t, "1m", complete.Metadata["unsupportedDosages"])
+	assert.NotContains(t, complete.Metadata, "citations")
+}
+
+func TestAssessConfidence(t *testing.T) {
+	sources := []Source{
+		{ID: "arnica", Title: "Arnica", Attribution: "boericke.pdf", Sentences: []string{"Arnica helps bruising and soreness after injury."}},
+		{ID: "aconite", Title: "Aconite", Sentences: []string{"Aconite suits sudden fear with restlessness after a shock."}},
+	}
+
+	supported, ok := AssessConfidence("Which remedy helps bruising after an injury?",
+		"Arnica helps bruising and soreness after an injury.", sources)
+	require.True(t, ok)
+	assert.Equal(t, 1.0, supported.Support)
+	assert.Greater(t, supported.Score, 0.8)
+
+	unsupported, ok := AssessConfidence("Which remedy treats diabetic neuropathy in elderly patients?",
+		"Phosphorus reverses diabetic neuropathy within weeks. Elderly patients tolerate it without side effects.", sources)
+	require.True(t, ok)
+	assert.Zero(t, unsupported.Support)
+	assert.Less(t, unsupported.Score, 0.35)
+	require.Len(t, unsupported.Closest, 2)
+
+	_, ok = AssessConfidence("Make it shorter.", "Arnica for bruising.", nil)
+	assert.False(t, ok, "answers without retrieved sources are not scored")
+}
+
+func TestReporterAbstains(t *testing.T) {
+	inner := &recordingReporter{}
+	question := "Which remedy treats diabetic neuropathy in elderly patients?"
+	guard := NewReporter(inner, Assessment{}, DosageAnnotate).WithAbstention(question, 0.35)
+
+	source := &schema.ToolResultChunk{
+		Title:       "Arnica",
+		Attribution: "boericke.pdf",
+		Sentences:   []string{"Arnica helps bruising and soreness after injury."},
+		Metadata:    map[string]string{"sectionId": "sec-1"},
+	}
+	answer := "Phosphorus 1M reverses diabetic neuropathy within weeks."
+
+	require.NoError(t, guard.Send(agentboot.NewToolExecutionResult("medicine-rag", source)))
+	require.NoError(t, guard.Send(agentboot.NewAnswerChunk(&schema.AnswerChunk{Content: answer})))
+	require.NoError(t, guard.Send(agentboot.NewStreamComplete(&schema.StreamComplete{Answer: answer})))
+
+	// the answer streamed as generated; the completion replaces it
+	require.Len(t, inner.events, 3)
+	complete := inner.events[2].GetComplete()
+	assert.Equal(t, AbstentionNotice+"\n\nThe closest sources found were:\n\n- Arnica (boericke.pdf)", complete.Answer)
+	assert.Equal(t, "true", complete.Metadata["abstained"])
+	assert.Equal(t, "0.00", complete.Metadata["confidence"])
+	assert.NotContains(t, complete.Metadata, "unsupportedDosages", "an abstention states no dosages")
+	assert.Equal(t, complete.Answer, guard.FinalAnswer())
+}
+
+func TestDisclaimerPolicy(t *testing.T) {
+	policy := NewDisclaimerPolicy("in", []Disclaimer{
+		{Name: "dosage-in", Text: "Dosage per the Drugs and Cosmetics Act.", Category: CategoryDosage, Jurisdictions: []string{"IN"}},
+		{Name: "dosage-us", Text: "Not evaluated by the FDA.", Category: CategoryDosage, Jurisdictions: []string{"US"}},
+		{Name: "general", Text: "For qualified physicians only.", Position: DisclaimerPrepend},
+		{Name: "empty", Text: "  "},
+	})
+
+	assert.Equal(t, CategoryDosage, Categorize("Give Arnica 30C twice."))
+	assert.Equal(t, CategoryGeneral, Categorize("Arnica suits bruising."))
+
+	general := policy.For(CategoryGeneral)
+	require.Len(t, general, 1, "other jurisdictions' disclaimers are left out")
+	assert.Equal(t, "general", general[0].Name)
+	assert.Equal(t, "> For qualified physicians only.\n\nArnica 30C.\n\n> Dosage per the Drugs and Cosmetics Act.",
+		AddDisclaimers("Arnica 30C.", policy.For(CategoryDosage)))
+	assert.True(t, NewDisclaimerPolicy("", nil).Empty())
+}
+
+func TestReporterDisclaimers(t *testing.T) {
+	policy := NewDisclaimerPolicy("", []Disclaimer{
+		{Name: "dosage", Text: "Confirm doses with a physician.", Category: CategoryDosage},
+		{Name: "general", Text: "General information only.", Category: CategoryGeneral},
+	})
+	source := &schema.ToolResultChunk{Title: "Arnica", Sentences: []string{"Arnica 30C helps bruising."}}
+
+	inner := &recordingReporter{}
+	guard := NewReporter(inner, Assessment{}, DosageAnnotate).WithDisclaimers(policy)
+	require.NoError(t, guard.Send(agentboot.NewToolExecutionResult("medicine-rag", source)))
+	require.NoError(t, guard.Send(agentboot.NewStreamComplete(&schema.StreamComplete{Answer: "Arnica 30C helps bruising."})))
+
+	complete := inner.events[len(inner.events)-1].GetComplete()
+	assert.Equal(t, "Arnica 30C helps bruising.\n\n> Confirm doses with a physician.", complete.Answer)
+	assert.Equal(t, "dosage", complete.Metadata["answerCategory"])
+	assert.Equal(t, "dosage", complete.Metadata["disclaimers"])
+
+	// a structured answer keeps its shape
+	inner = &recordingReporter{}
+	guard = NewReporter(inner, Assessment{}, DosageAnnotate).WithDisclaimers(policy).PreserveAnswer()
+	require.NoError(t, guard.Send(agentboot.NewStreamComplete(&schema.StreamComplete{Answer: `{"remedy":"Arnica"}`})))
+
+	complete = inner.events[len(inner.events)-1].GetComplete()
+	assert.Equal(t, `{"remedy":"Arnica"}`, complete.Answer)
+	assert.Equal(t, "general", complete.Metadata["disclaimers"])
+	assert.Equal(t, "> General information only.", complete.Metadata["disclaimerText"])
+}
+
+func TestContentPolicy(t *testing.T) {
+	policy, errs := NewContentPolicy([]string{"\n---"}, []ContentRule{
+		{Name: "brand", Pattern: `hahnemann labs?`},
+		{Name: "cure claim", Pattern: `\b(cures?|guaranteed) (all|every)\b`, Action: "regenerate"},
+		{Name: "broken", Pattern: `(`},
+	})
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], `"broken"`)
+
+	answer, stopped := policy.Truncate("Aconite 30C.\n---\nInternal notes")
+	assert.True(t, stopped)
+	assert.Equal(t, "Aconite 30C.", answer)
+
+	answer = "Hahnemann Lab's Arnica cures all bruises."
+	assert.Equal(t, []ContentViolation{
+		{Rule: "brand", Match: "Hahnemann Lab", Action: ContentRedact},
+		{Rule: "cure claim", Match: "cures all", Action: ContentRegenerate},
+	}, policy.Violations(answer))
+	assert.Equal(t, "[removed]'s Arnica [removed] bruises.", policy.Redact(answer))
+
+	assert.False(t, policy.Allows(answer))
+	assert.True(t, policy.Allows("Arnica for bruises."))
+}
+
+type rewritingModel struct {
+	llm.LLMClient
+	reply  string
+	prompt string
+}
+
+func (m *rewritingModel) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	m.prompt = messages[len(messages)-1].Content
+	return callback(m.reply)
+}
+
+func TestContentFilter(t *testing.T) {
+	policy, _ := NewContentPolicy(nil, []ContentRule{
+		{Name: "brand", Pattern: `hahnemann labs?`},
+		{Name: "cure claim", Pattern: `cures? all`, Action: ContentRegenerate},
+	})
+
+	inner := &recordingReporter{}
+	model := &rewritingModel{reply: "Hahnemann Labs Arnica helps with bruises."}
+	var recorded []ContentViolation
+	filter := NewContentFilter(t.Context(), inner, policy, model, func(violations []ContentViolation) { recorded = violations })
+
+	generated := "Hahnemann Labs Arnica cures all bruises."
+	require.NoError(t, filter.Send(agentboot.NewAnswerChunk(&schema.AnswerChunk{Content: generated})))
+	require.NoError(t, filter.Send(agentboot.NewStreamComplete(&schema.StreamComplete{Answer: generated})))
+
+	assert.Contains(t, model.prompt, `"cures all" (rule: cure claim)`)
+	assert.Len(t, recorded, 2)
+
+	// the regenerated answer still names the brand, which is redacted
+	require.Len(t, inner.events, 2)
+	assert.Equal(t, "[removed] Arnica helps with bruises.", inner.events[0].GetAnswer().Content)
+	complete := inner.events[1].GetComplete()
+	assert.Equal(t, "[removed] Arnica helps with bruises.", complete.Answer)
+	assert.Equal(t, "2", complete.Metadata["contentViolations"])
+	assert.Equal(t, "true", complete.Metadata["contentRegenerated"])
+}
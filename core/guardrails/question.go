@@ -0,0 +1,59 @@
+// Package guardrails checks questions before generation and answers after it for
+// medical-safety problems the model cannot be trusted to catch itself.
+package guardrails
+
+import (
+	"regexp"
+	"strings"
+)
+
+// EmergencyNotice is shown ahead of any answer to a question that describes emergency
+// symptoms. Homeopathic material is never a substitute for urgent care.
+const EmergencyNotice = "**Seek urgent care.** The symptoms described may be a medical emergency. " +
+	"Call your local emergency number or go to the nearest emergency department now; " +
+	"do not wait for or rely on the information below."
+
+// Assessment is the pre-generation classification of a question.
+type Assessment struct {
+	Emergency bool
+	Signals   []string // names of the emergency rules that matched
+}
+
+type emergencyRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// Emergency rules are deliberately broad: a spurious notice costs a sentence, a missed
+// one can cost a life.
+var emergencyRules = []emergencyRule{
+	{"chest pain", regexp.MustCompile(`\b(chest|heart)\s+(pain|tightness|pressure)\b|\bcrushing\s+pain\b|\bheart\s+attack\b`)},
+	{"breathing difficulty", regexp.MustCompile(`\b(can'?t|cannot|unable to|difficulty|trouble|struggling to)\s+breath(e|ing)?\b|\bshortness of breath\b|\bchoking\b|\bturning blue\b`)},
+	{"stroke signs", regexp.MustCompile(`\bstroke\b|\bface\s+(is\s+)?droop|\bslurred\s+speech\b|\bsudden\s+(numbness|weakness|paralysis)\b`)},
+	{"loss of consciousness", regexp.MustCompile(`\bunconscious\b|\bunresponsive\b|\bpassed out\b|\bfainted\b|\bcollapsed?\b`)},
+	{"seizure", regexp.MustCompile(`\bseizures?\b|\bconvulsions?\b|\b(is|was|started|keeps)\s+fitting\b`)},
+	{"severe bleeding", regexp.MustCompile(`\b(heavy|severe|uncontrolled|profuse)\s+bleeding\b|\bvomiting\s+blood\b|\bcoughing\s+(up\s+)?blood\b|\bblood\s+in\s+vomit\b`)},
+	{"anaphylaxis", regexp.MustCompile(`\banaphyla|\bthroat\s+(is\s+)?(swelling|closing)\b|\bswollen\s+(tongue|throat)\b`)},
+	{"poisoning or overdose", regexp.MustCompile(`\boverdose\b|\bpoison(ed|ing)?\b|\bswallowed\s+(bleach|battery|pills)\b`)},
+	{"self-harm", regexp.MustCompile(`\bsuicid|\bkill\s+(myself|himself|herself|themselves)\b|\bself[- ]harm\b|\bend\s+my\s+life\b`)},
+	{"infant fever", regexp.MustCompile(`\b(newborn|infant|\d+\s*(day|week)s?\s*old)\b.*\bfever\b|\bfever\b.*\b(newborn|infant)\b`)},
+	{"meningitis signs", regexp.MustCompile(`\bstiff\s+neck\b.*\bfever\b|\bfever\b.*\bstiff\s+neck\b|\bnon[- ]blanching\s+rash\b`)},
+}
+
+// lookalikes contain an emergency word without describing an emergency, and are
+// removed before the rules run: poison ivy is Rhus tox, and heat stroke is no stroke.
+var lookalikes = regexp.MustCompile(`\bpoison\s+(ivy|oak|sumac)\b|\b(heat|sun)[\s-]?stroke\b`)
+
+// AssessQuestion classifies a question before it is answered.
+func AssessQuestion(question string) Assessment {
+	text := lookalikes.ReplaceAllString(strings.ToLower(question), " ")
+
+	var a Assessment
+	for _, rule := range emergencyRules {
+		if rule.pattern.MatchString(text) {
+			a.Emergency = true
+			a.Signals = append(a.Signals, rule.name)
+		}
+	}
+	return a
+}
@@ -0,0 +1,259 @@
+package guardrails
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/SaiNageswarS/agent-boot/agentboot"
+	"github.com/SaiNageswarS/agent-boot/schema"
+)
+
+// DosageMode decides what happens to an answer that states a dose or potency none of
+// its retrieved sources contain.
+type DosageMode string
+
+const (
+	// DosageAnnotate streams the answer as generated and appends a warning naming the
+	// unsupported dosages.
+	DosageAnnotate DosageMode = "annotate"
+	// DosageBlock holds the answer back until it is complete and withholds it when it
+	// contains unsupported dosages. Answers are not streamed token by token in this mode.
+	DosageBlock DosageMode = "block"
+	// DosageOff disables the dosage check.
+	DosageOff DosageMode = "off"
+)
+
+// ParseDosageMode falls back to DosageAnnotate for empty or unknown values.
+func ParseDosageMode(value string) DosageMode {
+	switch mode := DosageMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case DosageBlock, DosageOff:
+		return mode
+	default:
+		return DosageAnnotate
+	}
+}
+
+const blockedDosageCode = "unsupported_dosage"
+
+const blockedAnswer = "This answer was withheld because it stated dosages or potencies that could not be " +
+	"found in the retrieved sources. Please rephrase the question or consult the materia medica directly."
+
+// Reporter applies the guardrails to an agent's stream. It remembers the tool results
+// the answer was generated from and checks the final answer against them before the
+// StreamComplete reaches the client.
+type Reporter struct {
+	agentboot.ProgressReporter
+	assessment  Assessment
+	mode        DosageMode
+	citations   CitationMode
+	preserve    bool
+	question    string
+	abstention  float64
+	disclaimers *DisclaimerPolicy
+
+	mu          sync.Mutex
+	sources     []Source
+	answer      strings.Builder
+	finalAnswer string
+}
+
+func NewReporter(inner agentboot.ProgressReporter, assessment Assessment, mode DosageMode) *Reporter {
+	return &Reporter{ProgressReporter: inner, assessment: assessment, mode: mode, citations: CitationOff}
+}
+
+// WithCitations enables strict citation enforcement. Like DosageBlock, it holds the
+// answer back until it is complete.
+func (r *Reporter) WithCitations(mode CitationMode) *Reporter {
+	r.citations = mode
+	return r
+}
+
+// WithAbstention replaces an answer whose confidence falls below threshold with an
+// abstention listing the closest sources. The answer still streams as generated; the
+// StreamComplete carries the abstention, so clients must show the completed answer.
+func (r *Reporter) WithAbstention(question string, threshold float64) *Reporter {
+	r.question = question
+	r.abstention = threshold
+	return r
+}
+
+// WithDisclaimers adds the policy's disclaimers for the answer's category to the
+// completed answer. Like the abstention, they arrive with the StreamComplete.
+func (r *Reporter) WithDisclaimers(policy *DisclaimerPolicy) *Reporter {
+	r.disclaimers = policy
+	return r
+}
+
+// PreserveAnswer leaves the answer text as generated, for answers that must stay
+// machine-readable. The emergency notice, unsupported dosages and disclaimers are
+// reported in the completion metadata only, strict citations are not enforced, and in
+// DosageBlock mode an answer with unsupported dosages is replaced by a StreamError.
+func (r *Reporter) PreserveAnswer() *Reporter {
+	r.preserve = true
+	return r
+}
+
+// buffered reports whether answer chunks are held back until the answer is checked.
+func (r *Reporter) buffered() bool {
+	return r.mode == DosageBlock || (!r.preserve && r.citations != CitationOff)
+}
+
+// Begin sends the emergency notice, if any, before the agent starts working.
+func (r *Reporter) Begin() error {
+	if !r.assessment.Emergency || r.preserve {
+		return nil
+	}
+	return r.ProgressReporter.Send(agentboot.NewAnswerChunk(&schema.AnswerChunk{Content: EmergencyNotice}))
+}
+
+func (r *Reporter) Send(event *schema.AgentStreamChunk) error {
+	switch chunk := event.ChunkType.(type) {
+	case *schema.AgentStreamChunk_ToolResultChunk:
+		if result := chunk.ToolResultChunk; result != nil {
+			r.mu.Lock()
+			r.sources = append(r.sources, SourceFromToolResult(result))
+			r.mu.Unlock()
+		}
+
+	case *schema.AgentStreamChunk_Answer:
+		if chunk.Answer != nil {
+			r.mu.Lock()
+			r.answer.WriteString(chunk.Answer.Content)
+			r.mu.Unlock()
+		}
+		if r.buffered() {
+			return nil // sent with the complete answer once it has been checked
+		}
+
+	case *schema.AgentStreamChunk_Complete:
+		if complete := chunk.Complete; complete != nil {
+			return r.complete(complete)
+		}
+	}
+
+	return r.ProgressReporter.Send(event)
+}
+
+func (r *Reporter) complete(complete *schema.StreamComplete) error {
+	r.mu.Lock()
+	answer := complete.Answer
+	if answer == "" {
+		answer = r.answer.String()
+	}
+	sources := slices.Clone(r.sources)
+	r.mu.Unlock()
+
+	if complete.Metadata == nil {
+		complete.Metadata = make(map[string]string)
+	}
+	// categorized as generated, before any warning mentions a dosage
+	category := Categorize(answer)
+
+	abstained := false
+	if r.abstention > 0 {
+		if confidence, ok := AssessConfidence(r.question, answer, sources); ok {
+			complete.Metadata["confidence"] = strconv.FormatFloat(confidence.Score, 'f', 2, 64)
+			if confidence.Score < r.abstention {
+				complete.Metadata["abstained"] = "true"
+				// a machine-readable answer keeps its shape; the metadata says it is unsupported
+				if !r.preserve {
+					answer, abstained = AbstentionAnswer(confidence.Closest), true
+				}
+			}
+		}
+	}
+
+	if r.citations != CitationOff && !r.preserve && !abstained {
+		var citations []Citation
+		answer, citations = EnforceCitations(answer, sources, r.citations)
+
+		unsupported := 0
+		for _, citation := range citations {
+			if !citation.Supported {
+				unsupported++
+			}
+		}
+		encoded, _ := json.Marshal(citations)
+		complete.Metadata["citations"] = string(encoded)
+		complete.Metadata["unsupportedSentences"] = strconv.Itoa(unsupported)
+	}
+
+	var unsupported []string
+	if r.mode != DosageOff && !abstained {
+		texts := make([]string, len(sources))
+		for i, source := range sources {
+			texts[i] = source.text()
+		}
+		unsupported = UnsupportedDosages(answer, texts)
+	}
+	if r.assessment.Emergency {
+		complete.Metadata["safety"] = "emergency"
+	}
+	blocked := false
+	if len(unsupported) > 0 {
+		complete.Metadata["unsupportedDosages"] = strings.Join(unsupported, ",")
+
+		switch {
+		case r.preserve && r.mode == DosageBlock:
+			answer, blocked = "", true
+			if err := r.ProgressReporter.Send(agentboot.NewStreamError(blockedAnswer, blockedDosageCode)); err != nil {
+				return err
+			}
+		case r.preserve:
+			// reported in the metadata only
+		case r.mode == DosageBlock:
+			answer, blocked = blockedAnswer, true
+		default:
+			answer += "\n\n> **Unverified dosage:** " + strings.Join(unsupported, ", ") +
+				" could not be found in the retrieved sources. Verify before use."
+		}
+	}
+	if !r.disclaimers.Empty() && !abstained && !blocked && answer != "" {
+		complete.Metadata["answerCategory"] = string(category)
+		if disclaimers := r.disclaimers.For(category); len(disclaimers) > 0 {
+			complete.Metadata["disclaimers"] = disclaimerNames(disclaimers)
+			if r.preserve {
+				complete.Metadata["disclaimerText"] = strings.TrimSpace(AddDisclaimers("", disclaimers))
+			} else {
+				answer = AddDisclaimers(answer, disclaimers)
+			}
+		}
+	}
+	if r.assessment.Emergency {
+		if r.preserve {
+			complete.Metadata["safetyNotice"] = EmergencyNotice
+		} else {
+			answer = EmergencyNotice + "\n\n" + answer
+		}
+	}
+
+	r.mu.Lock()
+	r.finalAnswer = answer
+	r.mu.Unlock()
+	complete.Answer = answer
+
+	if r.buffered() && answer != "" {
+		if err := r.ProgressReporter.Send(agentboot.NewAnswerChunk(&schema.AnswerChunk{Content: answer})); err != nil {
+			return err
+		}
+	}
+	return r.ProgressReporter.Send(agentboot.NewStreamComplete(complete))
+}
+
+// Sources are the tool results seen so far, in the order they arrived.
+func (r *Reporter) Sources() []Source {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.sources)
+}
+
+// FinalAnswer is the answer as the client received it, after the guardrails; empty
+// until the stream completes.
+func (r *Reporter) FinalAnswer() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.finalAnswer
+}
@@ -0,0 +1,179 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/entities"
+)
+
+var (
+	paragraphBreak = regexp.MustCompile(`\n\s*\n`)
+	sentenceEnd    = regexp.MustCompile(`[.!?]["')\]]*\s+`)
+)
+
+// ChunkMarkdown splits a converted document into chunks: one section per heading, and
+// each section cut into chunks of whole sentences by the chunker config picks for the
+// document's type; see NewChunker. Narrative text gets the windowed chunks the markdown
+// chunking workflow and the sidecar produce. Chunks are chained across sections in
+// document order, and record the chunking parameters they were cut with.
+//
+// Sections are titled by their heading; the workflow's LLM-generated titles are left
+// to it. Page markers left by the PDF converter set the pages each window spans, and
+// the OCR confidence of the scanned ones.
+func ChunkMarkdown(ctx context.Context, sourceUri string, md []byte, config db.ChunkingConfig) ([]db.ChunkModel, error) {
+	source, md := ParseFrontMatter(md)
+
+	chunker, err := NewChunker(DocumentType(source), config)
+	if err != nil {
+		return nil, err
+	}
+	params := chunker.Params()
+
+	sections, err := ParseMarkdownSections(ctx, md, chunker.MinSectionBytes())
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []db.ChunkModel
+	page := 0 // a section starts on the page the one before it ended
+	for idx, sec := range sections {
+		secHash, _ := odm.HashedKey(sec.Body)
+		title := sec.Path[len(sec.Path)-1]
+
+		section := db.ChunkModel{
+			SectionPath:  strings.Join(sec.Path, " | "),
+			SectionIndex: idx + 1,
+			SectionID:    secHash,
+			Title:        title,
+			SourceURI:    sourceUri,
+			Entities:     entities.ChunkTags(append([]string{title}, sec.Path...), sec.Body),
+			Chapter:      sec.Chapter(),
+			Section:      sec.Section(),
+
+			Book:            source.Book,
+			Author:          source.Author,
+			PublicationYear: source.Year,
+			Chunking:        &params,
+		}
+		chunks = append(chunks, windowSection(section, sec.Body, &page, chunker)...)
+	}
+
+	scanned := ocrConfidences(md)
+	for i := range chunks {
+		flagOcrConfidence(&chunks[i], scanned)
+		if i > 0 {
+			chunks[i].PrevChunkID = chunks[i-1].ChunkID
+		}
+		if i < len(chunks)-1 {
+			chunks[i].NextChunkID = chunks[i+1].ChunkID
+		}
+	}
+	return chunks, nil
+}
+
+// flagOcrConfidence records the lowest OCR confidence of the scanned pages a chunk is
+// on, and flags it for review when that is below LowOcrConfidence.
+func flagOcrConfidence(chunk *db.ChunkModel, scanned map[int]float64) {
+	if len(scanned) == 0 || chunk.PageStart == 0 {
+		return
+	}
+
+	found := false
+	for page := chunk.PageStart; page <= chunk.PageEnd; page++ {
+		if confidence, ok := scanned[page]; ok && (!found || confidence < chunk.OcrConfidence) {
+			chunk.OcrConfidence, found = confidence, true
+		}
+	}
+	chunk.NeedsReview = found && chunk.OcrConfidence < LowOcrConfidence
+}
+
+// windowSection cuts a section's body into the chunks chunker splits its sentences
+// into. page is the page the body starts on, and is left at the one it ends on.
+func windowSection(section db.ChunkModel, body string, page *int, chunker Chunker) []db.ChunkModel {
+	sentences, paragraphs, pages := splitParagraphSentences(body, page)
+	tokens := make([]int, len(sentences))
+	for i, sentence := range sentences {
+		tokens[i] = estimateTokens(sentence)
+	}
+
+	var windows []db.ChunkModel
+	for _, span := range chunker.Split(sentences, paragraphs, tokens) {
+		window := section
+		window.ChunkID = fmt.Sprintf("%s_%d", section.SectionID, len(windows))
+		window.WindowIndex = len(windows)
+		window.Sentences = sentences[span.Start:span.End]
+		window.Paragraphs = paragraphs[span.Start:span.End]
+		window.PageStart, window.PageEnd = pages[span.Start], pages[span.End-1]
+		windows = append(windows, window)
+	}
+	return windows
+}
+
+// splitParagraphSentences splits text into sentences, numbering the 0-based paragraph
+// each one is in, and the page it is on. Paragraphs are separated by blank lines, and
+// no sentence spans two. A paragraph that is a page marker moves page on, and one that
+// is a pipe table has a sentence per data row.
+func splitParagraphSentences(text string, page *int) ([]string, []int, []int) {
+	var (
+		sentences  []string
+		paragraphs []int
+		pages      []int
+		paragraph  int
+	)
+	for _, block := range paragraphBreak.Split(text, -1) {
+		if marker, ok := parsePageMarker(block); ok {
+			*page = marker.Page
+			continue
+		}
+
+		var blockSentences []string
+		if rows, ok := parsePipeTable(block); ok {
+			blockSentences = tableRowSentences(rows)
+		} else {
+			blockSentences = splitSentences(block)
+		}
+		if len(blockSentences) == 0 {
+			continue
+		}
+		sentences = append(sentences, blockSentences...)
+		for range blockSentences {
+			paragraphs = append(paragraphs, paragraph)
+			pages = append(pages, *page)
+		}
+		paragraph++
+	}
+	return sentences, paragraphs, pages
+}
+
+// splitSentences ends a sentence at a full stop, question or exclamation mark followed
+// by whitespace, keeping closing quotes and brackets with it. Line breaks within a
+// sentence, as in text extracted from PDFs, become spaces. Abbreviated remedy names
+// end sentences too, as they do with the sidecar's sentencizer.
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for _, loc := range sentenceEnd.FindAllStringIndex(text, -1) {
+		if sentence := collapseSpace(text[start:loc[1]]); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		start = loc[1]
+	}
+	if sentence := collapseSpace(text[start:]); sentence != "" {
+		sentences = append(sentences, sentence)
+	}
+	return sentences
+}
+
+func collapseSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// estimateTokens approximates tokens as four bytes each, as the embedding clients do.
+func estimateTokens(text string) int {
+	return len(text)/4 + 1
+}
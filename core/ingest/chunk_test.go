@@ -0,0 +1,61 @@
+package ingest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkMarkdown(t *testing.T) {
+	mind := strings.Repeat("Great fear and anxiety of mind. ", 130) // over MinSectionBytes, so a section of its own
+	md := "---\nbook: Pocket Manual\nauthor: William Boericke\nyear: 1901\n---\n" +
+		"# Aconitum Napellus\n\n## Mind\n\n" + mind + "\n\nWorse at night.\n\n## Fever\n\nDry burning heat. Compare Gels.\n"
+
+	chunks, err := ChunkMarkdown(t.Context(), "file://boericke.md", []byte(md), db.ChunkingConfig{})
+	require.NoError(t, err)
+	require.Len(t, chunks, 2, "the short fever section is merged into mind, which takes two windows")
+
+	first, second := chunks[0], chunks[1]
+	assert.Equal(t, first.SectionID, second.SectionID)
+	assert.Equal(t, first.SectionID+"_0", first.ChunkID)
+	assert.Equal(t, first.SectionID+"_1", second.ChunkID)
+	assert.Equal(t, []int{0, 1}, []int{first.WindowIndex, second.WindowIndex})
+	assert.Equal(t, "Aconitum Napellus | Mind | Fever", first.SectionPath)
+	assert.Equal(t, "Fever", first.Title)
+	assert.Equal(t, "Aconitum Napellus", first.Chapter)
+	assert.Equal(t, "Mind", first.Section, "where the merged section starts")
+	assert.Zero(t, first.PageStart, "markdown has no pages")
+	assert.Equal(t, "Pocket Manual", first.Book)
+	assert.Equal(t, "William Boericke", first.Author)
+	assert.Equal(t, 1901, first.PublicationYear)
+	assert.Equal(t, "file://boericke.md", first.SourceURI)
+	assert.Contains(t, first.Entities, "remedy:aconitum napellus")
+
+	assert.Equal(t, "", first.PrevChunkID)
+	assert.Equal(t, second.ChunkID, first.NextChunkID)
+	assert.Equal(t, first.ChunkID, second.PrevChunkID)
+	assert.Equal(t, "", second.NextChunkID)
+
+	assert.LessOrEqual(t, tokenCount(first.Sentences), DefaultChunkTokens)
+	assert.Equal(t, []string{"Worse at night.", "Dry burning heat.", "Compare Gels."}, second.Sentences[len(second.Sentences)-3:])
+	assert.Equal(t, []int{1, 2, 2}, second.Paragraphs[len(second.Paragraphs)-3:])
+	assert.Equal(t, first.Sentences[len(first.Sentences)-1], second.Sentences[0], "windows overlap")
+}
+
+func TestSplitSentences(t *testing.T) {
+	assert.Equal(t,
+		[]string{"Fear of death.", "Worse at night (after midnight.)", "Is it Acon.?", "Yes"},
+		splitSentences("Fear of death.  Worse at\nnight (after midnight.) Is it Acon.? Yes"))
+	assert.Empty(t, splitSentences(" \n "))
+}
+
+func tokenCount(sentences []string) int {
+	total := 0
+	for _, sentence := range sentences {
+		total += estimateTokens(sentence)
+	}
+	return total
+}
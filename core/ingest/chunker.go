@@ -0,0 +1,282 @@
+package ingest
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+)
+
+// Chunking strategies.
+const (
+	// ChunkFixed cuts windows of whole sentences of about the same size, overlapping.
+	ChunkFixed = "fixed"
+	// ChunkHeading keeps each heading's section whole, however short, cutting windows
+	// only from sections too long for one chunk.
+	ChunkHeading = "heading"
+	// ChunkSemantic groups a section's paragraphs into chunks, starting a new one where
+	// a paragraph shares few words with the one before it.
+	ChunkSemantic = "semantic"
+)
+
+// Document types, which pick the chunking strategy.
+const (
+	DocumentNarrative   = "narrative"    // materia medicas, textbooks and articles
+	DocumentRepertory   = "repertory"    // a rubric per heading
+	DocumentCaseJournal = "case-journal" // case records, one after another
+)
+
+// Default chunk sizes, in estimated tokens: those of the sidecar's window chunker.
+const (
+	DefaultChunkTokens   = 700
+	DefaultOverlapTokens = 100
+)
+
+var defaultStrategies = map[string]string{
+	DocumentNarrative:   ChunkFixed,
+	DocumentRepertory:   ChunkHeading,
+	DocumentCaseJournal: ChunkSemantic,
+}
+
+var (
+	repertoryTitle   = regexp.MustCompile(`(?i)\brepertor(y|ium)\b`)
+	caseJournalTitle = regexp.MustCompile(`(?i)\b(cases|casebook|case (journal|records|notes|histories|studies))\b`)
+)
+
+// Span is the sentences of a section one chunk holds, from Start up to End.
+type Span struct {
+	Start, End int
+}
+
+// Chunker cuts the sections of a document into chunks.
+type Chunker interface {
+	// Params are recorded on each chunk, so it can be cut again the same way.
+	Params() db.ChunkingParams
+	// MinSectionBytes is the length under which a section is merged into the one
+	// before it; zero keeps every section.
+	MinSectionBytes() int
+	// Split groups a section's sentences into chunks. paragraphs numbers the paragraph
+	// each sentence is in, and tokens estimates its tokens.
+	Split(sentences []string, paragraphs, tokens []int) []Span
+}
+
+// IsDocumentType reports whether documentType is one of the document types.
+func IsDocumentType(documentType string) bool {
+	_, ok := defaultStrategies[documentType]
+	return ok
+}
+
+// DocumentType is the type set in a document's front matter, else a repertory or case
+// journal when its title names one, else narrative.
+func DocumentType(source SourceMetadata) string {
+	switch {
+	case source.Type != "":
+		return source.Type
+	case repertoryTitle.MatchString(source.Book):
+		return DocumentRepertory
+	case caseJournalTitle.MatchString(source.Book):
+		return DocumentCaseJournal
+	default:
+		return DocumentNarrative
+	}
+}
+
+// NewChunker returns the chunker config sets for documentType, or the type's default
+// strategy: fixed windows for narrative text, heading sections for repertories and
+// semantic chunks for case journals. Sizes config leaves at zero are the defaults; a
+// negative overlap is none.
+func NewChunker(documentType string, config db.ChunkingConfig) (Chunker, error) {
+	if !IsDocumentType(documentType) {
+		return nil, errors.New("unknown document type: " + documentType)
+	}
+	strategy := config.Strategies[documentType]
+	if strategy == "" {
+		strategy = defaultStrategies[documentType]
+	}
+
+	params := db.ChunkingParams{
+		DocumentType:  documentType,
+		Strategy:      strategy,
+		MaxTokens:     config.MaxTokens,
+		OverlapTokens: config.OverlapTokens,
+	}
+	if params.MaxTokens == 0 {
+		params.MaxTokens = DefaultChunkTokens
+	}
+	if params.OverlapTokens == 0 {
+		params.OverlapTokens = DefaultOverlapTokens
+	}
+	params.OverlapTokens = max(params.OverlapTokens, 0)
+	if params.MaxTokens < 0 || params.OverlapTokens >= params.MaxTokens {
+		return nil, errors.New("chunk overlap must be less than the chunk size")
+	}
+
+	switch strategy {
+	case ChunkFixed:
+		return fixedChunker{params}, nil
+	case ChunkHeading:
+		return headingChunker{params}, nil
+	case ChunkSemantic:
+		return semanticChunker{params}, nil
+	}
+	return nil, errors.New("unknown chunking strategy: " + strategy)
+}
+
+type fixedChunker struct{ params db.ChunkingParams }
+
+func (c fixedChunker) Params() db.ChunkingParams { return c.params }
+
+func (c fixedChunker) MinSectionBytes() int { return MinSectionBytes }
+
+func (c fixedChunker) Split(sentences []string, paragraphs, tokens []int) []Span {
+	return windows(tokens, 0, len(tokens), c.params.MaxTokens, c.params.OverlapTokens)
+}
+
+type headingChunker struct{ params db.ChunkingParams }
+
+func (c headingChunker) Params() db.ChunkingParams { return c.params }
+
+func (c headingChunker) MinSectionBytes() int { return 0 }
+
+func (c headingChunker) Split(sentences []string, paragraphs, tokens []int) []Span {
+	total := 0
+	for _, count := range tokens {
+		total += count
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	if total <= c.params.MaxTokens {
+		return []Span{{0, len(tokens)}}
+	}
+	return windows(tokens, 0, len(tokens), c.params.MaxTokens, c.params.OverlapTokens)
+}
+
+// A paragraph sharing less than this share of its words with the one before starts a
+// semantic chunk, once the chunk has a quarter of its most tokens.
+const semanticBreakSimilarity = 0.1
+
+type semanticChunker struct{ params db.ChunkingParams }
+
+func (c semanticChunker) Params() db.ChunkingParams { return c.params }
+
+func (c semanticChunker) MinSectionBytes() int { return MinSectionBytes }
+
+// Split ends a chunk before a paragraph that would take it past the most tokens, or
+// that moves on to another topic. A paragraph longer than a chunk is cut into fixed
+// windows. Each chunk after a topic or size break starts with the last sentences of
+// the one before, up to the overlap.
+func (c semanticChunker) Split(sentences []string, paragraphs, tokens []int) []Span {
+	var (
+		spans     []Span
+		start     = 0 // of the chunk being grown
+		count     = 0 // its tokens
+		prevWords map[string]bool
+		paraStart = 0
+		maxTokens = c.params.MaxTokens
+		overlap   = c.params.OverlapTokens
+		minChunk  = maxTokens / 4
+	)
+
+	for paraStart < len(sentences) {
+		paraEnd := paraStart + 1
+		for paraEnd < len(sentences) && paragraphs[paraEnd] == paragraphs[paraStart] {
+			paraEnd++
+		}
+		paraTokens := 0
+		for _, n := range tokens[paraStart:paraEnd] {
+			paraTokens += n
+		}
+		words := contentWords(strings.Join(sentences[paraStart:paraEnd], " "))
+
+		switch {
+		case paraTokens > maxTokens:
+			if start < paraStart {
+				spans = append(spans, Span{start, paraStart})
+			}
+			spans = append(spans, windows(tokens, paraStart, paraEnd, maxTokens, overlap)...)
+			start, count = paraEnd, 0
+		case start < paraStart && (count+paraTokens > maxTokens || (count >= minChunk && similarity(prevWords, words) < semanticBreakSimilarity)):
+			spans = append(spans, Span{start, paraStart})
+			prev := start
+			start, count = paraStart, paraTokens
+			// carry the end of the chunk before over, while it fits
+			for carried := 0; start-1 > prev && carried+tokens[start-1] <= overlap && count+tokens[start-1] <= maxTokens; {
+				start--
+				carried += tokens[start]
+				count += tokens[start]
+			}
+		default:
+			count += paraTokens
+		}
+
+		prevWords = words
+		paraStart = paraEnd
+	}
+	if start < len(sentences) {
+		spans = append(spans, Span{start, len(sentences)})
+	}
+	return spans
+}
+
+// windows cuts the sentences from..to into windows of at most maxTokens, each starting
+// about maxTokens-overlap after the one before on a sentence boundary. A sentence
+// longer than a window gets a window of its own.
+func windows(tokens []int, from, to, maxTokens, overlap int) []Span {
+	stride := maxTokens - overlap
+
+	var spans []Span
+	for start := from; start < to; {
+		end, count := start, 0
+		for end < to && count+tokens[end] <= maxTokens {
+			count += tokens[end]
+			end++
+		}
+		if end == start {
+			end = start + 1
+		}
+		spans = append(spans, Span{start, end})
+		if end == to {
+			break
+		}
+
+		next, moved := start, 0
+		for next < end && moved+tokens[next] < stride {
+			moved += tokens[next]
+			next++
+		}
+		// a sentence as long as the stride still moves the window on
+		start = max(next, start+1)
+	}
+	return spans
+}
+
+// contentWords are the lower-cased words of at least four letters in text, which
+// leaves out most function words.
+func contentWords(text string) map[string]bool {
+	words := map[string]bool{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) >= 4 {
+			words[word] = true
+		}
+	}
+	return words
+}
+
+// similarity is the Jaccard index of two word sets.
+func similarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for word := range a {
+		if b[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -0,0 +1,80 @@
+package ingest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewChunker(t *testing.T) {
+	chunker, err := NewChunker(DocumentNarrative, db.ChunkingConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, db.ChunkingParams{DocumentType: "narrative", Strategy: ChunkFixed, MaxTokens: 700, OverlapTokens: 100}, chunker.Params())
+
+	chunker, err = NewChunker(DocumentRepertory, db.ChunkingConfig{MaxTokens: 300, OverlapTokens: -1})
+	require.NoError(t, err)
+	assert.Equal(t, db.ChunkingParams{DocumentType: "repertory", Strategy: ChunkHeading, MaxTokens: 300}, chunker.Params())
+
+	chunker, err = NewChunker(DocumentCaseJournal, db.ChunkingConfig{Strategies: map[string]string{"case-journal": "fixed"}})
+	require.NoError(t, err)
+	assert.Equal(t, ChunkFixed, chunker.Params().Strategy)
+
+	_, err = NewChunker("letters", db.ChunkingConfig{})
+	assert.Error(t, err)
+	_, err = NewChunker(DocumentNarrative, db.ChunkingConfig{Strategies: map[string]string{"narrative": "random"}})
+	assert.Error(t, err)
+	_, err = NewChunker(DocumentNarrative, db.ChunkingConfig{MaxTokens: 100, OverlapTokens: 100})
+	assert.Error(t, err)
+}
+
+func TestDocumentType(t *testing.T) {
+	assert.Equal(t, DocumentRepertory, DocumentType(SourceMetadata{Book: "Repertory of the Homoeopathic Materia Medica"}))
+	assert.Equal(t, DocumentCaseJournal, DocumentType(SourceMetadata{Book: "Clinical Cases"}))
+	assert.Equal(t, DocumentNarrative, DocumentType(SourceMetadata{Book: "Pocket Manual"}))
+	assert.Equal(t, DocumentNarrative, DocumentType(SourceMetadata{Book: "Clinical Cases", Type: "narrative"}), "front matter wins")
+}
+
+func TestChunkMarkdownHeadingStrategy(t *testing.T) {
+	md := "---\nbook: Kent's Repertory\n---\n# Mind\n\n## Fear\n\nAcon., Ars., Gels.\n\n## Restlessness\n\nArs., Rhus-t.\n"
+
+	chunks, err := ChunkMarkdown(t.Context(), "file://kent.md", []byte(md), db.ChunkingConfig{})
+	require.NoError(t, err)
+	require.Len(t, chunks, 2, "short rubrics are not merged")
+	assert.Equal(t, "Mind | Fear", chunks[0].SectionPath)
+	assert.Equal(t, "Mind | Restlessness", chunks[1].SectionPath)
+	assert.Equal(t, &db.ChunkingParams{DocumentType: "repertory", Strategy: "heading", MaxTokens: 700, OverlapTokens: 100}, chunks[1].Chunking)
+}
+
+func TestSemanticChunkerSplitsAtTopicShifts(t *testing.T) {
+	fever := strings.Repeat("The fever patient had burning heat and thirst. ", 8)
+	fever2 := strings.Repeat("Burning fever heat returned with thirst at night. ", 8)
+	injury := strings.Repeat("A fall caused bruised muscles and soreness. ", 8)
+	body := fever + "\n\n" + fever2 + "\n\n" + injury
+
+	chunker, err := NewChunker(DocumentCaseJournal, db.ChunkingConfig{MaxTokens: 400, OverlapTokens: 20})
+	require.NoError(t, err)
+
+	page := 0
+	sentences, paragraphs, _ := splitParagraphSentences(body, &page)
+	tokens := make([]int, len(sentences))
+	for i, sentence := range sentences {
+		tokens[i] = estimateTokens(sentence)
+	}
+
+	spans := chunker.Split(sentences, paragraphs, tokens)
+	require.Len(t, spans, 2)
+	assert.Equal(t, Span{0, 16}, spans[0], "the two fever paragraphs stay together")
+	assert.Equal(t, Span{15, 24}, spans[1], "the injury case starts a chunk, overlapping by a sentence")
+}
+
+func TestWindowsMoveOnPastLongSentences(t *testing.T) {
+	spans := windows([]int{650, 650, 10}, 0, 3, 700, 100)
+	assert.Equal(t, []Span{{0, 1}, {1, 3}}, spans)
+
+	// a window shorter than the stride is followed by the next sentence, not past it
+	spans = windows([]int{500, 300}, 0, 2, 700, 100)
+	assert.Equal(t, []Span{{0, 1}, {1, 2}}, spans)
+}
@@ -0,0 +1,40 @@
+package ingest
+
+import (
+	"context"
+	"path"
+	"strings"
+)
+
+// Converter reads a source document into a Document, with the book it is from where
+// the format records it.
+type Converter func(ctx context.Context, name string, data []byte) (*Document, error)
+
+// DefaultConverters converts markdown, PDF, EPUB, DOCX and HTML documents by file
+// extension. Scanned PDF pages are read with Tesseract.
+func DefaultConverters() map[string]Converter {
+	return map[string]Converter{
+		".md":       convertMarkdown,
+		".markdown": convertMarkdown,
+		".pdf":      PdfConverter(TesseractOCR{}),
+		".epub":     convertEpub,
+		".docx":     convertDocx,
+		".html":     convertHtml,
+		".htm":      convertHtml,
+	}
+}
+
+// Extension is the lower-cased extension converters are looked up by.
+func Extension(name string) string {
+	return strings.ToLower(path.Ext(name))
+}
+
+func convertMarkdown(_ context.Context, _ string, data []byte) (*Document, error) {
+	return ParseDocument(data), nil
+}
+
+// documentTitle is a document's file name without its extension.
+func documentTitle(name string) string {
+	base := path.Base(name)
+	return strings.TrimSuffix(base, path.Ext(base))
+}
@@ -0,0 +1,435 @@
+package ingest
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/entities"
+)
+
+// Kinds of identifier a Deidentifier finds.
+const (
+	IdentifierName    = "name"
+	IdentifierPhone   = "phone"
+	IdentifierEmail   = "email"
+	IdentifierAddress = "address"
+	IdentifierID      = "id" // medical record, file, insurance and national ID numbers
+)
+
+const (
+	maxCueAddressLength = 120
+	minPhoneDigits      = 7
+	// numbers this long are phone numbers without a cue, a country code or an area code
+	minBarePhoneDigits = 10
+	maxPhoneDigits     = 15
+)
+
+// personName is one to three capitalized words, maybe with initials: "Mary Smith",
+// "J. R. Smith".
+const personName = `(?:[A-Z]\.\s?)*[A-Z][\p{L}'’-]+(?:\s+(?:[A-Z]\.\s?)*[A-Z][\p{L}'’-]+){0,2}`
+
+var (
+	emailPattern = regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}\b`)
+	// digit groups separated by single spaces, dots or dashes, maybe after a country
+	// code or an area code in brackets
+	phonePattern = regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{1,5}\)[ .-]?)?\d{2,5}(?:[ .-]?\d{2,5}){1,4}`)
+	phoneCue     = regexp.MustCompile(`(?i)\b(?:tel|telephone|phone|ph|mob|mobile|cell|contact|fax|whatsapp)\.?\s*(?:no\.?|number|#)?\s*[:-]?\s*$`)
+
+	ssnPattern = regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)
+	// a label an ID number follows; words that often mean something else need "no." or
+	// "number" after them
+	idLabel = regexp.MustCompile(`(?i)\b(?:(?:mrn|uhid|ssn|aadhaa?r|passport|nhs|medicare|medicaid|patient id)(?:\s*(?:no\.?|number|#))?|(?:opd|ipd|reg|registration|file|record|case|chart|insurance|policy|member|card|id)\s*(?:no\.?|number|#))\s*[:#-]?\s*`)
+	idValue = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9/-]*`)
+
+	streetAddress = regexp.MustCompile(`(?:\b(?i:flat|apt|apartment|unit|suite|house)\.?\s*#?\s*\d[0-9A-Za-z/-]*,?\s+)?\b\d{1,5}[A-Za-z]?(?:/\d+)?,?(?:\s+[A-Z][\p{L}'’.-]*){1,4}\s+(?i:street|st|road|rd|avenue|ave|lane|ln|drive|dr|boulevard|blvd|way|court|ct|place|pl|terrace|crescent|close|highway|hwy|marg|nagar|colony|layout|sector)\b\.?(?:,\s*[A-Z][\p{L}'’-]*(?:\s+[A-Z][\p{L}'’-]*){0,2}){0,3}(?:,?\s+[A-Z]{2}\b)?(?:[, -]+\d{5,6}(?:-\d{4})?\b)?`)
+	addressCue    = regexp.MustCompile(`(?i)\b(?:address|addr|resides at|residing at|residence|lives at|living at|r/o)\b\.?\s*[:-]?\s*`)
+	// abbreviations an address's full stop may follow without ending it
+	addressAbbreviations = []string{"st", "rd", "ave", "apt", "no", "dr", "ln", "blvd", "h", "nr", "opp"}
+
+	honorificName = regexp.MustCompile(`\b(?:Mr|Mrs|Ms|Miss|Mx|Master|Dr|Prof|Shri|Sri|Smt|Kumari|Kum|Baby)\.?\s+(` + personName + `)`)
+	labelledName  = regexp.MustCompile(`(?i:\b(?:patient'?s? name|name|patient|pt|informant|mother|father|husband|wife|spouse|son|daughter|guardian|parent|attendant|caregiver|referred by)\s*[:-]\s*)(` + personName + `)`)
+	agedName      = regexp.MustCompile(`\b((?:[A-Z][\p{L}'’-]+\s+)?[A-Z][\p{L}'’-]+),?\s+(?:a |an |aged )?\d{1,3}[\s-]*(?:years?|yrs?|y/o|yo)\b`)
+
+	initials = regexp.MustCompile(`^[\s.A-Z]*$`)
+)
+
+// notNames are capitalized words the name cues catch that don't name anyone.
+var notNames = wordSet(`mr mrs ms miss mx master dr prof shri sri smt kumari kum baby
+	patient pt female male unknown anonymous none nil na same self child boy girl man woman
+	lady gentleman infant adult elderly the a an this that he she they his her their then now
+	case age aged mother father seen dear new old young january february march april may june
+	july august september october november december monday tuesday wednesday thursday friday
+	saturday sunday`)
+
+// Deidentifier takes patients' identifiers out of documents before they are chunked,
+// as a tenant's DeidentificationConfig says. It finds them with patterns and cues, not
+// a language model, so it is thorough where case notes follow the usual forms:
+//   - name: words after an honorific ("Mrs. Smith"), a label ("Patient: Mary Smith",
+//     "Mother - Anne") or before an age ("Mary, 45 years"), and every other capitalized
+//     mention of those words in the document. Remedies and AllowedNames are not names.
+//   - phone: numbers of 10 to 15 digits, and of 7 or more after a country code, an area
+//     code in brackets, or a cue such as "Tel:".
+//   - email: email addresses.
+//   - address: street addresses ("12 Park Lane, Pune 411001"), and what follows a cue
+//     such as "Address:" or "lives at" up to the end of its line or sentence.
+//   - id: numbers after a label such as "MRN", "UHID" or "File No.", and US social
+//     security numbers.
+//   - rules' kinds: what the tenant's rules match.
+type Deidentifier struct {
+	documents string
+	policies  map[string]string
+	allowed   map[string]bool
+	rules     []deidentificationRule
+}
+
+type deidentificationRule struct {
+	kind string
+	re   *regexp.Regexp
+}
+
+// NewDeidentifier reads a tenant's config. Unknown settings and rules that don't
+// compile fail, rather than let identifiers through.
+func NewDeidentifier(config db.DeidentificationConfig) (*Deidentifier, error) {
+	d := &Deidentifier{documents: config.Documents, policies: map[string]string{}, allowed: map[string]bool{}}
+	switch config.Documents {
+	case "", db.DeidentifyCaseJournals, db.DeidentifyAll, db.DeidentifyOff:
+	default:
+		return nil, errors.New("deidentification documents must be case-journal, all or off")
+	}
+	for kind, policy := range config.Policies {
+		switch policy {
+		case db.RedactionMask, db.RedactionPseudonym, db.RedactionKeep:
+			d.policies[strings.ToLower(kind)] = policy
+		default:
+			return nil, errors.New("deidentification policy for " + kind + " must be redact, pseudonymize or keep")
+		}
+	}
+	for _, name := range config.AllowedNames {
+		d.allowed[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	for _, rule := range config.Rules {
+		re, err := regexp.Compile("(?i)" + rule.Pattern)
+		if err != nil || rule.Pattern == "" {
+			return nil, errors.New("deidentification rule " + strconv.Quote(rule.Pattern) + " is not a valid pattern")
+		}
+		kind := strings.ToLower(rule.Kind)
+		if kind == "" {
+			kind = IdentifierID
+		}
+		d.rules = append(d.rules, deidentificationRule{kind: kind, re: re})
+	}
+	return d, nil
+}
+
+// Applies reports whether documents chunked as documentType are de-identified: case
+// journals by default, every document or none as configured.
+func (d *Deidentifier) Applies(documentType string) bool {
+	if d == nil {
+		return false
+	}
+	switch d.documents {
+	case db.DeidentifyAll:
+		return true
+	case db.DeidentifyOff:
+		return false
+	}
+	return documentType == DocumentCaseJournal
+}
+
+// Deidentify replaces the identifiers in the document's title, headings, paragraphs,
+// tables and figure captions, and returns how many of each kind it replaced. A
+// redacted identifier becomes its kind, as [PHONE]; a pseudonymized one its kind and a
+// number, the same for every mention of it in the document, as [NAME-2].
+func (d *Deidentifier) Deidentify(doc *Document) map[string]int {
+	texts := []*string{&doc.Source.Book}
+	for i := range doc.Blocks {
+		block := &doc.Blocks[i]
+		switch block.Kind {
+		case BlockHeading, BlockParagraph, BlockFigure:
+			texts = append(texts, &block.Text)
+		case BlockTable:
+			for _, row := range block.Rows {
+				for j := range row {
+					texts = append(texts, &row[j])
+				}
+			}
+		}
+	}
+
+	r := &redaction{Deidentifier: d, names: map[string]int{}, numbers: map[string]map[string]int{}, counts: map[string]int{}}
+	if d.policy(IdentifierName) != db.RedactionKeep {
+		// a name may be introduced after its first mention
+		for _, text := range texts {
+			r.findNames(*text)
+		}
+		r.compileNames()
+	}
+	for _, text := range texts {
+		*text = r.redact(*text)
+	}
+	return r.counts
+}
+
+func (d *Deidentifier) policy(kind string) string {
+	if policy, ok := d.policies[kind]; ok {
+		return policy
+	}
+	return db.RedactionMask
+}
+
+// redaction de-identifies one document.
+type redaction struct {
+	*Deidentifier
+	names     map[string]int // words of the names found → the number of the name they are from
+	nameCount int
+	nameWord  *regexp.Regexp // any of names; nil when there are none
+
+	numbers map[string]map[string]int // pseudonym numbers of identifiers, by kind and identifier
+	counts  map[string]int
+}
+
+type identifierSpan struct {
+	start, end int
+	kind, key  string
+}
+
+// findNames records the names text introduces with a cue.
+func (r *redaction) findNames(text string) {
+	for _, re := range []*regexp.Regexp{honorificName, labelledName, agedName} {
+		for _, match := range re.FindAllStringSubmatch(text, -1) {
+			name := match[1]
+			if r.allowed[strings.ToLower(name)] {
+				continue
+			}
+			if _, remedy := entities.RemedyName(name); remedy {
+				continue
+			}
+			var words []string
+			for _, word := range strings.Fields(name) {
+				key := strings.ToLower(word)
+				_, remedy := entities.RemedyName(word)
+				if strings.HasSuffix(word, ".") || notNames[key] || r.allowed[key] || remedy {
+					continue
+				}
+				words = append(words, word)
+			}
+			if len(words) == 0 {
+				continue
+			}
+			r.nameCount++
+			for _, word := range words {
+				if _, ok := r.names[word]; !ok {
+					r.names[word] = r.nameCount
+				}
+			}
+		}
+	}
+}
+
+func (r *redaction) compileNames() {
+	if len(r.names) == 0 {
+		return
+	}
+	words := make([]string, 0, len(r.names))
+	for word := range r.names {
+		words = append(words, regexp.QuoteMeta(word))
+	}
+	// longest first, so "Anne" doesn't match the start of "Annette"
+	slices.SortFunc(words, func(a, b string) int { return len(b) - len(a) })
+	r.nameWord = regexp.MustCompile(`\b(?:` + strings.Join(words, "|") + `)\b`)
+}
+
+// redact replaces the identifiers in text.
+func (r *redaction) redact(text string) string {
+	if strings.TrimSpace(text) == "" {
+		return text
+	}
+
+	var spans []identifierSpan
+	addKey := func(kind string, start, end int, key string) {
+		if start < end && r.policy(kind) != db.RedactionKeep {
+			spans = append(spans, identifierSpan{start: start, end: end, kind: kind, key: key})
+		}
+	}
+	add := func(kind string, start, end int) {
+		addKey(kind, start, end, strings.ToLower(text[start:end]))
+	}
+
+	for _, span := range emailPattern.FindAllStringIndex(text, -1) {
+		add(IdentifierEmail, span[0], span[1])
+	}
+	for _, span := range ssnPattern.FindAllStringIndex(text, -1) {
+		add(IdentifierID, span[0], span[1])
+	}
+	for _, span := range idLabel.FindAllStringIndex(text, -1) {
+		value := idValue.FindString(text[span[1]:])
+		if strings.ContainsFunc(value, unicode.IsDigit) {
+			add(IdentifierID, span[1], span[1]+len(value))
+		}
+	}
+	for _, span := range phonePattern.FindAllStringIndex(text, -1) {
+		if isPhone(text, span[0], span[1]) {
+			// however it is written, with a country code or without
+			digits := strings.Map(func(r rune) rune {
+				if unicode.IsDigit(r) {
+					return r
+				}
+				return -1
+			}, text[span[0]:span[1]])
+			addKey(IdentifierPhone, span[0], span[1], digits[max(0, len(digits)-minBarePhoneDigits):])
+		}
+	}
+	for _, span := range streetAddress.FindAllStringIndex(text, -1) {
+		add(IdentifierAddress, span[0], span[1])
+	}
+	for _, span := range addressCue.FindAllStringIndex(text, -1) {
+		start, end := span[1], cueAddressEnd(text, span[1])
+		// "lives at home with her husband" is not an address
+		if value := text[start:end]; strings.ContainsFunc(value, unicode.IsDigit) || (value != "" && unicode.IsUpper([]rune(value)[0])) {
+			add(IdentifierAddress, start, end)
+		}
+	}
+	for _, rule := range r.rules {
+		for _, span := range rule.re.FindAllStringIndex(text, -1) {
+			add(rule.kind, span[0], span[1])
+		}
+	}
+	if r.nameWord != nil {
+		for _, span := range r.nameWord.FindAllStringIndex(text, -1) {
+			addKey(IdentifierName, span[0], span[1], strconv.Itoa(r.names[text[span[0]:span[1]]]))
+		}
+	}
+	if len(spans) == 0 {
+		return text
+	}
+
+	// of overlapping identifiers, the one starting first, or else the longest, is kept
+	slices.SortFunc(spans, func(a, b identifierSpan) int {
+		if a.start != b.start {
+			return a.start - b.start
+		}
+		return b.end - a.end
+	})
+	kept := spans[:0]
+	for _, span := range spans {
+		if len(kept) == 0 || span.start >= kept[len(kept)-1].end {
+			kept = append(kept, span)
+			continue
+		}
+		// a name's words, apart or with initials between them, are one name
+		last := &kept[len(kept)-1]
+		if span.kind == IdentifierName && last.kind == IdentifierName {
+			last.end = max(last.end, span.end)
+		}
+	}
+	merged := kept[:0]
+	for _, span := range kept {
+		if n := len(merged); n > 0 && span.kind == IdentifierName && merged[n-1].kind == IdentifierName && initials.MatchString(text[merged[n-1].end:span.start]) {
+			merged[n-1].end = span.end
+			continue
+		}
+		merged = append(merged, span)
+	}
+
+	var b strings.Builder
+	at := 0
+	for _, span := range merged {
+		b.WriteString(text[at:span.start])
+		b.WriteString(r.replacement(span))
+		at = span.end
+		r.counts[span.kind]++
+	}
+	b.WriteString(text[at:])
+	return b.String()
+}
+
+// replacement is what an identifier is replaced by under its kind's policy.
+func (r *redaction) replacement(span identifierSpan) string {
+	label := strings.ToUpper(span.kind)
+	if r.policy(span.kind) != db.RedactionPseudonym {
+		return "[" + label + "]"
+	}
+	if span.kind == IdentifierName {
+		return "[" + label + "-" + span.key + "]"
+	}
+	numbers := r.numbers[span.kind]
+	if numbers == nil {
+		numbers = map[string]int{}
+		r.numbers[span.kind] = numbers
+	}
+	if _, ok := numbers[span.key]; !ok {
+		numbers[span.key] = len(numbers) + 1
+	}
+	return "[" + label + "-" + strconv.Itoa(numbers[span.key]) + "]"
+}
+
+// isPhone reports whether text[start:end], a run of digit groups, is a phone number
+// rather than a date, a dose or part of a longer number or word.
+func isPhone(text string, start, end int) bool {
+	if start > 0 {
+		if r := rune(text[start-1]); unicode.IsLetter(r) || unicode.IsDigit(r) || r == '/' {
+			return false
+		}
+	}
+	if end < len(text) {
+		if r := rune(text[end]); unicode.IsLetter(r) || unicode.IsDigit(r) || r == '/' {
+			return false
+		}
+	}
+	digits := 0
+	for _, r := range text[start:end] {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+	if digits < minPhoneDigits || digits > maxPhoneDigits {
+		return false
+	}
+	return digits >= minBarePhoneDigits || text[start] == '+' || text[start] == '(' || phoneCue.MatchString(text[:start])
+}
+
+// cueAddressEnd is where the address starting at start ends: at the end of its line or
+// sentence, or of a clause after a semicolon, and within maxCueAddressLength.
+func cueAddressEnd(text string, start int) int {
+	limit := min(len(text), start+maxCueAddressLength)
+	for i := start; i < limit; i++ {
+		switch text[i] {
+		case '\n', ';':
+			return trimmedEnd(text, start, i)
+		case '.':
+			if i+1 < len(text) && text[i+1] != ' ' && text[i+1] != '\n' {
+				continue
+			}
+			word := text[start:i]
+			if j := strings.LastIndexAny(word, " ,"); j >= 0 {
+				word = word[j+1:]
+			}
+			if !slices.Contains(addressAbbreviations, strings.ToLower(word)) {
+				return trimmedEnd(text, start, i)
+			}
+		}
+	}
+	if limit < len(text) {
+		if j := strings.LastIndexByte(text[start:limit], ' '); j > 0 {
+			limit = start + j
+		}
+	}
+	return trimmedEnd(text, start, limit)
+}
+
+func trimmedEnd(text string, start, end int) int {
+	return start + len(strings.TrimRight(text[start:end], " ,"))
+}
+
+func wordSet(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
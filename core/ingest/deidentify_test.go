@@ -0,0 +1,106 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func caseNotes() *Document {
+	return &Document{
+		Source: SourceMetadata{Book: "Case of Mrs. Mary Fernandes", Author: "Dr. Rao", Type: DocumentCaseJournal},
+		Blocks: []Block{
+			{Kind: BlockHeading, Text: "Case 12: Mary, 45 years", Level: 1},
+			{Kind: BlockParagraph, Text: "Patient: Mary Fernandes. MRN: 20231187. Mobile: 98450 12345. Email mary.f@example.com."},
+			{Kind: BlockParagraph, Text: "Address: 14 Rose Villa, MG Road, Pune 411001. Mother - Anne. Lives at home with her husband."},
+			{Kind: BlockParagraph, Text: "Fernandes complained of fear of death. Aconite 200 was given on 12.03.2023; Anne called on +91 98450 12345."},
+			{Kind: BlockTable, Rows: [][]string{{"Date", "Remedy", "Seen by"}, {"12.03.2023", "Aconite", "Mary"}}},
+			{Kind: BlockFigure, Text: "Fig. 1 Mary's tongue", Label: "Fig. 1"},
+		},
+	}
+}
+
+func TestDeidentify(t *testing.T) {
+	deid, err := NewDeidentifier(db.DeidentificationConfig{})
+	require.NoError(t, err)
+
+	doc := caseNotes()
+	counts := deid.Deidentify(doc)
+
+	assert.Equal(t, "Case of Mrs. [NAME]", doc.Source.Book)
+	assert.Equal(t, "Dr. Rao", doc.Source.Author, "the author is not a patient")
+	assert.Equal(t, "Case 12: [NAME], 45 years", doc.Blocks[0].Text)
+	assert.Equal(t, "Patient: [NAME]. MRN: [ID]. Mobile: [PHONE]. Email [EMAIL].", doc.Blocks[1].Text)
+	assert.Equal(t, "Address: [ADDRESS]. Mother - [NAME]. Lives at home with her husband.", doc.Blocks[2].Text)
+	assert.Equal(t, "[NAME] complained of fear of death. Aconite 200 was given on 12.03.2023; [NAME] called on [PHONE].", doc.Blocks[3].Text,
+		"remedies, doses and dates are kept")
+	assert.Equal(t, [][]string{{"Date", "Remedy", "Seen by"}, {"12.03.2023", "Aconite", "[NAME]"}}, doc.Blocks[4].Rows)
+	assert.Equal(t, "Fig. 1 [NAME]'s tongue", doc.Blocks[5].Text)
+	assert.Equal(t, map[string]int{IdentifierName: 8, IdentifierPhone: 2, IdentifierEmail: 1, IdentifierAddress: 1, IdentifierID: 1}, counts)
+}
+
+func TestDeidentifyPolicies(t *testing.T) {
+	deid, err := NewDeidentifier(db.DeidentificationConfig{
+		Policies:     map[string]string{IdentifierName: db.RedactionPseudonym, IdentifierPhone: db.RedactionPseudonym, IdentifierAddress: db.RedactionKeep},
+		AllowedNames: []string{"Anne"},
+		Rules:        []db.DeidentificationRule{{Kind: "ward", Pattern: `\bward \d+\b`}, {Pattern: `HMC-\d{4}`}},
+	})
+	require.NoError(t, err)
+
+	doc := caseNotes()
+	doc.Blocks = append(doc.Blocks, Block{Kind: BlockParagraph, Text: "Admitted to Ward 7 as HMC-1182. Call 98450-12345 or 020 2612 0000."})
+	counts := deid.Deidentify(doc)
+
+	assert.Equal(t, "Case of Mrs. [NAME-1]", doc.Source.Book)
+	assert.Equal(t, "Case 12: [NAME-1], 45 years", doc.Blocks[0].Text, "every mention of a name has its number")
+	assert.Equal(t, "Patient: [NAME-1]. MRN: [ID]. Mobile: [PHONE-1]. Email [EMAIL].", doc.Blocks[1].Text)
+	assert.Equal(t, "Address: 14 Rose Villa, MG Road, Pune 411001. Mother - Anne. Lives at home with her husband.", doc.Blocks[2].Text,
+		"addresses are kept and Anne is allowed")
+	assert.Contains(t, doc.Blocks[3].Text, "Anne called on [PHONE-1]", "the same number however it is written")
+	assert.Equal(t, "Admitted to [WARD] as [ID]. Call [PHONE-1] or [PHONE-2].", doc.Blocks[6].Text)
+	assert.NotContains(t, counts, IdentifierAddress)
+	assert.Equal(t, 1, counts["ward"])
+}
+
+func TestDeidentifyNames(t *testing.T) {
+	deid, err := NewDeidentifier(db.DeidentificationConfig{})
+	require.NoError(t, err)
+
+	doc := &Document{Blocks: []Block{
+		{Kind: BlockParagraph, Text: "Seen with Sulphur and Pulsatilla. Rakesh felt better in May."},
+		{Kind: BlockParagraph, Text: "Shri Rakesh K. Sharma, a 38-year-old teacher; referred by: Dr. Iyer."},
+		{Kind: BlockParagraph, Text: "Patient: Pulsatilla"},
+	}}
+	deid.Deidentify(doc)
+
+	assert.Equal(t, "Seen with Sulphur and Pulsatilla. [NAME] felt better in May.", doc.Blocks[0].Text, "a name introduced later")
+	assert.Equal(t, "Shri [NAME], a 38-year-old teacher; referred by: Dr. [NAME].", doc.Blocks[1].Text, "initials are part of the name")
+	assert.Equal(t, "Patient: Pulsatilla", doc.Blocks[2].Text, "remedies are not names")
+}
+
+func TestDeidentifierApplies(t *testing.T) {
+	var none *Deidentifier
+	assert.False(t, none.Applies(DocumentCaseJournal))
+
+	deid, err := NewDeidentifier(db.DeidentificationConfig{})
+	require.NoError(t, err)
+	assert.True(t, deid.Applies(DocumentCaseJournal))
+	assert.False(t, deid.Applies(DocumentNarrative))
+
+	deid, err = NewDeidentifier(db.DeidentificationConfig{Documents: db.DeidentifyAll})
+	require.NoError(t, err)
+	assert.True(t, deid.Applies(DocumentNarrative))
+
+	deid, err = NewDeidentifier(db.DeidentificationConfig{Documents: db.DeidentifyOff})
+	require.NoError(t, err)
+	assert.False(t, deid.Applies(DocumentCaseJournal))
+
+	_, err = NewDeidentifier(db.DeidentificationConfig{Documents: "some"})
+	assert.Error(t, err)
+	_, err = NewDeidentifier(db.DeidentificationConfig{Policies: map[string]string{IdentifierName: "hash"}})
+	assert.Error(t, err)
+	_, err = NewDeidentifier(db.DeidentificationConfig{Rules: []db.DeidentificationRule{{Pattern: "(unclosed"}}})
+	assert.Error(t, err)
+}
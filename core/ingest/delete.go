@@ -0,0 +1,218 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/SaiNageswarS/go-api-boot/config"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// UploadsPrefix is where documents sent to the Ingestion API are saved in the tenant's
+// bucket.
+const UploadsPrefix = "uploads/"
+
+// ErrDocumentNotFound is returned for a source with neither chunks nor ingestion progress.
+var ErrDocumentNotFound = errors.New("document not found")
+
+// Deletion counts what DeleteDocument removed.
+type Deletion struct {
+	Chunks        int // of every corpus version, live or retired
+	LiveChunks    int // of them, those that were searchable
+	Embeddings    int
+	TableRows     int
+	Figures       int
+	Images        int   // figure images deleted from the bucket
+	Uploads       int   // originals uploaded through the Ingestion API, deleted from the bucket
+	Quarantined   int   // chunks held for review, and reviews of chunks
+	Duplicates    int   // copies of the document, left without chunks
+	CorpusVersion int64 // recording the deletion
+}
+
+// DeleteDocument removes a source document from the tenant's corpus: its chunks of every
+// corpus version, their vectors, its table rows, figures and their images, the original
+// uploaded through the Ingestion API, quarantined chunks and ingestion progress, so
+// ingesting it again starts over. Vectors go first, so a deletion that fails part way
+// leaves no vector without its chunk. Files go before the records that locate them, and
+// the progress record last, so the deletion can be run again to finish it.
+//
+// The deletion is recorded as a corpus version retiring the chunks that were live, and
+// cached answers, which may cite them, are dropped.
+func DeleteDocument(ctx context.Context, mongo odm.MongoClient, blobs BlobDeleter, tenant, sourceUri string) (Deletion, error) {
+	var deletion Deletion
+
+	chunks := mongo.Database(tenant).Collection(db.ChunkModel{}.CollectionName())
+	var chunkIds []string
+	if err := chunks.Distinct(ctx, "_id", bson.M{"sourceUri": sourceUri}).Decode(&chunkIds); err != nil {
+		return deletion, errors.New("failed to find source chunks: " + err.Error())
+	}
+	live, err := chunks.CountDocuments(ctx, bson.M{"$and": bson.A{bson.M{"sourceUri": sourceUri}, db.LiveChunksFilter()}})
+	if err != nil {
+		return deletion, errors.New("failed to count live chunks: " + err.Error())
+	}
+
+	progress := db.NewIngestProgressModel(sourceUri)
+	ingested, err := async.Await(odm.CollectionOf[db.IngestProgressModel](mongo, tenant).Exists(ctx, progress.Id()))
+	if err != nil {
+		return deletion, errors.New("failed to load ingestion progress: " + err.Error())
+	}
+	if len(chunkIds) == 0 && !ingested {
+		return deletion, ErrDocumentNotFound
+	}
+
+	if len(chunkIds) > 0 {
+		result, err := mongo.Database(tenant).Collection(db.ChunkAnnModel{}.CollectionName()).
+			DeleteMany(ctx, bson.M{"_id": bson.M{"$in": chunkIds}})
+		if err != nil {
+			return deletion, errors.New("failed to delete chunk embeddings: " + err.Error())
+		}
+		deletion.Embeddings = int(result.DeletedCount)
+	}
+
+	result, err := chunks.DeleteMany(ctx, bson.M{"sourceUri": sourceUri})
+	if err != nil {
+		return deletion, errors.New("failed to delete chunks: " + err.Error())
+	}
+	deletion.Chunks = int(result.DeletedCount)
+	deletion.LiveChunks = int(live)
+
+	result, err = mongo.Database(tenant).Collection(db.TableRowModel{}.CollectionName()).
+		DeleteMany(ctx, bson.M{"sourceUri": sourceUri})
+	if err != nil {
+		return deletion, errors.New("failed to delete table rows: " + err.Error())
+	}
+	deletion.TableRows = int(result.DeletedCount)
+
+	// case journals' figures and originals can hold patient details, which are only
+	// redacted from chunks
+	figures := mongo.Database(tenant).Collection(db.FigureModel{}.CollectionName())
+	var images []string
+	if err := figures.Distinct(ctx, "imagePath", bson.M{"sourceUri": sourceUri, "imagePath": bson.M{"$ne": ""}}).Decode(&images); err != nil {
+		return deletion, errors.New("failed to find figure images: " + err.Error())
+	}
+	for _, image := range images {
+		if err := blobs.DeleteBlob(ctx, tenant, image); err != nil {
+			return deletion, errors.New("failed to delete figure image " + image + ": " + err.Error())
+		}
+		deletion.Images++
+	}
+
+	var uploads []string
+	if err := mongo.Database(tenant).Collection(db.IngestionJobModel{}.CollectionName()).
+		Distinct(ctx, "storagePath", bson.M{"sourceUri": sourceUri}).Decode(&uploads); err != nil {
+		return deletion, errors.New("failed to find uploaded originals: " + err.Error())
+	}
+	for _, upload := range uploads {
+		// a file the job was pointed at in the bucket is the tenant's own, not a copy
+		if !strings.HasPrefix(upload, UploadsPrefix) {
+			continue
+		}
+		if err := blobs.DeleteBlob(ctx, tenant, upload); err != nil {
+			return deletion, errors.New("failed to delete uploaded original " + upload + ": " + err.Error())
+		}
+		deletion.Uploads++
+	}
+
+	result, err = figures.DeleteMany(ctx, bson.M{"sourceUri": sourceUri})
+	if err != nil {
+		return deletion, errors.New("failed to delete figures: " + err.Error())
+	}
+	deletion.Figures = int(result.DeletedCount)
+
+	result, err = mongo.Database(tenant).Collection(db.QuarantinedChunkModel{}.CollectionName()).
+		DeleteMany(ctx, bson.M{"sourceUri": sourceUri})
+	if err != nil {
+		return deletion, errors.New("failed to delete quarantined chunks: " + err.Error())
+	}
+	deletion.Quarantined = int(result.DeletedCount)
+
+	if _, err := async.Await(odm.CollectionOf[db.IngestProgressModel](mongo, tenant).DeleteByID(ctx, progress.Id())); err != nil {
+		return deletion, errors.New("failed to delete ingestion progress: " + err.Error())
+	}
+	// copies of the document had its chunks stand for them; they are ingested on their
+	// own when next seen
+	result, err = mongo.Database(tenant).Collection(progress.CollectionName()).
+		DeleteMany(ctx, bson.M{"duplicateOf": sourceUri})
+	if err != nil {
+		return deletion, errors.New("failed to unlink duplicate documents: " + err.Error())
+	}
+	deletion.Duplicates = int(result.DeletedCount)
+
+	version, err := db.NextCorpusVersion(ctx, mongo, tenant)
+	if err != nil {
+		return deletion, errors.New("failed to allocate corpus version: " + err.Error())
+	}
+	corpusVersion := db.NewCorpusVersionModel(version)
+	corpusVersion.SourceURI = sourceUri
+	corpusVersion.RetiredChunks = deletion.LiveChunks
+	corpusVersion.Deleted = true
+	corpusVersion.CreatedOn = time.Now().Unix()
+	if _, err := async.Await(odm.CollectionOf[db.CorpusVersionModel](mongo, tenant).Save(ctx, *corpusVersion)); err != nil {
+		return deletion, errors.New("failed to record corpus version: " + err.Error())
+	}
+	deletion.CorpusVersion = version
+
+	if err := db.InvalidateAnswerCache(ctx, mongo, tenant); err != nil {
+		// Entries are keyed by corpus version as well, so stale ones are never served.
+		logger.Error("Failed to invalidate answer cache", zap.String("tenant", tenant), zap.Error(err))
+	}
+
+	logger.Info("Document deleted",
+		zap.String("tenant", tenant), zap.String("sourceUri", sourceUri), zap.Int64("version", version),
+		zap.Int("chunks", deletion.Chunks), zap.Int("embeddings", deletion.Embeddings), zap.Int("tableRows", deletion.TableRows),
+		zap.Int("figures", deletion.Figures), zap.Int("images", deletion.Images), zap.Int("uploads", deletion.Uploads))
+	return deletion, nil
+}
+
+// BlobDeleter deletes files from a tenant's bucket. A file that is already gone is not
+// an error, so a deletion can be run again.
+type BlobDeleter interface {
+	DeleteBlob(ctx context.Context, bucketName, path string) error
+}
+
+// AzureBlobDeleter deletes blobs of the deployment's storage account with the default
+// Azure credential, as cloud.Azure reads and writes them. The client is created on first
+// use.
+type AzureBlobDeleter struct {
+	account string
+
+	once   sync.Once
+	client *azblob.Client
+	err    error
+}
+
+func ProvideBlobDeleter(ccfg *config.BootConfig) BlobDeleter {
+	return &AzureBlobDeleter{account: ccfg.AzureStorageAccount}
+}
+
+func (d *AzureBlobDeleter) DeleteBlob(ctx context.Context, bucketName, path string) error {
+	d.once.Do(func() {
+		cred, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			d.err = errors.New("failed to get Azure credential: " + err.Error())
+			return
+		}
+		d.client, d.err = azblob.NewClient(fmt.Sprintf("https://%s.blob.core.windows.net/", d.account), cred, nil)
+	})
+	if d.err != nil {
+		return d.err
+	}
+
+	_, err := d.client.DeleteBlob(ctx, bucketName, path, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.ContainerNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,230 @@
+package ingest
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Document is the form every source format is converted to before chunking: the book
+// it is from, and its headings, paragraphs and tables in reading order. Chunking works
+// on its markdown rendering, which markdown sources already are.
+type Document struct {
+	Source SourceMetadata
+	Blocks []Block
+}
+
+type BlockKind string
+
+const (
+	BlockHeading   BlockKind = "heading"
+	BlockParagraph BlockKind = "paragraph"
+	BlockTable     BlockKind = "table"
+	// Starts a PDF page; the blocks after it are on the page until the next one.
+	BlockPage BlockKind = "page"
+	// A captioned figure, chunked as its caption.
+	BlockFigure BlockKind = "figure"
+)
+
+type Block struct {
+	Kind  BlockKind
+	Text  string     // of headings and paragraphs; paragraphs may span lines
+	Level int        // of headings, 1 for a chapter
+	Rows  [][]string // of tables: the header, then the data rows, all as wide
+
+	// page blocks
+	Page          int
+	Scanned       bool    // the page's text was read with OCR
+	OcrConfidence float64 // of scanned pages
+
+	// figure blocks; Text is the caption and Page the page it is on
+	Label string // e.g. "Fig. 3", as the caption numbers the figure
+	Image []byte // PNG of the figure; nil when none could be cut out
+}
+
+var (
+	blankLines        = regexp.MustCompile(`\n\s*\n`)
+	pageMarkerPattern = regexp.MustCompile(`^<!-- page (\d+)(?: ocr ([0-9.]+))? -->$`)
+)
+
+func (d *Document) AddHeading(level int, heading string) {
+	d.Blocks = append(d.Blocks, Block{Kind: BlockHeading, Level: level, Text: heading})
+}
+
+func (d *Document) AddParagraph(paragraph string) {
+	d.Blocks = append(d.Blocks, Block{Kind: BlockParagraph, Text: paragraph})
+}
+
+// AddFigure adds a figure on page with its caption, which starts with label, and its
+// PNG image, if any.
+func (d *Document) AddFigure(label, caption string, page int, image []byte) {
+	d.Blocks = append(d.Blocks, Block{Kind: BlockFigure, Label: label, Text: caption, Page: page, Image: image})
+}
+
+// Markdown renders the document as chunking reads it: the source as front matter,
+// headings at their level, tables as pipe tables, figures as their captions and a page
+// marker at the start of each page.
+func (d *Document) Markdown() []byte {
+	var md strings.Builder
+	if d.Source != (SourceMetadata{}) {
+		md.WriteString("---\n")
+		if d.Source.Book != "" {
+			fmt.Fprintf(&md, "book: %s\n", d.Source.Book)
+		}
+		if d.Source.Author != "" {
+			fmt.Fprintf(&md, "author: %s\n", d.Source.Author)
+		}
+		if d.Source.Year > 0 {
+			fmt.Fprintf(&md, "year: %d\n", d.Source.Year)
+		}
+		if d.Source.Type != "" {
+			fmt.Fprintf(&md, "type: %s\n", d.Source.Type)
+		}
+		md.WriteString("---\n\n")
+	}
+
+	for _, block := range d.Blocks {
+		switch block.Kind {
+		case BlockHeading:
+			fmt.Fprintf(&md, "%s %s\n\n", strings.Repeat("#", min(max(block.Level, 1), 6)), collapseSpace(block.Text))
+		case BlockParagraph, BlockFigure:
+			// a blank line would end the paragraph
+			md.WriteString(blankLines.ReplaceAllString(strings.TrimSpace(block.Text), "\n") + "\n\n")
+		case BlockTable:
+			md.WriteString(pipeTable(block.Rows) + "\n")
+		case BlockPage:
+			md.WriteString(pageMarker(block) + "\n\n")
+		}
+	}
+	return []byte(md.String())
+}
+
+// LowConfidencePages lists the scanned pages whose OCR confidence is below
+// LowOcrConfidence, in order.
+func (d *Document) LowConfidencePages() []int {
+	var pages []int
+	for _, block := range d.Blocks {
+		if block.Kind == BlockPage && block.Scanned && block.OcrConfidence < LowOcrConfidence {
+			pages = append(pages, block.Page)
+		}
+	}
+	sort.Ints(pages)
+	return pages
+}
+
+// ParseDocument reads markdown into a document: its front matter, headings, and the
+// paragraphs and pipe tables between them, page markers included.
+func ParseDocument(md []byte) *Document {
+	doc := &Document{}
+	doc.Source, md = ParseFrontMatter(md)
+
+	addParagraphs := func(body string) {
+		for _, paragraph := range paragraphBreak.Split(body, -1) {
+			if paragraph = strings.TrimSpace(paragraph); paragraph == "" {
+				continue
+			}
+			if block, ok := parsePageMarker(paragraph); ok {
+				doc.Blocks = append(doc.Blocks, block)
+				continue
+			}
+			if rows, ok := parsePipeTable(paragraph); ok {
+				doc.AddTable(rows)
+				continue
+			}
+			doc.AddParagraph(paragraph)
+		}
+	}
+
+	start := 0
+	for _, h := range markdownHeadings(md) {
+		addParagraphs(string(md[start:h.start]))
+		doc.AddHeading(h.level, h.title)
+		start = h.lineEnd
+	}
+	addParagraphs(string(md[start:]))
+	return doc
+}
+
+// markdownHeading is a heading of a markdown document, with its byte offsets.
+type markdownHeading struct {
+	start   int // byte offset of heading line start
+	lineEnd int // byte offset just *after* the end-of-line
+	level   int
+	title   string
+}
+
+// markdownHeadings finds the headings of a markdown document in order; "#" lines in
+// code blocks are not headings.
+func markdownHeadings(md []byte) []markdownHeading {
+	reader := text.NewReader(md)
+	root := goldmark.DefaultParser().Parse(reader)
+
+	var heads []markdownHeading
+	ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if h, ok := n.(*ast.Heading); ok && h.Lines().Len() > 0 {
+			seg := h.Lines().At(0) // first (and only) line
+			// seg.Start .. seg.Stop covers the heading's text, after its #s
+			start := seg.Start
+			for start > 0 && md[start-1] != '\n' {
+				start--
+			}
+			lineEnd := seg.Stop
+			// skip trailing CR/LF so body starts at the next content byte
+			for lineEnd < len(md) && (md[lineEnd] == '\n' || md[lineEnd] == '\r') {
+				lineEnd++
+			}
+			heads = append(heads, markdownHeading{
+				start:   start,
+				lineEnd: lineEnd,
+				level:   h.Level,
+				title:   strings.TrimSpace(string(h.Text(md))),
+			})
+		}
+		return ast.WalkContinue, nil
+	})
+	return heads
+}
+
+// pageMarker starts the text of a PDF page in a document's markdown; ChunkMarkdown
+// reads it to record the pages each chunk spans. Scanned pages carry their OCR
+// confidence: "<!-- page 12 ocr 0.83 -->".
+func pageMarker(page Block) string {
+	if page.Scanned {
+		return fmt.Sprintf("<!-- page %d ocr %.2f -->", page.Page, page.OcrConfidence)
+	}
+	return fmt.Sprintf("<!-- page %d -->", page.Page)
+}
+
+func parsePageMarker(line string) (Block, bool) {
+	m := pageMarkerPattern.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return Block{}, false
+	}
+	block := Block{Kind: BlockPage}
+	block.Page, _ = strconv.Atoi(m[1])
+	if m[2] != "" {
+		block.Scanned = true
+		block.OcrConfidence, _ = strconv.ParseFloat(m[2], 64)
+	}
+	return block, true
+}
+
+// ocrConfidences reads the OCR confidence of each scanned page from its page marker.
+func ocrConfidences(md []byte) map[int]float64 {
+	confidences := map[int]float64{}
+	for _, line := range strings.Split(string(md), "\n") {
+		if block, ok := parsePageMarker(line); ok && block.Scanned {
+			confidences[block.Page] = block.OcrConfidence
+		}
+	}
+	return confidences
+}
@@ -0,0 +1,28 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDocument(t *testing.T) {
+	md := "---\nbook: Keynotes\nyear: 1898\n---\nPreface text.\n\n# Aconitum\n\n<!-- page 3 ocr 0.42 -->\n\nGreat fear,\nworse at night.\n\n" +
+		"```\n# not a heading\n```\n\n### Fever\n\nDry heat.\n"
+
+	doc := ParseDocument([]byte(md))
+	assert.Equal(t, SourceMetadata{Book: "Keynotes", Year: 1898}, doc.Source)
+	assert.Equal(t, []Block{
+		{Kind: BlockParagraph, Text: "Preface text."},
+		{Kind: BlockHeading, Level: 1, Text: "Aconitum"},
+		{Kind: BlockPage, Page: 3, Scanned: true, OcrConfidence: 0.42},
+		{Kind: BlockParagraph, Text: "Great fear,\nworse at night."},
+		{Kind: BlockParagraph, Text: "```\n# not a heading\n```"},
+		{Kind: BlockHeading, Level: 3, Text: "Fever"},
+		{Kind: BlockParagraph, Text: "Dry heat."},
+	}, doc.Blocks)
+	assert.Equal(t, []int{3}, doc.LowConfidencePages())
+
+	assert.Equal(t, "---\nbook: Keynotes\nyear: 1898\n---\n\nPreface text.\n\n# Aconitum\n\n<!-- page 3 ocr 0.42 -->\n\n"+
+		"Great fear,\nworse at night.\n\n```\n# not a heading\n```\n\n### Fever\n\nDry heat.\n\n", string(doc.Markdown()))
+}
@@ -0,0 +1,203 @@
+package ingest
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type docxCoreProperties struct {
+	Title   string `xml:"title"`
+	Creator string `xml:"creator"`
+	Created string `xml:"created"`
+}
+
+type docxStyles struct {
+	Styles []struct {
+		ID         string   `xml:"styleId,attr"`
+		Name       docxVal  `xml:"name"`
+		OutlineLvl *docxVal `xml:"pPr>outlineLvl"`
+	} `xml:"style"`
+}
+
+type docxVal struct {
+	Val string `xml:"val,attr"`
+}
+
+// Built-in heading style names; documents name their style ids in their own language.
+var docxHeadingName = regexp.MustCompile(`(?i)^heading (\d)$`)
+
+// convertDocx reads the paragraphs of a Word document in order. Paragraphs in heading
+// styles, or with an outline level, are headings; a paragraph in the Title style names
+// the book when the document's properties do not. The properties' creator and creation
+// year name the author and year.
+func convertDocx(_ context.Context, name string, data []byte) (*Document, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errors.New("failed to open DOCX: " + err.Error())
+	}
+
+	doc := &Document{}
+	var props docxCoreProperties
+	if err := readXml(archive, "docProps/core.xml", &props); err == nil {
+		doc.Source.Book = strings.TrimSpace(props.Title)
+		doc.Source.Author = strings.TrimSpace(props.Creator)
+		if created := strings.TrimSpace(props.Created); len(created) >= 4 {
+			doc.Source.Year, _ = strconv.Atoi(created[:4])
+		}
+	}
+
+	levels, titleStyles := map[string]int{}, map[string]bool{}
+	var styles docxStyles
+	if err := readXml(archive, "word/styles.xml", &styles); err == nil {
+		for _, style := range styles.Styles {
+			if m := docxHeadingName.FindStringSubmatch(style.Name.Val); m != nil {
+				levels[style.ID], _ = strconv.Atoi(m[1])
+			} else if style.OutlineLvl != nil {
+				if level, err := strconv.Atoi(style.OutlineLvl.Val); err == nil && level < 9 {
+					levels[style.ID] = level + 1
+				}
+			}
+			if strings.EqualFold(style.Name.Val, "title") {
+				titleStyles[style.ID] = true
+			}
+		}
+	}
+
+	body, err := readZipFile(archive, "word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+	if err := readDocxBody(body, func(style string, outline int, text string) {
+		level := levels[style]
+		if outline > 0 {
+			level = outline
+		}
+		switch {
+		case text == "":
+		case titleStyles[style] || strings.EqualFold(style, "title"):
+			if doc.Source.Book == "" {
+				doc.Source.Book = collapseSpace(text)
+			}
+		case level > 0:
+			doc.AddHeading(level, text)
+		default:
+			doc.AddParagraph(text)
+		}
+	}, doc.AddTable); err != nil {
+		return nil, errors.New("failed to read DOCX body: " + err.Error())
+	}
+
+	if doc.Source.Book == "" {
+		doc.Source.Book = documentTitle(name)
+	}
+	if len(doc.Blocks) == 0 {
+		return nil, errors.New("DOCX has no text")
+	}
+	return doc, nil
+}
+
+// readDocxBody calls paragraph with the style id, outline level (1-based, 0 for none)
+// and text of each <w:p> of a document body, and table with the cell text of each
+// <w:tbl>, row by row. Tabs become spaces and line breaks newlines; paragraphs nested
+// in text boxes are read as part of the outer one, and tables nested in a cell as part
+// of the cell.
+func readDocxBody(body []byte, paragraph func(style string, outline int, text string), table func(rows [][]string)) error {
+	var (
+		depth   int
+		style   string
+		outline int
+		inText  bool
+		text    strings.Builder
+
+		tables int // depth of table nesting
+		rows   [][]string
+		row    []string
+		cell   []string
+	)
+
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "tbl":
+				if tables++; tables == 1 {
+					rows = nil
+				}
+			case "tr":
+				if tables == 1 {
+					row = nil
+				}
+			case "tc":
+				if tables == 1 {
+					cell = nil
+				}
+			case "p":
+				if depth == 0 {
+					style, outline = "", 0
+					text.Reset()
+				}
+				depth++
+			case "pStyle":
+				if depth == 1 {
+					style = xmlAttr(t, "val")
+				}
+			case "outlineLvl":
+				if level, err := strconv.Atoi(xmlAttr(t, "val")); depth == 1 && err == nil && level < 9 {
+					outline = level + 1
+				}
+			case "t":
+				inText = true
+			case "tab":
+				text.WriteByte(' ')
+			case "br", "cr":
+				text.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText && depth > 0 {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if depth--; depth > 0 {
+					break
+				}
+				if tables > 0 {
+					cell = append(cell, strings.TrimSpace(text.String()))
+				} else {
+					paragraph(style, outline, strings.TrimSpace(text.String()))
+				}
+			case "tc":
+				if tables == 1 {
+					row = append(row, strings.Join(cell, " "))
+				}
+			case "tr":
+				if tables == 1 {
+					rows = append(rows, row)
+				}
+			case "tbl":
+				if tables--; tables == 0 {
+					table(rows)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertDocx(t *testing.T) {
+	const w = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"`
+	docx := buildZip(t, map[string]string{
+		"docProps/core.xml": `<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties"
+  xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/">
+  <dc:creator>Dr. A. Shah</dc:creator><dcterms:created>2019-05-02T10:00:00Z</dcterms:created></cp:coreProperties>`,
+		"word/styles.xml": `<w:styles ` + w + `>
+  <w:style w:styleId="Titel"><w:name w:val="Title"/></w:style>
+  <w:style w:styleId="berschrift1"><w:name w:val="heading 1"/></w:style>
+  <w:style w:styleId="Case"><w:name w:val="Case heading"/><w:pPr><w:outlineLvl w:val="1"/></w:pPr></w:style>
+</w:styles>`,
+		"word/document.xml": `<w:document ` + w + `><w:body>
+  <w:p><w:pPr><w:pStyle w:val="Titel"/></w:pPr><w:r><w:t>Clinical Cases</w:t></w:r></w:p>
+  <w:p><w:pPr><w:pStyle w:val="berschrift1"/></w:pPr><w:r><w:t>Fevers</w:t></w:r></w:p>
+  <w:p><w:pPr><w:pStyle w:val="Case"/></w:pPr><w:r><w:t>Case 1</w:t></w:r></w:p>
+  <w:p><w:r><w:t xml:space="preserve">Sudden fever, </w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>Bell.</w:t></w:r><w:r><w:br/><w:t>Better</w:t><w:tab/><w:t>next day.</w:t></w:r></w:p>
+  <w:p></w:p>
+</w:body></w:document>`,
+	})
+
+	doc, err := convertDocx(t.Context(), "cases.docx", docx)
+	require.NoError(t, err)
+	assert.Equal(t, SourceMetadata{Book: "Clinical Cases", Author: "Dr. A. Shah", Year: 2019}, doc.Source)
+	assert.Equal(t, []Block{
+		{Kind: BlockHeading, Level: 1, Text: "Fevers"},
+		{Kind: BlockHeading, Level: 2, Text: "Case 1"},
+		{Kind: BlockParagraph, Text: "Sudden fever, Bell.\nBetter next day."},
+	}, doc.Blocks)
+
+	_, err = convertDocx(t.Context(), "broken.docx", []byte("not a zip"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	// NearDuplicateBits is how many of two documents' 64 fingerprint bits may differ for
+	// one to count as a copy of the other: the same book exported in another format or
+	// with a few typos fixed, but not a revised edition.
+	NearDuplicateBits = 3
+
+	// Fingerprints are indexed in bands of 16 bits. Two within NearDuplicateBits of each
+	// other share at least one of the four bands whole, so candidates are found by band.
+	fingerprintBandBits = 16
+)
+
+// Fingerprint is a SimHash of a document's text: documents sharing most of their word
+// triples get fingerprints a few bits apart, whatever their format or how they were
+// chunked. Sentences repeated by overlapping windows count once. A document without
+// text has none, zero.
+func Fingerprint(chunks []db.ChunkModel) uint64 {
+	var weights [64]int
+	seen := map[uint64]bool{}
+	for _, chunk := range chunks {
+		for _, sentence := range chunk.Sentences {
+			words := strings.FieldsFunc(strings.ToLower(sentence), func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+			})
+			if len(words) == 0 {
+				continue
+			}
+			// a sentence of fewer than three words is one shingle
+			for i := 0; i < max(1, len(words)-2); i++ {
+				shingle := fnv.New64a()
+				shingle.Write([]byte(strings.Join(words[i:min(i+3, len(words))], " ")))
+				hash := shingle.Sum64()
+				if seen[hash] {
+					continue
+				}
+				seen[hash] = true
+				for bit := range weights {
+					if hash&(1<<bit) != 0 {
+						weights[bit]++
+					} else {
+						weights[bit]--
+					}
+				}
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
+
+// fingerprintBands are the index keys of a fingerprint, one per band.
+func fingerprintBands(fingerprint uint64) []string {
+	bands := make([]string, 64/fingerprintBandBits)
+	for i := range bands {
+		bands[i] = fmt.Sprintf("%d:%04x", i, (fingerprint>>(fingerprintBandBits*i))&(1<<fingerprintBandBits-1))
+	}
+	return bands
+}
+
+// findCopy returns the ingested document with the same checksum as another source's,
+// for the same access groups; nil when there is none.
+func findCopy(ctx context.Context, mongo odm.MongoClient, tenant, sourceUri, checksum string, groups []string) (*db.IngestProgressModel, error) {
+	filter := originalsFilter(sourceUri, groups)
+	filter["checksum"] = checksum
+	originals, err := async.Await(odm.CollectionOf[db.IngestProgressModel](mongo, tenant).Find(ctx, filter, nil, 1, 0))
+	if err != nil {
+		return nil, errors.New("failed to look for duplicate documents: " + err.Error())
+	}
+	if len(originals) == 0 {
+		return nil, nil
+	}
+	return &originals[0], nil
+}
+
+// findNearCopy returns the ingested document whose fingerprint is nearest to another
+// source's, if within NearDuplicateBits, for the same access groups; nil when there is
+// none.
+func findNearCopy(ctx context.Context, mongo odm.MongoClient, tenant, sourceUri string, fingerprint uint64, groups []string) (*db.IngestProgressModel, error) {
+	if fingerprint == 0 {
+		return nil, nil
+	}
+	filter := originalsFilter(sourceUri, groups)
+	filter["fingerprintBands"] = bson.M{"$in": fingerprintBands(fingerprint)}
+	candidates, err := async.Await(odm.CollectionOf[db.IngestProgressModel](mongo, tenant).Find(ctx, filter, nil, 0, 0))
+	if err != nil {
+		return nil, errors.New("failed to look for duplicate documents: " + err.Error())
+	}
+
+	var nearest *db.IngestProgressModel
+	nearestBits := NearDuplicateBits + 1
+	for i, candidate := range candidates {
+		other, err := strconv.ParseUint(candidate.Fingerprint, 16, 64)
+		if err != nil {
+			continue
+		}
+		if distance := bits.OnesCount64(fingerprint ^ other); distance < nearestBits {
+			nearest, nearestBits = &candidates[i], distance
+		}
+	}
+	return nearest, nil
+}
+
+// originalsFilter matches the documents of other sources than sourceUri that a copy
+// for groups could stand for: those with published chunks for the same access groups,
+// that are not copies themselves. A copy for other groups is ingested on its own, so
+// that each document is retrieved by the users it was ingested for.
+func originalsFilter(sourceUri string, groups []string) bson.M {
+	filter := bson.M{
+		"sourceUri":    bson.M{"$ne": sourceUri},
+		"duplicateOf":  bson.M{"$exists": false},
+		"stage":        bson.M{"$in": bson.A{db.IngestStageChunked, db.IngestStageEmbedded}},
+		"accessGroups": bson.M{"$exists": false},
+	}
+	if len(groups) > 0 {
+		filter["accessGroups"] = groups
+	}
+	return filter
+}
+
+// formatFingerprint is how a fingerprint is stored.
+func formatFingerprint(fingerprint uint64) string {
+	return fmt.Sprintf("%016x", fingerprint)
+}
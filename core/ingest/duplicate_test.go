@@ -0,0 +1,93 @@
+package ingest
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const materiaMedica = `# Aconitum Napellus
+
+Aconite suits sudden and violent complaints that follow exposure to dry cold wind.
+The patient is restless, anxious and full of fear, and often predicts the hour of death.
+Fever comes on with a hot dry skin, great thirst for cold water and a full hard pulse.
+Complaints are worse in the evening and at night, and better in the open air.
+
+# Belladonna
+
+Belladonna acts upon every part of the nervous system, with active congestion and furious excitement.
+Heat, redness, throbbing and burning mark its inflammations, which come on suddenly.
+The face is red and hot, the pupils dilated, and the skin dry and burning to the touch.
+Complaints are worse from touch, jar, noise and light, and at three in the afternoon.
+
+# Bryonia Alba
+
+Bryonia complaints develop slowly and are worse from any motion whatever.
+The mucous membranes are dry, with great thirst for large quantities of water at long intervals.
+The patient wants to lie perfectly still, and lying on the painful side relieves.
+Irritability is marked; the patient wants to be left alone and talks of business.
+`
+
+func chunksOf(t *testing.T, md string, config db.ChunkingConfig) []db.ChunkModel {
+	chunks, err := ChunkMarkdown(t.Context(), "file://book.md", []byte(md), config)
+	require.NoError(t, err)
+	return chunks
+}
+
+func TestFingerprint(t *testing.T) {
+	fingerprint := Fingerprint(chunksOf(t, materiaMedica, db.ChunkingConfig{}))
+	require.NotZero(t, fingerprint)
+
+	rechunked := Fingerprint(chunksOf(t, materiaMedica, db.ChunkingConfig{
+		Strategies: map[string]string{DocumentNarrative: ChunkHeading}, MaxTokens: 40, OverlapTokens: 20}))
+	assert.Equal(t, fingerprint, rechunked, "chunking leaves the fingerprint alone")
+
+	punctuated := strings.Replace(materiaMedica, "dry cold wind", "dry, cold wind", 1)
+	assert.Equal(t, fingerprint, Fingerprint(chunksOf(t, punctuated, db.ChunkingConfig{})), "punctuation is not text")
+
+	assert.Zero(t, Fingerprint(nil))
+}
+
+// book writes a long document of distinct sentences, as a book has, a chapter per 40
+// sentences.
+func book(title, sentence string, sentences int) string {
+	var md strings.Builder
+	for i := range sentences {
+		if i%40 == 0 {
+			fmt.Fprintf(&md, "\n# %s %d\n\n", title, i/40+1)
+		}
+		fmt.Fprintf(&md, sentence+" ", i, i%24, i%7+1)
+	}
+	return md.String()
+}
+
+func TestFingerprintNearCopies(t *testing.T) {
+	original := book("Aconite", "Aconite relieves symptom %d when it is worse at hour %d and better after %d days.", 800)
+	fingerprint := Fingerprint(chunksOf(t, original, db.ChunkingConfig{}))
+
+	corrected := strings.Replace(original, "symptom 120 when", "symptom 120 only when", 1)
+	corrected = strings.Replace(corrected, "symptom 480 when it is", "symptom 480 if it is", 1)
+	distance := bits.OnesCount64(fingerprint ^ Fingerprint(chunksOf(t, corrected, db.ChunkingConfig{})))
+	assert.LessOrEqual(t, distance, NearDuplicateBits, "a corrected copy is near")
+
+	distance = bits.OnesCount64(fingerprint ^ Fingerprint(chunksOf(t, book("Belladonna",
+		"Case %d of Belladonna showed a red face by noon on day %d, with throbbing that ceased in week %d.", 800), db.ChunkingConfig{})))
+	assert.Greater(t, distance, NearDuplicateBits, "another book is not")
+}
+
+func TestFingerprintBands(t *testing.T) {
+	bands := fingerprintBands(0x0123456789abcdef)
+	assert.Equal(t, []string{"0:cdef", "1:89ab", "2:4567", "3:0123"}, bands)
+	assert.Equal(t, "0123456789abcdef", formatFingerprint(0x0123456789abcdef))
+}
+
+func TestOriginalsFilterAccessGroups(t *testing.T) {
+	assert.Equal(t, bson.M{"$exists": false}, originalsFilter("file://a.md", nil)["accessGroups"])
+	assert.Equal(t, []string{"practice-internal"}, originalsFilter("file://a.md", []string{"practice-internal"})["accessGroups"])
+}
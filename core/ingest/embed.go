@@ -0,0 +1,359 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// Bulk embedding defaults.
+const (
+	DefaultEmbedBatchSize = 128 // chunks per embedding request
+	DefaultEmbedWorkers   = 4   // requests in flight at once
+)
+
+// Rate limit backoff: a rate limited request waits what the provider asked, doubled for
+// each rate limit in a row up to maxEmbedBackoff, and embedding fails after
+// maxEmbedRateLimits in a row.
+const (
+	maxEmbedBackoff    = time.Minute
+	maxEmbedRateLimits = 8
+)
+
+// EmbedOptions tune how EmbedMissing embeds a source's chunks.
+type EmbedOptions struct {
+	BatchSize int // chunks per request at most; zero is DefaultEmbedBatchSize
+	Workers   int // requests in flight at once; zero is DefaultEmbedWorkers
+
+	// Progress, if set, is called after each batch is saved with the chunks embedded
+	// so far and the chunks that were missing. Calls never overlap.
+	Progress func(done, total int)
+}
+
+// ErrEmbeddingModelMismatch is returned for a tenant that has vectors of another model
+// than its embedder's. They would be searched as if they were comparable, so a tenant
+// switching models has its vectors deleted and re-embedded first.
+var ErrEmbeddingModelMismatch = errors.New("tenant has embeddings of another model; delete them before re-embedding")
+
+// TenantEmbedderName reads the embedder chosen in the tenant's config, empty for the
+// default one.
+func TenantEmbedderName(ctx context.Context, mongo odm.MongoClient, tenant string) (string, error) {
+	tenantConfig, err := loadTenantConfig(ctx, mongo, tenant)
+	if err != nil {
+		return "", err
+	}
+	return tenantConfig.Embedder, nil
+}
+
+// TenantChunking reads how the tenant's config has documents chunked.
+func TenantChunking(ctx context.Context, mongo odm.MongoClient, tenant string) (db.ChunkingConfig, error) {
+	tenantConfig, err := loadTenantConfig(ctx, mongo, tenant)
+	if err != nil {
+		return db.ChunkingConfig{}, err
+	}
+	return tenantConfig.Chunking, nil
+}
+
+// ErrSourceNotConfigured is returned for a source name the tenant's config lacks.
+var ErrSourceNotConfigured = errors.New("tenant has no source of that name")
+
+// TenantSource reads the source configured in the tenant's config as name.
+func TenantSource(ctx context.Context, mongo odm.MongoClient, tenant, name string) (db.SourceConfig, error) {
+	tenantConfig, err := loadTenantConfig(ctx, mongo, tenant)
+	if err != nil {
+		return db.SourceConfig{}, errors.New("failed to load tenant config: " + err.Error())
+	}
+	source, ok := tenantConfig.Source(name)
+	if !ok {
+		return db.SourceConfig{}, ErrSourceNotConfigured
+	}
+	return source, nil
+}
+
+// loadTenantConfig reads the tenant's config, empty when it has none.
+func loadTenantConfig(ctx context.Context, mongo odm.MongoClient, tenant string) (*db.TenantConfigModel, error) {
+	repo := odm.CollectionOf[db.TenantConfigModel](mongo, tenant)
+	exists, err := async.Await(repo.Exists(ctx, db.TenantConfigID))
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return &db.TenantConfigModel{ID: db.TenantConfigID}, nil
+	}
+	return async.Await(repo.FindOneByID(ctx, db.TenantConfigID))
+}
+
+// CheckStoredVectors fails with ErrEmbeddingModelMismatch when the tenant has vectors
+// of any embedding model but spec's, or its config names another embedder than spec,
+// as when another job switched it to the multilingual one. EmbedMissing checks again
+// before saving each batch, so a job never adds to vectors of another model.
+func CheckStoredVectors(ctx context.Context, mongo odm.MongoClient, tenant string, spec embedding.Spec) error {
+	name, err := TenantEmbedderName(ctx, mongo, tenant)
+	if err != nil {
+		return errors.New("failed to load tenant config: " + err.Error())
+	}
+	if name != "" && name != spec.Name {
+		logger.Error("Tenant's embedder is not the one embedding", zap.String("tenant", tenant), zap.String("embedder", spec.Name),
+			zap.String("tenantEmbedder", name))
+		return ErrEmbeddingModelMismatch
+	}
+
+	foreign, err := async.Await(odm.CollectionOf[db.ChunkAnnModel](mongo, tenant).Find(ctx, foreignVectorsFilter(spec), nil, 1, 0))
+	if err != nil {
+		return errors.New("failed to check stored embeddings: " + err.Error())
+	}
+	if len(foreign) > 0 {
+		logger.Error("Tenant has embeddings of another model", zap.String("tenant", tenant), zap.String("embedder", spec.Name),
+			zap.String("storedModel", foreign[0].Model), zap.Int("storedDimensions", foreign[0].Dimensions))
+		return ErrEmbeddingModelMismatch
+	}
+	return nil
+}
+
+// foreignVectorsFilter matches the stored vectors of any embedding model but spec's.
+func foreignVectorsFilter(spec embedding.Spec) bson.M {
+	other := bson.A{
+		bson.M{"model": bson.M{"$exists": true, "$ne": spec.Model}},
+		bson.M{"dimensions": bson.M{"$exists": true, "$ne": spec.Dimensions}},
+	}
+	if spec.Model != embedding.Legacy.Model || spec.Dimensions != embedding.Legacy.Dimensions {
+		other = append(other, bson.M{"model": bson.M{"$exists": false}})
+	}
+	return bson.M{"$or": other}
+}
+
+// EmbeddingText is the text a chunk's vector is computed from.
+func EmbeddingText(chunk db.ChunkModel) string {
+	return chunk.SectionPath + "\n" + strings.Join(chunk.Sentences, "\n")
+}
+
+// EmbedMissing embeds the live chunks of a source that have no vector yet. Workers
+// each send a batch of chunks in one request, where the embedder can embed a batch, and
+// save its vectors before taking the next, so every saved batch is a checkpoint: an
+// interrupted run embeds only the chunks it had not saved when it runs again.
+//
+// When the provider rate limits a request, every worker pauses for as long as it asked,
+// and batches are halved until requests go through again, then grow back.
+func EmbedMissing(ctx context.Context, mongo odm.MongoClient, spec embedding.Spec, embedder embed.Embedder,
+	tenant, sourceUri string, options EmbedOptions) (int, error) {
+	chunks, err := async.Await(odm.CollectionOf[db.ChunkModel](mongo, tenant).Find(ctx, bson.M{"$and": bson.A{
+		bson.M{"sourceUri": sourceUri},
+		db.LiveChunksFilter(),
+	}}, nil, 0, 0))
+	if err != nil {
+		return 0, errors.New("failed to find source chunks: " + err.Error())
+	}
+	if len(chunks) == 0 {
+		return 0, nil
+	}
+
+	chunkIds := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		chunkIds[i] = chunk.ChunkID
+	}
+	vectorRepo := odm.CollectionOf[db.ChunkAnnModel](mongo, tenant)
+	embedded, err := async.Await(vectorRepo.Find(ctx, bson.M{"_id": bson.M{"$in": chunkIds}}, nil, 0, 0))
+	if err != nil {
+		return 0, errors.New("failed to find chunk embeddings: " + err.Error())
+	}
+	present := make(map[string]bool, len(embedded))
+	for _, vector := range embedded {
+		present[vector.ChunkID] = true
+	}
+
+	var missing []db.ChunkModel
+	for _, chunk := range chunks {
+		if !present[chunk.ChunkID] {
+			missing = append(missing, chunk)
+		}
+	}
+
+	return embedChunks(ctx, embedder, missing, options, func(batch []db.ChunkModel, vectors [][]float32) error {
+		if err := CheckStoredVectors(ctx, mongo, tenant, spec); err != nil {
+			return err
+		}
+		for i, chunk := range batch {
+			chunkAnn := db.ChunkAnnModel{
+				ChunkID:    chunk.ChunkID,
+				Embedding:  bson.NewVector(vectors[i]),
+				Model:      spec.Model,
+				Dimensions: spec.Dimensions,
+			}
+			if _, err := async.Await(vectorRepo.Save(ctx, chunkAnn)); err != nil {
+				return errors.New("failed to save chunk embedding: " + err.Error())
+			}
+		}
+		return nil
+	})
+}
+
+// embedChunks embeds chunks with options.Workers workers, handing each batch and its
+// vectors to save. It stops at the first error and returns the chunks saved until then.
+func embedChunks(ctx context.Context, embedder embed.Embedder, chunks []db.ChunkModel, options EmbedOptions,
+	save func(batch []db.ChunkModel, vectors [][]float32) error) (int, error) {
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultEmbedBatchSize
+	}
+	if options.Workers <= 0 {
+		options.Workers = DefaultEmbedWorkers
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		backoff  = newEmbedBackoff(options.BatchSize)
+		mu       sync.Mutex
+		next     int // first chunk no worker has taken
+		done     int
+		firstErr error
+	)
+	take := func() []db.ChunkModel {
+		mu.Lock()
+		defer mu.Unlock()
+		batch := chunks[next:min(next+backoff.batchSize(), len(chunks))]
+		next += len(batch)
+		return batch
+	}
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	// embedBatch sends batch in one or more requests, smaller ones while rate limited.
+	embedBatch := func(batch []db.ChunkModel) error {
+		for len(batch) > 0 {
+			if err := backoff.wait(ctx); err != nil {
+				return err
+			}
+
+			part := batch[:min(len(batch), backoff.batchSize())]
+			texts := make([]string, len(part))
+			for i, chunk := range part {
+				texts[i] = EmbeddingText(chunk)
+			}
+
+			vectors, err := embedding.EmbedBatch(ctx, embedder, texts, embedding.WithTask("retrieval.passage"))
+			if retryAfter, limited := embedding.IsRateLimited(err); limited {
+				if err := backoff.rateLimited(retryAfter); err != nil {
+					return err
+				}
+				continue
+			}
+			if err != nil {
+				return errors.New("failed to embed chunks: " + err.Error())
+			}
+			backoff.succeeded()
+
+			if err := save(part, vectors); err != nil {
+				return err
+			}
+			mu.Lock()
+			done += len(part)
+			if options.Progress != nil {
+				options.Progress(done, len(chunks))
+			}
+			mu.Unlock()
+			batch = batch[len(part):]
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for range options.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := take(); len(batch) > 0; batch = take() {
+				if err := embedBatch(batch); err != nil {
+					fail(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return done, firstErr
+}
+
+// embedBackoff is shared by the workers of one EmbedMissing call. A rate limit pauses
+// them all and halves the batch size; each request that goes through grows it back by
+// a quarter.
+type embedBackoff struct {
+	mu       sync.Mutex
+	size     int
+	maxSize  int
+	limited  int       // rate limits in a row
+	resumeAt time.Time // end of the current pause
+}
+
+func newEmbedBackoff(maxSize int) *embedBackoff {
+	return &embedBackoff{size: maxSize, maxSize: maxSize}
+}
+
+func (b *embedBackoff) batchSize() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.size
+}
+
+// wait blocks until the current pause, if any, is over.
+func (b *embedBackoff) wait(ctx context.Context) error {
+	b.mu.Lock()
+	pause := time.Until(b.resumeAt)
+	b.mu.Unlock()
+	if pause <= 0 {
+		return ctx.Err()
+	}
+
+	select {
+	case <-time.After(pause):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rateLimited starts a pause of retryAfter, doubled for each rate limit in a row. It
+// fails once the provider has rate limited maxEmbedRateLimits requests in a row.
+func (b *embedBackoff) rateLimited(retryAfter time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.limited++
+	if b.limited > maxEmbedRateLimits {
+		return errors.New("failed to embed chunks: still rate limited after backing off")
+	}
+	b.size = max(1, b.size/2)
+
+	pause := min(maxEmbedBackoff, retryAfter<<(b.limited-1))
+	if resumeAt := time.Now().Add(pause); resumeAt.After(b.resumeAt) {
+		b.resumeAt = resumeAt
+	}
+	logger.Info("Embedding rate limited, backing off", zap.Duration("pause", pause), zap.Int("batch", b.size))
+	return nil
+}
+
+func (b *embedBackoff) succeeded() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.limited = 0
+	b.size = min(b.maxSize, b.size+max(1, b.size/4))
+}
@@ -0,0 +1,99 @@
+package ingest
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// throttledEmbedder rate limits batches larger than allowed until limits runs out.
+type throttledEmbedder struct {
+	mu      sync.Mutex
+	allowed int
+	limits  int
+	batches []int
+}
+
+func (e *throttledEmbedder) GetEmbedding(ctx context.Context, text string, opts ...embed.EmbedOption) <-chan async.Result[[]float32] {
+	return async.Go(func() ([]float32, error) { return []float32{float32(len(text))}, nil })
+}
+
+func (e *throttledEmbedder) GetEmbeddings(ctx context.Context, texts []string, opts ...embed.EmbedOption) ([][]float32, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(texts) > e.allowed && e.limits > 0 {
+		e.limits--
+		return nil, &embedding.RateLimitError{RetryAfter: time.Millisecond}
+	}
+	e.batches = append(e.batches, len(texts))
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text))}
+	}
+	return vectors, nil
+}
+
+func TestEmbedChunksBacksOffWhenRateLimited(t *testing.T) {
+	chunks := make([]db.ChunkModel, 20)
+	for i := range chunks {
+		chunks[i] = db.ChunkModel{ChunkID: strconv.Itoa(i), Sentences: []string{"Fear of death."}}
+	}
+	embedder := &throttledEmbedder{allowed: 4, limits: 1}
+
+	var mu sync.Mutex
+	saved := map[string]bool{}
+	var reported []int
+	done, err := embedChunks(t.Context(), embedder, chunks, EmbedOptions{
+		BatchSize: 8,
+		Workers:   3,
+		Progress:  func(done, total int) { reported = append(reported, done) },
+	}, func(batch []db.ChunkModel, vectors [][]float32) error {
+		mu.Lock()
+		defer mu.Unlock()
+		require.Len(t, vectors, len(batch))
+		for _, chunk := range batch {
+			saved[chunk.ChunkID] = true
+		}
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 20, done)
+	assert.Len(t, saved, 20, "every chunk is saved once the rate limit passes")
+	assert.Equal(t, 20, reported[len(reported)-1])
+	for _, size := range embedder.batches {
+		assert.LessOrEqual(t, size, 8)
+	}
+}
+
+func TestEmbedChunksGivesUpWhenStillRateLimited(t *testing.T) {
+	chunks := []db.ChunkModel{{ChunkID: "a"}, {ChunkID: "b"}}
+	embedder := &throttledEmbedder{allowed: 0, limits: maxEmbedRateLimits + 1}
+
+	done, err := embedChunks(t.Context(), embedder, chunks, EmbedOptions{Workers: 1}, func([]db.ChunkModel, [][]float32) error {
+		return nil
+	})
+	assert.Equal(t, 0, done)
+	assert.ErrorContains(t, err, "still rate limited")
+}
+
+func TestEmbedBackoff(t *testing.T) {
+	backoff := newEmbedBackoff(128)
+	require.NoError(t, backoff.rateLimited(0))
+	require.NoError(t, backoff.rateLimited(0))
+	assert.Equal(t, 32, backoff.batchSize(), "each rate limit halves the batch")
+
+	backoff.succeeded()
+	assert.Equal(t, 40, backoff.batchSize(), "a request that goes through grows it by a quarter")
+	assert.Equal(t, 0, backoff.limited)
+}
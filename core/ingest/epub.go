@@ -0,0 +1,113 @@
+package ingest
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epubPackage struct {
+	Title   string `xml:"metadata>title"`
+	Creator string `xml:"metadata>creator"`
+	Date    string `xml:"metadata>date"`
+	Items   []struct {
+		ID   string `xml:"id,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// convertEpub reads the chapters of an EPUB in reading order. Headings keep their level,
+// and the book's title, creator and year name its source.
+func convertEpub(_ context.Context, name string, data []byte) (*Document, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errors.New("failed to open EPUB: " + err.Error())
+	}
+
+	var container epubContainer
+	if err := readXml(archive, "META-INF/container.xml", &container); err != nil {
+		return nil, err
+	}
+	if len(container.Rootfiles) == 0 {
+		return nil, errors.New("EPUB container names no package")
+	}
+	packagePath := container.Rootfiles[0].FullPath
+
+	var pkg epubPackage
+	if err := readXml(archive, packagePath, &pkg); err != nil {
+		return nil, err
+	}
+
+	hrefs := make(map[string]string, len(pkg.Items))
+	for _, item := range pkg.Items {
+		hrefs[item.ID] = path.Join(path.Dir(packagePath), item.Href)
+	}
+
+	doc := &Document{Source: SourceMetadata{
+		Book:   strings.TrimSpace(pkg.Title),
+		Author: strings.TrimSpace(pkg.Creator),
+	}}
+	if doc.Source.Book == "" {
+		doc.Source.Book = documentTitle(name)
+	}
+	if date := strings.TrimSpace(pkg.Date); len(date) >= 4 {
+		doc.Source.Year, _ = strconv.Atoi(date[:4])
+	}
+
+	writer := &htmlWriter{doc: doc}
+	for _, ref := range pkg.Spine {
+		href, ok := hrefs[ref.IDRef]
+		if !ok {
+			continue
+		}
+		chapter, err := readZipFile(archive, href)
+		if err != nil {
+			return nil, err
+		}
+		root, err := html.Parse(bytes.NewReader(chapter))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse EPUB chapter %s: %w", href, err)
+		}
+		writer.write(root)
+		writer.flush()
+	}
+	return doc, nil
+}
+
+func readXml(archive *zip.Reader, name string, into any) error {
+	data, err := readZipFile(archive, name)
+	if err != nil {
+		return err
+	}
+	if err := xml.Unmarshal(data, into); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+func readZipFile(archive *zip.Reader, name string) ([]byte, error) {
+	file, err := archive.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("document is missing %s: %w", name, err)
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
@@ -0,0 +1,82 @@
+package ingest
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertEpub(t *testing.T) {
+	epub := buildZip(t, map[string]string{
+		"META-INF/container.xml": `<?xml version="1.0"?>
+<container xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`,
+		"OEBPS/content.opf": `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <metadata><dc:title>Keynotes</dc:title><dc:creator>H. C. Allen</dc:creator><dc:date>1898-01-01</dc:date></metadata>
+  <manifest>
+    <item id="c2" href="text/gels.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c1" href="text/acon.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+  <spine><itemref idref="c1"/><itemref idref="c2"/></spine>
+</package>`,
+		"OEBPS/text/acon.xhtml": `<html><head><title>x</title><style>p{}</style></head><body>
+<h1>Aconitum</h1><p>Great <em>fear</em>, worse at night.</p><p>Restless<br/>tossing.</p></body></html>`,
+		"OEBPS/text/gels.xhtml": `<html><body><h2>Gelsemium <small>(Yellow Jasmine)</small></h2><div>Dullness.</div></body></html>`,
+	})
+
+	doc, err := convertEpub(t.Context(), "keynotes.epub", epub)
+	require.NoError(t, err)
+
+	source, body := ParseFrontMatter(doc.Markdown())
+	assert.Equal(t, SourceMetadata{Book: "Keynotes", Author: "H. C. Allen", Year: 1898}, source)
+
+	sections, err := ParseMarkdownSections(t.Context(), body, 0)
+	require.NoError(t, err)
+	require.Len(t, sections, 2, "chapters in spine order")
+	assert.Equal(t, []string{"Aconitum"}, sections[0].Path)
+	assert.Equal(t, []string{"Great fear, worse at night.", "Restless\ntossing."}, paragraphs(sections[0].Body))
+	assert.Equal(t, []string{"Aconitum", "Gelsemium (Yellow Jasmine)"}, sections[1].Path)
+	assert.Equal(t, []string{"Dullness."}, paragraphs(sections[1].Body))
+
+	_, err = convertEpub(t.Context(), "broken.epub", []byte("not a zip"))
+	assert.Error(t, err)
+}
+
+func buildZip(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := archive.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, archive.Close())
+	return buf.Bytes()
+}
+
+func paragraphs(body string) []string {
+	var out []string
+	for _, block := range paragraphBreak.Split(body, -1) {
+		if block = trimLines(block); block != "" {
+			out = append(out, block)
+		}
+	}
+	return out
+}
+
+func trimLines(block string) string {
+	lines := bytes.Split([]byte(block), []byte("\n"))
+	var kept [][]byte
+	for _, line := range lines {
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			kept = append(kept, line)
+		}
+	}
+	return string(bytes.Join(kept, []byte("\n")))
+}
@@ -0,0 +1,201 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+const (
+	// A paragraph longer than this is text, however it starts.
+	maxCaptionBytes = 300
+	// Figure images are kept in the tenant's bucket under this prefix.
+	figureImagePrefix = "figures/"
+)
+
+var (
+	// a figure's number, as a caption starts with it or text mentions it: "Fig. 3",
+	// "Figure 12a", "PLATE IV"; the groups are the kind of figure, its number and, in
+	// a caption, the punctuation after it
+	figureLabelPattern   = regexp.MustCompile(`(?i)^(fig(?:ure)?s?\.?|plate|illustration|diagram)\s*([0-9]+[a-z]?|[ivxlc]+)\b\s*([.:—–-]?)`)
+	figureMentionPattern = regexp.MustCompile(`(?i)\b(fig(?:ure)?s?\.?|plate|illustration|diagram)\s*([0-9]+[a-z]?|[ivxlc]+)\b`)
+)
+
+// FigureStorage keeps figure images; cloud.Cloud is one.
+type FigureStorage interface {
+	UploadBuffer(ctx context.Context, bucketName, path string, fileData []byte) (string, error)
+}
+
+// Figure is a figure of a document, with its image to store.
+type Figure struct {
+	db.FigureModel
+	Image []byte // PNG; nil for a figure without one
+}
+
+// figureCaption returns the label a paragraph starts with when it captions a figure:
+// a short paragraph starting with a figure's number, followed by punctuation unless
+// the paragraph is next to an image, since "Figure 3 shows…" is text.
+func figureCaption(paragraph string, nextToImage bool) (string, bool) {
+	if len(paragraph) > maxCaptionBytes {
+		return "", false
+	}
+	m := figureLabelPattern.FindStringSubmatch(paragraph)
+	if m == nil || (m[3] == "" && !nextToImage) {
+		return "", false
+	}
+	return collapseSpace(m[1] + " " + m[2]), true
+}
+
+// figureKey is what a figure's label and the mentions of it have in common: "Fig. 3"
+// and "figure 3" are both "figure 3".
+func figureKey(kind, number string) string {
+	kind = strings.ToLower(strings.TrimSuffix(kind, "."))
+	if strings.HasPrefix(kind, "fig") {
+		kind = "figure"
+	}
+	return kind + " " + strings.ToLower(number)
+}
+
+// figureMentions returns the keys of the figures text mentions, once each.
+func figureMentions(text string) []string {
+	var keys []string
+	for _, m := range figureMentionPattern.FindAllStringSubmatch(text, -1) {
+		if key := figureKey(m[1], m[2]); !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Figures extracts the captioned figures of a document, each placed in the first of its
+// chunks the caption's last sentence is in, and links each chunk to the figures it
+// captions or mentions. A mention of "Fig. 3" links to the nearest Figure 3 of the same
+// chapter, as books often number their figures again in each chapter. Figures whose
+// caption chunking did not keep are left out.
+func Figures(sourceUri string, doc *Document, chunks []db.ChunkModel) []Figure {
+	var (
+		figures []Figure
+		placed  []int // the chunk of each figure
+		keys    []string
+	)
+	next := 0 // figures are in document order, as the chunks are
+	figureIndex := 0
+	for _, block := range doc.Blocks {
+		if block.Kind != BlockFigure {
+			continue
+		}
+		figureId, _ := odm.HashedKey(sourceUri, strconv.Itoa(figureIndex), block.Text)
+		figureIndex++
+
+		sentences := splitSentences(block.Text)
+		if len(sentences) == 0 {
+			continue
+		}
+		at := chunkWithSentence(chunks, next, sentences[len(sentences)-1])
+		if at < 0 {
+			continue
+		}
+		next = at
+
+		chunk := chunks[at]
+		figures = append(figures, Figure{
+			FigureModel: db.FigureModel{
+				FigureID:  figureId,
+				SourceURI: sourceUri,
+				Label:     block.Label,
+				Caption:   collapseSpace(block.Text),
+				Page:      block.Page,
+				SectionID: chunk.SectionID,
+				ChunkID:   chunk.ChunkID,
+				Title:     chunk.Title,
+				Book:      chunk.Book,
+				Author:    chunk.Author,
+			},
+			Image: block.Image,
+		})
+		placed = append(placed, at)
+		m := figureMentionPattern.FindStringSubmatch(block.Label)
+		keys = append(keys, figureKey(m[1], m[2]))
+	}
+
+	distance := func(a, b int) int { return max(a-b, b-a) }
+	for i := range chunks {
+		chunks[i].Figures = nil
+		link := func(f int) {
+			for _, ref := range chunks[i].Figures {
+				if ref.FigureID == figures[f].FigureID {
+					return
+				}
+			}
+			chunks[i].Figures = append(chunks[i].Figures, db.FigureRef{
+				FigureID: figures[f].FigureID, Label: figures[f].Label, Caption: figures[f].Caption})
+		}
+
+		for f := range figures {
+			if placed[f] == i {
+				link(f)
+			}
+		}
+		for _, key := range figureMentions(strings.Join(chunks[i].Sentences, " ")) {
+			nearest := -1
+			for f := range figures {
+				if keys[f] != key || chunks[placed[f]].Chapter != chunks[i].Chapter {
+					continue
+				}
+				if nearest < 0 || distance(placed[f], i) < distance(placed[nearest], i) {
+					nearest = f
+				}
+			}
+			if nearest >= 0 {
+				link(nearest)
+			}
+		}
+	}
+	return figures
+}
+
+// StoreFigureImages saves the figures' images in the tenant's bucket, named by figure,
+// and records where.
+func StoreFigureImages(ctx context.Context, storage FigureStorage, tenant string, figures []Figure) error {
+	for i := range figures {
+		if len(figures[i].Image) == 0 {
+			continue
+		}
+		path := figureImagePrefix + figures[i].FigureID + ".png"
+		if _, err := storage.UploadBuffer(ctx, tenant, path, figures[i].Image); err != nil {
+			return errors.New("failed to store figure image: " + err.Error())
+		}
+		figures[i].ImagePath = path
+	}
+	return nil
+}
+
+// PublishFigures replaces the figures of a source document with figures. Images of
+// figures it no longer has are left in the bucket.
+func PublishFigures(ctx context.Context, mongo odm.MongoClient, tenant, sourceUri string, figures []Figure) error {
+	_, err := mongo.Database(tenant).Collection(db.FigureModel{}.CollectionName()).DeleteMany(ctx, bson.M{"sourceUri": sourceUri})
+	if err != nil {
+		return errors.New("failed to remove replaced figures: " + err.Error())
+	}
+
+	repo := odm.CollectionOf[db.FigureModel](mongo, tenant)
+	for _, figure := range figures {
+		if _, err := async.Await(repo.Save(ctx, figure.FigureModel)); err != nil {
+			return errors.New("failed to save figure: " + err.Error())
+		}
+	}
+	if len(figures) > 0 {
+		logger.Info("Figures published", zap.String("tenant", tenant), zap.String("sourceUri", sourceUri), zap.Int("figures", len(figures)))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFigureCaption(t *testing.T) {
+	label, ok := figureCaption("Fig. 3. The flowering plant.", false)
+	assert.True(t, ok)
+	assert.Equal(t, "Fig. 3", label)
+
+	label, ok = figureCaption("PLATE IV — Belladonna berries", false)
+	assert.True(t, ok)
+	assert.Equal(t, "PLATE IV", label)
+
+	_, ok = figureCaption("Figure 3 shows the flowering plant.", false)
+	assert.False(t, ok, "text mentioning a figure")
+	label, ok = figureCaption("Figure 12a The root", true)
+	assert.True(t, ok, "next to an image, the punctuation may be left out")
+	assert.Equal(t, "Figure 12a", label)
+
+	_, ok = figureCaption("Figures are drawn from life.", true)
+	assert.False(t, ok)
+
+	assert.Equal(t, []string{"figure 3", "plate iv"}, figureMentions("See Fig. 3 and figure 3, and Plate IV."))
+}
+
+func TestFigures(t *testing.T) {
+	doc := &Document{}
+	doc.AddHeading(1, "Aconitum Napellus")
+	doc.AddParagraph("Aconite is a tall plant of mountain pastures, with helmet shaped blue flowers.")
+	doc.AddFigure("Fig. 1", "Fig. 1. The flowering plant.", 4, []byte("png"))
+	doc.AddParagraph("The root, shown in Fig. 1 beside the stem, is the most poisonous part.")
+	doc.AddHeading(1, "Belladonna")
+	doc.AddParagraph("Deadly nightshade bears shining black berries, as Fig. 1 and Plate 2 show.")
+	doc.AddFigure("Fig. 1", "Fig. 1. Berries and leaves.", 9, nil)
+
+	chunks, err := ChunkMarkdown(t.Context(), "file://atlas.pdf", doc.Markdown(), db.ChunkingConfig{
+		Strategies: map[string]string{DocumentNarrative: ChunkHeading}})
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+
+	figures := Figures("file://atlas.pdf", doc, chunks)
+	require.Len(t, figures, 2)
+	assert.Equal(t, "Fig. 1", figures[0].Label)
+	assert.Equal(t, "Fig. 1. The flowering plant.", figures[0].Caption)
+	assert.Equal(t, 4, figures[0].Page)
+	assert.Equal(t, chunks[0].ChunkID, figures[0].ChunkID)
+	assert.Equal(t, []byte("png"), figures[0].Image)
+	assert.Equal(t, chunks[1].ChunkID, figures[1].ChunkID)
+	assert.NotEqual(t, figures[0].FigureID, figures[1].FigureID, "figures numbered alike in two chapters")
+
+	require.Len(t, chunks[0].Figures, 1)
+	assert.Equal(t, db.FigureRef{FigureID: figures[0].FigureID, Label: "Fig. 1", Caption: "Fig. 1. The flowering plant."}, chunks[0].Figures[0])
+	require.Len(t, chunks[1].Figures, 1, "a mention links to its chapter's figure, and Plate 2 has none")
+	assert.Equal(t, figures[1].FigureID, chunks[1].Figures[0].FigureID)
+}
+
+type fakeStorage map[string][]byte
+
+func (s fakeStorage) UploadBuffer(ctx context.Context, bucketName, path string, fileData []byte) (string, error) {
+	s[bucketName+"/"+path] = fileData
+	return path, nil
+}
+
+func TestStoreFigureImages(t *testing.T) {
+	figures := []Figure{
+		{FigureModel: db.FigureModel{FigureID: "a"}, Image: []byte("png")},
+		{FigureModel: db.FigureModel{FigureID: "b"}},
+	}
+	storage := fakeStorage{}
+	require.NoError(t, StoreFigureImages(t.Context(), storage, "clinic", figures))
+	assert.Equal(t, fakeStorage{"clinic/figures/a.png": []byte("png")}, storage)
+	assert.Equal(t, "figures/a.png", figures[0].ImagePath)
+	assert.Empty(t, figures[1].ImagePath, "a figure without an image keeps its caption only")
+}
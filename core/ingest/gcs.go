@@ -0,0 +1,73 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// GCSSource reads the objects under a prefix of a Google Cloud Storage bucket, with
+// Application Default Credentials.
+type GCSSource struct {
+	client *storage.Client
+	bucket string
+	prefix string
+	etags  map[string]string // as of the last List
+}
+
+func NewGCSSource(ctx context.Context, bucket, prefix string, opts ...option.ClientOption) (*GCSSource, error) {
+	if bucket == "" {
+		return nil, errors.New("bucket is required")
+	}
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, errors.New("failed to create storage client: " + err.Error())
+	}
+	return &GCSSource{client: client, bucket: bucket, prefix: prefix}, nil
+}
+
+func (s *GCSSource) List(ctx context.Context) ([]string, error) {
+	var names []string
+	etags := map[string]string{}
+	objects := s.client.Bucket(s.bucket).Objects(ctx, &storage.Query{Prefix: s.prefix})
+	for {
+		attrs, err := objects.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, errors.New("failed to list objects: " + err.Error())
+		}
+		if strings.HasSuffix(attrs.Name, "/") {
+			continue
+		}
+		names = append(names, attrs.Name)
+		etags[attrs.Name] = attrs.Etag
+	}
+	sort.Strings(names)
+	s.etags = etags
+	return names, nil
+}
+
+func (s *GCSSource) Read(ctx context.Context, name string) ([]byte, error) {
+	reader, err := s.client.Bucket(s.bucket).Object(name).NewReader(ctx)
+	if err != nil {
+		return nil, errors.New("failed to download object: " + err.Error())
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func (s *GCSSource) URI(name string) string {
+	return "gs://" + s.bucket + "/" + name
+}
+
+func (s *GCSSource) Version(name string) string {
+	return s.etags[name]
+}
@@ -0,0 +1,229 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// convertHtml reads the main content of a web page: its <main> or <article> element
+// when it has one, else its body without navigation, headers, footers and sidebars.
+// The page's title and author meta tag name its source.
+func convertHtml(_ context.Context, name string, data []byte) (*Document, error) {
+	root, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("failed to parse HTML: " + err.Error())
+	}
+
+	doc := &Document{}
+	if title := findElement(root, atom.Title); title != nil {
+		doc.Source.Book = collapseSpace(nodeText(title))
+	}
+	for _, meta := range findElements(root, atom.Meta) {
+		content := collapseSpace(htmlAttr(meta, "content"))
+		switch strings.ToLower(htmlAttr(meta, "name")) {
+		case "author", "dc.creator", "citation_author":
+			if doc.Source.Author == "" {
+				doc.Source.Author = content
+			}
+		case "dc.date", "citation_publication_date", "article:published_time":
+			if len(content) >= 4 && doc.Source.Year == 0 {
+				doc.Source.Year, _ = strconv.Atoi(content[:4])
+			}
+		}
+	}
+	if doc.Source.Book == "" {
+		doc.Source.Book = documentTitle(name)
+	}
+
+	content := findElement(root, atom.Main)
+	if content == nil {
+		content = findElement(root, atom.Article)
+	}
+	if content == nil {
+		content = root
+	}
+
+	// an article's own header holds its title, so only the page's chrome is skipped
+	writer := &htmlWriter{doc: doc, skipChrome: content == root}
+	writer.write(content)
+	writer.flush()
+	if len(doc.Blocks) == 0 {
+		return nil, errors.New("HTML has no text")
+	}
+	return doc, nil
+}
+
+// htmlWriter adds the text of HTML to a document: headings as headings, tables as
+// tables, and each other block element as a paragraph of its own.
+type htmlWriter struct {
+	doc        *Document
+	text       strings.Builder // of the paragraph being read
+	skipChrome bool            // leave out navigation, page headers, footers and sidebars
+}
+
+func (w *htmlWriter) write(node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		// whitespace around inline text collapses to a single space
+		text := strings.Join(strings.Fields(node.Data), " ")
+		if text == "" {
+			if node.Data != "" && !w.endsWithSpace() {
+				w.text.WriteByte(' ')
+			}
+			return
+		}
+		if unicode.IsSpace(rune(node.Data[0])) && !w.endsWithSpace() {
+			w.text.WriteByte(' ')
+		}
+		w.text.WriteString(text)
+		if unicode.IsSpace(rune(node.Data[len(node.Data)-1])) {
+			w.text.WriteByte(' ')
+		}
+		return
+	case html.ElementNode:
+		switch node.DataAtom {
+		case atom.Script, atom.Style, atom.Head, atom.Noscript, atom.Template:
+			return
+		case atom.Nav, atom.Header, atom.Footer, atom.Aside, atom.Form:
+			if w.skipChrome {
+				return
+			}
+		case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+			w.flush()
+			if heading := collapseSpace(nodeText(node)); heading != "" {
+				w.doc.AddHeading(int(node.Data[1]-'0'), heading)
+			}
+			return
+		case atom.Table:
+			w.flush()
+			if caption := findElement(node, atom.Caption); caption != nil {
+				if text := collapseSpace(nodeText(caption)); text != "" {
+					w.doc.AddParagraph(text)
+				}
+			}
+			w.doc.AddTable(htmlTableRows(node))
+			return
+		case atom.Br:
+			w.text.WriteByte('\n')
+			return
+		}
+	}
+
+	block := node.Type == html.ElementNode && isBlock(node.DataAtom)
+	if block {
+		w.flush()
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		w.write(child)
+	}
+	if block {
+		w.flush()
+	}
+}
+
+// htmlTableRows reads the text of each cell of a table, row by row. The rows of tables
+// nested in a cell are part of that cell's text.
+func htmlTableRows(table *html.Node) [][]string {
+	var rows [][]string
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch child.DataAtom {
+			case atom.Tr:
+				var row []string
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+						row = append(row, collapseSpace(nodeText(cell)))
+					}
+				}
+				rows = append(rows, row)
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				walk(child)
+			}
+		}
+	}
+	walk(table)
+	return rows
+}
+
+// flush ends the paragraph being read, if it has any text.
+func (w *htmlWriter) flush() {
+	var lines []string
+	for _, line := range strings.Split(w.text.String(), "\n") {
+		if line = collapseSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	w.text.Reset()
+	if len(lines) > 0 {
+		w.doc.AddParagraph(strings.Join(lines, "\n"))
+	}
+}
+
+func (w *htmlWriter) endsWithSpace() bool {
+	text := w.text.String()
+	return text == "" || unicode.IsSpace(rune(text[len(text)-1]))
+}
+
+func isBlock(a atom.Atom) bool {
+	switch a {
+	case atom.P, atom.Div, atom.Li, atom.Blockquote, atom.Tr, atom.Table, atom.Section, atom.Article, atom.Main,
+		atom.Dd, atom.Dt, atom.Pre, atom.Ul, atom.Ol, atom.Figure, atom.Figcaption:
+		return true
+	}
+	return false
+}
+
+func nodeText(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	var text strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		text.WriteString(nodeText(child))
+		text.WriteByte(' ')
+	}
+	return text.String()
+}
+
+func findElement(node *html.Node, a atom.Atom) *html.Node {
+	if node.Type == html.ElementNode && node.DataAtom == a {
+		return node
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func findElements(node *html.Node, a atom.Atom) []*html.Node {
+	var found []*html.Node
+	if node.Type == html.ElementNode && node.DataAtom == a {
+		found = append(found, node)
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		found = append(found, findElements(child, a)...)
+	}
+	return found
+}
+
+func htmlAttr(node *html.Node, name string) string {
+	for _, attr := range node.Attr {
+		if strings.EqualFold(attr.Key, name) {
+			return attr.Val
+		}
+	}
+	return ""
+}
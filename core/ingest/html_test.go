@@ -0,0 +1,38 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertHtml(t *testing.T) {
+	page := `<!doctype html><html><head><title>Fever protocol</title>
+<meta name="author" content="Dr. A. Shah"><meta name="citation_publication_date" content="2021/03/01"></head>
+<body><nav><a href="/">Home</a></nav>
+<main><header><h1>Managing fever</h1></header>
+<p>Start with <b>Belladonna</b> for sudden, high fever.</p>
+<ul><li>Red face</li><li>Dilated pupils</li></ul>
+<aside>Related: <a href="/chill">Chill</a></aside></main>
+<footer>© Clinic</footer></body></html>`
+
+	doc, err := convertHtml(t.Context(), "fever.html", []byte(page))
+	require.NoError(t, err)
+	assert.Equal(t, SourceMetadata{Book: "Fever protocol", Author: "Dr. A. Shah", Year: 2021}, doc.Source)
+	assert.Equal(t, []Block{
+		{Kind: BlockHeading, Level: 1, Text: "Managing fever"},
+		{Kind: BlockParagraph, Text: "Start with Belladonna for sudden, high fever."},
+		{Kind: BlockParagraph, Text: "Red face"},
+		{Kind: BlockParagraph, Text: "Dilated pupils"},
+		{Kind: BlockParagraph, Text: "Related: Chill"},
+	}, doc.Blocks, "an article keeps its own header and asides")
+
+	doc, err = convertHtml(t.Context(), "pages/chill.htm", []byte(`<body><header>Clinic</header><h2>Chill</h2>Shaking chill.<footer>©</footer></body>`))
+	require.NoError(t, err)
+	assert.Equal(t, "chill", doc.Source.Book)
+	assert.Equal(t, []Block{
+		{Kind: BlockHeading, Level: 2, Text: "Chill"},
+		{Kind: BlockParagraph, Text: "Shaking chill."},
+	}, doc.Blocks, "page chrome is left out")
+}
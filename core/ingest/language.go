@@ -0,0 +1,150 @@
+package ingest
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
+	"github.com/SaiNageswarS/medicine-rag/core/lang"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+)
+
+// NamedEmbedder is an embedder with the registry name tenants choose it by.
+type NamedEmbedder struct {
+	Name     string
+	Spec     embedding.Spec
+	Embedder embed.Embedder
+}
+
+// DetectLanguages sets each chunk's language, as lang.Detect tells it from the chunk's
+// sentences. A chunk it can't tell, such as a list of remedy names, is taken to be in
+// the language most of the document's chunks are in.
+func DetectLanguages(chunks []db.ChunkModel) {
+	counts := map[string]int{}
+	for i := range chunks {
+		chunks[i].Language = ""
+		if detected, ok := lang.Detect(strings.Join(chunks[i].Sentences, " ")); ok {
+			chunks[i].Language = detected.Code
+			counts[detected.Code]++
+		}
+	}
+	prevailing := mostCommon(counts)
+	for i := range chunks {
+		if chunks[i].Language == "" {
+			chunks[i].Language = prevailing
+		}
+	}
+}
+
+// Languages returns the languages of chunks, most chunks first.
+func Languages(chunks []db.ChunkModel) []string {
+	counts := map[string]int{}
+	for _, chunk := range chunks {
+		if chunk.Language != "" {
+			counts[chunk.Language]++
+		}
+	}
+	languages := slices.Sorted(maps.Keys(counts))
+	slices.SortStableFunc(languages, func(a, b string) int { return cmp.Compare(counts[b], counts[a]) })
+	return languages
+}
+
+// routeEmbedder switches the tenant to the Multilingual embedder when chunks are not all
+// in English and the tenant's embedder knows English only. Only a tenant without
+// vectors is switched, since vectors of different models can't share its index, and
+// its vector index is resized for the new model's. One with vectors keeps its
+// embedder, and the document is embedded with it all the same.
+//
+// Only the config's embedder is set, and only while it is still the one this job
+// resolved, so a concurrent edit of the config survives and two jobs can't both switch.
+// A job still embedding with the old embedder is stopped by CheckStoredVectors.
+func (p *Pipeline) routeEmbedder(ctx context.Context, tenant, name string, chunks []db.ChunkModel) error {
+	languages := Languages(chunks)
+	english := len(languages) == 0 || len(languages) == 1 && languages[0] == lang.English.Code
+	if p.Multilingual == nil || english || p.spec.Multilingual() {
+		return nil
+	}
+
+	hasVectors := func() (bool, error) {
+		vectors, err := async.Await(odm.CollectionOf[db.ChunkAnnModel](p.mongo, tenant).Count(ctx, bson.M{}))
+		if err != nil {
+			return false, errors.New("failed to count stored embeddings: " + err.Error())
+		}
+		return vectors > 0, nil
+	}
+	if stored, err := hasVectors(); err != nil || stored {
+		if stored {
+			logger.Error("Document is not in English but the tenant's embedder knows English only; set a multilingual embedder and re-embed",
+				zap.String("tenant", tenant), zap.String("document", name), zap.Strings("languages", languages), zap.String("embedder", p.spec.Name))
+		}
+		return err
+	}
+
+	previous, err := TenantEmbedderName(ctx, p.mongo, tenant)
+	if err != nil {
+		return errors.New("failed to load tenant config: " + err.Error())
+	}
+	switched, err := setTenantEmbedder(ctx, p.mongo, tenant, previous, p.Multilingual.Name)
+	if err != nil {
+		return err
+	}
+	if !switched {
+		logger.Info("Tenant's embedder changed while ingesting; not switching it", zap.String("tenant", tenant), zap.String("document", name))
+		return nil
+	}
+
+	// another job may have saved vectors of the old model before the switch
+	if stored, err := hasVectors(); err != nil || stored {
+		if _, rollbackErr := setTenantEmbedder(ctx, p.mongo, tenant, p.Multilingual.Name, previous); rollbackErr != nil {
+			logger.Error("Failed to restore tenant embedder", zap.String("tenant", tenant), zap.String("embedder", previous), zap.Error(rollbackErr))
+		}
+		return err
+	}
+
+	if p.Multilingual.Spec.Dimensions != p.spec.Dimensions {
+		if err := db.ResizeVectorIndex(ctx, p.mongo, tenant, p.Multilingual.Spec.Dimensions); err != nil {
+			if _, rollbackErr := setTenantEmbedder(ctx, p.mongo, tenant, p.Multilingual.Name, previous); rollbackErr != nil {
+				logger.Error("Failed to restore tenant embedder", zap.String("tenant", tenant), zap.String("embedder", previous), zap.Error(rollbackErr))
+			}
+			return errors.New("failed to resize vector index: " + err.Error())
+		}
+	}
+
+	logger.Info("Switched tenant to the multilingual embedder", zap.String("tenant", tenant), zap.String("document", name),
+		zap.Strings("languages", languages), zap.String("from", p.spec.Name), zap.String("to", p.Multilingual.Name))
+	p.spec, p.embedder = p.Multilingual.Spec, p.Multilingual.Embedder
+	return nil
+}
+
+// setTenantEmbedder sets the embedder of the tenant's config to name if it is still
+// from, empty being the default. It reports whether it did.
+func setTenantEmbedder(ctx context.Context, client odm.MongoClient, tenant, from, name string) (bool, error) {
+	var current any = from
+	if from == "" {
+		current = bson.M{"$in": bson.A{nil, ""}}
+	}
+	result, err := client.Database(tenant).Collection(db.TenantConfigModel{}.CollectionName()).UpdateOne(ctx,
+		bson.M{"_id": db.TenantConfigID, "embedder": current},
+		bson.M{"$set": bson.M{"embedder": name}},
+		options.UpdateOne().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// the config exists, with another embedder
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.New("failed to save tenant embedder: " + err.Error())
+	}
+	return result.MatchedCount > 0 || result.UpsertedCount > 0, nil
+}
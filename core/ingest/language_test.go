@@ -0,0 +1,47 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectLanguages(t *testing.T) {
+	chunks := []db.ChunkModel{
+		{Sentences: []string{"Die Beschwerden sind schlimmer in der Nacht und bei Kälte."}},
+		{Sentences: []string{"Acon., Bell., Bry."}},
+		{Sentences: []string{"Der Patient ist unruhig und hat Angst vor dem Tod."}},
+		{Sentences: []string{"The patient is worse at night and in the cold."}},
+	}
+	DetectLanguages(chunks)
+	assert.Equal(t, "de", chunks[0].Language)
+	assert.Equal(t, "de", chunks[1].Language, "a list of remedies is in the document's language")
+	assert.Equal(t, "en", chunks[3].Language)
+	assert.Equal(t, []string{"de", "en"}, Languages(chunks))
+
+	remedies := []db.ChunkModel{{Sentences: []string{"Acon., Bell."}}}
+	DetectLanguages(remedies)
+	assert.Empty(t, remedies[0].Language, "nothing to tell it by")
+	assert.Empty(t, Languages(remedies))
+}
+
+func TestRouteEmbedderLeavesEnglish(t *testing.T) {
+	// English documents, and tenants whose model is multilingual, never look for vectors
+	multilingual := &NamedEmbedder{Name: "jina", Spec: embedding.Legacy}
+	english := []db.ChunkModel{{Language: "en"}}
+	german := []db.ChunkModel{{Language: "de"}}
+
+	p := &Pipeline{spec: embedding.Spec{Name: "nomic", Model: "nomic-embed-text", Dimensions: 768}, Multilingual: multilingual}
+	assert.NoError(t, p.routeEmbedder(t.Context(), "clinic", "notes.md", english))
+	assert.Equal(t, "nomic", p.spec.Name)
+
+	p = &Pipeline{spec: embedding.Spec{Name: "voyage", Model: "voyage-3.5", Dimensions: 1024}, Multilingual: multilingual}
+	assert.NoError(t, p.routeEmbedder(t.Context(), "clinic", "notes.md", german))
+	assert.Equal(t, "voyage", p.spec.Name)
+
+	p = &Pipeline{spec: embedding.Spec{Name: "nomic", Model: "nomic-embed-text", Dimensions: 768}}
+	assert.NoError(t, p.routeEmbedder(t.Context(), "clinic", "notes.md", german))
+	assert.Equal(t, "nomic", p.spec.Name)
+}
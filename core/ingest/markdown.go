@@ -0,0 +1,153 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-collection-boot/linq"
+	"go.uber.org/zap"
+)
+
+const MinSectionBytes = 4000 // Minimum bytes for a section to be considered valid
+
+// SourceMetadata describes the book a markdown file was converted from.
+type SourceMetadata struct {
+	Book   string
+	Author string
+	Year   int
+	Type   string // document type, e.g. "repertory"; empty to detect it; see DocumentType
+}
+
+// MarkdownSection is the body under one heading, with the headings above it.
+type MarkdownSection struct {
+	Path  []string // section path
+	Body  string   // section body
+	Trail []string // headings above where the section starts, before sections were merged into it
+}
+
+// Chapter is the top heading the section starts under.
+func (s MarkdownSection) Chapter() string {
+	return s.Trail[0]
+}
+
+// Section is the deepest heading the section starts under, empty for text directly
+// under its chapter.
+func (s MarkdownSection) Section() string {
+	if len(s.Trail) < 2 {
+		return ""
+	}
+	return s.Trail[len(s.Trail)-1]
+}
+
+// ParseFrontMatter reads the book, author, year and document type from a leading front
+// matter block fenced by "---" lines, and returns the markdown after it. Other keys are
+// ignored, and markdown without front matter is returned as is.
+//
+//	---
+//	book: Pocket Manual of Homoeopathic Materia Medica
+//	author: William Boericke
+//	year: 1901
+//	type: narrative
+//	---
+func ParseFrontMatter(md []byte) (SourceMetadata, []byte) {
+	var source SourceMetadata
+
+	text := string(md)
+	rest, ok := strings.CutPrefix(text, "---\n")
+	if !ok {
+		if rest, ok = strings.CutPrefix(text, "---\r\n"); !ok {
+			return source, md
+		}
+	}
+
+	for {
+		line, next, found := strings.Cut(rest, "\n")
+		line = strings.TrimSpace(line)
+		if line == "---" {
+			return source, []byte(next)
+		}
+		if !found {
+			return SourceMetadata{}, md // unterminated, so not front matter
+		}
+		rest = next
+
+		key, value, isPair := strings.Cut(line, ":")
+		if !isPair {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "book", "title":
+			source.Book = value
+		case "author":
+			source.Author = value
+		case "year", "publication_year", "publicationyear":
+			source.Year, _ = strconv.Atoi(value)
+		case "type", "document_type", "documenttype":
+			source.Type = strings.ToLower(value)
+		}
+	}
+}
+
+// ParseMarkdownSections splits markdown into the bodies under its headings. Sections
+// shorter than minBytes are merged into the section before them.
+func ParseMarkdownSections(ctx context.Context, md []byte, minBytes int) ([]MarkdownSection, error) {
+	heads := markdownHeadings(md)
+	if len(heads) == 0 {
+		return nil, errors.New("no headings found")
+	}
+
+	// ── slice raw markdown into sections (body = after heading) ───────────
+	var sections []MarkdownSection
+	var path []string
+	for i, h := range heads {
+		// update hierarchy
+		if len(path) >= h.level {
+			path = path[:h.level-1]
+		}
+		path = append(path, h.title)
+
+		start := h.lineEnd // <─ body starts *after* heading
+		end := len(md)
+		if i+1 < len(heads) {
+			end = heads[i+1].start
+		}
+
+		sections = append(sections, MarkdownSection{
+			Path:  append([]string(nil), path...), // copy
+			Body:  string(md[start:end]),
+			Trail: append([]string(nil), path...),
+		})
+	}
+
+	// ── merge small chunks ────────────────────────────────────────────────
+	if minBytes <= 0 {
+		return sections, nil
+	}
+	var merged []MarkdownSection
+	for _, s := range sections {
+		if len(s.Body) < minBytes && len(merged) > 0 {
+			prev := &merged[len(merged)-1]
+			prev.Body += "\n\n" + s.Body
+			// Append the current section's path to the previous section's path
+			prev.Path = append(prev.Path, s.Path...)
+			combinedPath, err := linq.Pipe2(
+				linq.FromSlice(ctx, prev.Path),
+				linq.Distinct(func(a string) string { return a }), // Ensure unique paths
+				linq.ToSlice[string](),
+			)
+
+			if err != nil {
+				logger.Error("Failed to combine section paths", zap.Error(err))
+			} else {
+				prev.Path = combinedPath
+			}
+		} else {
+			merged = append(merged, s)
+		}
+	}
+	return merged, nil
+}
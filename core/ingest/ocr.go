@@ -0,0 +1,109 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LowOcrConfidence is the OCR confidence below which a page is flagged for review.
+const LowOcrConfidence = 0.7
+
+// OCR recognizes the text of a scanned page. Tesseract is the default; a cloud OCR
+// API can be used instead by implementing it.
+type OCR interface {
+	// Recognize reads the text of a PNG page image, with paragraphs separated by blank
+	// lines.
+	Recognize(ctx context.Context, image []byte) (OCRResult, error)
+}
+
+type OCRResult struct {
+	Text       string
+	Confidence float64 // 0 to 1
+}
+
+// tesseractCommand is the Tesseract OCR engine, installed in the core image.
+var tesseractCommand = "tesseract"
+
+// TesseractOCR runs the tesseract command line tool.
+type TesseractOCR struct {
+	Languages string // tesseract's -l, e.g. "eng+deu"; empty uses its default
+}
+
+// Recognize takes the text and confidence of each word from tesseract's TSV output.
+// The page's confidence is the mean of its words', weighted by their length.
+func (t TesseractOCR) Recognize(ctx context.Context, image []byte) (OCRResult, error) {
+	dir, err := os.MkdirTemp("", "ingest-ocr-")
+	if err != nil {
+		return OCRResult{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	imagePath := filepath.Join(dir, "page.png")
+	if err := os.WriteFile(imagePath, image, 0o600); err != nil {
+		return OCRResult{}, err
+	}
+
+	args := []string{imagePath, "stdout"}
+	if t.Languages != "" {
+		args = append(args, "-l", t.Languages)
+	}
+	args = append(args, "tsv")
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, tesseractCommand, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return OCRResult{}, errors.New("failed to run tesseract: " + err.Error() + ": " + strings.TrimSpace(stderr.String()))
+	}
+	return parseTesseractTsv(stdout.String()), nil
+}
+
+// parseTesseractTsv reads the word rows (level 5) of tesseract's TSV output:
+//
+//	level page_num block_num par_num line_num word_num left top width height conf text
+func parseTesseractTsv(tsv string) OCRResult {
+	var (
+		text                strings.Builder
+		lastPar, lastLine   string
+		weighted, wordBytes float64
+	)
+	for _, row := range strings.Split(tsv, "\n") {
+		cols := strings.Split(strings.TrimRight(row, "\r"), "\t")
+		if len(cols) < 12 || cols[0] != "5" {
+			continue
+		}
+		word := strings.TrimSpace(cols[11])
+		conf, err := strconv.ParseFloat(cols[10], 64)
+		if word == "" || err != nil || conf < 0 {
+			continue
+		}
+
+		par, line := cols[1]+"."+cols[2]+"."+cols[3], cols[4]
+		switch {
+		case text.Len() == 0:
+		case par != lastPar:
+			text.WriteString("\n\n")
+		case line != lastLine:
+			text.WriteString("\n")
+		default:
+			text.WriteString(" ")
+		}
+		text.WriteString(word)
+		lastPar, lastLine = par, line
+
+		weighted += conf / 100 * float64(len(word))
+		wordBytes += float64(len(word))
+	}
+
+	result := OCRResult{Text: text.String()}
+	if wordBytes > 0 {
+		result.Confidence = weighted / wordBytes
+	}
+	return result
+}
@@ -0,0 +1,24 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTesseractTsv(t *testing.T) {
+	tsv := "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n" +
+		"1\t1\t0\t0\t0\t0\t0\t0\t2480\t3508\t-1\t\n" +
+		"5\t1\t1\t1\t1\t1\t10\t10\t50\t20\t96.0\tGreat\n" +
+		"5\t1\t1\t1\t1\t2\t70\t10\t50\t20\t90.0\tfear,\n" +
+		"5\t1\t1\t1\t2\t1\t10\t40\t50\t20\t60.0\tworse\n" +
+		"5\t1\t1\t1\t2\t2\t70\t40\t50\t20\t-1\t \n" +
+		"5\t1\t2\t1\t1\t1\t10\t90\t50\t20\t80.0\tRestless.\n"
+
+	result := parseTesseractTsv(tsv)
+	assert.Equal(t, "Great fear,\nworse\n\nRestless.", result.Text)
+	// (5*.96 + 5*.90 + 5*.60 + 9*.80) / 24
+	assert.InDelta(t, 0.8125, result.Confidence, 1e-9)
+
+	assert.Equal(t, OCRResult{}, parseTesseractTsv(""))
+}
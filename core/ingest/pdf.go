@@ -0,0 +1,574 @@
+package ingest
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"go.uber.org/zap"
+)
+
+// mutoolCommand is MuPDF's command line tool, installed in the core image.
+var mutoolCommand = "mutool"
+
+const (
+	// A block set at least this much larger than the body text is a heading.
+	headingSizeRatio = 1.15
+	maxHeadingBytes  = 120
+	// Heading font sizes past the third largest are all subsections.
+	maxHeadingLevel = 3
+
+	// Images smaller than this on either side, in points, are ornaments, not figures.
+	minFigureSide = 48
+	// A caption is at most this far above or below its image, in points.
+	maxCaptionGap = 36
+	// figureResolution is the DPI pages are rendered at to cut figures out of.
+	figureResolution = 150
+)
+
+var (
+	// page numbers, in arabic or roman numerals, sometimes dashed: "12", "- xiv -"
+	pageNumberPattern = regexp.MustCompile(`^[\s\-–—.]*([0-9]+|[ivxlcdm]+|[IVXLCDM]+)[\s\-–—.]*$`)
+)
+
+// PdfConverter extracts a PDF's text with its layout using mutool's structured text
+// output. Blocks set in larger type than the body text become headings, the largest
+// size a chapter, the next a section, the smaller ones subsections. Page numbers and
+// running headers are dropped, and each page's text starts with a page marker.
+//
+// Text before the first heading goes under one named after the document. A PDF with no
+// larger type has a section per page, merged when the markdown is sectioned.
+//
+// Pages without text, as in scanned books, are rendered and read with ocr; with a nil
+// ocr they are left out. A page that OCR fails on is kept empty, with no confidence.
+//
+// A short block starting with a figure's number, such as "Fig. 3. Pupils dilated",
+// captions a figure; see figureCaption. The image nearest above or below it is cut out
+// of the rendered page as the figure's image. A caption without an image, as under a
+// drawing made of lines, still makes a figure, and so does one whose page fails to
+// render.
+func PdfConverter(ocr OCR) Converter {
+	return func(ctx context.Context, name string, data []byte) (*Document, error) {
+		return convertPdf(ctx, name, data, ocr)
+	}
+}
+
+func convertPdf(ctx context.Context, name string, data []byte, ocr OCR) (*Document, error) {
+	dir, err := os.MkdirTemp("", "ingest-pdf-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	pdfPath := filepath.Join(dir, "document.pdf")
+	if err := os.WriteFile(pdfPath, data, 0o600); err != nil {
+		return nil, err
+	}
+
+	textPath := filepath.Join(dir, "text.xml")
+	var stderr bytes.Buffer
+	// images are only listed with preserve-images
+	cmd := exec.CommandContext(ctx, mutoolCommand, "draw", "-q", "-F", "stext", "-O", "preserve-images", "-o", textPath, pdfPath)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.New("failed to extract PDF text: " + err.Error() + ": " + strings.TrimSpace(stderr.String()))
+	}
+
+	text, err := os.Open(textPath)
+	if err != nil {
+		return nil, err
+	}
+	defer text.Close()
+
+	pages, err := parseStext(text)
+	if err != nil {
+		return nil, errors.New("failed to read PDF text: " + err.Error())
+	}
+	if ocr != nil {
+		if err := recognizeScannedPages(ctx, ocr, dir, pdfPath, pages); err != nil {
+			return nil, err
+		}
+	}
+	findFigures(pages)
+	if err := renderFigures(ctx, dir, pdfPath, pages); err != nil {
+		return nil, err
+	}
+	return pdfDocument(name, pages)
+}
+
+// findFigures finds the captions of each page's figures, pairing each with the nearest
+// unclaimed image above or below it that it overlaps horizontally.
+func findFigures(pages []pdfPage) {
+	for p := range pages {
+		page := &pages[p]
+		claimed := make([]bool, len(page.images))
+		for i, block := range page.blocks {
+			if !figureLabelPattern.MatchString(block.text) {
+				continue
+			}
+
+			nearest, gap := -1, 0.0
+			for j, box := range page.images {
+				if claimed[j] || min(box.x1, block.bbox.x1) <= max(box.x0, block.bbox.x0) {
+					continue
+				}
+				// negative when they overlap
+				g := max(block.bbox.y0-box.y1, box.y0-block.bbox.y1)
+				if g <= maxCaptionGap && (nearest < 0 || g < gap) {
+					nearest, gap = j, g
+				}
+			}
+
+			label, ok := figureCaption(block.text, nearest >= 0)
+			if !ok {
+				continue
+			}
+			figure := &pdfFigure{label: label}
+			if nearest >= 0 {
+				claimed[nearest] = true
+				figure.box = page.images[nearest]
+			}
+			if page.figures == nil {
+				page.figures = map[int]*pdfFigure{}
+			}
+			page.figures[i] = figure
+		}
+	}
+}
+
+// renderFigures renders the pages with figure images and cuts each image out. A page
+// that fails to render leaves its figures without images.
+func renderFigures(ctx context.Context, dir, pdfPath string, pages []pdfPage) error {
+	var rendered []string
+	for _, page := range pages {
+		for _, figure := range page.figures {
+			if !figure.box.empty() {
+				rendered = append(rendered, strconv.Itoa(page.number))
+				break
+			}
+		}
+	}
+	if len(rendered) == 0 {
+		return nil
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, mutoolCommand, "draw", "-q", "-r", strconv.Itoa(figureResolution),
+		"-o", filepath.Join(dir, "figures-%d.png"), pdfPath, strings.Join(rendered, ","))
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		logger.Error("Failed to render PDF figure pages", zap.Error(err), zap.String("stderr", strings.TrimSpace(stderr.String())))
+		return nil
+	}
+
+	for _, page := range pages {
+		for _, figure := range page.figures {
+			if figure.box.empty() {
+				continue
+			}
+			pagePng, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("figures-%d.png", page.number)))
+			if err == nil {
+				figure.image, err = cropFigure(pagePng, figure.box, figureResolution)
+			}
+			if err != nil {
+				logger.Error("Failed to cut out PDF figure", zap.Int("page", page.number), zap.String("figure", figure.label), zap.Error(err))
+			}
+		}
+	}
+	return nil
+}
+
+// cropFigure cuts box out of a page rendered at resolution DPI, as PNG.
+func cropFigure(pagePng []byte, box pdfRect, resolution int) ([]byte, error) {
+	page, err := png.Decode(bytes.NewReader(pagePng))
+	if err != nil {
+		return nil, err
+	}
+	scale := float64(resolution) / 72
+	rect := image.Rect(int(box.x0*scale), int(box.y0*scale), int(math.Ceil(box.x1*scale)), int(math.Ceil(box.y1*scale))).
+		Intersect(page.Bounds())
+	if rect.Empty() {
+		return nil, errors.New("figure is off its page")
+	}
+
+	cropped := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(cropped, cropped.Bounds(), page, rect.Min, draw.Src)
+	var out bytes.Buffer
+	if err := png.Encode(&out, cropped); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// ocrResolution is the DPI scanned pages are rendered at for OCR.
+const ocrResolution = 300
+
+// recognizeScannedPages fills in the text of the pages mutool found none on.
+func recognizeScannedPages(ctx context.Context, ocr OCR, dir, pdfPath string, pages []pdfPage) error {
+	var scanned []string
+	for _, page := range pages {
+		if len(page.blocks) == 0 {
+			scanned = append(scanned, strconv.Itoa(page.number))
+		}
+	}
+	if len(scanned) == 0 {
+		return nil
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, mutoolCommand, "draw", "-q", "-r", strconv.Itoa(ocrResolution),
+		"-o", filepath.Join(dir, "scan-%d.png"), pdfPath, strings.Join(scanned, ","))
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return errors.New("failed to render scanned PDF pages: " + err.Error() + ": " + strings.TrimSpace(stderr.String()))
+	}
+
+	for i := range pages {
+		page := &pages[i]
+		if len(page.blocks) > 0 {
+			continue
+		}
+		page.scanned = true
+
+		image, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("scan-%d.png", page.number)))
+		if err != nil {
+			return err
+		}
+		result, err := ocr.Recognize(ctx, image)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			logger.Error("Failed to OCR PDF page", zap.Int("page", page.number), zap.Error(err))
+			continue
+		}
+
+		page.confidence = result.Confidence
+		for _, paragraph := range paragraphBreak.Split(result.Text, -1) {
+			if lines := paragraphLines(paragraph); len(lines) > 0 {
+				page.blocks = append(page.blocks, pdfBlock{text: joinLines(lines)})
+			}
+		}
+	}
+	return nil
+}
+
+// paragraphLines splits a paragraph into its non-empty lines, spaces collapsed.
+func paragraphLines(paragraph string) []string {
+	var lines []string
+	for _, line := range strings.Split(paragraph, "\n") {
+		if line = collapseSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// pdfBlock is a paragraph or heading of a page, as mutool grouped its lines.
+type pdfBlock struct {
+	text string
+	size float64 // font size of most of its characters
+	bbox pdfRect // none for scanned pages
+}
+
+// pdfRect is a box on a page, in points from its top left corner.
+type pdfRect struct {
+	x0, y0, x1, y1 float64
+}
+
+func (r pdfRect) empty() bool { return r.x1 <= r.x0 || r.y1 <= r.y0 }
+
+// parseRect reads a bbox attribute: "x0 y0 x1 y1".
+func parseRect(bbox string) pdfRect {
+	var r pdfRect
+	fmt.Sscanf(bbox, "%g %g %g %g", &r.x0, &r.y0, &r.x1, &r.y1)
+	return r
+}
+
+// pdfFigure is a figure a block of a page captions.
+type pdfFigure struct {
+	label string
+	box   pdfRect // of its image; empty for a figure without one
+	image []byte  // PNG cut out of the page
+}
+
+type pdfPage struct {
+	number     int
+	blocks     []pdfBlock
+	images     []pdfRect          // large enough to be figures
+	scanned    bool               // its text was recognized from its image
+	confidence float64            // of the OCR, for scanned pages
+	figures    map[int]*pdfFigure // by the index of the block captioning them
+}
+
+// parseStext reads the pages, text blocks and images of mutool's structured text XML:
+//
+//	<page id="page1"><block bbox="…"><line><font name="Times-Bold" size="14"><char c="A"/>…
+//	<image bbox="72 90 300 310"/>
+//
+// A block's lines are joined with spaces, and words hyphenated across lines rejoined.
+func parseStext(r io.Reader) ([]pdfPage, error) {
+	var (
+		pages []pdfPage
+		page  *pdfPage
+		lines []string
+		line  strings.Builder
+		sizes map[float64]int
+		size  float64
+		bbox  pdfRect
+	)
+
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "page":
+				pages = append(pages, pdfPage{number: len(pages) + 1})
+				page = &pages[len(pages)-1]
+			case "block":
+				lines, sizes = nil, map[float64]int{}
+				bbox = parseRect(xmlAttr(t, "bbox"))
+			case "image":
+				if box := parseRect(xmlAttr(t, "bbox")); page != nil && box.x1-box.x0 >= minFigureSide && box.y1-box.y0 >= minFigureSide {
+					page.images = append(page.images, box)
+				}
+			case "line":
+				line.Reset()
+			case "font":
+				size, _ = strconv.ParseFloat(xmlAttr(t, "size"), 64)
+				size = math.Round(size*2) / 2 // sizes differ in rounding only
+			case "char":
+				c := xmlAttr(t, "c")
+				line.WriteString(c)
+				if strings.TrimSpace(c) != "" && sizes != nil {
+					sizes[size]++
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "line":
+				if text := collapseSpace(line.String()); text != "" {
+					lines = append(lines, text)
+				}
+			case "block":
+				if page != nil && len(lines) > 0 {
+					page.blocks = append(page.blocks, pdfBlock{text: joinLines(lines), size: mostCommon(sizes), bbox: bbox})
+				}
+				lines, sizes = nil, nil
+			}
+		}
+	}
+	return pages, nil
+}
+
+// pdfDocument lays out the pages' blocks as a document; see PdfConverter.
+func pdfDocument(name string, pages []pdfPage) (*Document, error) {
+	bodySize := pdfBodySize(pages)
+	furniture := runningHeaders(pages)
+	levels := headingLevels(pages, bodySize)
+
+	doc := &Document{}
+	headed, empty := false, true
+	startPage := func(page pdfPage, pageHeading bool) {
+		if !headed {
+			doc.AddHeading(1, documentTitle(name))
+			headed = true
+		}
+		if pageHeading {
+			doc.AddHeading(2, fmt.Sprintf("Page %d", page.number))
+		}
+		doc.Blocks = append(doc.Blocks, Block{Kind: BlockPage, Page: page.number, Scanned: page.scanned, OcrConfidence: page.confidence})
+	}
+
+	for _, page := range pages {
+		marked := false
+		if page.scanned && len(page.blocks) == 0 {
+			startPage(page, false) // kept, so the page is flagged for review
+		}
+		for i, block := range page.blocks {
+			if furniture[normalizeHeader(block.text)] || pageNumberPattern.MatchString(block.text) {
+				continue
+			}
+			empty = false
+
+			figure, captions := page.figures[i]
+			if level, ok := levels[block.size]; ok && !captions && isHeading(block, bodySize) {
+				doc.AddHeading(level, block.text)
+				headed = true
+				continue
+			}
+
+			if !marked {
+				// after the page's headings, so they start the section it marks
+				startPage(page, len(levels) == 0)
+				marked = true
+			}
+			if captions {
+				doc.AddFigure(figure.label, block.text, page.number, figure.image)
+				continue
+			}
+			doc.AddParagraph(block.text)
+		}
+	}
+	if empty {
+		return nil, errors.New("PDF has no extractable text")
+	}
+	return doc, nil
+}
+
+// pdfBodySize is the font size most of the text is set in. Scanned pages have no font
+// sizes.
+func pdfBodySize(pages []pdfPage) float64 {
+	bytesBySize := map[float64]int{}
+	for _, page := range pages {
+		if page.scanned {
+			continue
+		}
+		for _, block := range page.blocks {
+			bytesBySize[block.size] += len(block.text)
+		}
+	}
+	return mostCommon(bytesBySize)
+}
+
+func isHeading(block pdfBlock, bodySize float64) bool {
+	return bodySize > 0 && block.size >= bodySize*headingSizeRatio &&
+		len(block.text) <= maxHeadingBytes && strings.IndexFunc(block.text, unicode.IsLetter) >= 0
+}
+
+// headingLevels numbers the font sizes headings are set in, largest first.
+func headingLevels(pages []pdfPage, bodySize float64) map[float64]int {
+	var sizes []float64
+	seen := map[float64]bool{}
+	for _, page := range pages {
+		for _, block := range page.blocks {
+			if isHeading(block, bodySize) && !seen[block.size] {
+				seen[block.size] = true
+				sizes = append(sizes, block.size)
+			}
+		}
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(sizes)))
+
+	levels := make(map[float64]int, len(sizes))
+	for i, size := range sizes {
+		levels[size] = min(i+1, maxHeadingLevel)
+	}
+	return levels
+}
+
+// runningHeaders finds the short blocks repeated on most pages of a longer document,
+// such as a book's title atop each page. Numbers in them are ignored, as headers often
+// carry the page number.
+func runningHeaders(pages []pdfPage) map[string]bool {
+	headers := map[string]bool{}
+	if len(pages) < 4 {
+		return headers
+	}
+
+	pagesByText := map[string]int{}
+	for _, page := range pages {
+		onPage := map[string]bool{}
+		for _, block := range page.blocks {
+			if len(block.text) > maxHeadingBytes {
+				continue
+			}
+			if text := normalizeHeader(block.text); text != "" && !onPage[text] {
+				onPage[text] = true
+				pagesByText[text]++
+			}
+		}
+	}
+	for text, count := range pagesByText {
+		if count > len(pages)/2 {
+			headers[text] = true
+		}
+	}
+	return headers
+}
+
+func normalizeHeader(text string) string {
+	return collapseSpace(strings.ToLower(strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return ' '
+		}
+		return r
+	}, text)))
+}
+
+// joinLines joins a block's lines into one paragraph, rejoining words hyphenated at the
+// end of a line.
+func joinLines(lines []string) string {
+	var text strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			first, _ := firstRune(line)
+			if strings.HasSuffix(prev, "-") && unicode.IsLower(first) {
+				trimmed := strings.TrimSuffix(text.String(), "-")
+				text.Reset()
+				text.WriteString(trimmed)
+			} else {
+				text.WriteString(" ")
+			}
+		}
+		text.WriteString(line)
+	}
+	return text.String()
+}
+
+func firstRune(text string) (rune, bool) {
+	for _, r := range text {
+		return r, true
+	}
+	return 0, false
+}
+
+func mostCommon[K cmp.Ordered](counts map[K]int) K {
+	var best K
+	bestCount := 0
+	for value, count := range counts {
+		if count > bestCount || (count == bestCount && value < best) {
+			best, bestCount = value, count
+		}
+	}
+	return best
+}
+
+func xmlAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
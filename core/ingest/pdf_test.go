@@ -0,0 +1,176 @@
+package ingest
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPdfDocument(t *testing.T) {
+	mind := strings.Repeat("Great fear and anxiety of mind. ", 130)
+	pages := [][]string{
+		{block("Times-Bold", 20, "Aconitum Napellus"), block("Times-Bold", 14, "Mind"), block("Times-Roman", 10, mind)},
+		{block("Times-Roman", 10, "Worse at night, on lying on the af-", "fected side."), block("Times-Bold", 14, "Fever")},
+		{block("Times-Roman", 10, "Dry burning heat.")},
+		{block("Times-Roman", 10, "Chill when uncovered.")},
+	}
+
+	var stext strings.Builder
+	stext.WriteString(`<?xml version="1.0"?>` + "\n<document name=\"materia.pdf\">\n")
+	for i, blocks := range pages {
+		fmt.Fprintf(&stext, "<page id=\"page%d\" width=\"595\" height=\"842\">\n", i+1)
+		stext.WriteString(block("Times-Roman", 8, fmt.Sprintf("MATERIA MEDICA %d", i+1)))
+		for _, b := range blocks {
+			stext.WriteString(b)
+		}
+		stext.WriteString(block("Times-Roman", 8, fmt.Sprintf("- %d -", i+1)))
+		stext.WriteString("</page>\n")
+	}
+	stext.WriteString("</document>\n")
+
+	parsed, err := parseStext(strings.NewReader(stext.String()))
+	require.NoError(t, err)
+	require.Len(t, parsed, 4)
+	assert.Equal(t, pdfBlock{text: "Worse at night, on lying on the affected side.", size: 10}, parsed[1].blocks[1])
+
+	doc, err := pdfDocument("materia.pdf", parsed)
+	require.NoError(t, err)
+	md := doc.Markdown()
+	assert.NotContains(t, string(md), "MATERIA MEDICA", "running headers are dropped")
+	assert.NotContains(t, string(md), "- 2 -", "page numbers are dropped")
+	assert.True(t, strings.HasPrefix(string(md), "# Aconitum Napellus\n\n## Mind\n\n<!-- page 1 -->\n\n"))
+	assert.Contains(t, string(md), "## Fever\n\n<!-- page 3 -->\n\nDry burning heat.")
+
+	chunks, err := ChunkMarkdown(t.Context(), "file://materia.pdf", md, db.ChunkingConfig{})
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+	assert.Equal(t, "Aconitum Napellus", chunks[0].Chapter)
+	assert.Equal(t, "Mind", chunks[0].Section)
+	assert.Equal(t, []int{1, 1}, []int{chunks[0].PageStart, chunks[0].PageEnd})
+	assert.Equal(t, []int{1, 4}, []int{chunks[1].PageStart, chunks[1].PageEnd}, "the merged fever section runs to page 4")
+}
+
+func TestPdfDocumentWithoutHeadings(t *testing.T) {
+	pages := []pdfPage{
+		{number: 1, blocks: []pdfBlock{{text: "Dullness, drowsiness.", size: 11}}},
+		{number: 2, blocks: []pdfBlock{{text: "Trembling of the limbs.", size: 11}}},
+	}
+
+	doc, err := pdfDocument("books/gelsemium.pdf", pages)
+	require.NoError(t, err)
+	md := doc.Markdown()
+	assert.Equal(t, "# gelsemium\n\n## Page 1\n\n<!-- page 1 -->\n\nDullness, drowsiness.\n\n"+
+		"## Page 2\n\n<!-- page 2 -->\n\nTrembling of the limbs.\n\n", string(md))
+
+	_, err = pdfDocument("blank.pdf", []pdfPage{{number: 1}})
+	assert.Error(t, err)
+}
+
+func TestPdfDocumentScannedPages(t *testing.T) {
+	pages := []pdfPage{
+		{number: 1, blocks: []pdfBlock{{text: "Dullness, drowsiness.", size: 11}}},
+		{number: 2, blocks: []pdfBlock{{text: "Trembling of the limbs."}}, scanned: true, confidence: 0.55},
+		{number: 3, scanned: true}, // OCR failed
+		{number: 4, blocks: []pdfBlock{{text: "Thirstless."}}, scanned: true, confidence: 0.93},
+	}
+
+	doc, err := pdfDocument("gelsemium.pdf", pages)
+	require.NoError(t, err)
+	md := doc.Markdown()
+	assert.Contains(t, string(md), "## Page 2\n\n<!-- page 2 ocr 0.55 -->\n\nTrembling of the limbs.\n\n<!-- page 3 ocr 0.00 -->\n\n")
+	assert.Equal(t, []int{2, 3}, doc.LowConfidencePages())
+
+	chunks, err := ChunkMarkdown(t.Context(), "file://gelsemium.pdf", md, db.ChunkingConfig{})
+	require.NoError(t, err)
+	require.Len(t, chunks, 1, "short pages are merged")
+	assert.Equal(t, []int{1, 4}, []int{chunks[0].PageStart, chunks[0].PageEnd})
+	assert.Equal(t, 0.0, chunks[0].OcrConfidence, "page 3 is within the chunk's pages")
+	assert.True(t, chunks[0].NeedsReview)
+
+	clean := db.ChunkModel{PageStart: 4, PageEnd: 4}
+	flagOcrConfidence(&clean, ocrConfidences(md))
+	assert.Equal(t, 0.93, clean.OcrConfidence)
+	assert.False(t, clean.NeedsReview)
+}
+
+// block renders one stext block with a line per text, set in font at size.
+func block(font string, size float64, lines ...string) string {
+	var b strings.Builder
+	b.WriteString("<block>\n")
+	for _, line := range lines {
+		fmt.Fprintf(&b, "<line><font name=%q size=\"%g\">", font, size)
+		for _, c := range line {
+			fmt.Fprintf(&b, "<char c=%q/>", strings.ReplaceAll(string(c), `"`, "&quot;"))
+		}
+		b.WriteString("</font></line>\n")
+	}
+	b.WriteString("</block>\n")
+	return b.String()
+}
+
+func TestPdfDocumentFigures(t *testing.T) {
+	captioned := func(bbox, text string) string {
+		return strings.Replace(block("Times-Roman", 9, text), "<block>", fmt.Sprintf("<block bbox=%q>", bbox), 1)
+	}
+	stext := `<?xml version="1.0"?>` + "\n<document name=\"atlas.pdf\">\n<page id=\"page1\" width=\"595\" height=\"842\">\n" +
+		block("Times-Bold", 20, "Aconitum Napellus") +
+		`<image bbox="72 90 300 310"/>` + "\n" +
+		captioned("72 316 300 330", "Fig. 1. The flowering plant.") +
+		`<image bbox="400 90 420 100"/>` + "\n" + // an ornament, too small to be a figure
+		captioned("72 400 520 430", "Figure 2 shows the root, which is the most poisonous part.") +
+		block("Times-Roman", 9, "Plate II: The root, cut lengthwise.") +
+		"</page>\n</document>\n"
+
+	pages, err := parseStext(strings.NewReader(stext))
+	require.NoError(t, err)
+	require.Len(t, pages, 1)
+	assert.Equal(t, []pdfRect{{72, 90, 300, 310}}, pages[0].images)
+	assert.Equal(t, pdfRect{72, 316, 300, 330}, pages[0].blocks[1].bbox)
+
+	findFigures(pages)
+	require.Len(t, pages[0].figures, 2)
+	assert.Equal(t, &pdfFigure{label: "Fig. 1", box: pdfRect{72, 90, 300, 310}}, pages[0].figures[1])
+	assert.Equal(t, &pdfFigure{label: "Plate II"}, pages[0].figures[3], "captioned, but without an image")
+	assert.Nil(t, pages[0].figures[2], "text mentioning a figure is no caption")
+
+	pages[0].figures[1].image = []byte("png")
+	doc, err := pdfDocument("atlas.pdf", pages)
+	require.NoError(t, err)
+	var figures []Block
+	for _, block := range doc.Blocks {
+		if block.Kind == BlockFigure {
+			figures = append(figures, block)
+		}
+	}
+	require.Len(t, figures, 2)
+	assert.Equal(t, Block{Kind: BlockFigure, Label: "Fig. 1", Text: "Fig. 1. The flowering plant.", Page: 1, Image: []byte("png")}, figures[0])
+	assert.Equal(t, "Plate II", figures[1].Label)
+}
+
+func TestCropFigure(t *testing.T) {
+	page := image.NewRGBA(image.Rect(0, 0, 200, 300))
+	draw.Draw(page, image.Rect(50, 100, 150, 200), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	var pagePng bytes.Buffer
+	require.NoError(t, png.Encode(&pagePng, page))
+
+	// at 144 DPI a point is two pixels
+	cropped, err := cropFigure(pagePng.Bytes(), pdfRect{25, 50, 75, 100}, 144)
+	require.NoError(t, err)
+	figure, err := png.Decode(bytes.NewReader(cropped))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 100, 100), figure.Bounds())
+	r, g, b, _ := figure.At(50, 50).RGBA()
+	assert.Zero(t, r+g+b)
+
+	_, err = cropFigure(pagePng.Bytes(), pdfRect{500, 500, 600, 600}, 144)
+	assert.Error(t, err, "off the page")
+}
@@ -0,0 +1,490 @@
+package ingest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
+	"go.uber.org/zap"
+)
+
+// Pipeline ingests the documents of a source into a tenant: each document is converted
+// to a Document, chunked, published as a corpus version with the rows of its tables
+// and its figures, and embedded in batches.
+//
+// Progress is recorded per document in the tenant's ingest_progress collection, so a
+// run that is interrupted or fails on some documents can simply be started again.
+// Documents that were fully ingested and have not changed are skipped, and documents
+// whose chunks were published but not all embedded only have the rest embedded. Objects
+// of a VersionedSource whose ETag is the one ingested are skipped without being read.
+//
+// A document with the same bytes as one already ingested from another source, or
+// nearly the same text, is not chunked again. Its progress records the source it
+// duplicates, whose chunks stand for both. Only documents for the same access groups
+// stand for each other.
+//
+// A document ingested again for other access groups counts as changed. It is chunked
+// again to tag its chunks, but they keep their IDs, so none is embedded again.
+//
+// Chunks whose text scores below the tenant's minimum quality, such as OCR garbage or
+// running heads, are quarantined for review instead of published; see ScoreChunks.
+//
+// Case journals, or the documents the tenant's config says, have patients' names,
+// phone numbers, addresses and other identifiers taken out before they are chunked, so
+// no chunk, vector or table row holds them; see Deidentifier.
+type Pipeline struct {
+	mongo    odm.MongoClient
+	spec     embedding.Spec
+	embedder embed.Embedder
+
+	Converters   map[string]Converter // by lower-cased file extension
+	BatchSize    int                  // chunks embedded per request
+	Workers      int                  // embedding requests in flight at once
+	Force        bool                 // re-chunk unchanged documents too
+	DocumentType string               // chunk every document as this type; empty detects each one's
+
+	// Ingest documents that are copies of ones already ingested from other sources,
+	// instead of recording them as duplicates; see Fingerprint.
+	AllowDuplicates bool
+	// Only users in one of these groups may retrieve the documents; empty is everyone in
+	// the tenant. See db.AccessFilter.
+	AccessGroups []string
+	// Where figure images are kept, in the tenant's bucket; nil keeps only their
+	// captions. See Figures.
+	Storage FigureStorage
+	// What a tenant whose embedder knows English only is switched to by the first
+	// document it ingests in another language, while it has no vectors; nil never
+	// switches. See DetectLanguages.
+	Multilingual *NamedEmbedder
+	// Called with each document that becomes searchable, once its chunks are all
+	// embedded, and what ingesting it did; nil calls nothing. Unchanged documents and
+	// duplicates are not indexed.
+	Indexed func(ctx context.Context, sourceUri string, document Report)
+}
+
+// ErrUnsupportedDocument is returned for a document without a converter for its
+// file extension.
+var ErrUnsupportedDocument = errors.New("unsupported document type")
+
+// Report counts what a run did with the source's documents.
+type Report struct {
+	Ingested    int // chunked and embedded
+	Resumed     int // chunked on an earlier run, embedded on this one
+	Unchanged   int
+	Duplicates  int // copies of documents ingested from other sources, skipped
+	Unsupported int // no converter for the file extension
+	Failed      int
+	Chunks      int // chunks published
+	TableRows   int // table rows published
+	Figures     int // figures published
+	Quarantined int // chunks held for review; see ScoreChunks
+	Redacted    int // identifiers taken out of case notes; see Deidentifier
+	Embedded    int // chunk vectors saved
+
+	Flagged     map[string][]int  // scanned pages OCR was unsure of, by source URI
+	DuplicateOf map[string]string // the source each duplicate copies, by source URI
+}
+
+func NewPipeline(mongo odm.MongoClient, spec embedding.Spec, embedder embed.Embedder) *Pipeline {
+	return &Pipeline{
+		mongo:      mongo,
+		spec:       spec,
+		embedder:   embedder,
+		Converters: DefaultConverters(),
+		BatchSize:  DefaultEmbedBatchSize,
+		Workers:    DefaultEmbedWorkers,
+	}
+}
+
+// Run ingests every document of source into tenant. A document that fails is recorded
+// with its error and the run moves on; Run itself fails only when the source can't be
+// listed, the tenant has vectors of another model, or ctx is done.
+func (p *Pipeline) Run(ctx context.Context, tenant string, source Source) (Report, error) {
+	var report Report
+
+	if err := CheckStoredVectors(ctx, p.mongo, tenant, p.spec); err != nil {
+		return report, err
+	}
+
+	names, err := source.List(ctx)
+	if err != nil {
+		return report, errors.New("failed to list source documents: " + err.Error())
+	}
+	logger.Info("Ingesting documents", zap.String("tenant", tenant), zap.Int("documents", len(names)))
+
+	for idx, name := range names {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		if _, ok := p.Converters[Extension(name)]; !ok {
+			logger.Info("Skipping unsupported document", zap.String("document", name))
+			report.Unsupported++
+			continue
+		}
+
+		version := ""
+		if versioned, ok := source.(VersionedSource); ok {
+			version = versioned.Version(name)
+		}
+		if p.ingestedVersion(ctx, tenant, source.URI(name), version) {
+			report.Unchanged++
+			logger.Info("Ingestion progress", zap.Int("processed", idx+1), zap.Int("total", len(names)), zap.String("document", name))
+			continue
+		}
+
+		data, err := source.Read(ctx, name)
+		var document Report
+		if err == nil {
+			document, err = p.ingest(ctx, tenant, source.URI(name), name, data, version, nil)
+		} else {
+			err = errors.New("failed to read document: " + err.Error())
+		}
+		report.add(document)
+		if err != nil {
+			if ctx.Err() != nil {
+				return report, ctx.Err()
+			}
+			logger.Error("Failed to ingest document", zap.String("tenant", tenant), zap.String("document", name), zap.Error(err))
+			report.Failed++
+		}
+
+		logger.Info("Ingestion progress", zap.Int("processed", idx+1), zap.Int("total", len(names)), zap.String("document", name))
+	}
+	return report, nil
+}
+
+// Ingest ingests one document's bytes as sourceUri, converting it by the extension of
+// name. onStage, if set, is called with the db.IngestionJob status of each stage the
+// document enters. Progress is recorded as in Run, so an unchanged document that was
+// fully ingested before is skipped.
+func (p *Pipeline) Ingest(ctx context.Context, tenant, sourceUri, name string, data []byte, onStage func(stage string)) (Report, error) {
+	return p.ingest(ctx, tenant, sourceUri, name, data, "", onStage)
+}
+
+// ingest records version, the document's version in its source, once the document is
+// fully ingested; see VersionedSource.
+func (p *Pipeline) ingest(ctx context.Context, tenant, sourceUri, name string, data []byte, version string, onStage func(stage string)) (Report, error) {
+	var report Report
+	if onStage == nil {
+		onStage = func(string) {}
+	}
+
+	convert, ok := p.Converters[Extension(name)]
+	if !ok {
+		report.Unsupported++
+		return report, ErrUnsupportedDocument
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	progress, err := p.loadProgress(ctx, tenant, sourceUri)
+	if err != nil {
+		return report, err
+	}
+
+	tenantConfig, err := loadTenantConfig(ctx, p.mongo, tenant)
+	if err != nil {
+		return report, errors.New("failed to load tenant config: " + err.Error())
+	}
+
+	groups := db.NormalizeAccessGroups(p.AccessGroups)
+	unchanged := progress.Checksum == checksum && slices.Equal(progress.AccessGroups, groups) && !p.Force
+	if unchanged && progress.Stage == db.IngestStageEmbedded {
+		if version != "" && progress.ETag != version {
+			// the same bytes stored again; the next run can skip them unread
+			progress.ETag = version
+			p.saveProgress(ctx, tenant, progress, nil)
+		}
+		report.Unchanged++
+		if progress.DuplicateOf != "" {
+			report.DuplicateOf = map[string]string{sourceUri: progress.DuplicateOf}
+		}
+		return report, nil
+	}
+	resumed := unchanged && progress.Stage == db.IngestStageChunked
+
+	if !resumed {
+		if !p.AllowDuplicates {
+			original, err := findCopy(ctx, p.mongo, tenant, sourceUri, checksum, groups)
+			if err != nil {
+				p.saveProgress(ctx, tenant, progress, err)
+				return report, err
+			}
+			if original != nil {
+				return p.skipDuplicate(ctx, tenant, name, progress, checksum, version, groups, original)
+			}
+		}
+
+		deid, err := NewDeidentifier(tenantConfig.Deidentification)
+		if err != nil {
+			// never ingest case notes the tenant wants de-identified as they are
+			p.saveProgress(ctx, tenant, progress, err)
+			return report, err
+		}
+		chunked, err := chunkDocument(ctx, name, sourceUri, data, convert, p.DocumentType, tenantConfig.Chunking, deid, onStage)
+		chunks, tables, figures, flagged := chunked.chunks, chunked.tables, chunked.figures, chunked.flagged
+		fingerprint := Fingerprint(chunks)
+		if err == nil && !p.AllowDuplicates {
+			var original *db.IngestProgressModel
+			original, err = findNearCopy(ctx, p.mongo, tenant, sourceUri, fingerprint, groups)
+			if original != nil {
+				return p.skipDuplicate(ctx, tenant, name, progress, checksum, version, groups, original)
+			}
+		}
+		for i := range chunks {
+			chunks[i].AccessGroups = groups
+		}
+		for i := range tables {
+			tables[i].AccessGroups = groups
+		}
+		for i := range figures {
+			figures[i].AccessGroups = groups
+		}
+		quarantined := 0
+		if err == nil {
+			chunks, quarantined, err = quarantine(ctx, p.mongo, tenant, sourceUri, chunks, tenantConfig.MinChunkQuality)
+		}
+		if err == nil {
+			err = p.routeEmbedder(ctx, tenant, name, chunks)
+		}
+		if err == nil && p.Storage != nil {
+			err = StoreFigureImages(ctx, p.Storage, tenant, figures)
+		}
+		if err == nil {
+			onStage(db.IngestionJobIndexing)
+			err = Publish(ctx, p.mongo, tenant, sourceUri, chunks)
+		}
+		if err == nil {
+			err = PublishTables(ctx, p.mongo, tenant, sourceUri, tables)
+		}
+		if err == nil {
+			err = PublishFigures(ctx, p.mongo, tenant, sourceUri, figures)
+		}
+		if err != nil {
+			p.saveProgress(ctx, tenant, progress, err)
+			return report, err
+		}
+
+		progress.Checksum = checksum
+		progress.Stage = db.IngestStageChunked
+		progress.Chunks = len(chunks)
+		progress.FlaggedPages = flagged
+		progress.Fingerprint, progress.FingerprintBands = "", nil
+		if fingerprint != 0 {
+			progress.Fingerprint, progress.FingerprintBands = formatFingerprint(fingerprint), fingerprintBands(fingerprint)
+		}
+		progress.DuplicateOf = ""
+		progress.AccessGroups = groups
+		p.saveProgress(ctx, tenant, progress, nil)
+		report.Chunks += len(chunks)
+		report.TableRows += len(tables)
+		report.Figures += len(figures)
+		report.Quarantined += quarantined
+		for _, n := range chunked.redacted {
+			report.Redacted += n
+		}
+		if len(chunked.redacted) > 0 {
+			logger.Info("Identifiers redacted", zap.String("document", name), zap.Any("kinds", chunked.redacted))
+		}
+		if quarantined > 0 {
+			logger.Info("Low-quality chunks quarantined", zap.String("document", name), zap.Int("chunks", quarantined))
+		}
+		if len(flagged) > 0 {
+			logger.Info("Scanned pages need review", zap.String("document", name), zap.Ints("pages", flagged))
+			report.Flagged = map[string][]int{sourceUri: flagged}
+		}
+	}
+
+	onStage(db.IngestionJobEmbedding)
+	embedded, err := EmbedMissing(ctx, p.mongo, p.spec, p.embedder, tenant, sourceUri, EmbedOptions{
+		BatchSize: p.BatchSize,
+		Workers:   p.Workers,
+		Progress: func(done, total int) {
+			logger.Info("Embedded chunks progress", zap.String("document", name), zap.Int("processed", done), zap.Int("total", total))
+			// chunks embedded on earlier runs are those that were not missing
+			progress.Embedded = max(0, progress.Chunks-total) + done
+			p.saveProgress(ctx, tenant, progress, nil)
+		},
+	})
+	report.Embedded += embedded
+	if err != nil {
+		p.saveProgress(ctx, tenant, progress, err)
+		return report, err
+	}
+
+	progress.Stage = db.IngestStageEmbedded
+	progress.Embedded = progress.Chunks
+	progress.ETag = version
+	p.saveProgress(ctx, tenant, progress, nil)
+	if resumed {
+		report.Resumed++
+	} else {
+		report.Ingested++
+	}
+	if p.Indexed != nil {
+		p.Indexed(ctx, sourceUri, report)
+	}
+	return report, nil
+}
+
+// skipDuplicate records the document as a copy of original instead of ingesting it.
+// Chunks, table rows and figures the source had of its own, from before it became a
+// copy, are retired.
+func (p *Pipeline) skipDuplicate(ctx context.Context, tenant, name string, progress *db.IngestProgressModel, checksum, version string, groups []string, original *db.IngestProgressModel) (Report, error) {
+	var report Report
+	if progress.Chunks > 0 && progress.DuplicateOf == "" {
+		err := Publish(ctx, p.mongo, tenant, progress.SourceURI, nil)
+		if err == nil {
+			err = PublishTables(ctx, p.mongo, tenant, progress.SourceURI, nil)
+		}
+		if err == nil {
+			err = PublishFigures(ctx, p.mongo, tenant, progress.SourceURI, nil)
+		}
+		if err == nil {
+			_, _, err = quarantine(ctx, p.mongo, tenant, progress.SourceURI, nil, 0)
+		}
+		if err != nil {
+			p.saveProgress(ctx, tenant, progress, err)
+			return report, err
+		}
+	}
+
+	progress.Checksum = checksum
+	progress.Stage = db.IngestStageEmbedded
+	progress.Chunks = 0
+	progress.Embedded = 0
+	progress.FlaggedPages = nil
+	progress.Fingerprint, progress.FingerprintBands = "", nil
+	progress.DuplicateOf = original.SourceURI
+	progress.AccessGroups = groups
+	progress.ETag = version
+	p.saveProgress(ctx, tenant, progress, nil)
+
+	logger.Info("Skipping duplicate document", zap.String("document", name), zap.String("duplicateOf", original.SourceURI))
+	report.Duplicates++
+	report.DuplicateOf = map[string]string{progress.SourceURI: original.SourceURI}
+	return report, nil
+}
+
+func (r *Report) add(other Report) {
+	r.Ingested += other.Ingested
+	r.Resumed += other.Resumed
+	r.Unchanged += other.Unchanged
+	r.Duplicates += other.Duplicates
+	r.Unsupported += other.Unsupported
+	r.Failed += other.Failed
+	r.Chunks += other.Chunks
+	r.TableRows += other.TableRows
+	r.Figures += other.Figures
+	r.Quarantined += other.Quarantined
+	r.Redacted += other.Redacted
+	r.Embedded += other.Embedded
+	for sourceUri, pages := range other.Flagged {
+		if r.Flagged == nil {
+			r.Flagged = map[string][]int{}
+		}
+		r.Flagged[sourceUri] = pages
+	}
+	for sourceUri, original := range other.DuplicateOf {
+		if r.DuplicateOf == nil {
+			r.DuplicateOf = map[string]string{}
+		}
+		r.DuplicateOf[sourceUri] = original
+	}
+}
+
+// chunkedDocument is a document as chunkDocument cut it.
+type chunkedDocument struct {
+	chunks   []db.ChunkModel
+	tables   []db.TableRowModel
+	figures  []Figure
+	flagged  []int          // scanned pages OCR was unsure of
+	redacted map[string]int // identifiers taken out, by kind
+}
+
+// chunkDocument converts and chunks a document, as documentType when set, and detects
+// each chunk's language and scores its quality. It also returns the rows of its
+// tables, its figures and the scanned pages flagged for review. Documents deid applies
+// to are de-identified before they are chunked; deid may be nil.
+func chunkDocument(ctx context.Context, name, sourceUri string, data []byte, convert Converter, documentType string, config db.ChunkingConfig, deid *Deidentifier, onStage func(string)) (chunkedDocument, error) {
+	onStage(db.IngestionJobParsing)
+	doc, err := convert(ctx, name, data)
+	if err != nil {
+		return chunkedDocument{}, err
+	}
+	if documentType != "" {
+		doc.Source.Type = documentType
+	}
+	var redacted map[string]int
+	if deid.Applies(DocumentType(doc.Source)) {
+		redacted = deid.Deidentify(doc)
+	}
+	onStage(db.IngestionJobChunking)
+	chunks, err := ChunkMarkdown(ctx, sourceUri, doc.Markdown(), config)
+	if err != nil {
+		return chunkedDocument{}, errors.New("failed to chunk document: " + err.Error())
+	}
+	DetectLanguages(chunks)
+	ScoreChunks(chunks)
+	return chunkedDocument{
+		chunks:   chunks,
+		tables:   TableRows(sourceUri, doc, chunks),
+		figures:  Figures(sourceUri, doc, chunks),
+		flagged:  doc.LowConfidencePages(),
+		redacted: redacted,
+	}, nil
+}
+
+// ingestedVersion reports whether the document was fully ingested at version, so it
+// need not be read again. A failure to tell is logged and reads it.
+func (p *Pipeline) ingestedVersion(ctx context.Context, tenant, sourceUri, version string) bool {
+	if version == "" || p.Force {
+		return false
+	}
+	progress, err := p.loadProgress(ctx, tenant, sourceUri)
+	if err != nil {
+		logger.Error("Failed to check document version", zap.String("sourceUri", sourceUri), zap.Error(err))
+		return false
+	}
+	return progress.ETag == version && progress.Stage == db.IngestStageEmbedded &&
+		slices.Equal(progress.AccessGroups, db.NormalizeAccessGroups(p.AccessGroups))
+}
+
+func (p *Pipeline) loadProgress(ctx context.Context, tenant, sourceUri string) (*db.IngestProgressModel, error) {
+	progress := db.NewIngestProgressModel(sourceUri)
+	repo := odm.CollectionOf[db.IngestProgressModel](p.mongo, tenant)
+
+	exists, err := async.Await(repo.Exists(ctx, progress.Id()))
+	if err != nil {
+		return nil, errors.New("failed to load ingestion progress: " + err.Error())
+	}
+	if !exists {
+		return progress, nil
+	}
+	return async.Await(repo.FindOneByID(ctx, progress.Id()))
+}
+
+// saveProgress records the document's stage, and the error its run stopped at. Failing
+// to record it only costs repeated work on the next run, so it is logged, not returned.
+func (p *Pipeline) saveProgress(ctx context.Context, tenant string, progress *db.IngestProgressModel, runErr error) {
+	progress.Error = ""
+	if runErr != nil {
+		progress.Error = runErr.Error()
+	}
+	progress.UpdatedOn = time.Now().Unix()
+
+	if _, err := async.Await(odm.CollectionOf[db.IngestProgressModel](p.mongo, tenant).Save(ctx, *progress)); err != nil {
+		logger.Error("Failed to save ingestion progress", zap.String("sourceUri", progress.SourceURI), zap.Error(err))
+	}
+}
@@ -0,0 +1,88 @@
+package ingest
+
+import (
+	"context"
+	"maps"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+)
+
+// PreviewOptions try chunking settings out on a document before they are saved in the
+// tenant's config. Anything left empty keeps the config's.
+type PreviewOptions struct {
+	DocumentType  string // chunk the document as this type instead of detecting it
+	Strategy      string // for the document, whatever its type
+	MaxTokens     int
+	OverlapTokens int // negative is none
+
+	MinQuality float64 // the tenant's minChunkQuality, to count the chunks it quarantines
+
+	Deidentification db.DeidentificationConfig // the tenant's, so the preview shows chunks as ingested
+}
+
+// Preview is a document as ingesting it would chunk it.
+type Preview struct {
+	Params    db.ChunkingParams // the type the document was chunked as, and how
+	Chunks    []db.ChunkModel   // every chunk, in document order
+	TableRows int
+	Figures   int
+	Flagged   []int          // scanned pages OCR was unsure of
+	Redacted  map[string]int // identifiers taken out, by kind; see Deidentifier
+
+	// Chunks scoring below MinQuality are quarantined at ingest; see ScoreChunks.
+	MinQuality  float64
+	Quarantined int
+
+	// Estimated tokens of each chunk's embedding text, and of them all.
+	Tokens      []int
+	TotalTokens int
+}
+
+// PreviewDocument converts and chunks a document as the pipeline would with config
+// and options, but embeds and saves nothing, so chunking can be checked before paying
+// for embeddings.
+func PreviewDocument(ctx context.Context, name, sourceUri string, data []byte, config db.ChunkingConfig, options PreviewOptions) (*Preview, error) {
+	convert, ok := DefaultConverters()[Extension(name)]
+	if !ok {
+		return nil, ErrUnsupportedDocument
+	}
+
+	if options.Strategy != "" {
+		config.Strategies = maps.Clone(config.Strategies)
+		if config.Strategies == nil {
+			config.Strategies = map[string]string{}
+		}
+		for documentType := range defaultStrategies {
+			config.Strategies[documentType] = options.Strategy
+		}
+	}
+	if options.MaxTokens != 0 {
+		config.MaxTokens = options.MaxTokens
+	}
+	if options.OverlapTokens != 0 {
+		config.OverlapTokens = options.OverlapTokens
+	}
+
+	deid, err := NewDeidentifier(options.Deidentification)
+	if err != nil {
+		return nil, err
+	}
+	chunked, err := chunkDocument(ctx, name, sourceUri, data, convert, options.DocumentType, config, deid, func(string) {})
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &Preview{Chunks: chunked.chunks, TableRows: len(chunked.tables), Figures: len(chunked.figures), Flagged: chunked.flagged, Redacted: chunked.redacted}
+	preview.MinQuality = MinChunkQuality(options.MinQuality)
+	_, quarantined := Quarantine(preview.Chunks, preview.MinQuality)
+	preview.Quarantined = len(quarantined)
+	if len(preview.Chunks) > 0 && preview.Chunks[0].Chunking != nil {
+		preview.Params = *preview.Chunks[0].Chunking
+	}
+	for _, chunk := range preview.Chunks {
+		tokens := estimateTokens(EmbeddingText(chunk))
+		preview.Tokens = append(preview.Tokens, tokens)
+		preview.TotalTokens += tokens
+	}
+	return preview, nil
+}
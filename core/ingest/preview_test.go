@@ -0,0 +1,45 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewDocument(t *testing.T) {
+	md := []byte("# Aconite\n\nSudden onset after exposure to cold wind.\n\n# Belladonna\n\nHeat, redness and throbbing.\n")
+	config := db.ChunkingConfig{Strategies: map[string]string{DocumentNarrative: ChunkSemantic}, MaxTokens: 400}
+
+	preview, err := PreviewDocument(t.Context(), "remedies.md", "uploads/remedies.md", md, config, PreviewOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, db.ChunkingParams{DocumentType: DocumentNarrative, Strategy: ChunkSemantic, MaxTokens: 400, OverlapTokens: 100}, preview.Params)
+	require.NotEmpty(t, preview.Chunks)
+	assert.Equal(t, "uploads/remedies.md", preview.Chunks[0].SourceURI)
+	assert.Len(t, preview.Tokens, len(preview.Chunks))
+	assert.Positive(t, preview.TotalTokens)
+	assert.Equal(t, DefaultMinChunkQuality, preview.MinQuality)
+	assert.Zero(t, preview.Quarantined)
+
+	preview, err = PreviewDocument(t.Context(), "remedies.md", "uploads/remedies.md", md, config,
+		PreviewOptions{DocumentType: DocumentRepertory, Strategy: ChunkFixed, OverlapTokens: -1})
+	require.NoError(t, err)
+	assert.Equal(t, db.ChunkingParams{DocumentType: DocumentRepertory, Strategy: ChunkFixed, MaxTokens: 400}, preview.Params)
+	assert.Equal(t, ChunkSemantic, config.Strategies[DocumentNarrative], "the tenant's config is left alone")
+
+	notes := []byte("# Case 3\n\nPatient: Mary Smith, 45 years, came with a throbbing headache. Mobile 98450 12345.\n")
+	preview, err = PreviewDocument(t.Context(), "case.md", "", notes, config, PreviewOptions{DocumentType: DocumentCaseJournal})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{IdentifierName: 1, IdentifierPhone: 1}, preview.Redacted)
+	require.NotEmpty(t, preview.Chunks)
+	assert.NotContains(t, EmbeddingText(preview.Chunks[0]), "Mary")
+	_, err = PreviewDocument(t.Context(), "case.md", "", notes, config,
+		PreviewOptions{Deidentification: db.DeidentificationConfig{Documents: "most"}})
+	assert.Error(t, err)
+
+	_, err = PreviewDocument(t.Context(), "remedies.md", "", md, config, PreviewOptions{Strategy: "random"})
+	assert.Error(t, err)
+	_, err = PreviewDocument(t.Context(), "remedies.txt", "", md, config, PreviewOptions{})
+	assert.ErrorIs(t, err, ErrUnsupportedDocument)
+}
@@ -0,0 +1,164 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/go-collection-boot/ds"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/references"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// Publish saves the chunks of one source document as a new corpus version.
+// Chunks already live for the source keep the version that first added them; live
+// chunks of the source that are not in this batch are retired at the new version.
+// Retired chunks the batch adds back keep their first version too, and record the span
+// they were retired for as a lapse. The vectors of retired chunks are flagged so vector
+// search leaves them out.
+// Each chunk is saved with the links of its section to the sections of the source it
+// refers to or shares a chapter with; see package references.
+func Publish(ctx context.Context, mongo odm.MongoClient, tenant, sourceUri string, chunks []db.ChunkModel) error {
+	chunkRepo := odm.CollectionOf[db.ChunkModel](mongo, tenant)
+
+	existing, err := async.Await(chunkRepo.Find(ctx, bson.M{"sourceUri": sourceUri}, nil, 0, 0))
+	if err != nil {
+		return errors.New("failed to load existing chunks: " + err.Error())
+	}
+
+	liveChunks := make(map[string]db.ChunkModel, len(existing))
+	retiredChunks := make(map[string]db.ChunkModel)
+	for _, chunk := range existing {
+		if chunk.RetiredVersion > 0 {
+			retiredChunks[chunk.ChunkID] = chunk
+		} else {
+			liveChunks[chunk.ChunkID] = chunk
+		}
+	}
+
+	version, err := db.NextCorpusVersion(ctx, mongo, tenant)
+	if err != nil {
+		return errors.New("failed to allocate corpus version: " + err.Error())
+	}
+
+	// every window of a section carries the section's links
+	links := references.Link(sourceSections(chunks))
+
+	saved := ds.NewSet[string]()
+	var restored []string
+	added := 0
+	for _, chunkModel := range chunks {
+		if liveChunk, ok := liveChunks[chunkModel.ChunkID]; ok {
+			chunkModel.CorpusVersion = liveChunk.CorpusVersion
+			chunkModel.Lapses = liveChunk.Lapses
+		} else if retiredChunk, ok := retiredChunks[chunkModel.ChunkID]; ok {
+			chunkModel.CorpusVersion = retiredChunk.CorpusVersion
+			chunkModel.Lapses = append(retiredChunk.Lapses, db.ChunkLapse{RetiredVersion: retiredChunk.RetiredVersion, RestoredVersion: version})
+			restored = append(restored, chunkModel.ChunkID)
+			added++
+		} else {
+			chunkModel.CorpusVersion = version
+			added++
+		}
+		chunkModel.RetiredVersion = 0
+		chunkModel.Links = links[chunkModel.SectionID]
+
+		_, err = async.Await(chunkRepo.Save(ctx, chunkModel))
+		if err != nil {
+			return errors.New("failed to save chunk to database: " + err.Error())
+		}
+		saved.Add(chunkModel.ChunkID)
+	}
+
+	var retired []string
+	for chunkId := range liveChunks {
+		if !saved.Contains(chunkId) {
+			retired = append(retired, chunkId)
+		}
+	}
+
+	vectors := mongo.Database(tenant).Collection(db.ChunkAnnModel{}.CollectionName())
+	if len(retired) > 0 {
+		_, err = mongo.Database(tenant).Collection(db.ChunkModel{}.CollectionName()).UpdateMany(ctx,
+			bson.M{"_id": bson.M{"$in": retired}},
+			bson.M{"$set": bson.M{"retiredVersion": version}},
+		)
+		if err != nil {
+			return errors.New("failed to retire replaced chunks: " + err.Error())
+		}
+		_, err = vectors.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": retired}}, bson.M{"$set": bson.M{"retired": true}})
+		if err != nil {
+			return errors.New("failed to flag retired vectors: " + err.Error())
+		}
+	}
+	if len(restored) > 0 {
+		_, err = vectors.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": restored}}, bson.M{"$unset": bson.M{"retired": ""}})
+		if err != nil {
+			return errors.New("failed to unflag restored vectors: " + err.Error())
+		}
+	}
+
+	corpusVersion := db.NewCorpusVersionModel(version)
+	corpusVersion.SourceURI = sourceUri
+	corpusVersion.AddedChunks = added
+	corpusVersion.RetiredChunks = len(retired)
+	corpusVersion.CreatedOn = time.Now().Unix()
+
+	_, err = async.Await(odm.CollectionOf[db.CorpusVersionModel](mongo, tenant).Save(ctx, *corpusVersion))
+	if err != nil {
+		return errors.New("failed to record corpus version: " + err.Error())
+	}
+
+	if err := db.InvalidateAnswerCache(ctx, mongo, tenant); err != nil {
+		// Entries are keyed by corpus version as well, so stale ones are never served.
+		logger.Error("Failed to invalidate answer cache", zap.String("tenant", tenant), zap.Error(err))
+	}
+
+	logger.Info("Corpus version published",
+		zap.String("tenant", tenant), zap.String("sourceUri", sourceUri), zap.Int64("version", version),
+		zap.Int("added", added), zap.Int("retired", len(retired)))
+	return nil
+}
+
+// sourceSections joins the windows of each section of a source back into the section,
+// in window order. Sentences windows overlap by are kept twice, which only matters to
+// link finding as repeated text.
+func sourceSections(chunks []db.ChunkModel) []references.Section {
+	windows := make(map[string][]db.ChunkModel)
+	var order []string
+	for _, chunk := range chunks {
+		if _, ok := windows[chunk.SectionID]; !ok {
+			order = append(order, chunk.SectionID)
+		}
+		windows[chunk.SectionID] = append(windows[chunk.SectionID], chunk)
+	}
+
+	sections := make([]references.Section, 0, len(order))
+	for _, id := range order {
+		sectionWindows := windows[id]
+		sort.Slice(sectionWindows, func(i, j int) bool { return sectionWindows[i].WindowIndex < sectionWindows[j].WindowIndex })
+
+		var text strings.Builder
+		for _, window := range sectionWindows {
+			for _, sentence := range window.Sentences {
+				text.WriteString(sentence)
+				text.WriteByte('\n')
+			}
+		}
+		first := sectionWindows[0]
+		sections = append(sections, references.Section{
+			ID:    id,
+			Index: first.SectionIndex,
+			Path:  strings.Split(first.SectionPath, " | "),
+			Text:  text.String(),
+		})
+	}
+	return sections
+}
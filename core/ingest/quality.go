@@ -0,0 +1,204 @@
+package ingest
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+)
+
+// Issues that lower a chunk's quality; see ScoreChunks.
+const (
+	IssueOCRGarbage  = "ocr_garbage" // few real words, as OCR reads a bad scan
+	IssueTooShort    = "too_short"   // too few words to answer anything
+	IssueBoilerplate = "boilerplate" // running heads, copyright notices, contents leaders
+	IssueEncoding    = "encoding"    // replacement or control characters, or mojibake
+)
+
+// DefaultMinChunkQuality is the quality chunks are quarantined below when the tenant
+// sets none.
+const DefaultMinChunkQuality = 0.5
+
+const (
+	minChunkWords = 5
+	// Sentences this short repeated in this many sections are running heads or footers.
+	maxRunningHeadWords   = 12
+	minRunningHeadRepeats = 3
+)
+
+var (
+	boilerplatePattern = regexp.MustCompile(`(?i)all rights reserved|^\s*(copyright|©)|\bpage \d+ of \d+\b|intentionally left blank|\bisbn[\s:-]*[\dx-]{10,}|\.{5,}|(\. ){4,}|\bprinted in\b`)
+	digits             = regexp.MustCompile(`\d+`)
+	// UTF-8 read as Latin-1 or Windows-1252, such as "Ã©" for "é" or "â€™" for "’"
+	mojibakePattern = regexp.MustCompile(`[ÃÂ][\x{80}-\x{BF}]|â€`)
+)
+
+// ScoreChunks sets each chunk's Quality, from 1 for clean text down to 0, and the
+// QualityIssues that lowered it:
+//   - encoding: replacement characters, control characters or mojibake.
+//   - ocr_garbage: words that are mostly symbols, or long Latin words without a vowel.
+//   - too_short: fewer than 5 words, and the fewer the lower. Repertory rubrics are
+//     short by nature and exempt.
+//   - boilerplate: most of the text is copyright notices, page numbers, contents
+//     leaders, or short sentences repeated in 3 or more of the document's sections,
+//     such as running heads. Repeats don't count in repertories.
+func ScoreChunks(chunks []db.ChunkModel) {
+	repeated := runningHeads(chunks)
+	for i := range chunks {
+		chunks[i].Quality, chunks[i].QualityIssues = scoreChunk(chunks[i], repeated)
+	}
+}
+
+// Quarantine splits chunks into those at least minQuality, which are published, and
+// those below it. A negative minQuality keeps every chunk; zero uses
+// DefaultMinChunkQuality.
+func Quarantine(chunks []db.ChunkModel, minQuality float64) (kept, quarantined []db.ChunkModel) {
+	minQuality = MinChunkQuality(minQuality)
+	for _, chunk := range chunks {
+		if chunk.Quality < minQuality {
+			quarantined = append(quarantined, chunk)
+		} else {
+			kept = append(kept, chunk)
+		}
+	}
+	return kept, quarantined
+}
+
+// MinChunkQuality is the quality chunks are quarantined below for a tenant's
+// minChunkQuality setting.
+func MinChunkQuality(setting float64) float64 {
+	if setting == 0 {
+		return DefaultMinChunkQuality
+	}
+	return setting
+}
+
+func scoreChunk(chunk db.ChunkModel, repeated map[string]bool) (float64, []string) {
+	text := strings.Join(chunk.Sentences, " ")
+	score := 1.0
+	var issues []string
+	lower := func(issue string, factor float64) {
+		score *= max(0, factor)
+		issues = append(issues, issue)
+	}
+
+	if bad, total := badRunes(text); bad > 0 {
+		lower(IssueEncoding, 1-25*float64(bad)/float64(total))
+	}
+
+	if share := garbageWordShare(text); share > 0.2 {
+		lower(IssueOCRGarbage, (1-share)*(1-share))
+	}
+
+	words := len(strings.Fields(text))
+	if words < minChunkWords && (chunk.Chunking == nil || chunk.Chunking.DocumentType != DocumentRepertory) {
+		// one or two words are quarantined; three or four only flagged
+		lower(IssueTooShort, 0.15*float64(words+1))
+	}
+
+	boilerplate := 0
+	for _, sentence := range chunk.Sentences {
+		if boilerplatePattern.MatchString(sentence) || repeated[runningHeadKey(sentence)] {
+			boilerplate += len(strings.Fields(sentence))
+		}
+	}
+	if words > 0 && float64(boilerplate)/float64(words) > 0.5 {
+		lower(IssueBoilerplate, 1-float64(boilerplate)/float64(words))
+	}
+
+	return math.Round(score*100) / 100, issues
+}
+
+// badRunes counts the runes of text that are replacement or control characters, or
+// part of mojibake, and all its runes but spaces.
+func badRunes(text string) (bad, total int) {
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		total++
+		if r == unicode.ReplacementChar || unicode.IsControl(r) {
+			bad++
+		}
+	}
+	bad += 2 * len(mojibakePattern.FindAllStringIndex(text, -1))
+	return min(bad, total), total
+}
+
+// garbageWordShare is the share of text's words, leaving out numbers, that OCR seems to
+// have made up: mostly symbols, or Latin words of 4 letters or more without a vowel.
+// Text without a word at all is all garbage.
+func garbageWordShare(text string) float64 {
+	words, garbage := 0, 0
+	for _, field := range strings.Fields(text) {
+		word := strings.TrimFunc(field, func(r rune) bool { return unicode.IsPunct(r) || unicode.IsSymbol(r) })
+		letters, others := 0, 0
+		for _, r := range word {
+			switch {
+			case unicode.IsLetter(r) || unicode.IsMark(r):
+				letters++
+			case !unicode.IsDigit(r) && r != '-' && r != '\'' && r != '.':
+				others++
+			}
+		}
+		if letters == 0 && others == 0 && word != "" {
+			continue // a number
+		}
+		words++
+		if letters == 0 || others*2 > letters || (letters >= 4 && isLatin(word) && !strings.ContainsAny(strings.ToLower(word), "aeiouy")) {
+			garbage++
+		}
+	}
+	if words == 0 {
+		return 1
+	}
+	return float64(garbage) / float64(words)
+}
+
+func isLatin(word string) bool {
+	for _, r := range word {
+		if unicode.IsLetter(r) && r > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+// runningHeads returns the keys of short sentences found in at least 3 sections of
+// the chunks, outside repertories.
+func runningHeads(chunks []db.ChunkModel) map[string]bool {
+	sections := map[string]map[string]bool{}
+	for _, chunk := range chunks {
+		if chunk.Chunking != nil && chunk.Chunking.DocumentType == DocumentRepertory {
+			continue // rubrics repeat the same remedies
+		}
+		for _, sentence := range chunk.Sentences {
+			if len(strings.Fields(sentence)) > maxRunningHeadWords {
+				continue
+			}
+			key := runningHeadKey(sentence)
+			if key == "" {
+				continue
+			}
+			if sections[key] == nil {
+				sections[key] = map[string]bool{}
+			}
+			sections[key][chunk.SectionID] = true
+		}
+	}
+	repeated := map[string]bool{}
+	for key, in := range sections {
+		if len(in) >= minRunningHeadRepeats {
+			repeated[key] = true
+		}
+	}
+	return repeated
+}
+
+// runningHeadKey is the sentence lowercased without its numbers, so the same running
+// head on pages 12 and 13 is one.
+func runningHeadKey(sentence string) string {
+	return strings.Join(strings.Fields(strings.ToLower(digits.ReplaceAllString(sentence, ""))), " ")
+}
@@ -0,0 +1,54 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScoreChunks(t *testing.T) {
+	repertory := &db.ChunkingParams{DocumentType: DocumentRepertory}
+	chunks := []db.ChunkModel{
+		{SectionID: "a", Sentences: []string{"Aconite suits complaints that come on suddenly after exposure to a dry cold wind.", "Boericke's Materia Medica 12"}},
+		{SectionID: "b", Sentences: []string{"Tbrkl ~~## w1th rn@ %%!! qzxv Aconite &&* vvrp tr3@t."}},
+		{SectionID: "c", Sentences: []string{"Heat, redness and throbbing."}},
+		{SectionID: "d", Sentences: []string{"Contents"}},
+		{SectionID: "e", Sentences: []string{"HEAD - Pain, morning: Acon."}, Chunking: repertory},
+		{SectionID: "f", Sentences: []string{"Copyright 1901. All rights reserved.", "Printed in the United States of America by the publishers."}},
+		{SectionID: "g", Sentences: []string{"Boericke's Materia Medica 13", "Thuja."}},
+		{SectionID: "h", Sentences: []string{"Boericke's Materia Medica 14", "Belladonna acts on every part of the nervous system."}},
+		{SectionID: "i", Sentences: []string{"Ã©vidence que le remÃ¨de agit sur la tÃªte et le cÅ“ur ��."}},
+	}
+	ScoreChunks(chunks)
+
+	assert.Equal(t, 1.0, chunks[0].Quality, "one running head among good text")
+	assert.Empty(t, chunks[0].QualityIssues)
+	assert.Contains(t, chunks[1].QualityIssues, IssueOCRGarbage)
+	assert.Less(t, chunks[1].Quality, DefaultMinChunkQuality)
+	assert.Equal(t, []string{IssueTooShort}, chunks[2].QualityIssues)
+	assert.GreaterOrEqual(t, chunks[2].Quality, DefaultMinChunkQuality, "short but meaningful")
+	assert.Less(t, chunks[3].Quality, DefaultMinChunkQuality, "a single word")
+	assert.Equal(t, 1.0, chunks[4].Quality, "rubrics are short")
+	assert.Equal(t, []string{IssueBoilerplate}, chunks[5].QualityIssues)
+	assert.Less(t, chunks[5].Quality, DefaultMinChunkQuality)
+	assert.Contains(t, chunks[6].QualityIssues, IssueBoilerplate, "mostly a running head")
+	assert.Empty(t, chunks[7].QualityIssues)
+	assert.Contains(t, chunks[8].QualityIssues, IssueEncoding)
+	assert.Less(t, chunks[8].Quality, DefaultMinChunkQuality)
+}
+
+func TestQuarantine(t *testing.T) {
+	chunks := []db.ChunkModel{{ChunkID: "a", Quality: 1}, {ChunkID: "b", Quality: 0.3}, {ChunkID: "c", Quality: 0.6}}
+
+	kept, quarantined := Quarantine(chunks, 0)
+	assert.Equal(t, []db.ChunkModel{chunks[0], chunks[2]}, kept)
+	assert.Equal(t, []db.ChunkModel{chunks[1]}, quarantined)
+
+	_, quarantined = Quarantine(chunks, 0.7)
+	assert.Len(t, quarantined, 2)
+
+	kept, quarantined = Quarantine(chunks, -1)
+	assert.Equal(t, chunks, kept)
+	assert.Empty(t, quarantined)
+}
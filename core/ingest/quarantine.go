@@ -0,0 +1,156 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// ErrQuarantinedChunkNotFound is returned for reviewing a chunk that is not quarantined.
+var ErrQuarantinedChunkNotFound = errors.New("quarantined chunk not found")
+
+// quarantine takes the chunks of a source scoring below minQuality out of those to
+// publish, and saves them in the tenant's quarantined_chunks collection for review. A
+// chunk already approved is published whatever its quality, and one rejected is kept
+// out without being queued again. Reviews of chunks the source no longer has, and
+// pending chunks that now score high enough, are dropped.
+func quarantine(ctx context.Context, mongo odm.MongoClient, tenant, sourceUri string, chunks []db.ChunkModel, minQuality float64) (kept []db.ChunkModel, queued int, err error) {
+	repo := odm.CollectionOf[db.QuarantinedChunkModel](mongo, tenant)
+	reviews, err := async.Await(repo.Find(ctx, bson.M{"sourceUri": sourceUri}, nil, 0, 0))
+	if err != nil {
+		return nil, 0, errors.New("failed to load quarantined chunks: " + err.Error())
+	}
+	reviewed := make(map[string]db.QuarantinedChunkModel, len(reviews))
+	for _, review := range reviews {
+		reviewed[review.ChunkID] = review
+	}
+
+	passed, failed := Quarantine(chunks, minQuality)
+	for _, chunk := range failed {
+		review, ok := reviewed[chunk.ChunkID]
+		switch {
+		case ok && review.Status == db.QuarantineApproved:
+			passed = append(passed, chunk)
+		case ok && review.Status == db.QuarantineRejected:
+		default:
+			if !ok {
+				review = db.QuarantinedChunkModel{ChunkID: chunk.ChunkID, SourceURI: sourceUri, Status: db.QuarantinePending, CreatedOn: time.Now().Unix()}
+			}
+			review.Chunk = chunk
+			if _, err := async.Await(repo.Save(ctx, review)); err != nil {
+				return nil, 0, errors.New("failed to quarantine chunk: " + err.Error())
+			}
+			queued++
+		}
+	}
+
+	chunkIds := make([]string, 0, len(chunks))
+	order := make(map[string]int, len(chunks))
+	for i, chunk := range chunks {
+		chunkIds = append(chunkIds, chunk.ChunkID)
+		order[chunk.ChunkID] = i
+	}
+	failedIds := make([]string, 0, len(failed))
+	for _, chunk := range failed {
+		failedIds = append(failedIds, chunk.ChunkID)
+	}
+	_, err = mongo.Database(tenant).Collection(db.QuarantinedChunkModel{}.CollectionName()).DeleteMany(ctx, bson.M{
+		"sourceUri": sourceUri,
+		"$or": bson.A{
+			bson.M{"_id": bson.M{"$nin": chunkIds}},
+			bson.M{"status": db.QuarantinePending, "_id": bson.M{"$nin": failedIds}},
+		},
+	})
+	if err != nil {
+		return nil, 0, errors.New("failed to drop stale quarantined chunks: " + err.Error())
+	}
+
+	// published in document order, as the chunks chain
+	slices.SortStableFunc(passed, func(a, b db.ChunkModel) int {
+		return order[a.ChunkID] - order[b.ChunkID]
+	})
+	return passed, queued, nil
+}
+
+// ReviewQuarantined approves or rejects a quarantined chunk for reviewer. An approved
+// chunk is published as a new corpus version of its source and embedded; rejecting a
+// chunk approved before retires it again. Either way the review holds when the source
+// is ingested again, while the chunk's section is unchanged.
+func (p *Pipeline) ReviewQuarantined(ctx context.Context, tenant, chunkId string, approve bool, reviewer string) (*db.QuarantinedChunkModel, error) {
+	repo := odm.CollectionOf[db.QuarantinedChunkModel](p.mongo, tenant)
+	exists, err := async.Await(repo.Exists(ctx, chunkId))
+	if err != nil {
+		return nil, errors.New("failed to load quarantined chunk: " + err.Error())
+	}
+	if !exists {
+		return nil, ErrQuarantinedChunkNotFound
+	}
+	review, err := async.Await(repo.FindOneByID(ctx, chunkId))
+	if err != nil {
+		return nil, errors.New("failed to load quarantined chunk: " + err.Error())
+	}
+
+	status := db.QuarantineRejected
+	if approve {
+		status = db.QuarantineApproved
+	}
+	if review.Status != status && (approve || review.Status == db.QuarantineApproved) {
+		if err := p.republish(ctx, tenant, review.Chunk, approve); err != nil {
+			return nil, err
+		}
+	}
+
+	review.Status = status
+	review.ReviewedBy = reviewer
+	review.ReviewedOn = time.Now().Unix()
+	if _, err := async.Await(repo.Save(ctx, *review)); err != nil {
+		return nil, errors.New("failed to save review: " + err.Error())
+	}
+	logger.Info("Quarantined chunk reviewed", zap.String("tenant", tenant), zap.String("chunkId", chunkId),
+		zap.String("sourceUri", review.SourceURI), zap.String("status", status), zap.String("reviewer", reviewer))
+	return review, nil
+}
+
+// republish publishes the source's live chunks with chunk added, and embeds it, or with
+// it taken out.
+func (p *Pipeline) republish(ctx context.Context, tenant string, chunk db.ChunkModel, add bool) error {
+	live, err := async.Await(odm.CollectionOf[db.ChunkModel](p.mongo, tenant).Find(ctx, bson.M{"$and": bson.A{
+		bson.M{"sourceUri": chunk.SourceURI},
+		db.LiveChunksFilter(),
+	}}, nil, 0, 0))
+	if err != nil {
+		return errors.New("failed to load live chunks: " + err.Error())
+	}
+	live = slices.DeleteFunc(live, func(c db.ChunkModel) bool { return c.ChunkID == chunk.ChunkID })
+	if add {
+		live = append(live, chunk)
+	}
+	if err := Publish(ctx, p.mongo, tenant, chunk.SourceURI, live); err != nil {
+		return err
+	}
+
+	progress, err := p.loadProgress(ctx, tenant, chunk.SourceURI)
+	if err != nil {
+		return err
+	}
+	progress.Chunks = len(live)
+	if add {
+		if _, err := EmbedMissing(ctx, p.mongo, p.spec, p.embedder, tenant, chunk.SourceURI, EmbedOptions{BatchSize: p.BatchSize, Workers: p.Workers}); err != nil {
+			p.saveProgress(ctx, tenant, progress, err)
+			return err
+		}
+	}
+	if progress.Stage == db.IngestStageEmbedded {
+		progress.Embedded = progress.Chunks
+	}
+	p.saveProgress(ctx, tenant, progress, nil)
+	return nil
+}
@@ -0,0 +1,82 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3Source reads the objects under a prefix of an S3 bucket, with the default AWS
+// credential chain: environment variables, shared config files or the instance role.
+// endpoint, if set, points it at an S3-compatible store such as MinIO instead.
+type S3Source struct {
+	client *s3.Client
+	bucket string
+	prefix string
+	etags  map[string]string // as of the last List
+}
+
+func NewS3Source(ctx context.Context, region, endpoint, bucket, prefix string) (*S3Source, error) {
+	if bucket == "" {
+		return nil, errors.New("bucket is required")
+	}
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, errors.New("failed to load AWS config: " + err.Error())
+	}
+	if region != "" {
+		cfg.Region = region
+	}
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return &S3Source{client: client, bucket: bucket, prefix: prefix}, nil
+}
+
+func (s *S3Source) List(ctx context.Context) ([]string, error) {
+	var names []string
+	etags := map[string]string{}
+	pager := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{Bucket: &s.bucket, Prefix: &s.prefix})
+	for pager.HasMorePages() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, errors.New("failed to list objects: " + err.Error())
+		}
+		for _, object := range page.Contents {
+			if object.Key == nil || strings.HasSuffix(*object.Key, "/") {
+				continue
+			}
+			names = append(names, *object.Key)
+			etags[*object.Key] = aws.ToString(object.ETag)
+		}
+	}
+	sort.Strings(names)
+	s.etags = etags
+	return names, nil
+}
+
+func (s *S3Source) Read(ctx context.Context, name string) ([]byte, error) {
+	resp, err := s.client.GetObject(ctx, &s3.GetObjectInput{Bucket: &s.bucket, Key: &name})
+	if err != nil {
+		return nil, errors.New("failed to download object: " + err.Error())
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func (s *S3Source) URI(name string) string {
+	return "s3://" + s.bucket + "/" + name
+}
+
+func (s *S3Source) Version(name string) string {
+	return s.etags[name]
+}
@@ -0,0 +1,56 @@
+package ingest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestS3Source(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/library" && r.URL.Query().Get("list-type") == "2":
+			assert.Equal(t, "materia-medica/", r.URL.Query().Get("prefix"))
+			w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<ListBucketResult><Name>library</Name><IsTruncated>false</IsTruncated>
+<Contents><Key>materia-medica/kent.md</Key><ETag>"e2"</ETag></Contents>
+<Contents><Key>materia-medica/</Key><ETag>"d"</ETag></Contents>
+<Contents><Key>materia-medica/boericke.md</Key><ETag>"e1"</ETag></Contents>
+</ListBucketResult>`))
+		case r.URL.Path == "/library/materia-medica/kent.md":
+			w.Write([]byte("# Aconite"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+
+	source, err := OpenSource(t.Context(), db.SourceConfig{Kind: db.SourceS3, Bucket: "library", Prefix: "materia-medica/",
+		Region: "us-east-1", Endpoint: server.URL}, "")
+	require.NoError(t, err)
+
+	names, err := source.List(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"materia-medica/boericke.md", "materia-medica/kent.md"}, names, "sorted, without folder markers")
+
+	versioned, ok := source.(VersionedSource)
+	require.True(t, ok)
+	assert.Equal(t, `"e2"`, versioned.Version("materia-medica/kent.md"))
+	assert.Equal(t, "s3://library/materia-medica/kent.md", source.URI("materia-medica/kent.md"))
+
+	data, err := source.Read(t.Context(), "materia-medica/kent.md")
+	require.NoError(t, err)
+	assert.Equal(t, "# Aconite", string(data))
+
+	_, err = OpenSource(t.Context(), db.SourceConfig{Kind: "ftp"}, "")
+	assert.ErrorContains(t, err, "unknown source kind")
+}
@@ -0,0 +1,172 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+)
+
+// Source is a collection of documents to ingest, such as a directory or an object
+// store prefix.
+type Source interface {
+	// List returns the names of the source's documents, sorted.
+	List(ctx context.Context) ([]string, error)
+	Read(ctx context.Context, name string) ([]byte, error)
+	// URI is the sourceUri a document's chunks are saved with. It must stay the same
+	// across runs, so a re-ingested document replaces its earlier chunks.
+	URI(name string) string
+}
+
+// VersionedSource is a Source that knows each document's version without reading it,
+// such as an object store's ETag. Run skips documents whose version was fully ingested
+// before without downloading them.
+type VersionedSource interface {
+	Source
+	// Version is the document's version as of the last List; empty when unknown.
+	Version(name string) string
+}
+
+// OpenSource opens a tenant's configured source. Azure sources without an account use
+// azureAccount, the deployment's storage account.
+func OpenSource(ctx context.Context, config db.SourceConfig, azureAccount string) (Source, error) {
+	switch config.Kind {
+	case db.SourceS3:
+		return NewS3Source(ctx, config.Region, config.Endpoint, config.Bucket, config.Prefix)
+	case db.SourceGCS:
+		return NewGCSSource(ctx, config.Bucket, config.Prefix)
+	case db.SourceAzure:
+		account := config.Account
+		if account == "" {
+			account = azureAccount
+		}
+		return NewBlobSource(account, config.Bucket, config.Prefix)
+	case db.SourceDir:
+		return NewDirSource(config.Path)
+	case db.SourceWeb:
+		return NewWebSource(config.URLs, config.MaxPages)
+	}
+	return nil, errors.New("unknown source kind: " + config.Kind)
+}
+
+// DirSource reads the documents in a local directory and its subdirectories.
+type DirSource struct {
+	root string
+}
+
+func NewDirSource(root string) (*DirSource, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+	return &DirSource{root: abs}, nil
+}
+
+func (s *DirSource) List(ctx context.Context) ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(s.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return ctx.Err()
+	})
+	sort.Strings(names)
+	return names, err
+}
+
+func (s *DirSource) Read(_ context.Context, name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.root, filepath.FromSlash(name)))
+}
+
+func (s *DirSource) URI(name string) string {
+	return "file://" + filepath.ToSlash(filepath.Join(s.root, filepath.FromSlash(name)))
+}
+
+// BlobSource reads the blobs under a prefix of an Azure Blob Storage container, with
+// the default Azure credential, as the rest of the deployment does.
+type BlobSource struct {
+	client    *azblob.Client
+	account   string
+	container string
+	prefix    string
+	etags     map[string]string // as of the last List
+}
+
+func NewBlobSource(account, container, prefix string) (*BlobSource, error) {
+	if account == "" || container == "" {
+		return nil, errors.New("storage account and container are required")
+	}
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, errors.New("failed to get Azure credential: " + err.Error())
+	}
+	client, err := azblob.NewClient(fmt.Sprintf("https://%s.blob.core.windows.net/", account), cred, nil)
+	if err != nil {
+		return nil, errors.New("failed to create blob client: " + err.Error())
+	}
+	return &BlobSource{client: client, account: account, container: container, prefix: prefix}, nil
+}
+
+func (s *BlobSource) List(ctx context.Context) ([]string, error) {
+	var names []string
+	etags := map[string]string{}
+	pager := s.client.NewListBlobsFlatPager(s.container, &azblob.ListBlobsFlatOptions{Prefix: &s.prefix})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, errors.New("failed to list blobs: " + err.Error())
+		}
+		for _, blob := range page.Segment.BlobItems {
+			if blob.Name != nil && !strings.HasSuffix(*blob.Name, "/") {
+				names = append(names, *blob.Name)
+				if blob.Properties != nil && blob.Properties.ETag != nil {
+					etags[*blob.Name] = string(*blob.Properties.ETag)
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+	s.etags = etags
+	return names, nil
+}
+
+func (s *BlobSource) Read(ctx context.Context, name string) ([]byte, error) {
+	resp, err := s.client.DownloadStream(ctx, s.container, name, nil)
+	if err != nil {
+		return nil, errors.New("failed to download blob: " + err.Error())
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func (s *BlobSource) URI(name string) string {
+	return fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s", s.account, s.container, name)
+}
+
+func (s *BlobSource) Version(name string) string {
+	return s.etags[name]
+}
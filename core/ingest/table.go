@@ -0,0 +1,231 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// the delimiter row under a pipe table's header: "| --- | :---: |"
+var tableDelimiterRow = regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?$`)
+
+// AddTable adds a table whose first row is its header. Rows without text are dropped
+// and short rows padded to the header's width. Tables of one column or without a data
+// row only lay text out, and are added as paragraphs.
+func (d *Document) AddTable(rows [][]string) {
+	var table [][]string
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		empty := true
+		for i, cell := range row {
+			cells[i] = collapseSpace(cell)
+			empty = empty && cells[i] == ""
+		}
+		if !empty {
+			table = append(table, cells)
+		}
+	}
+
+	if len(table) < 2 || len(table[0]) < 2 {
+		for _, row := range table {
+			d.AddParagraph(strings.Join(nonEmpty(row), " "))
+		}
+		return
+	}
+
+	width := len(table[0])
+	for i, row := range table {
+		if len(row) < width {
+			table[i] = append(row, make([]string, width-len(row))...)
+		} else {
+			table[i] = row[:width]
+		}
+	}
+	d.Blocks = append(d.Blocks, Block{Kind: BlockTable, Rows: table})
+}
+
+// pipeTable renders a table in GitHub's markdown syntax, which is how tables reach
+// chunking.
+func pipeTable(rows [][]string) string {
+	var md strings.Builder
+	for i, row := range rows {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			cells[j] = strings.ReplaceAll(cell, "|", `\|`)
+		}
+		md.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		if i == 0 {
+			md.WriteString("|" + strings.Repeat(" --- |", len(row)) + "\n")
+		}
+	}
+	return md.String()
+}
+
+// parsePipeTable reads a paragraph that is a pipe table into its header and data rows.
+func parsePipeTable(paragraph string) ([][]string, bool) {
+	lines := strings.Split(strings.TrimSpace(paragraph), "\n")
+	if len(lines) < 2 || !strings.Contains(lines[0], "|") || !tableDelimiterRow.MatchString(strings.TrimSpace(lines[1])) {
+		return nil, false
+	}
+
+	header := pipeCells(lines[0])
+	rows := [][]string{header}
+	for _, line := range lines[2:] {
+		cells := pipeCells(line)
+		if len(cells) < len(header) {
+			cells = append(cells, make([]string, len(header)-len(cells))...)
+		}
+		rows = append(rows, cells[:len(header)])
+	}
+	return rows, true
+}
+
+// pipeCells splits a pipe table row at its unescaped pipes.
+func pipeCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var (
+		cells []string
+		cell  strings.Builder
+	)
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, collapseSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, collapseSpace(cell.String()))
+}
+
+// tableRowSentences turns each data row of a table into the sentence it is chunked
+// as, every cell labelled with its column: "Remedy: Aconite; Potency: 30C". A row is
+// never split across windows, so its values stay together.
+func tableRowSentences(rows [][]string) []string {
+	header := rows[0]
+	sentences := make([]string, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		var parts []string
+		for i, cell := range row {
+			switch {
+			case cell == "":
+			case i < len(header) && header[i] != "":
+				parts = append(parts, header[i]+": "+cell)
+			default:
+				parts = append(parts, cell)
+			}
+		}
+		if len(parts) > 0 {
+			sentences = append(sentences, strings.Join(parts, "; "))
+		}
+	}
+	return sentences
+}
+
+// TableRows extracts the data rows of a document's tables, each linked to the first of
+// its chunks the row's sentence is in. Rows chunking did not keep, such as those of a
+// table with a blank header and data, are left out.
+func TableRows(sourceUri string, doc *Document, chunks []db.ChunkModel) []db.TableRowModel {
+	var rows []db.TableRowModel
+	next := 0 // tables are in document order, as the chunks are
+	tableIndex := 0
+	for _, block := range doc.Blocks {
+		if block.Kind != BlockTable {
+			continue
+		}
+		tableId, _ := odm.HashedKey(sourceUri, strconv.Itoa(tableIndex))
+		tableIndex++
+
+		header := block.Rows[0]
+		rowIndex := 0
+		for _, cells := range block.Rows[1:] {
+			sentences := tableRowSentences([][]string{header, cells})
+			if len(sentences) == 0 {
+				continue
+			}
+			at := chunkWithSentence(chunks, next, sentences[0])
+			if at < 0 {
+				continue
+			}
+			next = at
+
+			chunk := chunks[at]
+			rows = append(rows, db.TableRowModel{
+				RowID:       fmt.Sprintf("%s_%d", tableId, rowIndex),
+				TableID:     tableId,
+				RowIndex:    rowIndex,
+				Header:      header,
+				Cells:       cells,
+				Text:        sentences[0],
+				SourceURI:   sourceUri,
+				SectionID:   chunk.SectionID,
+				ChunkID:     chunk.ChunkID,
+				SectionPath: chunk.SectionPath,
+				Title:       chunk.Title,
+				Book:        chunk.Book,
+				Author:      chunk.Author,
+			})
+			rowIndex++
+		}
+	}
+	return rows
+}
+
+func chunkWithSentence(chunks []db.ChunkModel, from int, sentence string) int {
+	for i := from; i < len(chunks); i++ {
+		for _, s := range chunks[i].Sentences {
+			if s == sentence {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// PublishTables replaces the table rows of a source document with rows.
+func PublishTables(ctx context.Context, mongo odm.MongoClient, tenant, sourceUri string, rows []db.TableRowModel) error {
+	_, err := mongo.Database(tenant).Collection(db.TableRowModel{}.CollectionName()).DeleteMany(ctx, bson.M{"sourceUri": sourceUri})
+	if err != nil {
+		return errors.New("failed to remove replaced table rows: " + err.Error())
+	}
+
+	repo := odm.CollectionOf[db.TableRowModel](mongo, tenant)
+	for _, row := range rows {
+		if _, err := async.Await(repo.Save(ctx, row)); err != nil {
+			return errors.New("failed to save table row: " + err.Error())
+		}
+	}
+	if len(rows) > 0 {
+		logger.Info("Table rows published", zap.String("tenant", tenant), zap.String("sourceUri", sourceUri), zap.Int("rows", len(rows)))
+	}
+	return nil
+}
+
+func nonEmpty(values []string) []string {
+	var kept []string
+	for _, value := range values {
+		if value != "" {
+			kept = append(kept, value)
+		}
+	}
+	return kept
+}
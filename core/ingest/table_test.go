@@ -0,0 +1,97 @@
+package ingest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentTables(t *testing.T) {
+	doc := &Document{}
+	doc.AddHeading(1, "Dosage")
+	doc.AddTable([][]string{
+		{"Remedy", "Potency", "Dose"},
+		{"Aconite", "30C", "3 pellets | hourly"},
+		{"", "", ""},
+		{"Belladonna", "6C"},
+	})
+	doc.AddTable([][]string{{"Layout only"}, {"one column"}})
+
+	md := doc.Markdown()
+	assert.Equal(t, "# Dosage\n\n| Remedy | Potency | Dose |\n| --- | --- | --- |\n| Aconite | 30C | 3 pellets \\| hourly |\n"+
+		"| Belladonna | 6C |  |\n\nLayout only\n\none column\n\n", string(md))
+
+	parsed := ParseDocument(md)
+	assert.Equal(t, doc.Blocks, parsed.Blocks, "tables read back from markdown as they were")
+
+	chunks, err := ChunkMarkdown(t.Context(), "file://dosage.md", md, db.ChunkingConfig{})
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+	assert.Equal(t, []string{
+		"Remedy: Aconite; Potency: 30C; Dose: 3 pellets | hourly",
+		"Remedy: Belladonna; Potency: 6C",
+		"Layout only",
+		"one column",
+	}, chunks[0].Sentences)
+	assert.Equal(t, []int{0, 0, 1, 2}, chunks[0].Paragraphs, "a table is one paragraph")
+
+	rows := TableRows("file://dosage.md", doc, chunks)
+	require.Len(t, rows, 2)
+	assert.Equal(t, []string{"Remedy", "Potency", "Dose"}, rows[1].Header)
+	assert.Equal(t, []string{"Belladonna", "6C", ""}, rows[1].Cells)
+	assert.Equal(t, 1, rows[1].RowIndex)
+	assert.Equal(t, rows[0].TableID, rows[1].TableID)
+	assert.True(t, strings.HasPrefix(rows[1].RowID, rows[1].TableID+"_"))
+	assert.Equal(t, chunks[0].ChunkID, rows[0].ChunkID)
+	assert.Equal(t, "Dosage", rows[0].Title)
+}
+
+func TestParsePipeTable(t *testing.T) {
+	rows, ok := parsePipeTable("Remedy | Grade\n:--- | ---:\nAcon. | 3 | extra\nArs.")
+	require.True(t, ok)
+	assert.Equal(t, [][]string{{"Remedy", "Grade"}, {"Acon.", "3"}, {"Ars.", ""}}, rows)
+
+	_, ok = parsePipeTable("Fear | anxiety\nwithout a delimiter row")
+	assert.False(t, ok)
+	_, ok = parsePipeTable("Heading\n---")
+	assert.False(t, ok)
+}
+
+func TestConvertHtmlTable(t *testing.T) {
+	page := `<html><body><main><h2>Potencies</h2>
+<table><caption>Common potencies</caption>
+<thead><tr><th>Remedy</th><th>Potency</th></tr></thead>
+<tbody><tr><td>Arnica</td><td><b>200C</b></td></tr><tr><td></td><td></td></tr></tbody>
+</table></main></body></html>`
+
+	doc, err := convertHtml(t.Context(), "potencies.html", []byte(page))
+	require.NoError(t, err)
+	assert.Equal(t, []Block{
+		{Kind: BlockHeading, Level: 2, Text: "Potencies"},
+		{Kind: BlockParagraph, Text: "Common potencies"},
+		{Kind: BlockTable, Rows: [][]string{{"Remedy", "Potency"}, {"Arnica", "200C"}}},
+	}, doc.Blocks)
+}
+
+func TestConvertDocxTable(t *testing.T) {
+	const w = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"`
+	docx := buildZip(t, map[string]string{
+		"word/document.xml": `<w:document ` + w + `><w:body>
+  <w:p><w:r><w:t>Doses by age.</w:t></w:r></w:p>
+  <w:tbl>
+    <w:tr><w:tc><w:p><w:r><w:t>Age</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Dose</w:t></w:r></w:p></w:tc></w:tr>
+    <w:tr><w:tc><w:p><w:r><w:t>Child</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>1 pellet</w:t></w:r></w:p><w:p><w:r><w:t>twice daily</w:t></w:r></w:p></w:tc></w:tr>
+  </w:tbl>
+</w:body></w:document>`,
+	})
+
+	doc, err := convertDocx(t.Context(), "doses.docx", docx)
+	require.NoError(t, err)
+	assert.Equal(t, []Block{
+		{Kind: BlockParagraph, Text: "Doses by age."},
+		{Kind: BlockTable, Rows: [][]string{{"Age", "Dose"}, {"Child", "1 pellet twice daily"}}},
+	}, doc.Blocks)
+}
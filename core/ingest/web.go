@@ -0,0 +1,404 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/temoto/robotstxt"
+	"go.uber.org/zap"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// DefaultCrawlPages is how many URLs a web source visits when its config sets no limit.
+	DefaultCrawlPages = 200
+	// DefaultCrawlDelay spaces requests to a site, unless its robots.txt asks for longer.
+	DefaultCrawlDelay = time.Second
+
+	crawlerUserAgent = "medicine-rag-crawler"
+	maxCrawlBytes    = 20 << 20
+)
+
+// Linked files with these extensions are documents of their own, read whole, rather
+// than pages to crawl.
+var linkedDocumentExtensions = map[string]bool{
+	".pdf": true, ".epub": true, ".docx": true, ".md": true, ".markdown": true,
+}
+
+// WebSource crawls the web pages under an allowlist of URLs, such as a tenant's own
+// published protocols. Starting from each allowed URL, it follows links to pages and
+// linked documents that are under one of them, as robots.txt allows. Each document's
+// sourceUri is its URL, so search results cite the page they came from.
+type WebSource struct {
+	client  *http.Client
+	allowed []*url.URL
+
+	MaxPages int           // URLs visited per List
+	Delay    time.Duration // between requests to a site
+
+	robots  map[string]*robotstxt.RobotsData // by site; as of the last List
+	fetched map[string]time.Time             // last request by site
+	urls    map[string]string                // by document name; as of the last List
+	pages   map[string][]byte                // pages read while crawling, by name
+}
+
+func NewWebSource(allowed []string, maxPages int) (*WebSource, error) {
+	if len(allowed) == 0 {
+		return nil, errors.New("at least one URL is required")
+	}
+	s := &WebSource{MaxPages: maxPages, Delay: DefaultCrawlDelay}
+	if s.MaxPages <= 0 {
+		s.MaxPages = DefaultCrawlPages
+	}
+	for _, raw := range allowed {
+		u, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("%q is not an http or https URL", raw)
+		}
+		s.allowed = append(s.allowed, normalizeURL(u))
+	}
+	s.client = &http.Client{
+		Timeout: time.Minute,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			// a redirect off the allowlist leaves the page unread
+			if len(via) >= 10 || !s.inScope(req.URL) {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+	return s, nil
+}
+
+func (s *WebSource) List(ctx context.Context) ([]string, error) {
+	s.robots = map[string]*robotstxt.RobotsData{}
+	s.fetched = map[string]time.Time{}
+	s.urls = map[string]string{}
+	s.pages = map[string][]byte{}
+
+	var queue []*url.URL
+	seen := map[string]bool{}
+	for _, u := range s.allowed {
+		if !seen[u.String()] {
+			seen[u.String()] = true
+			queue = append(queue, u)
+		}
+	}
+
+	var firstErr error
+	for visited := 0; len(queue) > 0 && visited < s.MaxPages; visited++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		u := queue[0]
+		queue = queue[1:]
+
+		links, err := s.visit(ctx, u, seen)
+		if err != nil {
+			// a broken link costs its page, not the crawl
+			logger.Error("Failed to crawl page", zap.String("url", u.String()), zap.Error(err))
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		queue = append(queue, links...)
+	}
+	if len(s.urls) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+
+	names := make([]string, 0, len(s.urls))
+	for name := range s.urls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// visit lists the document at u, and returns the links it has to follow that were
+// not seen before.
+func (s *WebSource) visit(ctx context.Context, u *url.URL, seen map[string]bool) ([]*url.URL, error) {
+	allowed, err := s.robotsAllow(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		logger.Info("Skipping page disallowed by robots.txt", zap.String("url", u.String()))
+		return nil, nil
+	}
+
+	if linkedDocumentExtensions[Extension(u.Path)] {
+		s.addDocument(webDocumentName(u, false), u)
+		return nil, nil
+	}
+
+	body, final, err := s.fetch(ctx, u, true)
+	if err != nil || body == nil {
+		return nil, err
+	}
+	if final.String() != u.String() {
+		if seen[final.String()] {
+			return nil, nil
+		}
+		seen[final.String()] = true
+	}
+
+	root, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.New("failed to parse HTML: " + err.Error())
+	}
+	index, follow := pageRobots(root)
+	if index {
+		name := webDocumentName(final, true)
+		if s.addDocument(name, final) {
+			s.pages[name] = body
+		}
+	}
+	if !follow {
+		return nil, nil
+	}
+
+	var links []*url.URL
+	for _, link := range pageLinks(root, final) {
+		if s.inScope(link) && !seen[link.String()] {
+			seen[link.String()] = true
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
+
+// addDocument lists the document at u as name, unless another URL has that name.
+func (s *WebSource) addDocument(name string, u *url.URL) bool {
+	if _, ok := s.urls[name]; ok {
+		return false
+	}
+	s.urls[name] = u.String()
+	return true
+}
+
+func (s *WebSource) Read(ctx context.Context, name string) ([]byte, error) {
+	if page, ok := s.pages[name]; ok {
+		return page, nil
+	}
+	raw, ok := s.urls[name]
+	if !ok {
+		return nil, errors.New("unknown document: " + name)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	body, _, err := s.fetch(ctx, u, false)
+	if err == nil && body == nil {
+		err = errors.New("no document at " + raw)
+	}
+	return body, err
+}
+
+func (s *WebSource) URI(name string) string {
+	return s.urls[name]
+}
+
+// inScope reports whether u is under one of the allowed URLs.
+func (s *WebSource) inScope(u *url.URL) bool {
+	for _, allowed := range s.allowed {
+		if u.Scheme == allowed.Scheme && strings.EqualFold(u.Host, allowed.Host) &&
+			strings.HasPrefix(u.Path, allowed.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetch reads u, waiting out the site's crawl delay first, and returns the URL it was
+// read from after redirects. Responses other than 200, and ones that aren't HTML when
+// wantHTML is set, come back as nil.
+func (s *WebSource) fetch(ctx context.Context, u *url.URL, wantHTML bool) ([]byte, *url.URL, error) {
+	if err := s.wait(ctx, u); err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", crawlerUserAgent)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, nil, errors.New("failed to fetch: " + err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		logger.Info("Skipping page", zap.String("url", u.String()), zap.Int("status", resp.StatusCode))
+		return nil, nil, nil
+	}
+	if wantHTML {
+		mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+			return nil, nil, nil
+		}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCrawlBytes+1))
+	if err != nil {
+		return nil, nil, errors.New("failed to read: " + err.Error())
+	}
+	if len(body) > maxCrawlBytes {
+		return nil, nil, fmt.Errorf("larger than %d MB", maxCrawlBytes>>20)
+	}
+	return body, normalizeURL(resp.Request.URL), nil
+}
+
+// wait sleeps until the site's crawl delay has passed since its last request.
+func (s *WebSource) wait(ctx context.Context, u *url.URL) error {
+	site := u.Scheme + "://" + u.Host
+	delay := s.Delay
+	if robots := s.robots[site]; robots != nil {
+		delay = max(delay, robots.FindGroup(crawlerUserAgent).CrawlDelay)
+	}
+	wait := time.Until(s.fetched[site].Add(delay))
+	if wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	s.fetched[site] = time.Now()
+	return nil
+}
+
+// robotsAllow reports whether the site's robots.txt lets the crawler read u. It is
+// read once per List. A site without one allows everything, and one whose server
+// fails to serve it allows nothing.
+func (s *WebSource) robotsAllow(ctx context.Context, u *url.URL) (bool, error) {
+	site := u.Scheme + "://" + u.Host
+	robots, ok := s.robots[site]
+	if !ok {
+		if err := s.wait(ctx, u); err != nil {
+			return false, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, site+"/robots.txt", nil)
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("User-Agent", crawlerUserAgent)
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return false, errors.New("failed to fetch robots.txt: " + err.Error())
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		if err != nil {
+			return false, errors.New("failed to read robots.txt: " + err.Error())
+		}
+		robots, err = robotstxt.FromStatusAndBytes(resp.StatusCode, body)
+		if err != nil {
+			return false, errors.New("failed to parse robots.txt: " + err.Error())
+		}
+		s.robots[site] = robots
+	}
+
+	target := u.EscapedPath()
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+	return robots.TestAgent(target, crawlerUserAgent), nil
+}
+
+// pageRobots reads a page's robots meta tag: whether it may be indexed, and whether
+// its links may be followed.
+func pageRobots(root *html.Node) (index, follow bool) {
+	index, follow = true, true
+	for _, meta := range findElements(root, atom.Meta) {
+		name := strings.ToLower(htmlAttr(meta, "name"))
+		if name != "robots" && name != crawlerUserAgent {
+			continue
+		}
+		for _, directive := range strings.Split(strings.ToLower(htmlAttr(meta, "content")), ",") {
+			switch strings.TrimSpace(directive) {
+			case "noindex":
+				index = false
+			case "nofollow":
+				follow = false
+			case "none":
+				index, follow = false, false
+			}
+		}
+	}
+	return index, follow
+}
+
+// pageLinks returns the URLs a page links to, resolved against its base, leaving out
+// links marked nofollow.
+func pageLinks(root *html.Node, page *url.URL) []*url.URL {
+	base := page
+	if element := findElement(root, atom.Base); element != nil {
+		if href, err := page.Parse(htmlAttr(element, "href")); err == nil {
+			base = href
+		}
+	}
+
+	var links []*url.URL
+	for _, a := range findElements(root, atom.A) {
+		href := strings.TrimSpace(htmlAttr(a, "href"))
+		nofollow := slices.ContainsFunc(strings.Fields(htmlAttr(a, "rel")), func(rel string) bool {
+			return strings.EqualFold(rel, "nofollow")
+		})
+		if href == "" || nofollow {
+			continue
+		}
+		link, err := base.Parse(href)
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") {
+			continue
+		}
+		links = append(links, normalizeURL(link))
+	}
+	return links
+}
+
+// normalizeURL drops u's fragment and lower-cases its host, so each page has one URL.
+func normalizeURL(u *url.URL) *url.URL {
+	normalized := *u
+	normalized.Fragment = ""
+	normalized.RawFragment = ""
+	normalized.Host = strings.ToLower(normalized.Host)
+	if normalized.Path == "" {
+		normalized.Path = "/"
+		normalized.RawPath = ""
+	}
+	return &normalized
+}
+
+// webDocumentName names the document at u after its host and path. Pages end in
+// .html, so they are converted as HTML whatever their URL looks like.
+func webDocumentName(u *url.URL, page bool) string {
+	name := u.Host + u.EscapedPath()
+	if !page {
+		return name
+	}
+	if strings.HasSuffix(name, "/") {
+		name += "index"
+	}
+	if u.RawQuery != "" {
+		return name + "?" + u.RawQuery + ".html"
+	}
+	if ext := path.Ext(name); ext != ".html" && ext != ".htm" {
+		name += ".html"
+	}
+	return name
+}
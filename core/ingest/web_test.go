@@ -0,0 +1,111 @@
+package ingest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/html"
+)
+
+func TestWebSource(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		assert.Equal(t, crawlerUserAgent, r.Header.Get("User-Agent"))
+
+		page := func(body string) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html><head><title>Protocols</title></head><body>" + body + "</body></html>"))
+		}
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /protocols/drafts/\n"))
+		case "/protocols/":
+			page(`<nav><a href="/">Home</a></nav><main><p>Clinic protocols.</p>
+				<a href="fever#dosing">Fever</a> <a href="/protocols/drafts/new">Draft</a>
+				<a href="/protocols/intake.pdf">Intake form</a> <a href="/protocols/old" rel="nofollow">Old</a>
+				<a href="/protocols/moved">Moved</a> <a href="/about">About</a>
+				<a href="mailto:clinic@example.org">Mail</a></main>`)
+		case "/protocols/fever":
+			page(`<main><h1>Fever</h1><p>Aconite in the first hours.</p><a href="/protocols/">Back</a></main>`)
+		case "/protocols/moved":
+			http.Redirect(w, r, "/protocols/fever", http.StatusMovedPermanently)
+		case "/protocols/intake.pdf":
+			w.Write([]byte("%PDF-1.4"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	source, err := OpenSource(t.Context(), db.SourceConfig{Kind: db.SourceWeb, URLs: []string{server.URL + "/protocols/"}}, "")
+	require.NoError(t, err)
+	web := source.(*WebSource)
+	web.Delay = 0
+
+	names, err := source.List(t.Context())
+	require.NoError(t, err)
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	assert.Equal(t, []string{host + "/protocols/fever.html", host + "/protocols/index.html", host + "/protocols/intake.pdf"}, names)
+	assert.Equal(t, server.URL+"/protocols/fever", source.URI(host+"/protocols/fever.html"))
+	assert.Equal(t, server.URL+"/protocols/intake.pdf", source.URI(host+"/protocols/intake.pdf"))
+
+	// disallowed, nofollow and off-allowlist links are never requested and the PDF is
+	// only listed; the redirect lands on a page already crawled, which is listed once
+	assert.Equal(t, []string{"/robots.txt", "/protocols/", "/protocols/fever", "/protocols/moved", "/protocols/fever"}, requested)
+
+	data, err := source.Read(t.Context(), host+"/protocols/fever.html")
+	require.NoError(t, err)
+	doc, err := convertHtml(t.Context(), host+"/protocols/fever.html", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Protocols", doc.Source.Book)
+
+	data, err = source.Read(t.Context(), host+"/protocols/intake.pdf")
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.4", string(data))
+}
+
+func TestWebSourceRobots(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			t.Errorf("requested %s although robots.txt failed", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	source, err := NewWebSource([]string{server.URL}, 0)
+	require.NoError(t, err)
+	source.Delay = 0
+
+	names, err := source.List(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestPageRobots(t *testing.T) {
+	for _, tc := range []struct {
+		meta          string
+		index, follow bool
+	}{
+		{``, true, true},
+		{`<meta name="robots" content="noindex">`, false, true},
+		{`<meta name="ROBOTS" content="index, nofollow">`, true, false},
+		{`<meta name="medicine-rag-crawler" content="none">`, false, false},
+		{`<meta name="googlebot" content="noindex">`, true, true},
+	} {
+		root, err := html.Parse(strings.NewReader("<html><head>" + tc.meta + "</head><body></body></html>"))
+		require.NoError(t, err)
+		index, follow := pageRobots(root)
+		assert.Equal(t, tc.index, index, tc.meta)
+		assert.Equal(t, tc.follow, follow, tc.meta)
+	}
+}
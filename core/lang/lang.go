@@ -0,0 +1,209 @@
+// Package lang picks the language an answer is written in, and tells the language of
+// text. Corpora are mostly English, so retrieval stays in English whatever the
+// question's language, and only the answer model is told to write in the user's.
+// Ingestion tags each chunk with its language, so searches may prefer the user's.
+package lang
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/medicine-rag/core/llmrouter"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// MetadataKey is the GenerateAnswerRequest metadata entry naming the answer language.
+// The request message belongs to agent-boot, so the language travels as metadata, as
+// the model does.
+const MetadataKey = "language"
+
+const translateTimeout = 10 * time.Second
+
+// Language is a base language such as "en" or "hi", with its English name.
+type Language struct {
+	Code string
+	Name string
+}
+
+var English = Language{Code: "en", Name: "English"}
+
+// Parse reads a BCP 47 tag, keeping only its base language: "pt-BR" is Portuguese.
+func Parse(value string) (Language, error) {
+	tag, err := language.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return Language{}, fmt.Errorf("invalid language %q: %w", value, err)
+	}
+	base, confidence := tag.Base()
+	if confidence == language.No || base.String() == "und" {
+		return Language{}, fmt.Errorf("invalid language %q", value)
+	}
+	name := display.English.Languages().Name(base)
+	if name == "" {
+		name = base.String()
+	}
+	return Language{Code: base.String(), Name: name}, nil
+}
+
+// Resolve is the requested language, or the question's when none was requested, or
+// English when that cannot be told.
+func Resolve(requested, question string) (Language, error) {
+	if strings.TrimSpace(requested) != "" {
+		return Parse(requested)
+	}
+	if detected, ok := Detect(question); ok {
+		return detected, nil
+	}
+	return English, nil
+}
+
+// Instruction tells the answer model which language to write in; English needs none.
+func (l Language) Instruction() string {
+	if l.Code == English.Code || l.Code == "" {
+		return ""
+	}
+	return "Write your answer in " + l.Name + ", even though the search results are in English. " +
+		"Keep remedy names, potencies and abbreviations exactly as they appear in the search results."
+}
+
+const translateSystemPrompt = "Translate the user's search query into English for searching an English homeopathy knowledge base. " +
+	"Keep remedy names, potencies and Latin terms unchanged. Reply with the translated query only."
+
+// ToEnglish translates a search query the tool selector wrote in another language, so
+// it matches the English corpus. Queries already in English, or in a language that
+// cannot be told, are returned as they are, as is the query when translation fails.
+func ToEnglish(ctx context.Context, model llm.LLMClient, query string) string {
+	if detected, ok := Detect(query); !ok || detected.Code == English.Code {
+		return query
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, translateTimeout)
+	defer cancel()
+
+	var translated strings.Builder
+	err := model.GenerateInference(ctx,
+		[]llm.Message{{Role: "user", Content: query}},
+		func(chunk string) error {
+			translated.WriteString(chunk)
+			return nil
+		},
+		llmrouter.WithSystemPrompt(translateSystemPrompt),
+		llmrouter.WithTemperature(0),
+	)
+	if err != nil || strings.TrimSpace(translated.String()) == "" {
+		return query
+	}
+	return strings.Trim(strings.TrimSpace(translated.String()), `"`)
+}
+
+// scripts maps writing systems used by a single language, or mostly by one, to it.
+var scripts = []struct {
+	table *unicode.RangeTable
+	code  string
+}{
+	{unicode.Devanagari, "hi"},
+	{unicode.Bengali, "bn"},
+	{unicode.Gurmukhi, "pa"},
+	{unicode.Gujarati, "gu"},
+	{unicode.Tamil, "ta"},
+	{unicode.Telugu, "te"},
+	{unicode.Kannada, "kn"},
+	{unicode.Malayalam, "ml"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Greek, "el"},
+	{unicode.Thai, "th"},
+	{unicode.Hangul, "ko"},
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Han, "zh"},
+}
+
+// urduLetters are Arabic-script letters Arabic itself does not use.
+const urduLetters = "ٹڈڑںےھ"
+
+// stopwords are frequent words that tell Latin-script languages apart.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "what", "which", "for", "with", "of", "in", "to", "how", "does", "can", "should"},
+	"de": {"der", "die", "das", "und", "ist", "sind", "was", "welche", "für", "mit", "bei", "wie", "nicht", "ein", "eine"},
+	"fr": {"le", "la", "les", "et", "est", "sont", "quel", "quelle", "pour", "avec", "des", "du", "une", "dans", "comment"},
+	"es": {"el", "la", "los", "las", "y", "es", "son", "qué", "cuál", "para", "con", "del", "una", "en", "cómo"},
+	"it": {"il", "lo", "gli", "e", "è", "sono", "che", "quale", "per", "con", "della", "una", "nel", "come", "del"},
+	"pt": {"o", "os", "as", "e", "é", "são", "que", "qual", "para", "com", "da", "do", "uma", "em", "como"},
+	"nl": {"de", "het", "een", "en", "is", "zijn", "wat", "welke", "voor", "met", "van", "bij", "hoe", "niet", "ook"},
+}
+
+// Detect tells the language of text from its script, or for Latin script from its
+// most frequent words. It reports false when neither settles it, as for a bare list
+// of remedy names.
+func Detect(text string) (Language, bool) {
+	counts := make(map[string]int)
+	latin := 0
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case strings.ContainsRune(urduLetters, r):
+			counts["ur"]++
+		default:
+			for _, script := range scripts {
+				if unicode.Is(script.table, r) {
+					counts[script.code]++
+					break
+				}
+			}
+		}
+	}
+	// kana marks Japanese even where kanji outnumber it
+	if counts["ja"] > 0 {
+		counts["ja"] += counts["zh"]
+		delete(counts, "zh")
+	}
+	if counts["ur"] > 0 {
+		counts["ur"] += counts["ar"]
+		delete(counts, "ar")
+	}
+
+	best, most := "", 0
+	for code, count := range counts {
+		if count > most || count == most && code < best {
+			best, most = code, count
+		}
+	}
+	if most > latin {
+		detected, err := Parse(best)
+		return detected, err == nil
+	}
+	if latin == 0 {
+		return Language{}, false
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	best, most = "", 0
+	for _, code := range slices.Sorted(maps.Keys(stopwords)) {
+		hits := 0
+		for _, word := range words {
+			if slices.Contains(stopwords[code], word) {
+				hits++
+			}
+		}
+		// English wins ties, as the corpus language
+		if hits > most || hits == most && hits > 0 && code == English.Code {
+			best, most = code, hits
+		}
+	}
+	if most == 0 {
+		return Language{}, false
+	}
+	detected, err := Parse(best)
+	return detected, err == nil
+}
@@ -0,0 +1,77 @@
+package lang
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type translatingModel struct {
+	llm.LLMClient
+	reply string
+	err   error
+	calls int
+}
+
+func (m *translatingModel) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	m.calls++
+	if m.err != nil {
+		return m.err
+	}
+	return callback(m.reply)
+}
+
+func TestDetect(t *testing.T) {
+	cases := map[string]string{
+		"Which remedy helps bruising after an injury?":               "en",
+		"Welches Mittel hilft bei Prellungen nach einer Verletzung?": "de",
+		"Quel remède pour les ecchymoses après une blessure?":        "fr",
+		"¿Qué remedio es bueno para los moretones?":                  "es",
+		"चोट के बाद नील के लिए कौन सी दवा है? Arnica 30C?":           "hi",
+		"چوٹ کے بعد نیل کے لیے کون سی دوا ہے؟":                       "ur",
+		"ما هو العلاج المناسب للكدمات؟":                              "ar",
+		"打撲にはどのレメディが良いですか？":                                          "ja",
+		"Какое средство помогает при ушибах?":                        "ru",
+	}
+	for text, want := range cases {
+		detected, ok := Detect(text)
+		require.True(t, ok, text)
+		assert.Equal(t, want, detected.Code, text)
+	}
+
+	_, ok := Detect("Arnica Bellis Hypericum")
+	assert.False(t, ok, "remedy names alone tell no language")
+}
+
+func TestResolve(t *testing.T) {
+	requested, err := Resolve("pt-BR", "Which remedy helps bruising?")
+	require.NoError(t, err)
+	assert.Equal(t, Language{Code: "pt", Name: "Portuguese"}, requested)
+
+	detected, err := Resolve("", "Welches Mittel hilft bei Prellungen?")
+	require.NoError(t, err)
+	assert.Equal(t, "German", detected.Name)
+
+	fallback, err := Resolve("", "Arnica 30C")
+	require.NoError(t, err)
+	assert.Equal(t, English, fallback)
+	assert.Empty(t, fallback.Instruction())
+
+	_, err = Resolve("not a language!", "")
+	assert.Error(t, err)
+}
+
+func TestToEnglish(t *testing.T) {
+	model := &translatingModel{reply: `"Arnica for bruising"`}
+	assert.Equal(t, "Arnica for bruising", ToEnglish(t.Context(), model, "Arnica bei Prellungen und Schwellungen mit der Verletzung"))
+
+	assert.Equal(t, "Arnica for bruising", ToEnglish(t.Context(), model, "Arnica for bruising"))
+	assert.Equal(t, 1, model.calls, "English queries are not translated")
+
+	failing := &translatingModel{err: errors.New("unavailable")}
+	assert.Equal(t, "Arnica bei der Prellung", ToEnglish(t.Context(), failing, "Arnica bei der Prellung"))
+}
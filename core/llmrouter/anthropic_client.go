@@ -0,0 +1,212 @@
+package llmrouter
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/ollama/ollama/api"
+)
+
+const (
+	anthropicMessagesURL = "https://api.anthropic.com/v1/messages"
+	anthropicVersion     = "2023-06-01"
+	// agent-boot's defaults, used when a call sets none
+	anthropicMaxTokens   = 4096
+	anthropicTemperature = 0.7
+)
+
+// AnthropicClient talks to the Anthropic messages API. It mirrors agent-boot's client,
+// which buffers the whole answer and drops the token counts, but streams the answer as
+// it is generated when streaming is requested and reports the provider's counts.
+type AnthropicClient struct {
+	apiKey     string
+	httpClient *http.Client
+	url        string
+	model      string
+}
+
+// NewAnthropicClient reads ANTHROPIC_API_KEY. Unlike agent-boot's constructor it
+// returns an error instead of exiting when the key is missing.
+func NewAnthropicClient(model string) (*AnthropicClient, error) {
+	apiKey := os.Getenv("ANTHROPIC_API_KEY")
+	if apiKey == "" {
+		return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable is not set")
+	}
+
+	return &AnthropicClient{
+		apiKey:     apiKey,
+		httpClient: &http.Client{},
+		url:        anthropicMessagesURL,
+		model:      model,
+	}, nil
+}
+
+// Capabilities reports no native tool calling, as agent-boot's client does, so the
+// agent selects tools from the model's text.
+func (c *AnthropicClient) Capabilities() llm.Capability {
+	return 0
+}
+
+func (c *AnthropicClient) GetModel() string {
+	return c.model
+}
+
+func (c *AnthropicClient) GenerateInferenceWithTools(ctx context.Context, messages []llm.Message, contentCallback func(chunk string) error, toolCallback func(toolCalls []api.ToolCall) error, opts ...llm.LLMOption) error {
+	return c.GenerateInference(ctx, messages, contentCallback, opts...)
+}
+
+func (c *AnthropicClient) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	s := resolveSettings(append([]llm.LLMOption{
+		WithMaxTokens(anthropicMaxTokens),
+		WithTemperature(anthropicTemperature),
+	}, opts...))
+
+	request := anthropicRequest{
+		Model:       c.model,
+		MaxTokens:   cmp.Or(s.maxTokens, anthropicMaxTokens),
+		Temperature: s.temperature,
+		System:      s.system,
+		Messages:    messages,
+		Stream:      s.stream,
+	}
+
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("error marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if request.Stream && resp.StatusCode == http.StatusOK {
+		return c.readStream(ctx, resp.Body, callback)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response anthropicResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("error unmarshaling response: %w", err)
+	}
+	if len(response.Content) == 0 {
+		return fmt.Errorf("no content in response")
+	}
+	reportUsage(ctx, response.Usage.InputTokens, response.Usage.OutputTokens)
+
+	var text strings.Builder
+	for _, block := range response.Content {
+		text.WriteString(block.Text)
+	}
+	if callback != nil {
+		return callback(text.String())
+	}
+	return nil
+}
+
+// readStream passes each text delta to callback as it arrives. The prompt tokens come
+// with the first event and the output tokens with the last.
+func (c *AnthropicClient) readStream(ctx context.Context, body io.Reader, callback func(chunk string) error) error {
+	var usage anthropicUsage
+	err := readEvents(body, func(_, data string) error {
+		var e anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			return fmt.Errorf("error unmarshaling stream event: %w", err)
+		}
+
+		switch e.Type {
+		case "message_start":
+			usage.InputTokens = e.Message.Usage.InputTokens
+		case "content_block_delta":
+			if e.Delta.Text != "" && callback != nil {
+				return callback(e.Delta.Text)
+			}
+		case "message_delta":
+			usage.OutputTokens = e.Usage.OutputTokens
+		case "error":
+			// errors after the response started come in the stream; they are reported
+			// with the status the same error would have had, so they are retried alike
+			return fmt.Errorf("API request failed with status %d: %s", anthropicErrorStatus(e.Error.Type), e.Error.Message)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	reportUsage(ctx, usage.InputTokens, usage.OutputTokens)
+	return nil
+}
+
+func anthropicErrorStatus(errorType string) int {
+	switch errorType {
+	case "overloaded_error":
+		return 529
+	case "rate_limit_error":
+		return http.StatusTooManyRequests
+	case "api_error":
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+type anthropicRequest struct {
+	Model       string        `json:"model"`
+	MaxTokens   int           `json:"max_tokens"`
+	Messages    []llm.Message `json:"messages"`
+	System      string        `json:"system,omitempty"`
+	Temperature float64       `json:"temperature"`
+	Stream      bool          `json:"stream,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage anthropicUsage `json:"usage"`
+}
+
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Text string `json:"text"`
+	} `json:"delta"`
+	Usage anthropicUsage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
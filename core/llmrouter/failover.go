@@ -0,0 +1,199 @@
+package llmrouter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/ollama/ollama/api"
+	"go.uber.org/zap"
+)
+
+// A provider's circuit opens after breakerThreshold transient failures in a row. While
+// open its models are skipped; after breakerCooldown one call is let through to probe it.
+const (
+	breakerThreshold = 5
+	breakerCooldown  = 30 * time.Second
+)
+
+var ErrProviderUnavailable = errors.New("provider unavailable")
+
+// ParseModelFallbacks reads name=fallback entries, e.g. "claude=gpt-4o-mini", into the
+// model each one fails over to.
+func ParseModelFallbacks(entries []string) (map[string]string, error) {
+	fallbacks := make(map[string]string)
+	for _, entry := range entries {
+		name, fallback, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		name, fallback = strings.TrimSpace(name), strings.TrimSpace(fallback)
+		if !ok || name == "" || fallback == "" {
+			return nil, fmt.Errorf("expected name=fallback, got %q", entry)
+		}
+		fallbacks[name] = fallback
+	}
+	return fallbacks, nil
+}
+
+// Failover describes a call that a fallback model served because Model failed.
+type Failover struct {
+	Model    string
+	Fallback string
+	Err      error
+}
+
+type failoverNoticeKey struct{}
+
+// WithFailoverNotice asks the clients serving calls made with the returned context to
+// call notify whenever a fallback model takes over a call.
+func WithFailoverNotice(ctx context.Context, notify func(Failover)) context.Context {
+	return context.WithValue(ctx, failoverNoticeKey{}, notify)
+}
+
+// transient reports whether err is worth retrying or failing over: rate limits, server
+// errors, timeouts and network failures. Other errors would fail the same way again.
+func transient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrProviderUnavailable) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	if code, ok := statusCode(err); ok {
+		return code == 429 || code >= 500
+	}
+	return false
+}
+
+var statusPattern = regexp.MustCompile(`status (\d{3})`)
+
+// statusCode reads the HTTP status out of a provider error. The providers' clients
+// report it only in the error text.
+func statusCode(err error) (int, bool) {
+	match := statusPattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0, false
+	}
+	code, _ := strconv.Atoi(match[1])
+	return code, true
+}
+
+// breaker is the circuit of one provider, shared by every model it serves.
+type breaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow reports whether a call may go to the provider. Once the cooldown is over a
+// single probe is let through; its outcome closes or reopens the circuit.
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < breakerThreshold {
+		return true
+	}
+	if now.Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// abandon ends a probe whose outcome is unknown, so the next call probes again.
+func (b *breaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *breaker) record(err error, now time.Time) (opened bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if !transient(err) {
+		b.failures = 0
+		return false
+	}
+	b.failures++
+	if b.failures >= breakerThreshold {
+		b.openUntil = now.Add(breakerCooldown)
+		return true
+	}
+	return false
+}
+
+// failoverTarget is one model of a failover chain.
+type failoverTarget struct {
+	spec    ModelSpec
+	client  llm.LLMClient
+	breaker *breaker
+}
+
+// failoverClient tries its chain of models in order. A call moves on to the next model
+// when the current one fails transiently before producing any output, or when its
+// provider's circuit is open, so callers never see a partial answer twice.
+type failoverClient struct {
+	llm.LLMClient // the primary's client, for everything but inference
+	chain         []failoverTarget
+}
+
+func (c *failoverClient) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	return c.do(ctx, func(target failoverTarget, emitted *bool) error {
+		return target.client.GenerateInference(ctx, messages, tracked(callback, emitted), opts...)
+	})
+}
+
+func (c *failoverClient) GenerateInferenceWithTools(ctx context.Context, messages []llm.Message, contentCallback func(chunk string) error, toolCallback func(toolCalls []api.ToolCall) error, opts ...llm.LLMOption) error {
+	return c.do(ctx, func(target failoverTarget, emitted *bool) error {
+		return target.client.GenerateInferenceWithTools(ctx, messages, tracked(contentCallback, emitted), trackedTools(toolCallback, emitted), opts...)
+	})
+}
+
+func (c *failoverClient) do(ctx context.Context, call func(target failoverTarget, emitted *bool) error) error {
+	primary := c.chain[0].spec
+	err := fmt.Errorf("%w: %s", ErrProviderUnavailable, primary.Provider)
+
+	for i, target := range c.chain {
+		if !target.breaker.allow(time.Now()) {
+			err = fmt.Errorf("%w: %s", ErrProviderUnavailable, target.spec.Provider)
+			continue
+		}
+
+		if i > 0 {
+			logger.Info("Failing over to fallback model", zap.String("model", primary.Name), zap.String("fallback", target.spec.Name), zap.Error(err))
+			servedBy(ctx, target.spec)
+			if notify, ok := ctx.Value(failoverNoticeKey{}).(func(Failover)); ok {
+				notify(Failover{Model: primary.Name, Fallback: target.spec.Name, Err: err})
+			}
+		}
+
+		emitted := false
+		err = call(target, &emitted)
+		// a call the caller gave up on says nothing about the provider
+		if ctx.Err() != nil {
+			target.breaker.abandon()
+			return err
+		}
+		if target.breaker.record(err, time.Now()) {
+			logger.Error("Provider circuit opened", zap.String("provider", target.spec.Provider), zap.Duration("cooldown", breakerCooldown), zap.Error(err))
+		}
+		if err == nil || emitted || !transient(err) {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,218 @@
+package llmrouter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type scriptedModel struct {
+	llm.LLMClient
+	reply string
+	errs  []error // returned by successive calls, after emitting reply if emit is set
+	emit  bool
+	calls int
+}
+
+func (m *scriptedModel) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	m.calls++
+	var err error
+	if len(m.errs) > 0 {
+		err, m.errs = m.errs[0], m.errs[1:]
+	}
+	if err != nil && !m.emit {
+		return err
+	}
+	if cbErr := callback(m.reply); cbErr != nil {
+		return cbErr
+	}
+	return err
+}
+
+func statusError(code int) error {
+	return fmt.Errorf("API request failed with status %d: {}", code)
+}
+
+func chain(models ...*scriptedModel) *failoverClient {
+	client := &failoverClient{LLMClient: models[0]}
+	for i, model := range models {
+		spec := ModelSpec{Name: fmt.Sprintf("model-%d", i), Provider: fmt.Sprintf("provider-%d", i)}
+		client.chain = append(client.chain, failoverTarget{spec: spec, client: model, breaker: &breaker{}})
+	}
+	return client
+}
+
+func generate(ctx context.Context, client llm.LLMClient) (string, error) {
+	var answer string
+	err := client.GenerateInference(ctx, nil, func(chunk string) error {
+		answer += chunk
+		return nil
+	})
+	return answer, err
+}
+
+func TestTransient(t *testing.T) {
+	assert.True(t, transient(statusError(429)))
+	assert.True(t, transient(statusError(503)))
+	assert.True(t, transient(fmt.Errorf("attempt: %w", context.DeadlineExceeded)))
+	assert.False(t, transient(statusError(400)))
+	assert.False(t, transient(errors.New("error unmarshaling response")))
+	assert.False(t, transient(nil))
+}
+
+func TestFailsOverOnTransientErrors(t *testing.T) {
+	primary := &scriptedModel{reply: "primary", errs: []error{statusError(529)}}
+	fallback := &scriptedModel{reply: "fallback"}
+	client := chain(primary, fallback)
+
+	var notices []Failover
+	ctx := WithFailoverNotice(t.Context(), func(f Failover) { notices = append(notices, f) })
+
+	answer, err := generate(ctx, client)
+	require.NoError(t, err)
+	assert.Equal(t, "fallback", answer)
+	require.Len(t, notices, 1)
+	assert.Equal(t, "model-0", notices[0].Model)
+	assert.Equal(t, "model-1", notices[0].Fallback)
+
+	// the next call goes back to the primary
+	answer, err = generate(ctx, client)
+	require.NoError(t, err)
+	assert.Equal(t, "primary", answer)
+}
+
+func TestKeepsErrorsThatWouldRepeat(t *testing.T) {
+	primary := &scriptedModel{errs: []error{statusError(400)}}
+	fallback := &scriptedModel{reply: "fallback"}
+
+	_, err := generate(t.Context(), chain(primary, fallback))
+	require.Error(t, err)
+	assert.Zero(t, fallback.calls)
+}
+
+func TestNeverRepeatsStreamedOutput(t *testing.T) {
+	primary := &scriptedModel{reply: "partial", emit: true, errs: []error{statusError(502)}}
+	fallback := &scriptedModel{reply: "fallback"}
+
+	answer, err := generate(t.Context(), chain(primary, fallback))
+	require.Error(t, err)
+	assert.Equal(t, "partial", answer)
+	assert.Zero(t, fallback.calls)
+}
+
+func TestCircuitOpensAndProbes(t *testing.T) {
+	var errs []error
+	for range breakerThreshold {
+		errs = append(errs, statusError(429))
+	}
+	primary := &scriptedModel{reply: "primary", errs: errs}
+	fallback := &scriptedModel{reply: "fallback"}
+	client := chain(primary, fallback)
+
+	for range breakerThreshold {
+		_, err := generate(t.Context(), client)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, breakerThreshold, primary.calls)
+
+	// open: the primary is skipped
+	answer, err := generate(t.Context(), client)
+	require.NoError(t, err)
+	assert.Equal(t, "fallback", answer)
+	assert.Equal(t, breakerThreshold, primary.calls)
+
+	// after the cooldown one probe goes through and closes the circuit
+	b := client.chain[0].breaker
+	b.openUntil = time.Now().Add(-time.Second)
+	answer, err = generate(t.Context(), client)
+	require.NoError(t, err)
+	assert.Equal(t, "primary", answer)
+	assert.True(t, b.allow(time.Now()))
+}
+
+func TestAllCircuitsOpen(t *testing.T) {
+	client := chain(&scriptedModel{}, &scriptedModel{})
+	for _, target := range client.chain {
+		target.breaker.failures = breakerThreshold
+		target.breaker.openUntil = time.Now().Add(time.Minute)
+	}
+
+	_, err := generate(t.Context(), client)
+	assert.ErrorIs(t, err, ErrProviderUnavailable)
+}
+
+func TestBillsTheServingModel(t *testing.T) {
+	primary := &scriptedModel{errs: []error{statusError(500)}}
+	fallback := &scriptedModel{reply: "fallback answer"}
+	client := chain(primary, fallback)
+
+	meter := NewMeter()
+	_, err := generate(t.Context(), meter.Wrap(client.chain[0].spec, client))
+	require.NoError(t, err)
+
+	usage := meter.Usage()
+	assert.Contains(t, usage, UsageKey{Provider: "provider-1", Model: "model-1"})
+	assert.NotContains(t, usage, UsageKey{Provider: "provider-0", Model: "model-0"})
+}
+
+func TestFallbackChain(t *testing.T) {
+	r := &Registry{
+		specs: map[string]ModelSpec{
+			"claude":  {Name: "claude", Provider: "anthropic"},
+			"gpt":     {Name: "gpt", Provider: "openai"},
+			"ollama":  {Name: "ollama", Provider: "ollama"},
+			"ollama2": {Name: "ollama2", Provider: "ollama"},
+		},
+		fallbacks: map[string]string{"claude": "gpt", "gpt": "claude", "ollama": "claude", "ollama2": "ollama"},
+	}
+
+	assert.Equal(t, []string{"gpt"}, r.fallbackChain("claude"), "cycles end")
+	assert.Empty(t, r.fallbackChain("ollama"), "local models stay local")
+	assert.Equal(t, []string{"ollama"}, r.fallbackChain("ollama2"))
+}
+
+func TestClientFallbacksFollowAllowlists(t *testing.T) {
+	r := &Registry{
+		specs: map[string]ModelSpec{
+			"claude":       {Name: "claude", Provider: "anthropic"},
+			"claude-haiku": {Name: "claude-haiku", Provider: "anthropic"},
+			"gpt-4o-mini":  {Name: "gpt-4o-mini", Provider: "openai"},
+		},
+		fallbacks: map[string]string{"claude": "gpt-4o-mini", "gpt-4o-mini": "claude-haiku"},
+		direct: map[string]llm.LLMClient{
+			"claude": &scriptedModel{}, "claude-haiku": &scriptedModel{}, "gpt-4o-mini": &scriptedModel{},
+		},
+		breakers: map[string]*breaker{},
+	}
+	names := func(client llm.LLMClient) []string {
+		var names []string
+		for _, target := range client.(*failoverClient).chain {
+			names = append(names, target.spec.Name)
+		}
+		return names
+	}
+
+	client, err := r.Client("claude")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"claude", "gpt-4o-mini", "claude-haiku"}, names(client))
+
+	anthropicOnly := []string{"claude", "claude-haiku"}
+	client, err = r.Client("claude", anthropicOnly, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"claude", "claude-haiku"}, names(client), "the tenant's allowlist removes the OpenAI fallback")
+
+	client, err = r.Client("claude", nil, []string{"claude"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"claude"}, names(client), "and the user's removes the rest")
+
+	name, client, err := r.Resolve("claude", anthropicOnly)
+	require.NoError(t, err)
+	assert.Equal(t, "claude", name)
+	assert.Equal(t, []string{"claude", "claude-haiku"}, names(client))
+}
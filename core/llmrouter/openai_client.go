@@ -0,0 +1,307 @@
+package llmrouter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/ollama/ollama/api"
+)
+
+const (
+	openAIChatCompletionsURL = "https://api.openai.com/v1/chat/completions"
+	groqChatCompletionsURL   = "https://api.groq.com/openai/v1/chat/completions"
+	defaultAzureAPIVersion   = "2024-10-21"
+)
+
+// OpenAIClient talks to the OpenAI chat completions API, to an Azure OpenAI deployment
+// or to Groq, which serve the same wire format. It mirrors agent-boot's Groq client,
+// and streams the answer as it is generated when streaming is requested.
+type OpenAIClient struct {
+	authHeader string
+	authValue  string
+	httpClient *http.Client
+	url        string
+	model      string
+}
+
+// NewOpenAIClient reads OPENAI_API_KEY. Unlike agent-boot's constructors it returns
+// an error instead of exiting when the key is missing.
+func NewOpenAIClient(model string) (*OpenAIClient, error) {
+	apiKey := os.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		return nil, fmt.Errorf("OPENAI_API_KEY environment variable is not set")
+	}
+
+	return &OpenAIClient{
+		authHeader: "Authorization",
+		authValue:  "Bearer " + apiKey,
+		httpClient: &http.Client{},
+		url:        openAIChatCompletionsURL,
+		model:      model,
+	}, nil
+}
+
+// NewAzureOpenAIClient targets the Azure OpenAI deployment named by deployment. It reads
+// AZURE_OPENAI_API_KEY, AZURE_OPENAI_ENDPOINT (https://<resource>.openai.azure.com) and
+// optionally AZURE_OPENAI_API_VERSION.
+func NewAzureOpenAIClient(deployment string) (*OpenAIClient, error) {
+	apiKey := os.Getenv("AZURE_OPENAI_API_KEY")
+	endpoint := strings.TrimSuffix(os.Getenv("AZURE_OPENAI_ENDPOINT"), "/")
+	if apiKey == "" || endpoint == "" {
+		return nil, fmt.Errorf("AZURE_OPENAI_API_KEY and AZURE_OPENAI_ENDPOINT environment variables must be set")
+	}
+
+	apiVersion := os.Getenv("AZURE_OPENAI_API_VERSION")
+	if apiVersion == "" {
+		apiVersion = defaultAzureAPIVersion
+	}
+
+	return &OpenAIClient{
+		authHeader: "api-key",
+		authValue:  apiKey,
+		httpClient: &http.Client{},
+		url:        endpoint + "/openai/deployments/" + url.PathEscape(deployment) + "/chat/completions?api-version=" + url.QueryEscape(apiVersion),
+		model:      deployment,
+	}, nil
+}
+
+// NewGroqClient reads GROQ_API_KEY. It replaces agent-boot's Groq client, which
+// cannot read a streamed response.
+func NewGroqClient(model string) (*OpenAIClient, error) {
+	apiKey := os.Getenv("GROQ_API_KEY")
+	if apiKey == "" {
+		return nil, fmt.Errorf("GROQ_API_KEY environment variable is not set")
+	}
+
+	return &OpenAIClient{
+		authHeader: "Authorization",
+		authValue:  "Bearer " + apiKey,
+		httpClient: &http.Client{},
+		url:        groqChatCompletionsURL,
+		model:      model,
+	}, nil
+}
+
+func (c *OpenAIClient) Capabilities() llm.Capability {
+	// Every current chat model supports function calling except the o1-mini/preview family.
+	if strings.HasPrefix(c.model, "o1-mini") || strings.HasPrefix(c.model, "o1-preview") {
+		return 0
+	}
+	return llm.NativeToolCalling
+}
+
+func (c *OpenAIClient) GetModel() string {
+	return c.model
+}
+
+func (c *OpenAIClient) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	return c.GenerateInferenceWithTools(ctx, messages, callback, nil, opts...)
+}
+
+func (c *OpenAIClient) GenerateInferenceWithTools(
+	ctx context.Context,
+	messages []llm.Message,
+	contentCallback func(chunk string) error,
+	toolCallback func(toolCalls []api.ToolCall) error,
+	opts ...llm.LLMOption,
+) error {
+	s := resolveSettings(opts)
+
+	request := openAIRequest{
+		Model:       c.model,
+		Messages:    toOpenAIMessages(s.system, messages),
+		Temperature: s.temperature,
+		MaxTokens:   s.maxTokens,
+	}
+	if toolCallback != nil && len(s.tools) > 0 {
+		request.Tools = toOpenAITools(s.tools)
+		request.ToolChoice = "auto"
+	} else if s.stream {
+		// tool calls arrive whole; only text is streamed
+		request.Stream = true
+		request.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("error marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(c.authHeader, c.authValue)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if request.Stream && resp.StatusCode == http.StatusOK {
+		return c.readStream(ctx, resp.Body, contentCallback)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response openAIResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("error unmarshaling response: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return fmt.Errorf("no choices in response")
+	}
+	reportUsage(ctx, response.Usage.PromptTokens, response.Usage.CompletionTokens)
+
+	choice := response.Choices[0]
+	if len(choice.Message.ToolCalls) > 0 && toolCallback != nil {
+		toolCalls := make([]api.ToolCall, len(choice.Message.ToolCalls))
+		for i, tc := range choice.Message.ToolCalls {
+			var args map[string]any
+			if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+				return fmt.Errorf("error parsing tool call arguments: %w", err)
+			}
+			toolCalls[i] = api.ToolCall{
+				Function: api.ToolCallFunction{Name: tc.Function.Name, Arguments: args},
+			}
+		}
+		return toolCallback(toolCalls)
+	}
+
+	if choice.Message.Content != "" && contentCallback != nil {
+		return contentCallback(choice.Message.Content)
+	}
+	return nil
+}
+
+// readStream passes each text delta to contentCallback as it arrives. The provider's
+// token counts come in the last event.
+func (c *OpenAIClient) readStream(ctx context.Context, body io.Reader, contentCallback func(chunk string) error) error {
+	return readEvents(body, func(_, data string) error {
+		if data == "[DONE]" {
+			return nil
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("error unmarshaling stream event: %w", err)
+		}
+		if chunk.Usage != nil {
+			reportUsage(ctx, chunk.Usage.PromptTokens, chunk.Usage.CompletionTokens)
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" && contentCallback != nil {
+				if err := contentCallback(choice.Delta.Content); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+type openAIRequest struct {
+	Model       string          `json:"model"`
+	Messages    []openAIMessage `json:"messages"`
+	Temperature float64         `json:"temperature,omitempty"`
+	MaxTokens   int             `json:"max_tokens,omitempty"`
+	Tools       []openAITool    `json:"tools,omitempty"`
+	ToolChoice  string          `json:"tool_choice,omitempty"`
+
+	Stream        bool                 `json:"stream,omitempty"`
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []openAIToolCall `json:"tool_calls,omitempty"`
+}
+
+type openAITool struct {
+	Type     string             `json:"type"`
+	Function openAIToolFunction `json:"function"`
+}
+
+type openAIToolFunction struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Parameters  any    `json:"parameters"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+	Usage openAIUsage `json:"usage"`
+}
+
+// openAIStreamChunk is one event of a streamed response; only the last one has usage.
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+func toOpenAIMessages(system string, messages []llm.Message) []openAIMessage {
+	out := make([]openAIMessage, 0, len(messages)+1)
+	if system != "" {
+		out = append(out, openAIMessage{Role: "system", Content: system})
+	}
+	for _, m := range messages {
+		out = append(out, openAIMessage{Role: m.Role, Content: m.Content})
+	}
+	return out
+}
+
+func toOpenAITools(tools []api.Tool) []openAITool {
+	out := make([]openAITool, 0, len(tools))
+	for _, t := range tools {
+		out = append(out, openAITool{
+			Type: "function",
+			Function: openAIToolFunction{
+				Name:        t.Function.Name,
+				Description: t.Function.Description,
+				Parameters:  t.Function.Parameters,
+			},
+		})
+	}
+	return out
+}
@@ -0,0 +1,153 @@
+package llmrouter
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/ollama/ollama/api"
+	"go.uber.org/zap"
+)
+
+const retryBaseDelay = 500 * time.Millisecond
+
+// CallPolicy bounds each call to a provider. A zero Timeout means no deadline beyond
+// the caller's context.
+type CallPolicy struct {
+	Timeout    time.Duration
+	MaxRetries int
+}
+
+// ParseCallPolicies reads provider:value entries, e.g. timeouts "openai:60s" and
+// retries "openai:2", into a policy per provider.
+func ParseCallPolicies(timeouts, retries []string) (map[string]CallPolicy, error) {
+	policies := make(map[string]CallPolicy)
+
+	for _, entry := range timeouts {
+		provider, value, err := parseProviderEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout for %s: %w", provider, err)
+		}
+		policy := policies[provider]
+		policy.Timeout = timeout
+		policies[provider] = policy
+	}
+
+	for _, entry := range retries {
+		provider, value, err := parseProviderEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		maxRetries, err := strconv.Atoi(value)
+		if err != nil || maxRetries < 0 {
+			return nil, fmt.Errorf("invalid retry count for %s: %q", provider, value)
+		}
+		policy := policies[provider]
+		policy.MaxRetries = maxRetries
+		policies[provider] = policy
+	}
+
+	return policies, nil
+}
+
+func parseProviderEntry(entry string) (string, string, error) {
+	provider, value, ok := strings.Cut(strings.TrimSpace(entry), ":")
+	if !ok {
+		return "", "", fmt.Errorf("expected provider:value, got %q", entry)
+	}
+	if _, known := providerEnv[provider]; !known {
+		return "", "", fmt.Errorf("unknown provider %q", provider)
+	}
+	return provider, strings.TrimSpace(value), nil
+}
+
+// policyClient applies a CallPolicy to a client. A call that failed transiently is
+// retried only if it produced no output yet, so callers never see a partial answer twice.
+type policyClient struct {
+	llm.LLMClient
+	provider string
+	policy   CallPolicy
+}
+
+func withPolicy(client llm.LLMClient, provider string, policy CallPolicy) llm.LLMClient {
+	if policy == (CallPolicy{}) {
+		return client
+	}
+	return &policyClient{LLMClient: client, provider: provider, policy: policy}
+}
+
+func (c *policyClient) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	return c.do(ctx, func(ctx context.Context, emitted *bool) error {
+		return c.LLMClient.GenerateInference(ctx, messages, tracked(callback, emitted), opts...)
+	})
+}
+
+func (c *policyClient) GenerateInferenceWithTools(ctx context.Context, messages []llm.Message, contentCallback func(chunk string) error, toolCallback func(toolCalls []api.ToolCall) error, opts ...llm.LLMOption) error {
+	return c.do(ctx, func(ctx context.Context, emitted *bool) error {
+		return c.LLMClient.GenerateInferenceWithTools(ctx, messages, tracked(contentCallback, emitted), trackedTools(toolCallback, emitted), opts...)
+	})
+}
+
+func (c *policyClient) do(ctx context.Context, call func(ctx context.Context, emitted *bool) error) error {
+	var err error
+	for attempt := 0; attempt <= c.policy.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff(attempt)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			logger.Info("Retrying LLM call", zap.String("provider", c.provider), zap.Int("attempt", attempt), zap.Error(err))
+		}
+
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if c.policy.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, c.policy.Timeout)
+		}
+
+		emitted := false
+		err = call(attemptCtx, &emitted)
+		cancel()
+
+		if err == nil || emitted || ctx.Err() != nil || !transient(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// backoff doubles the delay with each attempt and jitters it, so callers that failed
+// together do not retry together.
+func backoff(attempt int) time.Duration {
+	delay := retryBaseDelay << (attempt - 1)
+	return delay/2 + rand.N(delay/2)
+}
+
+func tracked(callback func(chunk string) error, emitted *bool) func(chunk string) error {
+	if callback == nil {
+		return nil
+	}
+	return func(chunk string) error {
+		*emitted = true
+		return callback(chunk)
+	}
+}
+
+func trackedTools(callback func(toolCalls []api.ToolCall) error, emitted *bool) func(toolCalls []api.ToolCall) error {
+	if callback == nil {
+		return nil
+	}
+	return func(toolCalls []api.ToolCall) error {
+		*emitted = true
+		return callback(toolCalls)
+	}
+}
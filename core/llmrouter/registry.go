@@ -0,0 +1,321 @@
+package llmrouter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"go.uber.org/zap"
+)
+
+// Supported providers and the environment variable each one needs.
+var providerEnv = map[string]string{
+	"anthropic":    "ANTHROPIC_API_KEY",
+	"azure-openai": "AZURE_OPENAI_API_KEY",
+	"groq":         "GROQ_API_KEY",
+	"ollama":       "OLLAMA_HOST",
+	"openai":       "OPENAI_API_KEY",
+}
+
+// localProvider is the only provider that runs inside the deployment.
+const localProvider = "ollama"
+
+var ErrNoModelAvailable = errors.New("no model available")
+
+// ModelSpec is one entry of the `models` config list, written as name=provider:model,
+// e.g. claude=anthropic:claude-3-5-haiku-20241022.
+type ModelSpec struct {
+	Name     string
+	Provider string
+	Model    string
+}
+
+// OfflineModels names the local models used for tenants in offline mode, where no
+// prompt may leave the deployment.
+type OfflineModels struct {
+	Model        string
+	MiniModel    string
+	ToolSelector string
+}
+
+// Registry maps the model names clients may request to LLM clients. Clients are built
+// on first use, so a provider without credentials only disables its own models.
+type Registry struct {
+	specs        map[string]ModelSpec
+	defaultModel string
+	offline      OfflineModels
+	policies     map[string]CallPolicy
+	fallbacks    map[string]string
+
+	mu       sync.Mutex
+	direct   map[string]llm.LLMClient // without failover
+	breakers map[string]*breaker      // per provider
+}
+
+func ProvideRegistry(ccfg *appconfig.AppConfig) *Registry {
+	r := &Registry{
+		specs:        make(map[string]ModelSpec),
+		defaultModel: ccfg.DefaultModel,
+		offline: OfflineModels{
+			Model:        ccfg.OfflineModel,
+			MiniModel:    ccfg.OfflineMiniModel,
+			ToolSelector: ccfg.OfflineToolSelector,
+		},
+		direct:   make(map[string]llm.LLMClient),
+		breakers: make(map[string]*breaker),
+	}
+
+	policies, err := ParseCallPolicies(ccfg.ProviderTimeouts, ccfg.ProviderRetries)
+	if err != nil {
+		logger.Error("Ignoring invalid provider timeout/retry settings", zap.Error(err))
+	}
+	r.policies = policies
+
+	fallbacks, err := ParseModelFallbacks(ccfg.ModelFallbacks)
+	if err != nil {
+		logger.Error("Ignoring invalid model fallbacks", zap.Error(err))
+	}
+	r.fallbacks = fallbacks
+
+	for _, entry := range ccfg.Models {
+		spec, err := ParseModelSpec(entry)
+		if err != nil {
+			logger.Error("Ignoring invalid model entry", zap.String("entry", entry), zap.Error(err))
+			continue
+		}
+		r.specs[spec.Name] = spec
+	}
+
+	if _, ok := r.specs[r.defaultModel]; !ok {
+		logger.Error("Default model is not registered", zap.String("defaultModel", r.defaultModel))
+	}
+	for _, name := range []string{r.offline.Model, r.offline.MiniModel, r.offline.ToolSelector} {
+		if !r.IsLocal(name) {
+			logger.Error("Offline model is not a registered local model", zap.String("model", name))
+		}
+	}
+	return r
+}
+
+func ParseModelSpec(entry string) (ModelSpec, error) {
+	name, target, ok := strings.Cut(strings.TrimSpace(entry), "=")
+	if !ok {
+		return ModelSpec{}, fmt.Errorf("expected name=provider:model")
+	}
+	provider, model, ok := strings.Cut(target, ":") // model ids may contain ':' themselves
+	if !ok || name == "" || model == "" {
+		return ModelSpec{}, fmt.Errorf("expected name=provider:model")
+	}
+	if _, known := providerEnv[provider]; !known {
+		return ModelSpec{}, fmt.Errorf("unknown provider %q", provider)
+	}
+
+	return ModelSpec{Name: strings.TrimSpace(name), Provider: provider, Model: strings.TrimSpace(model)}, nil
+}
+
+// Resolve returns the client for the requested model name. A model is used only if
+// it is registered, its provider is configured, and it appears in every non-empty
+// allowlist (tenant, user, ...). Otherwise the default model is used under the same rules.
+func (r *Registry) Resolve(requested string, allowlists ...[]string) (string, llm.LLMClient, error) {
+	return r.resolve(requested, r.defaultModel, allowlists)
+}
+
+// ResolveOffline is Resolve restricted to local models, falling back to the offline
+// model instead of the default one.
+func (r *Registry) ResolveOffline(requested string, allowlists ...[]string) (string, llm.LLMClient, error) {
+	return r.resolve(requested, r.offline.Model, append(allowlists, r.LocalModels()))
+}
+
+func (r *Registry) resolve(requested, fallback string, allowlists [][]string) (string, llm.LLMClient, error) {
+	for _, name := range []string{requested, fallback} {
+		if name == "" || !allowed(name, allowlists) {
+			continue
+		}
+
+		client, err := r.Client(name, allowlists...)
+		if err != nil {
+			logger.Error("Model unavailable", zap.String("model", name), zap.Error(err))
+			continue
+		}
+
+		if name != requested && requested != "" {
+			logger.Info("Falling back to default model", zap.String("requested", requested), zap.String("model", name))
+		}
+		return name, client, nil
+	}
+
+	return "", nil, ErrNoModelAvailable
+}
+
+// Client returns a client for a registered model. Calls fail over along the model's
+// fallbacks when its provider errors or is down, skipping fallbacks missing from any
+// non-empty allowlist, as Resolve does, so a tenant's prompts never reach a provider it
+// doesn't allow. The chain is built per call, since allowlists differ between tenants
+// and users; the models' own clients and their providers' breakers are shared.
+func (r *Registry) Client(name string, allowlists ...[]string) (llm.LLMClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	primary, err := r.directClient(name)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := []failoverTarget{r.target(name, primary)}
+	for _, fallback := range r.fallbackChain(name) {
+		if !allowed(fallback, allowlists) {
+			continue
+		}
+		client, err := r.directClient(fallback)
+		if err != nil {
+			logger.Error("Fallback model unavailable", zap.String("model", name), zap.String("fallback", fallback), zap.Error(err))
+			continue
+		}
+		chain = append(chain, r.target(fallback, client))
+	}
+
+	return &failoverClient{LLMClient: primary, chain: chain}, nil
+}
+
+// directClient returns a model's own client, without failover. r.mu must be held.
+func (r *Registry) directClient(name string) (llm.LLMClient, error) {
+	spec, ok := r.specs[name]
+	if !ok {
+		return nil, fmt.Errorf("model %q is not registered", name)
+	}
+	if client, ok := r.direct[name]; ok {
+		return client, nil
+	}
+
+	client, err := newClient(spec)
+	if err != nil {
+		return nil, err
+	}
+	client = withPolicy(client, spec.Provider, r.policies[spec.Provider])
+	r.direct[name] = client
+	return client, nil
+}
+
+func (r *Registry) target(name string, client llm.LLMClient) failoverTarget {
+	spec := r.specs[name]
+	b, ok := r.breakers[spec.Provider]
+	if !ok {
+		b = &breaker{}
+		r.breakers[spec.Provider] = b
+	}
+	return failoverTarget{spec: spec, client: client, breaker: b}
+}
+
+// fallbackChain follows a model's fallbacks, then theirs, stopping at a cycle. A local
+// model only fails over to other local models, as it may serve offline tenants.
+func (r *Registry) fallbackChain(name string) []string {
+	seen := map[string]bool{name: true}
+	var chain []string
+	for next := r.fallbacks[name]; next != "" && !seen[next]; next = r.fallbacks[next] {
+		seen[next] = true
+		if r.IsLocal(name) && !r.IsLocal(next) {
+			continue
+		}
+		chain = append(chain, next)
+	}
+	return chain
+}
+
+// Spec returns the registration of a model name.
+func (r *Registry) Spec(name string) (ModelSpec, bool) {
+	spec, ok := r.specs[name]
+	return spec, ok
+}
+
+// Models lists the registered model names.
+func (r *Registry) Models() []string {
+	names := make([]string, 0, len(r.specs))
+	for name := range r.specs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (r *Registry) DefaultModel() string {
+	return r.defaultModel
+}
+
+func (r *Registry) Offline() OfflineModels {
+	return r.offline
+}
+
+// IsLocal reports whether name is a registered model served inside the deployment.
+func (r *Registry) IsLocal(name string) bool {
+	spec, ok := r.specs[name]
+	return ok && spec.Provider == localProvider
+}
+
+// LocalModels lists the registered models served inside the deployment.
+func (r *Registry) LocalModels() []string {
+	var names []string
+	for _, name := range r.Models() {
+		if r.IsLocal(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func allowed(name string, allowlists [][]string) bool {
+	for _, list := range allowlists {
+		if len(list) > 0 && !slices.Contains(list, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// agent-boot's constructors exit the process when their credentials are missing,
+// so the environment is checked before calling them.
+func newClient(spec ModelSpec) (llm.LLMClient, error) {
+	if env := providerEnv[spec.Provider]; os.Getenv(env) == "" {
+		return nil, fmt.Errorf("%s is not set", env)
+	}
+
+	var (
+		client llm.LLMClient
+		err    error
+	)
+	switch spec.Provider {
+	case "anthropic":
+		client, err = NewAnthropicClient(spec.Model)
+	case "groq":
+		client, err = NewGroqClient(spec.Model)
+	case "ollama":
+		client = llm.NewOllamaClient(spec.Model)
+	case "openai":
+		client, err = NewOpenAIClient(spec.Model)
+	case "azure-openai":
+		client, err = NewAzureOpenAIClient(spec.Model)
+	default:
+		return nil, fmt.Errorf("unknown provider %q", spec.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return streamingClient{client}, nil
+}
+
+// The clients buffer the whole answer unless streaming is requested, and the agent
+// never requests it. Every text call streams, so the answer reaches the user token by
+// token as it is generated; callers that need the whole text collect the deltas.
+type streamingClient struct {
+	llm.LLMClient
+}
+
+func (c streamingClient) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	return c.LLMClient.GenerateInference(ctx, messages, callback, append(opts, WithStreaming(true))...)
+}
@@ -0,0 +1,103 @@
+package llmrouter
+
+import (
+	"context"
+	"sync"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/ollama/ollama/api"
+)
+
+// settings is what a call's options set. llm.LLMSettings keeps its fields unexported,
+// so the options below record their values here as they are applied.
+type settings struct {
+	temperature float64
+	maxTokens   int
+	system      string
+	stream      bool
+	tools       []api.Tool
+}
+
+// captures maps the llm.LLMSettings that resolveSettings is applying options to onto
+// the settings they are recorded in.
+var captures sync.Map
+
+// capture wraps an agent-boot option so it also records its value when resolved here.
+// Agent-boot's own clients see the option unchanged.
+func capture(opt llm.LLMOption, record func(*settings)) llm.LLMOption {
+	return func(raw *llm.LLMSettings) {
+		opt(raw)
+		if s, ok := captures.Load(raw); ok {
+			record(s.(*settings))
+		}
+	}
+}
+
+// WithTemperature is llm.WithTemperature, readable by this package's clients.
+func WithTemperature(temperature float64) llm.LLMOption {
+	return capture(llm.WithTemperature(temperature), func(s *settings) { s.temperature = temperature })
+}
+
+// WithMaxTokens is llm.WithMaxTokens, readable by this package's clients.
+func WithMaxTokens(tokens int) llm.LLMOption {
+	return capture(llm.WithMaxTokens(tokens), func(s *settings) { s.maxTokens = tokens })
+}
+
+// WithSystemPrompt is llm.WithSystemPrompt, readable by this package's clients.
+func WithSystemPrompt(prompt string) llm.LLMOption {
+	return capture(llm.WithSystemPrompt(prompt), func(s *settings) { s.system = prompt })
+}
+
+// WithStreaming is llm.WithStreaming, readable by this package's clients.
+func WithStreaming(stream bool) llm.LLMOption {
+	return capture(llm.WithStreaming(stream), func(s *settings) { s.stream = stream })
+}
+
+// WithTools is llm.WithTools, readable by this package's clients.
+func WithTools(tools []api.Tool) llm.LLMOption {
+	return capture(llm.WithTools(tools), func(s *settings) { s.tools = tools })
+}
+
+// resolveSettings applies opts and returns what they set. Options made with llm's own
+// constructors set nothing here; see WithOptions for calls made inside agent-boot.
+func resolveSettings(opts []llm.LLMOption) settings {
+	raw := new(llm.LLMSettings)
+	s := new(settings)
+	captures.Store(raw, s)
+	defer captures.Delete(raw)
+
+	for _, opt := range opts {
+		opt(raw)
+	}
+	return *s
+}
+
+// DescribeOptions returns the system prompt and the names of the tools that opts set on
+// a call, for callers outside this package that record calls.
+func DescribeOptions(opts []llm.LLMOption) (system string, tools []string) {
+	s := resolveSettings(opts)
+	for _, tool := range s.tools {
+		tools = append(tools, tool.Function.Name)
+	}
+	return s.system, tools
+}
+
+// WithOptions returns client with opts set ahead of every call's own options. Agent-boot
+// builds the options of the calls it makes with llm's constructors, so the clients
+// handed to an agent restate them with the ones above.
+func WithOptions(client llm.LLMClient, opts func() []llm.LLMOption) llm.LLMClient {
+	return optionsClient{LLMClient: client, opts: opts}
+}
+
+type optionsClient struct {
+	llm.LLMClient
+	opts func() []llm.LLMOption
+}
+
+func (c optionsClient) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	return c.LLMClient.GenerateInference(ctx, messages, callback, append(c.opts(), opts...)...)
+}
+
+func (c optionsClient) GenerateInferenceWithTools(ctx context.Context, messages []llm.Message, contentCallback func(chunk string) error, toolCallback func(toolCalls []api.ToolCall) error, opts ...llm.LLMOption) error {
+	return c.LLMClient.GenerateInferenceWithTools(ctx, messages, contentCallback, toolCallback, append(c.opts(), opts...)...)
+}
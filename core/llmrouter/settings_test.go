@@ -0,0 +1,56 @@
+package llmrouter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/ollama/ollama/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSettings(t *testing.T) {
+	tool := api.Tool{Type: "function", Function: api.ToolFunction{Name: "search"}}
+
+	s := resolveSettings([]llm.LLMOption{
+		WithTemperature(0.2),
+		WithMaxTokens(32),
+		WithSystemPrompt("Be brief."),
+		WithStreaming(true),
+		WithTools([]api.Tool{tool}),
+		WithSystemPrompt("Be briefer."),
+	})
+
+	assert.Equal(t, 0.2, s.temperature)
+	assert.Equal(t, 32, s.maxTokens)
+	assert.Equal(t, "Be briefer.", s.system)
+	assert.True(t, s.stream)
+	assert.Equal(t, []api.Tool{tool}, s.tools)
+}
+
+func TestWithOptionsRestatesOptions(t *testing.T) {
+	fake := &recordingClient{}
+	client := WithOptions(fake, func() []llm.LLMOption {
+		return []llm.LLMOption{WithSystemPrompt("Answer from the context."), WithMaxTokens(2000)}
+	})
+
+	// llm's own options can't be read, so the restated ones stand; the call's own
+	// readable options still take precedence
+	require.NoError(t, client.GenerateInference(t.Context(), nil, func(string) error { return nil },
+		llm.WithSystemPrompt("ignored"), WithMaxTokens(500)))
+
+	system, _ := DescribeOptions(fake.opts)
+	assert.Equal(t, "Answer from the context.", system)
+	assert.Equal(t, 500, resolveSettings(fake.opts).maxTokens)
+}
+
+type recordingClient struct {
+	llm.LLMClient
+	opts []llm.LLMOption
+}
+
+func (c *recordingClient) GenerateInference(_ context.Context, _ []llm.Message, _ func(string) error, opts ...llm.LLMOption) error {
+	c.opts = opts
+	return nil
+}
@@ -0,0 +1,44 @@
+package llmrouter
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// maxEventSize bounds one server-sent event line; deltas are small, but a final event
+// may carry a whole message.
+const maxEventSize = 1 << 20
+
+// readEvents calls handle with each server-sent event's type and data as it arrives,
+// until the stream ends or handle fails. Events without a type have event "".
+func readEvents(body io.Reader, handle func(event, data string) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+
+	var event string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				if err := handle(event, strings.Join(data, "\n")); err != nil {
+					return err
+				}
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(data) > 0 {
+		return handle(event, strings.Join(data, "\n"))
+	}
+	return nil
+}
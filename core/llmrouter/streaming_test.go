@@ -0,0 +1,119 @@
+package llmrouter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventServer answers every request with the given server-sent events and records
+// the request body.
+func eventServer(t *testing.T, events string, body *map[string]any) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(raw, body))
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, events)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func collect(t *testing.T, client llm.LLMClient, key UsageKey) ([]string, Usage, error) {
+	meter := NewMeter()
+	var deltas []string
+	err := meter.Wrap(ModelSpec{Provider: key.Provider, Name: key.Model}, streamingClient{client}).GenerateInference(t.Context(),
+		[]llm.Message{{Role: "user", Content: "What helps bruising?"}},
+		func(chunk string) error {
+			deltas = append(deltas, chunk)
+			return nil
+		})
+	return deltas, meter.Usage()[key], err
+}
+
+func TestOpenAIStreaming(t *testing.T) {
+	events := `data: {"choices":[{"delta":{"role":"assistant","content":""}}]}
+
+data: {"choices":[{"delta":{"content":"Arnica"}}]}
+
+data: {"choices":[{"delta":{"content":" helps."}}]}
+
+data: {"choices":[],"usage":{"prompt_tokens":12,"completion_tokens":3}}
+
+data: [DONE]
+
+`
+	var request map[string]any
+	srv := eventServer(t, events, &request)
+	client := &OpenAIClient{authHeader: "Authorization", authValue: "Bearer test", httpClient: srv.Client(), url: srv.URL, model: "gpt-4o"}
+
+	deltas, usage, err := collect(t, client, UsageKey{Provider: "openai", Model: "gpt-4o"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Arnica", " helps."}, deltas)
+	assert.Equal(t, Usage{PromptTokens: 12, CompletionTokens: 3}, usage)
+	assert.Equal(t, true, request["stream"])
+	assert.Equal(t, map[string]any{"include_usage": true}, request["stream_options"])
+}
+
+func TestAnthropicStreaming(t *testing.T) {
+	events := `event: message_start
+data: {"type":"message_start","message":{"usage":{"input_tokens":20,"output_tokens":1}}}
+
+event: ping
+data: {"type":"ping"}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Arnica"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" helps."}}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":4}}
+
+event: message_stop
+data: {"type":"message_stop"}
+`
+	var request map[string]any
+	srv := eventServer(t, events, &request)
+	client := &AnthropicClient{apiKey: "test", httpClient: srv.Client(), url: srv.URL, model: "claude"}
+
+	deltas, usage, err := collect(t, client, UsageKey{Provider: "anthropic", Model: "claude"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Arnica", " helps."}, deltas)
+	assert.Equal(t, Usage{PromptTokens: 20, CompletionTokens: 4}, usage)
+	assert.Equal(t, true, request["stream"])
+	assert.EqualValues(t, anthropicMaxTokens, request["max_tokens"])
+}
+
+func TestAnthropicStreamError(t *testing.T) {
+	events := `event: error
+data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}
+
+`
+	var request map[string]any
+	srv := eventServer(t, events, &request)
+	client := &AnthropicClient{apiKey: "test", httpClient: srv.Client(), url: srv.URL, model: "claude"}
+
+	_, _, err := collect(t, client, UsageKey{Provider: "anthropic", Model: "claude"})
+	require.Error(t, err)
+	assert.True(t, transient(err), "an overloaded stream fails over like an overloaded response")
+}
+
+func TestReadEvents(t *testing.T) {
+	var got []string
+	err := readEvents(strings.NewReader("event: a\ndata: one\ndata: two\n\n: comment\ndata: three"), func(event, data string) error {
+		got = append(got, event+"|"+data)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a|one\ntwo", "|three"}, got)
+}
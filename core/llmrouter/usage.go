@@ -0,0 +1,223 @@
+package llmrouter
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/ollama/ollama/api"
+)
+
+// Usage is the number of tokens sent to and generated by a model.
+type Usage struct {
+	PromptTokens     int64
+	CompletionTokens int64
+	// Estimated is set when at least one call was counted from text length because
+	// the provider's client does not return token counts.
+	Estimated bool
+}
+
+func (u Usage) Total() int64 {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// UsageKey identifies the registered model a Usage belongs to.
+type UsageKey struct {
+	Provider string
+	Model    string
+}
+
+// Meter totals the tokens used through the clients it wraps, typically for one agent
+// execution. Clients that see the provider's own counts report them exactly; agent-boot's
+// clients drop those counts, so their calls are estimated from the text exchanged.
+type Meter struct {
+	mu    sync.Mutex
+	usage map[UsageKey]Usage
+}
+
+func NewMeter() *Meter {
+	return &Meter{usage: make(map[UsageKey]Usage)}
+}
+
+// Wrap meters every call made through client under spec's provider and model name.
+func (m *Meter) Wrap(spec ModelSpec, client llm.LLMClient) llm.LLMClient {
+	return &meteredClient{
+		LLMClient: client,
+		meter:     m,
+		key:       UsageKey{Provider: spec.Provider, Model: spec.Name},
+	}
+}
+
+// Usage returns a copy of the totals per model.
+func (m *Meter) Usage() map[UsageKey]Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make(map[UsageKey]Usage, len(m.usage))
+	for key, usage := range m.usage {
+		out[key] = usage
+	}
+	return out
+}
+
+// Total returns the tokens used across all models.
+func (m *Meter) Total() Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var total Usage
+	for _, usage := range m.usage {
+		total.PromptTokens += usage.PromptTokens
+		total.CompletionTokens += usage.CompletionTokens
+		total.Estimated = total.Estimated || usage.Estimated
+	}
+	return total
+}
+
+func (m *Meter) add(key UsageKey, usage Usage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	total := m.usage[key]
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.Estimated = total.Estimated || usage.Estimated
+	m.usage[key] = total
+}
+
+type meteredClient struct {
+	llm.LLMClient
+	meter *Meter
+	key   UsageKey
+}
+
+func (c *meteredClient) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	call := &meteredCall{}
+	err := c.LLMClient.GenerateInference(context.WithValue(ctx, meteredCallKey{}, call), messages, call.content(callback), opts...)
+	c.record(ctx, call, call.usage(messages, opts))
+	return err
+}
+
+func (c *meteredClient) GenerateInferenceWithTools(ctx context.Context, messages []llm.Message, contentCallback func(chunk string) error, toolCallback func(toolCalls []api.ToolCall) error, opts ...llm.LLMOption) error {
+	call := &meteredCall{}
+	err := c.LLMClient.GenerateInferenceWithTools(context.WithValue(ctx, meteredCallKey{}, call), messages, call.content(contentCallback), call.tools(toolCallback), opts...)
+	c.record(ctx, call, call.usage(messages, opts))
+	return err
+}
+
+func (c *meteredClient) record(ctx context.Context, call *meteredCall, usage Usage) {
+	key := c.key
+	call.mu.Lock()
+	if call.served != nil {
+		key = *call.served
+	}
+	call.mu.Unlock()
+
+	c.meter.add(key, usage)
+	if slot, ok := ctx.Value(callUsageKey{}).(*Usage); ok {
+		*slot = usage
+	}
+}
+
+type callUsageKey struct{}
+
+// WithCallUsage asks the metered client that serves a call made with the returned
+// context to also store that call's usage in u once it returns.
+func WithCallUsage(ctx context.Context, u *Usage) context.Context {
+	return context.WithValue(ctx, callUsageKey{}, u)
+}
+
+type meteredCallKey struct{}
+
+// meteredCall collects the output of one call. Streaming clients call back once per
+// delta, so the output is summed rather than replaced.
+type meteredCall struct {
+	mu              sync.Mutex
+	completionBytes int
+	exact           *Usage
+	served          *UsageKey // set when a fallback model served the call
+}
+
+func (c *meteredCall) content(callback func(chunk string) error) func(chunk string) error {
+	if callback == nil {
+		return nil
+	}
+	return func(chunk string) error {
+		c.mu.Lock()
+		c.completionBytes += len(chunk)
+		c.mu.Unlock()
+		return callback(chunk)
+	}
+}
+
+func (c *meteredCall) tools(callback func(toolCalls []api.ToolCall) error) func(toolCalls []api.ToolCall) error {
+	if callback == nil {
+		return nil
+	}
+	return func(toolCalls []api.ToolCall) error {
+		encoded, _ := json.Marshal(toolCalls)
+		c.mu.Lock()
+		c.completionBytes += len(encoded)
+		c.mu.Unlock()
+		return callback(toolCalls)
+	}
+}
+
+func (c *meteredCall) usage(messages []llm.Message, opts []llm.LLMOption) Usage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.exact != nil {
+		return *c.exact
+	}
+
+	s := resolveSettings(opts)
+	promptBytes := len(s.system)
+	for _, m := range messages {
+		promptBytes += len(m.Content)
+	}
+	if len(s.tools) > 0 {
+		encoded, _ := json.Marshal(s.tools)
+		promptBytes += len(encoded)
+	}
+
+	return Usage{
+		PromptTokens:     estimateTokens(promptBytes),
+		CompletionTokens: estimateTokens(c.completionBytes),
+		Estimated:        true,
+	}
+}
+
+// reportUsage records the provider's own token counts for the call made with ctx,
+// replacing the estimate. A retried call reports again and the last attempt wins.
+func reportUsage(ctx context.Context, promptTokens, completionTokens int) {
+	call, ok := ctx.Value(meteredCallKey{}).(*meteredCall)
+	if !ok {
+		return
+	}
+
+	call.mu.Lock()
+	defer call.mu.Unlock()
+	call.exact = &Usage{PromptTokens: int64(promptTokens), CompletionTokens: int64(completionTokens)}
+}
+
+// servedBy bills the call made with ctx to the fallback model that served it.
+func servedBy(ctx context.Context, spec ModelSpec) {
+	call, ok := ctx.Value(meteredCallKey{}).(*meteredCall)
+	if !ok {
+		return
+	}
+
+	call.mu.Lock()
+	defer call.mu.Unlock()
+	call.served = &UsageKey{Provider: spec.Provider, Model: spec.Name}
+}
+
+// estimateTokens approximates four bytes per token, as the embedding client does.
+func estimateTokens(n int) int64 {
+	if n == 0 {
+		return 0
+	}
+	return int64(n/4 + 1)
+}
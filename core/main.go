@@ -13,12 +13,17 @@ import (
 	"github.com/SaiNageswarS/go-api-boot/cloud"
 	"github.com/SaiNageswarS/go-api-boot/config"
 	"github.com/SaiNageswarS/go-api-boot/dotenv"
-	"github.com/SaiNageswarS/go-api-boot/embed"
 	"github.com/SaiNageswarS/go-api-boot/logger"
 	"github.com/SaiNageswarS/go-api-boot/odm"
 	"github.com/SaiNageswarS/go-api-boot/server"
 	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
+	"github.com/SaiNageswarS/medicine-rag/core/events"
+	"github.com/SaiNageswarS/medicine-rag/core/ingest"
+	"github.com/SaiNageswarS/medicine-rag/core/llmrouter"
+	"github.com/SaiNageswarS/medicine-rag/core/mcp"
 	"github.com/SaiNageswarS/medicine-rag/core/services"
+	"github.com/SaiNageswarS/medicine-rag/core/tenancy"
 	"github.com/SaiNageswarS/medicine-rag/core/workers/activities"
 	"github.com/SaiNageswarS/medicine-rag/core/workers/workflows"
 	temporalClient "go.temporal.io/sdk/client"
@@ -38,16 +43,42 @@ func main() {
 		logger.Fatal("Failed to load config", zap.Error(err))
 	}
 
+	// The API key guard runs as an interceptor, and the source scheduler outside any
+	// request, so they are built before the container, with what they share with it.
+	mongo := odm.ProvideMongoClient()
+	az := cloud.ProvideAzure(&ccfgg.BootConfig) // or cloud.ProvideGcp
+	apiKeyGuard := services.ProvideApiKeyGuard(mongo)
+	embedders := embedding.ProvideRegistry(ccfgg)
+	limits := tenancy.ProvideLimits(ccfgg)
+	dispatcher := events.ProvideDispatcher(mongo)
+	sourceScheduler := services.ProvideSourceScheduler(ccfgg, mongo, az, embedders, limits, dispatcher)
+
 	boot, err := server.New().
 		GRPCPort(":50051"). // or ":0" for dynamic
 		HTTPPort(":8081").
 		Provide(ccfgg).
 		Provide(&ccfgg.BootConfig).
-		ProvideFunc(cloud.ProvideAzure). // or cloud.ProvideGcp
+		ProvideAs(az, (*cloud.Cloud)(nil)).
 
 		// ProvideFunc(llm.ProvideOllamaEmbeddingClient).
-		ProvideFunc(embed.ProvideJinaAIEmbeddingClient).
-		ProvideFunc(odm.ProvideMongoClient).
+		Provide(embedders).
+		ProvideAs(mongo, (*odm.MongoClient)(nil)).
+		Provide(apiKeyGuard).
+		Provide(sourceScheduler).
+		Provide(dispatcher).
+		ProvideFunc(llmrouter.ProvideRegistry).
+		Provide(limits).
+		ProvideFunc(services.ProvideAgentConfigStore).
+		ProvideFunc(ingest.ProvideBlobDeleter).
+		ProvideFunc(services.ProvideAnswerCache).
+		ProvideFunc(mcp.ProvideEmbeddingCache).
+		ProvideFunc(mcp.ProvideSessionCache).
+		ProvideFunc(mcp.ProvideExactVectorIndex).
+		ProvideFunc(mcp.ProvideReranker).
+		ProvideFunc(mcp.ProvideSynonymIndex).
+		ProvideFunc(mcp.ProvideSpellingIndex).
+		ProvideFunc(services.ProvideSearchTools).
+		ProvideFunc(services.ProvideStreamRegistry).
 
 		// Add Workers
 		WithTemporal(ccfgg.TemporalGoTaskQueue, &temporalClient.Options{
@@ -60,8 +91,21 @@ func main() {
 
 		// Register gRPC service impls
 		ApplySettings(getStreamingOptimizations()).
+		Unary(apiKeyGuard.Unary).
+		Stream(apiKeyGuard.Stream).
 		RegisterService(server.Adapt(pb.RegisterLoginServer), services.ProvideLoginService).
 		RegisterService(server.Adapt(schema.RegisterAgentServer), services.ProvideAgentService).
+		RegisterService(server.Adapt(pb.RegisterConversationServer), services.ProvideConversationService).
+		RegisterService(server.Adapt(pb.RegisterFeedbackServer), services.ProvideFeedbackService).
+		RegisterService(server.Adapt(pb.RegisterPortalServer), services.ProvidePortalService).
+		RegisterService(server.Adapt(pb.RegisterCorpusServer), services.ProvideCorpusService).
+		RegisterService(server.Adapt(pb.RegisterUsageServer), services.ProvideUsageService).
+		RegisterService(server.Adapt(pb.RegisterInteractionsServer), services.ProvideInteractionService).
+		RegisterService(server.Adapt(pb.RegisterSearchServer), services.ProvideSearchService).
+		RegisterService(server.Adapt(pb.RegisterNotificationsServer), services.ProvideNotificationService).
+		RegisterService(server.Adapt(pb.RegisterAdminServer), services.ProvideAdminService).
+		RegisterService(server.Adapt(pb.RegisterApiKeysServer), services.ProvideApiKeyService).
+		RegisterService(server.Adapt(pb.RegisterIngestionServer), services.ProvideIngestionService).
 		Build()
 
 	if err != nil {
@@ -69,6 +113,7 @@ func main() {
 	}
 
 	ctx := getCancellableContext()
+	go sourceScheduler.Run(ctx)
 	// catch SIGINT ‑> cancel
 	_ = boot.Serve(ctx)
 }
@@ -0,0 +1,458 @@
+package mcp
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SaiNageswarS/agent-boot/schema"
+)
+
+// calculator limits.
+const (
+	maxCalculatorPotencies = 10
+	maxScheduleDays        = 366
+)
+
+// PotencyScale is a homeopathic dilution scale.
+type PotencyScale string
+
+const (
+	ScaleDecimal         PotencyScale = "X"  // 1:10 per step, also written D or DH
+	ScaleCentesimal      PotencyScale = "C"  // 1:100 per step, also written CH
+	ScaleFiftyMillesimal PotencyScale = "LM" // 1:50,000 per step, also written Q
+)
+
+// Potency is a number of dilution steps on a scale, e.g. 30C.
+type Potency struct {
+	Steps int
+	Scale PotencyScale
+}
+
+// centesimal potencies written with roman-numeral style suffixes
+var namedPotencies = map[string]int{"M": 1000, "1M": 1000, "10M": 10000, "50M": 50000, "CM": 100000, "MM": 1000000}
+
+var potencyPattern = regexp.MustCompile(`^(\d+)\s*(X|D|DH|C|CH|LM|Q)$|^(LM|Q)\s*(\d+)$`)
+
+// ParsePotency reads potencies such as "6X", "D6", "30C", "200CH", "1M", "LM1" and "Q3".
+func ParsePotency(text string) (Potency, error) {
+	normalized := strings.ToUpper(strings.Join(strings.Fields(text), ""))
+	if steps, ok := namedPotencies[normalized]; ok {
+		return Potency{Steps: steps, Scale: ScaleCentesimal}, nil
+	}
+	// "D6" is the German way of writing 6X
+	if len(normalized) > 1 && normalized[0] == 'D' && normalized[1] >= '0' && normalized[1] <= '9' {
+		normalized = normalized[1:] + "X"
+	}
+
+	match := potencyPattern.FindStringSubmatch(normalized)
+	if match == nil {
+		return Potency{}, fmt.Errorf("cannot read potency %q; write it as e.g. 6X, 30C, 1M or LM1", text)
+	}
+
+	digits, scale := match[1], match[2]
+	if digits == "" {
+		digits, scale = match[4], match[3]
+	}
+	steps, err := strconv.Atoi(digits)
+	if err != nil || steps <= 0 {
+		return Potency{}, fmt.Errorf("potency %q must have at least one dilution step", text)
+	}
+
+	switch scale {
+	case "X", "D", "DH":
+		return Potency{Steps: steps, Scale: ScaleDecimal}, nil
+	case "C", "CH":
+		return Potency{Steps: steps, Scale: ScaleCentesimal}, nil
+	default:
+		return Potency{Steps: steps, Scale: ScaleFiftyMillesimal}, nil
+	}
+}
+
+func (p Potency) String() string {
+	if p.Scale == ScaleFiftyMillesimal {
+		return "LM" + strconv.Itoa(p.Steps)
+	}
+	return strconv.Itoa(p.Steps) + string(p.Scale)
+}
+
+// DilutionExponent is n in the total dilution of 1 in 10^n.
+func (p Potency) DilutionExponent() float64 {
+	switch p.Scale {
+	case ScaleDecimal:
+		return float64(p.Steps)
+	case ScaleCentesimal:
+		return 2 * float64(p.Steps)
+	default:
+		return float64(p.Steps) * math.Log10(50000)
+	}
+}
+
+// avogadroExponent is log10 of Avogadro's number, 6.022 × 10^23.
+var avogadroExponent = math.Log10(6.02214076e23)
+
+// PotencyConversion describes a potency on every scale it can be written on exactly.
+type PotencyConversion struct {
+	Potency          Potency
+	DilutionExponent float64
+	Equivalents      []Potency
+	// The dilution exceeds Avogadro's number: no molecule of a one-mole starting
+	// quantity is expected to remain.
+	BeyondAvogadro bool
+}
+
+// ConvertPotency converts a potency between the decimal and centesimal scales. LM
+// potencies have no exact decimal or centesimal equivalent and are described by their
+// dilution only.
+func ConvertPotency(p Potency) PotencyConversion {
+	conversion := PotencyConversion{
+		Potency:          p,
+		DilutionExponent: p.DilutionExponent(),
+		BeyondAvogadro:   p.DilutionExponent() > avogadroExponent,
+	}
+
+	switch p.Scale {
+	case ScaleCentesimal:
+		conversion.Equivalents = []Potency{{Steps: 2 * p.Steps, Scale: ScaleDecimal}}
+	case ScaleDecimal:
+		if p.Steps%2 == 0 {
+			conversion.Equivalents = []Potency{{Steps: p.Steps / 2, Scale: ScaleCentesimal}}
+		}
+	}
+	return conversion
+}
+
+// Sentences states the conversion for the agent.
+func (c PotencyConversion) Sentences() []string {
+	name := c.Potency.String()
+	ratio := map[PotencyScale]string{ScaleDecimal: "1:10", ScaleCentesimal: "1:100", ScaleFiftyMillesimal: "1:50,000"}[c.Potency.Scale]
+
+	sentences := []string{fmt.Sprintf("%s is %s dilution repeated %s, a total dilution of 1 in 10^%s.",
+		name, ratio, pluralize(c.Potency.Steps, "time"), formatQuantity(roundTo(c.DilutionExponent, 2)))}
+	for _, equivalent := range c.Equivalents {
+		sentences = append(sentences, fmt.Sprintf("%s is equivalent to %s.", name, equivalent))
+	}
+	if len(c.Equivalents) == 0 {
+		sentences = append(sentences, fmt.Sprintf("%s has no exact equivalent on the other scales.", name))
+	}
+	if c.BeyondAvogadro {
+		sentences = append(sentences, fmt.Sprintf("The dilution of %s exceeds Avogadro's number (6.022 × 10^23), which is passed after 12C or 24X.", name))
+	}
+	return sentences
+}
+
+// DoseAmount is a quantity taken at a time, e.g. 2 pellets.
+type DoseAmount struct {
+	Quantity float64
+	Unit     string
+}
+
+var dosePattern = regexp.MustCompile(`^(\d+/\d+|\d+(?:\.\d+)?)\s*(.*)$`)
+
+// ParseDose reads doses such as "2 pellets", "5 ml" or "1/2 tablet".
+func ParseDose(text string) (DoseAmount, error) {
+	match := dosePattern.FindStringSubmatch(strings.TrimSpace(strings.ToLower(text)))
+	if match == nil {
+		return DoseAmount{}, fmt.Errorf("cannot read dose %q; write it as e.g. \"2 pellets\" or \"5 ml\"", text)
+	}
+
+	var quantity float64
+	if numerator, denominator, ok := strings.Cut(match[1], "/"); ok {
+		n, _ := strconv.ParseFloat(numerator, 64)
+		d, _ := strconv.ParseFloat(denominator, 64)
+		if d == 0 {
+			return DoseAmount{}, fmt.Errorf("cannot read dose %q", text)
+		}
+		quantity = n / d
+	} else {
+		quantity, _ = strconv.ParseFloat(match[1], 64)
+	}
+	if quantity <= 0 {
+		return DoseAmount{}, fmt.Errorf("dose %q must be more than zero", text)
+	}
+	return DoseAmount{Quantity: quantity, Unit: strings.TrimSpace(match[2])}, nil
+}
+
+func (d DoseAmount) String() string {
+	return formatQuantity(d.Quantity) + unitSuffix(d.Unit)
+}
+
+// total is the amount of n doses, with the unit's plural where it has an obvious one.
+func (d DoseAmount) total(n int) string {
+	total := d.Quantity * float64(n)
+	unit := d.Unit
+	if total > 1 && unit != "" && !strings.HasSuffix(unit, "s") && !measurementUnits[unit] {
+		unit += "s"
+	}
+	return formatQuantity(roundTo(total, 3)) + unitSuffix(unit)
+}
+
+// units that are not pluralized
+var measurementUnits = map[string]bool{"ml": true, "mg": true, "mcg": true, "g": true, "gm": true, "iu": true}
+
+// Frequency is how often a dose is taken.
+type Frequency struct {
+	Interval time.Duration
+	Label    string
+}
+
+var (
+	timesPerPattern = regexp.MustCompile(`^(once|twice|thrice|\d+)\s*(?:x|times?)?\s*(?:a|per|every|each)?\s*(day|daily|week|weekly)$`)
+	everyPattern    = regexp.MustCompile(`^(?:once\s+)?every\s+(\d+|other)?\s*(hours?|hrs?|days?|weeks?)$`)
+)
+
+// abbreviations used on prescriptions
+var prescriptionFrequencies = map[string]int{"od": 1, "qd": 1, "bd": 2, "bid": 2, "tds": 3, "tid": 3, "qds": 4, "qid": 4}
+
+// ParseFrequency reads frequencies such as "3 times a day", "twice daily", "TDS",
+// "every 4 hours", "every other day" and "once a week".
+func ParseFrequency(text string) (Frequency, error) {
+	normalized := strings.Join(strings.Fields(strings.ToLower(strings.Trim(text, ". "))), " ")
+	fail := fmt.Errorf("cannot read frequency %q; write it as e.g. \"3 times a day\" or \"every 4 hours\"", text)
+
+	if perDay, ok := prescriptionFrequencies[normalized]; ok {
+		return timesPer(perDay, 24*time.Hour, "day"), nil
+	}
+	switch normalized {
+	case "daily", "once daily":
+		return timesPer(1, 24*time.Hour, "day"), nil
+	case "weekly":
+		return timesPer(1, 7*24*time.Hour, "week"), nil
+	}
+
+	if match := timesPerPattern.FindStringSubmatch(normalized); match != nil {
+		count, err := countWord(match[1])
+		if err != nil || count <= 0 || count > 24 {
+			return Frequency{}, fail
+		}
+		if strings.HasPrefix(match[2], "week") {
+			return timesPer(count, 7*24*time.Hour, "week"), nil
+		}
+		return timesPer(count, 24*time.Hour, "day"), nil
+	}
+
+	if match := everyPattern.FindStringSubmatch(normalized); match != nil {
+		count := 1
+		switch match[1] {
+		case "":
+		case "other":
+			count = 2
+		default:
+			count, _ = strconv.Atoi(match[1])
+		}
+		if count <= 0 {
+			return Frequency{}, fail
+		}
+
+		unit := map[byte]time.Duration{'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}[match[2][0]]
+		name := map[byte]string{'h': "hour", 'd': "day", 'w': "week"}[match[2][0]]
+		label := "every " + name
+		if count > 1 {
+			label = "every " + pluralize(count, name)
+		}
+		if unit == time.Hour && 24%count != 0 {
+			return Frequency{}, fmt.Errorf("an interval of %d hours does not divide the day; give the frequency as times a day instead", count)
+		}
+		return Frequency{Interval: time.Duration(count) * unit, Label: label}, nil
+	}
+	return Frequency{}, fail
+}
+
+func timesPer(count int, period time.Duration, name string) Frequency {
+	label := map[int]string{1: "once", 2: "twice"}[count]
+	if label == "" {
+		label = strconv.Itoa(count) + " times"
+	}
+	return Frequency{Interval: period / time.Duration(count), Label: label + " a " + name}
+}
+
+func countWord(word string) (int, error) {
+	switch word {
+	case "once":
+		return 1, nil
+	case "twice":
+		return 2, nil
+	case "thrice":
+		return 3, nil
+	}
+	return strconv.Atoi(word)
+}
+
+var durationPattern = regexp.MustCompile(`^(?:for\s+)?(\d+|a|one)\s*(days?|weeks?|months?)$`)
+
+// ParseCourse reads the length of a course such as "7 days", "2 weeks" or "1 month" as
+// a number of days. A month counts as 30 days.
+func ParseCourse(text string) (int, error) {
+	match := durationPattern.FindStringSubmatch(strings.Join(strings.Fields(strings.ToLower(strings.Trim(text, ". "))), " "))
+	if match == nil {
+		return 0, fmt.Errorf("cannot read duration %q; write it as e.g. \"7 days\" or \"2 weeks\"", text)
+	}
+
+	count := 1
+	if match[1] != "a" && match[1] != "one" {
+		count, _ = strconv.Atoi(match[1])
+	}
+	days := count * map[byte]int{'d': 1, 'w': 7, 'm': 30}[match[2][0]]
+	if days <= 0 || days > maxScheduleDays {
+		return 0, fmt.Errorf("duration %q must be between 1 and %d days", text, maxScheduleDays)
+	}
+	return days, nil
+}
+
+// DosingSchedule is a course of doses taken at a fixed frequency.
+type DosingSchedule struct {
+	Dose      DoseAmount
+	Frequency Frequency
+	Days      int
+}
+
+// Doses is the number of doses in the course. The first dose is taken at the start of
+// day 1 and no dose falls after the end of the last day.
+func (s DosingSchedule) Doses() int {
+	course := time.Duration(s.Days) * 24 * time.Hour
+	return int((course-1)/s.Frequency.Interval) + 1
+}
+
+// LastDoseDay is the day of the course the last dose falls on.
+func (s DosingSchedule) LastDoseDay() int {
+	last := time.Duration(s.Doses()-1) * s.Frequency.Interval
+	return int(last/(24*time.Hour)) + 1
+}
+
+// Sentences states the schedule's totals for the agent.
+func (s DosingSchedule) Sentences() []string {
+	doses := s.Doses()
+	sentences := []string{
+		fmt.Sprintf("%s %s for %s is %s.", s.Dose, s.Frequency.Label, pluralize(s.Days, "day"), pluralize(doses, "dose")),
+		fmt.Sprintf("The course uses %s in total.", s.Dose.total(doses)),
+	}
+	if s.Frequency.Interval >= 24*time.Hour {
+		sentences = append(sentences, fmt.Sprintf("Doses fall on days %s.", s.doseDays()))
+	}
+	if last := s.LastDoseDay(); last < s.Days {
+		sentences = append(sentences, fmt.Sprintf("The last dose is taken on day %d.", last))
+	}
+	return sentences
+}
+
+// doseDays lists the days of a course with at most one dose a day.
+func (s DosingSchedule) doseDays() string {
+	var days []string
+	for i := 0; i < s.Doses(); i++ {
+		day := int(time.Duration(i)*s.Frequency.Interval/(24*time.Hour)) + 1
+		if len(days) == 12 {
+			days = append(days, "and so on")
+			break
+		}
+		days = append(days, strconv.Itoa(day))
+	}
+	return strings.Join(days, ", ")
+}
+
+// CalculatorRequest holds the calculations asked for in one tool call. Either part may
+// be empty.
+type CalculatorRequest struct {
+	Potencies []string
+	Dose      string
+	Frequency string
+	Duration  string
+}
+
+// RunCalculator converts potencies and works out dosing schedules without the model, so
+// numbers in answers are computed rather than generated. Each problem with the input is
+// reported as an error result the agent can correct and retry.
+func RunCalculator(req CalculatorRequest) <-chan *schema.ToolResultChunk {
+	out := make(chan *schema.ToolResultChunk, maxCalculatorPotencies+1)
+
+	go func() {
+		defer close(out)
+
+		for _, text := range distinctTerms(req.Potencies, maxCalculatorPotencies) {
+			potency, err := ParsePotency(text)
+			if err != nil {
+				out <- &schema.ToolResultChunk{Error: err.Error()}
+				continue
+			}
+
+			conversion := ConvertPotency(potency)
+			out <- &schema.ToolResultChunk{
+				Title:       "Potency: " + potency.String(),
+				Id:          "potency-" + strings.ToLower(potency.String()),
+				Attribution: "Calculated",
+				Sentences:   conversion.Sentences(),
+				Metadata: map[string]string{
+					"scale":            string(potency.Scale),
+					"dilutionExponent": formatQuantity(roundTo(conversion.DilutionExponent, 2)),
+				},
+			}
+		}
+
+		if req.Dose == "" && req.Frequency == "" && req.Duration == "" {
+			return
+		}
+		schedule, err := parseSchedule(req)
+		if err != nil {
+			out <- &schema.ToolResultChunk{Error: err.Error()}
+			return
+		}
+		out <- &schema.ToolResultChunk{
+			Title:       fmt.Sprintf("Dosing schedule: %s %s for %s", schedule.Dose, schedule.Frequency.Label, pluralize(schedule.Days, "day")),
+			Id:          "dosing-schedule",
+			Attribution: "Calculated",
+			Sentences:   schedule.Sentences(),
+			Metadata: map[string]string{
+				"doses":       strconv.Itoa(schedule.Doses()),
+				"total":       schedule.Dose.total(schedule.Doses()),
+				"lastDoseDay": strconv.Itoa(schedule.LastDoseDay()),
+			},
+		}
+	}()
+
+	return out
+}
+
+func parseSchedule(req CalculatorRequest) (DosingSchedule, error) {
+	if req.Dose == "" || req.Frequency == "" || req.Duration == "" {
+		return DosingSchedule{}, fmt.Errorf("a dosing schedule needs a dose, a frequency and a duration")
+	}
+
+	dose, err := ParseDose(req.Dose)
+	if err != nil {
+		return DosingSchedule{}, err
+	}
+	frequency, err := ParseFrequency(req.Frequency)
+	if err != nil {
+		return DosingSchedule{}, err
+	}
+	days, err := ParseCourse(req.Duration)
+	if err != nil {
+		return DosingSchedule{}, err
+	}
+	return DosingSchedule{Dose: dose, Frequency: frequency, Days: days}, nil
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
+}
+
+func unitSuffix(unit string) string {
+	if unit == "" {
+		return ""
+	}
+	return " " + unit
+}
+
+func formatQuantity(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func roundTo(v float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(v*scale) / scale
+}
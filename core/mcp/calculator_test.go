@@ -0,0 +1,125 @@
+package mcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePotency(t *testing.T) {
+	for text, want := range map[string]Potency{
+		"30C":    {Steps: 30, Scale: ScaleCentesimal},
+		"200 ch": {Steps: 200, Scale: ScaleCentesimal},
+		"6X":     {Steps: 6, Scale: ScaleDecimal},
+		"D12":    {Steps: 12, Scale: ScaleDecimal},
+		"1M":     {Steps: 1000, Scale: ScaleCentesimal},
+		"CM":     {Steps: 100000, Scale: ScaleCentesimal},
+		"LM1":    {Steps: 1, Scale: ScaleFiftyMillesimal},
+		"Q 3":    {Steps: 3, Scale: ScaleFiftyMillesimal},
+	} {
+		potency, err := ParsePotency(text)
+		require.NoError(t, err, text)
+		assert.Equal(t, want, potency, text)
+	}
+
+	for _, text := range []string{"", "30", "0C", "30Z", "C30"} {
+		_, err := ParsePotency(text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestConvertPotency(t *testing.T) {
+	conversion := ConvertPotency(Potency{Steps: 6, Scale: ScaleCentesimal})
+	assert.Equal(t, 12.0, conversion.DilutionExponent)
+	assert.Equal(t, []Potency{{Steps: 12, Scale: ScaleDecimal}}, conversion.Equivalents)
+	assert.False(t, conversion.BeyondAvogadro)
+	assert.Equal(t, []string{
+		"6C is 1:100 dilution repeated 6 times, a total dilution of 1 in 10^12.",
+		"6C is equivalent to 12X.",
+	}, conversion.Sentences())
+
+	// 12C is 10^24, just past Avogadro's number
+	assert.True(t, ConvertPotency(Potency{Steps: 12, Scale: ScaleCentesimal}).BeyondAvogadro)
+	assert.False(t, ConvertPotency(Potency{Steps: 23, Scale: ScaleDecimal}).BeyondAvogadro)
+
+	assert.Equal(t, []Potency{{Steps: 3, Scale: ScaleCentesimal}}, ConvertPotency(Potency{Steps: 6, Scale: ScaleDecimal}).Equivalents)
+	assert.Empty(t, ConvertPotency(Potency{Steps: 3, Scale: ScaleDecimal}).Equivalents)
+
+	lm := ConvertPotency(Potency{Steps: 1, Scale: ScaleFiftyMillesimal})
+	assert.InDelta(t, 4.699, lm.DilutionExponent, 0.001)
+	assert.Empty(t, lm.Equivalents)
+	assert.Contains(t, lm.Sentences()[0], "1 in 10^4.7.")
+}
+
+func TestParseFrequency(t *testing.T) {
+	for text, want := range map[string]Frequency{
+		"3 times a day":   {Interval: 8 * time.Hour, Label: "3 times a day"},
+		"TDS":             {Interval: 8 * time.Hour, Label: "3 times a day"},
+		"twice daily":     {Interval: 12 * time.Hour, Label: "twice a day"},
+		"once a week":     {Interval: 7 * 24 * time.Hour, Label: "once a week"},
+		"every 4 hours":   {Interval: 4 * time.Hour, Label: "every 4 hours"},
+		"every other day": {Interval: 48 * time.Hour, Label: "every 2 days"},
+		"every day":       {Interval: 24 * time.Hour, Label: "every day"},
+	} {
+		frequency, err := ParseFrequency(text)
+		require.NoError(t, err, text)
+		assert.Equal(t, want, frequency, text)
+	}
+
+	for _, text := range []string{"often", "every 5 hours", "0 times a day"} {
+		_, err := ParseFrequency(text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestDosingSchedule(t *testing.T) {
+	schedule, err := parseSchedule(CalculatorRequest{Dose: "2 pellets", Frequency: "3 times a day", Duration: "1 week"})
+	require.NoError(t, err)
+	assert.Equal(t, 21, schedule.Doses())
+	assert.Equal(t, []string{
+		"2 pellets 3 times a day for 7 days is 21 doses.",
+		"The course uses 42 pellets in total.",
+	}, schedule.Sentences())
+
+	schedule, err = parseSchedule(CalculatorRequest{Dose: "1/2 tablet", Frequency: "every other day", Duration: "6 days"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, schedule.Doses())
+	assert.Equal(t, []string{
+		"0.5 tablet every 2 days for 6 days is 3 doses.",
+		"The course uses 1.5 tablets in total.",
+		"Doses fall on days 1, 3, 5.",
+		"The last dose is taken on day 5.",
+	}, schedule.Sentences())
+
+	schedule, err = parseSchedule(CalculatorRequest{Dose: "2.5 ml", Frequency: "every 6 hours", Duration: "2 days"})
+	require.NoError(t, err)
+	assert.Equal(t, 8, schedule.Doses())
+	assert.Equal(t, "20 ml", schedule.Dose.total(schedule.Doses()))
+
+	_, err = parseSchedule(CalculatorRequest{Dose: "2 pellets", Frequency: "3 times a day"})
+	assert.Error(t, err)
+	_, err = parseSchedule(CalculatorRequest{Dose: "2 pellets", Frequency: "3 times a day", Duration: "2 years"})
+	assert.Error(t, err)
+}
+
+func TestRunCalculator(t *testing.T) {
+	var results []*schema.ToolResultChunk
+	for result := range RunCalculator(CalculatorRequest{
+		Potencies: []string{"30C", "30c", "thirty"},
+		Dose:      "4 globules", Frequency: "BD", Duration: "5 days",
+	}) {
+		results = append(results, result)
+	}
+
+	require.Len(t, results, 3)
+	assert.Equal(t, "Potency: 30C", results[0].Title)
+	assert.Equal(t, "60", results[0].Metadata["dilutionExponent"])
+	assert.Contains(t, results[0].Sentences, "30C is equivalent to 60X.")
+	assert.Contains(t, results[1].Error, `cannot read potency "thirty"`)
+	assert.Equal(t, "Dosing schedule: 4 globules twice a day for 5 days", results[2].Title)
+	assert.Equal(t, "10", results[2].Metadata["doses"])
+	assert.Equal(t, "40 globules", results[2].Metadata["total"])
+}
@@ -0,0 +1,124 @@
+package mcp
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+)
+
+// ContextMode is how much of the text around its matching windows a search result
+// carries.
+type ContextMode string
+
+const (
+	ContextWindows   ContextMode = "windows"   // the whole windows either side of each match
+	ContextSentences ContextMode = "sentences" // a number of sentences either side of each match
+	ContextParagraph ContextMode = "paragraph" // the paragraphs each match starts and ends in
+)
+
+// ContextExpansion configures the text around a section's matching windows. The zero
+// value, like an unknown mode, keeps the neighbouring windows whole.
+type ContextExpansion struct {
+	Mode      ContextMode
+	Sentences int // sentences either side of a match, for ContextSentences
+}
+
+// contextSentence is a sentence of a section's windows laid out in reading order.
+type contextSentence struct {
+	text      string
+	paragraph string // section and paragraph number; empty for chunks indexed without paragraphs
+	hit       bool   // in one of the matching windows
+}
+
+// mergeWindows lays out the sentences of chunks, in reading order, once each: a window
+// following the one before it starts with the sentences they overlap by, which are
+// dropped.
+func mergeWindows(chunks []*db.ChunkModel, hits map[string]bool) []contextSentence {
+	var merged []contextSentence
+	var prev *db.ChunkModel
+	for _, chunk := range chunks {
+		overlap := 0
+		if prev != nil && chunk.PrevChunkID == prev.ChunkID {
+			overlap = windowOverlap(prev.Sentences, chunk.Sentences)
+		}
+
+		hit := hits[chunk.ChunkID]
+		if hit {
+			for i := len(merged) - overlap; i < len(merged); i++ {
+				merged[i].hit = true
+			}
+		}
+
+		numbered := len(chunk.Paragraphs) == len(chunk.Sentences)
+		for i := overlap; i < len(chunk.Sentences); i++ {
+			sentence := contextSentence{text: chunk.Sentences[i], hit: hit}
+			if numbered {
+				sentence.paragraph = chunk.SectionID + "/" + strconv.Itoa(chunk.Paragraphs[i])
+			}
+			merged = append(merged, sentence)
+		}
+		prev = chunk
+	}
+	return merged
+}
+
+// windowOverlap is the number of sentences next starts with that prev ends with.
+func windowOverlap(prev, next []string) int {
+	for n := min(len(prev), len(next)); n > 0; n-- {
+		if slices.Equal(prev[len(prev)-n:], next[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// expand picks the sentences of merged a result carries. Context only reaches as far
+// as the neighbouring windows. Paragraph expansion of chunks indexed without paragraph
+// numbers keeps the neighbouring windows whole.
+func (e ContextExpansion) expand(merged []contextSentence) []string {
+	keep := make([]bool, len(merged))
+	switch e.Mode {
+	case ContextSentences:
+		n := max(e.Sentences, 0)
+		for i, sentence := range merged {
+			if !sentence.hit {
+				continue
+			}
+			for j := max(i-n, 0); j <= min(i+n, len(merged)-1); j++ {
+				keep[j] = true
+			}
+		}
+
+	case ContextParagraph:
+		paragraphs := make(map[string]bool)
+		for _, sentence := range merged {
+			if !sentence.hit {
+				continue
+			}
+			if sentence.paragraph == "" {
+				return texts(merged, nil)
+			}
+			paragraphs[sentence.paragraph] = true
+		}
+		for i, sentence := range merged {
+			keep[i] = sentence.hit || paragraphs[sentence.paragraph]
+		}
+
+	default:
+		return texts(merged, nil)
+	}
+	return texts(merged, keep)
+}
+
+// texts returns the text of the sentences of merged that keep marks, or of all of them
+// when keep is nil.
+func texts(merged []contextSentence, keep []bool) []string {
+	out := make([]string, 0, len(merged))
+	for i, sentence := range merged {
+		if keep == nil || keep[i] {
+			out = append(out, sentence.text)
+		}
+	}
+	return out
+}
@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// contextWindows are three overlapping windows of one section over five paragraphs.
+func contextWindows() []*db.ChunkModel {
+	return []*db.ChunkModel{
+		{ChunkID: "s_0", SectionID: "s", NextChunkID: "s_1",
+			Sentences: []string{"Intro.", "Fever begins.", "Face red."}, Paragraphs: []int{0, 1, 1}},
+		{ChunkID: "s_1", SectionID: "s", PrevChunkID: "s_0", NextChunkID: "s_2", WindowIndex: 1,
+			Sentences: []string{"Face red.", "Pupils dilated.", "Worse from light."}, Paragraphs: []int{1, 2, 2}},
+		{ChunkID: "s_2", SectionID: "s", PrevChunkID: "s_1", WindowIndex: 2,
+			Sentences: []string{"Worse from light.", "Better lying.", "Thirstless."}, Paragraphs: []int{2, 3, 4}},
+	}
+}
+
+func TestMergeWindows(t *testing.T) {
+	merged := mergeWindows(contextWindows(), map[string]bool{"s_1": true})
+
+	assert.Equal(t, []string{"Intro.", "Fever begins.", "Face red.", "Pupils dilated.", "Worse from light.", "Better lying.", "Thirstless."},
+		texts(merged, nil), "overlapping sentences are kept once")
+	assert.Equal(t, []bool{false, false, true, true, true, false, false}, hitFlags(merged),
+		"sentences the matching window shares with its neighbours are matches")
+	assert.Equal(t, "s/2", merged[3].paragraph)
+}
+
+func TestMergeWindowsUnlinked(t *testing.T) {
+	chunks := []*db.ChunkModel{
+		{ChunkID: "a", Sentences: []string{"Same."}},
+		{ChunkID: "b", Sentences: []string{"Same.", "Other."}},
+	}
+	assert.Equal(t, []string{"Same.", "Same.", "Other."}, texts(mergeWindows(chunks, nil), nil),
+		"only consecutive windows overlap")
+}
+
+func TestContextExpansion(t *testing.T) {
+	merged := mergeWindows(contextWindows(), map[string]bool{"s_1": true})
+
+	assert.Equal(t, texts(merged, nil), ContextExpansion{}.expand(merged), "windows are kept whole by default")
+	assert.Equal(t, texts(merged, nil), ContextExpansion{Mode: "unknown"}.expand(merged))
+
+	assert.Equal(t, []string{"Fever begins.", "Face red.", "Pupils dilated.", "Worse from light.", "Better lying."},
+		ContextExpansion{Mode: ContextSentences, Sentences: 1}.expand(merged))
+	assert.Equal(t, []string{"Face red.", "Pupils dilated.", "Worse from light."},
+		ContextExpansion{Mode: ContextSentences}.expand(merged), "no sentences keeps the matching window")
+
+	assert.Equal(t, []string{"Fever begins.", "Face red.", "Pupils dilated.", "Worse from light."},
+		ContextExpansion{Mode: ContextParagraph}.expand(merged), "paragraphs 1 and 2")
+}
+
+func TestContextExpansionWithoutParagraphs(t *testing.T) {
+	chunks := contextWindows()
+	for _, chunk := range chunks {
+		chunk.Paragraphs = nil
+	}
+	merged := mergeWindows(chunks, map[string]bool{"s_1": true})
+	assert.Equal(t, texts(merged, nil), ContextExpansion{Mode: ContextParagraph}.expand(merged),
+		"chunks indexed without paragraphs keep their neighbours whole")
+}
+
+func TestSearchExpandsContext(t *testing.T) {
+	windows := contextWindows()
+	chunkRepository := odmtest.NewCollection(*windows[0], *windows[1], *windows[2])
+
+	for mode, want := range map[ContextMode][]string{
+		ContextWindows:   {"Intro.", "Fever begins.", "Face red.", "Pupils dilated.", "Worse from light.", "Better lying.", "Thirstless."},
+		ContextParagraph: {"Fever begins.", "Face red.", "Pupils dilated.", "Worse from light."},
+	} {
+		searchTool := NewSearchTool(chunkRepository, odmtest.NewCollection[db.ChunkAnnModel](), nil,
+			WithLexicalOnly(), WithContextExpansion(ContextExpansion{Mode: mode}))
+
+		var results int
+		for result := range searchTool.Run(t.Context(), "pupils", SearchFilter{}, SearchPage{}) {
+			require.Empty(t, result.Error)
+			assert.Equal(t, want, result.Sentences, mode)
+			results++
+		}
+		assert.Equal(t, 1, results, mode)
+	}
+}
+
+func hitFlags(merged []contextSentence) []bool {
+	flags := make([]bool, len(merged))
+	for i, sentence := range merged {
+		flags[i] = sentence.hit
+	}
+	return flags
+}
@@ -0,0 +1,194 @@
+package mcp
+
+import (
+	"cmp"
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/llmrouter"
+	"go.uber.org/zap"
+)
+
+const (
+	// decomposeTimeout bounds splitting the query; a search whose parts are late goes on
+	// with the whole query's results alone.
+	decomposeTimeout = 8 * time.Second
+
+	// minDecomposedWords is the fewest words a query is split at: shorter ones rarely ask
+	// about more than one thing.
+	minDecomposedWords = 6
+
+	// maxSubQueries is the most parts a query is searched as.
+	maxSubQueries = 4
+)
+
+const decomposeSystemPrompt = "You split a search query over homeopathic literature into the independent parts it asks about, so each can be searched on its own. " +
+	"Each part is a short search query keeping the query's own words for that part, such as one symptom with its modalities. " +
+	"Reply with one part per line and nothing else, at most four. If the query asks about one thing only, reply with the query unchanged."
+
+// WithDecomposition splits multi-part questions ("fear of death with restlessness worse at
+// midnight") into sub-queries model writes, and searches each part alongside the whole
+// query, in parallel. Their rankings are merged by fused score: a chunk found by several
+// keeps its best score. A passage that answers every part usually ranks well for the
+// whole query already; the parts find those that answer one part in the words of the
+// corpus. The split costs a model call per search of six words or more. A split that
+// fails, is late, or finds a single part leaves the search as it was. Results whose best
+// window was found by a part carry it in their subQuery metadata entry.
+func WithDecomposition(model llm.LLMClient) SearchToolOption {
+	return func(s *SearchTool) { s.decompose = model }
+}
+
+// subQueryLine strips list markers the model may put before a part.
+var subQueryLine = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])?\s*`)
+
+// subQueries asks the model for the parts of query. It returns none when the query has
+// a single part.
+func (s *SearchTool) subQueries(ctx context.Context, query string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, decomposeTimeout)
+	defer cancel()
+
+	var reply strings.Builder
+	err := s.decompose.GenerateInference(ctx,
+		[]llm.Message{{Role: "user", Content: query}},
+		func(chunk string) error {
+			reply.WriteString(chunk)
+			return nil
+		},
+		llmrouter.WithSystemPrompt(decomposeSystemPrompt),
+		llmrouter.WithTemperature(0),
+	)
+	if err != nil {
+		logger.Error("Failed to split search query", zap.String("query", query), zap.Error(err))
+		return nil, err
+	}
+	return parseSubQueries(query, reply.String()), nil
+}
+
+// parseSubQueries reads the parts of query from the model's reply, one per line, leaving
+// out repeats and the query itself. It returns none when fewer than two are left.
+func parseSubQueries(query, reply string) []string {
+	var parts []string
+	for line := range strings.Lines(reply) {
+		part := strings.TrimSpace(subQueryLine.ReplaceAllString(line, ""))
+		if part == "" || strings.EqualFold(part, strings.TrimSpace(query)) {
+			continue
+		}
+		if slices.ContainsFunc(parts, func(p string) bool { return strings.EqualFold(p, part) }) {
+			continue
+		}
+		parts = append(parts, part)
+		if len(parts) == maxSubQueries {
+			break
+		}
+	}
+	if len(parts) < 2 {
+		return nil
+	}
+	return parts
+}
+
+// subQueryRanking is what the search of one part of the query found.
+type subQueryRanking struct {
+	query  string
+	ranked rankedChunks
+}
+
+// searchSubQueries splits query and searches each part, in parallel. It returns none
+// when the query isn't split; a part whose search fails is left out.
+func (s *SearchTool) searchSubQueries(ctx context.Context, parsed Query, filter SearchFilter, depth int) <-chan async.Result[[]subQueryRanking] {
+	return async.Go(func() ([]subQueryRanking, error) {
+		if !parsed.IsPlain() || len(termWords(parsed.Text())) < minDecomposedWords {
+			return nil, nil
+		}
+		parts, err := s.subQueries(ctx, parsed.Text())
+		if err != nil || len(parts) == 0 {
+			return nil, err
+		}
+
+		// the parts are searched as written: no passage of their own, and no further split
+		part := *s
+		part.hyde, part.decompose = nil, nil
+		tasks := make([]<-chan async.Result[rankedChunks], len(parts))
+		for i, query := range parts {
+			tasks[i] = async.Go(func() (rankedChunks, error) {
+				return part.searchSources(ctx, ParseQuery(query), filter, depth, nil)
+			})
+		}
+
+		rankings := make([]subQueryRanking, 0, len(parts))
+		for i, task := range tasks {
+			ranked, err := async.Await(task)
+			if err != nil {
+				logger.Error("Sub-query search failed", zap.String("subQuery", parts[i]), zap.Error(err))
+				continue
+			}
+			rankings = append(rankings, subQueryRanking{query: parts[i], ranked: ranked})
+		}
+		return rankings, nil
+	})
+}
+
+// mergeSubQueryResults adds the chunks the parts of the query found to ranked, keeping
+// the best depth by fused score. A chunk found by several keeps its best score, and its
+// engine ranks and scores from the search that found it first, the whole query's first.
+func mergeSubQueryResults(ranked rankedChunks, parts []subQueryRanking, depth int) rankedChunks {
+	if len(parts) == 0 {
+		return ranked
+	}
+
+	merged := ranked
+	merged.scores = make(map[string]float64, len(ranked.scores))
+	merged.subQueries = make(map[string]string)
+	chunks := make(map[string]*db.ChunkModel, len(ranked.chunks))
+	add := func(from rankedChunks, query string) {
+		for _, chunk := range from.chunks {
+			id := chunk.ChunkID
+			if _, ok := chunks[id]; !ok {
+				chunks[id] = chunk
+			}
+			if score, ok := merged.scores[id]; ok && score >= from.scores[id] {
+				continue
+			}
+			merged.scores[id] = from.scores[id]
+			if query != "" {
+				merged.subQueries[id] = query
+			}
+		}
+	}
+	add(ranked, "")
+	for _, part := range parts {
+		add(part.ranked, part.query)
+		merged.text = engineHits{ranks: mergeScores(merged.text.ranks, part.ranked.text.ranks), scores: mergeScores(merged.text.scores, part.ranked.text.scores)}
+		merged.vector = engineHits{ranks: mergeScores(merged.vector.ranks, part.ranked.vector.ranks), scores: mergeScores(merged.vector.scores, part.ranked.vector.scores)}
+		merged.packs = mergeScores(merged.packs, part.ranked.packs)
+		merged.partial = merged.partial || part.ranked.partial
+		merged.ceiling = max(merged.ceiling, part.ranked.ceiling)
+	}
+
+	merged.chunks = make([]*db.ChunkModel, 0, len(chunks))
+	for _, chunk := range ranked.chunks {
+		merged.chunks = append(merged.chunks, chunk)
+		delete(chunks, chunk.ChunkID)
+	}
+	for _, part := range parts {
+		for _, chunk := range part.ranked.chunks {
+			if _, ok := chunks[chunk.ChunkID]; ok {
+				merged.chunks = append(merged.chunks, chunk)
+				delete(chunks, chunk.ChunkID)
+			}
+		}
+	}
+	// ties keep the whole query's chunks first
+	slices.SortStableFunc(merged.chunks, func(a, b *db.ChunkModel) int {
+		return cmp.Compare(merged.scores[b.ChunkID], merged.scores[a.ChunkID])
+	})
+	merged.chunks = merged.chunks[:min(len(merged.chunks), depth)]
+	return merged
+}
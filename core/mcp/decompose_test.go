@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSubQueries(t *testing.T) {
+	const query = "fear of death with restlessness worse at midnight"
+
+	assert.Equal(t, []string{"fear of death", "Restlessness worse at midnight"},
+		parseSubQueries(query, "1. fear of death\n- Restlessness worse at midnight\n\n2) fear of Death\n"))
+	assert.Empty(t, parseSubQueries(query, query+"\n"), "a query with one part isn't split")
+	assert.Empty(t, parseSubQueries(query, "fear of death"))
+	assert.Len(t, parseSubQueries(query, "a\nb\nc\nd\ne\nf"), maxSubQueries)
+}
+
+func TestSearchDecomposition(t *testing.T) {
+	const query = "fear of death with restlessness worse at midnight"
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "aconite", SectionID: "aconite", Title: "Aconite", Sentences: []string{"Fear of death, fear of death."}},
+		db.ChunkModel{ChunkID: "arsenicum", SectionID: "arsenicum", Title: "Arsenicum", Sentences: []string{"Restlessness after midnight."}},
+	)
+
+	// fusedScore and subQuery of each section
+	search := func(model *passageModel, query string) (map[string]string, map[string]string) {
+		searchTool := NewSearchTool(chunkRepository, odmtest.NewCollection[db.ChunkAnnModel](), nil, WithLexicalOnly(), WithDecomposition(model))
+		scores, subQueries := make(map[string]string), make(map[string]string)
+		for result := range searchTool.Run(t.Context(), query, SearchFilter{}, SearchPage{}) {
+			require.Empty(t, result.Error)
+			scores[result.Id] = result.Metadata["fusedScore"]
+			if subQuery, ok := result.Metadata["subQuery"]; ok {
+				subQueries[result.Id] = subQuery
+			}
+		}
+		return scores, subQueries
+	}
+
+	model := &passageModel{passage: "fear of death\nrestlessness worse at midnight"}
+	scores, subQueries := search(model, query)
+	assert.Equal(t, 1, model.calls)
+	assert.Equal(t, map[string]string{"aconite": "0.01639", "arsenicum": "0.01639"}, scores, "each keeps its best rank across the searches")
+	assert.Equal(t, map[string]string{"arsenicum": "restlessness worse at midnight"}, subQueries)
+
+	failing := &passageModel{err: errors.New("model unavailable")}
+	scores, subQueries = search(failing, query)
+	assert.Equal(t, map[string]string{"aconite": "0.01639", "arsenicum": "0.01613"}, scores, "a failed split searches the whole query")
+	assert.Empty(t, subQueries)
+
+	short := &passageModel{passage: "fear\ndeath"}
+	search(short, "fear of death")
+	assert.Zero(t, short.calls, "short queries aren't split")
+}
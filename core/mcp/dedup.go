@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"hash/fnv"
+	"math"
+	"strings"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+)
+
+// near-duplicate detection parameters.
+const (
+	shingleWords            = 3    // words per shingle
+	minSignatureWords       = 8    // shorter chunks, such as bare remedy lists, are never duplicates
+	nearDuplicateSimilarity = 0.75 // least estimated shingle overlap (Jaccard) of near-identical chunks
+)
+
+// minhash is a MinHash signature of a text's word shingles: the share of positions two
+// signatures agree on estimates the share of shingles the texts have in common.
+type minhash [64]uint64
+
+// signature is the MinHash signature of text, ignoring case and punctuation. ok is false
+// when text is too short to sign.
+func signature(text string) (sig minhash, ok bool) {
+	words := wordPattern.FindAllString(strings.ToLower(text), -1)
+	if len(words) < minSignatureWords {
+		return sig, false
+	}
+
+	for i := range sig {
+		sig[i] = math.MaxUint64
+	}
+	for i := 0; i+shingleWords <= len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:i+shingleWords], " ")))
+		shingle := h.Sum64()
+		for j := range sig {
+			sig[j] = min(sig[j], mix(shingle^(uint64(j+1)*0x9e3779b97f4a7c15)))
+		}
+	}
+	return sig, true
+}
+
+// similarity estimates the Jaccard similarity of the shingles of the texts a and b sign.
+func (a minhash) similarity(b minhash) float64 {
+	same := 0
+	for i := range a {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(a))
+}
+
+// mix is the splitmix64 finalizer, which turns one shingle hash into many independent ones.
+func mix(x uint64) uint64 {
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// collapseDuplicates drops each ranked chunk that is a near-identical copy of a better
+// ranked chunk in another section, such as the same passage in two editions of a materia
+// medica, and records the copy's source on the chunk it was collapsed into.
+func collapseDuplicates(ranked rankedChunks) rankedChunks {
+	type kept struct {
+		chunk *db.ChunkModel
+		sig   minhash
+	}
+	var signed []kept
+
+	chunks := make([]*db.ChunkModel, 0, len(ranked.chunks))
+	for _, chunk := range ranked.chunks {
+		sig, ok := signature(strings.Join(chunk.Sentences, " "))
+		if !ok {
+			chunks = append(chunks, chunk)
+			continue
+		}
+
+		var original *db.ChunkModel
+		for _, k := range signed {
+			if k.chunk.SectionID != chunk.SectionID && k.sig.similarity(sig) >= nearDuplicateSimilarity {
+				original = k.chunk
+				break
+			}
+		}
+		if original == nil {
+			signed = append(signed, kept{chunk, sig})
+			chunks = append(chunks, chunk)
+			continue
+		}
+
+		if ranked.duplicates == nil {
+			ranked.duplicates = make(map[string][]string)
+		}
+		ranked.duplicates[original.ChunkID] = append(ranked.duplicates[original.ChunkID], chunk.SourceURI)
+	}
+
+	ranked.chunks = chunks
+	return ranked
+}
+
+// attribution lists the sources of a section's windows and of the copies collapsed into
+// them, the section's own first, comma separated.
+func (r rankedChunks) attribution(sectionChunks []*db.ChunkModel) string {
+	sources := []string{sectionChunks[0].SourceURI}
+	seen := map[string]bool{sectionChunks[0].SourceURI: true}
+	for _, chunk := range sectionChunks {
+		for _, source := range r.duplicates[chunk.ChunkID] {
+			if source != "" && !seen[source] {
+				seen[source] = true
+				sources = append(sources, source)
+			}
+		}
+	}
+	return strings.Join(sources, ", ")
+}
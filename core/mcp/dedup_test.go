@@ -0,0 +1,72 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	belladonnaPassage = "Sudden onset of high fever with a hot, red face and dilated pupils; the patient is worse from light, noise and jarring."
+	belladonnaReprint = "Sudden onset of high fever, with a hot red face and dilated pupils. The patient is worse from light, noise, and jarring!"
+	bryoniaPassage    = "Stitching pains in the chest, worse from the slightest motion and better from pressure and lying on the painful side."
+)
+
+func TestSignature(t *testing.T) {
+	a, ok := signature(belladonnaPassage)
+	require.True(t, ok)
+	b, _ := signature(belladonnaReprint)
+	c, _ := signature(bryoniaPassage)
+	d, _ := signature(belladonnaPassage + " Complaints come on in the afternoon.")
+
+	assert.Equal(t, 1.0, a.similarity(b), "punctuation and case are ignored")
+	assert.GreaterOrEqual(t, a.similarity(d), nearDuplicateSimilarity, "a sentence more is still a copy")
+	assert.Less(t, a.similarity(c), 0.1)
+
+	_, ok = signature("Ars., Acon., Calc.")
+	assert.False(t, ok, "too short")
+}
+
+func TestCollapseDuplicates(t *testing.T) {
+	ranked := rankedChunks{chunks: []*db.ChunkModel{
+		{ChunkID: "boericke", SectionID: "boericke", SourceURI: "boericke.md", Sentences: []string{belladonnaPassage}},
+		{ChunkID: "bryonia", SectionID: "bryonia", SourceURI: "boericke.md", Sentences: []string{bryoniaPassage}},
+		{ChunkID: "reprint", SectionID: "reprint", SourceURI: "boericke-1927.md", Sentences: []string{belladonnaReprint}},
+		{ChunkID: "window", SectionID: "boericke", SourceURI: "boericke.md", Sentences: []string{belladonnaPassage}},
+	}}
+
+	collapsed := collapseDuplicates(ranked)
+	var ids []string
+	for _, chunk := range collapsed.chunks {
+		ids = append(ids, chunk.ChunkID)
+	}
+	assert.Equal(t, []string{"boericke", "bryonia", "window"}, ids, "copies within a section are kept")
+	assert.Equal(t, "boericke.md, boericke-1927.md", collapsed.attribution(collapsed.chunks[:1]))
+	assert.Equal(t, "boericke.md", collapsed.attribution(collapsed.chunks[1:2]))
+}
+
+func TestSearchCollapsesDuplicates(t *testing.T) {
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "boericke", SectionID: "boericke", Title: "Belladonna", SourceURI: "boericke.md", Sentences: []string{belladonnaPassage}},
+		db.ChunkModel{ChunkID: "reprint", SectionID: "reprint", Title: "Belladonna", SourceURI: "boericke-1927.md", Sentences: []string{belladonnaReprint}},
+	)
+
+	search := func(opts ...SearchToolOption) []string {
+		searchTool := NewSearchTool(chunkRepository, odmtest.NewCollection[db.ChunkAnnModel](), nil, append(opts, WithLexicalOnly())...)
+		var attributions []string
+		for result := range searchTool.Run(t.Context(), "dilated pupils", SearchFilter{}, SearchPage{}) {
+			require.Empty(t, result.Error)
+			attributions = append(attributions, result.Attribution)
+		}
+		return attributions
+	}
+
+	assert.Len(t, search(), 2)
+	attributions := search(WithDeduplication())
+	require.Len(t, attributions, 1)
+	assert.Contains(t, attributions[0], "boericke.md")
+	assert.Contains(t, attributions[0], "boericke-1927.md")
+}
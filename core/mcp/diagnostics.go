@@ -0,0 +1,100 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// queryLog collects the database queries one search runs, for WithDiagnostics.
+type queryLog struct {
+	mu      sync.Mutex
+	queries []bson.D
+}
+
+type queryLogKey struct{}
+
+// withQueryLog returns a context whose searches record their queries on the log.
+func withQueryLog(ctx context.Context) (context.Context, *queryLog) {
+	log := &queryLog{}
+	return context.WithValue(ctx, queryLogKey{}, log), log
+}
+
+// logQuery records query on ctx's log, if it has one.
+func logQuery(ctx context.Context, query bson.D) {
+	log, ok := ctx.Value(queryLogKey{}).(*queryLog)
+	if !ok {
+		return
+	}
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.queries = append(log.queries, query)
+}
+
+// json is the queries recorded so far as a relaxed extended JSON array.
+func (l *queryLog) json() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	queries := make([]string, 0, len(l.queries))
+	for _, query := range l.queries {
+		b, err := bson.MarshalExtJSON(query, false, false)
+		if err != nil {
+			b = []byte(`{"error": "unprintable query"}`)
+		}
+		queries = append(queries, string(b))
+	}
+	return "[" + strings.Join(queries, ", ") + "]"
+}
+
+// textSearchQuery is the aggregation odm runs for a text search, without its projection.
+func textSearchQuery(query string, filter bson.M, limit int) bson.D {
+	return bson.D{
+		{Key: "collection", Value: db.ChunkModel{}.CollectionName()},
+		{Key: "aggregate", Value: bson.A{
+			bson.D{{Key: "$search", Value: bson.D{
+				{Key: "index", Value: db.TextSearchIndexName},
+				{Key: "text", Value: bson.D{{Key: "query", Value: query}, {Key: "path", Value: db.TextSearchPaths}}},
+			}}},
+			bson.D{{Key: "$match", Value: filter}},
+			bson.D{{Key: "$limit", Value: limit}},
+		}},
+	}
+}
+
+// vectorSearchQuery is the aggregation odm runs for a vector search, with the query
+// vector reduced to its length.
+func vectorSearchQuery(dimensions, k, numCandidates int) bson.D {
+	return bson.D{
+		{Key: "collection", Value: db.ChunkAnnModel{}.CollectionName()},
+		{Key: "aggregate", Value: bson.A{
+			bson.D{{Key: "$vectorSearch", Value: bson.D{
+				{Key: "index", Value: db.VectorIndexName},
+				{Key: "path", Value: db.VectorPath},
+				{Key: "queryVector", Value: bson.D{{Key: "dimensions", Value: dimensions}}},
+				{Key: "numCandidates", Value: numCandidates},
+				{Key: "limit", Value: k},
+				{Key: "filter", Value: db.LiveVectorsFilter()},
+			}}},
+		}},
+	}
+}
+
+// exactScanQuery stands for a vector search answered by the in-process exact scan.
+func exactScanQuery(k int) bson.D {
+	return bson.D{
+		{Key: "collection", Value: db.ChunkAnnModel{}.CollectionName()},
+		{Key: "exactScan", Value: bson.D{{Key: "limit", Value: k}}},
+	}
+}
+
+// findChunksQuery is a lookup of chunks by filter.
+func findChunksQuery(filter bson.M) bson.D {
+	return bson.D{
+		{Key: "collection", Value: db.ChunkModel{}.CollectionName()},
+		{Key: "find", Value: filter},
+	}
+}
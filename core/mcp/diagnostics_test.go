@@ -0,0 +1,75 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestSearchScores(t *testing.T) {
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "aconite", SectionID: "aconite", Title: "Aconite", Sentences: []string{"Fear of death."}},
+		db.ChunkModel{ChunkID: "arsenicum", SectionID: "arsenicum", Title: "Arsenicum", Sentences: []string{"Restless anxiety."}},
+	)
+	vectorRepository := odmtest.NewCollection(
+		db.ChunkAnnModel{ChunkID: "aconite", Embedding: bson.NewVector([]float32{1, 0})},
+		db.ChunkAnnModel{ChunkID: "arsenicum", Embedding: bson.NewVector([]float32{0, 1})},
+	)
+	searchTool := NewSearchTool(chunkRepository, vectorRepository, fixedEmbedder{1, 0})
+
+	results := make(map[string]map[string]string)
+	for result := range searchTool.Run(t.Context(), "fear of death", SearchFilter{}, SearchPage{}) {
+		require.Empty(t, result.Error)
+		results[result.Id] = result.Metadata
+	}
+
+	require.Contains(t, results, "aconite")
+	aconite := results["aconite"]
+	assert.Equal(t, "1", aconite["textRank"])
+	assert.Equal(t, "1", aconite["vectorRank"])
+	assert.NotEmpty(t, aconite["textScore"])
+	assert.Equal(t, "1", aconite["vectorScore"], "cosine scores map to [0, 1]")
+	assert.NotContains(t, aconite, "queries", "queries are only reported on request")
+
+	require.Contains(t, results, "arsenicum")
+	assert.NotContains(t, results["arsenicum"], "textRank", "only the engines that found a window score it")
+	assert.Equal(t, "2", results["arsenicum"]["vectorRank"])
+}
+
+func TestSearchDiagnostics(t *testing.T) {
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "aconite", SectionID: "aconite", Title: "Aconite", Sentences: []string{"Fear of death."}},
+	)
+	vectorRepository := odmtest.NewCollection(db.ChunkAnnModel{ChunkID: "aconite", Embedding: bson.NewVector([]float32{1, 0})})
+	searchTool := NewSearchTool(chunkRepository, vectorRepository, fixedEmbedder{1, 0}, WithDiagnostics())
+
+	var results int
+	for result := range searchTool.Run(t.Context(), "fear of death", SearchFilter{}, SearchPage{}) {
+		require.Empty(t, result.Error)
+
+		var queries []map[string]any
+		require.NoError(t, json.Unmarshal([]byte(result.Metadata["queries"]), &queries))
+		require.Len(t, queries, 2)
+		assert.Equal(t, "chunks", queries[0]["collection"])
+		assert.Contains(t, result.Metadata["queries"], `"$search"`)
+		assert.Contains(t, result.Metadata["queries"], `"query":"fear of death"`)
+		assert.Equal(t, "chunk_ann_index", queries[1]["collection"])
+		assert.Contains(t, result.Metadata["queries"], `"queryVector":{"dimensions":2}`)
+		results++
+	}
+	assert.Equal(t, 1, results)
+}
+
+func TestQueryLog(t *testing.T) {
+	logQuery(t.Context(), findChunksQuery(bson.M{"_id": "a"})) // no log, nothing happens
+
+	ctx, log := withQueryLog(t.Context())
+	assert.Equal(t, "[]", log.json())
+	logQuery(ctx, findChunksQuery(bson.M{"title": bson.Regex{Pattern: "aconite", Options: "i"}}))
+	assert.JSONEq(t, `[{"collection": "chunks", "find": {"title": {"$regularExpression": {"pattern": "aconite", "options": "i"}}}}]`, log.json())
+}
@@ -0,0 +1,155 @@
+package mcp
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// how long a query's embedding is kept in the tenant's database when
+// embedding_cache_ttl_days is unset
+const defaultEmbeddingCacheTTL = 30 * 24 * time.Hour
+
+// EmbeddingCache remembers the embeddings of search queries, so a physician repeating a
+// question, or the agent repeating a search on a later iteration, doesn't wait for the
+// embedding API again. The most recently used embeddings are held in memory across
+// tenants; the rest are read from the tenant's database, where they expire after the
+// TTL. Queries are matched ignoring case and spacing.
+type EmbeddingCache struct {
+	capacity int
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[queryEmbeddingKey]*list.Element
+	order   *list.List // front is most recently used
+}
+
+type queryEmbeddingKey struct {
+	tenant string
+	model  string
+	query  string // normalized
+}
+
+type queryEmbedding struct {
+	key       queryEmbeddingKey
+	embedding []float32
+}
+
+func ProvideEmbeddingCache(ccfg *appconfig.AppConfig) *EmbeddingCache {
+	return NewEmbeddingCache(ccfg.EmbeddingCacheSize, time.Duration(ccfg.EmbeddingCacheTTLDays)*24*time.Hour)
+}
+
+// NewEmbeddingCache holds up to capacity embeddings in memory and keeps each in the
+// database for ttl, by default 30 days. A zero capacity disables the cache.
+func NewEmbeddingCache(capacity int, ttl time.Duration) *EmbeddingCache {
+	if ttl <= 0 {
+		ttl = defaultEmbeddingCacheTTL
+	}
+	return &EmbeddingCache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[queryEmbeddingKey]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// WithEmbeddingCache reads query embeddings from cache before asking the embedder, and
+// caches those it had to ask for. model names the embedder's model, whose embeddings
+// alone are reused.
+func WithEmbeddingCache(cache *EmbeddingCache, tenant, model string, repo odm.OdmCollectionInterface[db.QueryEmbeddingModel]) SearchToolOption {
+	return func(s *SearchTool) {
+		if cache != nil && cache.capacity > 0 {
+			s.embeddingCache = &tenantEmbeddingCache{cache: cache, tenant: tenant, model: model, repo: repo}
+		}
+	}
+}
+
+// tenantEmbeddingCache is the cache of one tenant's query embeddings by one model.
+type tenantEmbeddingCache struct {
+	cache  *EmbeddingCache
+	tenant string
+	model  string
+	repo   odm.OdmCollectionInterface[db.QueryEmbeddingModel]
+}
+
+// embed returns the embedding of query, from cache when it was embedded before.
+func (t *tenantEmbeddingCache) embed(ctx context.Context, embedder embed.Embedder, query string) ([]float32, error) {
+	key := queryEmbeddingKey{tenant: t.tenant, model: t.model, query: normalizeQuery(query)}
+	if embedding, ok := t.cache.get(key); ok {
+		return embedding, nil
+	}
+
+	id, _ := odm.HashedKey(key.model, key.query)
+	stored, err := async.Await(t.repo.Find(ctx, bson.M{"_id": id, "expiresAt": bson.M{"$gt": time.Now()}}, nil, 1, 0))
+	if err != nil {
+		logger.Error("Failed to read cached query embedding", zap.String("tenant", t.tenant), zap.Error(err))
+	}
+	if len(stored) > 0 {
+		if vector, ok := stored[0].Embedding.Float32OK(); ok {
+			t.cache.put(key, vector)
+			return vector, nil
+		}
+	}
+
+	vector, err := awaitLeg(ctx, embedder.GetEmbedding(ctx, query, embedding.WithTask("retrieval.query")))
+	if err != nil {
+		return nil, err
+	}
+	t.cache.put(key, vector)
+
+	// the search goes on while the embedding is stored
+	model := db.QueryEmbeddingModel{ID: id, Model: key.model, Query: key.query, Embedding: bson.NewVector(vector), ExpiresAt: time.Now().Add(t.cache.ttl)}
+	go func() {
+		if _, err := async.Await(t.repo.Save(context.WithoutCancel(ctx), model)); err != nil {
+			logger.Error("Failed to cache query embedding", zap.String("tenant", t.tenant), zap.Error(err))
+		}
+	}()
+	return vector, nil
+}
+
+func (c *EmbeddingCache) get(key queryEmbeddingKey) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*queryEmbedding).embedding, true
+}
+
+func (c *EmbeddingCache) put(key queryEmbeddingKey, embedding []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*queryEmbedding).embedding = embedding
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&queryEmbedding{key: key, embedding: embedding})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*queryEmbedding).key)
+	}
+}
+
+// normalizeQuery lower-cases query and collapses its spacing.
+func normalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
@@ -0,0 +1,81 @@
+package mcp
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// countingEmbedder embeds every text as the same vector and counts the calls.
+type countingEmbedder struct {
+	vector []float32
+	calls  atomic.Int32
+}
+
+func (e *countingEmbedder) GetEmbedding(ctx context.Context, text string, opts ...embed.EmbedOption) <-chan async.Result[[]float32] {
+	e.calls.Add(1)
+	return async.Go(func() ([]float32, error) { return e.vector, nil })
+}
+
+func TestSearchEmbeddingCache(t *testing.T) {
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "aconite", SectionID: "aconite", Title: "Aconite", Sentences: []string{"Sudden fear of death."}},
+	)
+	vectorRepository := odmtest.NewCollection(db.ChunkAnnModel{ChunkID: "aconite", Embedding: bson.NewVector([]float32{1, 0})})
+	embeddings := odmtest.NewCollection[db.QueryEmbeddingModel]()
+	embedder := &countingEmbedder{vector: []float32{1, 0}}
+
+	search := func(cache *EmbeddingCache, model, query string) {
+		searchTool := NewSearchTool(chunkRepository, vectorRepository, embedder, WithEmbeddingCache(cache, "tenant", model, embeddings))
+		for result := range searchTool.Run(t.Context(), query, SearchFilter{}, SearchPage{}) {
+			require.Empty(t, result.Error)
+		}
+	}
+
+	cache := NewEmbeddingCache(10, time.Hour)
+	search(cache, "jina-embeddings-v4", "fear of death")
+	search(cache, "jina-embeddings-v4", "  Fear of   DEATH")
+	assert.Equal(t, int32(1), embedder.calls.Load(), "a repeated query is embedded once")
+
+	search(cache, "voyage-3.5", "fear of death")
+	assert.Equal(t, int32(2), embedder.calls.Load(), "another model's embedding isn't reused")
+
+	require.Eventually(t, func() bool { return len(embeddings.Docs()) == 2 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "fear of death", embeddings.Docs()[0].Query)
+
+	// a restarted process reads what the last one stored
+	search(NewEmbeddingCache(10, time.Hour), "jina-embeddings-v4", "fear of death")
+	assert.Equal(t, int32(2), embedder.calls.Load())
+
+	search(NewEmbeddingCache(0, time.Hour), "jina-embeddings-v4", "fear of death")
+	assert.Equal(t, int32(3), embedder.calls.Load(), "a zero size disables the cache")
+}
+
+func TestEmbeddingCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewEmbeddingCache(2, time.Hour)
+	key := func(query string) queryEmbeddingKey {
+		return queryEmbeddingKey{tenant: "tenant", model: "model", query: query}
+	}
+
+	cache.put(key("fear"), []float32{1})
+	cache.put(key("grief"), []float32{2})
+	_, ok := cache.get(key("fear"))
+	require.True(t, ok)
+	cache.put(key("anger"), []float32{3})
+
+	_, ok = cache.get(key("grief"))
+	assert.False(t, ok, "grief was used least recently")
+	for _, query := range []string{"fear", "anger"} {
+		_, ok := cache.get(key(query))
+		assert.True(t, ok, query)
+	}
+}
@@ -0,0 +1,44 @@
+package mcp
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/entities"
+)
+
+// WithEntityBoost raises the fused score of chunks tagged with the remedies, rubrics and
+// body systems the query mentions, by weight times the share of them a chunk is tagged
+// with, and re-orders the chunks by the boosted score before reranking. A chunk about
+// Aconite and fear then outranks one that only shares the query's words. Chunks
+// ingested before tagging have no entities and are left as fused.
+func WithEntityBoost(weight float64) SearchToolOption {
+	return func(s *SearchTool) { s.entityBoost = max(weight, 0) }
+}
+
+// boostEntities boosts the chunks of ranked tagged with the entities query mentions.
+func (s *SearchTool) boostEntities(query string, ranked rankedChunks) rankedChunks {
+	if s.entityBoost == 0 || len(ranked.chunks) == 0 {
+		return ranked
+	}
+	mentioned := entities.Recognize(query)
+	if len(mentioned) == 0 {
+		return ranked
+	}
+
+	boosted := ranked
+	boosted.scores = maps.Clone(ranked.scores)
+	boosted.chunks = slices.Clone(ranked.chunks)
+	for _, chunk := range boosted.chunks {
+		if matched := entities.Matches(chunk.Entities, mentioned); matched > 0 {
+			boosted.scores[chunk.ChunkID] *= 1 + s.entityBoost*float64(matched)/float64(len(mentioned))
+		}
+	}
+	// ties keep their fused order
+	slices.SortStableFunc(boosted.chunks, func(a, b *db.ChunkModel) int {
+		return cmp.Compare(boosted.scores[b.ChunkID], boosted.scores[a.ChunkID])
+	})
+	return boosted
+}
@@ -0,0 +1,36 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoostEntities(t *testing.T) {
+	ranked := rankedChunks{
+		chunks: []*db.ChunkModel{
+			{ChunkID: "kent", Entities: []string{"rubric:fear", "system:mind"}},
+			{ChunkID: "boericke", Entities: []string{"remedy:aconitum napellus", "rubric:fear"}},
+			{ChunkID: "untagged"},
+			{ChunkID: "clarke", Entities: []string{"remedy:aconitum napellus"}},
+		},
+		scores: map[string]float64{"kent": 0.032, "boericke": 0.03, "untagged": 0.029, "clarke": 0.028},
+	}
+	ids := func(ranked rankedChunks) []string {
+		var ids []string
+		for _, chunk := range ranked.chunks {
+			ids = append(ids, chunk.ChunkID)
+		}
+		return ids
+	}
+
+	boosted := NewSearchTool(nil, nil, nil, WithEntityBoost(0.25)).boostEntities("Aconite for fear of death", ranked)
+	assert.Equal(t, []string{"boericke", "kent", "clarke", "untagged"}, ids(boosted))
+	assert.InDelta(t, 0.0375, boosted.scores["boericke"], 1e-9, "both entities matched")
+	assert.InDelta(t, 0.036, boosted.scores["kent"], 1e-9, "one of two matched")
+	assert.Equal(t, 0.032, ranked.scores["kent"], "the fused scores are kept")
+
+	assert.Equal(t, ids(ranked), ids(NewSearchTool(nil, nil, nil, WithEntityBoost(0.25)).boostEntities("What is the dose?", ranked)))
+	assert.Equal(t, ids(ranked), ids(NewSearchTool(nil, nil, nil).boostEntities("Aconite for fear of death", ranked)), "boosting is off by default")
+}
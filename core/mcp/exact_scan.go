@@ -0,0 +1,206 @@
+package mcp
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/go-collection-boot/ds"
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"go.uber.org/zap"
+)
+
+const (
+	// How long a tenant's vectors are served from memory before they are re-read. Chunks
+	// are embedded asynchronously after ingestion, so a corpus version alone does not
+	// tell when the vectors are complete.
+	exactScanReloadInterval = time.Minute
+	// How long a tenant's vectors are kept in memory after its last search.
+	exactScanIdleTTL        = 30 * time.Minute
+	defaultExactScanTenants = 50
+)
+
+// ExactVectorIndex scores query embeddings against every chunk vector of small tenants
+// in-process. Below a few thousand chunks a full cosine scan is faster than a round
+// trip to the ANN index, exact rather than approximate, and works before the tenant's
+// vector index has been built. Larger tenants keep using the ANN index. Tenants are
+// dropped from memory exactScanIdleTTL after their last search, and the least recently
+// searched beyond capacity sooner.
+type ExactVectorIndex struct {
+	maxChunks int
+	capacity  int
+
+	mu      sync.Mutex
+	tenants map[string]*list.Element
+	order   *list.List // front is most recently searched
+}
+
+type tenantVectors struct {
+	tenant     string
+	searchedAt time.Time // guarded by ExactVectorIndex.mu
+
+	mu       sync.Mutex // held while loading
+	loadedAt time.Time
+	tooLarge bool
+	ids      []string
+	vectors  [][]float32 // unit length
+}
+
+func ProvideExactVectorIndex(ccfg *appconfig.AppConfig) *ExactVectorIndex {
+	return NewExactVectorIndex(ccfg.ExactVectorScanMaxChunks, ccfg.ExactVectorScanTenants)
+}
+
+// NewExactVectorIndex scans tenants with at most maxChunks vectors, holding those of up
+// to capacity tenants, by default 50, in memory. A zero maxChunks disables it.
+func NewExactVectorIndex(maxChunks, capacity int) *ExactVectorIndex {
+	if capacity <= 0 {
+		capacity = defaultExactScanTenants
+	}
+	return &ExactVectorIndex{
+		maxChunks: maxChunks,
+		capacity:  capacity,
+		tenants:   make(map[string]*list.Element),
+		order:     list.New(),
+	}
+}
+
+// Search returns the k chunks closest to embedding, scored as the ANN index scores
+// cosine similarity, from 0 to 1. ok is false when the tenant has more than maxChunks
+// vectors and the caller should use the ANN index instead.
+func (x *ExactVectorIndex) Search(ctx context.Context, tenant string, repo odm.OdmCollectionInterface[db.ChunkAnnModel], embedding []float32, k int) (hits []odm.SearchHit[db.ChunkAnnModel], ok bool, err error) {
+	if x.maxChunks <= 0 {
+		return nil, false, nil
+	}
+
+	vectors, err := x.load(ctx, tenant, repo)
+	if err != nil || vectors.tooLarge {
+		return nil, false, err
+	}
+
+	query := normalized(embedding)
+	if query == nil {
+		return nil, true, nil
+	}
+
+	h := ds.NewMinHeap(func(a, b odm.SearchHit[db.ChunkAnnModel]) bool { return a.Score < b.Score })
+	for i, vector := range vectors.vectors {
+		if len(vector) != len(query) {
+			continue
+		}
+
+		var score float64
+		for j := range vector {
+			score += float64(vector[j]) * float64(query[j])
+		}
+
+		h.Push(odm.SearchHit[db.ChunkAnnModel]{Doc: db.ChunkAnnModel{ChunkID: vectors.ids[i]}, Score: (1 + score) / 2})
+		if h.Len() > k {
+			h.Pop()
+		}
+	}
+
+	hits = h.ToSortedSlice()
+	slices.Reverse(hits) // highest score first
+	return hits, true, nil
+}
+
+// load returns the vectors of the tenant's live chunks, reading them again once they
+// are older than exactScanReloadInterval. Concurrent searches of one tenant share a single read.
+func (x *ExactVectorIndex) load(ctx context.Context, tenant string, repo odm.OdmCollectionInterface[db.ChunkAnnModel]) (*tenantVectors, error) {
+	vectors := x.entry(tenant)
+
+	vectors.mu.Lock()
+	defer vectors.mu.Unlock()
+
+	if time.Since(vectors.loadedAt) < exactScanReloadInterval {
+		return vectors, nil
+	}
+
+	count, err := async.Await(repo.Count(ctx, db.LiveVectorsFilter()))
+	if err != nil {
+		return nil, err
+	}
+	if count > int64(x.maxChunks) {
+		vectors.tooLarge, vectors.ids, vectors.vectors = true, nil, nil
+		vectors.loadedAt = time.Now()
+		return vectors, nil
+	}
+
+	docs, err := async.Await(repo.Find(ctx, db.LiveVectorsFilter(), nil, int64(x.maxChunks), 0))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(docs))
+	unit := make([][]float32, 0, len(docs))
+	for _, doc := range docs {
+		embedding, ok := doc.Embedding.Float32OK()
+		if !ok {
+			continue
+		}
+		if v := normalized(embedding); v != nil {
+			ids = append(ids, doc.ChunkID)
+			unit = append(unit, v)
+		}
+	}
+
+	vectors.tooLarge, vectors.ids, vectors.vectors = false, ids, unit
+	vectors.loadedAt = time.Now()
+	logger.Info("Loaded vectors for exact scan", zap.String("tenant", tenant), zap.Int("vectors", len(ids)))
+	return vectors, nil
+}
+
+// entry returns the tenant's vectors, empty when they aren't held, and marks them most
+// recently searched. Tenants idle longer than exactScanIdleTTL, and the least recently
+// searched beyond capacity, are dropped.
+func (x *ExactVectorIndex) entry(tenant string) *tenantVectors {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	now := time.Now()
+	for oldest := x.order.Back(); oldest != nil && now.Sub(oldest.Value.(*tenantVectors).searchedAt) > exactScanIdleTTL; oldest = x.order.Back() {
+		x.order.Remove(oldest)
+		delete(x.tenants, oldest.Value.(*tenantVectors).tenant)
+	}
+
+	element, ok := x.tenants[tenant]
+	if ok {
+		x.order.MoveToFront(element)
+	} else {
+		element = x.order.PushFront(&tenantVectors{tenant: tenant})
+		x.tenants[tenant] = element
+	}
+	for x.order.Len() > x.capacity {
+		oldest := x.order.Back()
+		x.order.Remove(oldest)
+		delete(x.tenants, oldest.Value.(*tenantVectors).tenant)
+	}
+
+	vectors := element.Value.(*tenantVectors)
+	vectors.searchedAt = now
+	return vectors
+}
+
+func normalized(v []float32) []float32 {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return nil
+	}
+
+	norm = math.Sqrt(norm)
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = float32(float64(x) / norm)
+	}
+	return out
+}
@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestExactVectorIndex(t *testing.T) {
+	ctx := t.Context()
+
+	vectors := odmtest.NewCollection(
+		db.ChunkAnnModel{ChunkID: "a", Embedding: bson.NewVector([]float32{1, 0, 0})},
+		db.ChunkAnnModel{ChunkID: "b", Embedding: bson.NewVector([]float32{0.7, 0.7, 0})},
+		db.ChunkAnnModel{ChunkID: "c", Embedding: bson.NewVector([]float32{0, 0, 1})},
+	)
+
+	t.Run("ScoresSmallTenantsExactly", func(t *testing.T) {
+		index := NewExactVectorIndex(10, 0)
+
+		hits, ok, err := index.Search(ctx, "small", vectors, []float32{2, 0.2, 0}, 2)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Len(t, hits, 2)
+		assert.Equal(t, "a", hits[0].Doc.ChunkID)
+		assert.Equal(t, "b", hits[1].Doc.ChunkID)
+		assert.InDelta(t, 0.9975, hits[0].Score, 0.001, "cosine 0.995, scored as the ANN index scores it")
+	})
+
+	t.Run("LargeTenantsUseANN", func(t *testing.T) {
+		index := NewExactVectorIndex(2, 0)
+
+		_, ok, err := index.Search(ctx, "large", vectors, []float32{1, 0, 0}, 2)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("EvictsLeastRecentlySearchedTenants", func(t *testing.T) {
+		index := NewExactVectorIndex(10, 2)
+
+		for _, tenant := range []string{"a", "b", "a", "c"} {
+			_, ok, err := index.Search(ctx, tenant, vectors, []float32{1, 0, 0}, 1)
+			require.NoError(t, err)
+			require.True(t, ok)
+		}
+		assert.Len(t, index.tenants, 2)
+		assert.Contains(t, index.tenants, "a")
+		assert.Contains(t, index.tenants, "c")
+
+		index.tenants["a"].Value.(*tenantVectors).searchedAt = time.Now().Add(-exactScanIdleTTL - time.Second)
+		_, _, err := index.Search(ctx, "c", vectors, []float32{1, 0, 0}, 1)
+		require.NoError(t, err)
+		assert.NotContains(t, index.tenants, "a", "idle tenants are dropped")
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		_, ok, err := NewExactVectorIndex(0, 0).Search(ctx, "small", vectors, []float32{1, 0, 0}, 2)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,62 @@
+package mcp
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+)
+
+// Facets count the sections of a search's candidates, all the chunks it ranked and not
+// just the page sent, by the values of the fields its filter narrows.
+type Facets struct {
+	Books    map[string]int `json:"books,omitempty"`
+	Authors  map[string]int `json:"authors,omitempty"`
+	Chapters map[string]int `json:"chapters,omitempty"` // a section path's first heading
+}
+
+// WithFacets adds the search's Facets, as JSON, to the facets metadata entry of the
+// first ranked result it sends, so the agent can tell how widely the evidence spreads
+// and a UI can offer the values as filters. Speculative results don't carry them.
+func WithFacets() SearchToolOption {
+	return func(s *SearchTool) { s.facets = true }
+}
+
+// countFacets counts each section of chunks once, by the fields of its best ranked
+// chunk. Empty values aren't counted.
+func countFacets(chunks []*db.ChunkModel) Facets {
+	facets := Facets{Books: map[string]int{}, Authors: map[string]int{}, Chapters: map[string]int{}}
+	count := func(counts map[string]int, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			counts[value]++
+		}
+	}
+
+	seen := make(map[string]bool, len(chunks))
+	for _, chunk := range chunks {
+		if seen[chunk.SectionID] {
+			continue
+		}
+		seen[chunk.SectionID] = true
+
+		chapter, _, _ := strings.Cut(chunk.SectionPath, " | ")
+		count(facets.Books, chunk.Book)
+		count(facets.Authors, chunk.Author)
+		count(facets.Chapters, chapter)
+	}
+	return facets
+}
+
+// ParseFacets reads the facets metadata entry of a search result, if it has one.
+func ParseFacets(metadata map[string]string) (Facets, bool) {
+	var facets Facets
+	if metadata["facets"] == "" || json.Unmarshal([]byte(metadata["facets"]), &facets) != nil {
+		return Facets{}, false
+	}
+	return facets, true
+}
+
+func (f Facets) json() string {
+	b, _ := json.Marshal(f)
+	return string(b)
+}
@@ -0,0 +1,55 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestCountFacets(t *testing.T) {
+	facets := countFacets([]*db.ChunkModel{
+		{SectionID: "aconite", Book: "Materia Medica", Author: "Boericke", SectionPath: "Aconite | Mind"},
+		{SectionID: "aconite", Book: "Materia Medica", Author: "Boericke", SectionPath: "Aconite | Mind"},
+		{SectionID: "gelsemium", Book: "Materia Medica", Author: "Boericke", SectionPath: "Gelsemium"},
+		{SectionID: "kent", Book: "Lectures", Author: "Kent", SectionPath: "Aconite | Fever"},
+		{SectionID: "notes", SectionPath: ""},
+	})
+
+	assert.Equal(t, map[string]int{"Materia Medica": 2, "Lectures": 1}, facets.Books, "each section counts once")
+	assert.Equal(t, map[string]int{"Boericke": 2, "Kent": 1}, facets.Authors)
+	assert.Equal(t, map[string]int{"Aconite": 2, "Gelsemium": 1}, facets.Chapters)
+}
+
+func TestSearchFacets(t *testing.T) {
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "aconite", SectionID: "aconite", Title: "Aconite", Book: "Materia Medica", Author: "Boericke", SectionPath: "Aconite", Sentences: []string{"Sudden fear of death."}},
+		db.ChunkModel{ChunkID: "gelsemium", SectionID: "gelsemium", Title: "Gelsemium", Book: "Lectures", Author: "Kent", SectionPath: "Gelsemium", Sentences: []string{"Fear of death with trembling."}},
+	)
+	vectorRepository := odmtest.NewCollection(
+		db.ChunkAnnModel{ChunkID: "aconite", Embedding: bson.NewVector([]float32{1, 0})},
+		db.ChunkAnnModel{ChunkID: "gelsemium", Embedding: bson.NewVector([]float32{1, 0})},
+	)
+
+	search := func(page SearchPage, opts ...SearchToolOption) []Facets {
+		searchTool := NewSearchTool(chunkRepository, vectorRepository, fixedEmbedder{1, 0}, opts...)
+		var found []Facets
+		for result := range searchTool.Run(t.Context(), "fear of death", SearchFilter{}, page) {
+			require.Empty(t, result.Error)
+			if facets, ok := ParseFacets(result.Metadata); ok {
+				found = append(found, facets)
+			}
+		}
+		return found
+	}
+
+	found := search(SearchPage{Limit: 1}, WithFacets())
+	require.Len(t, found, 1, "one result carries the facets")
+	assert.Equal(t, map[string]int{"Materia Medica": 1, "Lectures": 1}, found[0].Books, "counted beyond the page")
+	assert.Equal(t, map[string]int{"Boericke": 1, "Kent": 1}, found[0].Authors)
+
+	assert.Empty(t, search(SearchPage{}), "facets are off by default")
+}
@@ -0,0 +1,43 @@
+package mcp
+
+import (
+	"encoding/json"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+)
+
+// WithFigures adds the figures a result's windows caption or mention, as JSON, to its
+// figures metadata entry, so a UI can show them next to the text. The agent sees
+// metadata too, so it is off by default.
+func WithFigures() SearchToolOption {
+	return func(s *SearchTool) { s.figures = true }
+}
+
+// sectionFigures lists the figures of a section's windows once each, in window order.
+func sectionFigures(chunks []*db.ChunkModel) []db.FigureRef {
+	var figures []db.FigureRef
+	seen := map[string]bool{}
+	for _, chunk := range chunks {
+		for _, figure := range chunk.Figures {
+			if !seen[figure.FigureID] {
+				seen[figure.FigureID] = true
+				figures = append(figures, figure)
+			}
+		}
+	}
+	return figures
+}
+
+// ParseFigures reads the figures metadata entry of a search result.
+func ParseFigures(metadata map[string]string) ([]db.FigureRef, bool) {
+	var figures []db.FigureRef
+	if metadata["figures"] == "" || json.Unmarshal([]byte(metadata["figures"]), &figures) != nil {
+		return nil, false
+	}
+	return figures, true
+}
+
+func figuresJSON(figures []db.FigureRef) string {
+	b, _ := json.Marshal(figures)
+	return string(b)
+}
@@ -0,0 +1,41 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestSearchFigures(t *testing.T) {
+	pupils := db.FigureRef{FigureID: "fig-3", Label: "Fig. 3", Caption: "Fig. 3. Dilated pupils of Belladonna."}
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "bell_0", SectionID: "bell", Title: "Belladonna", SectionPath: "Belladonna", NextChunkID: "bell_1",
+			Sentences: []string{"The pupils are widely dilated, see Fig. 3."}, Figures: []db.FigureRef{pupils}},
+		db.ChunkModel{ChunkID: "bell_1", SectionID: "bell", Title: "Belladonna", SectionPath: "Belladonna", WindowIndex: 1, PrevChunkID: "bell_0",
+			Sentences: []string{"Fig.", "3.", "Dilated pupils of Belladonna."}, Figures: []db.FigureRef{pupils}},
+	)
+	vectorRepository := odmtest.NewCollection(
+		db.ChunkAnnModel{ChunkID: "bell_0", Embedding: bson.NewVector([]float32{1, 0})},
+		db.ChunkAnnModel{ChunkID: "bell_1", Embedding: bson.NewVector([]float32{1, 0})},
+	)
+
+	search := func(opts ...SearchToolOption) map[string]string {
+		searchTool := NewSearchTool(chunkRepository, vectorRepository, fixedEmbedder{1, 0}, opts...)
+		for result := range searchTool.Run(t.Context(), "dilated pupils", SearchFilter{}, SearchPage{}) {
+			require.Empty(t, result.Error)
+			return result.Metadata
+		}
+		return nil
+	}
+
+	figures, ok := ParseFigures(search(WithFigures()))
+	require.True(t, ok)
+	assert.Equal(t, []db.FigureRef{pupils}, figures, "a figure both windows link to is listed once")
+
+	_, ok = ParseFigures(search())
+	assert.False(t, ok, "figures are off by default")
+}
@@ -0,0 +1,105 @@
+package mcp
+
+import (
+	"cmp"
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SaiNageswarS/medicine-rag/core/entities"
+)
+
+// Highlight marks where a result's sentence mentions a term or entity of the query, so a
+// client can show why the result was found. Start and End count characters (Unicode
+// code points) from the start of the sentence; End is exclusive.
+type Highlight struct {
+	Sentence int    `json:"sentence"` // index into the result's sentences
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Match    string `json:"match"` // the query term, lower case, or the entity's tag
+}
+
+// highlightStopWords are common words of three letters or more that are not worth
+// marking.
+var highlightStopWords = []string{"the", "and", "for", "are", "but", "not", "with", "this", "that", "these", "those",
+	"from", "what", "which", "when", "who", "how", "has", "have", "was", "were", "any", "can", "does"}
+
+// highlighter finds the terms of a query, the synonyms they expand to, and the entities
+// it mentions in the sentences of a result.
+type highlighter struct {
+	terms    *regexp.Regexp // nil when the query has no term worth marking
+	entities []string       // tags of the entities the query mentions
+}
+
+// highlighter is the highlighter of query's text.
+func (s *SearchTool) highlighter(query string) highlighter {
+	text := ParseQuery(query).Text()
+
+	var terms []string
+	for _, term := range queryTerms(s.synonyms.Expand(text)) {
+		if !slices.Contains(terms, term) && !slices.Contains(highlightStopWords, term) {
+			terms = append(terms, regexp.QuoteMeta(term))
+		}
+	}
+
+	var h highlighter
+	if len(terms) > 0 {
+		h.terms = regexp.MustCompile(`(?i)\b(?:` + strings.Join(terms, "|") + `)\b`)
+	}
+	for _, entity := range entities.Recognize(text) {
+		h.entities = append(h.entities, entity.Tag())
+	}
+	return h
+}
+
+// highlights marks the query's terms and entities in sentences. Entities are marked
+// before terms, and a longer mark before a shorter one; marks never overlap.
+func (h highlighter) highlights(sentences []string) []Highlight {
+	var marks []Highlight
+	for i, sentence := range sentences {
+		var found []Highlight
+		if len(h.entities) > 0 {
+			for _, mention := range entities.Mentions(sentence) {
+				if slices.Contains(h.entities, mention.Tag()) {
+					found = append(found, Highlight{Sentence: i, Start: mention.Start, End: mention.End, Match: mention.Tag()})
+				}
+			}
+		}
+		if h.terms != nil {
+			for _, span := range h.terms.FindAllStringIndex(sentence, -1) {
+				found = append(found, Highlight{Sentence: i, Start: span[0], End: span[1], Match: strings.ToLower(sentence[span[0]:span[1]])})
+			}
+		}
+		slices.SortStableFunc(found, func(a, b Highlight) int {
+			return cmp.Or(cmp.Compare(a.Start, b.Start), cmp.Compare(b.End, a.End))
+		})
+
+		end := 0
+		for _, mark := range found {
+			if mark.Start < end {
+				continue
+			}
+			end = mark.End
+			// byte offsets to characters
+			mark.Start, mark.End = utf8.RuneCountInString(sentence[:mark.Start]), utf8.RuneCountInString(sentence[:mark.End])
+			marks = append(marks, mark)
+		}
+	}
+	return marks
+}
+
+// ParseHighlights reads the highlights metadata entry of a search result.
+func ParseHighlights(metadata map[string]string) ([]Highlight, bool) {
+	var highlights []Highlight
+	if metadata["highlights"] == "" || json.Unmarshal([]byte(metadata["highlights"]), &highlights) != nil {
+		return nil, false
+	}
+	return highlights, true
+}
+
+func highlightsJSON(highlights []Highlight) string {
+	b, _ := json.Marshal(highlights)
+	return string(b)
+}
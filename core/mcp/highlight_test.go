@@ -0,0 +1,49 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHighlights(t *testing.T) {
+	searchTool := NewSearchTool(nil, nil, nil, WithSynonyms(NewSynonymDictionary([]string{"Tuberculinum", "Tub."})))
+	marks := searchTool.highlighter(`Aconite or arsenicum album for fear of death NOT "grief", tuberculinum`)
+
+	highlights := marks.highlights([]string{
+		"Aconitum napellus — sudden fear of death.",
+		"Compare Ars. for the restlessness, and Tub.",
+		"Grief with sighing.",
+	})
+	assert.Equal(t, []Highlight{
+		{Sentence: 0, Start: 0, End: 17, Match: "remedy:aconitum napellus"},
+		{Sentence: 0, Start: 27, End: 31, Match: "rubric:fear"},
+		{Sentence: 0, Start: 35, End: 40, Match: "death"},
+		{Sentence: 1, Start: 8, End: 12, Match: "remedy:arsenicum album"},
+		{Sentence: 1, Start: 39, End: 42, Match: "tub"},
+	}, highlights, "offsets count characters; excluded terms and stop words aren't marked")
+
+	assert.Empty(t, searchTool.highlighter("of the").highlights([]string{"Worse of the cold."}))
+}
+
+func TestSearchHighlights(t *testing.T) {
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "aconite", SectionID: "aconite", Title: "Aconite", Sentences: []string{"Sudden fear of death.", "Worse at night."}},
+	)
+	searchTool := NewSearchTool(chunkRepository, odmtest.NewCollection[db.ChunkAnnModel](), nil, WithLexicalOnly())
+
+	var found []Highlight
+	for result := range searchTool.Run(t.Context(), "death at night", SearchFilter{}, SearchPage{}) {
+		require.Empty(t, result.Error)
+		highlights, ok := ParseHighlights(result.Metadata)
+		require.True(t, ok)
+		found = append(found, highlights...)
+	}
+	assert.Equal(t, []Highlight{
+		{Sentence: 0, Start: 15, End: 20, Match: "death"},
+		{Sentence: 1, Start: 9, End: 14, Match: "night"},
+	}, found)
+}
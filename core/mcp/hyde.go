@@ -0,0 +1,93 @@
+package mcp
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
+	"github.com/SaiNageswarS/medicine-rag/core/llmrouter"
+	"go.uber.org/zap"
+)
+
+// hydeTimeout bounds writing the hypothetical passage; a search whose passage is late
+// goes on with the query's vector hits alone.
+const hydeTimeout = 8 * time.Second
+
+const hydeSystemPrompt = "You write the passage a homeopathic materia medica or repertory would contain in answer to a search query. " +
+	"Write three to five sentences in the style of the classical literature: remedy names, symptoms, modalities and concomitants. " +
+	"It is used only to find similar passages, so write a plausible one even if unsure. Reply with the passage only."
+
+// WithHyDE also searches the vector index with the embedding of a passage model writes
+// to answer the query (hypothetical document embeddings): a description of symptoms
+// reads more like the corpus than a short question does, so it finds passages the
+// query's embedding misses. The hits of both embeddings are merged into one vector
+// ranking before fusion. The passage costs a model call per vector search; it is
+// skipped when lexical hits answer the query, and a passage that fails or is late is
+// left out.
+func WithHyDE(model llm.LLMClient) SearchToolOption {
+	return func(s *SearchTool) { s.hyde = model }
+}
+
+var errNoPassage = errors.New("no hypothetical passage")
+
+// hypotheticalPassageEmbedding writes a passage answering query and embeds it like the
+// corpus's passages.
+func (s *SearchTool) hypotheticalPassageEmbedding(ctx context.Context, query string) <-chan async.Result[[]float32] {
+	return async.Go(func() ([]float32, error) {
+		ctx, cancel := context.WithTimeout(ctx, hydeTimeout)
+		defer cancel()
+
+		var passage strings.Builder
+		err := s.hyde.GenerateInference(ctx,
+			[]llm.Message{{Role: "user", Content: query}},
+			func(chunk string) error {
+				passage.WriteString(chunk)
+				return nil
+			},
+			llmrouter.WithSystemPrompt(hydeSystemPrompt),
+			llmrouter.WithTemperature(0),
+		)
+		if err != nil {
+			logger.Error("Failed to write hypothetical passage", zap.String("query", query), zap.Error(err))
+			return nil, err
+		}
+		if strings.TrimSpace(passage.String()) == "" {
+			return nil, errNoPassage
+		}
+		return async.Await(s.embedder.GetEmbedding(ctx, passage.String(), embedding.WithTask("retrieval.passage")))
+	})
+}
+
+// unionVectorHits merges the vector hits of two embeddings into one ranking by score:
+// both are cosine scores against the same index, so a chunk found by both keeps its
+// better score.
+func unionVectorHits(a, b engineHits) engineHits {
+	union := engineHits{ranks: make(map[string]int, len(a.ranks)+len(b.ranks)), scores: make(map[string]float64, len(a.scores)+len(b.scores))}
+	for _, hits := range []engineHits{a, b} {
+		for id := range hits.ranks {
+			if score, ok := union.scores[id]; !ok || hits.scores[id] > score {
+				union.scores[id] = hits.scores[id]
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(union.scores))
+	for id := range union.scores {
+		ids = append(ids, id)
+	}
+	// ties go by ID so the ranking is the same every time
+	slices.SortFunc(ids, func(x, y string) int {
+		return cmp.Or(cmp.Compare(union.scores[y], union.scores[x]), strings.Compare(x, y))
+	})
+	for i, id := range ids {
+		union.ranks[id] = i + 1
+	}
+	return union
+}
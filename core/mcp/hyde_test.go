@@ -0,0 +1,88 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type passageModel struct {
+	llm.LLMClient
+	passage string
+	err     error
+	calls   int
+}
+
+func (m *passageModel) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	m.calls++
+	if m.err != nil {
+		return m.err
+	}
+	return callback(m.passage)
+}
+
+// textEmbedder embeds each text it knows as given, and any other as fallback.
+type textEmbedder struct {
+	vectors  map[string][]float32
+	fallback []float32
+}
+
+func (e textEmbedder) GetEmbedding(ctx context.Context, text string, opts ...embed.EmbedOption) <-chan async.Result[[]float32] {
+	return async.Go(func() ([]float32, error) {
+		if vector, ok := e.vectors[text]; ok {
+			return vector, nil
+		}
+		return e.fallback, nil
+	})
+}
+
+func TestUnionVectorHits(t *testing.T) {
+	query := engineHits{ranks: map[string]int{"a": 1, "b": 2}, scores: map[string]float64{"a": 0.9, "b": 0.6}}
+	passage := engineHits{ranks: map[string]int{"c": 1, "b": 2}, scores: map[string]float64{"c": 0.95, "b": 0.8}}
+
+	union := unionVectorHits(query, passage)
+	assert.Equal(t, map[string]int{"c": 1, "a": 2, "b": 3}, union.ranks)
+	assert.Equal(t, 0.8, union.scores["b"], "a chunk both found keeps its better score")
+}
+
+func TestSearchHyDE(t *testing.T) {
+	const passage = "Gelsemium: dullness, drowsiness and trembling; fever without thirst."
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "aconite", SectionID: "aconite", Title: "Aconite", Sentences: []string{"Sudden fear."}},
+		db.ChunkModel{ChunkID: "gelsemium", SectionID: "gelsemium", Title: "Gelsemium", Sentences: []string{"Drowsy, trembling."}},
+	)
+	vectorRepository := odmtest.NewCollection(
+		db.ChunkAnnModel{ChunkID: "aconite", Embedding: bson.NewVector([]float32{1, 0})},
+		db.ChunkAnnModel{ChunkID: "gelsemium", Embedding: bson.NewVector([]float32{0, 1})},
+	)
+	embedder := textEmbedder{vectors: map[string][]float32{passage: {0, 1}}, fallback: []float32{1, 0}}
+
+	// vectorScore of each section, searching by vector alone
+	search := func(opts ...SearchToolOption) map[string]string {
+		searchTool := NewSearchTool(chunkRepository, vectorRepository, embedder, append(opts, WithFusionWeights(FusionWeights{Vector: 1}))...)
+		scores := make(map[string]string)
+		for result := range searchTool.Run(t.Context(), "flu with heaviness", SearchFilter{}, SearchPage{}) {
+			require.Empty(t, result.Error)
+			scores[result.Id] = result.Metadata["vectorScore"]
+		}
+		return scores
+	}
+
+	assert.Equal(t, map[string]string{"aconite": "1", "gelsemium": "0.5"}, search())
+
+	model := &passageModel{passage: passage}
+	assert.Equal(t, map[string]string{"aconite": "1", "gelsemium": "1"}, search(WithHyDE(model)), "the passage finds Gelsemium")
+	assert.Equal(t, 1, model.calls)
+
+	failing := &passageModel{err: errors.New("model unavailable")}
+	assert.Equal(t, map[string]string{"aconite": "1", "gelsemium": "0.5"}, search(WithHyDE(failing)), "the query's hits are kept")
+}
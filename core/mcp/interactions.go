@@ -0,0 +1,177 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// interaction check parameters.
+const (
+	maxInteractionSubstances = 10  // substances checked per call
+	maxInteractionRecords    = 500 // records read per check
+)
+
+// InteractionChecker looks up known antidotes and incompatibilities between remedies and
+// conventional drugs in the tenant's interactions collection. It backs both the agent's
+// interaction tool and the Interactions gRPC service.
+type InteractionChecker struct {
+	repository odm.OdmCollectionInterface[db.InteractionModel]
+}
+
+func NewInteractionChecker(repository odm.OdmCollectionInterface[db.InteractionModel]) *InteractionChecker {
+	return &InteractionChecker{repository: repository}
+}
+
+// InteractionReport is the outcome of checking a list of substances.
+type InteractionReport struct {
+	Substances   []string // as checked, after dropping blanks and repeats
+	Interactions []db.InteractionModel
+	// Unchecked pairs of substances with no recorded interaction.
+	NoRecord [][2]string
+	// Substances that no interaction record mentions, usually a misspelling or a
+	// substance the collection does not cover.
+	Unknown []string
+}
+
+// Check returns the interactions recorded between any two of the substances. A single
+// substance is checked against everything: all of its interactions are returned.
+func (c *InteractionChecker) Check(ctx context.Context, substances []string) (*InteractionReport, error) {
+	report := &InteractionReport{Substances: distinctTerms(substances, maxInteractionSubstances)}
+	if len(report.Substances) == 0 {
+		return report, nil
+	}
+
+	keys := make([]string, len(report.Substances))
+	for i, substance := range report.Substances {
+		keys[i] = db.InteractionKey(substance)
+	}
+
+	records, err := async.Await(c.repository.Find(ctx, bson.M{"keys": bson.M{"$in": keys}}, nil, maxInteractionRecords, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	known := make([]bool, len(keys))
+	paired := make(map[[2]int]bool)
+	for _, record := range records {
+		first, second := matchingSubstances(keys, record.Substances[0]), matchingSubstances(keys, record.Substances[1])
+		for _, i := range append(slices.Clone(first), second...) {
+			known[i] = true
+		}
+
+		include := len(keys) == 1
+		for _, i := range first {
+			for _, j := range second {
+				if i != j {
+					include = true
+					paired[[2]int{min(i, j), max(i, j)}] = true
+				}
+			}
+		}
+		if include {
+			report.Interactions = append(report.Interactions, record)
+		}
+	}
+
+	for i := range keys {
+		if !known[i] {
+			report.Unknown = append(report.Unknown, report.Substances[i])
+		}
+		for j := i + 1; j < len(keys); j++ {
+			if !paired[[2]int{i, j}] {
+				report.NoRecord = append(report.NoRecord, [2]string{report.Substances[i], report.Substances[j]})
+			}
+		}
+	}
+	return report, nil
+}
+
+// matchingSubstances returns the indexes of the keys that name the substance.
+func matchingSubstances(keys []string, substance db.InteractionSubstance) []int {
+	names := substance.Keys()
+	var matches []int
+	for i, key := range keys {
+		if slices.Contains(names, key) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+// Run sends one result per interaction found and a closing summary of the pairs and
+// substances with no record, so the agent does not mistake silence for safety.
+func (c *InteractionChecker) Run(ctx context.Context, substances []string) <-chan *schema.ToolResultChunk {
+	out := make(chan *schema.ToolResultChunk, maxInteractionSubstances+1)
+
+	go func() {
+		defer close(out)
+
+		report, err := c.Check(ctx, substances)
+		if err != nil {
+			logger.Error("Failed to check interactions", zap.Strings("substances", substances), zap.Error(err))
+			out <- &schema.ToolResultChunk{Error: "Interaction check failed"}
+			return
+		}
+
+		for _, interaction := range report.Interactions {
+			sentences := []string{DescribeInteraction(interaction)}
+			if interaction.Note != "" {
+				sentences = append(sentences, interaction.Note)
+			}
+			out <- &schema.ToolResultChunk{
+				Title:       "Interaction: " + interaction.Substances[0].Name + " / " + interaction.Substances[1].Name,
+				Attribution: interaction.Source,
+				Id:          interaction.InteractionID,
+				Sentences:   sentences,
+				Metadata:    map[string]string{"interactionId": interaction.InteractionID, "kind": interaction.Kind},
+			}
+		}
+
+		var gaps []string
+		for _, pair := range report.NoRecord {
+			gaps = append(gaps, fmt.Sprintf("No interaction between %s and %s is recorded.", pair[0], pair[1]))
+		}
+		for _, substance := range report.Unknown {
+			gaps = append(gaps, fmt.Sprintf("%s does not appear in the interaction records.", substance))
+		}
+		if len(gaps) > 0 {
+			out <- &schema.ToolResultChunk{
+				Title:     "Interaction check: " + strings.Join(report.Substances, ", "),
+				Id:        "interaction-check",
+				Sentences: append(gaps, "The absence of a record does not mean a combination is safe."),
+			}
+		}
+	}()
+
+	return out
+}
+
+// DescribeInteraction states an interaction as a sentence.
+func DescribeInteraction(interaction db.InteractionModel) string {
+	first, second := substanceLabel(interaction.Substances[0]), substanceLabel(interaction.Substances[1])
+	switch interaction.Kind {
+	case db.InteractionAntidote:
+		return fmt.Sprintf("%s antidotes %s.", first, second)
+	case db.InteractionIncompatible:
+		return fmt.Sprintf("%s and %s are incompatible and should not be used together.", first, second)
+	default:
+		return fmt.Sprintf("%s and %s should only be combined with caution.", first, second)
+	}
+}
+
+func substanceLabel(substance db.InteractionSubstance) string {
+	if substance.Type == "" {
+		return substance.Name
+	}
+	return substance.Name + " (" + substance.Type + ")"
+}
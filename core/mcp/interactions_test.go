@@ -0,0 +1,64 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInteractionChecker(t *testing.T) {
+	nux := db.InteractionSubstance{Name: "Nux vomica", Type: "remedy", Aliases: []string{"Nux-v"}}
+	coffee := db.InteractionSubstance{Name: "Coffee", Type: "drug", Aliases: []string{"Caffeine"}}
+	camphor := db.InteractionSubstance{Name: "Camphor", Type: "drug"}
+	causticum := db.InteractionSubstance{Name: "Causticum", Type: "remedy"}
+	phosphorus := db.InteractionSubstance{Name: "Phosphorus", Type: "remedy"}
+
+	repository := odmtest.NewCollection(
+		*newInteraction(db.InteractionAntidote, coffee, nux, "Strong coffee antidotes the action of Nux vomica."),
+		*newInteraction(db.InteractionAntidote, camphor, nux, ""),
+		*newInteraction(db.InteractionIncompatible, causticum, phosphorus, "Do not use before or after each other."),
+	)
+	checker := NewInteractionChecker(repository)
+
+	t.Run("Pairs", func(t *testing.T) {
+		report, err := checker.Check(t.Context(), []string{"nux vomica", "CAFFEINE", "Phosphorus", "Unobtainium"})
+		require.NoError(t, err)
+
+		require.Len(t, report.Interactions, 1)
+		assert.Equal(t, "Coffee (drug) antidotes Nux vomica (remedy).", DescribeInteraction(report.Interactions[0]))
+		assert.Equal(t, []string{"Unobtainium"}, report.Unknown)
+		assert.Contains(t, report.NoRecord, [2]string{"nux vomica", "Phosphorus"})
+		assert.NotContains(t, report.NoRecord, [2]string{"nux vomica", "CAFFEINE"})
+	})
+
+	t.Run("SingleSubstance", func(t *testing.T) {
+		report, err := checker.Check(t.Context(), []string{"Nux-v"})
+		require.NoError(t, err)
+		assert.Len(t, report.Interactions, 2)
+	})
+
+	t.Run("Tool", func(t *testing.T) {
+		var results []*schema.ToolResultChunk
+		for result := range checker.Run(t.Context(), []string{"Causticum", "Phosphorus", "Arnica"}) {
+			results = append(results, result)
+		}
+
+		require.Len(t, results, 2)
+		assert.Equal(t, []string{
+			"Causticum (remedy) and Phosphorus (remedy) are incompatible and should not be used together.",
+			"Do not use before or after each other.",
+		}, results[0].Sentences)
+		assert.Contains(t, results[1].Sentences, "Arnica does not appear in the interaction records.")
+		assert.Contains(t, results[1].Sentences, "The absence of a record does not mean a combination is safe.")
+	})
+}
+
+func newInteraction(kind string, first, second db.InteractionSubstance, note string) *db.InteractionModel {
+	interaction := db.NewInteractionModel(kind, first, second)
+	interaction.Note = note
+	return interaction
+}
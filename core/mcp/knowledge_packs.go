@@ -0,0 +1,164 @@
+package mcp
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"go.uber.org/zap"
+)
+
+// KnowledgePack is a corpus maintained centrally and shared by tenants, such as the
+// classic materia medicas, kept in a database of its own.
+type KnowledgePack struct {
+	Name    string // the pack's database
+	Chunks  odm.OdmCollectionInterface[db.ChunkModel]
+	Vectors odm.OdmCollectionInterface[db.ChunkAnnModel]
+
+	// LexicalOnly searches the pack's text index alone, for packs whose vectors were
+	// made by another embedding model than the tenant's.
+	LexicalOnly bool
+}
+
+// WithKnowledgePacks also searches packs, with the same query, filter and options as the
+// tenant's own chunks, and merges the results by fused score. Each source fuses its own
+// rankings, so their best chunks score alike and the sources interleave. Results from a
+// pack carry its name in the knowledgePack metadata entry. The query is embedded once
+// for all of them; HyDE passages and speculative results are the tenant's alone. A pack
+// whose search fails is left out.
+func WithKnowledgePacks(packs ...KnowledgePack) SearchToolOption {
+	return func(s *SearchTool) { s.packs = append(s.packs, packs...) }
+}
+
+// searchSources runs the hybrid search of the tenant's chunks and of each knowledge pack,
+// and merges their rankings.
+func (s *SearchTool) searchSources(ctx context.Context, parsed Query, filter SearchFilter, depth int, onLexical func([]odm.SearchHit[db.ChunkModel])) (rankedChunks, error) {
+	if len(s.packs) == 0 {
+		return async.Await(s.hybridSearch(ctx, parsed, filter, depth, onLexical))
+	}
+
+	shared := *s
+	if s.embedder != nil {
+		shared.embedder = &sharedEmbedder{embedder: s.embedder, results: make(map[string]*sharedEmbedding)}
+	}
+
+	tenantTask := shared.hybridSearch(ctx, parsed, filter, depth, onLexical)
+	packTasks := make([]<-chan async.Result[rankedChunks], len(s.packs))
+	for i, pack := range s.packs {
+		packTasks[i] = shared.packTool(pack).hybridSearch(ctx, parsed, filter, depth, nil)
+	}
+
+	ranked, err := async.Await(tenantTask)
+	if err != nil {
+		return ranked, err
+	}
+	for i, pack := range s.packs {
+		packRanked, err := async.Await(packTasks[i])
+		if err != nil {
+			logger.Error("Knowledge pack search failed", zap.String("pack", pack.Name), zap.Error(err))
+			continue
+		}
+		ranked = mergePackResults(ranked, packRanked, pack.Name, depth)
+	}
+	return ranked, nil
+}
+
+// packTool searches pack in place of the tenant's chunks.
+func (s *SearchTool) packTool(pack KnowledgePack) *SearchTool {
+	t := *s
+	t.chunkRepository, t.vectorRepository = pack.Chunks, pack.Vectors
+	t.packs, t.hyde, t.decompose = nil, nil, nil
+	t.tenant = pack.Name // the exact scan caches vectors by database
+	t.lexicalOnly = s.lexicalOnly || pack.LexicalOnly
+	return &t
+}
+
+// source is the search tool of the knowledge pack named pack, or s for the tenant's own
+// chunks.
+func (s *SearchTool) source(pack string) *SearchTool {
+	for _, p := range s.packs {
+		if p.Name == pack {
+			return s.packTool(p)
+		}
+	}
+	return s
+}
+
+// mergePackResults adds the ranked chunks of a knowledge pack to ranked, keeping the best
+// depth of both by fused score. A chunk both hold is kept as the tenant's.
+func mergePackResults(ranked, pack rankedChunks, name string, depth int) rankedChunks {
+	merged := ranked
+	merged.scores = mergeScores(ranked.scores, pack.scores)
+	merged.text = engineHits{ranks: mergeScores(ranked.text.ranks, pack.text.ranks), scores: mergeScores(ranked.text.scores, pack.text.scores)}
+	merged.vector = engineHits{ranks: mergeScores(ranked.vector.ranks, pack.vector.ranks), scores: mergeScores(ranked.vector.scores, pack.vector.scores)}
+	merged.partial = ranked.partial || pack.partial
+
+	merged.packs = maps.Clone(ranked.packs)
+	if merged.packs == nil {
+		merged.packs = make(map[string]string, len(pack.chunks))
+	}
+	merged.chunks = slices.Clone(ranked.chunks)
+	for _, chunk := range pack.chunks {
+		if _, ok := ranked.scores[chunk.ChunkID]; ok {
+			continue
+		}
+		merged.packs[chunk.ChunkID] = name
+		merged.chunks = append(merged.chunks, chunk)
+	}
+
+	// ties keep the tenant's chunks first
+	slices.SortStableFunc(merged.chunks, func(a, b *db.ChunkModel) int {
+		return cmp.Compare(merged.scores[b.ChunkID], merged.scores[a.ChunkID])
+	})
+	merged.chunks = merged.chunks[:min(len(merged.chunks), depth)]
+	return merged
+}
+
+// mergeScores returns the entries of both maps, those of tenant winning.
+func mergeScores[V any](tenant, pack map[string]V) map[string]V {
+	merged := make(map[string]V, len(tenant)+len(pack))
+	maps.Copy(merged, pack)
+	maps.Copy(merged, tenant)
+	return merged
+}
+
+// sharedEmbedder embeds each text once for the searches of the tenant's chunks and its
+// knowledge packs, which all need the query's embedding at the same time.
+type sharedEmbedder struct {
+	embedder embed.Embedder
+
+	mu      sync.Mutex
+	results map[string]*sharedEmbedding
+}
+
+type sharedEmbedding struct {
+	done      chan struct{}
+	embedding []float32
+	err       error
+}
+
+func (e *sharedEmbedder) GetEmbedding(ctx context.Context, text string, opts ...embed.EmbedOption) <-chan async.Result[[]float32] {
+	e.mu.Lock()
+	shared, ok := e.results[text]
+	if !ok {
+		shared = &sharedEmbedding{done: make(chan struct{})}
+		e.results[text] = shared
+		go func() {
+			defer close(shared.done)
+			shared.embedding, shared.err = async.Await(e.embedder.GetEmbedding(ctx, text, opts...))
+		}()
+	}
+	e.mu.Unlock()
+
+	return async.Go(func() ([]float32, error) {
+		<-shared.done
+		return shared.embedding, shared.err
+	})
+}
@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// failingTextSearch fails every text search.
+type failingTextSearch struct {
+	odm.OdmCollectionInterface[db.ChunkModel]
+}
+
+func (c failingTextSearch) TermSearch(ctx context.Context, query string, params odm.TermSearchParams) <-chan async.Result[[]odm.SearchHit[db.ChunkModel]] {
+	return async.Go(func() ([]odm.SearchHit[db.ChunkModel], error) { return nil, errors.New("connection reset") })
+}
+
+func TestSearchKnowledgePacks(t *testing.T) {
+	tenantChunks := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "notes", SectionID: "notes", Title: "Clinic notes", SourceURI: "clinic.md", Sentences: []string{"Aconite helped a sudden fear of death."}},
+	)
+	tenantVectors := odmtest.NewCollection(db.ChunkAnnModel{ChunkID: "notes", Embedding: bson.NewVector([]float32{1, 0})})
+
+	packChunks := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "kent-1", SectionID: "kent", WindowIndex: 0, NextChunkID: "kent-2", Title: "Aconite", SourceURI: "kent.md", Sentences: []string{"Great fear of death."}},
+		db.ChunkModel{ChunkID: "kent-2", SectionID: "kent", WindowIndex: 1, PrevChunkID: "kent-1", Title: "Aconite", SourceURI: "kent.md", Sentences: []string{"Predicts the hour."}},
+	)
+	packVectors := odmtest.NewCollection(
+		db.ChunkAnnModel{ChunkID: "kent-1", Embedding: bson.NewVector([]float32{1, 0})},
+	)
+	pack := KnowledgePack{Name: "classics", Chunks: packChunks, Vectors: packVectors}
+
+	embedder := &countingEmbedder{vector: []float32{1, 0}}
+	search := func(packs ...KnowledgePack) map[string]map[string]string {
+		searchTool := NewSearchTool(tenantChunks, tenantVectors, embedder, WithKnowledgePacks(packs...))
+		results := make(map[string]map[string]string)
+		for result := range searchTool.Run(t.Context(), "fear of death", SearchFilter{}, SearchPage{}) {
+			require.Empty(t, result.Error)
+			results[result.Id] = result.Metadata
+			results[result.Id]["text"] = strings.Join(result.Sentences, " ")
+		}
+		return results
+	}
+
+	results := search(pack)
+	require.Len(t, results, 2)
+	assert.Equal(t, int32(1), embedder.calls.Load(), "the query is embedded once for both")
+
+	assert.Empty(t, results["notes"]["knowledgePack"])
+	assert.Equal(t, "classics", results["kent"]["knowledgePack"])
+	assert.Equal(t, "1", results["kent"]["vectorRank"], "the pack's ranks and scores are its own")
+	assert.Contains(t, results["kent"]["text"], "Predicts the hour.", "neighbouring windows come from the pack")
+
+	pack.LexicalOnly = true
+	results = search(pack)
+	require.Len(t, results, 2)
+	assert.Empty(t, results["kent"]["vectorRank"], "a pack of another embedding model is searched on text only")
+
+	results = search(KnowledgePack{Name: "broken", Chunks: failingTextSearch{packChunks}, Vectors: packVectors, LexicalOnly: true})
+	assert.Len(t, results, 1, "a failed pack is left out")
+}
+
+func TestMergePackResults(t *testing.T) {
+	tenant := rankedChunks{
+		chunks: []*db.ChunkModel{{ChunkID: "a"}, {ChunkID: "shared"}},
+		scores: map[string]float64{"a": 0.03, "shared": 0.02},
+		text:   engineHits{ranks: map[string]int{"a": 1, "shared": 2}, scores: map[string]float64{"a": 5, "shared": 4}},
+	}
+	pack := rankedChunks{
+		chunks:  []*db.ChunkModel{{ChunkID: "shared"}, {ChunkID: "b"}, {ChunkID: "c"}},
+		scores:  map[string]float64{"shared": 0.033, "b": 0.025, "c": 0.01},
+		partial: true,
+	}
+
+	merged := mergePackResults(tenant, pack, "classics", 3)
+	var ids []string
+	for _, chunk := range merged.chunks {
+		ids = append(ids, chunk.ChunkID)
+	}
+	assert.Equal(t, []string{"a", "b", "shared"}, ids)
+	assert.Equal(t, map[string]string{"b": "classics", "c": "classics"}, merged.packs)
+	assert.Equal(t, 0.02, merged.scores["shared"], "a chunk both hold is the tenant's")
+	assert.Equal(t, 1, merged.text.ranks["a"])
+	assert.True(t, merged.partial)
+	assert.Nil(t, tenant.packs, "ranked is not changed")
+}
@@ -0,0 +1,356 @@
+package mcp
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// queryFields are the fields a term can be scoped to, as in title:"Kent", and the chunk
+// fields each searches. Unscoped terms search the text search paths.
+var queryFields = map[string][]string{
+	"title":   {"title"},
+	"section": {"sectionPath"},
+	"chapter": {"sectionPath"},
+	"tag":     {"tags"},
+	"book":    {"book"},
+	"author":  {"author"},
+	"text":    {"sentences"},
+}
+
+var unscopedFields = []string{"sentences", "sectionPath", "tags", "title"}
+
+// Query is a search query parsed for its operators:
+//
+//	"high fever"       the words in this order
+//	title:Aconite      the term in a field: title, section, chapter, tag, book, author or text
+//	title:"Nux vomica" a phrase in a field
+//	a AND b, a b       both
+//	a OR b             either
+//	NOT a, -a          not
+//	( ... )            grouping
+//
+// AND binds tighter than OR. Terms match whole words, ignoring case. A query without
+// operators is plain: it is ranked by the text and vector searches as it is, with no
+// term required. A query with operators must match too, and is ranked by its terms
+// outside NOT. Operators written where they make no sense are ignored rather than
+// failing the search.
+type Query struct {
+	text  string
+	match bson.M // nil for a plain query
+}
+
+// ParseQuery parses query's operators.
+func ParseQuery(query string) Query {
+	tokens := lexQuery(query)
+	if !structured(tokens) {
+		return Query{text: query}
+	}
+
+	p := &queryParser{tokens: tokens}
+	root := p.or()
+	// a stray ")" ends a group that never started; parse what follows it too
+	for p.pos < len(p.tokens) {
+		p.pos++
+		if next := p.or(); next != nil {
+			root = combine(opAnd, root, next)
+		}
+	}
+	if root == nil {
+		return Query{text: query}
+	}
+
+	var words []string
+	root.rankingTerms(false, &words)
+	return Query{text: strings.Join(words, " "), match: root.bson(false)}
+}
+
+// IsPlain reports whether the query has no operators.
+func (q Query) IsPlain() bool { return q.match == nil }
+
+// Text is what the query is ranked by.
+func (q Query) Text() string { return q.text }
+
+// bson matches the chunks the query allows, nil for a plain query.
+func (q Query) bson() bson.M { return q.match }
+
+// ──────────────────────────────────────────────────────────────────────────────
+//	Lexer
+// ──────────────────────────────────────────────────────────────────────────────
+
+type queryTokenKind int
+
+const (
+	tokenTerm queryTokenKind = iota
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+type queryToken struct {
+	kind   queryTokenKind
+	text   string
+	field  string // for terms scoped to a field
+	phrase bool   // the term was quoted
+}
+
+func lexQuery(query string) []queryToken {
+	var tokens []queryToken
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: tokenOpen})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: tokenClose})
+			i++
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != '-':
+			tokens = append(tokens, queryToken{kind: tokenNot})
+			i++
+		case r == '"':
+			text, next := quoted(runes, i)
+			tokens = append(tokens, queryToken{kind: tokenTerm, text: text, phrase: true})
+			i = next
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`()"`, runes[i]) {
+				i++
+			}
+			word := string(runes[start:i])
+
+			if field, value, ok := strings.Cut(word, ":"); ok {
+				if _, known := queryFields[strings.ToLower(field)]; known {
+					token := queryToken{kind: tokenTerm, field: strings.ToLower(field), text: value}
+					if value == "" && i < len(runes) && runes[i] == '"' {
+						token.text, i = quoted(runes, i)
+						token.phrase = true
+					}
+					tokens = append(tokens, token)
+					continue
+				}
+			}
+
+			switch word {
+			case "AND":
+				tokens = append(tokens, queryToken{kind: tokenAnd})
+			case "OR":
+				tokens = append(tokens, queryToken{kind: tokenOr})
+			case "NOT":
+				tokens = append(tokens, queryToken{kind: tokenNot})
+			default:
+				tokens = append(tokens, queryToken{kind: tokenTerm, text: word})
+			}
+		}
+	}
+	return tokens
+}
+
+// quoted reads the phrase whose opening quote is at runes[i], up to the closing quote or
+// the end of the query, and returns it with the index after it.
+func quoted(runes []rune, i int) (string, int) {
+	end := i + 1
+	for end < len(runes) && runes[end] != '"' {
+		end++
+	}
+	return string(runes[i+1 : min(end, len(runes))]), min(end+1, len(runes))
+}
+
+// structured reports whether tokens use any operator.
+func structured(tokens []queryToken) bool {
+	for _, token := range tokens {
+		if token.kind != tokenTerm || token.phrase || token.field != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+//	Parser
+//
+//	or    := and ("OR" and)*
+//	and   := unary ("AND"? unary)*
+//	unary := "NOT" unary | "(" or ")" | term
+// ──────────────────────────────────────────────────────────────────────────────
+
+type queryOp int
+
+const (
+	opTerm queryOp = iota
+	opAnd
+	opOr
+	opNot
+)
+
+type queryNode struct {
+	op       queryOp
+	children []*queryNode
+	term     queryToken
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() (queryTokenKind, bool) {
+	if p.pos >= len(p.tokens) {
+		return 0, false
+	}
+	return p.tokens[p.pos].kind, true
+}
+
+func (p *queryParser) or() *queryNode {
+	var node *queryNode
+	for {
+		node = combine(opOr, node, p.and())
+		if kind, ok := p.peek(); !ok || kind != tokenOr {
+			return node
+		}
+		p.pos++
+	}
+}
+
+func (p *queryParser) and() *queryNode {
+	var node *queryNode
+	for {
+		kind, ok := p.peek()
+		switch {
+		case !ok || kind == tokenOr || kind == tokenClose:
+			return node
+		case kind == tokenAnd:
+			p.pos++
+		default:
+			node = combine(opAnd, node, p.unary())
+		}
+	}
+}
+
+func (p *queryParser) unary() *queryNode {
+	kind, ok := p.peek()
+	if !ok {
+		return nil
+	}
+
+	switch kind {
+	case tokenNot:
+		p.pos++
+		if operand := p.unary(); operand != nil {
+			return &queryNode{op: opNot, children: []*queryNode{operand}}
+		}
+		return nil
+	case tokenOpen:
+		p.pos++
+		node := p.or()
+		if kind, ok := p.peek(); ok && kind == tokenClose {
+			p.pos++
+		}
+		return node
+	case tokenTerm:
+		p.pos++
+		if len(termWords(p.tokens[p.pos-1].text)) == 0 {
+			return nil
+		}
+		return &queryNode{op: opTerm, term: p.tokens[p.pos-1]}
+	default:
+		// NOT before an operator or ")": the NOT is dropped and the caller reads the token
+		return nil
+	}
+}
+
+// combine joins a and b with op, flattening nested nodes of the same op. Either may be
+// nil.
+func combine(op queryOp, a, b *queryNode) *queryNode {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	}
+
+	node := &queryNode{op: op}
+	for _, child := range []*queryNode{a, b} {
+		if child.op == op {
+			node.children = append(node.children, child.children...)
+		} else {
+			node.children = append(node.children, child)
+		}
+	}
+	return node
+}
+
+// rankingTerms appends the text of the terms outside NOT to words.
+func (n *queryNode) rankingTerms(negated bool, words *[]string) {
+	switch n.op {
+	case opTerm:
+		if !negated {
+			*words = append(*words, n.term.text)
+		}
+	case opNot:
+		n.children[0].rankingTerms(!negated, words)
+	default:
+		for _, child := range n.children {
+			child.rankingTerms(negated, words)
+		}
+	}
+}
+
+// bson matches the chunks n allows, or when negated those it doesn't.
+func (n *queryNode) bson(negated bool) bson.M {
+	switch n.op {
+	case opTerm:
+		match := n.term.bson()
+		if negated {
+			return bson.M{"$nor": bson.A{match}}
+		}
+		return match
+	case opNot:
+		return n.children[0].bson(!negated)
+	}
+
+	// De Morgan: NOT (a AND b) is NOT a OR NOT b
+	op := "$and"
+	if (n.op == opOr) != negated {
+		op = "$or"
+	}
+	clauses := make(bson.A, 0, len(n.children))
+	for _, child := range n.children {
+		clauses = append(clauses, child.bson(negated))
+	}
+	return bson.M{op: clauses}
+}
+
+// bson matches the chunks with the term's words, in order, in any of its fields.
+func (t queryToken) bson() bson.M {
+	words := termWords(t.text)
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+	pattern := bson.Regex{Pattern: `\b` + strings.Join(words, `\W+`) + `\b`, Options: "i"}
+
+	fields := unscopedFields
+	if t.field != "" {
+		fields = queryFields[t.field]
+	}
+	if len(fields) == 1 {
+		return bson.M{fields[0]: bson.M{"$regex": pattern}}
+	}
+	alternatives := make(bson.A, 0, len(fields))
+	for _, field := range fields {
+		alternatives = append(alternatives, bson.M{field: bson.M{"$regex": pattern}})
+	}
+	return bson.M{"$or": alternatives}
+}
+
+// termWords splits a term into its runs of letters and digits.
+func termWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+}
@@ -0,0 +1,99 @@
+package mcp
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestParseQueryPlain(t *testing.T) {
+	for _, query := range []string{"fear of death", "Nat-m. grief", "anxiety and restlessness", "ratio 1:2"} {
+		parsed := ParseQuery(query)
+		assert.True(t, parsed.IsPlain(), query)
+		assert.Equal(t, query, parsed.Text())
+		assert.Nil(t, parsed.bson())
+	}
+}
+
+func TestParseQueryText(t *testing.T) {
+	for query, want := range map[string]string{
+		`"high fever" NOT Belladonna`:             "high fever",
+		`title:"Nux vomica" AND (chilly OR cold)`: "Nux vomica chilly cold",
+		`-(Sulphur OR Sulph.) itching`:            "itching",
+		`fever NOT NOT thirst`:                    "fever thirst",
+		`author:Kent title: fear`:                 "Kent fear",
+		`(fever OR ) AND chill)`:                  "fever chill",
+	} {
+		parsed := ParseQuery(query)
+		assert.False(t, parsed.IsPlain(), query)
+		assert.Equal(t, want, parsed.Text(), query)
+	}
+
+	assert.True(t, ParseQuery("NOT").IsPlain(), "nothing to parse")
+}
+
+func TestParseQueryMatch(t *testing.T) {
+	term := func(field, pattern string) bson.M {
+		return bson.M{field: bson.M{"$regex": bson.Regex{Pattern: pattern, Options: "i"}}}
+	}
+
+	assert.Equal(t, bson.M{"$and": bson.A{
+		term("title", `\bNux\W+vomica\b`),
+		bson.M{"$nor": bson.A{term("author", `\bKent\b`)}},
+	}}, ParseQuery(`title:"Nux vomica" -author:Kent`).bson())
+
+	assert.Equal(t, bson.M{"$and": bson.A{
+		bson.M{"$nor": bson.A{term("title", `\bSulphur\b`)}},
+		bson.M{"$nor": bson.A{term("book", `\bKent\b`)}},
+	}}, ParseQuery(`fever NOT (title:Sulphur OR book:Kent)`).bson()["$and"].(bson.A)[1], "NOT (a OR b) is NOT a AND NOT b")
+}
+
+func TestSearchQueryOperators(t *testing.T) {
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "aconite", SectionID: "aconite", Title: "Aconitum napellus", Author: "William Boericke",
+			Sentences: []string{"Great fear and anxiety of mind, with great nervous excitability. High fever."}},
+		db.ChunkModel{ChunkID: "belladonna", SectionID: "belladonna", Title: "Belladonna", Author: "James Tyler Kent",
+			Sentences: []string{"High fever with hot red face. Fear of imaginary things."}},
+		db.ChunkModel{ChunkID: "gelsemium", SectionID: "gelsemium", Title: "Gelsemium", Author: "William Boericke",
+			Sentences: []string{"Fever without thirst; the fever is high and the patient is drowsy."}},
+	)
+	// every chunk is a vector hit, so the operators have to drop some of them too
+	vectorRepository := odmtest.NewCollection(
+		db.ChunkAnnModel{ChunkID: "aconite", Embedding: bson.NewVector([]float32{1, 0})},
+		db.ChunkAnnModel{ChunkID: "belladonna", Embedding: bson.NewVector([]float32{1, 0})},
+		db.ChunkAnnModel{ChunkID: "gelsemium", Embedding: bson.NewVector([]float32{1, 0})},
+	)
+
+	search := func(query string) []string {
+		searchTool := NewSearchTool(chunkRepository, vectorRepository, fixedEmbedder{1, 0})
+		var ids []string
+		for result := range searchTool.Run(t.Context(), query, SearchFilter{}, SearchPage{}) {
+			require.Empty(t, result.Error)
+			ids = append(ids, result.Id)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+
+	assert.Equal(t, []string{"aconite", "belladonna", "gelsemium"}, search("high fever"))
+	assert.Equal(t, []string{"aconite", "belladonna"}, search(`"high fever"`), "the words in order")
+	assert.Equal(t, []string{"aconite", "gelsemium"}, search(`fever NOT author:Kent`))
+	assert.Equal(t, []string{"belladonna", "gelsemium"}, search(`title:Belladonna OR drowsy`))
+	assert.Equal(t, []string{"aconite"}, search(`fear AND author:"William Boericke"`))
+	assert.Empty(t, search(`title:Aconitum AND title:Belladonna`))
+}
+
+func TestSearchQueryOnlyExclusions(t *testing.T) {
+	searchTool := NewSearchTool(odmtest.NewCollection[db.ChunkModel](), odmtest.NewCollection[db.ChunkAnnModel](), nil, WithLexicalOnly())
+	var errors []string
+	for result := range searchTool.Run(t.Context(), "NOT Sulphur", SearchFilter{}, SearchPage{}) {
+		errors = append(errors, result.Error)
+	}
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0], "no terms to search for")
+}
@@ -0,0 +1,161 @@
+package mcp
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/references"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// reference tool parameters.
+const (
+	maxFollowedSections = 4 // sections whose links are followed per call
+	maxFollowedLinks    = 8 // linked sections sent per call
+)
+
+// ReferenceTool follows the links of retrieved sections to the passages they point at:
+// the sections their "see also" and "compare" mentions name, and the nearest sections
+// of their chapter. The links are found when a source is ingested; see package
+// references. Where SearchTool finds passages like the question, this finds those the
+// library itself ties to a passage already found. Only sections of documents a user in
+// accessGroups may read are followed and sent; see db.AccessFilter.
+type ReferenceTool struct {
+	chunkRepository odm.OdmCollectionInterface[db.ChunkModel]
+	access          bson.M
+}
+
+func NewReferenceTool(chunkRepository odm.OdmCollectionInterface[db.ChunkModel], accessGroups []string) *ReferenceTool {
+	return &ReferenceTool{chunkRepository: chunkRepository, access: db.AccessFilter(accessGroups)}
+}
+
+// followedLink is a link of one of the sections asked about.
+type followedLink struct {
+	from string
+	db.ChunkLink
+}
+
+// Run sends the sections linked from each of sectionIDs, those they mention before
+// those of their chapter, up to maxFollowedLinks. kinds, when not empty, keeps only links
+// of those kinds. Sections asked about are never sent again, and a section linked from
+// several is sent once, for the first link to it.
+func (t *ReferenceTool) Run(ctx context.Context, sectionIDs, kinds []string) <-chan *schema.ToolResultChunk {
+	out := make(chan *schema.ToolResultChunk, maxFollowedLinks)
+
+	go func() {
+		defer close(out)
+
+		sectionIDs = distinctTerms(sectionIDs, maxFollowedSections)
+		if len(sectionIDs) == 0 {
+			out <- &schema.ToolResultChunk{Error: "No section IDs to follow references from"}
+			return
+		}
+
+		from, err := async.Await(t.chunkRepository.Find(ctx, t.liveSections(sectionIDs), nil, 0, 0))
+		if err != nil {
+			logger.Error("Failed to load sections to follow", zap.Strings("sectionIds", sectionIDs), zap.Error(err))
+			out <- &schema.ToolResultChunk{Error: "Failed to load the sections to follow references from"}
+			return
+		}
+
+		links := followLinks(sectionIDs, from, kinds)
+		if len(links) == 0 {
+			return
+		}
+		targetIDs := make([]string, len(links))
+		for i, link := range links {
+			targetIDs[i] = link.SectionID
+		}
+		targets, err := async.Await(t.chunkRepository.Find(ctx, t.liveSections(targetIDs), nil, 0, 0))
+		if err != nil {
+			logger.Error("Failed to load linked sections", zap.Strings("sectionIds", targetIDs), zap.Error(err))
+			out <- &schema.ToolResultChunk{Error: "Failed to load the referenced sections"}
+			return
+		}
+
+		windows := make(map[string][]*db.ChunkModel)
+		for _, chunk := range targets {
+			windows[chunk.SectionID] = append(windows[chunk.SectionID], &chunk)
+		}
+		for _, link := range links {
+			// a section retired since its source was linked has no live windows
+			if sectionWindows := windows[link.SectionID]; len(sectionWindows) > 0 {
+				out <- linkedSectionResult(link, sectionWindows)
+			}
+		}
+	}()
+
+	return out
+}
+
+// followLinks picks the links to follow from the windows of the sections asked about:
+// every mention link, in the order the sections were asked about, then every chapter
+// link, up to maxFollowedLinks.
+func followLinks(sectionIDs []string, from []db.ChunkModel, kinds []string) []followedLink {
+	sectionLinks := make(map[string][]db.ChunkLink, len(sectionIDs))
+	for _, chunk := range from {
+		// every window of a section carries the same links
+		if _, ok := sectionLinks[chunk.SectionID]; !ok {
+			sectionLinks[chunk.SectionID] = chunk.Links
+		}
+	}
+
+	seen := make(map[string]bool, len(sectionIDs))
+	for _, id := range sectionIDs {
+		seen[id] = true
+	}
+	var followed []followedLink
+	for _, chapter := range []bool{false, true} {
+		for _, id := range sectionIDs {
+			for _, link := range sectionLinks[id] {
+				if (link.Kind == references.KindChapter) != chapter || seen[link.SectionID] || len(followed) == maxFollowedLinks {
+					continue
+				}
+				if len(kinds) > 0 && !slices.Contains(kinds, link.Kind) {
+					continue
+				}
+				seen[link.SectionID] = true
+				followed = append(followed, followedLink{from: id, ChunkLink: link})
+			}
+		}
+	}
+	return followed
+}
+
+// liveSections matches the live windows of sectionIDs the user may read.
+func (t *ReferenceTool) liveSections(sectionIDs []string) bson.M {
+	return bson.M{"$and": bson.A{
+		bson.M{"sectionId": bson.M{"$in": sectionIDs}},
+		db.LiveChunksFilter(),
+		t.access,
+	}}
+}
+
+// linkedSectionResult is the whole text of a linked section, with the link that led to it.
+func linkedSectionResult(link followedLink, windows []*db.ChunkModel) *schema.ToolResultChunk {
+	sort.Slice(windows, func(i, j int) bool { return windows[i].WindowIndex < windows[j].WindowIndex })
+	first := windows[0]
+
+	result := &schema.ToolResultChunk{
+		Title:       first.Title,
+		Attribution: first.SourceURI,
+		Id:          first.SectionID,
+		Sentences:   texts(mergeWindows(windows, nil), nil),
+		Metadata: map[string]string{
+			"sectionId":  first.SectionID,
+			"linkedFrom": link.from,
+			"linkKind":   link.Kind,
+		},
+	}
+	if link.Text != "" {
+		result.Metadata["linkText"] = link.Text
+	}
+	return result
+}
@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/SaiNageswarS/medicine-rag/core/references"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferenceTool(t *testing.T) {
+	mindLinks := []db.ChunkLink{
+		{SectionID: "acon-mod", Kind: references.KindChapter},
+		{SectionID: "gels-mind", Kind: references.KindCompare, Text: "Compare Gels."},
+		{SectionID: "retired", Kind: references.KindReference, Text: "see Old notes"},
+	}
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "acon-mind-0", SectionID: "acon-mind", NextChunkID: "acon-mind-1", Title: "Aconite: Mind", Sentences: []string{"Great fear."}, Links: mindLinks},
+		db.ChunkModel{ChunkID: "acon-mind-1", SectionID: "acon-mind", PrevChunkID: "acon-mind-0", WindowIndex: 1, Title: "Aconite: Mind", Sentences: []string{"Great fear.", "Compare Gels."}, Links: mindLinks},
+		db.ChunkModel{ChunkID: "acon-mod", SectionID: "acon-mod", Title: "Aconite: Modalities", Sentences: []string{"Worse at night."},
+			Links: []db.ChunkLink{{SectionID: "acon-mind", Kind: references.KindChapter}}},
+		db.ChunkModel{ChunkID: "gels-mind-1", SectionID: "gels-mind", PrevChunkID: "gels-mind-0", WindowIndex: 1, Title: "Gelsemium: Mind", SourceURI: "file://boericke.md", Sentences: []string{"Wants to be quiet.", "Fear of falling."}},
+		db.ChunkModel{ChunkID: "gels-mind-0", SectionID: "gels-mind", NextChunkID: "gels-mind-1", Title: "Gelsemium: Mind", SourceURI: "file://boericke.md", Sentences: []string{"Dullness.", "Wants to be quiet."}},
+		db.ChunkModel{ChunkID: "retired", SectionID: "retired", Title: "Old notes", Sentences: []string{"Replaced."}, RetiredVersion: 2},
+		db.ChunkModel{ChunkID: "notes", SectionID: "notes", Title: "Case notes", Sentences: []string{"See the modalities."}, AccessGroups: []string{"practice-internal"},
+			Links: []db.ChunkLink{{SectionID: "acon-mod", Kind: references.KindReference}}},
+	)
+	tool := NewReferenceTool(chunkRepository, nil)
+
+	run := func(sectionIDs, kinds []string) []*schema.ToolResultChunk {
+		var results []*schema.ToolResultChunk
+		for result := range tool.Run(t.Context(), sectionIDs, kinds) {
+			results = append(results, result)
+		}
+		return results
+	}
+
+	results := run([]string{"acon-mind"}, nil)
+	require.Len(t, results, 2, "retired sections aren't sent")
+	assert.Equal(t, "gels-mind", results[0].Id, "mentions come before the chapter")
+	assert.Equal(t, []string{"Dullness.", "Wants to be quiet.", "Fear of falling."}, results[0].Sentences, "the whole section, its windows merged")
+	assert.Equal(t, "file://boericke.md", results[0].Attribution)
+	assert.Equal(t, map[string]string{"sectionId": "gels-mind", "linkedFrom": "acon-mind", "linkKind": "compare", "linkText": "Compare Gels."}, results[0].Metadata)
+	assert.Equal(t, "acon-mod", results[1].Id)
+
+	results = run([]string{"acon-mind", "acon-mod"}, []string{references.KindChapter})
+	assert.Empty(t, results, "sections asked about aren't sent again")
+
+	results = run([]string{"notes"}, nil)
+	assert.Empty(t, results, "sections of restricted documents aren't followed")
+
+	results = run([]string{" "}, nil)
+	require.Len(t, results, 1)
+	assert.NotEmpty(t, results[0].Error)
+}
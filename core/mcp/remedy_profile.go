@@ -0,0 +1,299 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/go-collection-boot/ds"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// remedy profile parameters.
+const (
+	maxProfileRemedies    = 4   // remedies profiled per call, enough for a comparison
+	profileCandidates     = 100 // chunks read per remedy before filtering to its sections
+	profileFacetSentences = 8   // sentences kept per facet
+	remedyPrefixLength    = 5   // "Aconite" and "Aconitum" share "aconi"
+)
+
+// Profile facets, in the order they are listed.
+const (
+	facetKeynotes   = "Keynote"
+	facetMental     = "Mental"
+	facetModalities = "Modality"
+)
+
+// Section headings that decide a sentence's facet. A materia medica entry is usually
+// split into an overview followed by sections such as "Mind", "Head" and "Modalities".
+var (
+	mentalSections   = []string{"mind", "mental", "emotion", "psych"}
+	modalitySections = []string{"modalit"}
+	keynoteSections  = []string{"keynote", "characteristic", "generalit", "guiding"}
+)
+
+// Sentence cues for sections that are not about a single facet, such as the overview.
+var (
+	modalityPattern = regexp.MustCompile(`(?i)\b(worse|better|aggravat\w*|ameliorat\w*|agg\.|amel\.)`)
+	mentalPattern   = regexp.MustCompile(`(?i)\b(fears?|fright\w*|afraid|anxi\w*|anguish|mind|mental\w*|irritab\w*|despair|weep\w*|grief|anger|sad\w*|delusion\w*|jealous\w*|indifferen\w*|memory|restless\w*|excitab\w*)\b`)
+)
+
+var sentenceBoundary = regexp.MustCompile(`([.!?])\s+`)
+
+// RemedyProfileTool gathers what the library says about a remedy into a profile of its
+// keynotes, mental symptoms and modalities. Where SearchTool returns the passages that
+// best match a question, this returns the remedy's own materia medica entry, sorted by
+// facet, so remedies can be compared side by side. Profiles are built from the documents
+// a user in accessGroups may read; see db.AccessFilter.
+type RemedyProfileTool struct {
+	chunkRepository odm.OdmCollectionInterface[db.ChunkModel]
+	access          bson.M
+}
+
+func NewRemedyProfileTool(chunkRepository odm.OdmCollectionInterface[db.ChunkModel], accessGroups []string) *RemedyProfileTool {
+	return &RemedyProfileTool{chunkRepository: chunkRepository, access: db.AccessFilter(accessGroups)}
+}
+
+// RemedyProfile is a remedy's materia medica entry sorted by facet.
+type RemedyProfile struct {
+	Remedy     string // as named in the library, e.g. "Aconitum Napellus"
+	Keynotes   []string
+	Mental     []string
+	Modalities []string
+	SectionIDs []string
+	Sources    []string
+}
+
+// Run sends one profile per remedy, in the order the remedies were asked for.
+func (t *RemedyProfileTool) Run(ctx context.Context, remedies []string) <-chan *schema.ToolResultChunk {
+	out := make(chan *schema.ToolResultChunk, maxProfileRemedies)
+
+	go func() {
+		defer close(out)
+
+		for _, remedy := range distinctTerms(remedies, maxProfileRemedies) {
+			profile, err := t.Profile(ctx, remedy)
+			if err != nil {
+				logger.Error("Failed to build remedy profile", zap.String("remedy", remedy), zap.Error(err))
+				out <- &schema.ToolResultChunk{Error: fmt.Sprintf("Failed to load the profile of %q", remedy)}
+				continue
+			}
+			if profile == nil {
+				out <- &schema.ToolResultChunk{Error: fmt.Sprintf("No materia medica entry for %q was found in the library", remedy)}
+				continue
+			}
+			out <- profile.toolResult()
+		}
+	}()
+
+	return out
+}
+
+// Profile builds the profile of a remedy from the live chunks of its own sections. It
+// returns nil when the library has no entry for the remedy.
+func (t *RemedyProfileTool) Profile(ctx context.Context, remedy string) (*RemedyProfile, error) {
+	words := remedyWords(remedy)
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	filter := bson.M{"$and": bson.A{
+		db.LiveChunksFilter(),
+		t.access,
+		bson.M{"sectionPath": bson.M{"$regex": bson.Regex{Pattern: `\b` + regexp.QuoteMeta(words[0]), Options: "i"}}},
+	}}
+	chunks, err := async.Await(t.chunkRepository.Find(ctx, filter, nil, profileCandidates, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	return buildRemedyProfile(words, chunks), nil
+}
+
+func buildRemedyProfile(words []string, chunks []db.ChunkModel) *RemedyProfile {
+	type sectionChunk struct {
+		chunk   db.ChunkModel
+		heading int // index of the remedy's heading in the section path
+		path    []string
+	}
+
+	var matched []sectionChunk
+	for _, chunk := range chunks {
+		path := strings.Split(chunk.SectionPath, "|")
+		for i := range path {
+			path[i] = strings.TrimSpace(path[i])
+		}
+		for i, heading := range path {
+			if namesRemedy(heading, words) {
+				matched = append(matched, sectionChunk{chunk: chunk, heading: i, path: path})
+				break
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+
+	// reading order, so the overview comes before the sections that follow it
+	sort.SliceStable(matched, func(i, j int) bool {
+		a, b := matched[i].chunk, matched[j].chunk
+		if a.SourceURI != b.SourceURI {
+			return a.SourceURI < b.SourceURI
+		}
+		if a.SectionIndex != b.SectionIndex {
+			return a.SectionIndex < b.SectionIndex
+		}
+		return a.WindowIndex < b.WindowIndex
+	})
+
+	profile := &RemedyProfile{Remedy: matched[0].path[matched[0].heading]}
+	seen, sections, sources := ds.NewSet[string](), ds.NewSet[string](), ds.NewSet[string]()
+	facets := map[string]*[]string{
+		facetKeynotes:   &profile.Keynotes,
+		facetMental:     &profile.Mental,
+		facetModalities: &profile.Modalities,
+	}
+
+	for _, m := range matched {
+		if !sections.Contains(m.chunk.SectionID) {
+			sections.Add(m.chunk.SectionID)
+			profile.SectionIDs = append(profile.SectionIDs, m.chunk.SectionID)
+		}
+		if !sources.Contains(m.chunk.SourceURI) {
+			sources.Add(m.chunk.SourceURI)
+			profile.Sources = append(profile.Sources, m.chunk.SourceURI)
+		}
+
+		// the remedy's own heading is its overview; deeper headings name a section
+		section := ""
+		if m.heading < len(m.path)-1 {
+			section = strings.ToLower(m.path[len(m.path)-1])
+		}
+
+		for _, text := range m.chunk.Sentences {
+			for _, sentence := range splitProfileSentences(text) {
+				key := strings.ToLower(sentence)
+				if seen.Contains(key) {
+					continue // windows of a section overlap
+				}
+				seen.Add(key)
+
+				if facet := classifySentence(section, sentence); facet != "" && len(*facets[facet]) < profileFacetSentences {
+					*facets[facet] = append(*facets[facet], sentence)
+				}
+			}
+		}
+	}
+	return profile
+}
+
+// classifySentence returns the facet a sentence belongs to, or "" for sentences about
+// other body regions, which the profile leaves to the search tool.
+func classifySentence(section, sentence string) string {
+	switch {
+	case containsAny(section, modalitySections):
+		return facetModalities
+	case containsAny(section, mentalSections):
+		return facetMental
+	case containsAny(section, keynoteSections):
+		return facetKeynotes
+	case section != "":
+		if modalityPattern.MatchString(sentence) {
+			return facetModalities
+		}
+		return ""
+	}
+
+	// overview
+	switch {
+	case modalityPattern.MatchString(sentence):
+		return facetModalities
+	case mentalPattern.MatchString(sentence):
+		return facetMental
+	default:
+		return facetKeynotes
+	}
+}
+
+func containsAny(text string, needles []string) bool {
+	for _, needle := range needles {
+		if strings.Contains(text, needle) {
+			return true
+		}
+	}
+	return false
+}
+
+// remedyWords are the prefixes that identify a remedy name: "Nux vomica" becomes
+// ["nux", "vomic"], which matches "Nux Vomica" but not "Nux Moschata".
+func remedyWords(remedy string) []string {
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(remedy), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		words = append(words, string([]rune(word)[:min(len([]rune(word)), remedyPrefixLength)]))
+	}
+	return words
+}
+
+// namesRemedy reports whether every remedy word starts a word of the heading.
+func namesRemedy(heading string, words []string) bool {
+	headingWords := strings.FieldsFunc(strings.ToLower(heading), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, word := range words {
+		found := false
+		for _, headingWord := range headingWords {
+			if strings.HasPrefix(headingWord, word) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func splitProfileSentences(text string) []string {
+	var sentences []string
+	for _, sentence := range strings.Split(sentenceBoundary.ReplaceAllString(text, "$1\n"), "\n") {
+		if sentence = strings.TrimSpace(sentence); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+	}
+	return sentences
+}
+
+func (p *RemedyProfile) toolResult() *schema.ToolResultChunk {
+	sentences := make([]string, 0, len(p.Keynotes)+len(p.Mental)+len(p.Modalities))
+	for _, facet := range []struct {
+		name      string
+		sentences []string
+	}{{facetKeynotes, p.Keynotes}, {facetMental, p.Mental}, {facetModalities, p.Modalities}} {
+		for _, sentence := range facet.sentences {
+			sentences = append(sentences, facet.name+": "+sentence)
+		}
+	}
+
+	return &schema.ToolResultChunk{
+		Title:       "Remedy profile: " + p.Remedy,
+		Attribution: strings.Join(p.Sources, ", "),
+		Id:          "profile:" + p.Remedy,
+		Sentences:   sentences,
+		Metadata: map[string]string{
+			"remedy":     p.Remedy,
+			"sectionIds": strings.Join(p.SectionIDs, ","),
+		},
+	}
+}
@@ -0,0 +1,55 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemedyProfileTool(t *testing.T) {
+	repository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "acon", SectionID: "acon", SourceURI: "boericke", SectionIndex: 1, SectionPath: "Boericke | Aconitum Napellus",
+			Sentences: []string{"A state of fear, anxiety; anguish of mind and body. Acute, sudden, and violent invasion, with fever, call for it. Worse in evening and night; better in open air."}},
+		db.ChunkModel{ChunkID: "acon-head", SectionID: "acon-head", SourceURI: "boericke", SectionIndex: 2, SectionPath: "Boericke | Aconitum Napellus | Head",
+			Sentences: []string{"Fullness; heavy, pulsating, hot, bursting, burning. Worse from motion."}},
+		db.ChunkModel{ChunkID: "acon-mind", SectionID: "acon-mind", SourceURI: "boericke", SectionIndex: 3, SectionPath: "Boericke | Aconitum Napellus | Mind",
+			Sentences: []string{"Predicts the day of death.", "Fear of death."}},
+		db.ChunkModel{ChunkID: "acon-old", SectionID: "acon-old", SourceURI: "boericke", SectionIndex: 4, SectionPath: "Boericke | Aconitum Napellus | Mind",
+			Sentences: []string{"Retired text."}, RetiredVersion: 2},
+		db.ChunkModel{ChunkID: "ars", SectionID: "ars", SourceURI: "boericke", SectionIndex: 5, SectionPath: "Boericke | Arsenicum Album",
+			Sentences: []string{"Great exhaustion after the slightest exertion. Fears of death, of being left alone. Worse after midnight."}},
+		db.ChunkModel{ChunkID: "ars-iod", SectionID: "ars-iod", SourceURI: "boericke", SectionIndex: 6, SectionPath: "Boericke | Arsenicum Iodatum",
+			Sentences: []string{"Persistent irritating discharge."}},
+	)
+
+	tool := NewRemedyProfileTool(repository, nil)
+
+	var results []*schema.ToolResultChunk
+	for result := range tool.Run(t.Context(), []string{"Aconite", "Arsenicum album", "Aconite", "Crotalus"}) {
+		results = append(results, result)
+	}
+	require.Len(t, results, 3)
+
+	aconite := results[0]
+	assert.Equal(t, "Remedy profile: Aconitum Napellus", aconite.Title)
+	assert.Equal(t, []string{
+		"Keynote: Acute, sudden, and violent invasion, with fever, call for it.",
+		"Mental: A state of fear, anxiety; anguish of mind and body.",
+		"Mental: Predicts the day of death.",
+		"Mental: Fear of death.",
+		"Modality: Worse in evening and night; better in open air.",
+		"Modality: Worse from motion.",
+	}, aconite.Sentences)
+	assert.Equal(t, "acon,acon-head,acon-mind", aconite.Metadata["sectionIds"])
+
+	// Arsenicum Iodatum is a different remedy
+	assert.Equal(t, "Remedy profile: Arsenicum Album", results[1].Title)
+	assert.Contains(t, results[1].Sentences, "Mental: Fears of death, of being left alone.")
+	assert.Equal(t, "ars", results[1].Metadata["sectionIds"])
+
+	assert.Contains(t, results[2].Error, `"Crotalus"`)
+}
@@ -0,0 +1,195 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/go-collection-boot/ds"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"go.uber.org/zap"
+)
+
+// repertory lookup parameters.
+const (
+	maxRepertorySymptoms  = 8  // symptoms looked up per call
+	rubricsPerSymptom     = 3  // matching rubrics returned per symptom
+	repertorisedRemedies  = 10 // remedies listed in the repertorisation
+	repertorisationResult = "repertorisation"
+)
+
+var gradeLabels = map[int]string{
+	3: "Grade 3 (strongly indicated)",
+	2: "Grade 2",
+	1: "Grade 1",
+}
+
+// RepertoryTool looks symptoms up as rubrics in the tenant's ingested repertory and
+// returns the remedies listed under them. Unlike SearchTool it answers "which remedies
+// cover these symptoms" from the repertory's structure rather than from free text.
+type RepertoryTool struct {
+	repository odm.OdmCollectionInterface[db.RubricModel]
+}
+
+func NewRepertoryTool(repository odm.OdmCollectionInterface[db.RubricModel]) *RepertoryTool {
+	return &RepertoryTool{repository: repository}
+}
+
+// Run sends the matching rubrics of each symptom and, when more than one symptom
+// matched, a repertorisation ranking remedies by how many of the symptoms they cover.
+func (r *RepertoryTool) Run(ctx context.Context, symptoms []string) <-chan *schema.ToolResultChunk {
+	out := make(chan *schema.ToolResultChunk, maxRepertorySymptoms*rubricsPerSymptom+1)
+
+	go func() {
+		defer close(out)
+
+		sent, counted := ds.NewSet[string](), ds.NewSet[string]()
+		var best []db.RubricModel // top rubric of each symptom
+		for _, symptom := range distinctTerms(symptoms, maxRepertorySymptoms) {
+			hits, err := async.Await(r.repository.TermSearch(ctx, symptom, odm.TermSearchParams{
+				IndexName: db.RepertorySearchIndexName,
+				Path:      db.RepertorySearchPaths,
+				Limit:     rubricsPerSymptom,
+			}))
+			if err != nil {
+				logger.Error("Failed to search repertory", zap.String("symptom", symptom), zap.Error(err))
+				out <- &schema.ToolResultChunk{Error: fmt.Sprintf("Repertory lookup failed for %q", symptom)}
+				continue
+			}
+			if len(hits) == 0 {
+				continue
+			}
+
+			if top := hits[0].Doc; !counted.Contains(top.RubricID) {
+				counted.Add(top.RubricID)
+				best = append(best, top)
+			}
+			for _, hit := range hits {
+				if sent.Contains(hit.Doc.RubricID) {
+					continue
+				}
+				sent.Add(hit.Doc.RubricID)
+				out <- rubricResult(hit.Doc)
+			}
+		}
+
+		if len(best) > 1 {
+			out <- repertorise(best)
+		}
+	}()
+
+	return out
+}
+
+// distinctTerms trims the terms a model passed to a tool and drops blanks and repeats,
+// keeping at most limit of them.
+func distinctTerms(terms []string, limit int) []string {
+	seen := ds.NewSet[string]()
+	cleaned := make([]string, 0, len(terms))
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		key := strings.ToLower(term)
+		if term == "" || seen.Contains(key) {
+			continue
+		}
+		seen.Add(key)
+		cleaned = append(cleaned, term)
+		if len(cleaned) == limit {
+			break
+		}
+	}
+	return cleaned
+}
+
+func rubricResult(rubric db.RubricModel) *schema.ToolResultChunk {
+	byGrade := make(map[int][]string)
+	for _, remedy := range rubric.Remedies {
+		grade := min(max(remedy.Grade, 1), 3)
+		byGrade[grade] = append(byGrade[grade], remedyLabel(remedy))
+	}
+
+	sentences := make([]string, 0, 3)
+	for grade := 3; grade >= 1; grade-- {
+		if len(byGrade[grade]) > 0 {
+			sentences = append(sentences, gradeLabels[grade]+": "+strings.Join(byGrade[grade], ", ")+".")
+		}
+	}
+
+	return &schema.ToolResultChunk{
+		Title:       "Repertory: " + rubric.Rubric,
+		Attribution: rubric.Repertory,
+		Id:          rubric.RubricID,
+		Sentences:   sentences,
+		Metadata:    map[string]string{"rubricId": rubric.RubricID, "repertory": rubric.Repertory},
+	}
+}
+
+func remedyLabel(remedy db.RubricRemedy) string {
+	if remedy.Abbreviation == "" {
+		return remedy.Name
+	}
+	return remedy.Name + " (" + remedy.Abbreviation + ")"
+}
+
+type remedyCoverage struct {
+	name       string
+	rubrics    int
+	totalGrade int
+}
+
+// repertorise ranks remedies by the number of rubrics they appear in, then by the sum
+// of their grades, as a homeopath does when working through a repertory by hand.
+func repertorise(rubrics []db.RubricModel) *schema.ToolResultChunk {
+	coverage := make(map[string]*remedyCoverage)
+	repertories := ds.NewSet[string]()
+	ids := make([]string, 0, len(rubrics))
+	for _, rubric := range rubrics {
+		ids = append(ids, rubric.RubricID)
+		if rubric.Repertory != "" {
+			repertories.Add(rubric.Repertory)
+		}
+		for _, remedy := range rubric.Remedies {
+			c, ok := coverage[remedy.Name]
+			if !ok {
+				c = &remedyCoverage{name: remedy.Name}
+				coverage[remedy.Name] = c
+			}
+			c.rubrics++
+			c.totalGrade += min(max(remedy.Grade, 1), 3)
+		}
+	}
+
+	ranked := make([]*remedyCoverage, 0, len(coverage))
+	for _, c := range coverage {
+		ranked = append(ranked, c)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].rubrics != ranked[j].rubrics {
+			return ranked[i].rubrics > ranked[j].rubrics
+		}
+		if ranked[i].totalGrade != ranked[j].totalGrade {
+			return ranked[i].totalGrade > ranked[j].totalGrade
+		}
+		return ranked[i].name < ranked[j].name
+	})
+
+	sentences := make([]string, 0, repertorisedRemedies)
+	for _, c := range ranked[:min(len(ranked), repertorisedRemedies)] {
+		sentences = append(sentences, fmt.Sprintf("%s covers %d of %d rubrics, total grade %d.", c.name, c.rubrics, len(rubrics), c.totalGrade))
+	}
+
+	attribution := repertories.ToSlice()
+	sort.Strings(attribution)
+	return &schema.ToolResultChunk{
+		Title:       fmt.Sprintf("Repertorisation of %d rubrics", len(rubrics)),
+		Attribution: strings.Join(attribution, ", "),
+		Id:          repertorisationResult,
+		Sentences:   sentences,
+		Metadata:    map[string]string{"rubricIds": strings.Join(ids, ",")},
+	}
+}
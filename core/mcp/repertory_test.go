@@ -0,0 +1,60 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepertoryTool(t *testing.T) {
+	repository := odmtest.NewCollection(
+		db.RubricModel{RubricID: "mind-fear-death", Repertory: "Kent", Chapter: "Mind", Rubric: "Mind; Fear; death, of",
+			Synonyms: []string{"afraid of dying"},
+			Remedies: []db.RubricRemedy{{Name: "Aconitum napellus", Abbreviation: "Acon.", Grade: 3}, {Name: "Arsenicum album", Abbreviation: "Ars.", Grade: 3}, {Name: "Gelsemium", Grade: 1}}},
+		db.RubricModel{RubricID: "mind-restlessness-night", Repertory: "Kent", Chapter: "Mind", Rubric: "Mind; Restlessness; night",
+			Remedies: []db.RubricRemedy{{Name: "Arsenicum album", Abbreviation: "Ars.", Grade: 3}, {Name: "Rhus toxicodendron", Grade: 2}}},
+		db.RubricModel{RubricID: "stomach-thirst-small", Repertory: "Kent", Chapter: "Stomach", Rubric: "Stomach; Thirst; small quantities, for",
+			Remedies: []db.RubricRemedy{{Name: "Arsenicum album", Grade: 2}}},
+	)
+
+	tool := NewRepertoryTool(repository)
+
+	var results []*schema.ToolResultChunk
+	for result := range tool.Run(t.Context(), []string{"afraid of dying", "restlessness at night", " ", "Afraid of dying"}) {
+		require.Empty(t, result.Error)
+		results = append(results, result)
+	}
+
+	require.NotEmpty(t, results)
+	first := results[0]
+	assert.Equal(t, "Repertory: Mind; Fear; death, of", first.Title)
+	assert.Equal(t, "Kent", first.Attribution)
+	assert.Equal(t, []string{
+		"Grade 3 (strongly indicated): Aconitum napellus (Acon.), Arsenicum album (Ars.).",
+		"Grade 1: Gelsemium.",
+	}, first.Sentences)
+
+	last := results[len(results)-1]
+	assert.Equal(t, "Repertorisation of 2 rubrics", last.Title)
+	assert.Equal(t, "Arsenicum album covers 2 of 2 rubrics, total grade 6.", last.Sentences[0])
+	assert.Equal(t, "mind-fear-death,mind-restlessness-night", last.Metadata["rubricIds"])
+}
+
+func TestRepertoryToolSingleSymptom(t *testing.T) {
+	repository := odmtest.NewCollection(
+		db.RubricModel{RubricID: "mind-fear-death", Repertory: "Kent", Rubric: "Mind; Fear; death, of",
+			Remedies: []db.RubricRemedy{{Name: "Aconitum napellus", Grade: 3}}},
+	)
+
+	var titles []string
+	for result := range NewRepertoryTool(repository).Run(t.Context(), []string{"fear of death", "toothache"}) {
+		titles = append(titles, result.Title)
+	}
+
+	// a single matching rubric is not repertorised
+	assert.Equal(t, []string{"Repertory: Mind; Fear; death, of"}, titles)
+}
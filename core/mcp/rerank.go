@@ -0,0 +1,297 @@
+package mcp
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/ollama/ollama/api"
+	"go.uber.org/zap"
+)
+
+const (
+	jinaRerankURL          = "https://api.jina.ai/v1/rerank"
+	jinaDefaultRerankModel = "jina-reranker-v2-base-multilingual"
+
+	defaultRerankTopK   = maxChunks
+	defaultRerankBudget = time.Second
+
+	// each candidate is scored on its title and the start of its text
+	maxRerankDocumentLength = 2000
+
+	// concurrent relevance prompts for a local model
+	ollamaRerankParallelism = 4
+)
+
+// relevanceScorer scores each document's relevance to query, higher being more relevant.
+type relevanceScorer interface {
+	Score(ctx context.Context, query string, documents []string) ([]float64, error)
+}
+
+// Reranker re-orders the top hybrid search hits by a cross-encoder's relevance score,
+// which reads query and chunk together and so judges relevance better than either
+// ranking fused. A reranker that fails or overruns its latency budget leaves the fused
+// order as it was.
+type Reranker struct {
+	scorer relevanceScorer // nil disables reranking
+	topK   int
+	budget time.Duration
+}
+
+// ProvideReranker builds the reranker named by the reranker setting: "jina" for Jina's
+// rerank API, "ollama" for a local model, or empty for none.
+func ProvideReranker(ccfg *appconfig.AppConfig) *Reranker {
+	var scorer relevanceScorer
+	switch ccfg.Reranker {
+	case "":
+	case "jina":
+		apiKey := os.Getenv("JINA_AI_API_KEY")
+		if apiKey == "" {
+			logger.Error("JINA_AI_API_KEY is not set, reranking disabled")
+			break
+		}
+		scorer = NewJinaReranker(apiKey, cmp.Or(ccfg.RerankerModel, jinaDefaultRerankModel))
+	case "ollama":
+		client, err := api.ClientFromEnvironment()
+		if err != nil {
+			logger.Error("Failed to create Ollama client, reranking disabled", zap.Error(err))
+			break
+		}
+		scorer = NewOllamaReranker(client, cmp.Or(ccfg.RerankerModel, ccfg.OllamaMiniModel))
+	default:
+		logger.Error("Unknown reranker, reranking disabled", zap.String("reranker", ccfg.Reranker))
+	}
+
+	return NewReranker(scorer, ccfg.RerankTopK, time.Duration(ccfg.RerankBudgetMs)*time.Millisecond)
+}
+
+// NewReranker reranks the topK best fused hits within budget. Zero values use the
+// defaults, and a nil scorer disables reranking.
+func NewReranker(scorer relevanceScorer, topK int, budget time.Duration) *Reranker {
+	if topK <= 0 {
+		topK = defaultRerankTopK
+	}
+	if budget <= 0 {
+		budget = defaultRerankBudget
+	}
+	return &Reranker{scorer: scorer, topK: topK, budget: budget}
+}
+
+func (r *Reranker) Enabled() bool {
+	return r != nil && r.scorer != nil
+}
+
+// Rerank re-orders the first topK chunks by relevance to query, leaving the rest after
+// them in fused order.
+func (r *Reranker) Rerank(ctx context.Context, query string, ranked rankedChunks) rankedChunks {
+	if !r.Enabled() || len(ranked.chunks) < 2 {
+		return ranked
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.budget)
+	defer cancel()
+
+	candidates := ranked.chunks[:min(len(ranked.chunks), r.topK)]
+	documents := make([]string, len(candidates))
+	for i, chunk := range candidates {
+		documents[i] = rerankDocument(chunk)
+	}
+
+	started := time.Now()
+	scores, err := r.scorer.Score(ctx, query, documents)
+	if err == nil && len(scores) != len(documents) {
+		err = fmt.Errorf("got %d relevance scores for %d documents", len(scores), len(documents))
+	}
+	if err != nil {
+		logger.Error("Reranking failed, keeping fused order", zap.Duration("elapsed", time.Since(started)), zap.Error(err))
+		return ranked
+	}
+
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	// ties keep their fused order
+	slices.SortStableFunc(order, func(a, b int) int { return cmp.Compare(scores[b], scores[a]) })
+
+	reranked := ranked
+	reranked.chunks = make([]*db.ChunkModel, 0, len(ranked.chunks))
+	reranked.rerankScores = make(map[string]float64, len(candidates))
+	for _, i := range order {
+		reranked.chunks = append(reranked.chunks, candidates[i])
+		reranked.rerankScores[candidates[i].ChunkID] = scores[i]
+	}
+	reranked.chunks = append(reranked.chunks, ranked.chunks[len(candidates):]...)
+	return reranked
+}
+
+func rerankDocument(chunk *db.ChunkModel) string {
+	text := chunk.Title + "\n" + strings.Join(chunk.Sentences, " ")
+	if len(text) > maxRerankDocumentLength {
+		text = strings.ToValidUTF8(text[:maxRerankDocumentLength], "")
+	}
+	return text
+}
+
+// JinaReranker scores documents with Jina's hosted cross-encoders.
+type JinaReranker struct {
+	apiKey     string
+	model      string
+	url        string
+	httpClient *http.Client
+}
+
+func NewJinaReranker(apiKey, model string) *JinaReranker {
+	return &JinaReranker{
+		apiKey:     apiKey,
+		model:      model,
+		url:        jinaRerankURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (j *JinaReranker) Score(ctx context.Context, query string, documents []string) ([]float64, error) {
+	body, err := json.Marshal(map[string]any{
+		"model":            j.model,
+		"query":            query,
+		"documents":        documents,
+		"top_n":            len(documents),
+		"return_documents": false,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+j.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := j.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to rerank: %s", resp.Status)
+	}
+
+	var result struct {
+		Results []struct {
+			Index          int     `json:"index"`
+			RelevanceScore float64 `json:"relevance_score"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if len(result.Results) != len(documents) {
+		return nil, errors.New("rerank result count does not match document count")
+	}
+
+	scores := make([]float64, len(documents))
+	for _, r := range result.Results {
+		if r.Index < 0 || r.Index >= len(documents) {
+			return nil, fmt.Errorf("rerank result index %d out of range", r.Index)
+		}
+		scores[r.Index] = r.RelevanceScore
+	}
+	return scores, nil
+}
+
+// OllamaReranker scores documents with a local model, asking it to rate each
+// query-document pair on its own, as a cross-encoder would.
+type OllamaReranker struct {
+	client *api.Client
+	model  string
+}
+
+func NewOllamaReranker(client *api.Client, model string) *OllamaReranker {
+	return &OllamaReranker{client: client, model: model}
+}
+
+func (o *OllamaReranker) Score(ctx context.Context, query string, documents []string) ([]float64, error) {
+	scores := make([]float64, len(documents))
+	errs := make([]error, len(documents))
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, ollamaRerankParallelism)
+	for i, document := range documents {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			scores[i], errs[i] = o.score(ctx, query, document)
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+func (o *OllamaReranker) score(ctx context.Context, query, document string) (float64, error) {
+	stream := false
+	var reply strings.Builder
+	err := o.client.Generate(ctx, &api.GenerateRequest{
+		Model:  o.model,
+		Prompt: relevancePrompt(query, document),
+		Stream: &stream,
+		Options: map[string]any{
+			"temperature": 0,
+			"num_predict": 8,
+		},
+	}, func(resp api.GenerateResponse) error {
+		reply.WriteString(resp.Response)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return parseRelevance(reply.String())
+}
+
+func relevancePrompt(query, document string) string {
+	return "Rate how well the document answers the search query, from 0 (unrelated) to 10 (answers it directly). " +
+		"Reply with the number only.\n\nQuery: " + query + "\n\nDocument:\n" + document + "\n\nRating:"
+}
+
+var relevanceNumber = regexp.MustCompile(`\d+(?:\.\d+)?`)
+
+// parseRelevance reads a 0-10 rating from a model's reply as a score from 0 to 1.
+func parseRelevance(reply string) (float64, error) {
+	match := relevanceNumber.FindString(reply)
+	if match == "" {
+		return 0, fmt.Errorf("no relevance rating in %q", reply)
+	}
+	rating, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return 0, err
+	}
+	return min(max(rating, 0), 10) / 10, nil
+}
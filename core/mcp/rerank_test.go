@@ -0,0 +1,128 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type fixedScorer struct {
+	scores []float64
+	err    error
+	delay  time.Duration
+}
+
+func (f fixedScorer) Score(ctx context.Context, query string, documents []string) ([]float64, error) {
+	select {
+	case <-time.After(f.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return f.scores, f.err
+}
+
+func rankedOf(ids ...string) rankedChunks {
+	ranked := rankedChunks{scores: make(map[string]float64)}
+	for _, id := range ids {
+		ranked.chunks = append(ranked.chunks, &db.ChunkModel{ChunkID: id, SectionID: id})
+	}
+	return ranked
+}
+
+func chunkIDs(ranked rankedChunks) []string {
+	ids := make([]string, len(ranked.chunks))
+	for i, chunk := range ranked.chunks {
+		ids[i] = chunk.ChunkID
+	}
+	return ids
+}
+
+func TestRerank(t *testing.T) {
+	reranker := NewReranker(fixedScorer{scores: []float64{0.1, 0.9, 0.5}}, 3, time.Second)
+	reranked := reranker.Rerank(t.Context(), "fear", rankedOf("a", "b", "c", "d"))
+	assert.Equal(t, []string{"b", "c", "a", "d"}, chunkIDs(reranked), "hits beyond top K keep their place")
+	assert.Equal(t, 0.9, reranked.rerankScores["b"])
+	assert.NotContains(t, reranked.rerankScores, "d")
+}
+
+func TestRerankFallback(t *testing.T) {
+	failing := NewReranker(fixedScorer{err: errors.New("unavailable")}, 0, 0)
+	assert.Equal(t, []string{"a", "b"}, chunkIDs(failing.Rerank(t.Context(), "fear", rankedOf("a", "b"))))
+
+	slow := NewReranker(fixedScorer{scores: []float64{0, 1}, delay: time.Second}, 0, 10*time.Millisecond)
+	assert.Equal(t, []string{"a", "b"}, chunkIDs(slow.Rerank(t.Context(), "fear", rankedOf("a", "b"))), "over budget")
+
+	short := NewReranker(fixedScorer{scores: []float64{1}}, 0, 0)
+	assert.Equal(t, []string{"a", "b"}, chunkIDs(short.Rerank(t.Context(), "fear", rankedOf("a", "b"))))
+
+	var disabled *Reranker
+	assert.Equal(t, []string{"a", "b"}, chunkIDs(disabled.Rerank(t.Context(), "fear", rankedOf("a", "b"))))
+}
+
+func TestSearchReranked(t *testing.T) {
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "aconite", SectionID: "aconite", Title: "Aconite", Sentences: []string{"Great fear and anxiety; predicts the day of death."}},
+		db.ChunkModel{ChunkID: "bryonia", SectionID: "bryonia", Title: "Bryonia", Sentences: []string{"Stitching pains worse by motion."}},
+	)
+	vectorRepository := odmtest.NewCollection(
+		db.ChunkAnnModel{ChunkID: "aconite", Embedding: bson.NewVector([]float32{1, 0})},
+		db.ChunkAnnModel{ChunkID: "bryonia", Embedding: bson.NewVector([]float32{0.9, 0.1})},
+	)
+
+	// the fused order is aconite, bryonia; the reranker prefers the second
+	reranker := NewReranker(fixedScorer{scores: []float64{0.2, 0.8}}, 0, 0)
+	searchTool := NewSearchTool(chunkRepository, vectorRepository, fixedEmbedder{1, 0}, WithReranker(reranker))
+
+	var ids []string
+	for result := range searchTool.Run(t.Context(), "fear of death", SearchFilter{}, SearchPage{}) {
+		require.Empty(t, result.Error)
+		ids = append(ids, result.Id)
+		assert.NotEmpty(t, result.Metadata["rerankScore"])
+	}
+	assert.Equal(t, []string{"bryonia", "aconite"}, ids)
+}
+
+func TestJinaReranker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+		var body struct {
+			Query     string   `json:"query"`
+			Documents []string `json:"documents"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "fear", body.Query)
+		assert.Len(t, body.Documents, 2)
+		// results come sorted by relevance, not by index
+		_, _ = w.Write([]byte(`{"results":[{"index":1,"relevance_score":0.8},{"index":0,"relevance_score":0.3}]}`))
+	}))
+	defer server.Close()
+
+	reranker := NewJinaReranker("key", jinaDefaultRerankModel)
+	reranker.url = server.URL
+	scores, err := reranker.Score(t.Context(), "fear", []string{"Bryonia", "Aconite"})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0.3, 0.8}, scores)
+}
+
+func TestParseRelevance(t *testing.T) {
+	score, err := parseRelevance(" 8\n")
+	require.NoError(t, err)
+	assert.Equal(t, 0.8, score)
+
+	score, err = parseRelevance("Rating: 12")
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, score, "ratings are capped at 10")
+
+	_, err = parseRelevance("relevant")
+	assert.Error(t, err)
+}
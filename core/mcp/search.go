@@ -5,7 +5,13 @@ import (
 	"math"
 	"slices"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
 
+	"github.com/SaiNageswarS/agent-boot/llm"
 	"github.com/SaiNageswarS/agent-boot/schema"
 	"github.com/SaiNageswarS/go-api-boot/embed"
 	"github.com/SaiNageswarS/go-api-boot/logger"
@@ -14,6 +20,7 @@ import (
 	"github.com/SaiNageswarS/go-collection-boot/ds"
 	"github.com/SaiNageswarS/go-collection-boot/linq"
 	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
@@ -22,36 +29,301 @@ import (
 
 // search parameters.
 const (
-	rrfK               = 60  // “dampening” constant from the RRF paper
-	textSearchWeight   = 1.0 // optional per-engine weights
-	vectorSearchWeight = 1.0
-	vecK               = 20 // # of hits to keep from each engine
-	textK              = 20
-	maxChunks          = 20
+	rrfK      = 60 // “dampening” constant from the RRF paper
+	vecK      = 20 // # of hits to keep from each engine
+	textK     = 20
+	maxChunks = 20
+
+	speculativeChunks   = 5 // lexical hits considered for early results
+	speculativeSections = 3
+
+	// the vector index can't filter by source or query operators, so filtered searches
+	// fetch this many times more vector hits to make up for those the filter drops
+	filteredVecOversample = 5
+
+	maxSearchDepth = 100 // most chunks ranked for any page
 )
 
+// SearchPage selects a slice of a search's sections in rank order. Later pages rank
+// more chunks, up to the tool's SearchLimits.MaxDepth, so they reach sections the first
+// page never sees. The zero value is the first page: the tool's SearchLimits.TopK
+// sections of its top SearchLimits.Depth chunks.
+type SearchPage struct {
+	Offset int // sections to skip
+	Limit  int // sections to send; zero for the tool's SearchLimits.TopK
+
+	// MinScore is the least relevance of the sections to send: the fused score of their
+	// best window as a share of the most a chunk can score, that of one every engine
+	// ranked first. Ranks count only the sections sent. Zero, or anything below the
+	// tool's SearchLimits.MinScore, counts as the latter.
+	MinScore float64
+}
+
+func (p SearchPage) slice(sections [][]*db.ChunkModel) [][]*db.ChunkModel {
+	start := min(max(p.Offset, 0), len(sections))
+	end := len(sections)
+	if p.Limit > 0 {
+		end = min(start+p.Limit, end)
+	}
+	return sections[start:end]
+}
+
+// FusionWeights scale each engine's vote in reciprocal rank fusion. A zero weight
+// ignores that engine.
+type FusionWeights struct {
+	Text   float64 // BM25 text search
+	Vector float64 // ANN or exact vector search
+}
+
+// DefaultFusionWeights count both engines equally.
+var DefaultFusionWeights = FusionWeights{Text: 1, Vector: 1}
+
 type SearchTool struct {
 	embedder         embed.Embedder
 	chunkRepository  odm.OdmCollectionInterface[db.ChunkModel]
 	vectorRepository odm.OdmCollectionInterface[db.ChunkAnnModel]
+
+	weights FusionWeights
+
+	progressive bool
+	speculative bool
+	lexicalOnly bool
+
+	exact  *ExactVectorIndex
+	tenant string
+
+	reranker *Reranker
+	synonyms *SynonymDictionary
+
+	vocabulary *Vocabulary
+
+	contextExpansion ContextExpansion
+
+	dedupe bool
+
+	diagnostics bool
+
+	hyde      llm.LLMClient
+	decompose llm.LLMClient
+
+	budget time.Duration
+
+	entityBoost float64
+	sourceBoost SourceBoost
+
+	packs []KnowledgePack
+
+	exclusions Exclusions
+
+	access bson.M // nil searches every document
+
+	embeddingCache *tenantEmbeddingCache
+
+	sessionCache *sessionSearchCache
+
+	limits SearchLimits
+
+	facets bool
+
+	figures bool
 }
 
-func NewSearchTool(chunkRepository odm.OdmCollectionInterface[db.ChunkModel], vectorRepository odm.OdmCollectionInterface[db.ChunkAnnModel], embedder embed.Embedder) *SearchTool {
-	return &SearchTool{
+type SearchToolOption func(*SearchTool)
+
+// WithProgressiveRetrieval answers from lexical search alone when its hits are
+// convincing, and only pays for the query embedding and vector search otherwise.
+func WithProgressiveRetrieval() SearchToolOption {
+	return func(s *SearchTool) { s.progressive = true }
+}
+
+// WithSpeculativeResults sends the top lexical sections before fusion finishes, so
+// summarizing them overlaps with the embedding and vector search. Fused results that
+// were already sent are skipped; speculative sections fusion would have ranked lower
+// are still kept, which costs at most speculativeSections extra results.
+func WithSpeculativeResults() SearchToolOption {
+	return func(s *SearchTool) { s.speculative = true }
+}
+
+// WithLexicalOnly never embeds the query, so no query text leaves the deployment.
+// Used by offline tenants, whose embedder would be a hosted API.
+func WithLexicalOnly() SearchToolOption {
+	return func(s *SearchTool) { s.lexicalOnly = true }
+}
+
+// WithExactScan scores small tenants' vectors exactly in-process instead of querying
+// the ANN index.
+func WithExactScan(index *ExactVectorIndex, tenant string) SearchToolOption {
+	return func(s *SearchTool) { s.exact, s.tenant = index, tenant }
+}
+
+// WithFusionWeights weighs text and vector rankings against each other. Negative weights
+// count as zero. A zero vector weight never embeds the query, as WithLexicalOnly; a zero
+// text weight never stops at lexical hits, as WithProgressiveRetrieval otherwise may.
+// Both zero keeps DefaultFusionWeights.
+func WithFusionWeights(weights FusionWeights) SearchToolOption {
+	return func(s *SearchTool) {
+		weights.Text, weights.Vector = max(weights.Text, 0), max(weights.Vector, 0)
+		if weights.Text > 0 || weights.Vector > 0 {
+			s.weights = weights
+		}
+	}
+}
+
+// WithReranker re-orders the top fused hits by relevance before they are grouped into
+// sections. Speculative results are sent before reranking and keep their place.
+func WithReranker(reranker *Reranker) SearchToolOption {
+	return func(s *SearchTool) { s.reranker = reranker }
+}
+
+// WithSynonyms expands the text search query with the synonyms of the remedy names and
+// other terms it mentions, so "Arsenicum album" also matches "Ars.". The query is
+// embedded, reranked and judged for lexical confidence as written.
+func WithSynonyms(dictionary *SynonymDictionary) SearchToolOption {
+	return func(s *SearchTool) { s.synonyms = dictionary }
+}
+
+// WithSpellingCorrection corrects query words the corpus doesn't contain to the closest
+// words it does before searching, so "beladonna" finds Belladonna. Results of a
+// corrected query carry it in the correctedQuery metadata entry.
+func WithSpellingCorrection(vocabulary *Vocabulary) SearchToolOption {
+	return func(s *SearchTool) { s.vocabulary = vocabulary }
+}
+
+// WithContextExpansion sets how much text around its matching windows each section
+// result carries. By default it is the neighbouring windows whole.
+func WithContextExpansion(expansion ContextExpansion) SearchToolOption {
+	return func(s *SearchTool) { s.contextExpansion = expansion }
+}
+
+// WithDeduplication collapses near-identical chunks from different sections, such as a
+// passage every edition of a materia medica repeats, into the best ranked one, so the
+// results aren't spent on copies. A collapsed section's attribution lists the sources
+// of all its copies.
+func WithDeduplication() SearchToolOption {
+	return func(s *SearchTool) { s.dedupe = true }
+}
+
+// WithDiagnostics adds the database queries a search ran, as a relaxed extended JSON
+// array, to the queries metadata entry of each of its results, for tuning retrieval.
+func WithDiagnostics() SearchToolOption {
+	return func(s *SearchTool) { s.diagnostics = true }
+}
+
+// WithExclusions leaves chunks from excluded documents and authors out of both the text
+// and the vector results, as a source filter would, including those of knowledge packs.
+func WithExclusions(exclusions Exclusions) SearchToolOption {
+	return func(s *SearchTool) { s.exclusions = exclusions }
+}
+
+// WithAccessGroups only retrieves chunks of the documents a user in groups may read;
+// see db.AccessFilter. Knowledge packs are searched the same way.
+func WithAccessGroups(groups []string) SearchToolOption {
+	return func(s *SearchTool) { s.access = db.AccessFilter(groups) }
+}
+
+func NewSearchTool(chunkRepository odm.OdmCollectionInterface[db.ChunkModel], vectorRepository odm.OdmCollectionInterface[db.ChunkAnnModel], embedder embed.Embedder, opts ...SearchToolOption) *SearchTool {
+	s := &SearchTool{
 		chunkRepository:  chunkRepository,
 		vectorRepository: vectorRepository,
 		embedder:         embedder,
+		weights:          DefaultFusionWeights,
+		limits:           DefaultSearchLimits,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-func (s *SearchTool) Run(ctx context.Context, query string) <-chan *schema.ToolResultChunk {
+// Run searches the corpus for query, restricted to the sources filter allows, and sends
+// one result per matching section of page, best first. Each carries its rank among all
+// the sections, counting from 1, in the rank metadata entry, and the scores of its best
+// window: fused, and the text and vector search rank and score of the engines that
+// found it.
+func (s *SearchTool) Run(ctx context.Context, query string, filter SearchFilter, page SearchPage) <-chan *schema.ToolResultChunk {
+	if s.sessionCache != nil && !s.lexicalOnly && !s.diagnostics && s.embedder != nil && s.weights.Vector > 0 {
+		return s.runCached(ctx, query, filter, page)
+	}
+	return s.run(ctx, query, filter, page)
+}
+
+func (s *SearchTool) run(ctx context.Context, query string, filter SearchFilter, page SearchPage) <-chan *schema.ToolResultChunk {
 	out := make(chan *schema.ToolResultChunk, 20)
 
+	var queries *queryLog
+	if s.diagnostics {
+		ctx, queries = withQueryLog(ctx)
+	}
+
+	query, corrected := s.correct(query)
+	if corrected {
+		logger.Info("Corrected search query", zap.String("query", query))
+	}
+
+	marks := s.highlighter(query)
+
+	// speculative results can't be held to a minimum score
+	firstPage := page == (SearchPage{}) && s.limits.MinScore == 0
+	page = s.limits.page(page)
+
 	go func() {
 		defer close(out)
 
+		// send reports the corrected query, and the queries run so far for diagnostics,
+		// with every section.
+		send := func(result *schema.ToolResultChunk) {
+			if corrected && result.Metadata != nil {
+				result.Metadata["correctedQuery"] = query
+			}
+			if queries != nil && result.Metadata != nil {
+				result.Metadata["queries"] = queries.json()
+			}
+			out <- result
+		}
+
+		var (
+			mu          sync.Mutex
+			emitted     = ds.NewSet[string]() // section IDs already sent
+			speculative sync.WaitGroup
+		)
+		defer speculative.Wait() // runs before close(out)
+
+		// claim reports whether the section still has to be sent, and marks it as sent.
+		claim := func(sectionId string) bool {
+			mu.Lock()
+			defer mu.Unlock()
+			if emitted.Contains(sectionId) {
+				return false
+			}
+			emitted.Add(sectionId)
+			return true
+		}
+
+		var onLexical func([]odm.SearchHit[db.ChunkModel])
+		if s.speculative && s.weights.Text > 0 && firstPage {
+			// Send the best lexical sections right away so the agent can start summarizing
+			// them while the query is still being embedded and vector-searched.
+			onLexical = func(hits []odm.SearchHit[db.ChunkModel]) {
+				speculative.Add(1)
+				go func() {
+					defer speculative.Done()
+
+					top, _ := s.materializeTextHits(ctx, hits[:min(len(hits), speculativeChunks)])
+					if s.dedupe {
+						top = collapseDuplicates(top)
+					}
+					sections := GroupBySectionWithRank(top.chunks)
+					for _, section := range sections[:min(len(sections), speculativeSections)] {
+						if claim(section[0].SectionID) {
+							send(s.sectionResult(ctx, section, top, marks))
+						}
+					}
+				}()
+			}
+		}
+
 		// 1. Perform Hybrid Search and Collect results ranked by RRF score
-		rankedChunks, err := async.Await(s.hybridSearch(ctx, query))
+		ranked, err := s.search(ctx, query, filter, s.limits.depth(page), onLexical)
 		if err != nil {
 			logger.Error("Failed to perform hybrid search", zap.Error(err))
 			out <- &schema.ToolResultChunk{
@@ -60,67 +332,43 @@ func (s *SearchTool) Run(ctx context.Context, query string) <-chan *schema.ToolR
 			return
 		}
 
-		// 2. Group by section with adjoining chunks and rank
-		sectionChunks := GroupBySectionWithRank(rankedChunks)
+		// 2. Group by section with adjoining chunks and rank, and keep the page
+		sectionChunks := page.slice(relevant(GroupBySectionWithRank(ranked.chunks), ranked, page.MinScore))
+		ranks := make(map[string]int, len(sectionChunks))
+		for i, section := range sectionChunks {
+			ranks[section[0].SectionID] = page.Offset + i + 1
+		}
+
+		var facets string
+		if s.facets {
+			facets = countFacets(ranked.chunks).json()
+		}
 
 		_, err = linq.Pipe3(
 			linq.FromSlice(ctx, sectionChunks),
 
-			// sort windows in the section.
-			linq.Select(func(sectionChunks []*db.ChunkModel) []*db.ChunkModel {
-				sort.Slice(sectionChunks, func(i, j int) bool {
-					return sectionChunks[i].WindowIndex < sectionChunks[j].WindowIndex
-				})
-				return sectionChunks
+			// skip sections already sent speculatively.
+			linq.Where(func(sectionChunks []*db.ChunkModel) bool {
+				return claim(sectionChunks[0].SectionID)
 			}),
 
-			// get neighboring chunks
+			// sort windows and get neighboring chunks.
 			linq.Select(func(sectionChunks []*db.ChunkModel) *schema.ToolResultChunk {
-				result := &schema.ToolResultChunk{
-					Title:       sectionChunks[0].Title,
-					Attribution: sectionChunks[0].SourceURI,
-					Id:          sectionChunks[0].SectionID,
-				}
-
-				cache := make(map[string]*db.ChunkModel, len(sectionChunks)*2)
-				for _, ch := range sectionChunks {
-					cache[ch.ChunkID] = ch
-				}
-
-				// Collect only missing neighbor IDs
-				added := ds.NewSet[string]()
-				needIds := make([]string, 0, len(sectionChunks)*2)
-				for _, ch := range sectionChunks {
-					if id := ch.PrevChunkID; id != "" && !added.Contains(id) {
-						added.Add(id)
-						needIds = append(needIds, id)
-					}
-
-					if id := ch.ChunkID; id != "" && !added.Contains(id) {
-						added.Add(id)
-						needIds = append(needIds, id)
-					}
-
-					if id := ch.NextChunkID; id != "" && !added.Contains(id) {
-						added.Add(id)
-						needIds = append(needIds, id)
-					}
-				}
-
-				allChunks := s.fetchChunksByIds(ctx, cache, needIds)
-
-				sentences := make([]string, 0, len(allChunks)*20)
-				for _, chunk := range allChunks {
-					sentences = append(sentences, chunk.Sentences...)
+				result := s.sectionResult(ctx, sectionChunks, ranked, marks)
+				result.Metadata["rank"] = strconv.Itoa(ranks[result.Id])
+				if ranked.partial {
+					result.Metadata["partial"] = "true"
 				}
-
-				result.Sentences = sentences
 				return result
 			}),
 
 			linq.ForEach(func(result *schema.ToolResultChunk) {
+				// the first ranked result sent carries the facets
+				if facets != "" {
+					result.Metadata["facets"], facets = facets, ""
+				}
 				// Add the result to the output channel
-				out <- result
+				send(result)
 			}),
 		)
 
@@ -132,6 +380,176 @@ func (s *SearchTool) Run(ctx context.Context, query string) <-chan *schema.ToolR
 	return out
 }
 
+// correct corrects the spelling of a plain query. Queries with operators are searched
+// as written.
+func (s *SearchTool) correct(query string) (string, bool) {
+	if !ParseQuery(query).IsPlain() {
+		return query, false
+	}
+	return s.vocabulary.correct(query, s.synonyms)
+}
+
+// search runs the hybrid search for the top depth chunks of the tenant's corpus and its
+// knowledge packs, boosts those about the entities the query mentions and those from
+// newer and preferred sources, reranks them when a reranker is configured and collapses
+// near duplicates, keeping the best ranked copy, when deduplication is on.
+func (s *SearchTool) search(ctx context.Context, query string, filter SearchFilter, depth int, onLexical func([]odm.SearchHit[db.ChunkModel])) (rankedChunks, error) {
+	parsed := ParseQuery(query)
+	if strings.TrimSpace(parsed.Text()) == "" {
+		return rankedChunks{}, status.Error(codes.InvalidArgument, "the query has no terms to search for outside NOT")
+	}
+
+	var parts <-chan async.Result[[]subQueryRanking]
+	if s.decompose != nil {
+		parts = s.searchSubQueries(ctx, parsed, filter, depth)
+	}
+	ranked, err := s.searchSources(ctx, parsed, filter, depth, onLexical)
+	if err != nil {
+		return ranked, err
+	}
+	if parts != nil {
+		rankings, _ := async.Await(parts)
+		ranked = mergeSubQueryResults(ranked, rankings, depth)
+	}
+	ranked = s.boostEntities(parsed.Text(), ranked)
+	ranked = s.boostSources(ranked)
+	ranked = s.reranker.Rerank(ctx, parsed.Text(), ranked)
+	if s.dedupe {
+		ranked = collapseDuplicates(ranked)
+	}
+	return ranked, nil
+}
+
+// sourceLocation places a section's windows in their source for citations: the
+// chapter and section headings they are under, the PDF pages they span, such as "12"
+// or "12-14", and the language they are in.
+func sourceLocation(sectionChunks []*db.ChunkModel) map[string]string {
+	location := map[string]string{}
+	first := sectionChunks[0]
+	if first.Chapter != "" {
+		location["chapter"] = first.Chapter
+	}
+	if first.Section != "" {
+		location["section"] = first.Section
+	}
+	if first.Language != "" {
+		location["language"] = first.Language
+	}
+
+	start, end := 0, 0
+	for _, ch := range sectionChunks {
+		if ch.PageStart > 0 && (start == 0 || ch.PageStart < start) {
+			start = ch.PageStart
+		}
+		end = max(end, ch.PageEnd)
+	}
+	switch {
+	case start == 0:
+	case end > start:
+		location["pages"] = strconv.Itoa(start) + "-" + strconv.Itoa(end)
+	default:
+		location["pages"] = strconv.Itoa(start)
+	}
+	return location
+}
+
+// sectionResult turns the ranked windows of one section into a tool result,
+// pulling in the neighbouring windows and expanding into them as s.contextExpansion says.
+// The section's fused and rerank scores are those of its best window, and its text
+// and vector scores those of its best fused window. Where its sentences mention the
+// query's terms and entities goes in the highlights metadata entry, and with
+// WithFigures, the figures its windows caption or mention in the figures entry.
+func (s *SearchTool) sectionResult(ctx context.Context, sectionChunks []*db.ChunkModel, ranked rankedChunks, marks highlighter) *schema.ToolResultChunk {
+	var score, rerankScore float64
+	reranked := false
+	best := sectionChunks[0]
+	for _, ch := range sectionChunks {
+		if ranked.scores[ch.ChunkID] > score {
+			score, best = ranked.scores[ch.ChunkID], ch
+		}
+		if r, ok := ranked.rerankScores[ch.ChunkID]; ok {
+			rerankScore = max(rerankScore, r)
+			reranked = true
+		}
+	}
+
+	// sort windows in the section.
+	sort.Slice(sectionChunks, func(i, j int) bool {
+		return sectionChunks[i].WindowIndex < sectionChunks[j].WindowIndex
+	})
+
+	result := &schema.ToolResultChunk{
+		Title:       sectionChunks[0].Title,
+		Attribution: ranked.attribution(sectionChunks),
+		Id:          sectionChunks[0].SectionID,
+		// agent-boot drops Id when it summarizes a result; metadata survives for citations.
+		Metadata: map[string]string{
+			"sectionId":  sectionChunks[0].SectionID,
+			"fusedScore": strconv.FormatFloat(score, 'g', 4, 64),
+		},
+	}
+	if reranked {
+		result.Metadata["rerankScore"] = strconv.FormatFloat(rerankScore, 'g', 4, 64)
+	}
+	if boost, ok := ranked.sourceBoosts[best.ChunkID]; ok {
+		result.Metadata["sourceBoost"] = strconv.FormatFloat(boost, 'g', 4, 64)
+	}
+	if subQuery, ok := ranked.subQueries[best.ChunkID]; ok {
+		result.Metadata["subQuery"] = subQuery
+	}
+	for key, value := range sourceLocation(sectionChunks) {
+		result.Metadata[key] = value
+	}
+	pack := ranked.packs[best.ChunkID]
+	if pack != "" {
+		result.Metadata["knowledgePack"] = pack
+	}
+	for engine, hits := range map[string]engineHits{"text": ranked.text, "vector": ranked.vector} {
+		if rank, ok := hits.ranks[best.ChunkID]; ok {
+			result.Metadata[engine+"Rank"] = strconv.Itoa(rank)
+			result.Metadata[engine+"Score"] = strconv.FormatFloat(hits.scores[best.ChunkID], 'g', 4, 64)
+		}
+	}
+
+	cache := make(map[string]*db.ChunkModel, len(sectionChunks)*2)
+	hits := make(map[string]bool, len(sectionChunks))
+	for _, ch := range sectionChunks {
+		cache[ch.ChunkID] = ch
+		hits[ch.ChunkID] = true
+	}
+
+	// Collect only missing neighbor IDs
+	added := ds.NewSet[string]()
+	needIds := make([]string, 0, len(sectionChunks)*2)
+	for _, ch := range sectionChunks {
+		if id := ch.PrevChunkID; id != "" && !added.Contains(id) {
+			added.Add(id)
+			needIds = append(needIds, id)
+		}
+
+		if id := ch.ChunkID; id != "" && !added.Contains(id) {
+			added.Add(id)
+			needIds = append(needIds, id)
+		}
+
+		if id := ch.NextChunkID; id != "" && !added.Contains(id) {
+			added.Add(id)
+			needIds = append(needIds, id)
+		}
+	}
+
+	allChunks := s.source(pack).fetchChunksByIds(ctx, cache, needIds)
+
+	result.Sentences = s.contextExpansion.expand(mergeWindows(allChunks, hits))
+	if highlights := marks.highlights(result.Sentences); len(highlights) > 0 {
+		result.Metadata["highlights"] = highlightsJSON(highlights)
+	}
+	if figures := sectionFigures(allChunks); s.figures && len(figures) > 0 {
+		result.Metadata["figures"] = figuresJSON(figures)
+	}
+	return result
+}
+
 // ──────────────────────────────────────────────────────────────────────────────
 //
 //	Reciprocal-Rank Fusion (RRF)
@@ -175,56 +593,154 @@ func (s *SearchTool) Run(ctx context.Context, query string) <-chan *schema.ToolR
 //	score thresholds only for domain-specific guard-rails.
 //
 // ──────────────────────────────────────────────────────────────────────────────
-func (s *SearchTool) hybridSearch(ctx context.Context, query string) <-chan async.Result[[]*db.ChunkModel] {
+// onLexical, when set, is called with the lexical hits as soon as they arrive and
+// before fusion, unless lexical hits alone end up answering the query.
+//
+// Both engines rank by parsed's text. The chunks parsed's operators, filter, the
+// exclusions and the access groups allow are matched after the text search, and looked
+// up for the vector hits. Vector search only fetches extra hits for the first three,
+// which may leave out most of the corpus, while a library's restricted documents are
+// usually few.
+func (s *SearchTool) hybridSearch(ctx context.Context, parsed Query, filter SearchFilter, depth int, onLexical func([]odm.SearchHit[db.ChunkModel])) <-chan async.Result[rankedChunks] {
+	query := parsed.Text()
+	match, restricted := filter.bson(), !filter.IsZero()
+	if !parsed.IsPlain() {
+		match, restricted = bson.M{"$and": bson.A{match, parsed.bson()}}, true
+	}
+	if !s.exclusions.IsZero() {
+		match, restricted = bson.M{"$and": bson.A{match, s.exclusions.Filter()}}, true
+	}
+	filtered := restricted
+	if s.access != nil {
+		match, filtered = bson.M{"$and": bson.A{match, s.access}}, true
+	}
+
+	return async.Go(func() (rankedChunks, error) {
+		// the engines search within the time budget
+		legs, cancel := s.legsContext(ctx)
+		defer cancel()
 
-	return async.Go(func() ([]*db.ChunkModel, error) {
 		//----------------------------------------------------------------------
 		// 1. Fire the two independent searches in parallel
 		//----------------------------------------------------------------------
+		textQuery, textLimit := s.synonyms.Expand(query), textK*depth/maxChunks
+		logQuery(ctx, textSearchQuery(textQuery, match, textLimit))
 		textTask := s.chunkRepository.
-			TermSearch(ctx, query, odm.TermSearchParams{
+			TermSearch(legs, textQuery, odm.TermSearchParams{
 				IndexName: db.TextSearchIndexName,
 				Path:      db.TextSearchPaths,
-				Limit:     textK,
+				Filter:    match,
+				Limit:     textLimit,
 			})
 
-		emb, err := async.Await(s.embedder.GetEmbedding(ctx, query, embed.WithTask("retrieval.query")))
-		if err != nil {
-			return nil, status.Errorf(codes.Internal, "embed: %v", err)
+		if s.lexicalOnly || s.weights.Vector == 0 {
+			hits, err := awaitLeg(legs, textTask)
+			if err != nil {
+				if expired(ctx, legs) {
+					return rankedChunks{}, errSearchTimedOut
+				}
+				return rankedChunks{}, status.Errorf(codes.Internal, "text search: %v", err)
+			}
+			return s.materializeTextHits(ctx, hits)
 		}
 
-		vecTask := s.vectorRepository.
-			VectorSearch(ctx, emb, odm.VectorSearchParams{
-				IndexName:     db.VectorIndexName,
-				Path:          db.VectorPath,
-				K:             vecK,
-				NumCandidates: 100,
+		var textHits []odm.SearchHit[db.ChunkModel]
+		if s.progressive && s.weights.Text > 0 {
+			// Cheap pass first: wait for lexical hits and stop there if they are convincing.
+			hits, err := awaitLeg(legs, textTask)
+			if err == nil && lexicalConfident(query, hits) {
+				logger.Info("Lexical search confident, skipping vector search", zap.String("query", query))
+				return s.materializeTextHits(ctx, hits)
+			}
+			textHits = hits
+			if err == nil && onLexical != nil {
+				onLexical(hits)
+			}
+			textTask = async.Go(func() ([]odm.SearchHit[db.ChunkModel], error) { return hits, err })
+		} else if onLexical != nil {
+			lexicalTask := textTask
+			textTask = async.Go(func() ([]odm.SearchHit[db.ChunkModel], error) {
+				hits, err := awaitLeg(legs, lexicalTask)
+				if err == nil {
+					onLexical(hits)
+				}
+				return hits, err
 			})
+		}
+
+		// the hypothetical passage is written while the query is embedded and searched
+		var passageTask <-chan async.Result[[]float32]
+		if s.hyde != nil {
+			passageTask = s.hypotheticalPassageEmbedding(legs, query)
+		}
+
+		var vecTask, passageVecTask <-chan async.Result[[]odm.SearchHit[db.ChunkAnnModel]]
+		emb, err := s.embedQuery(legs, query)
+		switch {
+		case err == nil:
+			k := vecK * depth / maxChunks
+			if restricted {
+				k *= filteredVecOversample
+			}
+			vecTask = s.vectorSearch(legs, emb, k)
+
+			if passageTask != nil {
+				if passageEmb, err := awaitLeg(legs, passageTask); err == nil {
+					passageVecTask = s.vectorSearch(legs, passageEmb, k)
+				}
+			}
+		case expired(ctx, legs):
+			// out of time: fuse whatever text hits there are
+		case len(textHits) > 0:
+			// Escalation failed; lexical hits are better than nothing.
+			logger.Error("Failed to embed query, using lexical hits only", zap.Error(err))
+			return s.materializeTextHits(ctx, textHits)
+		default:
+			return rankedChunks{}, status.Errorf(codes.Internal, "embed: %v", err)
+		}
 
 		//----------------------------------------------------------------------
 		// 2. Convert each result list → id→rank    (rank ∈ {1,2,…})
 		//----------------------------------------------------------------------
-		textRanks, cache, err := collectTextSearchRanks(textTask)
-		if err != nil {
-			logger.Error("text search failed", zap.Error(err))
+		text, cache, textErr := collectTextSearchRanks(legs, textTask)
+		if textErr != nil {
+			logger.Error("text search failed", zap.Error(textErr))
 		}
 
-		vecRanks, err := collectVectorSearchRanks(vecTask)
-		if err != nil {
-			logger.Error("vector search failed", zap.Error(err))
+		vector := engineHits{ranks: make(map[string]int), scores: make(map[string]float64)}
+		vecErr := err
+		if vecTask != nil {
+			vector, vecErr = collectVectorSearchRanks(legs, vecTask)
+			if vecErr != nil {
+				logger.Error("vector search failed", zap.Error(vecErr))
+			}
+		}
+		if passageVecTask != nil {
+			passage, err := collectVectorSearchRanks(legs, passageVecTask)
+			if err != nil {
+				logger.Error("hypothetical passage vector search failed", zap.Error(err))
+			} else {
+				vector = unionVectorHits(vector, passage)
+			}
+		}
+
+		partial := (textErr != nil || vecErr != nil) && expired(ctx, legs)
+		if partial {
+			if textErr != nil && vecErr != nil {
+				return rankedChunks{}, errSearchTimedOut
+			}
+			logger.Info("Search time budget ran out, returning partial results", zap.String("query", query),
+				zap.Bool("textAnswered", textErr == nil), zap.Bool("vectorAnswered", vecErr == nil))
+		}
+		if filtered {
+			s.filterVectorRanks(ctx, match, cache, vector.ranks)
 		}
 
 		//----------------------------------------------------------------------
 		// 3. Reciprocal-Rank Fusion
 		//     score(id) = Σ  weight_e / (rrfK + rank_e(id))
 		//----------------------------------------------------------------------
-		combined := make(map[string]float64)
-		for id, r := range textRanks {
-			combined[id] = textSearchWeight / float64(rrfK+r)
-		}
-		for id, r := range vecRanks {
-			combined[id] += vectorSearchWeight / float64(rrfK+r)
-		}
+		combined := fuse(s.weights, text.ranks, vector.ranks)
 
 		//----------------------------------------------------------------------
 		// 4. Keep the top-N with a min-heap (higher RRF score = better)
@@ -237,7 +753,7 @@ func (s *SearchTool) hybridSearch(ctx context.Context, query string) <-chan asyn
 		h := ds.NewMinHeap(func(a, b pair) bool { return a.score < b.score })
 		for id, sc := range combined {
 			h.Push(pair{id, sc})
-			if h.Len() > maxChunks {
+			if h.Len() > depth {
 				h.Pop()
 			}
 		}
@@ -250,58 +766,252 @@ func (s *SearchTool) hybridSearch(ctx context.Context, query string) <-chan asyn
 
 		if err != nil {
 			logger.Error("Failed to collect top-N chunk IDs", zap.Error(err))
-			return nil, status.Errorf(codes.Internal, "collect top-N: %v", err)
+			return rankedChunks{}, status.Errorf(codes.Internal, "collect top-N: %v", err)
 		}
 
 		//----------------------------------------------------------------------
-		// 5. Materialise the chunks
+		// 5. Materialise the chunks. Both engines filtered out retired chunks
+		//    before ranking.
 		//----------------------------------------------------------------------
-		return s.fetchChunksByIds(ctx, cache, ids), nil
+		chunks := s.fetchChunksByIds(ctx, cache, ids)
+		return rankedChunks{chunks: chunks, scores: combined, text: text, vector: vector, partial: partial, ceiling: scoreCeiling(s.weights)}, err
 	})
 }
 
+// rankedChunks are search results in ranked order, with the fused score of each by
+// chunk ID, the rank and score each engine gave the chunks it found, and the rerank
+// score of those the reranker scored.
+type rankedChunks struct {
+	chunks       []*db.ChunkModel
+	scores       map[string]float64
+	text         engineHits
+	vector       engineHits
+	rerankScores map[string]float64
+	duplicates   map[string][]string // chunk ID → sources of the copies collapsed into it
+	partial      bool                // an engine ran out of time budget and was left out
+	packs        map[string]string   // chunk ID → knowledge pack it came from, for those not the tenant's
+	ceiling      float64             // fused score of a chunk every engine that ran ranked first
+	sourceBoosts map[string]float64  // chunk ID → factor its score was boosted by for its source
+	subQueries   map[string]string   // chunk ID → part of the query that found it, for those the whole query didn't rank higher
+}
+
+// engineHits are one search engine's rank, from 1, and raw score of each chunk it found.
+type engineHits struct {
+	ranks  map[string]int
+	scores map[string]float64
+}
+
+// fuse scores every ranked chunk by reciprocal rank fusion. Engines weighted zero do not
+// vote, so chunks only they found are left out.
+func fuse(weights FusionWeights, textRanks, vecRanks map[string]int) map[string]float64 {
+	combined := make(map[string]float64, len(textRanks)+len(vecRanks))
+	if weights.Text > 0 {
+		for id, r := range textRanks {
+			combined[id] += weights.Text / float64(rrfK+r)
+		}
+	}
+	if weights.Vector > 0 {
+		for id, r := range vecRanks {
+			combined[id] += weights.Vector / float64(rrfK+r)
+		}
+	}
+	return combined
+}
+
+// embedQuery embeds query for retrieval, through the embedding cache when there is one.
+// It gives up when ctx is done, even if the embedder doesn't.
+func (s *SearchTool) embedQuery(ctx context.Context, query string) ([]float32, error) {
+	if s.embeddingCache != nil {
+		return s.embeddingCache.embed(ctx, s.embedder, query)
+	}
+	return awaitLeg(ctx, s.embedder.GetEmbedding(ctx, query, embedding.WithTask("retrieval.query")))
+}
+
+// vectorSearch uses the exact scan when the tenant is small enough, and the ANN index otherwise.
+func (s *SearchTool) vectorSearch(ctx context.Context, emb []float32, k int) <-chan async.Result[[]odm.SearchHit[db.ChunkAnnModel]] {
+	if s.exact != nil {
+		hits, ok, err := s.exact.Search(ctx, s.tenant, s.vectorRepository, emb, k)
+		if err != nil {
+			logger.Error("Exact vector scan failed, using ANN index", zap.String("tenant", s.tenant), zap.Error(err))
+		} else if ok {
+			logQuery(ctx, exactScanQuery(k))
+			return async.Go(func() ([]odm.SearchHit[db.ChunkAnnModel], error) { return hits, nil })
+		}
+	}
+
+	logQuery(ctx, vectorSearchQuery(len(emb), k, 5*k))
+	return s.vectorRepository.
+		VectorSearch(ctx, emb, odm.VectorSearchParams{
+			IndexName:     db.VectorIndexName,
+			Path:          db.VectorPath,
+			K:             k,
+			NumCandidates: 5 * k,
+			Filter:        db.LiveVectorsFilter(),
+		})
+}
+
+// filterVectorRanks drops the vector hits match doesn't allow. The vector index holds
+// embeddings alone, so each hit's chunk is looked up, and kept in cache for fusion to
+// materialize. Text hits in cache were matched by the text search already.
+func (s *SearchTool) filterVectorRanks(ctx context.Context, match bson.M, cache map[string]*db.ChunkModel, vecRanks map[string]int) {
+	var missing []string
+	for id := range vecRanks {
+		if _, ok := cache[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	lookup := bson.M{"$and": bson.A{
+		bson.M{"_id": bson.M{"$in": missing}},
+		match,
+	}}
+	logQuery(ctx, findChunksQuery(lookup))
+	chunks, err := async.Await(s.chunkRepository.Find(ctx, lookup, nil, 0, 0))
+	if err != nil {
+		// without the chunks there is no telling which hits match, so drop them all
+		logger.Error("Failed to filter vector hits", zap.Error(err))
+	}
+	for i := range chunks {
+		cache[chunks[i].ChunkID] = &chunks[i]
+	}
+	for _, id := range missing {
+		if _, ok := cache[id]; !ok {
+			delete(vecRanks, id)
+		}
+	}
+}
+
+// materializeTextHits ranks lexical hits on their own, scored as fusion would score a
+// text ranking with no vector votes. The text search limit already cut hits to depth.
+func (s *SearchTool) materializeTextHits(ctx context.Context, hits []odm.SearchHit[db.ChunkModel]) (rankedChunks, error) {
+	chunks := make([]*db.ChunkModel, 0, len(hits))
+	text := engineHits{ranks: make(map[string]int, len(hits)), scores: make(map[string]float64, len(hits))}
+	for i := range hits {
+		chunks = append(chunks, &hits[i].Doc)
+		if _, seen := text.ranks[hits[i].Doc.ChunkID]; !seen {
+			text.ranks[hits[i].Doc.ChunkID] = i + 1
+			text.scores[hits[i].Doc.ChunkID] = hits[i].Score
+		}
+	}
+
+	// offline tenants search text alone whatever the weights
+	weights := FusionWeights{Text: s.weights.Text}
+	if weights.Text == 0 {
+		weights.Text = DefaultFusionWeights.Text
+	}
+
+	return rankedChunks{chunks: chunks, scores: fuse(weights, text.ranks, nil), text: text, ceiling: scoreCeiling(weights)}, nil
+}
+
+// Lexical confidence thresholds for progressive retrieval.
+const (
+	minConfidentHits     = 3    // fewer hits than this means the corpus barely matched
+	minQueryTermCoverage = 0.8  // share of query terms found in the top hits
+	topHitsForCoverage   = 3    // how many top hits the coverage is measured over
+	minScoreMargin       = 1.15 // top BM25 score must lead the runner-up by this ratio
+)
+
+// lexicalConfident decides whether BM25 hits alone are good enough to answer.
+// Raw BM25 scores aren't comparable across corpora, so it relies on term coverage
+// and on the top hit clearly leading, both of which are scale-free.
+func lexicalConfident(query string, hits []odm.SearchHit[db.ChunkModel]) bool {
+	if len(hits) < minConfidentHits {
+		return false
+	}
+
+	terms := queryTerms(query)
+	if len(terms) == 0 {
+		return false
+	}
+
+	var top strings.Builder
+	for _, h := range hits[:min(len(hits), topHitsForCoverage)] {
+		top.WriteString(strings.ToLower(h.Doc.Title))
+		top.WriteByte(' ')
+		for _, sentence := range h.Doc.Sentences {
+			top.WriteString(strings.ToLower(sentence))
+			top.WriteByte(' ')
+		}
+	}
+	text := top.String()
+
+	covered := 0
+	for _, term := range terms {
+		if strings.Contains(text, term) {
+			covered++
+		}
+	}
+	if float64(covered)/float64(len(terms)) < minQueryTermCoverage {
+		return false
+	}
+
+	return hits[1].Score <= 0 || hits[0].Score/hits[1].Score >= minScoreMargin
+}
+
+// queryTerms lower-cases the query and drops short words, which are mostly stop words.
+func queryTerms(query string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if len([]rune(f)) > 2 {
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
+
 // Returns id→rank (1-based) **and** a cache of the full ChunkModel docs.
 func collectTextSearchRanks(
+	legs context.Context,
 	task <-chan async.Result[[]odm.SearchHit[db.ChunkModel]],
-) (map[string]int, map[string]*db.ChunkModel, error) {
+) (engineHits, map[string]*db.ChunkModel, error) {
 
-	ranks := make(map[string]int) // id → rank
+	text := engineHits{ranks: make(map[string]int), scores: make(map[string]float64)}
 	cache := make(map[string]*db.ChunkModel)
 
-	hits, err := async.Await(task)
+	hits, err := awaitLeg(legs, task)
 	if err != nil {
-		return ranks, cache, status.Errorf(codes.Internal, "await text hits: %v", err)
+		return text, cache, status.Errorf(codes.Internal, "await text hits: %v", err)
 	}
 
 	for i, h := range hits {
 		id := h.Doc.Id()
-		if _, seen := ranks[id]; !seen { // keep first (best-ranked) hit
-			ranks[id] = i + 1  // 1-based rank
+		if _, seen := text.ranks[id]; !seen { // keep first (best-ranked) hit
+			text.ranks[id] = i + 1 // 1-based rank
+			text.scores[id] = h.Score
 			cache[id] = &h.Doc // stash full doc for later
 		}
 	}
-	return ranks, cache, nil
+	return text, cache, nil
 }
 
-// Returns id→rank (1-based) for vector search hits.
+// Returns id→rank (1-based) and id→score for vector search hits.
 func collectVectorSearchRanks(
+	legs context.Context,
 	task <-chan async.Result[[]odm.SearchHit[db.ChunkAnnModel]],
-) (map[string]int, error) {
+) (engineHits, error) {
 
-	ranks := make(map[string]int)
+	vector := engineHits{ranks: make(map[string]int), scores: make(map[string]float64)}
 
-	hits, err := async.Await(task)
+	hits, err := awaitLeg(legs, task)
 	if err != nil {
-		return ranks, status.Errorf(codes.Internal, "await vector hits: %v", err)
+		return vector, status.Errorf(codes.Internal, "await vector hits: %v", err)
 	}
 
 	for i, h := range hits {
 		id := h.Doc.Id()
-		if _, seen := ranks[id]; !seen {
-			ranks[id] = i + 1
+		if _, seen := vector.ranks[id]; !seen {
+			vector.ranks[id] = i + 1
+			vector.scores[id] = h.Score
 		}
 	}
-	return ranks, nil
+	return vector, nil
 }
 
 func (s *SearchTool) fetchChunksByIds(ctx context.Context, cache map[string]*db.ChunkModel, rankedIds []string) []*db.ChunkModel {
@@ -324,8 +1034,13 @@ func (s *SearchTool) fetchChunksByIds(ctx context.Context, cache map[string]*db.
 
 	if len(missing) > 0 {
 		/* 2. fetch all missing in **one** DB round-trip -------- */
+		lookup := bson.M{"_id": bson.M{"$in": missing}}
+		if s.access != nil {
+			lookup = bson.M{"$and": bson.A{lookup, s.access}}
+		}
+		logQuery(ctx, findChunksQuery(lookup))
 		dbChunks, err := async.Await(
-			s.chunkRepository.Find(ctx, bson.M{"_id": bson.M{"$in": missing}}, nil, 0, 0),
+			s.chunkRepository.Find(ctx, lookup, nil, 0, 0),
 		)
 		if err != nil {
 			logger.Error("Failed to fetch chunks from database", zap.Error(err))
@@ -0,0 +1,115 @@
+package mcp
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// SearchFilter restricts a search to chunks from particular sources. Books, authors
+// and chapters match any part of the stored name, ignoring case, so "boericke" finds
+// "William Boericke". A chunk must match every field that is set, and any one value of
+// each. Chapters are matched against the section path. Languages are base languages
+// such as "de", matched whole; chunks ingested before languages were detected have
+// none, and are left out by them.
+type SearchFilter struct {
+	Books     []string
+	Authors   []string
+	Chapters  []string
+	Languages []string
+	YearFrom  int // inclusive; zero for no lower bound
+	YearTo    int // inclusive; zero for no upper bound
+}
+
+func (f SearchFilter) IsZero() bool {
+	return len(nonBlank(f.Books)) == 0 && len(nonBlank(f.Authors)) == 0 && len(nonBlank(f.Chapters)) == 0 &&
+		len(nonBlank(f.Languages)) == 0 && f.YearFrom <= 0 && f.YearTo <= 0
+}
+
+// bson matches the live chunks the filter allows. Chunks with no publication year are
+// left out by either year bound.
+func (f SearchFilter) bson() bson.M {
+	if f.IsZero() {
+		return db.LiveChunksFilter()
+	}
+
+	clauses := bson.A{db.LiveChunksFilter()}
+	for _, clause := range []struct {
+		field  string
+		values []string
+	}{{"book", f.Books}, {"author", f.Authors}, {"sectionPath", f.Chapters}} {
+		if match, ok := anyContains(clause.field, clause.values); ok {
+			clauses = append(clauses, match)
+		}
+	}
+
+	if languages := nonBlank(f.Languages); len(languages) > 0 {
+		for i := range languages {
+			languages[i] = strings.ToLower(languages[i])
+		}
+		clauses = append(clauses, bson.M{"language": bson.M{"$in": languages}})
+	}
+
+	year := bson.M{}
+	if f.YearFrom > 0 {
+		year["$gte"] = f.YearFrom
+	}
+	if f.YearTo > 0 {
+		year["$lte"] = f.YearTo
+	}
+	if len(year) > 0 {
+		clauses = append(clauses, bson.M{"publicationYear": year})
+	}
+
+	return bson.M{"$and": clauses}
+}
+
+// Exclusions are sources a tenant never searches: documents by source URI, and authors
+// by whole name, ignoring case.
+type Exclusions struct {
+	Documents []string
+	Authors   []string
+}
+
+func (e Exclusions) IsZero() bool {
+	return len(nonBlank(e.Documents)) == 0 && len(nonBlank(e.Authors)) == 0
+}
+
+// Filter matches the chunks that are not from an excluded source. It must not be used
+// when IsZero, since $nor takes at least one clause.
+func (e Exclusions) Filter() bson.M {
+	var excluded bson.A
+	if documents := nonBlank(e.Documents); len(documents) > 0 {
+		excluded = append(excluded, bson.M{"sourceUri": bson.M{"$in": documents}})
+	}
+	for _, author := range nonBlank(e.Authors) {
+		excluded = append(excluded, bson.M{"author": bson.M{"$regex": bson.Regex{Pattern: "^" + regexp.QuoteMeta(author) + "$", Options: "i"}}})
+	}
+	return bson.M{"$nor": excluded}
+}
+
+// anyContains matches documents whose field contains any of values, ignoring case.
+func anyContains(field string, values []string) (bson.M, bool) {
+	values = nonBlank(values)
+	if len(values) == 0 {
+		return nil, false
+	}
+
+	alternatives := make(bson.A, 0, len(values))
+	for _, value := range values {
+		alternatives = append(alternatives, bson.M{field: bson.M{"$regex": bson.Regex{Pattern: regexp.QuoteMeta(value), Options: "i"}}})
+	}
+	return bson.M{"$or": alternatives}, true
+}
+
+func nonBlank(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			out = append(out, value)
+		}
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+package mcp
+
+import (
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+)
+
+// SearchLimits bound how much a search ranks and sends. Zero fields take the defaults.
+type SearchLimits struct {
+	TopK     int     // sections sent when the page sets no limit; zero for all that were ranked
+	Depth    int     // chunks ranked for the first page; maxChunks by default
+	MaxDepth int     // most chunks ranked for any page; maxSearchDepth by default
+	MinScore float64 // least relevance a section is sent with; see SearchPage.MinScore
+}
+
+// DefaultSearchLimits rank the top maxChunks chunks and send all their sections.
+var DefaultSearchLimits = SearchLimits{Depth: maxChunks, MaxDepth: maxSearchDepth}
+
+// WithSearchLimits sets how deep the search ranks and which sections it sends. A page's
+// limit and minimum score apply within them: no page ranks more than MaxDepth chunks,
+// and a page can raise the minimum score but not lower it.
+func WithSearchLimits(limits SearchLimits) SearchToolOption {
+	return func(s *SearchTool) {
+		limits.TopK = max(limits.TopK, 0)
+		if limits.Depth <= 0 {
+			limits.Depth = DefaultSearchLimits.Depth
+		}
+		if limits.MaxDepth <= 0 {
+			limits.MaxDepth = DefaultSearchLimits.MaxDepth
+		}
+		limits.MaxDepth = max(limits.MaxDepth, limits.Depth)
+		limits.MinScore = min(max(limits.MinScore, 0), 1)
+		s.limits = limits
+	}
+}
+
+// page applies the limits to the page a caller asked for.
+func (l SearchLimits) page(p SearchPage) SearchPage {
+	p.Offset = max(p.Offset, 0)
+	if p.Limit <= 0 {
+		p.Limit = l.TopK
+	}
+	p.MinScore = min(max(p.MinScore, l.MinScore), 1)
+	return p
+}
+
+// depth is how many chunks are ranked for p: twice as many as the page reaches, which
+// leaves room for sections made of several ranked windows.
+func (l SearchLimits) depth(p SearchPage) int {
+	return min(max(l.Depth, 2*(max(p.Offset, 0)+max(p.Limit, 0))), l.MaxDepth)
+}
+
+// relevant keeps the sections whose best window scores at least minScore of the most a
+// chunk could have scored in ranked.
+func relevant(sections [][]*db.ChunkModel, ranked rankedChunks, minScore float64) [][]*db.ChunkModel {
+	if minScore <= 0 || ranked.ceiling <= 0 {
+		return sections
+	}
+
+	kept := sections[:0:0]
+	for _, section := range sections {
+		var best float64
+		for _, chunk := range section {
+			best = max(best, ranked.scores[chunk.ChunkID])
+		}
+		if best >= minScore*ranked.ceiling {
+			kept = append(kept, section)
+		}
+	}
+	return kept
+}
+
+// scoreCeiling is the fused score of a chunk every engine of weights ranked first.
+func scoreCeiling(weights FusionWeights) float64 {
+	return (weights.Text + weights.Vector) / float64(rrfK+1)
+}
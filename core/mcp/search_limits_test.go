@@ -0,0 +1,43 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchLimits(t *testing.T) {
+	var chunks []db.ChunkModel
+	for i := range 30 {
+		id := fmt.Sprintf("remedy-%02d", i)
+		chunks = append(chunks, db.ChunkModel{ChunkID: id, SectionID: id, Title: id, Sentences: []string{"Fear of death " + strings.Repeat("fear ", 30-i)}})
+	}
+	chunkRepository, vectorRepository := odmtest.NewCollection(chunks...), odmtest.NewCollection[db.ChunkAnnModel]()
+
+	search := func(limits SearchLimits, page SearchPage) (ids []string) {
+		searchTool := NewSearchTool(chunkRepository, vectorRepository, nil, WithLexicalOnly(), WithSearchLimits(limits))
+		for result := range searchTool.Run(t.Context(), "fear of death", SearchFilter{}, page) {
+			require.Empty(t, result.Error)
+			ids = append(ids, result.Id)
+		}
+		return ids
+	}
+
+	assert.Len(t, search(SearchLimits{}, SearchPage{}), maxChunks, "zero limits are the defaults")
+	assert.Len(t, search(SearchLimits{Depth: 25}, SearchPage{}), 25)
+	assert.Len(t, search(SearchLimits{TopK: 5}, SearchPage{}), 5)
+	assert.Len(t, search(SearchLimits{TopK: 5}, SearchPage{Limit: 8}), 8, "a page sets its own limit")
+	assert.Len(t, search(SearchLimits{MaxDepth: 20}, SearchPage{Offset: 20}), 0, "no page ranks beyond the maximum depth")
+
+	// a text-only hit of rank r scores 61/(60+r) of the most it could
+	assert.Equal(t, []string{"remedy-00", "remedy-01", "remedy-02", "remedy-03", "remedy-04", "remedy-05", "remedy-06"},
+		search(SearchLimits{MinScore: 0.9}, SearchPage{}))
+	assert.Len(t, search(SearchLimits{}, SearchPage{MinScore: 0.9}), 7)
+	assert.Len(t, search(SearchLimits{MinScore: 0.9}, SearchPage{MinScore: 0.5}), 7, "a page can't lower the minimum score")
+	assert.Len(t, search(SearchLimits{MinScore: 0.5}, SearchPage{MinScore: 0.9}), 7, "but can raise it")
+}
@@ -1,15 +1,24 @@
 package mcp
 
 import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/SaiNageswarS/agent-boot/schema"
 	"github.com/SaiNageswarS/go-api-boot/dotenv"
 	"github.com/SaiNageswarS/go-api-boot/embed"
 	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
 	"github.com/SaiNageswarS/go-collection-boot/linq"
 	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 func TestSearch(t *testing.T) {
@@ -30,7 +39,7 @@ func TestSearch(t *testing.T) {
 		expectedChunkPrefixes := []string{"1544328200c1", "9a24dcec7d80"}
 
 		searchTool := NewSearchTool(chunkRepository, vectorRepository, embedder)
-		resultsChan := searchTool.Run(ctx, testQuery)
+		resultsChan := searchTool.Run(ctx, testQuery, SearchFilter{}, SearchPage{})
 
 		// Collect all results from the channel
 		var searchResults []*schema.ToolResultChunk
@@ -61,3 +70,269 @@ func TestSearch(t *testing.T) {
 		}
 	})
 }
+
+func TestSearchInMemory(t *testing.T) {
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "aconite", SectionID: "aconite", Title: "Aconite", Sentences: []string{"Great fear and anxiety; predicts the day of death."}},
+		db.ChunkModel{ChunkID: "bryonia", SectionID: "bryonia", Title: "Bryonia", Sentences: []string{"Stitching pains worse by motion."}},
+		db.ChunkModel{ChunkID: "retired", SectionID: "retired", Title: "Aconite (old)", Sentences: []string{"Fear of death."}, RetiredVersion: 2},
+	)
+	vectorRepository := odmtest.NewCollection(
+		db.ChunkAnnModel{ChunkID: "aconite", Embedding: bson.NewVector([]float32{1, 0})},
+		db.ChunkAnnModel{ChunkID: "bryonia", Embedding: bson.NewVector([]float32{0, 1})},
+		db.ChunkAnnModel{ChunkID: "retired", Embedding: bson.NewVector([]float32{1, 0}), Retired: true},
+	)
+
+	searchTool := NewSearchTool(chunkRepository, vectorRepository, fixedEmbedder{1, 0})
+
+	var ids []string
+	for result := range searchTool.Run(t.Context(), "fear of death", SearchFilter{}, SearchPage{}) {
+		assert.Empty(t, result.Error)
+		ids = append(ids, result.Id)
+	}
+
+	assert.Contains(t, ids, "aconite")
+	assert.NotContains(t, ids, "retired")
+}
+
+func TestFuse(t *testing.T) {
+	textRanks := map[string]int{"aconite": 1, "bryonia": 2}
+	vecRanks := map[string]int{"bryonia": 1, "gelsemium": 2}
+
+	equal := fuse(DefaultFusionWeights, textRanks, vecRanks)
+	assert.Greater(t, equal["bryonia"], equal["aconite"], "found by both engines")
+	assert.InDelta(t, 1.0/61+1.0/62, equal["bryonia"], 1e-9)
+
+	textOnly := fuse(FusionWeights{Text: 1}, textRanks, vecRanks)
+	assert.NotContains(t, textOnly, "gelsemium", "an engine weighted zero does not vote")
+
+	vectorHeavy := fuse(FusionWeights{Text: 1, Vector: 3}, textRanks, vecRanks)
+	assert.Greater(t, vectorHeavy["gelsemium"], vectorHeavy["aconite"])
+}
+
+func TestSearchFusedScore(t *testing.T) {
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "aconite", SectionID: "aconite", Title: "Aconite", Sentences: []string{"Great fear and anxiety; predicts the day of death."}},
+		db.ChunkModel{ChunkID: "bryonia", SectionID: "bryonia", Title: "Bryonia", Sentences: []string{"Stitching pains worse by motion."}},
+	)
+	vectorRepository := odmtest.NewCollection(
+		db.ChunkAnnModel{ChunkID: "aconite", Embedding: bson.NewVector([]float32{1, 0})},
+		db.ChunkAnnModel{ChunkID: "bryonia", Embedding: bson.NewVector([]float32{0, 1})},
+	)
+
+	scores := func(weights FusionWeights) map[string]float64 {
+		searchTool := NewSearchTool(chunkRepository, vectorRepository, fixedEmbedder{0, 1}, WithFusionWeights(weights))
+		scores := make(map[string]float64)
+		for result := range searchTool.Run(t.Context(), "fear of death", SearchFilter{}, SearchPage{}) {
+			require.Empty(t, result.Error)
+			score, err := strconv.ParseFloat(result.Metadata["fusedScore"], 64)
+			require.NoError(t, err)
+			scores[result.Id] = score
+		}
+		return scores
+	}
+
+	vectorOnly := scores(FusionWeights{Vector: 1})
+	assert.InDelta(t, 1.0/61, vectorOnly["bryonia"], 1e-4, "the nearest vector ranks first")
+
+	textOnly := scores(FusionWeights{Text: 1, Vector: -1})
+	assert.InDelta(t, 1.0/61, textOnly["aconite"], 1e-4, "a negative weight counts as zero")
+	assert.NotContains(t, textOnly, "bryonia", "the query was not embedded")
+}
+
+func TestSearchFiltered(t *testing.T) {
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "boericke", SectionID: "boericke", Title: "Aconite", SectionPath: "Materia Medica | Aconitum Napellus",
+			Book: "Pocket Manual of Homoeopathic Materia Medica", Author: "William Boericke", PublicationYear: 1901,
+			Sentences: []string{"Great fear and anxiety; predicts the day of death."}},
+		db.ChunkModel{ChunkID: "kent", SectionID: "kent", Title: "Aconite", SectionPath: "Lectures | Aconite",
+			Book: "Lectures on Homoeopathic Materia Medica", Author: "James Tyler Kent", PublicationYear: 1905, Language: "en",
+			Sentences: []string{"Fear of death, anxiety of mind."}},
+		db.ChunkModel{ChunkID: "undated", SectionID: "undated", Title: "Aconite", Language: "de", Sentences: []string{"Fear of death."}},
+	)
+	// every chunk is a vector hit, so the filter has to drop some of them too
+	vectorRepository := odmtest.NewCollection(
+		db.ChunkAnnModel{ChunkID: "boericke", Embedding: bson.NewVector([]float32{1, 0})},
+		db.ChunkAnnModel{ChunkID: "kent", Embedding: bson.NewVector([]float32{1, 0})},
+		db.ChunkAnnModel{ChunkID: "undated", Embedding: bson.NewVector([]float32{1, 0})},
+	)
+
+	search := func(filter SearchFilter, opts ...SearchToolOption) []string {
+		searchTool := NewSearchTool(chunkRepository, vectorRepository, fixedEmbedder{1, 0}, opts...)
+		var ids []string
+		for result := range searchTool.Run(t.Context(), "fear of death", filter, SearchPage{}) {
+			require.Empty(t, result.Error)
+			ids = append(ids, result.Id)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+
+	assert.Equal(t, []string{"boericke", "kent", "undated"}, search(SearchFilter{}))
+	assert.Equal(t, []string{"kent"}, search(SearchFilter{Authors: []string{"kent"}}), "authors match ignoring case")
+	assert.Equal(t, []string{"boericke", "kent"}, search(SearchFilter{Books: []string{"Pocket Manual", "Lectures"}}), "any book matches")
+	assert.Equal(t, []string{"boericke"}, search(SearchFilter{Chapters: []string{"aconitum"}}))
+	assert.Equal(t, []string{"boericke"}, search(SearchFilter{YearTo: 1902}), "undated chunks are left out")
+	assert.Empty(t, search(SearchFilter{Authors: []string{"Kent"}, YearTo: 1902}), "every field must match")
+	assert.Equal(t, []string{"kent"}, search(SearchFilter{YearFrom: 1903}, WithFusionWeights(FusionWeights{Vector: 1})), "vector hits are filtered")
+	assert.Equal(t, []string{"undated"}, search(SearchFilter{Languages: []string{"DE"}}))
+	assert.Equal(t, []string{"kent", "undated"}, search(SearchFilter{Languages: []string{"en", "de"}}), "chunks without a language are left out")
+}
+
+func TestSearchExclusions(t *testing.T) {
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "boericke", SectionID: "boericke", Title: "Aconite", SourceURI: "file://boericke.pdf", Author: "William Boericke",
+			Sentences: []string{"Great fear and anxiety; predicts the day of death."}},
+		db.ChunkModel{ChunkID: "kent", SectionID: "kent", Title: "Aconite", SourceURI: "file://kent.pdf", Author: "James Tyler Kent",
+			Sentences: []string{"Fear of death, anxiety of mind."}},
+		db.ChunkModel{ChunkID: "kent-repertory", SectionID: "kent-repertory", Title: "Mind", SourceURI: "file://repertory.pdf", Author: "James Tyler Kent Jr",
+			Sentences: []string{"Fear of death: Acon., Ars."}},
+	)
+	vectorRepository := odmtest.NewCollection(
+		db.ChunkAnnModel{ChunkID: "boericke", Embedding: bson.NewVector([]float32{1, 0})},
+		db.ChunkAnnModel{ChunkID: "kent", Embedding: bson.NewVector([]float32{1, 0})},
+		db.ChunkAnnModel{ChunkID: "kent-repertory", Embedding: bson.NewVector([]float32{1, 0})},
+	)
+
+	search := func(exclusions Exclusions, opts ...SearchToolOption) []string {
+		searchTool := NewSearchTool(chunkRepository, vectorRepository, fixedEmbedder{1, 0}, append(opts, WithExclusions(exclusions))...)
+		var ids []string
+		for result := range searchTool.Run(t.Context(), "fear of death", SearchFilter{}, SearchPage{}) {
+			require.Empty(t, result.Error)
+			ids = append(ids, result.Id)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+
+	assert.Equal(t, []string{"boericke", "kent", "kent-repertory"}, search(Exclusions{}))
+	assert.Equal(t, []string{"kent", "kent-repertory"}, search(Exclusions{Documents: []string{"file://boericke.pdf"}}))
+	assert.Equal(t, []string{"boericke", "kent-repertory"}, search(Exclusions{Authors: []string{"james tyler kent"}}), "authors match whole names ignoring case")
+	assert.Equal(t, []string{"kent-repertory"}, search(Exclusions{Documents: []string{"file://boericke.pdf"}, Authors: []string{"James Tyler Kent"}}))
+	assert.Equal(t, []string{"boericke"}, search(Exclusions{Documents: []string{"file://repertory.pdf"}, Authors: []string{"James Tyler Kent"}},
+		WithFusionWeights(FusionWeights{Vector: 1})), "vector hits are excluded")
+	assert.Equal(t, []string{"kent", "kent-repertory"}, search(Exclusions{Documents: []string{"file://boericke.pdf"}},
+		WithFusionWeights(FusionWeights{Text: 1}), WithLexicalOnly()), "text hits are excluded")
+}
+
+func TestSearchAccessGroups(t *testing.T) {
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "boericke", SectionID: "boericke", Title: "Aconite", Sentences: []string{"Great fear and anxiety; predicts the day of death."}},
+		db.ChunkModel{ChunkID: "notes", SectionID: "notes", Title: "Aconite", AccessGroups: []string{"practice-internal"},
+			Sentences: []string{"Fear of death after the accident; Aconite 200C helped."}},
+		db.ChunkModel{ChunkID: "trial", SectionID: "trial", Title: "Aconite", AccessGroups: []string{"research"},
+			Sentences: []string{"Fear of death in two of the provers."}},
+	)
+	vectorRepository := odmtest.NewCollection(
+		db.ChunkAnnModel{ChunkID: "boericke", Embedding: bson.NewVector([]float32{1, 0})},
+		db.ChunkAnnModel{ChunkID: "notes", Embedding: bson.NewVector([]float32{1, 0})},
+		db.ChunkAnnModel{ChunkID: "trial", Embedding: bson.NewVector([]float32{1, 0})},
+	)
+
+	search := func(opts ...SearchToolOption) []string {
+		searchTool := NewSearchTool(chunkRepository, vectorRepository, fixedEmbedder{1, 0}, opts...)
+		var ids []string
+		for result := range searchTool.Run(t.Context(), "fear of death", SearchFilter{}, SearchPage{}) {
+			require.Empty(t, result.Error)
+			ids = append(ids, result.Id)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+
+	assert.Equal(t, []string{"boericke", "notes", "trial"}, search(), "without groups every document is searched")
+	assert.Equal(t, []string{"boericke"}, search(WithAccessGroups(nil)), "a user in no group reads untagged documents")
+	assert.Equal(t, []string{"boericke", "notes"}, search(WithAccessGroups([]string{"practice-internal"})))
+	assert.Equal(t, []string{"boericke", "trial"}, search(WithAccessGroups([]string{"research"}), WithFusionWeights(FusionWeights{Vector: 1})),
+		"vector hits are filtered")
+	assert.Equal(t, []string{"boericke", "notes"}, search(WithAccessGroups([]string{"practice-internal"}), WithLexicalOnly()), "text hits are filtered")
+}
+
+func TestSearchPaged(t *testing.T) {
+	// more sections than the first page ranks
+	var chunks []db.ChunkModel
+	for i := range 30 {
+		id := fmt.Sprintf("remedy-%02d", i)
+		chunks = append(chunks, db.ChunkModel{ChunkID: id, SectionID: id, Title: id, Sentences: []string{"Fear of death " + strings.Repeat("fear ", 30-i)}})
+	}
+	searchTool := NewSearchTool(odmtest.NewCollection(chunks...), odmtest.NewCollection[db.ChunkAnnModel](), nil, WithLexicalOnly())
+
+	search := func(page SearchPage) (ids, ranks []string) {
+		for result := range searchTool.Run(t.Context(), "fear of death", SearchFilter{}, page) {
+			require.Empty(t, result.Error)
+			ids = append(ids, result.Id)
+			ranks = append(ranks, result.Metadata["rank"])
+		}
+		return ids, ranks
+	}
+
+	first, _ := search(SearchPage{})
+	assert.Len(t, first, maxChunks)
+
+	ids, ranks := search(SearchPage{Limit: 3})
+	assert.Equal(t, []string{"remedy-00", "remedy-01", "remedy-02"}, ids)
+	assert.Equal(t, []string{"1", "2", "3"}, ranks)
+
+	ids, ranks = search(SearchPage{Offset: 3, Limit: 3})
+	assert.Equal(t, []string{"remedy-03", "remedy-04", "remedy-05"}, ids, "the next page starts where the last ended")
+	assert.Equal(t, []string{"4", "5", "6"}, ranks)
+
+	rest, _ := search(SearchPage{Offset: maxChunks})
+	assert.Len(t, rest, 30-maxChunks, "later pages rank more chunks")
+	assert.NotContains(t, rest, first[len(first)-1])
+}
+
+func TestSearchFilterIsZero(t *testing.T) {
+	assert.True(t, SearchFilter{}.IsZero())
+	assert.True(t, SearchFilter{Books: []string{" "}}.IsZero(), "blank names are ignored")
+	assert.False(t, SearchFilter{YearFrom: 1900}.IsZero())
+	assert.Equal(t, db.LiveChunksFilter(), SearchFilter{}.bson())
+
+	assert.True(t, Exclusions{Authors: []string{""}}.IsZero())
+}
+
+type fixedEmbedder []float32
+
+func (e fixedEmbedder) GetEmbedding(ctx context.Context, text string, opts ...embed.EmbedOption) <-chan async.Result[[]float32] {
+	return async.Go(func() ([]float32, error) { return e, nil })
+}
+
+func TestLexicalConfident(t *testing.T) {
+	hit := func(score float64, sentences ...string) odm.SearchHit[db.ChunkModel] {
+		return odm.SearchHit[db.ChunkModel]{Score: score, Doc: db.ChunkModel{Sentences: sentences}}
+	}
+
+	t.Run("ClearLeaderWithFullCoverage", func(t *testing.T) {
+		hits := []odm.SearchHit[db.ChunkModel]{
+			hit(12, "Aconite for sudden fear of death with anxiety."),
+			hit(8, "Arsenicum album anxiety at night."),
+			hit(5, "Gelsemium anticipatory anxiety."),
+		}
+		assert.True(t, lexicalConfident("aconite fear of death anxiety", hits))
+	})
+
+	t.Run("TooFewHits", func(t *testing.T) {
+		hits := []odm.SearchHit[db.ChunkModel]{
+			hit(12, "Aconite for sudden fear of death."),
+		}
+		assert.False(t, lexicalConfident("aconite fear", hits))
+	})
+
+	t.Run("LowTermCoverage", func(t *testing.T) {
+		hits := []odm.SearchHit[db.ChunkModel]{
+			hit(12, "Aconite for sudden fright."),
+			hit(8, "Arsenicum album restlessness."),
+			hit(5, "Gelsemium trembling."),
+		}
+		assert.False(t, lexicalConfident("aconite fear of death anxiety", hits))
+	})
+
+	t.Run("NoClearLeader", func(t *testing.T) {
+		hits := []odm.SearchHit[db.ChunkModel]{
+			hit(10, "Aconite fear of death anxiety."),
+			hit(9.8, "Arsenicum fear of death anxiety."),
+			hit(9.5, "Gelsemium fear anxiety."),
+		}
+		assert.False(t, lexicalConfident("fear of death anxiety", hits))
+	})
+}
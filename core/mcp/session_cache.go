@@ -0,0 +1,255 @@
+package mcp
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// session cache parameters.
+const (
+	defaultSessionCacheSimilarity = 0.95
+	defaultSessionCacheSessions   = 1000
+	maxSessionSearches            = 50 // searches remembered per session, the oldest dropped first
+)
+
+// SessionCache remembers the results of a conversation's searches, so when the agent
+// searches for much the same thing again, on a later iteration or a follow-up question,
+// the results are replayed instead of searching Mongo again. Searches match when the
+// cosine similarity of their query embeddings reaches the threshold and their filter,
+// page and corpus version are the same. A session's searches are forgotten ttl after
+// its last search, and the least recently used sessions beyond capacity are dropped.
+type SessionCache struct {
+	capacity   int
+	ttl        time.Duration
+	similarity float64
+
+	mu       sync.Mutex
+	sessions map[sessionKey]*list.Element
+	order    *list.List // front is most recently used
+}
+
+type sessionKey struct {
+	tenant  string
+	session string
+}
+
+type sessionSearches struct {
+	key      sessionKey
+	usedAt   time.Time
+	searches []cachedSearch // oldest first
+}
+
+type cachedSearch struct {
+	scope     string // see sessionScope
+	embedding []float32
+	results   []*schema.ToolResultChunk
+}
+
+func ProvideSessionCache(ccfg *appconfig.AppConfig) *SessionCache {
+	return NewSessionCache(ccfg.SessionCacheSessions, time.Duration(ccfg.SessionCacheTTLMinutes)*time.Minute, ccfg.SessionCacheSimilarity)
+}
+
+// NewSessionCache holds the searches of up to capacity sessions, by default 1000, for
+// ttl after each session's last search. similarity defaults to 0.95. A zero ttl
+// disables the cache.
+func NewSessionCache(capacity int, ttl time.Duration, similarity float64) *SessionCache {
+	if capacity <= 0 {
+		capacity = defaultSessionCacheSessions
+	}
+	if similarity <= 0 || similarity > 1 {
+		similarity = defaultSessionCacheSimilarity
+	}
+	return &SessionCache{
+		capacity:   capacity,
+		ttl:        ttl,
+		similarity: similarity,
+		sessions:   make(map[sessionKey]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// WithSessionCache replays the results of an earlier search of the session for a query
+// similar enough, and remembers complete results for later ones. corpusVersion keeps
+// results from before the corpus changed from being replayed. Replayed results carry a
+// sessionCached metadata entry of "true". Searches whose query can't be embedded, such
+// as those of offline tenants, and diagnostic searches are not cached.
+func WithSessionCache(cache *SessionCache, tenant, session string, corpusVersion int64) SearchToolOption {
+	return func(s *SearchTool) {
+		if cache != nil && cache.ttl > 0 && session != "" {
+			s.sessionCache = &sessionSearchCache{cache: cache, key: sessionKey{tenant: tenant, session: session}, corpusVersion: corpusVersion}
+		}
+	}
+}
+
+// sessionSearchCache is the cache of one session's searches.
+type sessionSearchCache struct {
+	cache         *SessionCache
+	key           sessionKey
+	corpusVersion int64
+}
+
+// sessionScope is what must be the same for a search's results to be replayed, besides
+// a similar query: the corpus version, the tenant's search settings, the query's
+// operators, the filter and the page.
+func (s *SearchTool) sessionScope(parsed Query, filter SearchFilter, page SearchPage) string {
+	packs := make([]string, len(s.packs))
+	for i, pack := range s.packs {
+		packs[i] = pack.Name
+	}
+	return fmt.Sprintf("%d|%v|%v|%v|%q|%q|%q|%t|%t|%t|%v|%q|%q|%q|%d|%d|%v", s.sessionCache.corpusVersion, s.weights, s.limits, s.sourceBoost, s.exclusions.Documents,
+		s.exclusions.Authors, packs, s.hyde != nil, s.decompose != nil, s.facets, parsed.bson(), filter.Books, filter.Authors, filter.Chapters,
+		filter.YearFrom, filter.YearTo, page)
+}
+
+// runCached replays the session's results for a similar query, or runs the search and
+// remembers its results when they are complete.
+func (s *SearchTool) runCached(ctx context.Context, query string, filter SearchFilter, page SearchPage) <-chan *schema.ToolResultChunk {
+	out := make(chan *schema.ToolResultChunk, 20)
+
+	// the search embeds the same text, which is then embedded only once
+	t := *s
+	t.embedder = &sharedEmbedder{embedder: s.embedder, results: make(map[string]*sharedEmbedding)}
+
+	go func() {
+		defer close(out)
+
+		corrected, _ := t.correct(query)
+		parsed := ParseQuery(corrected)
+		scope := s.sessionScope(parsed, filter, page)
+		embedding, err := t.embedQuery(ctx, parsed.Text())
+		if err != nil {
+			logger.Error("Failed to embed query for the session cache", zap.Error(err))
+		} else if results, ok := s.sessionCache.cache.lookup(s.sessionCache.key, scope, embedding); ok {
+			logger.Info("Replaying session search results", zap.String("session", s.sessionCache.key.session), zap.String("query", query))
+			marks := s.highlighter(corrected) // for this query's words, not the cached one's
+			for _, result := range results {
+				result.Metadata["sessionCached"] = "true"
+				delete(result.Metadata, "highlights")
+				if highlights := marks.highlights(result.Sentences); len(highlights) > 0 {
+					result.Metadata["highlights"] = highlightsJSON(highlights)
+				}
+				out <- result
+			}
+			return
+		}
+
+		var results []*schema.ToolResultChunk
+		complete := err == nil
+		for result := range t.run(ctx, query, filter, page) {
+			if result.Error != "" || result.Metadata["partial"] == "true" {
+				complete = false
+			}
+			results = append(results, cloneResult(result))
+			out <- result
+		}
+		if complete && len(results) > 0 && ctx.Err() == nil {
+			s.sessionCache.cache.store(s.sessionCache.key, cachedSearch{scope: scope, embedding: embedding, results: results})
+		}
+	}()
+
+	return out
+}
+
+// lookup returns copies of the results of the session's most similar search in scope,
+// if any is similar enough.
+func (c *SessionCache) lookup(key sessionKey, scope string, embedding []float32) ([]*schema.ToolResultChunk, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.sessions[key]
+	if !ok {
+		return nil, false
+	}
+	session := element.Value.(*sessionSearches)
+	if time.Since(session.usedAt) > c.ttl {
+		c.order.Remove(element)
+		delete(c.sessions, key)
+		return nil, false
+	}
+
+	best, bestSimilarity := -1, c.similarity
+	for i, search := range session.searches {
+		if search.scope != scope {
+			continue
+		}
+		if similarity := cosine(search.embedding, embedding); similarity >= bestSimilarity {
+			best, bestSimilarity = i, similarity
+		}
+	}
+	if best < 0 {
+		return nil, false
+	}
+
+	session.usedAt = time.Now()
+	c.order.MoveToFront(element)
+	results := make([]*schema.ToolResultChunk, len(session.searches[best].results))
+	for i, result := range session.searches[best].results {
+		results[i] = cloneResult(result)
+	}
+	return results, true
+}
+
+func (c *SessionCache) store(key sessionKey, search cachedSearch) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.sessions[key]
+	if !ok || time.Since(element.Value.(*sessionSearches).usedAt) > c.ttl {
+		if ok {
+			c.order.Remove(element)
+		}
+		element = c.order.PushFront(&sessionSearches{key: key})
+		c.sessions[key] = element
+	}
+	session := element.Value.(*sessionSearches)
+	session.usedAt = time.Now()
+	session.searches = append(session.searches, search)
+	if len(session.searches) > maxSessionSearches {
+		session.searches = slices.Delete(session.searches, 0, len(session.searches)-maxSessionSearches)
+	}
+	c.order.MoveToFront(element)
+
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.sessions, oldest.Value.(*sessionSearches).key)
+	}
+}
+
+// cloneResult copies result, so a replayed result can be changed without changing the
+// cached one.
+func cloneResult(result *schema.ToolResultChunk) *schema.ToolResultChunk {
+	clone := proto.Clone(result).(*schema.ToolResultChunk)
+	if clone.Metadata == nil {
+		clone.Metadata = make(map[string]string)
+	}
+	return clone
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestSearchSessionCache(t *testing.T) {
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "aconite", SectionID: "aconite", Title: "Aconite", Author: "Boericke", Sentences: []string{"Sudden fear of death."}},
+	)
+	vectorRepository := odmtest.NewCollection(db.ChunkAnnModel{ChunkID: "aconite", Embedding: bson.NewVector([]float32{1, 0})})
+	embedder := textEmbedder{vectors: map[string][]float32{"thirst": {0, 1}}, fallback: []float32{1, 0}}
+	cache := NewSessionCache(10, time.Hour, 0.95)
+
+	// the ids of the sections found, and whether they were replayed
+	search := func(session string, corpusVersion int64, query string, filter SearchFilter) map[string]bool {
+		searchTool := NewSearchTool(chunkRepository, vectorRepository, embedder, WithSessionCache(cache, "tenant", session, corpusVersion))
+		results := make(map[string]bool)
+		for result := range searchTool.Run(t.Context(), query, filter, SearchPage{}) {
+			require.Empty(t, result.Error)
+			results[result.Id] = result.Metadata["sessionCached"] == "true"
+		}
+		return results
+	}
+
+	assert.Equal(t, map[string]bool{"aconite": false}, search("s1", 1, "fear of death", SearchFilter{}))
+
+	// a section added since isn't found by a replayed search
+	<-chunkRepository.Save(t.Context(), db.ChunkModel{ChunkID: "gelsemium", SectionID: "gelsemium", Title: "Gelsemium", Author: "Boericke", Sentences: []string{"Fear of death with trembling."}})
+	<-vectorRepository.Save(t.Context(), db.ChunkAnnModel{ChunkID: "gelsemium", Embedding: bson.NewVector([]float32{1, 0})})
+
+	assert.Equal(t, map[string]bool{"aconite": true}, search("s1", 1, "dying of fear", SearchFilter{}), "a similar query is replayed")
+	assert.Len(t, search("s1", 1, "thirst", SearchFilter{}), 2, "a dissimilar query is searched")
+	assert.Len(t, search("s2", 1, "fear of death", SearchFilter{}), 2, "sessions don't share searches")
+	assert.Len(t, search("s1", 2, "fear of death", SearchFilter{}), 2, "searches of an older corpus aren't replayed")
+	assert.Len(t, search("s1", 1, "fear of death", SearchFilter{Authors: []string{"Boericke"}}), 2, "nor those of another filter")
+
+	assert.Len(t, search("", 1, "fear of death", SearchFilter{}), 2, "searches outside a session aren't cached")
+	assert.Equal(t, map[string]bool{"aconite": false, "gelsemium": false}, search("s3", 1, "fear of death", SearchFilter{}))
+	assert.Equal(t, map[string]bool{"aconite": true, "gelsemium": true}, search("s3", 1, "fear of death", SearchFilter{}))
+}
+
+func TestSessionCacheEviction(t *testing.T) {
+	embedding := []float32{1, 0}
+	cache := NewSessionCache(2, time.Hour, 0)
+	for _, session := range []string{"s1", "s2", "s3"} {
+		cache.store(sessionKey{session: session}, cachedSearch{embedding: embedding})
+	}
+	_, ok := cache.lookup(sessionKey{session: "s1"}, "", embedding)
+	assert.False(t, ok, "the least recently used session is dropped")
+	_, ok = cache.lookup(sessionKey{session: "s3"}, "", embedding)
+	assert.True(t, ok)
+
+	expiring := NewSessionCache(10, time.Millisecond, 0)
+	expiring.store(sessionKey{session: "s1"}, cachedSearch{embedding: embedding})
+	time.Sleep(5 * time.Millisecond)
+	_, ok = expiring.lookup(sessionKey{session: "s1"}, "", embedding)
+	assert.False(t, ok, "a session is forgotten ttl after its last search")
+}
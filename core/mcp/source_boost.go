@@ -0,0 +1,102 @@
+package mcp
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+)
+
+// SourceBoost favours newer editions and the sources a tenant prefers.
+type SourceBoost struct {
+	// Recency is the boost of the chunks with the newest publication year among those
+	// ranked; older years get a share in proportion to their place between the oldest and
+	// newest. Chunks with no year get none.
+	Recency float64
+
+	// Preferred is the boost of chunks from the preferred documents, by source URI, or
+	// authors, by whole name ignoring case.
+	Preferred          float64
+	PreferredDocuments []string
+	PreferredAuthors   []string
+
+	// PreferredLanguage also gives the Preferred boost to chunks in this base language,
+	// such as "de"; see WithPreferredLanguage.
+	PreferredLanguage string
+}
+
+// WithSourceBoost raises the fused score of chunks from newer editions and preferred
+// sources by the sum of their boosts, and re-orders the chunks by the boosted score
+// before reranking. Each result whose best window was boosted carries the factor in its
+// sourceBoost metadata entry. Negative boosts count as zero.
+func WithSourceBoost(boost SourceBoost) SearchToolOption {
+	return func(s *SearchTool) {
+		boost.Recency, boost.Preferred = max(boost.Recency, 0), max(boost.Preferred, 0)
+		s.sourceBoost = boost
+	}
+}
+
+// WithPreferredLanguage gives chunks in language, such as the one the user writes in,
+// the preferred source boost, so passages the user can read rank above those in other
+// languages. It applies after WithSourceBoost only, which sets no language.
+func WithPreferredLanguage(language string) SearchToolOption {
+	return func(s *SearchTool) { s.sourceBoost.PreferredLanguage = strings.ToLower(strings.TrimSpace(language)) }
+}
+
+// boostSources boosts the chunks of ranked from newer and preferred sources.
+func (s *SearchTool) boostSources(ranked rankedChunks) rankedChunks {
+	boost := s.sourceBoost
+	if (boost.Recency == 0 && boost.Preferred == 0) || len(ranked.chunks) == 0 {
+		return ranked
+	}
+
+	oldest, newest := 0, 0
+	for _, chunk := range ranked.chunks {
+		if year := chunk.PublicationYear; year > 0 {
+			if oldest == 0 || year < oldest {
+				oldest = year
+			}
+			newest = max(newest, year)
+		}
+	}
+
+	boosted := ranked
+	boosted.scores = maps.Clone(ranked.scores)
+	boosted.chunks = slices.Clone(ranked.chunks)
+	boosted.sourceBoosts = make(map[string]float64)
+	for _, chunk := range boosted.chunks {
+		factor := 1.0
+		if newest > oldest && chunk.PublicationYear > 0 {
+			factor += boost.Recency * float64(chunk.PublicationYear-oldest) / float64(newest-oldest)
+		}
+		if boost.preferred(chunk) {
+			factor += boost.Preferred
+		}
+		if factor > 1 {
+			boosted.scores[chunk.ChunkID] *= factor
+			boosted.sourceBoosts[chunk.ChunkID] = factor
+		}
+	}
+	// ties keep their fused order
+	slices.SortStableFunc(boosted.chunks, func(a, b *db.ChunkModel) int {
+		return cmp.Compare(boosted.scores[b.ChunkID], boosted.scores[a.ChunkID])
+	})
+	return boosted
+}
+
+func (b SourceBoost) preferred(chunk *db.ChunkModel) bool {
+	if b.Preferred == 0 {
+		return false
+	}
+	if chunk.SourceURI != "" && slices.Contains(b.PreferredDocuments, chunk.SourceURI) {
+		return true
+	}
+	if chunk.Language != "" && chunk.Language == b.PreferredLanguage {
+		return true
+	}
+	return chunk.Author != "" && slices.ContainsFunc(b.PreferredAuthors, func(author string) bool {
+		return strings.EqualFold(strings.TrimSpace(author), chunk.Author)
+	})
+}
@@ -0,0 +1,67 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoostSources(t *testing.T) {
+	ranked := rankedChunks{
+		chunks: []*db.ChunkModel{
+			{ChunkID: "1901", PublicationYear: 1901, Author: "William Boericke"},
+			{ChunkID: "undated", SourceURI: "file://notes.md"},
+			{ChunkID: "1927", PublicationYear: 1927, Author: "William Boericke"},
+			{ChunkID: "1914", PublicationYear: 1914, Author: "James Tyler Kent", Language: "de"},
+		},
+		scores: map[string]float64{"1901": 0.032, "undated": 0.031, "1927": 0.03, "1914": 0.029},
+	}
+	ids := func(ranked rankedChunks) []string {
+		var ids []string
+		for _, chunk := range ranked.chunks {
+			ids = append(ids, chunk.ChunkID)
+		}
+		return ids
+	}
+	boost := func(boost SourceBoost) rankedChunks {
+		return NewSearchTool(nil, nil, nil, WithSourceBoost(boost)).boostSources(ranked)
+	}
+
+	recent := boost(SourceBoost{Recency: 0.1})
+	assert.Equal(t, []string{"1927", "1901", "undated", "1914"}, ids(recent), "the newest edition leads")
+	assert.InDelta(t, 0.033, recent.scores["1927"], 1e-9)
+	assert.InDelta(t, 1.05, recent.sourceBoosts["1914"], 1e-9, "halfway between the oldest and newest")
+	assert.NotContains(t, recent.sourceBoosts, "1901", "the oldest isn't boosted")
+	assert.Equal(t, 0.03, ranked.scores["1927"], "the fused scores are kept")
+
+	preferred := boost(SourceBoost{Preferred: 0.2, PreferredDocuments: []string{"file://notes.md"}, PreferredAuthors: []string{"james tyler kent"}})
+	assert.Equal(t, []string{"undated", "1914", "1901", "1927"}, ids(preferred))
+	assert.Equal(t, map[string]float64{"undated": 1.2, "1914": 1.2}, preferred.sourceBoosts)
+
+	german := NewSearchTool(nil, nil, nil, WithSourceBoost(SourceBoost{Preferred: 0.2}), WithPreferredLanguage("DE")).boostSources(ranked)
+	assert.Equal(t, []string{"1914", "1901", "undated", "1927"}, ids(german))
+	assert.Equal(t, map[string]float64{"1914": 1.2}, german.sourceBoosts)
+
+	assert.Equal(t, ids(ranked), ids(boost(SourceBoost{PreferredAuthors: []string{"James Tyler Kent"}})), "boosting is off without a factor")
+}
+
+func TestSearchSourceBoostMetadata(t *testing.T) {
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "old", SectionID: "old", Title: "Aconite", PublicationYear: 1901, Sentences: []string{"Fear of death, fear."}},
+		db.ChunkModel{ChunkID: "new", SectionID: "new", Title: "Aconite", PublicationYear: 1927, Sentences: []string{"Fear of death."}},
+	)
+	searchTool := NewSearchTool(chunkRepository, odmtest.NewCollection[db.ChunkAnnModel](), nil, WithLexicalOnly(), WithSourceBoost(SourceBoost{Recency: 0.5}))
+
+	boosts := make(map[string]string)
+	var ids []string
+	for result := range searchTool.Run(t.Context(), "fear of death", SearchFilter{}, SearchPage{}) {
+		require.Empty(t, result.Error)
+		ids = append(ids, result.Id)
+		boosts[result.Id] = result.Metadata["sourceBoost"]
+	}
+	assert.Equal(t, []string{"new", "old"}, ids)
+	assert.Equal(t, map[string]string{"new": "1.5", "old": ""}, boosts)
+}
@@ -0,0 +1,233 @@
+package mcp
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// spelling correction parameters.
+const (
+	minVocabularyWordLength = 3     // shorter words are mostly stop words and abbreviations
+	minCorrectedWordLength  = 4     // shorter query words are too ambiguous to correct
+	longWordLength          = 6     // words this long may be two edits off
+	maxVocabularyChunks     = 50000 // chunks read to build a tenant's vocabulary
+)
+
+// Vocabulary is the set of words in a tenant's corpus, with how often each occurs,
+// that query words are corrected against.
+type Vocabulary struct {
+	counts   map[string]int
+	byLength map[int][]string // words by rune count, to skip words too long or short to be close
+}
+
+// NewVocabulary counts the words of texts.
+func NewVocabulary(texts ...string) *Vocabulary {
+	v := &Vocabulary{counts: make(map[string]int), byLength: make(map[int][]string)}
+	for _, text := range texts {
+		v.add(text)
+	}
+	return v
+}
+
+func (v *Vocabulary) add(text string) {
+	for _, word := range wordPattern.FindAllString(strings.ToLower(text), -1) {
+		n := utf8.RuneCountInString(word)
+		if n < minVocabularyWordLength {
+			continue
+		}
+		if v.counts[word] == 0 {
+			v.byLength[n] = append(v.byLength[n], word)
+		}
+		v.counts[word]++
+	}
+}
+
+var wordPattern = regexp.MustCompile(`\p{L}+`)
+
+// Correct replaces each query word the corpus doesn't contain with the closest word it
+// does, by edit distance with adjacent letters swapped counting as one edit. Words of
+// up to five letters may be one edit off and longer words two; ties go to the more
+// frequent word. Words with no close match are kept. corrected is false when nothing
+// changed.
+func (v *Vocabulary) Correct(query string) (string, bool) {
+	return v.correct(query, nil)
+}
+
+// correct is Correct, keeping the words synonyms knows, such as remedy abbreviations
+// the corpus may not use.
+func (v *Vocabulary) correct(query string, synonyms *SynonymDictionary) (string, bool) {
+	if v == nil || len(v.counts) == 0 {
+		return query, false
+	}
+
+	changed := false
+	result := wordPattern.ReplaceAllStringFunc(query, func(word string) string {
+		lower := strings.ToLower(word)
+		n := utf8.RuneCountInString(lower)
+		if n < minCorrectedWordLength || v.counts[lower] > 0 || synonyms.has(lower) {
+			return word
+		}
+
+		maxEdits := 1
+		if n >= longWordLength {
+			maxEdits = 2
+		}
+
+		best, bestDistance := "", maxEdits+1
+		for length := n - maxEdits; length <= n+maxEdits; length++ {
+			for _, candidate := range v.byLength[length] {
+				// one over the best so far, so an equal distance is exact and can break the tie
+				d := editDistance(lower, candidate, bestDistance+1)
+				if d < bestDistance || (d == bestDistance && best != "" && v.counts[candidate] > v.counts[best]) {
+					best, bestDistance = candidate, d
+				}
+			}
+		}
+		if best == "" {
+			return word
+		}
+
+		changed = true
+		return matchCase(word, best)
+	})
+	return result, changed
+}
+
+// editDistance is the optimal string alignment distance between a and b, or limit when
+// it is at least limit.
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) >= limit {
+		return limit
+	}
+
+	// three rows: two back for transpositions, the previous and the current
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin >= limit {
+			return limit
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return min(prev[len(rb)], limit)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// matchCase writes the correction in the case of the word it replaces: all upper case
+// or capitalized words stay so.
+func matchCase(original, correction string) string {
+	if strings.ToUpper(original) == original && utf8.RuneCountInString(original) > 1 {
+		return strings.ToUpper(correction)
+	}
+	if first, _ := utf8.DecodeRuneInString(original); unicode.IsUpper(first) {
+		r, size := utf8.DecodeRuneInString(correction)
+		return string(unicode.ToUpper(r)) + correction[size:]
+	}
+	return correction
+}
+
+// SpellingIndex builds each tenant's vocabulary from its live chunks, once per corpus
+// version. Chunks with access groups are left out, so corrections never reveal the
+// words of documents a user may not read. Building reads the whole corpus, so it runs in the background: until it is
+// done, queries go uncorrected or are corrected against the previous version's words.
+type SpellingIndex struct {
+	mu      sync.Mutex
+	tenants map[string]*tenantVocabulary
+}
+
+type tenantVocabulary struct {
+	version    int64
+	building   bool
+	vocabulary *Vocabulary
+}
+
+func ProvideSpellingIndex() *SpellingIndex {
+	return &SpellingIndex{tenants: make(map[string]*tenantVocabulary)}
+}
+
+// Vocabulary returns the tenant's latest built vocabulary, nil before the first one is
+// built, and starts building the vocabulary of version if it is newer.
+func (x *SpellingIndex) Vocabulary(ctx context.Context, tenant string, version int64, repo odm.OdmCollectionInterface[db.ChunkModel]) *Vocabulary {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	entry, ok := x.tenants[tenant]
+	if !ok {
+		entry = &tenantVocabulary{version: -1}
+		x.tenants[tenant] = entry
+	}
+
+	if entry.version != version && !entry.building {
+		entry.building = true
+		go x.build(context.WithoutCancel(ctx), tenant, version, repo, entry)
+	}
+	return entry.vocabulary
+}
+
+func (x *SpellingIndex) build(ctx context.Context, tenant string, version int64, repo odm.OdmCollectionInterface[db.ChunkModel], entry *tenantVocabulary) {
+	vocabulary, err := buildVocabulary(ctx, repo)
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	entry.building = false
+	if err != nil {
+		// the next query tries again
+		logger.Error("Failed to build spelling vocabulary", zap.String("tenant", tenant), zap.Error(err))
+		return
+	}
+	entry.version, entry.vocabulary = version, vocabulary
+	logger.Info("Built spelling vocabulary", zap.String("tenant", tenant), zap.Int64("corpusVersion", version), zap.Int("words", len(vocabulary.counts)))
+}
+
+func buildVocabulary(ctx context.Context, repo odm.OdmCollectionInterface[db.ChunkModel]) (*Vocabulary, error) {
+	chunks, err := async.Await(repo.Find(ctx, bson.M{"$and": bson.A{db.LiveChunksFilter(), db.AccessFilter(nil)}}, nil, maxVocabularyChunks, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	vocabulary := NewVocabulary()
+	for _, chunk := range chunks {
+		vocabulary.add(chunk.Title)
+		vocabulary.add(chunk.SectionPath)
+		for _, sentence := range chunk.Sentences {
+			vocabulary.add(sentence)
+		}
+	}
+	return vocabulary, nil
+}
@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorrect(t *testing.T) {
+	vocabulary := NewVocabulary(
+		"Belladonna: sudden high fever with anxiety and restlessness.",
+		"Anxiety at night; anxiety about health. Anxious restlessness.",
+	)
+
+	corrected, ok := vocabulary.Correct("Beladonna for anxeity at nihgt")
+	assert.True(t, ok)
+	assert.Equal(t, "Belladonna for anxiety at night", corrected, "case is kept and short words are left alone")
+
+	corrected, ok = vocabulary.Correct("ANXEITY")
+	assert.True(t, ok)
+	assert.Equal(t, "ANXIETY", corrected)
+
+	corrected, ok = vocabulary.Correct("restlessness with xylophone")
+	assert.False(t, ok, "known words and words with no close match are kept")
+	assert.Equal(t, "restlessness with xylophone", corrected)
+
+	var none *Vocabulary
+	corrected, ok = none.Correct("anxeity")
+	assert.False(t, ok)
+	assert.Equal(t, "anxeity", corrected)
+}
+
+func TestCorrectPrefersFrequentWords(t *testing.T) {
+	vocabulary := NewVocabulary("fever fever fever", "lever")
+	corrected, _ := vocabulary.Correct("hever")
+	assert.Equal(t, "fever", corrected)
+}
+
+func TestCorrectKeepsSynonyms(t *testing.T) {
+	vocabulary := NewVocabulary("Calf cramps at night.")
+	corrected, ok := vocabulary.correct("calc cramps", NewSynonymDictionary(builtinSynonyms...))
+	assert.False(t, ok, "remedy abbreviations are not corrected")
+	assert.Equal(t, "calc cramps", corrected)
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 1, editDistance("anxeity", "anxiety", 3), "a swap is one edit")
+	assert.Equal(t, 1, editDistance("beladonna", "belladonna", 3))
+	assert.Equal(t, 2, editDistance("fver", "fevers", 3))
+	assert.Equal(t, 3, editDistance("abc", "xyzxyz", 3), "capped at the limit")
+}
+
+func TestSpellingIndex(t *testing.T) {
+	repo := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "a", Title: "Belladonna", Sentences: []string{"Sudden fever."}},
+		db.ChunkModel{ChunkID: "b", Title: "Retired", Sentences: []string{"Gelsemium."}, RetiredVersion: 2},
+	)
+
+	index := ProvideSpellingIndex()
+	assert.Nil(t, index.Vocabulary(t.Context(), "tenant", 1, repo), "built in the background")
+
+	var vocabulary *Vocabulary
+	require.Eventually(t, func() bool {
+		vocabulary = index.Vocabulary(t.Context(), "tenant", 1, repo)
+		return vocabulary != nil
+	}, time.Second, 5*time.Millisecond)
+
+	corrected, _ := vocabulary.Correct("beladona fevr")
+	assert.Equal(t, "belladonna fever", corrected)
+	assert.Zero(t, vocabulary.counts["gelsemium"], "retired chunks are left out")
+}
+
+func TestSearchCorrectsSpelling(t *testing.T) {
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "belladonna", SectionID: "belladonna", Title: "Belladonna", Sentences: []string{"Sudden high fever, hot red face."}},
+	)
+	vocabulary := NewVocabulary("Belladonna", "Sudden high fever, hot red face.")
+	searchTool := NewSearchTool(chunkRepository, odmtest.NewCollection[db.ChunkAnnModel](), nil, WithLexicalOnly(), WithSpellingCorrection(vocabulary))
+
+	var results int
+	for result := range searchTool.Run(t.Context(), "beladonna", SearchFilter{}, SearchPage{}) {
+		require.Empty(t, result.Error)
+		assert.Equal(t, "belladonna", result.Metadata["correctedQuery"])
+		results++
+	}
+	assert.Equal(t, 1, results)
+}
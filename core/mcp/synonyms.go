@@ -0,0 +1,170 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/entities"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// query expansion parameters.
+const (
+	// how long a tenant's synonyms are served from memory before they are re-read
+	synonymReloadInterval = time.Minute
+
+	maxTenantSynonymGroups = 5000 // groups read per tenant
+	maxExpansionTerms      = 20   // synonyms appended to one query
+)
+
+// builtinSynonyms are the remedy names every tenant's queries are expanded with: the
+// Latin name, common names and the abbreviations repertories and older materia
+// medicas use.
+var builtinSynonyms = entities.RemedyNames
+
+// SynonymDictionary expands search queries with the synonyms of the terms they mention.
+type SynonymDictionary struct {
+	groups    [][]string
+	byKey     map[string][]int // synonym key → indexes of the groups with that term
+	maxLength int              // most words in a key
+}
+
+// NewSynonymDictionary indexes groups of interchangeable terms. A term in several groups
+// expands to the terms of all of them.
+func NewSynonymDictionary(groups ...[]string) *SynonymDictionary {
+	d := &SynonymDictionary{byKey: make(map[string][]int)}
+	for _, group := range groups {
+		index := len(d.groups)
+		added := false
+		for _, term := range group {
+			key := db.SynonymKey(term)
+			if key == "" {
+				continue
+			}
+			if n := len(d.byKey[key]); n == 0 || d.byKey[key][n-1] != index {
+				d.byKey[key] = append(d.byKey[key], index)
+			}
+			d.maxLength = max(d.maxLength, strings.Count(key, " ")+1)
+			added = true
+		}
+		if added {
+			d.groups = append(d.groups, group)
+		}
+	}
+	return d
+}
+
+// Expand appends to query the synonyms of each term it mentions, longest match first,
+// so "Arsenicum album" also finds "Ars." but "album" alone expands to nothing. Terms
+// the query already has are not repeated. A query that mentions no term is returned
+// as is.
+func (d *SynonymDictionary) Expand(query string) string {
+	if d == nil || len(d.byKey) == 0 {
+		return query
+	}
+
+	words := strings.Fields(db.SynonymKey(query))
+	mentioned := make(map[string]bool)
+	var matched []int
+	for i := 0; i < len(words); {
+		n := min(d.maxLength, len(words)-i)
+		for ; n > 0; n-- {
+			key := strings.Join(words[i:i+n], " ")
+			if groups, ok := d.byKey[key]; ok {
+				mentioned[key] = true
+				matched = append(matched, groups...)
+				break
+			}
+		}
+		i += max(n, 1)
+	}
+
+	var expansion []string
+	for _, index := range matched {
+		for _, term := range d.groups[index] {
+			key := db.SynonymKey(term)
+			if key == "" || mentioned[key] || len(expansion) == maxExpansionTerms {
+				continue
+			}
+			mentioned[key] = true
+			expansion = append(expansion, term)
+		}
+	}
+	if len(expansion) == 0 {
+		return query
+	}
+	return query + " " + strings.Join(expansion, " ")
+}
+
+// has reports whether key is one of the dictionary's terms.
+func (d *SynonymDictionary) has(key string) bool {
+	if d == nil {
+		return false
+	}
+	_, ok := d.byKey[key]
+	return ok
+}
+
+// SynonymIndex caches each tenant's synonym dictionary: the built-in remedy names and
+// the groups in the tenant's synonyms collection.
+type SynonymIndex struct {
+	mu      sync.Mutex
+	tenants map[string]*tenantSynonyms
+}
+
+type tenantSynonyms struct {
+	mu         sync.Mutex // held while loading
+	loadedAt   time.Time
+	dictionary *SynonymDictionary
+}
+
+func ProvideSynonymIndex() *SynonymIndex {
+	return &SynonymIndex{tenants: make(map[string]*tenantSynonyms)}
+}
+
+// Dictionary returns the tenant's dictionary, reading the tenant's groups again once
+// they are older than synonymReloadInterval. If a read fails the dictionary loaded
+// before keeps being served, or the built-in one if there is none.
+func (x *SynonymIndex) Dictionary(ctx context.Context, tenant string, repo odm.OdmCollectionInterface[db.SynonymModel]) *SynonymDictionary {
+	x.mu.Lock()
+	synonyms, ok := x.tenants[tenant]
+	if !ok {
+		synonyms = &tenantSynonyms{}
+		x.tenants[tenant] = synonyms
+	}
+	x.mu.Unlock()
+
+	synonyms.mu.Lock()
+	defer synonyms.mu.Unlock()
+
+	if time.Since(synonyms.loadedAt) < synonymReloadInterval {
+		return synonyms.dictionary
+	}
+
+	// a failed read is retried on the next interval rather than on every query
+	synonyms.loadedAt = time.Now()
+
+	docs, err := async.Await(repo.Find(ctx, bson.M{}, nil, maxTenantSynonymGroups, 0))
+	if err != nil {
+		logger.Error("Failed to load synonyms", zap.String("tenant", tenant), zap.Error(err))
+		if synonyms.dictionary == nil {
+			synonyms.dictionary = NewSynonymDictionary(builtinSynonyms...)
+		}
+		return synonyms.dictionary
+	}
+
+	groups := make([][]string, 0, len(builtinSynonyms)+len(docs))
+	groups = append(groups, builtinSynonyms...)
+	for _, doc := range docs {
+		groups = append(groups, doc.Terms)
+	}
+	synonyms.dictionary = NewSynonymDictionary(groups...)
+	return synonyms.dictionary
+}
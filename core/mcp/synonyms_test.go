@@ -0,0 +1,61 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpand(t *testing.T) {
+	dictionary := NewSynonymDictionary(builtinSynonyms...)
+
+	assert.Equal(t, "Arsenicum album anxiety at night Arsenic Ars. Ars-alb.",
+		dictionary.Expand("Arsenicum album anxiety at night"), "spellings with the same key are added once")
+	assert.Equal(t, "Nat-m. grief Natrum muriaticum Natrum mur. Nat. mur.", dictionary.Expand("Nat-m. grief"))
+	assert.Equal(t, "Hepar sulph. splinters Hepar sulphuris calcareum Hep.", dictionary.Expand("Hepar sulph. splinters"),
+		"the longest match wins over Sulph.")
+	assert.Equal(t, "white album of symptoms", dictionary.Expand("white album of symptoms"))
+
+	var none *SynonymDictionary
+	assert.Equal(t, "Ars.", none.Expand("Ars."))
+}
+
+func TestSynonymDictionaryOverlap(t *testing.T) {
+	dictionary := NewSynonymDictionary(
+		[]string{"Mercurius solubilis", "Merc."},
+		[]string{"Mercurius corrosivus", "Merc-c.", "Merc."},
+	)
+	assert.Equal(t, "Merc. ulcers Mercurius solubilis Mercurius corrosivus Merc-c.", dictionary.Expand("Merc. ulcers"))
+}
+
+func TestSynonymIndex(t *testing.T) {
+	repo := odmtest.NewCollection(db.SynonymModel{SynonymID: "tub", Terms: []string{"Tuberculinum", "Tub."}})
+
+	dictionary := ProvideSynonymIndex().Dictionary(t.Context(), "tenant", repo)
+	require.NotNil(t, dictionary)
+	assert.Equal(t, "Tub. Tuberculinum", dictionary.Expand("Tub."), "tenant groups are added")
+	assert.Contains(t, dictionary.Expand("Lyc."), "Lycopodium clavatum", "built-in groups are kept")
+}
+
+func TestSearchExpandsSynonyms(t *testing.T) {
+	chunkRepository := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "kent", SectionID: "kent", Title: "Mind; Anxiety; night", Sentences: []string{"Ars., Acon., Calc."}},
+		db.ChunkModel{ChunkID: "bryonia", SectionID: "bryonia", Title: "Bryonia", Sentences: []string{"Stitching pains worse by motion."}},
+	)
+
+	search := func(opts ...SearchToolOption) []string {
+		searchTool := NewSearchTool(chunkRepository, odmtest.NewCollection[db.ChunkAnnModel](), nil, append(opts, WithLexicalOnly())...)
+		var ids []string
+		for result := range searchTool.Run(t.Context(), "Arsenicum album", SearchFilter{}, SearchPage{}) {
+			require.Empty(t, result.Error)
+			ids = append(ids, result.Id)
+		}
+		return ids
+	}
+
+	assert.Empty(t, search())
+	assert.Equal(t, []string{"kent"}, search(WithSynonyms(NewSynonymDictionary(builtinSynonyms...))))
+}
@@ -0,0 +1,123 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/go-collection-boot/ds"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// table lookup parameters.
+const (
+	maxTableQueries = 5  // queries looked up per call
+	rowsPerQuery    = 10 // matching rows returned per query
+)
+
+// TableTool looks rows up in the tables of the tenant's ingested documents, such as
+// dosage tables and repertory pages, and returns them whole with their column
+// headers. Text search returns the windows a row is in; this returns just the rows
+// that match, each with every one of its values. Only rows of documents a user in
+// accessGroups may read are looked up; see db.AccessFilter.
+type TableTool struct {
+	repository odm.OdmCollectionInterface[db.TableRowModel]
+	access     bson.M
+}
+
+func NewTableTool(repository odm.OdmCollectionInterface[db.TableRowModel], accessGroups []string) *TableTool {
+	return &TableTool{repository: repository, access: db.AccessFilter(accessGroups)}
+}
+
+// Rows finds the table rows best matching query, best first.
+func (t *TableTool) Rows(ctx context.Context, query string, limit int) ([]db.TableRowModel, error) {
+	hits, err := async.Await(t.repository.TermSearch(ctx, query, odm.TermSearchParams{
+		IndexName: db.TableSearchIndexName,
+		Path:      db.TableSearchPaths,
+		Filter:    t.access,
+		Limit:     limit,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]db.TableRowModel, len(hits))
+	for i, hit := range hits {
+		rows[i] = hit.Doc
+	}
+	return rows, nil
+}
+
+// Run sends, for each table with rows matching one of the queries, a result listing
+// those rows in table order.
+func (t *TableTool) Run(ctx context.Context, queries []string) <-chan *schema.ToolResultChunk {
+	out := make(chan *schema.ToolResultChunk, maxTableQueries*rowsPerQuery)
+
+	go func() {
+		defer close(out)
+
+		sent := ds.NewSet[string]()
+		for _, query := range distinctTerms(queries, maxTableQueries) {
+			rows, err := t.Rows(ctx, query, rowsPerQuery)
+			if err != nil {
+				logger.Error("Failed to search table rows", zap.String("query", query), zap.Error(err))
+				out <- &schema.ToolResultChunk{Error: fmt.Sprintf("Table lookup failed for %q", query)}
+				continue
+			}
+
+			var tables []string
+			byTable := make(map[string][]db.TableRowModel)
+			for _, row := range rows {
+				if sent.Contains(row.RowID) {
+					continue
+				}
+				sent.Add(row.RowID)
+				if _, ok := byTable[row.TableID]; !ok {
+					tables = append(tables, row.TableID)
+				}
+				byTable[row.TableID] = append(byTable[row.TableID], row)
+			}
+			for _, tableId := range tables {
+				out <- tableResult(byTable[tableId])
+			}
+		}
+	}()
+
+	return out
+}
+
+// tableResult lists rows of one table, each as "Column: value; ..." so a row's values
+// stay with their columns whatever the table's width.
+func tableResult(rows []db.TableRowModel) *schema.ToolResultChunk {
+	sort.Slice(rows, func(i, j int) bool { return rows[i].RowIndex < rows[j].RowIndex })
+
+	sentences := make([]string, 0, len(rows))
+	indexes := make([]string, 0, len(rows))
+	for _, row := range rows {
+		sentences = append(sentences, row.Text)
+		indexes = append(indexes, strconv.Itoa(row.RowIndex))
+	}
+
+	first := rows[0]
+	return &schema.ToolResultChunk{
+		Title:       "Table: " + first.Title,
+		Attribution: first.Book,
+		Id:          first.TableID,
+		Sentences:   sentences,
+		Metadata: map[string]string{
+			"tableId":   first.TableID,
+			"sectionId": first.SectionID,
+			"sourceUri": first.SourceURI,
+			"columns":   strings.Join(first.Header, " | "),
+			"rows":      strings.Join(indexes, ","),
+		},
+	}
+}
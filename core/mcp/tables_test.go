@@ -0,0 +1,54 @@
+package mcp
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableTool(t *testing.T) {
+	header := []string{"Remedy", "Potency", "Dose"}
+	row := func(table string, index int, cells []string, text string) db.TableRowModel {
+		return db.TableRowModel{RowID: table + "_" + strconv.Itoa(index), TableID: table, RowIndex: index,
+			Header: header, Cells: cells, Text: text, Title: "Dosage", Book: "Practice of Medicine", SectionID: "sec-" + table}
+	}
+	internal := row("t3", 0, []string{"Arnica", "1M", "1 dose"}, "Remedy: Arnica; Potency: 1M; Dose: 1 dose")
+	internal.AccessGroups = []string{"practice-internal"}
+	repository := odmtest.NewCollection(
+		internal,
+		row("t1", 1, []string{"Belladonna", "30C", "3 pellets"}, "Remedy: Belladonna; Potency: 30C; Dose: 3 pellets"),
+		row("t1", 0, []string{"Aconite", "30C", "2 pellets"}, "Remedy: Aconite; Potency: 30C; Dose: 2 pellets"),
+		row("t2", 0, []string{"Arnica", "200C", "1 dose"}, "Remedy: Arnica; Potency: 200C; Dose: 1 dose"),
+	)
+
+	tool := NewTableTool(repository, nil)
+	rows, err := tool.Rows(t.Context(), "Arnica", 5)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, []string{"Arnica", "200C", "1 dose"}, rows[0].Cells, "rows of restricted documents are left out")
+
+	rows, err = NewTableTool(repository, []string{"practice-internal"}).Rows(t.Context(), "Arnica", 5)
+	require.NoError(t, err)
+	assert.Len(t, rows, 2)
+
+	var results []*schema.ToolResultChunk
+	for result := range tool.Run(t.Context(), []string{"30C", "Aconite", " "}) {
+		require.Empty(t, result.Error)
+		results = append(results, result)
+	}
+
+	require.Len(t, results, 1, "rows already sent are not repeated")
+	assert.Equal(t, "Table: Dosage", results[0].Title)
+	assert.Equal(t, "Practice of Medicine", results[0].Attribution)
+	assert.Equal(t, []string{
+		"Remedy: Aconite; Potency: 30C; Dose: 2 pellets",
+		"Remedy: Belladonna; Potency: 30C; Dose: 3 pellets",
+	}, results[0].Sentences, "rows are in table order")
+	assert.Equal(t, "0,1", results[0].Metadata["rows"])
+	assert.Equal(t, "Remedy | Potency | Dose", results[0].Metadata["columns"])
+}
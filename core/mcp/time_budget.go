@@ -0,0 +1,54 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WithTimeBudget gives the text and vector searches budget to answer, so one slow engine
+// doesn't hold up the agent's iteration. An engine still searching when the budget runs
+// out is left out, and the results of the other are sent with a partial metadata entry
+// of "true". When neither answered in time the search fails. Looking up the chunks found
+// and reranking them are not counted; the reranker keeps its own budget.
+func WithTimeBudget(budget time.Duration) SearchToolOption {
+	return func(s *SearchTool) { s.budget = budget }
+}
+
+var errSearchTimedOut = status.Error(codes.DeadlineExceeded, "search timed out before any engine answered")
+
+// legsContext bounds the engines' searches by the time budget. The search itself keeps
+// ctx, so it can still look up what the engines found in time.
+func (s *SearchTool) legsContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.budget <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.budget)
+}
+
+// expired reports whether legs ran out of time budget, rather than ctx being cancelled.
+func expired(ctx, legs context.Context) bool {
+	return ctx.Err() == nil && errors.Is(legs.Err(), context.DeadlineExceeded)
+}
+
+// awaitLeg waits for an engine's search until legs is done. A search that answered in
+// time is used even when it is awaited after the budget ran out.
+func awaitLeg[T any](legs context.Context, task <-chan async.Result[T]) (T, error) {
+	select {
+	case result := <-task:
+		return result.Data, result.Err
+	default:
+	}
+
+	select {
+	case result := <-task:
+		return result.Data, result.Err
+	case <-legs.Done():
+		var zero T
+		return zero, legs.Err()
+	}
+}
@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// slowEmbedder answers after delay, ignoring cancellation like an unresponsive API.
+type slowEmbedder struct {
+	delay  time.Duration
+	vector []float32
+}
+
+func (e slowEmbedder) GetEmbedding(ctx context.Context, text string, opts ...embed.EmbedOption) <-chan async.Result[[]float32] {
+	return async.Go(func() ([]float32, error) {
+		time.Sleep(e.delay)
+		return e.vector, nil
+	})
+}
+
+// slowTextSearch answers text searches after delay.
+type slowTextSearch struct {
+	odm.OdmCollectionInterface[db.ChunkModel]
+	delay time.Duration
+}
+
+func (c slowTextSearch) TermSearch(ctx context.Context, query string, params odm.TermSearchParams) <-chan async.Result[[]odm.SearchHit[db.ChunkModel]] {
+	return async.Go(func() ([]odm.SearchHit[db.ChunkModel], error) {
+		time.Sleep(c.delay)
+		return async.Await(c.OdmCollectionInterface.TermSearch(ctx, query, params))
+	})
+}
+
+func TestSearchTimeBudget(t *testing.T) {
+	chunks := odmtest.NewCollection(
+		db.ChunkModel{ChunkID: "aconite", SectionID: "aconite", Title: "Aconite", Sentences: []string{"Sudden fear of death."}},
+		db.ChunkModel{ChunkID: "gelsemium", SectionID: "gelsemium", Title: "Gelsemium", Sentences: []string{"Drowsy and trembling."}},
+	)
+	vectors := odmtest.NewCollection(
+		db.ChunkAnnModel{ChunkID: "aconite", Embedding: bson.NewVector([]float32{0, 1})},
+		db.ChunkAnnModel{ChunkID: "gelsemium", Embedding: bson.NewVector([]float32{1, 0})},
+	)
+	const budget = 50 * time.Millisecond
+	fast, slow := time.Duration(0), 10*budget
+
+	// ids and partial markers of the results, or the error
+	search := func(textDelay, embedDelay time.Duration, opts ...SearchToolOption) (ids []string, partial []string, errors []string) {
+		searchTool := NewSearchTool(slowTextSearch{chunks, textDelay}, vectors, slowEmbedder{embedDelay, []float32{1, 0}}, opts...)
+		for result := range searchTool.Run(t.Context(), "fear of death", SearchFilter{}, SearchPage{}) {
+			if result.Error != "" {
+				errors = append(errors, result.Error)
+				continue
+			}
+			ids = append(ids, result.Id)
+			partial = append(partial, result.Metadata["partial"])
+		}
+		return ids, partial, errors
+	}
+
+	ids, partial, errors := search(fast, fast, WithTimeBudget(budget))
+	require.Empty(t, errors)
+	assert.ElementsMatch(t, []string{"aconite", "gelsemium"}, ids)
+	assert.Equal(t, []string{"", ""}, partial, "both engines answered in time")
+
+	started := time.Now()
+	ids, partial, errors = search(fast, slow, WithTimeBudget(budget))
+	require.Empty(t, errors)
+	assert.Less(t, time.Since(started), slow, "the slow embedding isn't waited for")
+	assert.Equal(t, []string{"aconite"}, ids, "only the text search answered")
+	assert.Equal(t, []string{"true"}, partial)
+
+	ids, partial, errors = search(slow, fast, WithTimeBudget(budget))
+	require.Empty(t, errors)
+	assert.Equal(t, []string{"gelsemium", "aconite"}, ids, "only the vector search answered")
+	assert.Equal(t, []string{"true", "true"}, partial)
+
+	_, _, errors = search(slow, slow, WithTimeBudget(budget))
+	require.Len(t, errors, 1)
+	assert.Contains(t, errors[0], "timed out")
+
+	ids, partial, errors = search(fast, 2*budget)
+	require.Empty(t, errors)
+	assert.ElementsMatch(t, []string{"aconite", "gelsemium"}, ids, "without a budget both are waited for")
+	assert.Equal(t, []string{"", ""}, partial)
+}
@@ -0,0 +1,126 @@
+// Package notifications holds the catalogue of events users can be notified about and
+// resolves each user's preferences for them. Every subsystem that notifies users asks
+// Preferences.Allows before sending, rather than keeping a toggle of its own.
+package notifications
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+)
+
+// Channels an event can be delivered on.
+const (
+	ChannelInApp   = "in_app"  // shown in the open chat page
+	ChannelEmail   = "email"   // included in the email digest
+	ChannelPush    = "push"    // browser or mobile push
+	ChannelWebhook = "webhook" // forwarded to the tenant's webhook
+)
+
+// Events users can be notified about.
+const (
+	EventCorpusUpdate = "corpus_update"
+	EventReminder     = "reminder"
+)
+
+// Email digest frequencies.
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// Event describes a kind of notification and where it may be sent.
+type Event struct {
+	Name        string
+	Description string
+	Channels    []string // channels the event can be delivered on
+	Defaults    []string // channels used until the user chooses
+}
+
+// Events is the catalogue, in the order settings pages list it.
+var Events = []Event{
+	{
+		Name:        EventCorpusUpdate,
+		Description: "New or updated documents become searchable in your library",
+		Channels:    []string{ChannelInApp, ChannelEmail, ChannelPush, ChannelWebhook},
+		Defaults:    []string{ChannelInApp},
+	},
+	{
+		Name:        EventReminder,
+		Description: "Reminders to follow up on a case",
+		Channels:    []string{ChannelInApp, ChannelEmail, ChannelPush},
+		Defaults:    []string{ChannelInApp, ChannelEmail},
+	},
+}
+
+var digestFrequencies = []string{DigestOff, DigestDaily, DigestWeekly}
+
+func lookupEvent(name string) (Event, bool) {
+	i := slices.IndexFunc(Events, func(event Event) bool { return event.Name == name })
+	if i < 0 {
+		return Event{}, false
+	}
+	return Events[i], true
+}
+
+// Preferences are a user's stored choices with defaults filled in.
+type Preferences struct {
+	Channels        map[string][]string
+	DigestFrequency string
+	UpdatedOn       int64
+}
+
+// Resolve fills in the defaults for everything the user has not chosen. stored may be nil.
+func Resolve(stored *db.NotificationPreferencesModel) Preferences {
+	prefs := Preferences{Channels: make(map[string][]string, len(Events)), DigestFrequency: DigestOff}
+	for _, event := range Events {
+		prefs.Channels[event.Name] = slices.Clone(event.Defaults)
+	}
+	if stored == nil {
+		return prefs
+	}
+
+	for name, channels := range stored.Channels {
+		if event, ok := lookupEvent(name); ok {
+			// channels dropped from the catalogue since they were chosen are ignored
+			prefs.Channels[name] = slices.DeleteFunc(slices.Clone(channels), func(channel string) bool {
+				return !slices.Contains(event.Channels, channel)
+			})
+		}
+	}
+	if slices.Contains(digestFrequencies, stored.DigestFrequency) {
+		prefs.DigestFrequency = stored.DigestFrequency
+	}
+	prefs.UpdatedOn = stored.UpdatedOn
+	return prefs
+}
+
+// Allows reports whether the event may be sent to the user on the channel.
+func (p Preferences) Allows(event, channel string) bool {
+	return slices.Contains(p.Channels[event], channel)
+}
+
+// Validate checks an update before it is stored, returning every problem found. Events
+// left out of channels keep their current setting.
+func Validate(channels map[string][]string, digestFrequency string) []error {
+	var problems []error
+	for _, name := range slices.Sorted(maps.Keys(channels)) {
+		event, ok := lookupEvent(name)
+		if !ok {
+			problems = append(problems, fmt.Errorf("unknown event %q", name))
+			continue
+		}
+		for _, channel := range channels[name] {
+			if !slices.Contains(event.Channels, channel) {
+				problems = append(problems, fmt.Errorf("event %q cannot be sent by %q", name, channel))
+			}
+		}
+	}
+	if digestFrequency != "" && !slices.Contains(digestFrequencies, digestFrequency) {
+		problems = append(problems, fmt.Errorf("digest frequency must be one of %v", digestFrequencies))
+	}
+	return problems
+}
@@ -0,0 +1,45 @@
+package notifications
+
+import (
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	defaults := Resolve(nil)
+	assert.True(t, defaults.Allows(EventCorpusUpdate, ChannelInApp))
+	assert.False(t, defaults.Allows(EventCorpusUpdate, ChannelEmail))
+	assert.Equal(t, DigestOff, defaults.DigestFrequency)
+
+	prefs := Resolve(&db.NotificationPreferencesModel{
+		Channels: map[string][]string{
+			EventCorpusUpdate: {},
+			EventReminder:     {ChannelPush, ChannelWebhook},
+			"retired_event":   {ChannelEmail},
+		},
+		DigestFrequency: "hourly",
+		UpdatedOn:       42,
+	})
+	assert.False(t, prefs.Allows(EventCorpusUpdate, ChannelInApp), "an empty list turns the event off")
+	assert.Equal(t, []string{ChannelPush}, prefs.Channels[EventReminder], "reminders cannot go to the webhook")
+	assert.NotContains(t, prefs.Channels, "retired_event")
+	assert.Equal(t, DigestOff, prefs.DigestFrequency)
+	assert.Equal(t, int64(42), prefs.UpdatedOn)
+}
+
+func TestValidate(t *testing.T) {
+	assert.Empty(t, Validate(map[string][]string{EventCorpusUpdate: {ChannelEmail, ChannelWebhook}}, DigestWeekly))
+	assert.Empty(t, Validate(nil, ""))
+
+	problems := Validate(map[string][]string{
+		EventReminder: {ChannelWebhook},
+		"sms_alert":   {ChannelInApp},
+	}, "hourly")
+	require.Len(t, problems, 3)
+	assert.EqualError(t, problems[0], `event "reminder" cannot be sent by "webhook"`)
+	assert.EqualError(t, problems[1], `unknown event "sms_alert"`)
+	assert.ErrorContains(t, problems[2], "digest frequency")
+}
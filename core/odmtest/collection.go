@@ -0,0 +1,348 @@
+// Package odmtest provides an in-memory stand-in for odm collections so services and
+// tools can be unit tested without a Mongo deployment.
+//
+// It covers the query subset this repo uses: field equality (including array
+// membership and dotted paths), $and/$or/$nor, $in/$nin, $ne, $gt/$gte/$lt/$lte,
+// $exists, $not and $regex; sorting, limit and skip; brute-force cosine VectorSearch
+// and a term-frequency TermSearch. Anything else fails loudly instead of silently
+// matching.
+package odmtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var ErrNotFound = mongo.ErrNoDocuments
+
+// Collection is an in-memory odm.OdmCollectionInterface. Documents are stored as BSON,
+// so bson tags, omitempty and round-tripping behave as they do against Mongo.
+type Collection[T odm.DbModel] struct {
+	mu    sync.RWMutex
+	docs  map[string]bson.M
+	order []string // insertion order, used when no sort is given
+	now   func() int64
+}
+
+var _ odm.OdmCollectionInterface[odm.DbModel] = (*Collection[odm.DbModel])(nil)
+
+func NewCollection[T odm.DbModel](seed ...T) *Collection[T] {
+	c := &Collection[T]{
+		docs: make(map[string]bson.M),
+		now:  odm.DefaultTimer{}.Now,
+	}
+	for _, model := range seed {
+		if err := c.put(model); err != nil {
+			panic(fmt.Sprintf("odmtest: cannot seed %T: %v", model, err))
+		}
+	}
+	return c
+}
+
+// Docs returns a snapshot of the stored models in insertion order.
+func (c *Collection[T]) Docs() []T {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	out := make([]T, 0, len(c.order))
+	for _, id := range c.order {
+		model, _ := decode[T](c.docs[id])
+		out = append(out, model)
+	}
+	return out
+}
+
+// Save upserts like odm: fields are $set onto any existing document and
+// createdOn/updatedOn are stamped.
+func (c *Collection[T]) Save(ctx context.Context, model T) <-chan async.Result[struct{}] {
+	return async.Go(func() (struct{}, error) {
+		return struct{}{}, c.put(model)
+	})
+}
+
+func (c *Collection[T]) put(model T) error {
+	doc, err := encode(model)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := model.Id()
+	existing, ok := c.docs[id]
+	if ok {
+		doc["updatedOn"] = c.now()
+		for key, value := range doc {
+			existing[key] = value
+		}
+		return nil
+	}
+
+	doc["_id"] = id
+	doc["createdOn"] = c.now()
+	c.docs[id] = doc
+	c.order = append(c.order, id)
+	return nil
+}
+
+func (c *Collection[T]) FindOneByID(ctx context.Context, id string) <-chan async.Result[*T] {
+	return c.FindOne(ctx, bson.M{"_id": id})
+}
+
+func (c *Collection[T]) FindOne(ctx context.Context, filters bson.M) <-chan async.Result[*T] {
+	return async.Go(func() (*T, error) {
+		if filters == nil {
+			return nil, errors.New("filters cannot be nil for FindOne")
+		}
+
+		docs, err := c.query(filters, nil, 1, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(docs) == 0 {
+			return nil, ErrNotFound
+		}
+
+		model, err := decode[T](docs[0])
+		return &model, err
+	})
+}
+
+func (c *Collection[T]) Find(ctx context.Context, filters bson.M, sort bson.D, limit, skip int64) <-chan async.Result[[]T] {
+	return async.Go(func() ([]T, error) {
+		docs, err := c.query(filters, sort, limit, skip)
+		if err != nil {
+			return nil, err
+		}
+		return decodeAll[T](docs)
+	})
+}
+
+func (c *Collection[T]) DeleteByID(ctx context.Context, id string) <-chan async.Result[struct{}] {
+	return c.DeleteOne(ctx, bson.M{"_id": id})
+}
+
+func (c *Collection[T]) DeleteOne(ctx context.Context, filters bson.M) <-chan async.Result[struct{}] {
+	return async.Go(func() (struct{}, error) {
+		if filters == nil {
+			return struct{}{}, errors.New("filters cannot be nil for DeleteOne")
+		}
+
+		docs, err := c.query(filters, nil, 1, 0)
+		if err != nil || len(docs) == 0 {
+			return struct{}{}, err
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		id := docs[0]["_id"].(string)
+		delete(c.docs, id)
+		c.order = slices.DeleteFunc(c.order, func(other string) bool { return other == id })
+		return struct{}{}, nil
+	})
+}
+
+func (c *Collection[T]) Count(ctx context.Context, filters bson.M) <-chan async.Result[int64] {
+	return async.Go(func() (int64, error) {
+		docs, err := c.query(filters, nil, 0, 0)
+		return int64(len(docs)), err
+	})
+}
+
+func (c *Collection[T]) DistinctInto(ctx context.Context, field string, filters bson.D, out any) error {
+	if out == nil {
+		return errors.New("output slice cannot be nil")
+	}
+
+	filterMap := bson.M{}
+	for _, elem := range filters {
+		filterMap[elem.Key] = elem.Value
+	}
+
+	docs, err := c.query(filterMap, nil, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	var values bson.A
+	for _, doc := range docs {
+		value, ok := lookup(doc, field)
+		if !ok {
+			continue
+		}
+		candidates := bson.A{value}
+		if arr, isArr := value.(bson.A); isArr {
+			candidates = arr
+		}
+		for _, candidate := range candidates {
+			if !slices.ContainsFunc(values, func(seen any) bool { return equal(seen, candidate) }) {
+				values = append(values, candidate)
+			}
+		}
+	}
+
+	raw, err := bson.Marshal(bson.M{"values": values})
+	if err != nil {
+		return err
+	}
+	return bson.Raw(raw).Lookup("values").Unmarshal(out)
+}
+
+// Aggregate supports pipelines built from $match, $sort, $skip and $limit.
+func (c *Collection[T]) Aggregate(ctx context.Context, pipeline mongo.Pipeline) <-chan async.Result[[]T] {
+	return async.Go(func() ([]T, error) {
+		docs, err := c.query(bson.M{}, nil, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, stage := range pipeline {
+			if len(stage) != 1 {
+				return nil, fmt.Errorf("odmtest: pipeline stage must have exactly one operator")
+			}
+
+			switch op := stage[0]; op.Key {
+			case "$match":
+				filter, err := toM(op.Value)
+				if err != nil {
+					return nil, err
+				}
+				docs, err = filterDocs(docs, filter)
+				if err != nil {
+					return nil, err
+				}
+			case "$sort":
+				spec, ok := op.Value.(bson.D)
+				if !ok {
+					return nil, fmt.Errorf("odmtest: $sort expects bson.D")
+				}
+				sortDocs(docs, spec)
+			case "$skip":
+				docs = docs[min(int(toInt64(op.Value)), len(docs)):]
+			case "$limit":
+				docs = docs[:min(int(toInt64(op.Value)), len(docs))]
+			default:
+				return nil, fmt.Errorf("odmtest: unsupported pipeline stage %s", op.Key)
+			}
+		}
+
+		return decodeAll[T](docs)
+	})
+}
+
+func (c *Collection[T]) Exists(ctx context.Context, id string) <-chan async.Result[bool] {
+	return async.Go(func() (bool, error) {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+
+		_, ok := c.docs[id]
+		return ok, nil
+	})
+}
+
+// query returns copies of the matching documents, so callers never alias storage.
+func (c *Collection[T]) query(filters bson.M, sortSpec bson.D, limit, skip int64) ([]bson.M, error) {
+	c.mu.RLock()
+	docs := make([]bson.M, 0, len(c.order))
+	for _, id := range c.order {
+		docs = append(docs, clone(c.docs[id]))
+	}
+	c.mu.RUnlock()
+
+	docs, err := filterDocs(docs, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	sortDocs(docs, sortSpec)
+
+	docs = docs[min(int(skip), len(docs)):]
+	if limit > 0 {
+		docs = docs[:min(int(limit), len(docs))]
+	}
+	return docs, nil
+}
+
+func filterDocs(docs []bson.M, filters bson.M) ([]bson.M, error) {
+	out := docs[:0]
+	for _, doc := range docs {
+		ok, err := matches(doc, filters)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, doc)
+		}
+	}
+	return out, nil
+}
+
+func sortDocs(docs []bson.M, spec bson.D) {
+	if len(spec) == 0 {
+		return
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, key := range spec {
+			a, _ := lookup(docs[i], key.Key)
+			b, _ := lookup(docs[j], key.Key)
+			cmp, _ := compare(a, b)
+			if cmp == 0 {
+				continue
+			}
+			if toInt64(key.Value) < 0 {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+}
+
+func encode(model any) (bson.M, error) {
+	raw, err := bson.Marshal(model)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	err = bson.Unmarshal(raw, &doc)
+	return doc, err
+}
+
+func decode[T any](doc bson.M) (T, error) {
+	var model T
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return model, err
+	}
+	err = bson.Unmarshal(raw, &model)
+	return model, err
+}
+
+func decodeAll[T any](docs []bson.M) ([]T, error) {
+	out := make([]T, 0, len(docs))
+	for _, doc := range docs {
+		model, err := decode[T](doc)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, model)
+	}
+	return out, nil
+}
+
+func clone(doc bson.M) bson.M {
+	copied, _ := encode(doc)
+	return copied
+}
@@ -0,0 +1,143 @@
+package odmtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestCollection(t *testing.T) {
+	ctx := t.Context()
+
+	chunks := NewCollection(
+		db.ChunkModel{ChunkID: "a", Title: "Aconite", Tags: []string{"public", "fear"}, Sentences: []string{"Sudden fear of death."}, CorpusVersion: 1},
+		db.ChunkModel{ChunkID: "b", Title: "Arsenicum", Tags: []string{"anxiety"}, Sentences: []string{"Anxiety and restlessness after midnight."}, CorpusVersion: 2},
+		db.ChunkModel{ChunkID: "c", Title: "Gelsemium", Tags: []string{"public"}, Sentences: []string{"Fear of exciting news; trembling."}, CorpusVersion: 2, RetiredVersion: 3},
+	)
+	var repo odm.OdmCollectionInterface[db.ChunkModel] = chunks
+
+	t.Run("SaveAndFindByID", func(t *testing.T) {
+		_, err := async.Await(repo.Save(ctx, db.ChunkModel{ChunkID: "d", Title: "Belladonna"}))
+		require.NoError(t, err)
+
+		found, err := async.Await(repo.FindOneByID(ctx, "d"))
+		require.NoError(t, err)
+		assert.Equal(t, "Belladonna", found.Title)
+
+		_, err = async.Await(repo.FindOneByID(ctx, "missing"))
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		_, err = async.Await(repo.DeleteByID(ctx, "d"))
+		require.NoError(t, err)
+		exists, _ := async.Await(repo.Exists(ctx, "d"))
+		assert.False(t, exists)
+	})
+
+	t.Run("FilterSortLimit", func(t *testing.T) {
+		live, err := async.Await(repo.Find(ctx, db.LiveChunksFilter(), bson.D{{Key: "title", Value: -1}}, 0, 0))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"b", "a"}, ids(live))
+
+		public, err := async.Await(repo.Find(ctx, bson.M{"tags": bson.M{"$in": []string{"public"}}}, bson.D{{Key: "_id", Value: 1}}, 1, 1))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"c"}, ids(public))
+
+		count, err := async.Await(repo.Count(ctx, bson.M{"$or": bson.A{
+			bson.M{"corpusVersion": bson.M{"$gte": 2}},
+			bson.M{"title": bson.M{"$regex": "^aco", "$options": "i"}},
+		}}))
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, count)
+	})
+
+	t.Run("Dates", func(t *testing.T) {
+		now := time.Now()
+		embeddings := NewCollection(
+			db.QueryEmbeddingModel{ID: "expired", Embedding: bson.NewVector([]float32{1}), ExpiresAt: now.Add(-time.Minute)},
+			db.QueryEmbeddingModel{ID: "live", Embedding: bson.NewVector([]float32{1}), ExpiresAt: now.Add(time.Minute)},
+		)
+		live, err := async.Await(embeddings.Find(ctx, bson.M{"expiresAt": bson.M{"$gt": now}}, nil, 0, 0))
+		require.NoError(t, err)
+		require.Len(t, live, 1)
+		assert.Equal(t, "live", live[0].ID)
+	})
+
+	t.Run("UnsupportedOperatorFails", func(t *testing.T) {
+		_, err := async.Await(repo.Find(ctx, bson.M{"title": bson.M{"$size": 1}}, nil, 0, 0))
+		assert.Error(t, err)
+	})
+
+	t.Run("TermSearch", func(t *testing.T) {
+		hits, err := async.Await(repo.TermSearch(ctx, "fear of death", odm.TermSearchParams{
+			IndexName: db.TextSearchIndexName,
+			Path:      db.TextSearchPaths,
+			Filter:    db.LiveChunksFilter(),
+			Limit:     10,
+		}))
+		require.NoError(t, err)
+		require.NotEmpty(t, hits)
+		assert.Equal(t, "a", hits[0].Doc.ChunkID)
+		for _, hit := range hits {
+			assert.NotEqual(t, "c", hit.Doc.ChunkID, "retired chunks are filtered out")
+		}
+	})
+
+	t.Run("VectorSearch", func(t *testing.T) {
+		vectors := NewCollection(
+			db.ChunkAnnModel{ChunkID: "a", Embedding: bson.NewVector([]float32{1, 0, 0})},
+			db.ChunkAnnModel{ChunkID: "b", Embedding: bson.NewVector([]float32{0.7, 0.7, 0})},
+			db.ChunkAnnModel{ChunkID: "c", Embedding: bson.NewVector([]float32{0, 0, 1})},
+		)
+
+		hits, err := async.Await(vectors.VectorSearch(ctx, []float32{1, 0.1, 0}, odm.VectorSearchParams{
+			IndexName: "chunkEmbeddingIndex",
+			Path:      "embedding",
+			K:         2,
+		}))
+		require.NoError(t, err)
+		require.Len(t, hits, 2)
+		assert.Equal(t, "a", hits[0].Doc.ChunkID)
+		assert.Equal(t, "b", hits[1].Doc.ChunkID)
+		assert.Greater(t, hits[0].Score, hits[1].Score)
+
+		vectors = NewCollection(
+			db.ChunkAnnModel{ChunkID: "a", Embedding: bson.NewVector([]float32{1, 0, 0}), Retired: true},
+			db.ChunkAnnModel{ChunkID: "b", Embedding: bson.NewVector([]float32{0.7, 0.7, 0})},
+		)
+		hits, err = async.Await(vectors.VectorSearch(ctx, []float32{1, 0.1, 0}, odm.VectorSearchParams{
+			IndexName: "chunkEmbeddingIndex",
+			Path:      "embedding",
+			K:         1,
+			Filter:    db.LiveVectorsFilter(),
+		}))
+		require.NoError(t, err)
+		require.Len(t, hits, 1)
+		assert.Equal(t, "b", hits[0].Doc.ChunkID, "retired vectors are filtered out before ranking")
+	})
+
+	t.Run("ChunksAtVersion", func(t *testing.T) {
+		// added at 1, retired at 2 and added back at 4
+		lapsed := NewCollection(db.ChunkModel{ChunkID: "e", Title: "Nux vomica", CorpusVersion: 1,
+			Lapses: []db.ChunkLapse{{RetiredVersion: 2, RestoredVersion: 4}}})
+
+		for version, want := range map[int64]int64{1: 1, 2: 0, 3: 0, 4: 1, 5: 1} {
+			count, err := async.Await(lapsed.Count(ctx, db.ChunksAtVersionFilter(version)))
+			require.NoError(t, err)
+			assert.Equal(t, want, count, "at version %d", version)
+		}
+	})
+}
+
+func ids(chunks []db.ChunkModel) []string {
+	out := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		out = append(out, chunk.ChunkID)
+	}
+	return out
+}
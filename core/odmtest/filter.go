@@ -0,0 +1,373 @@
+package odmtest
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// matches reports whether doc satisfies a Mongo query filter.
+func matches(doc bson.M, filter bson.M) (bool, error) {
+	for key, cond := range filter {
+		var (
+			ok  bool
+			err error
+		)
+
+		switch key {
+		case "$and", "$or", "$nor":
+			ok, err = matchLogical(doc, key, cond)
+		default:
+			if strings.HasPrefix(key, "$") {
+				return false, fmt.Errorf("odmtest: unsupported top-level operator %s", key)
+			}
+			value, present := lookup(doc, key)
+			ok, err = matchField(value, present, cond)
+		}
+
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchLogical(doc bson.M, op string, cond any) (bool, error) {
+	clauses, ok := cond.(bson.A)
+	if !ok {
+		if arr, isSlice := cond.([]bson.M); isSlice {
+			for _, m := range arr {
+				clauses = append(clauses, m)
+			}
+		} else {
+			return false, fmt.Errorf("odmtest: %s expects an array", op)
+		}
+	}
+
+	for _, clause := range clauses {
+		m, err := toM(clause)
+		if err != nil {
+			return false, err
+		}
+		ok, err := matches(doc, m)
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case op == "$and" && !ok:
+			return false, nil
+		case op == "$or" && ok:
+			return true, nil
+		case op == "$nor" && ok:
+			return false, nil
+		}
+	}
+	return op != "$or", nil
+}
+
+// matchField applies cond to a single field value. A cond without operators is an
+// equality test, which for arrays also matches any element.
+func matchField(value any, present bool, cond any) (bool, error) {
+	ops, isOps := operators(cond)
+	if !isOps {
+		return equalsOrContains(value, cond), nil
+	}
+
+	for op, arg := range ops {
+		ok, err := matchOperator(value, present, op, arg)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchOperator(value any, present bool, op string, arg any) (bool, error) {
+	switch op {
+	case "$eq":
+		return equalsOrContains(value, arg), nil
+	case "$ne":
+		return !equalsOrContains(value, arg), nil
+	case "$in", "$nin":
+		candidates, ok := toArray(arg)
+		if !ok {
+			return false, fmt.Errorf("odmtest: %s expects an array", op)
+		}
+		found := false
+		for _, candidate := range candidates {
+			if equalsOrContains(value, candidate) {
+				found = true
+				break
+			}
+		}
+		return found == (op == "$in"), nil
+	case "$gt", "$gte", "$lt", "$lte":
+		return anyElement(value, func(v any) bool {
+			cmp, comparable := compare(v, arg)
+			if !comparable {
+				return false
+			}
+			switch op {
+			case "$gt":
+				return cmp > 0
+			case "$gte":
+				return cmp >= 0
+			case "$lt":
+				return cmp < 0
+			default:
+				return cmp <= 0
+			}
+		}), nil
+	case "$exists":
+		want, _ := arg.(bool)
+		return present == want, nil
+	case "$not":
+		ok, err := matchField(value, present, arg)
+		return !ok, err
+	case "$elemMatch":
+		cond, err := toM(arg)
+		if err != nil {
+			return false, err
+		}
+		arr, _ := value.(bson.A)
+		for _, elem := range arr {
+			doc, err := toM(elem)
+			if err != nil {
+				continue // only documents are matched against field conditions
+			}
+			if ok, err := matches(doc, cond); err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case "$regex":
+		var pattern, options string
+		switch r := arg.(type) {
+		case string:
+			pattern = r
+		case bson.Regex:
+			pattern, options = r.Pattern, r.Options
+		default:
+			return false, fmt.Errorf("odmtest: $regex expects a string")
+		}
+		if options != "" {
+			pattern = "(?" + options + ")" + pattern
+		}
+
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, err
+		}
+		return anyElement(value, func(v any) bool {
+			s, isString := v.(string)
+			return isString && re.MatchString(s)
+		}), nil
+	case "$options":
+		return true, nil // folded into $regex by callers that need it
+	default:
+		return false, fmt.Errorf("odmtest: unsupported operator %s", op)
+	}
+}
+
+// operators returns cond as an operator map when every key starts with '$'.
+func operators(cond any) (bson.M, bool) {
+	m, err := toM(cond)
+	if err != nil || len(m) == 0 {
+		return nil, false
+	}
+	for key := range m {
+		if !strings.HasPrefix(key, "$") {
+			return nil, false
+		}
+	}
+
+	if options, ok := m["$options"].(string); ok {
+		if pattern, ok := m["$regex"].(string); ok {
+			m["$regex"] = bson.Regex{Pattern: pattern, Options: options}
+		}
+	}
+	return m, true
+}
+
+func equalsOrContains(value, target any) bool {
+	if equal(value, target) {
+		return true
+	}
+	if arr, ok := value.(bson.A); ok {
+		for _, elem := range arr {
+			if equal(elem, target) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func anyElement(value any, pred func(any) bool) bool {
+	if arr, ok := value.(bson.A); ok {
+		for _, elem := range arr {
+			if pred(elem) {
+				return true
+			}
+		}
+		return false
+	}
+	return pred(value)
+}
+
+func equal(a, b any) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if cmp, ok := compare(a, b); ok {
+		return cmp == 0
+	}
+
+	ea, errA := bson.Marshal(bson.M{"v": a})
+	eb, errB := bson.Marshal(bson.M{"v": b})
+	return errA == nil && errB == nil && string(ea) == string(eb)
+}
+
+// compare orders numbers, strings and booleans; other types are not comparable.
+func compare(a, b any) (int, bool) {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case fa < fb:
+			return -1, true
+		case fa > fb:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	if ta, ok := toTime(a); ok {
+		tb, ok := toTime(b)
+		if !ok {
+			return 0, false
+		}
+		return ta.Compare(tb), true
+	}
+
+	if sa, ok := a.(string); ok {
+		sb, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(sa, sb), true
+	}
+
+	if ba, ok := a.(bool); ok {
+		bb, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case ba == bb:
+			return 0, true
+		case !ba:
+			return -1, true
+		}
+		return 1, true
+	}
+
+	return 0, false
+}
+
+// toTime reads dates as stored documents hold them and as filters pass them.
+func toTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case bson.DateTime:
+		return t.Time(), true
+	}
+	return time.Time{}, false
+}
+
+// lookup resolves a dotted path, descending into nested documents.
+func lookup(doc bson.M, path string) (any, bool) {
+	var current any = doc
+	for _, part := range strings.Split(path, ".") {
+		m, err := toM(current)
+		if err != nil {
+			return nil, false
+		}
+		value, ok := m[part]
+		if !ok {
+			return nil, false
+		}
+		current = value
+	}
+	return current, true
+}
+
+func toM(value any) (bson.M, error) {
+	switch v := value.(type) {
+	case bson.M:
+		return v, nil
+	case map[string]any:
+		return bson.M(v), nil
+	case bson.D:
+		m := bson.M{}
+		for _, elem := range v {
+			m[elem.Key] = elem.Value
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("odmtest: expected a document, got %T", value)
+	}
+}
+
+func toArray(value any) (bson.A, bool) {
+	switch v := value.(type) {
+	case bson.A:
+		return v, true
+	case []any:
+		return bson.A(v), true
+	case []string:
+		out := make(bson.A, len(v))
+		for i, s := range v {
+			out[i] = s
+		}
+		return out, true
+	case []int64:
+		out := make(bson.A, len(v))
+		for i, n := range v {
+			out[i] = n
+		}
+		return out, true
+	default:
+		return nil, false
+	}
+}
+
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func toInt64(value any) int64 {
+	f, _ := toFloat(value)
+	return int64(math.Round(f))
+}
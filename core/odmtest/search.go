@@ -0,0 +1,171 @@
+package odmtest
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// VectorSearch scores every document by cosine similarity against params.Path, the
+// exact answer Atlas approximates. Scores are mapped to [0, 1] like Atlas' cosine score.
+func (c *Collection[T]) VectorSearch(ctx context.Context, embedding []float32, params odm.VectorSearchParams) <-chan async.Result[[]odm.SearchHit[T]] {
+	return async.Go(func() ([]odm.SearchHit[T], error) {
+		if len(embedding) == 0 || params.IndexName == "" || params.Path == "" || params.K <= 0 {
+			return nil, errors.New("invalid input - embedding, index name, path, and K must be provided")
+		}
+
+		docs, err := c.query(params.Filter, nil, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		scored := make([]scoredDoc, 0, len(docs))
+		for _, doc := range docs {
+			value, _ := lookup(doc, params.Path)
+			vector, ok := toVector(value)
+			if !ok || len(vector) != len(embedding) {
+				continue
+			}
+			scored = append(scored, scoredDoc{doc: doc, score: (1 + cosine(embedding, vector)) / 2})
+		}
+
+		return topHits[T](scored, params.K)
+	})
+}
+
+// TermSearch scores documents by how often the query's terms occur in params.Path,
+// weighting rarer terms higher. It is a stand-in for Atlas Search ranking, not a copy.
+func (c *Collection[T]) TermSearch(ctx context.Context, query string, params odm.TermSearchParams) <-chan async.Result[[]odm.SearchHit[T]] {
+	return async.Go(func() ([]odm.SearchHit[T], error) {
+		if query == "" || params.IndexName == "" || len(params.Path) == 0 || params.Limit <= 0 {
+			return nil, errors.New("invalid input - query, index name, path, and limit must be provided")
+		}
+
+		docs, err := c.query(params.Filter, nil, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		terms := tokenize(query)
+		docTerms := make([]map[string]int, len(docs))
+		docFreq := make(map[string]int)
+		for i, doc := range docs {
+			docTerms[i] = make(map[string]int)
+			for _, path := range params.Path {
+				value, _ := lookup(doc, path)
+				for _, text := range texts(value) {
+					for _, token := range tokenize(text) {
+						docTerms[i][token]++
+					}
+				}
+			}
+			for _, term := range terms {
+				if docTerms[i][term] > 0 {
+					docFreq[term]++
+				}
+			}
+		}
+
+		scored := make([]scoredDoc, 0, len(docs))
+		for i, doc := range docs {
+			score := 0.0
+			for _, term := range terms {
+				if tf := docTerms[i][term]; tf > 0 {
+					idf := math.Log(1 + float64(len(docs))/float64(docFreq[term]))
+					score += (1 + math.Log(float64(tf))) * idf
+				}
+			}
+			if score > 0 {
+				scored = append(scored, scoredDoc{doc: doc, score: score})
+			}
+		}
+
+		return topHits[T](scored, params.Limit)
+	})
+}
+
+type scoredDoc struct {
+	doc   bson.M
+	score float64
+}
+
+func topHits[T odm.DbModel](scored []scoredDoc, limit int) ([]odm.SearchHit[T], error) {
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].score > scored[j].score })
+	scored = scored[:min(limit, len(scored))]
+
+	hits := make([]odm.SearchHit[T], 0, len(scored))
+	for _, s := range scored {
+		model, err := decode[T](s.doc)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, odm.SearchHit[T]{Score: s.score, Doc: model})
+	}
+	return hits, nil
+}
+
+func toVector(value any) ([]float32, bool) {
+	switch v := value.(type) {
+	case bson.Binary:
+		vector, err := bson.NewVectorFromBinary(v)
+		if err != nil {
+			return nil, false
+		}
+		return vector.Float32OK()
+	case bson.Vector:
+		return v.Float32OK()
+	case bson.A:
+		out := make([]float32, 0, len(v))
+		for _, elem := range v {
+			f, ok := toFloat(elem)
+			if !ok {
+				return nil, false
+			}
+			out = append(out, float32(f))
+		}
+		return out, true
+	default:
+		return nil, false
+	}
+}
+
+func cosine(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+func texts(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case bson.A:
+		var out []string
+		for _, elem := range v {
+			out = append(out, texts(elem)...)
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,43 @@
+package prompts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeCaseContext(t *testing.T) {
+	assert.Nil(t, NormalizeCaseContext(db.CaseContext{Sex: " ", ChiefComplaints: []string{"", "  "}}))
+
+	normalized := NormalizeCaseContext(db.CaseContext{Sex: " Female", ChiefComplaints: []string{" migraine ", ""}, History: " recurrent since 2019 "})
+	require.NotNil(t, normalized)
+	assert.Equal(t, db.CaseContext{Sex: "female", ChiefComplaints: []string{"migraine"}, History: "recurrent since 2019"}, *normalized)
+}
+
+func TestValidateCaseContext(t *testing.T) {
+	assert.Empty(t, ValidateCaseContext(db.CaseContext{AgeYears: 42, Sex: "male", ChiefComplaints: []string{"insomnia"}}))
+
+	problems := ValidateCaseContext(db.CaseContext{
+		AgeYears:        140,
+		Sex:             "unknown",
+		ChiefComplaints: []string{strings.Repeat("a", MaxCaseEntryLength+1)},
+		History:         strings.Repeat("h", MaxCaseHistoryLength+1),
+	})
+	require.Len(t, problems, 4)
+	assert.ErrorContains(t, problems[0], "age")
+	assert.ErrorContains(t, problems[1], "sex must be one of female, male, other")
+	assert.ErrorContains(t, problems[2], "chief complaints must be at most 200 characters")
+	assert.ErrorContains(t, problems[3], "history")
+}
+
+func TestCaseContextPrompt(t *testing.T) {
+	assert.Empty(t, CaseContextPrompt(nil))
+
+	prompt := CaseContextPrompt(&db.CaseContext{AgeYears: 42, ChiefComplaints: []string{"migraine", "insomnia"}, Medications: []string{"Propranolol"}})
+	assert.Contains(t, prompt, "not instructions")
+	assert.True(t, strings.HasSuffix(prompt, "\n- Age: 42 years\n- Chief complaints: migraine; insomnia\n- Current medications: Propranolol"))
+	assert.NotContains(t, prompt, "Sex")
+}
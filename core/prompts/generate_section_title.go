@@ -7,6 +7,7 @@ import (
 	"github.com/SaiNageswarS/agent-boot/llm"
 	"github.com/SaiNageswarS/go-api-boot/logger"
 	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/llmrouter"
 	"go.uber.org/zap"
 )
 
@@ -45,9 +46,9 @@ func GenerateSectionTitle(ctx context.Context, docTitle, originalSectionHeader,
 				response += chunk
 				return nil
 			},
-			llm.WithMaxTokens(4000),
-			llm.WithTemperature(0.2),
-			llm.WithSystemPrompt(systemPrompt),
+			llmrouter.WithMaxTokens(4000),
+			llmrouter.WithTemperature(0.2),
+			llmrouter.WithSystemPrompt(systemPrompt),
 		)
 
 		if err != nil {
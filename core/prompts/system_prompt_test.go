@@ -0,0 +1,41 @@
+package prompts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderPromptTemplate(t *testing.T) {
+	now := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	variables := PromptVariables("clinic", map[string]string{"tenant_name": "Sunrise Clinic", "tenant": "other", "date": "never"}, now)
+
+	rendered, err := RenderPromptTemplate("  You answer for {{tenant_name}} ({{ tenant }}), a {{specialty}} practice, in {{language}}. Today is {{date}}.\n", variables)
+	require.NoError(t, err)
+	assert.Equal(t, "You answer for Sunrise Clinic (clinic), a homeopathy practice, in English. Today is 2026-03-04.", rendered)
+
+	_, err = RenderPromptTemplate("{{tenant_nme}} {{clinic}} {{tenant_nme}}", variables)
+	assert.EqualError(t, err, "unknown template variables: {{tenant_nme}}, {{clinic}}")
+}
+
+func TestValidatePromptTemplate(t *testing.T) {
+	assert.Empty(t, ValidatePromptTemplate("Answer in {{language}} for {{doctor}}.", map[string]string{"doctor": "Dr. Rao", "language": "Hindi"}))
+
+	problems := ValidatePromptTemplate("Answer for {{doctor}}.", map[string]string{"Doctor": "Dr. Rao", "date": "today"})
+	require.Len(t, problems, 3)
+	assert.ErrorContains(t, problems[0], `variable name "Doctor"`)
+	assert.ErrorContains(t, problems[1], `variable "date" is built in`)
+	assert.ErrorContains(t, problems[2], "{{doctor}}")
+}
+
+func TestComposeSystemPrompt(t *testing.T) {
+	assert.Equal(t, "Base.", ComposeSystemPrompt("Base.", " "))
+
+	composed := ComposeSystemPrompt("Base.", "Be brief.")
+	assert.Contains(t, composed, "Base.\n\n")
+	assert.Contains(t, composed, "always take precedence")
+	assert.True(t, len(composed) > len("Base.\n\nBe brief."))
+	assert.Regexp(t, `Be brief\.$`, composed)
+}
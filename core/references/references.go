@@ -0,0 +1,225 @@
+// Package references links the sections of a source document to the sections they point
+// at: those a "see also" or "compare" mention names, by heading or by remedy, and the
+// nearest sections of the same chapter. Links are found when a document is ingested, so
+// the agent can follow a retrieved passage to the passages it refers to.
+package references
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/entities"
+)
+
+// Link kinds.
+const (
+	KindReference = "reference" // "see", "see also", "vide" or "refer to" names the target
+	KindCompare   = "compare"   // "compare" or "cf." names the target
+	KindChapter   = "chapter"   // the target is in the same chapter
+)
+
+const (
+	maxReferenceLinks = 10 // reference and compare links kept per section
+	maxChapterLinks   = 4  // nearest sections of the same chapter linked per section
+	maxMentionLength  = 120
+	minHeadingLength  = 4 // shorter headings would match words in passing
+)
+
+// Section is a section of a source document, made of all its windows.
+type Section struct {
+	ID    string
+	Index int      // order in the document
+	Path  []string // headings, outermost first; the first names the chapter
+	Text  string
+}
+
+// mentionCue starts a mention of another section, and mentionEnd ends it: before a
+// bracket, a semicolon or a line break, or after a full stop ending a word too long to
+// be an abbreviation like "Gels.". The next cue ends it too.
+var (
+	mentionCue = regexp.MustCompile(`(?i)\b(see also|see|vide|refer to|compare|comp\.|cf\.)\s*:?\s*`)
+	mentionEnd = regexp.MustCompile(`([;()\[\]\n])|\p{Ll}{4,}[.!?](?:\s|$)`)
+)
+
+// mention is where a section names others.
+type mention struct {
+	kind string
+	text string // the cue and what it names, as written
+	body string // what the cue names
+}
+
+// Link returns the links of each section, by section ID: first those its mentions name,
+// in the order they are mentioned, then the nearest sections of its chapter. A section
+// is never linked to itself or twice to the same section.
+func Link(sections []Section) map[string][]db.ChunkLink {
+	ordered := byIndex(sections)
+	remedyChapters := make(map[string]Section) // remedy → first section of its chapter
+	for _, section := range ordered {
+		if remedy, ok := chapterRemedy(section); ok {
+			if _, seen := remedyChapters[remedy]; !seen {
+				remedyChapters[remedy] = section
+			}
+		}
+	}
+
+	links := make(map[string][]db.ChunkLink, len(sections))
+	for _, section := range sections {
+		linked := map[string]bool{section.ID: true}
+		var sectionLinks []db.ChunkLink
+		add := func(target, kind, text string) {
+			if !linked[target] {
+				linked[target] = true
+				sectionLinks = append(sectionLinks, db.ChunkLink{SectionID: target, Kind: kind, Text: text})
+			}
+		}
+
+		for _, m := range mentions(section.Text) {
+			if len(sectionLinks) >= maxReferenceLinks {
+				break
+			}
+			for _, entity := range entities.Recognize(m.body) {
+				if target, ok := remedyChapters[entity.Name]; ok && entity.Kind == entities.KindRemedy {
+					add(target.ID, m.kind, m.text)
+				}
+			}
+			for _, target := range headingTargets(m.body, section, ordered) {
+				add(target.ID, m.kind, m.text)
+			}
+		}
+		sectionLinks = sectionLinks[:min(len(sectionLinks), maxReferenceLinks)]
+
+		for _, target := range chapterNeighbours(section, sections) {
+			add(target.ID, KindChapter, "")
+		}
+		if len(sectionLinks) > 0 {
+			links[section.ID] = sectionLinks
+		}
+	}
+	return links
+}
+
+// mentions finds where text names other sections.
+func mentions(text string) []mention {
+	var found []mention
+	cues := mentionCue.FindAllStringSubmatchIndex(text, -1)
+	for i, cue := range cues {
+		end := min(len(text), cue[1]+maxMentionLength)
+		if i+1 < len(cues) {
+			end = min(end, cues[i+1][0])
+		}
+		rest := text[cue[1]:end]
+		if stop := mentionEnd.FindStringSubmatchIndex(rest); stop != nil {
+			if stop[2] >= 0 {
+				rest = rest[:stop[2]] // before the bracket, semicolon or line break
+			} else {
+				rest = rest[:stop[1]]
+			}
+		}
+		body := strings.TrimSpace(rest)
+		if body == "" {
+			continue
+		}
+
+		kind := KindReference
+		switch strings.ToLower(text[cue[2]:cue[3]]) {
+		case "compare", "comp.", "cf.":
+			kind = KindCompare
+		}
+		found = append(found, mention{kind: kind, text: strings.TrimSpace(text[cue[0]:cue[1]] + body), body: body})
+	}
+	return found
+}
+
+// chapterRemedy is the remedy a section's chapter heading names, if any.
+func chapterRemedy(section Section) (string, bool) {
+	if len(section.Path) == 0 {
+		return "", false
+	}
+	for _, entity := range entities.Recognize(section.Path[0]) {
+		if entity.Kind == entities.KindRemedy {
+			return entity.Name, true
+		}
+	}
+	return "", false
+}
+
+// headingTargets are the sections whose own heading text names. Of several with the same
+// heading, such as the "Mind" section of every remedy, those in from's chapter are
+// meant, or else the first in the document. ordered is in document order.
+func headingTargets(text string, from Section, ordered []Section) []Section {
+	byHeading := make(map[string][]Section)
+	var headings []string
+	for _, target := range ordered {
+		heading := strings.ToLower(lastHeading(target))
+		if len(heading) < minHeadingLength || !namesHeading(text, heading) {
+			continue
+		}
+		if _, ok := byHeading[heading]; !ok {
+			headings = append(headings, heading)
+		}
+		byHeading[heading] = append(byHeading[heading], target)
+	}
+
+	var targets []Section
+	for _, heading := range headings {
+		candidates := byHeading[heading]
+		i := slices.IndexFunc(candidates, func(target Section) bool { return sameChapter(target, from) })
+		targets = append(targets, candidates[max(i, 0)])
+	}
+	return targets
+}
+
+func sameChapter(a, b Section) bool {
+	return len(a.Path) > 0 && len(b.Path) > 0 && strings.TrimSpace(a.Path[0]) != "" && a.Path[0] == b.Path[0]
+}
+
+func lastHeading(section Section) string {
+	if len(section.Path) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(section.Path[len(section.Path)-1])
+}
+
+// namesHeading reports whether text has heading as whole words, ignoring case.
+func namesHeading(text, heading string) bool {
+	text, heading = strings.ToLower(text), strings.ToLower(heading)
+	for i := 0; ; {
+		at := strings.Index(text[i:], heading)
+		if at < 0 {
+			return false
+		}
+		start, end := i+at, i+at+len(heading)
+		if (start == 0 || !isWordByte(text[start-1])) && (end == len(text) || !isWordByte(text[end])) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= '0' && b <= '9' || b >= 0x80
+}
+
+// chapterNeighbours are the sections of section's chapter nearest to it in the document.
+func chapterNeighbours(section Section, sections []Section) []Section {
+	var chapter []Section
+	for _, other := range sections {
+		if other.ID != section.ID && sameChapter(other, section) {
+			chapter = append(chapter, other)
+		}
+	}
+	distance := func(s Section) int { return max(s.Index-section.Index, section.Index-s.Index) }
+	slices.SortStableFunc(chapter, func(a, b Section) int {
+		return cmp.Or(cmp.Compare(distance(a), distance(b)), cmp.Compare(a.Index, b.Index))
+	})
+	return chapter[:min(len(chapter), maxChapterLinks)]
+}
+
+func byIndex(sections []Section) []Section {
+	sorted := slices.Clone(sections)
+	slices.SortStableFunc(sorted, func(a, b Section) int { return cmp.Compare(a.Index, b.Index) })
+	return sorted
+}
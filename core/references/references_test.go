@@ -0,0 +1,45 @@
+package references
+
+import (
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMentions(t *testing.T) {
+	found := mentions("Fear of death. Compare Gels., Bry. and Nux; worse at night (see also Fevers). We see that it works.")
+
+	var texts []string
+	for _, m := range found {
+		texts = append(texts, m.kind+"="+m.text)
+	}
+	assert.Equal(t, []string{"compare=Compare Gels., Bry. and Nux", "reference=see also Fevers", "reference=see that it works."}, texts)
+}
+
+func TestLink(t *testing.T) {
+	sections := []Section{
+		{ID: "acon-mind", Index: 1, Path: []string{"Aconitum Napellus", "Mind"}, Text: "Great fear; compare Gels. (see also Fevers)."},
+		{ID: "acon-fever", Index: 2, Path: []string{"Aconitum Napellus", "Fevers"}, Text: "Dry burning heat."},
+		{ID: "acon-mod", Index: 3, Path: []string{"Aconitum Napellus", "Modalities"}, Text: "Worse at night."},
+		{ID: "gels-mind", Index: 4, Path: []string{"Gelsemium", "Mind"}, Text: "Dullness. See Mind of Aconite."},
+		{ID: "gels-fever", Index: 5, Path: []string{"Gelsemium", "Fevers"}, Text: "Chill up the back."},
+	}
+	links := Link(sections)
+
+	assert.Equal(t, []db.ChunkLink{
+		{SectionID: "gels-mind", Kind: KindCompare, Text: "compare Gels."},
+		{SectionID: "acon-fever", Kind: KindReference, Text: "see also Fevers"},
+		{SectionID: "acon-mod", Kind: KindChapter},
+	}, links["acon-mind"], "a heading in several chapters means the section's own, and is linked once")
+
+	assert.Equal(t, []db.ChunkLink{
+		{SectionID: "acon-mind", Kind: KindReference, Text: "See Mind of Aconite."},
+		{SectionID: "gels-fever", Kind: KindChapter},
+	}, links["gels-mind"], "a remedy links to the first section of its chapter")
+
+	assert.Equal(t, []db.ChunkLink{
+		{SectionID: "acon-mind", Kind: KindChapter},
+		{SectionID: "acon-mod", Kind: KindChapter},
+	}, links["acon-fever"])
+}
@@ -0,0 +1,73 @@
+package repertory
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/go-collection-boot/ds"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/entities"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Report is what Import did.
+type Report struct {
+	Rubrics  int      // saved
+	Remedies int      // remedy entries across the rubrics
+	Removed  int      // rubrics of the repertory the import no longer has
+	Unknown  []string // remedy names entities.RemedyNames doesn't know, sorted
+}
+
+// Import replaces a repertory's rubrics in the tenant's repertory collection with
+// rubrics, as Parse reads them. Rubrics keep their IDs when the repertory is imported
+// again; those the import no longer has are deleted. Other repertories' rubrics are
+// left alone. The tenant's cached answers are dropped, since they may be built on the
+// rubrics replaced.
+func Import(ctx context.Context, mongo odm.MongoClient, tenant, repertory string, rubrics []db.RubricModel) (Report, error) {
+	if len(rubrics) == 0 {
+		// never empty a repertory by importing the wrong file
+		return Report{}, errors.New("no rubrics to import")
+	}
+
+	report := Report{}
+	unknown := ds.NewSet[string]()
+	ids := make([]string, 0, len(rubrics))
+	repo := odm.CollectionOf[db.RubricModel](mongo, tenant)
+	now := time.Now().Unix()
+	for _, rubric := range rubrics {
+		rubric.Repertory = repertory
+		rubric.RubricID = RubricID(repertory, rubric.Rubric)
+		rubric.ImportedOn = now
+		if _, err := async.Await(repo.Save(ctx, rubric)); err != nil {
+			return report, errors.New("failed to save rubric " + rubric.Rubric + ": " + err.Error())
+		}
+		ids = append(ids, rubric.RubricID)
+		report.Rubrics++
+		report.Remedies += len(rubric.Remedies)
+		for _, remedy := range rubric.Remedies {
+			if _, known := entities.RemedyName(remedy.Name); !known {
+				unknown.Add(remedy.Name)
+			}
+		}
+	}
+	report.Unknown = unknown.ToSlice()
+	slices.Sort(report.Unknown)
+
+	removed, err := mongo.Database(tenant).Collection(db.RubricModel{}.CollectionName()).DeleteMany(ctx, bson.M{
+		"repertory": repertory,
+		"_id":       bson.M{"$nin": ids},
+	})
+	if err != nil {
+		return report, errors.New("failed to remove old rubrics: " + err.Error())
+	}
+	report.Removed = int(removed.DeletedCount)
+
+	if err := db.InvalidateAnswerCache(ctx, mongo, tenant); err != nil {
+		return report, errors.New("failed to invalidate answer cache: " + err.Error())
+	}
+	return report, nil
+}
@@ -0,0 +1,530 @@
+// Package repertory imports structured repertories, rubrics with the remedies listed
+// under them and their grades, into a tenant's repertory collection. The agent's
+// repertory tool looks symptoms up there and ranks remedies by their grades.
+package repertory
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/entities"
+)
+
+// Formats of a repertory file.
+const (
+	FormatText = "text" // a rubric per line, indented under its parents: "Fear: ACON., *Bell.*, Calc."
+	FormatCSV  = "csv"  // a row per rubric, or per remedy of a rubric
+	FormatTSV  = "tsv"
+	FormatJSON = "json" // RubricModel objects, in an array or one per line
+)
+
+// MaxGrade is the grade of the remedies a repertory marks most strongly, as rubrics
+// store them; the weakest are grade 1.
+const MaxGrade = 3
+
+const (
+	pathSeparator   = "; "
+	synonymSplitter = "|"
+	tabWidth        = 4
+)
+
+// explicitGrade is a remedy written with its grade in brackets, "Bell. (2)" or "Bell.[2]".
+var explicitGrade = regexp.MustCompile(`^(.+?)\s*[(\[]\s*(\d+)\s*[)\]]$`)
+
+// emphasis is a remedy written in markdown bold or italics, maybe with its full stop
+// after the marks: "**Acon.**", "*Bell*." or "_Bell._".
+var emphasis = regexp.MustCompile(`^(\*\*|\*|_)(.+?)(\*\*|\*|_)(\.?)$`)
+
+// Options say how to read a repertory file.
+type Options struct {
+	Repertory string // the repertory's name, e.g. "Kent"; required
+	Format    string // FormatOf the file's name when empty
+	// MaxGrade is the highest grade the file's numbers use, such as 4 in Synthesis.
+	// Grades are scaled from it to 1 to MaxGrade; 3 when zero.
+	MaxGrade int
+}
+
+// FormatOf returns the format of a repertory file by its extension: .txt or .md files
+// are text, .jsonl files JSON. It is empty for other extensions.
+func FormatOf(fileName string) string {
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".txt", ".md":
+		return FormatText
+	case ".csv":
+		return FormatCSV
+	case ".tsv":
+		return FormatTSV
+	case ".json", ".jsonl":
+		return FormatJSON
+	}
+	return ""
+}
+
+// Parse reads the rubrics of a repertory file. Rubrics written more than once are
+// merged, keeping each remedy's highest grade. Each rubric's remedies are sorted by
+// grade, highest first, then by name. Rubrics without remedies are left out.
+//
+// Remedies are named by the Latin name entities.RemedyNames gives any of their names,
+// keeping the abbreviation they were written as. Remedies it doesn't know keep the name
+// they were written as.
+func Parse(r io.Reader, opts Options) ([]db.RubricModel, error) {
+	if strings.TrimSpace(opts.Repertory) == "" {
+		return nil, errors.New("repertory name is required")
+	}
+	if opts.MaxGrade == 0 {
+		opts.MaxGrade = MaxGrade
+	}
+	if opts.MaxGrade < 1 {
+		return nil, errors.New("max grade must be positive")
+	}
+
+	b := &builder{maxGrade: opts.MaxGrade, byKey: map[string]int{}}
+	var err error
+	switch opts.Format {
+	case FormatText:
+		err = b.readText(r)
+	case FormatCSV:
+		err = b.readCSV(r, ',')
+	case FormatTSV:
+		err = b.readCSV(r, '\t')
+	case FormatJSON:
+		err = b.readJSON(r)
+	default:
+		err = errors.New("unknown format " + strconv.Quote(opts.Format) + "; use text, csv, tsv or json")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b.rubrics(strings.TrimSpace(opts.Repertory)), nil
+}
+
+// builder collects rubrics in the order they are first read.
+type builder struct {
+	maxGrade int
+	read     []db.RubricModel
+	byKey    map[string]int // lowercased rubric → index in read
+}
+
+// readText reads rubric lines. A line is a rubric's path, with its headings separated by
+// semicolons, then a colon and its remedies separated by commas. The path continues
+// that of the nearest line above indented less, so a repertory can be written as its
+// book prints it:
+//
+//	MIND
+//	  Fear: Acon., Ars.
+//	    death, of: ACON., *Ars.*, Gels.
+//
+// A line without a colon is a heading only. Blank lines and lines starting with # are
+// skipped.
+func (b *builder) readText(r io.Reader) error {
+	type heading struct {
+		indent int
+		path   []string
+	}
+	var parents []heading
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 4<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		indent := indentOf(text)
+		for len(parents) > 0 && parents[len(parents)-1].indent >= indent {
+			parents = parents[:len(parents)-1]
+		}
+		name, list, hasRemedies := strings.Cut(trimmed, ":")
+		rubric := splitPath(name)
+		if len(rubric) == 0 {
+			return lineError(line, errors.New("rubric is empty"))
+		}
+		if len(parents) > 0 {
+			rubric = append(slices.Clone(parents[len(parents)-1].path), rubric...)
+		}
+		parents = append(parents, heading{indent: indent, path: rubric})
+
+		if !hasRemedies {
+			continue
+		}
+		remedies, err := b.parseRemedies(list)
+		if err != nil {
+			return lineError(line, err)
+		}
+		b.add(rubric, nil, remedies)
+	}
+	return scanner.Err()
+}
+
+// readCSV reads rows with a header naming their columns, in any order and case:
+//   - rubric: the rubric's path, required.
+//   - chapter: put before the path, unless it starts with it.
+//   - remedies: remedies as a text line lists them, "ACON., *Bell.*, Calc.".
+//   - remedy, grade and abbreviation: one remedy; without a grade, it is read from how
+//     the remedy is written.
+//   - synonyms: plain-language phrasings of the symptom, separated by |.
+//
+// A rubric's remedies can be listed in one row or over several.
+func (b *builder) readCSV(r io.Reader, comma rune) error {
+	reader := csv.NewReader(r)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.LazyQuotes = comma == '\t'
+
+	header, err := reader.Read()
+	if err != nil {
+		return errors.New("failed to read header: " + err.Error())
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["rubric"]; !ok {
+		return errors.New("header has no rubric column")
+	}
+	_, hasRemedy := columns["remedy"]
+	if _, ok := columns["remedies"]; !ok && !hasRemedy {
+		return errors.New("header has no remedy or remedies column")
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err // a csv.ParseError names its line
+		}
+		line, _ := reader.FieldPos(0)
+		get := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		rubric := splitPath(get("rubric"))
+		if len(rubric) == 0 {
+			return lineError(line, errors.New("rubric is empty"))
+		}
+		if chapter := unshout(get("chapter")); chapter != "" && !strings.EqualFold(rubric[0], chapter) {
+			rubric = append([]string{chapter}, rubric...)
+		}
+
+		remedies, err := b.parseRemedies(get("remedies"))
+		if err != nil {
+			return lineError(line, err)
+		}
+		if written := get("remedy"); written != "" {
+			grade := 0
+			if number := get("grade"); number != "" {
+				n, err := strconv.Atoi(number)
+				if err != nil {
+					return lineError(line, errors.New("grade "+strconv.Quote(number)+" is not a number"))
+				}
+				if grade, err = scaleGrade(n, b.maxGrade); err != nil {
+					return lineError(line, err)
+				}
+			} else if written, grade, err = b.remedyGrade(written); err != nil {
+				return lineError(line, err)
+			}
+			remedies = append(remedies, newRemedy(written, get("abbreviation"), grade))
+		}
+
+		var synonyms []string
+		for _, synonym := range strings.Split(get("synonyms"), synonymSplitter) {
+			if synonym = strings.TrimSpace(synonym); synonym != "" {
+				synonyms = append(synonyms, synonym)
+			}
+		}
+		b.add(rubric, synonyms, remedies)
+	}
+}
+
+// readJSON reads RubricModel objects, as an array or one after another as in JSON
+// Lines. Their rubric, chapter, synonyms and remedies are read; a remedy's grade is 1
+// when it has none.
+func (b *builder) readJSON(r io.Reader) error {
+	reader := bufio.NewReader(r)
+	decoder := json.NewDecoder(reader)
+	var next func(*db.RubricModel) (bool, error)
+	if first, err := firstByte(reader); err != nil {
+		return err
+	} else if first == '[' {
+		var rubrics []db.RubricModel
+		if err := decoder.Decode(&rubrics); err != nil {
+			return errors.New("failed to read rubrics: " + err.Error())
+		}
+		next = func(rubric *db.RubricModel) (bool, error) {
+			if len(rubrics) == 0 {
+				return false, nil
+			}
+			*rubric, rubrics = rubrics[0], rubrics[1:]
+			return true, nil
+		}
+	} else {
+		next = func(rubric *db.RubricModel) (bool, error) {
+			*rubric = db.RubricModel{}
+			if err := decoder.Decode(rubric); err != nil {
+				if err == io.EOF {
+					return false, nil
+				}
+				return false, err
+			}
+			return true, nil
+		}
+	}
+
+	for i := 1; ; i++ {
+		var rubric db.RubricModel
+		ok, err := next(&rubric)
+		if err != nil {
+			return errors.New("rubric " + strconv.Itoa(i) + ": " + err.Error())
+		}
+		if !ok {
+			return nil
+		}
+
+		path := splitPath(rubric.Rubric)
+		if len(path) == 0 {
+			return errors.New("rubric " + strconv.Itoa(i) + ": rubric is empty")
+		}
+		if chapter := unshout(strings.TrimSpace(rubric.Chapter)); chapter != "" && !strings.EqualFold(path[0], chapter) {
+			path = append([]string{chapter}, path...)
+		}
+		remedies := make([]db.RubricRemedy, 0, len(rubric.Remedies))
+		for _, remedy := range rubric.Remedies {
+			if strings.TrimSpace(remedy.Name) == "" {
+				return errors.New("rubric " + strconv.Itoa(i) + ": remedy has no name")
+			}
+			grade, err := scaleGrade(max(remedy.Grade, 1), b.maxGrade)
+			if err != nil {
+				return errors.New("rubric " + strconv.Itoa(i) + ": " + err.Error())
+			}
+			remedies = append(remedies, newRemedy(strings.TrimSpace(remedy.Name), strings.TrimSpace(remedy.Abbreviation), grade))
+		}
+		b.add(path, rubric.Synonyms, remedies)
+	}
+}
+
+// parseRemedies reads a comma-separated list of remedies, each graded as remedyGrade
+// reads it.
+func (b *builder) parseRemedies(list string) ([]db.RubricRemedy, error) {
+	var remedies []db.RubricRemedy
+	for _, written := range strings.Split(list, ",") {
+		if written = strings.TrimSpace(written); written == "" {
+			continue
+		}
+		name, grade, err := b.remedyGrade(written)
+		if err != nil {
+			return nil, err
+		}
+		remedies = append(remedies, newRemedy(name, "", grade))
+	}
+	return remedies, nil
+}
+
+// remedyGrade reads a remedy's grade from how it is written: a number in brackets,
+// "Bell. (2)", scaled from the file's highest grade; or else as printed repertories
+// mark it, bold or capitals for grade 3, italics for 2 and plain type for 1. It
+// returns the remedy's name without the marks.
+func (b *builder) remedyGrade(written string) (string, int, error) {
+	if match := explicitGrade.FindStringSubmatch(written); match != nil {
+		n, _ := strconv.Atoi(match[2])
+		grade, err := scaleGrade(n, b.maxGrade)
+		return unshout(match[1]), grade, err
+	}
+	if match := emphasis.FindStringSubmatch(written); match != nil && match[1] == match[3] {
+		name := strings.TrimSpace(match[2]) + match[4]
+		if match[1] == "**" {
+			return unshout(name), MaxGrade, nil
+		}
+		return unshout(name), 2, nil
+	}
+	if isCapitals(written) {
+		return unshout(written), MaxGrade, nil
+	}
+	return written, 1, nil
+}
+
+// scaleGrade scales grade n of a repertory whose highest grade is maxGrade to 1 to
+// MaxGrade, so 1 to 4 of Synthesis are 1, 2, 2 and 3.
+func scaleGrade(n, maxGrade int) (int, error) {
+	if n < 1 || n > maxGrade {
+		return 0, errors.New("grade " + strconv.Itoa(n) + " is not between 1 and " + strconv.Itoa(maxGrade))
+	}
+	if maxGrade == 1 {
+		return 1, nil
+	}
+	return 1 + int(math.Round(float64((n-1)*(MaxGrade-1))/float64(maxGrade-1))), nil
+}
+
+// newRemedy names a remedy written as written by its Latin name when it is known, by
+// written or else by its abbreviation. An abbreviation is kept, or taken from written.
+func newRemedy(written, abbreviation string, grade int) db.RubricRemedy {
+	name, known := entities.RemedyName(written)
+	if !known && abbreviation != "" {
+		name, known = entities.RemedyName(abbreviation)
+	}
+	if !known {
+		name = written
+	}
+	if abbreviation == "" && known && strings.HasSuffix(written, ".") {
+		abbreviation = written
+	}
+	return db.RubricRemedy{Name: name, Abbreviation: abbreviation, Grade: grade}
+}
+
+// add merges a rubric into those read, keeping the highest grade of a remedy listed
+// twice.
+func (b *builder) add(path, synonyms []string, remedies []db.RubricRemedy) {
+	rubric := strings.Join(path, pathSeparator)
+	key := strings.ToLower(rubric)
+	i, ok := b.byKey[key]
+	if !ok {
+		i = len(b.read)
+		b.byKey[key] = i
+		b.read = append(b.read, db.RubricModel{Chapter: path[0], Rubric: rubric})
+	}
+	read := &b.read[i]
+
+	for _, synonym := range synonyms {
+		if !slices.ContainsFunc(read.Synonyms, func(s string) bool { return strings.EqualFold(s, synonym) }) {
+			read.Synonyms = append(read.Synonyms, synonym)
+		}
+	}
+	for _, remedy := range remedies {
+		j := slices.IndexFunc(read.Remedies, func(r db.RubricRemedy) bool { return r.Name == remedy.Name })
+		if j < 0 {
+			read.Remedies = append(read.Remedies, remedy)
+			continue
+		}
+		read.Remedies[j].Grade = max(read.Remedies[j].Grade, remedy.Grade)
+		if read.Remedies[j].Abbreviation == "" {
+			read.Remedies[j].Abbreviation = remedy.Abbreviation
+		}
+	}
+}
+
+// rubrics returns the rubrics read that list remedies, with their IDs, each remedy
+// list sorted by grade.
+func (b *builder) rubrics(repertory string) []db.RubricModel {
+	rubrics := make([]db.RubricModel, 0, len(b.read))
+	for _, rubric := range b.read {
+		if len(rubric.Remedies) == 0 {
+			continue
+		}
+		rubric.Repertory = repertory
+		rubric.RubricID = RubricID(repertory, rubric.Rubric)
+		slices.SortStableFunc(rubric.Remedies, func(a, b db.RubricRemedy) int {
+			if a.Grade != b.Grade {
+				return b.Grade - a.Grade
+			}
+			return strings.Compare(a.Name, b.Name)
+		})
+		rubrics = append(rubrics, rubric)
+	}
+	return rubrics
+}
+
+// RubricID is the ID of a repertory's rubric, the same whenever the repertory is
+// imported again, whatever the rubric's case.
+func RubricID(repertory, rubric string) string {
+	id, _ := odm.HashedKey(strings.ToLower(repertory), strings.ToLower(rubric))
+	return id
+}
+
+// splitPath splits a rubric's path at its semicolons. A heading printed in capitals,
+// as repertories print chapters, is written in title case.
+func splitPath(rubric string) []string {
+	var path []string
+	for _, heading := range strings.Split(rubric, ";") {
+		if heading = strings.TrimSpace(heading); heading != "" {
+			path = append(path, unshout(heading))
+		}
+	}
+	return path
+}
+
+// unshout writes a word in capitals with only its first letter capital: "NAT-M." is
+// "Nat-m.". Other text is returned as it is.
+func unshout(text string) string {
+	if !isCapitals(text) {
+		return text
+	}
+	runes := []rune(strings.ToLower(text))
+	for i, r := range runes {
+		if unicode.IsLetter(r) {
+			runes[i] = unicode.ToUpper(r)
+			break
+		}
+	}
+	return string(runes)
+}
+
+// isCapitals reports whether text has at least two letters and none in lower case.
+func isCapitals(text string) bool {
+	letters := 0
+	for _, r := range text {
+		if unicode.IsLower(r) {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	return letters >= 2
+}
+
+func indentOf(line string) int {
+	indent := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			indent++
+		case '\t':
+			indent += tabWidth
+		default:
+			return indent
+		}
+	}
+	return indent
+}
+
+func firstByte(reader *bufio.Reader) (byte, error) {
+	for {
+		c, err := reader.ReadByte()
+		if err == io.EOF {
+			return 0, errors.New("file is empty")
+		}
+		if err != nil {
+			return 0, err
+		}
+		if !unicode.IsSpace(rune(c)) && c != 0xEF && c != 0xBB && c != 0xBF { // and a UTF-8 BOM
+			return c, reader.UnreadByte()
+		}
+	}
+}
+
+func lineError(line int, err error) error {
+	return errors.New("line " + strconv.Itoa(line) + ": " + err.Error())
+}
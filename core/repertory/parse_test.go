@@ -0,0 +1,128 @@
+package repertory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseText(t *testing.T) {
+	text := `# Kent, Mind chapter
+MIND
+  Fear: Acon., Ars. (2), Calc.
+    death, of: ACON., *Ars.*, Gels., Zinc.
+      alone, when: **Ars.**, Phos.(1)
+  Anxiety
+    night: Ars., acon.
+
+Mind; Fear: Bell.
+`
+	rubrics, err := Parse(strings.NewReader(text), Options{Repertory: "Kent", Format: FormatText})
+	require.NoError(t, err)
+	require.Len(t, rubrics, 4, "a heading without remedies is not a rubric")
+
+	fear := rubrics[0]
+	assert.Equal(t, "Mind; Fear", fear.Rubric)
+	assert.Equal(t, "Mind", fear.Chapter)
+	assert.Equal(t, "Kent", fear.Repertory)
+	assert.Equal(t, RubricID("Kent", "mind; fear"), fear.RubricID)
+	assert.Equal(t, []db.RubricRemedy{
+		{Name: "Arsenicum album", Abbreviation: "Ars.", Grade: 2},
+		{Name: "Aconitum napellus", Abbreviation: "Acon.", Grade: 1},
+		{Name: "Belladonna", Abbreviation: "Bell.", Grade: 1},
+		{Name: "Calcarea carbonica", Abbreviation: "Calc.", Grade: 1},
+	}, fear.Remedies, "the rubric written again is merged")
+
+	death := rubrics[1]
+	assert.Equal(t, "Mind; Fear; death, of", death.Rubric)
+	assert.Equal(t, []db.RubricRemedy{
+		{Name: "Aconitum napellus", Abbreviation: "Acon.", Grade: 3},
+		{Name: "Arsenicum album", Abbreviation: "Ars.", Grade: 2},
+		{Name: "Gelsemium sempervirens", Abbreviation: "Gels.", Grade: 1},
+		{Name: "Zinc.", Grade: 1},
+	}, death.Remedies)
+
+	alone := rubrics[2]
+	assert.Equal(t, "Mind; Fear; death, of; alone, when", alone.Rubric)
+	assert.Equal(t, 3, alone.Remedies[0].Grade)
+
+	assert.Equal(t, "Mind; Anxiety; night", rubrics[3].Rubric)
+
+	_, err = Parse(strings.NewReader("Mind; Fear: Acon. (4)"), Options{Repertory: "Kent", Format: FormatText})
+	assert.EqualError(t, err, "line 1: grade 4 is not between 1 and 3")
+}
+
+func TestParseGrades(t *testing.T) {
+	text := "Mind; Fear: Acon.(1), Ars.(2), Bell.(3), Calc.(4)"
+	rubrics, err := Parse(strings.NewReader(text), Options{Repertory: "Synthesis", Format: FormatText, MaxGrade: 4})
+	require.NoError(t, err)
+
+	grades := map[string]int{}
+	for _, remedy := range rubrics[0].Remedies {
+		grades[remedy.Abbreviation] = remedy.Grade
+	}
+	assert.Equal(t, map[string]int{"Acon.": 1, "Ars.": 2, "Bell.": 2, "Calc.": 3}, grades)
+}
+
+func TestParseCSV(t *testing.T) {
+	csv := `Chapter,Rubric,Remedy,Grade,Abbreviation,Synonyms
+MIND,Fear; death of,Aconite,3,Acon.,fear of dying|afraid of death
+Mind,Fear; death of,Arsenicum album,2,,
+Mind,"Fear; death of",Zincum metallicum,1,Zinc.,afraid of death
+`
+	rubrics, err := Parse(strings.NewReader(csv), Options{Repertory: "Kent", Format: FormatCSV})
+	require.NoError(t, err)
+	require.Len(t, rubrics, 1)
+	assert.Equal(t, "Mind; Fear; death of", rubrics[0].Rubric)
+	assert.Equal(t, []string{"fear of dying", "afraid of death"}, rubrics[0].Synonyms)
+	assert.Equal(t, []db.RubricRemedy{
+		{Name: "Aconitum napellus", Abbreviation: "Acon.", Grade: 3},
+		{Name: "Arsenicum album", Grade: 2},
+		{Name: "Zincum metallicum", Abbreviation: "Zinc.", Grade: 1},
+	}, rubrics[0].Remedies)
+
+	tsv := "rubric\tremedies\nStomach; Thirst; large quantities, for\tBRY., *Phos.*, Nat-m.\n"
+	rubrics, err = Parse(strings.NewReader(tsv), Options{Repertory: "Kent", Format: FormatTSV})
+	require.NoError(t, err)
+	assert.Equal(t, []db.RubricRemedy{
+		{Name: "Bryonia alba", Abbreviation: "Bry.", Grade: 3},
+		{Name: "Phosphorus", Abbreviation: "Phos.", Grade: 2},
+		{Name: "Natrum muriaticum", Abbreviation: "Nat-m.", Grade: 1},
+	}, rubrics[0].Remedies)
+
+	_, err = Parse(strings.NewReader("rubric,remedy,grade\nMind; Fear,Acon.,high\n"), Options{Repertory: "Kent", Format: FormatCSV})
+	assert.EqualError(t, err, `line 2: grade "high" is not a number`)
+	_, err = Parse(strings.NewReader("symptom,remedy\n"), Options{Repertory: "Kent", Format: FormatCSV})
+	assert.EqualError(t, err, "header has no rubric column")
+}
+
+func TestParseJSON(t *testing.T) {
+	array := `[{"chapter": "Mind", "rubric": "Fear; sudden", "synonyms": ["sudden fright"],
+		"remedies": [{"name": "Acon.", "grade": 3}, {"name": "Opium"}]}]`
+	lines := `{"rubric": "Mind; Fear; sudden", "synonyms": ["sudden fright"], "remedies": [{"name": "Acon.", "grade": 3}, {"name": "Opium"}]}
+`
+	for _, input := range []string{array, lines} {
+		rubrics, err := Parse(strings.NewReader(input), Options{Repertory: "Boger", Format: FormatJSON})
+		require.NoError(t, err)
+		require.Len(t, rubrics, 1)
+		assert.Equal(t, "Mind; Fear; sudden", rubrics[0].Rubric)
+		assert.Equal(t, []string{"sudden fright"}, rubrics[0].Synonyms)
+		assert.Equal(t, []db.RubricRemedy{
+			{Name: "Aconitum napellus", Abbreviation: "Acon.", Grade: 3},
+			{Name: "Opium", Grade: 1},
+		}, rubrics[0].Remedies)
+	}
+
+	_, err := Parse(strings.NewReader(`[{"rubric": "Mind; Fear", "remedies": [{"grade": 2}]}]`), Options{Repertory: "Boger", Format: FormatJSON})
+	assert.EqualError(t, err, "rubric 1: remedy has no name")
+}
+
+func TestFormatOf(t *testing.T) {
+	assert.Equal(t, FormatText, FormatOf("kent.TXT"))
+	assert.Equal(t, FormatCSV, FormatOf("exports/kent.csv"))
+	assert.Equal(t, FormatJSON, FormatOf("boger.jsonl"))
+	assert.Empty(t, FormatOf("kent.pdf"))
+}
@@ -0,0 +1,710 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/cloud"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/backup"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
+	"github.com/SaiNageswarS/medicine-rag/core/ingest"
+	"github.com/SaiNageswarS/medicine-rag/core/prompts"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const adminKeyHeader = "x-admin-key"
+
+const (
+	maxAccessGroups      = 20 // per user or document
+	maxAccessGroupLength = 64
+)
+
+// Corpus archives are stored under this prefix of the tenant's bucket.
+const corpusExportPrefix = "exports/"
+
+type AdminService struct {
+	pb.UnimplementedAdminServer
+	mongo     odm.MongoClient
+	az        cloud.Cloud
+	embedders *embedding.Registry
+	streams   *StreamRegistry
+	scheduler *SourceScheduler
+}
+
+func ProvideAdminService(mongo odm.MongoClient, az cloud.Cloud, embedders *embedding.Registry, streams *StreamRegistry, scheduler *SourceScheduler) *AdminService {
+	return &AdminService{
+		mongo:     mongo,
+		az:        az,
+		embedders: embedders,
+		streams:   streams,
+		scheduler: scheduler,
+	}
+}
+
+// Operators work across tenants, so the admin API takes a shared key instead of a
+// tenant login. Without ADMIN_API_KEY in the environment the API is disabled.
+func (s *AdminService) AuthFuncOverride(ctx context.Context, fullMethodName string) (context.Context, error) {
+	key := os.Getenv("ADMIN_API_KEY")
+	if key == "" {
+		return nil, status.Error(codes.PermissionDenied, "Admin API is disabled")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	provided := md.Get(adminKeyHeader)
+	if len(provided) == 0 || subtle.ConstantTimeCompare([]byte(provided[0]), []byte(key)) != 1 {
+		return nil, status.Error(codes.Unauthenticated, "Invalid admin key")
+	}
+
+	return ctx, nil
+}
+
+func (s *AdminService) ListActiveStreams(ctx context.Context, req *pb.ListActiveStreamsRequest) (*pb.ListActiveStreamsResponse, error) {
+	now := time.Now()
+
+	resp := &pb.ListActiveStreamsResponse{}
+	for _, run := range s.streams.List(req.Tenant) {
+		resp.Streams = append(resp.Streams, activeStreamProto(run, now))
+	}
+
+	return resp, nil
+}
+
+func (s *AdminService) TerminateStream(ctx context.Context, req *pb.TerminateStreamRequest) (*pb.TerminateStreamResponse, error) {
+	if req.RunId == "" {
+		return nil, status.Error(codes.InvalidArgument, "runId is required")
+	}
+
+	run, ok := s.streams.Terminate(req.RunId)
+	if !ok {
+		return nil, status.Error(codes.NotFound, "No active stream with this runId")
+	}
+
+	logger.Info("Terminated agent run",
+		zap.String("runId", run.ID),
+		zap.String("tenant", run.Tenant),
+		zap.String("userId", run.UserID),
+		zap.String("stage", run.Stage),
+		zap.Duration("age", time.Since(run.Started)),
+		zap.String("reason", req.Reason))
+
+	return &pb.TerminateStreamResponse{Stream: activeStreamProto(run, time.Now())}, nil
+}
+
+func (s *AdminService) ListContentViolations(ctx context.Context, req *pb.ListContentViolationsRequest) (*pb.ListContentViolationsResponse, error) {
+	if req.Tenant == "" {
+		return nil, status.Error(codes.InvalidArgument, "tenant is required")
+	}
+
+	limit := int64(req.Limit)
+	if limit <= 0 {
+		limit = 50
+	}
+	limit = min(limit, 500)
+
+	filter := bson.M{}
+	if req.Rule != "" {
+		filter["rule"] = req.Rule
+	}
+
+	repo := odm.CollectionOf[db.ContentViolationModel](s.mongo, req.Tenant)
+	violations, err := async.Await(repo.Find(ctx, filter, bson.D{{Key: "createdOn", Value: -1}}, limit, 0))
+	if err != nil {
+		logger.Error("Failed to list content violations", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to list content violations")
+	}
+
+	resp := &pb.ListContentViolationsResponse{}
+	for _, violation := range violations {
+		resp.Violations = append(resp.Violations, &pb.ContentViolation{
+			Id:        violation.ViolationID,
+			SessionId: violation.SessionID,
+			UserId:    violation.UserID,
+			Rule:      violation.Rule,
+			Match:     violation.Match,
+			Action:    violation.Action,
+			Question:  violation.Question,
+			CreatedOn: violation.CreatedOn,
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *AdminService) ListIngestionJobs(ctx context.Context, req *pb.ListIngestionJobsRequest) (*pb.ListIngestionJobsResponse, error) {
+	if req.Tenant == "" {
+		return nil, status.Error(codes.InvalidArgument, "tenant is required")
+	}
+
+	limit := int64(req.Limit)
+	if limit <= 0 {
+		limit = 50
+	}
+	limit = min(limit, 500)
+
+	filter := bson.M{}
+	if req.Status != "" {
+		filter["status"] = req.Status
+	}
+
+	repo := odm.CollectionOf[db.IngestionJobModel](s.mongo, req.Tenant)
+	jobs, err := async.Await(repo.Find(ctx, filter, bson.D{{Key: "createdOn", Value: -1}}, limit, 0))
+	if err != nil {
+		logger.Error("Failed to list ingestion jobs", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to list ingestion jobs")
+	}
+
+	resp := &pb.ListIngestionJobsResponse{}
+	for _, job := range jobs {
+		resp.Jobs = append(resp.Jobs, ingestionJobProto(job))
+	}
+
+	return resp, nil
+}
+
+func (s *AdminService) ListSyncRuns(ctx context.Context, req *pb.ListSyncRunsRequest) (*pb.ListSyncRunsResponse, error) {
+	if req.Tenant == "" {
+		return nil, status.Error(codes.InvalidArgument, "tenant is required")
+	}
+
+	limit := int64(req.Limit)
+	if limit <= 0 {
+		limit = 50
+	}
+	limit = min(limit, 500)
+
+	filter := bson.M{}
+	if req.Source != "" {
+		filter["source"] = req.Source
+	}
+
+	repo := odm.CollectionOf[db.SyncRunModel](s.mongo, req.Tenant)
+	runs, err := async.Await(repo.Find(ctx, filter, bson.D{{Key: "startedOn", Value: -1}}, limit, 0))
+	if err != nil {
+		logger.Error("Failed to list sync runs", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to list sync runs")
+	}
+
+	resp := &pb.ListSyncRunsResponse{}
+	for _, run := range runs {
+		resp.Runs = append(resp.Runs, syncRunProto(run))
+	}
+
+	return resp, nil
+}
+
+func (s *AdminService) RunSourceSync(ctx context.Context, req *pb.RunSourceSyncRequest) (*pb.SyncRun, error) {
+	if req.Tenant == "" || req.Source == "" {
+		return nil, status.Error(codes.InvalidArgument, "tenant and source are required")
+	}
+
+	source, err := ingest.TenantSource(ctx, s.mongo, req.Tenant, req.Source)
+	if errors.Is(err, ingest.ErrSourceNotConfigured) {
+		return nil, status.Error(codes.NotFound, "Tenant has no source of that name")
+	}
+	if err != nil {
+		logger.Error("Failed to load tenant source", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load tenant source")
+	}
+
+	run, err := s.scheduler.Start(ctx, req.Tenant, source, db.SyncTriggerManual, time.Time{})
+	if errors.Is(err, ErrSyncRunning) {
+		return nil, status.Error(codes.FailedPrecondition, "Source is already syncing")
+	}
+	if err != nil {
+		logger.Error("Failed to start source sync", zap.String("tenant", req.Tenant),
+			zap.String("source", req.Source), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to start source sync")
+	}
+
+	return syncRunProto(*run), nil
+}
+
+func (s *AdminService) GetSystemPrompt(ctx context.Context, req *pb.GetSystemPromptRequest) (*pb.SystemPrompt, error) {
+	if req.Tenant == "" {
+		return nil, status.Error(codes.InvalidArgument, "tenant is required")
+	}
+
+	config, err := loadAgentConfig(ctx, s.mongo, req.Tenant)
+	if err != nil {
+		logger.Error("Failed to load agent config", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load agent config")
+	}
+
+	return systemPromptProto(req.Tenant, config), nil
+}
+
+func (s *AdminService) UpdateSystemPrompt(ctx context.Context, req *pb.UpdateSystemPromptRequest) (*pb.SystemPrompt, error) {
+	if req.Tenant == "" {
+		return nil, status.Error(codes.InvalidArgument, "tenant is required")
+	}
+
+	template := strings.TrimSpace(req.Template)
+	if problems := prompts.ValidatePromptTemplate(template, req.Variables); len(problems) > 0 {
+		return nil, status.Error(codes.InvalidArgument, errors.Join(problems...).Error())
+	}
+
+	// The stored document is updated as is, without the defaults loadAgentConfig fills in.
+	repo := odm.CollectionOf[db.AgentConfigModel](s.mongo, req.Tenant)
+	stored := &db.AgentConfigModel{ID: db.AgentConfigID}
+	exists, err := async.Await(repo.Exists(ctx, db.AgentConfigID))
+	if err == nil && exists {
+		stored, err = async.Await(repo.FindOneByID(ctx, db.AgentConfigID))
+	}
+	if err != nil {
+		logger.Error("Failed to load agent config", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load agent config")
+	}
+
+	stored.PromptTemplate = template
+	stored.PromptVariables = req.Variables
+	stored.UpdatedOn = time.Now().Unix()
+	if _, err := async.Await(repo.Save(ctx, *stored)); err != nil {
+		logger.Error("Failed to save agent config", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to save agent config")
+	}
+
+	logger.Info("Updated tenant system prompt", zap.String("tenant", req.Tenant), zap.Int("templateLength", len(template)))
+
+	config, err := loadAgentConfig(ctx, s.mongo, req.Tenant)
+	if err != nil {
+		logger.Error("Failed to load agent config", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load agent config")
+	}
+	return systemPromptProto(req.Tenant, config), nil
+}
+
+func (s *AdminService) SetUserRole(ctx context.Context, req *pb.SetUserRoleRequest) (*pb.SetUserRoleResponse, error) {
+	if req.Tenant == "" || req.Email == "" {
+		return nil, status.Error(codes.InvalidArgument, "tenant and email are required")
+	}
+	if req.Role != "" && req.Role != db.RoleAdmin {
+		return nil, status.Error(codes.InvalidArgument, `role must be "admin" or empty`)
+	}
+
+	userId := db.NewLoginModel(req.Email).Id()
+	update := bson.M{"$unset": bson.M{"role": ""}}
+	if req.Role != "" {
+		update = bson.M{"$set": bson.M{"role": req.Role}}
+	}
+	result, err := s.mongo.Database(req.Tenant).Collection(db.LoginModel{}.CollectionName()).UpdateOne(ctx,
+		bson.M{"_id": userId}, update)
+	if err != nil {
+		logger.Error("Failed to set user role", zap.String("tenant", req.Tenant), zap.String("userId", userId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to set user role")
+	}
+	if result.MatchedCount == 0 {
+		return nil, status.Error(codes.NotFound, "User not found")
+	}
+
+	logger.Info("Set user role", zap.String("tenant", req.Tenant), zap.String("userId", userId), zap.String("role", req.Role))
+	return &pb.SetUserRoleResponse{UserId: userId, Role: req.Role}, nil
+}
+
+func (s *AdminService) SetUserAccessGroups(ctx context.Context, req *pb.SetUserAccessGroupsRequest) (*pb.SetUserAccessGroupsResponse, error) {
+	if req.Tenant == "" || req.Email == "" {
+		return nil, status.Error(codes.InvalidArgument, "tenant and email are required")
+	}
+	groups, err := accessGroupsParam(req.AccessGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	userId := db.NewLoginModel(req.Email).Id()
+	update := bson.M{"$unset": bson.M{"accessGroups": ""}}
+	if len(groups) > 0 {
+		update = bson.M{"$set": bson.M{"accessGroups": groups}}
+	}
+	result, err := s.mongo.Database(req.Tenant).Collection(db.LoginModel{}.CollectionName()).UpdateOne(ctx,
+		bson.M{"_id": userId}, update)
+	if err != nil {
+		logger.Error("Failed to set user access groups", zap.String("tenant", req.Tenant), zap.String("userId", userId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to set user access groups")
+	}
+	if result.MatchedCount == 0 {
+		return nil, status.Error(codes.NotFound, "User not found")
+	}
+
+	logger.Info("Set user access groups", zap.String("tenant", req.Tenant), zap.String("userId", userId), zap.Strings("accessGroups", groups))
+	return &pb.SetUserAccessGroupsResponse{UserId: userId, AccessGroups: groups}, nil
+}
+
+// accessGroupsParam normalizes the access groups of a request; see db.NormalizeAccessGroups.
+func accessGroupsParam(groups []string) ([]string, error) {
+	groups = db.NormalizeAccessGroups(groups)
+	if len(groups) > maxAccessGroups {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d access groups are allowed", maxAccessGroups)
+	}
+	for _, group := range groups {
+		if len(group) > maxAccessGroupLength || strings.Contains(group, ",") {
+			return nil, status.Errorf(codes.InvalidArgument, "access group %q must be at most %d characters, without commas", group, maxAccessGroupLength)
+		}
+	}
+	return groups, nil
+}
+
+func (s *AdminService) GetExecutionTrace(ctx context.Context, req *pb.GetExecutionTraceRequest) (*pb.ExecutionTrace, error) {
+	if req.Tenant == "" || req.TraceId == "" {
+		return nil, status.Error(codes.InvalidArgument, "tenant and traceId are required")
+	}
+
+	repo := odm.CollectionOf[db.ExecutionTraceModel](s.mongo, req.Tenant)
+	exists, err := async.Await(repo.Exists(ctx, req.TraceId))
+	if err != nil {
+		logger.Error("Failed to load execution trace", zap.String("tenant", req.Tenant), zap.String("traceId", req.TraceId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load execution trace")
+	}
+	if !exists {
+		return nil, status.Error(codes.NotFound, "No execution trace with this traceId")
+	}
+
+	trace, err := async.Await(repo.FindOneByID(ctx, req.TraceId))
+	if err != nil {
+		logger.Error("Failed to load execution trace", zap.String("tenant", req.Tenant), zap.String("traceId", req.TraceId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load execution trace")
+	}
+
+	return executionTraceProto(req.Tenant, *trace, true), nil
+}
+
+func (s *AdminService) ListExecutionTraces(ctx context.Context, req *pb.ListExecutionTracesRequest) (*pb.ListExecutionTracesResponse, error) {
+	if req.Tenant == "" {
+		return nil, status.Error(codes.InvalidArgument, "tenant is required")
+	}
+
+	limit := int64(req.Limit)
+	if limit <= 0 {
+		limit = 50
+	}
+	limit = min(limit, 500)
+
+	filter := bson.M{}
+	if req.SessionId != "" {
+		filter["sessionId"] = req.SessionId
+	}
+
+	// steps can run to megabytes per trace, so they are not read for a listing
+	collection := s.mongo.Database(req.Tenant).Collection(db.ExecutionTraceModel{}.CollectionName())
+	cursor, err := collection.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "createdOn", Value: -1}}).
+		SetLimit(limit).
+		SetProjection(bson.M{"steps": 0}))
+	var traces []db.ExecutionTraceModel
+	if err == nil {
+		err = cursor.All(ctx, &traces)
+	}
+	if err != nil {
+		logger.Error("Failed to list execution traces", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to list execution traces")
+	}
+
+	resp := &pb.ListExecutionTracesResponse{}
+	for _, trace := range traces {
+		resp.Traces = append(resp.Traces, executionTraceProto(req.Tenant, trace, false))
+	}
+	return resp, nil
+}
+
+func (s *AdminService) GetSourceExclusions(ctx context.Context, req *pb.GetSourceExclusionsRequest) (*pb.SourceExclusions, error) {
+	if req.Tenant == "" {
+		return nil, status.Error(codes.InvalidArgument, "tenant is required")
+	}
+
+	config, err := loadTenantConfig(ctx, s.mongo, req.Tenant)
+	if err != nil {
+		logger.Error("Failed to load tenant config", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load tenant config")
+	}
+	return sourceExclusionsProto(req.Tenant, config), nil
+}
+
+func (s *AdminService) UpdateSourceExclusions(ctx context.Context, req *pb.UpdateSourceExclusionsRequest) (*pb.SourceExclusions, error) {
+	if req.Tenant == "" {
+		return nil, status.Error(codes.InvalidArgument, "tenant is required")
+	}
+
+	config, err := loadTenantConfig(ctx, s.mongo, req.Tenant)
+	if err != nil {
+		logger.Error("Failed to load tenant config", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load tenant config")
+	}
+
+	config.ExcludedDocuments = distinctValues(req.Documents)
+	config.ExcludedAuthors = distinctValues(req.Authors)
+	config.ExclusionsUpdatedOn = time.Now().Unix()
+	if _, err := async.Await(odm.CollectionOf[db.TenantConfigModel](s.mongo, req.Tenant).Save(ctx, *config)); err != nil {
+		logger.Error("Failed to save tenant config", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to save tenant config")
+	}
+
+	logger.Info("Updated source exclusions", zap.String("tenant", req.Tenant),
+		zap.Int("documents", len(config.ExcludedDocuments)), zap.Int("authors", len(config.ExcludedAuthors)))
+	return sourceExclusionsProto(req.Tenant, config), nil
+}
+
+func (s *AdminService) SetShareLinksEnabled(ctx context.Context, req *pb.SetShareLinksEnabledRequest) (*pb.SetShareLinksEnabledResponse, error) {
+	if req.Tenant == "" {
+		return nil, status.Error(codes.InvalidArgument, "tenant is required")
+	}
+
+	config, err := loadTenantConfig(ctx, s.mongo, req.Tenant)
+	if err != nil {
+		logger.Error("Failed to load tenant config", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load tenant config")
+	}
+
+	config.ShareLinksEnabled = req.Enabled
+	if _, err := async.Await(odm.CollectionOf[db.TenantConfigModel](s.mongo, req.Tenant).Save(ctx, *config)); err != nil {
+		logger.Error("Failed to save tenant config", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to save tenant config")
+	}
+
+	logger.Info("Set share links", zap.String("tenant", req.Tenant), zap.Bool("enabled", req.Enabled))
+	return &pb.SetShareLinksEnabledResponse{Tenant: req.Tenant, Enabled: req.Enabled}, nil
+}
+
+func (s *AdminService) ExportCorpus(ctx context.Context, req *pb.ExportCorpusRequest) (*pb.ExportCorpusResponse, error) {
+	if req.Tenant == "" {
+		return nil, status.Error(codes.InvalidArgument, "tenant is required")
+	}
+
+	config, err := loadTenantConfig(ctx, s.mongo, req.Tenant)
+	if err != nil {
+		logger.Error("Failed to load tenant config", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load tenant config")
+	}
+	spec, err := s.embedders.Spec(config.Embedder)
+	if err != nil {
+		// an importing deployment must know the model the vectors are of
+		return nil, status.Error(codes.FailedPrecondition, "Tenant's embedder is not registered: "+err.Error())
+	}
+
+	// the archive is written to disk first, since a large corpus doesn't fit in memory twice
+	file, err := os.CreateTemp("", "corpus-*.jsonl.gz")
+	if err != nil {
+		logger.Error("Failed to create corpus archive", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to export corpus")
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	embedder := backup.Embedder{Name: spec.Name, Model: spec.Model, Dimensions: spec.Dimensions}
+	header, counts, err := backup.Export(ctx, s.mongo, s.az, req.Tenant, embedder, file)
+	if err != nil {
+		logger.Error("Failed to export corpus", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to export corpus")
+	}
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		logger.Error("Failed to read corpus archive", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to export corpus")
+	}
+	storagePath := corpusExportPrefix + "corpus-" + time.Unix(header.ExportedOn, 0).UTC().Format("20060102-150405") + ".jsonl.gz"
+	if _, err := s.az.UploadBuffer(ctx, req.Tenant, storagePath, data); err != nil {
+		logger.Error("Failed to store corpus archive", zap.String("tenant", req.Tenant), zap.String("path", storagePath), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to store corpus archive")
+	}
+
+	logger.Info("Corpus exported", zap.String("tenant", req.Tenant), zap.String("path", storagePath),
+		zap.Int("bytes", len(data)), zap.Int64("corpusVersion", header.CorpusVersion), zap.Any("counts", counts))
+	return &pb.ExportCorpusResponse{
+		StoragePath:   storagePath,
+		SizeBytes:     int64(len(data)),
+		CorpusVersion: header.CorpusVersion,
+		Documents:     int32(len(header.Documents)),
+		Counts:        countsProto(counts),
+		Embedder:      spec.Name,
+		Model:         spec.Model,
+		Dimensions:    int32(spec.Dimensions),
+	}, nil
+}
+
+func (s *AdminService) ImportCorpus(ctx context.Context, req *pb.ImportCorpusRequest) (*pb.ImportCorpusResponse, error) {
+	if req.Tenant == "" {
+		return nil, status.Error(codes.InvalidArgument, "tenant is required")
+	}
+	if req.StoragePath == "" {
+		return nil, status.Error(codes.InvalidArgument, "storagePath is required")
+	}
+
+	filePath, err := s.az.DownloadFile(ctx, req.Tenant, req.StoragePath)
+	if err != nil {
+		logger.Error("Failed to download corpus archive", zap.String("tenant", req.Tenant), zap.String("path", req.StoragePath), zap.Error(err))
+		return nil, status.Error(codes.NotFound, "Failed to read corpus archive from storage")
+	}
+	defer os.Remove(filePath)
+	file, err := os.Open(filePath)
+	if err != nil {
+		logger.Error("Failed to open corpus archive", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to read corpus archive")
+	}
+	defer file.Close()
+	archive, err := backup.NewReader(file)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Failed to read corpus archive: "+err.Error())
+	}
+
+	// the archive's vectors are only searchable with queries embedded by the same model
+	vectors := archive.Header.Embedder
+	spec, ok := s.embedders.Find(vectors.Model, vectors.Dimensions)
+	if !ok {
+		return nil, status.Error(codes.FailedPrecondition, "No registered embedder has the archive's model "+vectors.Model+" at "+strconv.Itoa(vectors.Dimensions)+" dimensions")
+	}
+	if !req.Replace {
+		chunks, err := async.Await(odm.CollectionOf[db.ChunkModel](s.mongo, req.Tenant).Count(ctx, bson.M{}))
+		if err != nil {
+			logger.Error("Failed to count chunks", zap.String("tenant", req.Tenant), zap.Error(err))
+			return nil, status.Error(codes.Internal, "Failed to import corpus")
+		}
+		if chunks > 0 {
+			return nil, status.Error(codes.FailedPrecondition, "Tenant already has a corpus; set replace to delete it first")
+		}
+	}
+	report, err := backup.Import(ctx, s.mongo, s.az, req.Tenant, archive, req.Replace)
+	switch {
+	case errors.Is(err, backup.ErrCorpusNotEmpty):
+		return nil, status.Error(codes.FailedPrecondition, "Tenant already has a corpus; set replace to delete it first")
+	case errors.Is(err, backup.ErrTruncated):
+		return nil, status.Error(codes.InvalidArgument, "Corpus archive is truncated; import it again once it is whole")
+	case err != nil:
+		logger.Error("Failed to import corpus", zap.String("tenant", req.Tenant), zap.String("path", req.StoragePath), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to import corpus; run the import again to finish it")
+	}
+
+	// until the old vectors are gone and the archive's are in, the tenant keeps the
+	// embedder and index its vectors were made for
+	if err := s.switchEmbedder(ctx, req.Tenant, spec); err != nil {
+		logger.Error("Failed to switch tenant embedder", zap.String("tenant", req.Tenant), zap.String("embedder", spec.Name), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Corpus imported, but failed to switch the tenant's embedder; run the import again with replace set")
+	}
+
+	return &pb.ImportCorpusResponse{
+		SourceTenant:  archive.Header.Tenant,
+		ExportedOn:    archive.Header.ExportedOn,
+		Documents:     int32(len(archive.Header.Documents)),
+		Counts:        countsProto(report.Imported),
+		Cleared:       int32(report.Cleared),
+		CorpusVersion: report.CorpusVersion,
+		Embedder:      spec.Name,
+	}, nil
+}
+
+// switchEmbedder makes spec the tenant's embedder when its own embeds with another
+// model, resizing its vector index for spec's vectors. It is only for a tenant whose
+// vectors have just been replaced by spec's.
+func (s *AdminService) switchEmbedder(ctx context.Context, tenant string, spec embedding.Spec) error {
+	config, err := loadTenantConfig(ctx, s.mongo, tenant)
+	if err != nil {
+		return errors.New("failed to load tenant config: " + err.Error())
+	}
+	current, err := s.embedders.Spec(config.Embedder)
+	if err == nil && current.Model == spec.Model && current.Dimensions == spec.Dimensions {
+		return nil
+	}
+	if err != nil || current.Dimensions != spec.Dimensions {
+		if err := db.ResizeVectorIndex(ctx, s.mongo, tenant, spec.Dimensions); err != nil {
+			return errors.New("failed to resize vector index: " + err.Error())
+		}
+	}
+	previous := config.Embedder
+	config.Embedder = spec.Name
+	if _, err := async.Await(odm.CollectionOf[db.TenantConfigModel](s.mongo, tenant).Save(ctx, *config)); err != nil {
+		return errors.New("failed to save tenant embedder: " + err.Error())
+	}
+	logger.Info("Switched tenant embedder for an imported corpus", zap.String("tenant", tenant),
+		zap.String("from", previous), zap.String("to", spec.Name))
+	return nil
+}
+
+func countsProto(counts backup.Counts) map[string]int32 {
+	resp := map[string]int32{}
+	for key, n := range counts {
+		resp[key] = int32(n)
+	}
+	return resp
+}
+
+// distinctValues trims values and drops blanks and repeats, keeping their order.
+func distinctValues(values []string) []string {
+	var distinct []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" && !slices.Contains(distinct, value) {
+			distinct = append(distinct, value)
+		}
+	}
+	return distinct
+}
+
+func sourceExclusionsProto(tenant string, config *db.TenantConfigModel) *pb.SourceExclusions {
+	return &pb.SourceExclusions{
+		Tenant:    tenant,
+		Documents: config.ExcludedDocuments,
+		Authors:   config.ExcludedAuthors,
+		UpdatedOn: config.ExclusionsUpdatedOn,
+	}
+}
+
+func systemPromptProto(tenant string, config db.AgentConfigModel) *pb.SystemPrompt {
+	return &pb.SystemPrompt{
+		Tenant:         tenant,
+		Template:       config.PromptTemplate,
+		Variables:      config.PromptVariables,
+		BasePrompt:     config.SystemPrompt,
+		RenderedPrompt: tenantSystemPrompt(tenant, config),
+		UpdatedOn:      config.UpdatedOn,
+	}
+}
+
+func activeStreamProto(run runSnapshot, now time.Time) *pb.ActiveStream {
+	return &pb.ActiveStream{
+		RunId:          run.ID,
+		Tenant:         run.Tenant,
+		UserId:         run.UserID,
+		SessionId:      run.SessionID,
+		Model:          run.Model,
+		StartedOn:      run.Started.Unix(),
+		AgeSeconds:     int64(now.Sub(run.Started).Seconds()),
+		Stage:          run.Stage,
+		StageDetail:    run.StageDetail,
+		StageUpdatedOn: run.StageUpdated.Unix(),
+	}
+}
+
+func syncRunProto(run db.SyncRunModel) *pb.SyncRun {
+	return &pb.SyncRun{
+		RunId:          run.RunID,
+		Source:         run.Source,
+		Kind:           run.Kind,
+		Trigger:        run.Trigger,
+		ScheduledFor:   run.ScheduledFor,
+		Status:         run.Status,
+		Error:          run.Error,
+		StartedOn:      run.StartedOn,
+		FinishedOn:     run.FinishedOn,
+		Ingested:       int32(run.Ingested),
+		Resumed:        int32(run.Resumed),
+		Unchanged:      int32(run.Unchanged),
+		Duplicates:     int32(run.Duplicates),
+		Quarantined:    int32(run.Quarantined),
+		Redacted:       int32(run.Redacted),
+		Unsupported:    int32(run.Unsupported),
+		Failed:         int32(run.Failed),
+		Chunks:         int32(run.Chunks),
+		EmbeddedChunks: int32(run.Embedded),
+	}
+}
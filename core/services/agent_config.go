@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/llmrouter"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+// How long a tenant's agent config is served from memory before it is re-read,
+// i.e. how quickly an edit to the agent_config document takes effect.
+const agentConfigReloadInterval = 30 * time.Second
+
+// AgentConfigStore caches each tenant's agent config and reloads it from Mongo once
+// it is older than agentConfigReloadInterval, so edits apply without a redeploy.
+type AgentConfigStore struct {
+	mongo  odm.MongoClient
+	models *llmrouter.Registry
+
+	mu      sync.Mutex
+	entries map[string]agentConfigEntry
+}
+
+type agentConfigEntry struct {
+	config   db.AgentConfigModel
+	loadedAt time.Time
+}
+
+func ProvideAgentConfigStore(mongo odm.MongoClient, models *llmrouter.Registry) *AgentConfigStore {
+	return &AgentConfigStore{
+		mongo:   mongo,
+		models:  models,
+		entries: make(map[string]agentConfigEntry),
+	}
+}
+
+// Get returns the tenant's agent config with defaults applied. If a reload fails the
+// previously loaded config keeps being served.
+func (s *AgentConfigStore) Get(ctx context.Context, tenant string) db.AgentConfigModel {
+	s.mu.Lock()
+	entry, ok := s.entries[tenant]
+	s.mu.Unlock()
+
+	if ok && time.Since(entry.loadedAt) < agentConfigReloadInterval {
+		return entry.config
+	}
+
+	config, err := loadAgentConfig(ctx, s.mongo, tenant)
+	if err != nil {
+		logger.Error("Failed to load agent config", zap.String("tenant", tenant), zap.Error(err))
+		if !ok {
+			entry.config = withAgentDefaults(db.AgentConfigModel{ID: db.AgentConfigID})
+		}
+		config = entry.config
+	}
+	config = withDefaultModels(config, s.models.DefaultModel())
+
+	s.mu.Lock()
+	s.entries[tenant] = agentConfigEntry{config: config, loadedAt: time.Now()}
+	s.mu.Unlock()
+
+	return config
+}
+
+func loadAgentConfig(ctx context.Context, mongo odm.MongoClient, tenant string) (db.AgentConfigModel, error) {
+	repo := odm.CollectionOf[db.AgentConfigModel](mongo, tenant)
+	exists, err := async.Await(repo.Exists(ctx, db.AgentConfigID))
+	if err != nil {
+		return db.AgentConfigModel{}, err
+	}
+	config := &db.AgentConfigModel{ID: db.AgentConfigID}
+	if exists {
+		if config, err = async.Await(repo.FindOneByID(ctx, db.AgentConfigID)); err != nil {
+			return db.AgentConfigModel{}, err
+		}
+	}
+
+	// Tools backed by an optional dataset are offered by default only to tenants that
+	// have ingested it; otherwise the tool selector would be choosing an empty tool.
+	if len(config.Tools) == 0 {
+		config.Tools = []string{searchToolName, remedyProfileToolName, calculatorToolName}
+
+		for _, dataset := range []struct {
+			tool  string
+			count func() <-chan async.Result[int64]
+		}{
+			{repertoryToolName, func() <-chan async.Result[int64] {
+				return odm.CollectionOf[db.RubricModel](mongo, tenant).Count(ctx, bson.M{})
+			}},
+			{interactionToolName, func() <-chan async.Result[int64] {
+				return odm.CollectionOf[db.InteractionModel](mongo, tenant).Count(ctx, bson.M{})
+			}},
+			{tablesToolName, func() <-chan async.Result[int64] {
+				return odm.CollectionOf[db.TableRowModel](mongo, tenant).Count(ctx, bson.M{})
+			}},
+			{referencesToolName, func() <-chan async.Result[int64] {
+				return odm.CollectionOf[db.ChunkModel](mongo, tenant).Count(ctx, bson.M{"links": bson.M{"$exists": true}})
+			}},
+		} {
+			records, err := async.Await(dataset.count())
+			if err != nil {
+				return db.AgentConfigModel{}, err
+			}
+			if records > 0 {
+				config.Tools = append(config.Tools, dataset.tool)
+			}
+		}
+	}
+	return withAgentDefaults(*config), nil
+}
+
+// abstentionThreshold resolves the confidence below which the tenant's agent abstains;
+// zero disables abstention.
+func abstentionThreshold(config db.AgentConfigModel, ccfg *appconfig.AppConfig) float64 {
+	switch {
+	case config.AbstentionThreshold > 0:
+		return config.AbstentionThreshold
+	case config.AbstentionThreshold < 0:
+		return 0
+	default:
+		return max(ccfg.AbstentionThreshold, 0)
+	}
+}
+
+func withAgentDefaults(config db.AgentConfigModel) db.AgentConfigModel {
+	if config.SystemPrompt == "" {
+		config.SystemPrompt = db.DefaultAgentSystemPrompt
+	}
+	if config.MaxTurns <= 0 {
+		config.MaxTurns = db.DefaultAgentMaxTurns
+	}
+	if len(config.Tools) == 0 {
+		config.Tools = []string{searchToolName, remedyProfileToolName, calculatorToolName}
+	}
+	return config
+}
+
+// withDefaultModels has the summary and tool-selector calls use the registry's
+// default_model unless the tenant's config names its own.
+func withDefaultModels(config db.AgentConfigModel, defaultModel string) db.AgentConfigModel {
+	if config.MiniModel == "" {
+		config.MiniModel = defaultModel
+	}
+	if config.ToolSelectorModel == "" {
+		config.ToolSelectorModel = defaultModel
+	}
+	return config
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"sync/atomic"
+
+	"github.com/SaiNageswarS/agent-boot/agentboot"
+	"github.com/SaiNageswarS/agent-boot/llm"
+	bootprompts "github.com/SaiNageswarS/agent-boot/prompts"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/medicine-rag/core/llmrouter"
+	"github.com/ollama/ollama/api"
+	"go.uber.org/zap"
+)
+
+// The agent builds the options of its calls with llm's constructors, which llmrouter's
+// clients can't read. The clients handed to it restate those options as agent-boot
+// v1.0.41 sets them; TestAgentModelsRestateAgentBootOptions fails when a new version
+// sets others.
+
+// agentMaxTokens is agent-boot's default, which the builder is also given.
+const agentMaxTokens = 2000
+
+// agentAnswerModel writes the answer from the gathered context.
+func agentAnswerModel(client llm.LLMClient, systemPrompt string) llm.LLMClient {
+	return llmrouter.WithOptions(client, func() []llm.LLMOption {
+		return []llm.LLMOption{
+			llmrouter.WithMaxTokens(agentMaxTokens),
+			llmrouter.WithTemperature(0.7),
+			llmrouter.WithSystemPrompt(systemPrompt),
+		}
+	})
+}
+
+// agentSummaryModel summarizes tool results for the answer model.
+func agentSummaryModel(client llm.LLMClient) llm.LLMClient {
+	// the system prompt doesn't depend on the result summarized
+	systemPrompt, _, err := bootprompts.RenderSummarizationPrompt("", "", "")
+	if err != nil {
+		logger.Error("Failed to render summarization prompt", zap.Error(err))
+	}
+	return llmrouter.WithOptions(client, func() []llm.LLMOption {
+		return []llm.LLMOption{
+			llmrouter.WithTemperature(0.3),
+			llmrouter.WithSystemPrompt(systemPrompt),
+		}
+	})
+}
+
+// agentToolSelector picks each turn's tools. It is called once a turn, and its prompt
+// names the turn, counted from 0.
+func agentToolSelector(client llm.LLMClient, tools []agentboot.MCPTool) llm.LLMClient {
+	apiTools := make([]api.Tool, len(tools))
+	for i, tool := range tools {
+		apiTools[i] = tool.Tool
+	}
+
+	var turns atomic.Int32
+	return llmrouter.WithOptions(client, func() []llm.LLMOption {
+		systemPrompt, err := bootprompts.RenderToolSelectionPrompt(int(turns.Add(1) - 1))
+		if err != nil {
+			logger.Error("Failed to render tool selection prompt", zap.Error(err))
+		}
+		return []llm.LLMOption{
+			llmrouter.WithTools(apiTools),
+			llmrouter.WithMaxTokens(agentMaxTokens),
+			llmrouter.WithSystemPrompt(systemPrompt),
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/SaiNageswarS/agent-boot/agentboot"
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/ollama/ollama/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The clients handed to the agent restate the options agent-boot sets on its calls. This
+// runs an agent with clients that record the options it sets, so bumping agent-boot
+// fails here when they change, rather than silently changing how the models are called.
+func TestAgentModelsRestateAgentBootOptions(t *testing.T) {
+	const systemPrompt = "Answer from the context."
+
+	tool := agentboot.NewMCPToolBuilder("search", "Searches the corpus.").
+		StringParam("query", "What to search for.", true).
+		Summarize(true).
+		WithHandler(func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+			results := make(chan *schema.ToolResultChunk, 1)
+			results <- agentboot.NewToolResultChunk().Title("Aconite").Sentences("Sudden fright.", "Restlessness.").Build()
+			close(results)
+			return results
+		}).
+		Build()
+
+	answer, mini := &optionsRecorder{}, &optionsRecorder{}
+	toolSelector := &optionsRecorder{toolCalls: []api.ToolCall{{Function: api.ToolCallFunction{
+		Name:      "search",
+		Arguments: api.ToolCallFunctionArguments{"query": "remedy for fright"},
+	}}}}
+	agent := agentboot.NewAgentBuilder().
+		WithBigModel(answer).
+		WithMiniModel(mini).
+		WithToolSelector(toolSelector).
+		WithSystemPrompt(systemPrompt).
+		WithMaxTokens(agentMaxTokens).
+		WithMaxTurns(2).
+		AddTool(tool).
+		Build()
+	_, err := agent.Execute(t.Context(), &agentboot.NoOpProgressReporter{}, &schema.GenerateAnswerRequest{Question: "Remedy for sudden fright?"})
+	require.NoError(t, err)
+
+	require.Len(t, answer.calls, 1)
+	assert.Equal(t, applied(answer.calls[0]), restated(t, func(r llm.LLMClient) llm.LLMClient { return agentAnswerModel(r, systemPrompt) }, 1)[0], "answer model")
+
+	require.Len(t, mini.calls, 1)
+	assert.Equal(t, applied(mini.calls[0]), restated(t, agentSummaryModel, 1)[0], "summary model")
+
+	require.Len(t, toolSelector.calls, 2)
+	selector := restated(t, func(r llm.LLMClient) llm.LLMClient { return agentToolSelector(r, []agentboot.MCPTool{tool}) }, 2)
+	for turn, call := range toolSelector.calls {
+		assert.Equal(t, applied(call), selector[turn], "tool selector on turn %d", turn)
+	}
+}
+
+// optionsRecorder records the options of each call. Tool-calling calls answer with
+// toolCalls the first time.
+type optionsRecorder struct {
+	mu        sync.Mutex
+	calls     [][]llm.LLMOption
+	toolCalls []api.ToolCall
+}
+
+func (r *optionsRecorder) GenerateInference(_ context.Context, _ []llm.Message, _ func(string) error, opts ...llm.LLMOption) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, opts)
+	return nil
+}
+
+func (r *optionsRecorder) GenerateInferenceWithTools(_ context.Context, _ []llm.Message, _ func(string) error, toolCallback func([]api.ToolCall) error, opts ...llm.LLMOption) error {
+	r.mu.Lock()
+	r.calls = append(r.calls, opts)
+	first := len(r.calls) == 1
+	r.mu.Unlock()
+
+	if first && len(r.toolCalls) > 0 {
+		return toolCallback(r.toolCalls)
+	}
+	return nil
+}
+
+func (r *optionsRecorder) Capabilities() llm.Capability {
+	return llm.NativeToolCalling
+}
+
+func (r *optionsRecorder) GetModel() string {
+	return "recorder"
+}
+
+// restated returns the settings of calls calls through the client wrap returns, made
+// without options of their own.
+func restated(t *testing.T, wrap func(llm.LLMClient) llm.LLMClient, calls int) []llm.LLMSettings {
+	recorder := &optionsRecorder{}
+	client := wrap(recorder)
+
+	settings := make([]llm.LLMSettings, calls)
+	for i := range settings {
+		require.NoError(t, client.GenerateInference(t.Context(), nil, func(string) error { return nil }))
+		settings[i] = applied(recorder.calls[i])
+	}
+	return settings
+}
+
+// applied is what opts set, including the fields llm.LLMSettings keeps unexported.
+func applied(opts []llm.LLMOption) llm.LLMSettings {
+	var settings llm.LLMSettings
+	for _, opt := range opts {
+		opt(&settings)
+	}
+	return settings
+}
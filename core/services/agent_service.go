@@ -2,63 +2,757 @@ package services
 
 import (
 	"context"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/SaiNageswarS/agent-boot/agentboot"
 	"github.com/SaiNageswarS/agent-boot/llm"
 	"github.com/SaiNageswarS/agent-boot/memory"
 	"github.com/SaiNageswarS/agent-boot/schema"
 	"github.com/SaiNageswarS/go-api-boot/auth"
-	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-api-boot/logger"
 	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/SaiNageswarS/medicine-rag/core/audit"
+	"github.com/SaiNageswarS/medicine-rag/core/budget"
+	"github.com/SaiNageswarS/medicine-rag/core/compaction"
 	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/explain"
+	"github.com/SaiNageswarS/medicine-rag/core/fanout"
+	"github.com/SaiNageswarS/medicine-rag/core/followups"
+	"github.com/SaiNageswarS/medicine-rag/core/guardrails"
+	"github.com/SaiNageswarS/medicine-rag/core/lang"
+	"github.com/SaiNageswarS/medicine-rag/core/llmrouter"
 	"github.com/SaiNageswarS/medicine-rag/core/mcp"
+	"github.com/SaiNageswarS/medicine-rag/core/prompts"
+	"github.com/SaiNageswarS/medicine-rag/core/structured"
+	"github.com/SaiNageswarS/medicine-rag/core/tenancy"
+	"github.com/SaiNageswarS/medicine-rag/core/titles"
 	"github.com/ollama/ollama/api"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type AgentService struct {
 	schema.UnimplementedAgentServer
-	mongo    odm.MongoClient
-	embedder embed.Embedder
+	mongo        odm.MongoClient
+	models       *llmrouter.Registry
+	limits       *tenancy.Limits
+	configs      *AgentConfigStore
+	cache        *AnswerCache
+	sessionCache *mcp.SessionCache
+	searchTools  *SearchTools
+	streams      *StreamRegistry
+	ccfg         *appconfig.AppConfig
 }
 
-func ProvideAgentService(mongo odm.MongoClient, embedder embed.Embedder) *AgentService {
+func ProvideAgentService(mongo odm.MongoClient, models *llmrouter.Registry, limits *tenancy.Limits, configs *AgentConfigStore, cache *AnswerCache, sessionCache *mcp.SessionCache, searchTools *SearchTools, streams *StreamRegistry, ccfg *appconfig.AppConfig) *AgentService {
 	return &AgentService{
-		mongo:    mongo,
-		embedder: embedder,
+		mongo:        mongo,
+		models:       models,
+		limits:       limits,
+		configs:      configs,
+		cache:        cache,
+		sessionCache: sessionCache,
+		searchTools:  searchTools,
+		streams:      streams,
+		ccfg:         ccfg,
 	}
 }
 
+const (
+	searchToolName        = "medicine-rag"
+	repertoryToolName     = "repertory"
+	remedyProfileToolName = "remedy-profile"
+	interactionToolName   = "interactions"
+	calculatorToolName    = "calculator"
+	referencesToolName    = "follow-references"
+	tablesToolName        = "tables"
+)
+
 func (s *AgentService) Execute(req *schema.GenerateAnswerRequest, stream grpc.ServerStreamingServer[schema.AgentStreamChunk]) error {
 	ctx := stream.Context()
-	_, tenant := auth.GetUserIdAndTenant(ctx)
+	started := time.Now()
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
 
-	chunkRepository := odm.CollectionOf[db.ChunkModel](s.mongo, tenant)
-	vectorRepository := odm.CollectionOf[db.ChunkAnnModel](s.mongo, tenant)
+	ctx, run := s.streams.Start(ctx, tenant, userId, req.SessionId)
+	defer s.streams.finish(run)
 
 	conversationRepo := odm.CollectionOf[memory.Conversation](s.mongo, tenant)
+	conversations := odm.CollectionOf[db.ConversationModel](s.mongo, tenant)
+	ensureConversation(ctx, conversations, req.SessionId, userId)
+	caseContext := sessionCaseContext(ctx, conversations, req.SessionId)
+
+	tenantConfig, err := loadTenantConfig(ctx, s.mongo, tenant)
+	if err != nil {
+		logger.Error("Failed to load tenant config", zap.String("tenant", tenant), zap.Error(err))
+		return status.Error(codes.Internal, "Failed to load tenant config")
+	}
+
+	// A quota that cannot be read is not enforced; usage is still recorded below.
+	exceeded, err := quotaExceeded(ctx, s.mongo, tenant, monthlyTokenQuota(tenantConfig, s.ccfg))
+	if err != nil {
+		logger.Error("Failed to read token usage", zap.String("tenant", tenant), zap.Error(err))
+	}
+	if exceeded {
+		logger.Info("Monthly token quota exceeded", zap.String("tenant", tenant))
+		return stream.Send(agentboot.NewStreamError("This workspace has used its monthly token quota. It resets at the start of next month.", quotaExceededCode))
+	}
+
+	format, err := structured.ParseFormat(req.Metadata)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// a dry run selects and runs tools but writes out the plan instead of an answer
+	dryRun := explain.Requested(req.Metadata)
+	if dryRun && format != nil {
+		return status.Error(codes.InvalidArgument, "explain cannot be combined with a response format")
+	}
+
+	// retrieval stays in English; only the answer is written in this language
+	answerLanguage, err := lang.Resolve(req.Metadata[lang.MetadataKey], req.Question)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	agentConfig := s.configs.Get(ctx, tenant)
+	policy := contentPolicy(tenant, agentConfig)
+
+	models, err := s.selectModels(ctx, tenant, userId, tenantConfig, agentConfig, req.Metadata["model"])
+	if err != nil {
+		logger.Error("No model available", zap.String("tenant", tenant), zap.Bool("offline", tenantConfig.OfflineMode), zap.Error(err))
+		return status.Error(codes.Unavailable, "No model is available for this request")
+	}
+	run.setModel(models.name)
+
+	corpusVersion, err := db.CurrentCorpusVersion(ctx, s.mongo, tenant)
+	if err != nil {
+		logger.Error("Failed to read corpus version", zap.String("tenant", tenant), zap.Error(err))
+	}
+
+	// every model and tool call of the run is kept for audit; see GetExecutionTrace
+	recorder := audit.NewRecorder(started)
 
-	search := mcp.NewSearchTool(chunkRepository, vectorRepository, s.embedder)
-
-	mcp := agentboot.NewMCPToolBuilder("medicine-rag", "Search and retrieve medical information and remedies from the database for the user query.").
-		StringParam("query", "Search Query to perform search", true).
-		WithHandler(func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
-			query := params["query"].(string)
-			return search.Run(ctx, query)
-		}).
-		Summarize(true).
-		Build()
-
-	agent := agentboot.NewAgentBuilder().
-		WithMiniModel(llm.NewAnthropicClient("claude-3-5-haiku-20241022")).
-		WithBigModel(llm.NewAnthropicClient("claude-3-5-haiku-20241022")).
-		WithToolSelector(llm.NewGroqClient("openai/gpt-oss-20b")).
-		WithSystemPrompt("You are an assistant for Qualified Homeopathic Physicians. You are provided with medicine-rag tool to query medical knowledge database. Use ONLY INFORMATION from medicine-rag to answer the User Query.").
-		AddTool(mcp).
-		WithConversationManager(conversationRepo, 5).
-		Build()
-
-	streamReporter := &agentboot.GrpcProgressReporter{Stream: stream}
-	_, err := agent.Execute(ctx, streamReporter, req)
+	meter := llmrouter.NewMeter()
+	metered := func(name string, client llm.LLMClient) llm.LLMClient {
+		spec, _ := s.models.Spec(name)
+		return s.limits.LLM(tenant, meter.Wrap(spec, client))
+	}
+
+	// documents tagged with access groups are only retrieved for users in one of them
+	accessGroups := userAccessGroups(ctx, s.mongo, tenant, userId)
+
+	searchOptions := []mcp.SearchToolOption{mcp.WithFacets(),
+		mcp.WithSessionCache(s.sessionCache, tenant, req.SessionId, corpusVersion), mcp.WithAccessGroups(accessGroups)}
+	if tenantConfig.ProgressiveRetrieval {
+		searchOptions = append(searchOptions, mcp.WithProgressiveRetrieval())
+	}
+	if tenantConfig.SpeculativeResults {
+		searchOptions = append(searchOptions, mcp.WithSpeculativeResults())
+	}
+	if tenantConfig.HyDE {
+		searchOptions = append(searchOptions, mcp.WithHyDE(recorder.WrapLLM("hyde", models.miniName, metered(models.miniName, models.mini))))
+	}
+	if tenantConfig.Decomposition {
+		searchOptions = append(searchOptions, mcp.WithDecomposition(recorder.WrapLLM("decompose", models.miniName, metered(models.miniName, models.mini))))
+	}
+	if tenantConfig.FigureReferences {
+		searchOptions = append(searchOptions, mcp.WithFigures())
+	}
+	if answerLanguage != lang.English {
+		// passages the user can read first, where the corpus has them
+		searchOptions = append(searchOptions, mcp.WithPreferredLanguage(answerLanguage.Code))
+	}
+	debug := debugRequested(req.Metadata)
+	if debug {
+		searchOptions = append(searchOptions, mcp.WithDiagnostics())
+	}
+	search, embedder := s.searchTools.Build(ctx, tenant, tenantConfig, corpusVersion, searchOptions...)
+
+	firstTurn := isFirstTurn(ctx, conversationRepo, req.SessionId)
+
+	// Only standalone plain-text questions answered in English are cached: a follow-up, or
+	// a question about an attached case, means something different in each conversation.
+	// Offline tenants, and those whose embedder is unavailable, are skipped since the key
+	// needs an embedding, and debug runs so their searches are run and logged.
+	var cacheKey *answerCacheKey
+	if s.cache.Enabled() && format == nil && !dryRun && !debug && answerLanguage == lang.English && !tenantConfig.OfflineMode && embedder != nil && caseContext == nil && firstTurn {
+		key, err := s.cache.Key(ctx, req.Question, embedder, models.name, corpusVersion, agentConfigHash(tenant, agentConfig), accessGroups)
+		if err != nil {
+			logger.Info("Answer not cacheable", zap.String("tenant", tenant), zap.Error(err))
+		} else {
+			cacheKey = &key
+			if req.Metadata["fresh"] != "true" {
+				// answers cached before the content rules were edited are regenerated
+				if cached, ok := s.cache.Lookup(ctx, tenant, key); ok && policy.Allows(cached.Answer) {
+					err := s.serveCachedAnswer(ctx, stream, conversationRepo, tenant, run.id, req, cached, started)
+					saveExecutionTrace(context.WithoutCancel(ctx), s.mongo, tenant, started, db.ExecutionTraceModel{
+						TraceID:       run.id,
+						SessionID:     req.SessionId,
+						UserID:        userId,
+						Question:      req.Question,
+						Model:         cached.Model,
+						CorpusVersion: cached.CorpusVersion,
+						Outcome:       traceCached,
+						Answer:        cached.Answer,
+					}, nil, llmrouter.Usage{})
+					return err
+				}
+			}
+		}
+	}
+
+	var toolCalls atomic.Int32
+	tools := map[string]func() agentboot.MCPTool{
+		searchToolName: func() agentboot.MCPTool {
+			return agentboot.NewMCPToolBuilder(searchToolName, "Search and retrieve medical information and remedies from the database for the user query.").
+				StringParam("query", "Search Query to perform search, in English. For precise searches it may quote exact phrases, combine terms with AND, OR, NOT and parentheses, and scope terms to a field, e.g. title:\"Nux vomica\" AND chilly NOT author:Kent. Fields: title, section, tag, book, author, text", true).
+				StringSliceParam("books", "Only search these source books, only when the user names them, e.g. \"Materia Medica\"", false).
+				StringSliceParam("authors", "Only search books by these authors, only when the user names them, e.g. \"Boericke\", \"Kent\"", false).
+				StringSliceParam("chapters", "Only search these chapters or sections, e.g. \"Aconitum Napellus\"", false).
+				StringSliceParam("languages", "Only search passages in these languages, as ISO 639-1 codes, only when the user asks for sources in a language, e.g. \"de\"", false).
+				StringParam("published_from", "Only search books published in or after this year, e.g. \"1900\"", false).
+				StringParam("published_to", "Only search books published in or before this year, e.g. \"1950\"", false).
+				StringParam("offset", "Results to skip, to get more evidence for a query already searched: the highest rank already seen, e.g. \"8\"", false).
+				StringParam("limit", "Most results to return, e.g. \"5\"", false).
+				StringParam("min_score", "Least relevance, from 0 to 1, of the results to return; raise it when results are off-topic, e.g. \"0.5\"", false).
+				WithHandler(func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+					toolCalls.Add(1)
+					query := params["query"].(string)
+					return search.Run(ctx, query, searchFilter(params), searchPage(params))
+				}).
+				Summarize(true).
+				Build()
+		},
+		repertoryToolName: func() agentboot.MCPTool {
+			repertory := mcp.NewRepertoryTool(odm.CollectionOf[db.RubricModel](s.mongo, tenant))
+			return agentboot.NewMCPToolBuilder(repertoryToolName, "Look up symptoms as rubrics in the homeopathic repertory and list the remedies graded under them. Use for symptom-to-remedy questions; pass each distinct symptom separately.").
+				StringSliceParam("symptoms", "Symptoms to look up, one per entry, e.g. \"fear of death\", \"restlessness at night\"", true).
+				WithHandler(func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+					toolCalls.Add(1)
+					return repertory.Run(ctx, stringSliceParam(params["symptoms"]))
+				}).
+				// rubric listings are already compact, and summarizing would lose the grades
+				Summarize(false).
+				Build()
+		},
+		remedyProfileToolName: func() agentboot.MCPTool {
+			profiles := mcp.NewRemedyProfileTool(odm.CollectionOf[db.ChunkModel](s.mongo, tenant), accessGroups)
+			return agentboot.NewMCPToolBuilder(remedyProfileToolName, "Get the materia medica profile of one or more remedies: keynotes, mental symptoms and modalities. Use to describe a remedy or to compare remedies; pass every remedy to compare in one call.").
+				StringSliceParam("remedies", "Remedy names, e.g. \"Aconite\", \"Arsenicum album\"", true).
+				WithHandler(func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+					toolCalls.Add(1)
+					return profiles.Run(ctx, stringSliceParam(params["remedies"]))
+				}).
+				// profiles are already condensed and labelled by facet
+				Summarize(false).
+				Build()
+		},
+		interactionToolName: func() agentboot.MCPTool {
+			checker := mcp.NewInteractionChecker(odm.CollectionOf[db.InteractionModel](s.mongo, tenant))
+			return agentboot.NewMCPToolBuilder(interactionToolName, "Check known antidotes and incompatibilities between homeopathic remedies and conventional drugs. Use whenever a question involves taking a remedy together with another remedy, a medication, coffee, camphor or similar substances.").
+				StringSliceParam("substances", "Remedies and drugs to check against each other, e.g. \"Nux vomica\", \"Coffee\"", true).
+				WithHandler(func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+					toolCalls.Add(1)
+					return checker.Run(ctx, stringSliceParam(params["substances"]))
+				}).
+				// interaction records must reach the answer verbatim
+				Summarize(false).
+				Build()
+		},
+		referencesToolName: func() agentboot.MCPTool {
+			references := mcp.NewReferenceTool(odm.CollectionOf[db.ChunkModel](s.mongo, tenant), accessGroups)
+			return agentboot.NewMCPToolBuilder(referencesToolName, "Follow search results to the passages they refer to: sections their \"see also\" and \"compare\" notes name, and neighbouring sections of the same chapter. Use when a result points elsewhere or its context is needed.").
+				StringSliceParam("section_ids", "sectionId of each search result to follow, e.g. \"3f9a1c\"", true).
+				StringSliceParam("kinds", "Only follow these kinds of link: \"reference\" (see also), \"compare\" or \"chapter\"", false).
+				WithHandler(func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+					toolCalls.Add(1)
+					return references.Run(ctx, stringSliceParam(params["section_ids"]), stringSliceParam(params["kinds"]))
+				}).
+				Summarize(true).
+				Build()
+		},
+		tablesToolName: func() agentboot.MCPTool {
+			tables := mcp.NewTableTool(odm.CollectionOf[db.TableRowModel](s.mongo, tenant), accessGroups)
+			return agentboot.NewMCPToolBuilder(tablesToolName, "Look up rows of the tables in the books, such as dosage tables, potency charts and repertory tables, and get each matching row whole with its column names. Use when the answer is a value from a table.").
+				StringSliceParam("queries", "What to look up, one per entry, e.g. \"Aconite dosage\", \"Belladonna potency children\"", true).
+				WithHandler(func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+					toolCalls.Add(1)
+					return tables.Run(ctx, stringSliceParam(params["queries"]))
+				}).
+				// a summary would separate values from their columns
+				Summarize(false).
+				Build()
+		},
+		calculatorToolName: func() agentboot.MCPTool {
+			return agentboot.NewMCPToolBuilder(calculatorToolName, "Convert potencies between the X, C and LM scales and work out dosing schedules: number of doses and total quantity. Always use this instead of calculating potencies or doses yourself.").
+				StringSliceParam("potencies", "Potencies to convert, e.g. \"30C\", \"6X\", \"LM1\", \"1M\"", false).
+				StringParam("dose", "Amount per dose for a schedule, e.g. \"2 pellets\", \"5 ml\"", false).
+				StringParam("frequency", "How often the dose is taken, e.g. \"3 times a day\", \"every 4 hours\", \"every other day\"", false).
+				StringParam("duration", "Length of the course, e.g. \"7 days\", \"2 weeks\"", false).
+				WithHandler(func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+					toolCalls.Add(1)
+					return mcp.RunCalculator(mcp.CalculatorRequest{
+						Potencies: stringSliceParam(params["potencies"]),
+						Dose:      stringParam(params["dose"]),
+						Frequency: stringParam(params["frequency"]),
+						Duration:  stringParam(params["duration"]),
+					})
+				}).
+				// the figures must reach the answer exactly as calculated
+				Summarize(false).
+				Build()
+		},
+	}
+
+	citationMode := guardrails.ParseCitationMode(agentConfig.CitationMode)
+	systemPrompt := tenantSystemPrompt(tenant, agentConfig)
+	if casePrompt := prompts.CaseContextPrompt(caseContext); casePrompt != "" {
+		systemPrompt += "\n\n" + casePrompt
+	}
+	if citationMode != guardrails.CitationOff {
+		systemPrompt += "\n\n" + guardrails.CitationInstruction
+	}
+	if format != nil {
+		systemPrompt += "\n\n" + format.Instruction()
+	}
+	if instruction := answerLanguage.Instruction(); instruction != "" {
+		systemPrompt += "\n\n" + instruction
+	}
+
+	// tools stop at their share of the execution budget and the answer is cut off at the
+	// rest; the partial answer still completes
+	spend := budget.New(started, executionTimeout(tenantConfig, s.ccfg), executionTokenBudget(tenantConfig, s.ccfg),
+		func() int64 { return meter.Total().Total() })
+
+	bigModel := spend.Answer(recorder.WrapLLM("answer", models.name, metered(models.name, models.big)))
+	plan := explain.NewPlan()
+	if dryRun {
+		bigModel = recorder.WrapLLM("answer", explain.ModelName, plan.Model())
+	}
+	miniModel := metered(models.miniName, models.mini)
+
+	// turns trimmed from the session are summarized with the mini model and handed back
+	// to the agent ahead of the turns it keeps; that runs after the turn, outside its trace
+	compactedRepo := compaction.NewCollection(conversationRepo,
+		odm.CollectionOf[db.ConversationSummaryModel](s.mongo, tenant), miniModel)
+	var sessions odm.OdmCollectionInterface[memory.Conversation] = compactedRepo
+	if dryRun {
+		sessions = dryRunConversations{compactedRepo}
+	}
+
+	builder := agentboot.NewAgentBuilder().
+		WithMiniModel(agentSummaryModel(recorder.WrapLLM("mini", models.miniName, miniModel))).
+		WithBigModel(agentAnswerModel(bigModel, systemPrompt)).
+		WithSystemPrompt(systemPrompt).
+		WithMaxTokens(agentMaxTokens).
+		WithMaxTurns(agentTurns(req.MaxIterations, agentConfig, tenantConfig, s.ccfg)).
+		WithConversationManager(sessions, 5)
+
+	var agentTools []agentboot.MCPTool
+	for _, name := range agentConfig.Tools {
+		newTool, ok := tools[name]
+		if !ok {
+			logger.Error("Unknown tool in agent config", zap.String("tenant", tenant), zap.String("tool", name))
+			continue
+		}
+		tool := newTool()
+		if dryRun {
+			// the plan lists what each call retrieved, not the mini model's summary of it
+			tool.SummarizeContext = false
+			tool.Handler = plan.Tool(name, tool.Handler)
+		}
+		if name == searchToolName {
+			tool.Handler = englishQueries(tool.Handler, recorder.WrapLLM("translateQuery", models.miniName, miniModel))
+		}
+		tool.Handler = fanout.Tool(spend.Tool(recorder.WrapTool(name, tool.Handler)), s.ccfg.ToolParallelism)
+		builder.AddTool(tool)
+		agentTools = append(agentTools, tool)
+	}
+	// several searches picked in one turn run as one concurrent call; see fanout
+	toolSelector := fanout.Selector(spend.ToolSelector(recorder.WrapLLM("toolSelector", models.toolSelectorName, metered(models.toolSelectorName, models.toolSelector))), searchToolName)
+	agent := builder.WithToolSelector(agentToolSelector(toolSelector, agentTools)).Build()
+
+	// a model standing in for a failed one is announced in the stream, not as a failure
+	degraded := newFailoverNotice(&agentboot.GrpcProgressReporter{Stream: stream})
+	ctx = llmrouter.WithFailoverNotice(ctx, degraded.record)
+
+	guard := guardrails.NewReporter(degraded,
+		guardrails.AssessQuestion(req.Question), guardrails.ParseDosageMode(s.ccfg.GuardrailDosageMode))
+	// a plan is not an answer: it cites nothing, and is never abstained from or disclaimed
+	if !dryRun {
+		guard.WithCitations(citationMode).
+			WithAbstention(req.Question, abstentionThreshold(agentConfig, s.ccfg)).
+			WithDisclaimers(disclaimerPolicy(tenantConfig, agentConfig))
+	}
+	if format != nil {
+		guard.PreserveAnswer()
+	}
+	if err := guard.Begin(); err != nil {
+		return err
+	}
+
+	streamReporter := newCompletionReporter(guard,
+		func(complete *schema.StreamComplete) {
+			complete.Metadata["corpusVersion"] = strconv.FormatInt(corpusVersion, 10)
+			complete.Metadata["model"] = models.name
+			complete.Metadata["miniModel"] = models.miniName
+			complete.Metadata["toolSelectorModel"] = models.toolSelectorName
+			complete.Metadata["toolCalls"] = strconv.Itoa(int(toolCalls.Load()))
+			complete.Metadata["latencyMs"] = strconv.FormatInt(time.Since(started).Milliseconds(), 10)
+			complete.Metadata["tokens"] = strconv.FormatInt(meter.Total().Total(), 10)
+			complete.Metadata["traceId"] = run.id
+			complete.Metadata["language"] = answerLanguage.Code
+			if fallbacks := degraded.Fallbacks(); fallbacks != "" {
+				complete.Metadata["fallbackModels"] = fallbacks
+			}
+			if dryRun {
+				complete.Metadata[explain.MetadataKey] = "true"
+				return
+			}
+			s.recordAnswerProvenance(ctx, tenant, req.SessionId, corpusVersion)
+		})
+	var reporter agentboot.ProgressReporter = spend.Reporter(streamReporter)
+	if format != nil {
+		reporter = structured.NewReporter(ctx, reporter, format, bigModel, s.ccfg.StructuredOutputRepairAttempts)
+	}
+	// Banned content is filtered before a structured answer is validated, so a
+	// regenerated answer is validated too.
+	if !policy.Empty() {
+		reporter = guardrails.NewContentFilter(ctx, reporter, policy, bigModel, func(violations []guardrails.ContentViolation) {
+			recordContentViolations(context.WithoutCancel(ctx), s.mongo, tenant, req.SessionId, userId, req.Question, violations)
+		})
+	}
+	streamRun := &runReporter{ProgressReporter: reporter, run: run}
+	response, err := agent.Execute(ctx, streamRun, req)
+
+	// Suggestions follow the completed answer and are generated from its sources, so a
+	// failed or cut-off answer gets none.
+	if err == nil && !run.Terminated() && !streamReporter.Failed() && format == nil && !dryRun && ctx.Err() == nil {
+		followUpModel := recorder.WrapLLM("followUps", models.miniName, miniModel)
+		questions, suggestErr := followups.Suggest(ctx, followUpModel, req.Question, guard.FinalAnswer(), guard.Sources(), s.ccfg.FollowUpSuggestions)
+		if suggestErr != nil {
+			logger.Error("Failed to suggest follow-up questions", zap.String("tenant", tenant), zap.Error(suggestErr))
+		} else if len(questions) > 0 {
+			if sendErr := streamRun.Send(followups.Chunk(questions)); sendErr != nil {
+				logger.Error("Failed to send follow-up questions", zap.String("tenant", tenant), zap.Error(sendErr))
+			}
+		}
+	}
+
+	// The history list names a session once its first answer is complete.
+	if firstTurn && !dryRun && err == nil && !run.Terminated() && !streamReporter.Failed() && ctx.Err() == nil {
+		titleModel := recorder.WrapLLM("title", models.miniName, miniModel)
+		s.nameConversation(context.WithoutCancel(ctx), titleModel, tenant, req.SessionId, req.Question, guard.FinalAnswer())
+	}
+
+	// Tokens spent before a client disconnects are still billed.
+	recordUsage(context.WithoutCancel(ctx), s.mongo, tenant, req.SessionId, userId, meter)
+
+	// agent-boot saves the answer before the guardrails amend it; keep what the user saw.
+	answer := guard.FinalAnswer()
+
+	trace := db.ExecutionTraceModel{
+		TraceID:       run.id,
+		SessionID:     req.SessionId,
+		UserID:        userId,
+		Question:      req.Question,
+		Model:         models.name,
+		CorpusVersion: corpusVersion,
+		Outcome:       traceCompleted,
+		Answer:        answer,
+	}
+	budgetReason, budgetStage := spend.Exhausted()
+	switch {
+	case run.Terminated():
+		trace.Outcome = traceTerminated
+	case err != nil:
+		trace.Outcome, trace.Error = traceFailed, err.Error()
+	case budgetReason != "":
+		trace.Outcome, trace.Error = traceBudgetExhausted, budget.Message(budgetReason, budgetStage)
+	case streamReporter.Failed():
+		trace.Outcome, trace.Error = traceFailed, streamReporter.Failure()
+	case dryRun:
+		trace.Outcome, trace.Model = traceDryRun, explain.ModelName
+	}
+	saveExecutionTrace(context.WithoutCancel(ctx), s.mongo, tenant, started, trace, recorder, meter.Total())
+
+	if run.Terminated() {
+		logger.Info("Agent run terminated by operator", zap.String("tenant", tenant), zap.String("runId", run.id))
+		return run.terminate(&agentboot.GrpcProgressReporter{Stream: stream})
+	}
+
+	if response != nil && answer != "" && answer != response.Answer && !dryRun {
+		replaceLastAnswer(context.WithoutCancel(ctx), conversationRepo, req.SessionId, answer)
+	}
+
+	// an answer from nothing retrieved is not worth serving again
+	if cacheKey != nil && err == nil && !streamReporter.Failed() && degraded.Fallbacks() == "" && answer != "" && len(guard.Sources()) > 0 {
+		s.cache.Store(context.WithoutCancel(ctx), tenant, *cacheKey, answer)
+	}
 	return err
 }
+
+func replaceLastAnswer(ctx context.Context, conversationRepo odm.OdmCollectionInterface[memory.Conversation], sessionId, answer string) {
+	if sessionId == "" {
+		return
+	}
+
+	conversation, err := async.Await(conversationRepo.FindOneByID(ctx, sessionId))
+	if err != nil || conversation == nil {
+		logger.Error("Failed to load conversation", zap.String("sessionId", sessionId), zap.Error(err))
+		return
+	}
+
+	for i := len(conversation.Messages) - 1; i >= 0; i-- {
+		if conversation.Messages[i].Role == "assistant" {
+			conversation.Messages[i].Content = answer
+			break
+		}
+	}
+	if _, err := async.Await(conversationRepo.Save(ctx, *conversation)); err != nil {
+		logger.Error("Failed to save conversation", zap.String("sessionId", sessionId), zap.Error(err))
+	}
+}
+
+// serveCachedAnswer streams a cached answer as if the agent had produced it, and records
+// the exchange in the conversation the way the agent would.
+func (s *AgentService) serveCachedAnswer(ctx context.Context, stream grpc.ServerStreamingServer[schema.AgentStreamChunk], conversationRepo odm.OdmCollectionInterface[memory.Conversation], tenant, traceId string, req *schema.GenerateAnswerRequest, cached *db.AnswerCacheModel, started time.Time) error {
+	logger.Info("Serving cached answer", zap.String("tenant", tenant), zap.String("cacheId", cached.ID))
+
+	if req.SessionId != "" {
+		conversation := &memory.Conversation{ID: req.SessionId}
+		conversation.AddUserMessage(req.Question)
+		conversation.AddAssistantMessage(cached.Answer)
+		if _, err := async.Await(conversationRepo.Save(ctx, *conversation)); err != nil {
+			logger.Error("Failed to save conversation", zap.String("sessionId", req.SessionId), zap.Error(err))
+		}
+	}
+
+	if err := stream.Send(agentboot.NewAnswerChunk(&schema.AnswerChunk{Content: cached.Answer})); err != nil {
+		return err
+	}
+
+	s.recordAnswerProvenance(ctx, tenant, req.SessionId, cached.CorpusVersion)
+	return stream.Send(agentboot.NewStreamComplete(&schema.StreamComplete{
+		Answer:    cached.Answer,
+		ToolsUsed: []string{},
+		Metadata: map[string]string{
+			"corpusVersion": strconv.FormatInt(cached.CorpusVersion, 10),
+			"model":         cached.Model,
+			"toolCalls":     "0",
+			"latencyMs":     strconv.FormatInt(time.Since(started).Milliseconds(), 10),
+			"tokens":        "0",
+			"cached":        "true",
+			"traceId":       traceId,
+		},
+	}))
+}
+
+// isFirstTurn reports whether the session has no earlier messages.
+func isFirstTurn(ctx context.Context, conversationRepo odm.OdmCollectionInterface[memory.Conversation], sessionId string) bool {
+	if sessionId == "" {
+		return true
+	}
+
+	conversation, err := async.Await(conversationRepo.FindOneByID(ctx, sessionId))
+	return err != nil || conversation == nil || len(conversation.Messages) == 0
+}
+
+// agent-boot loads a missing session as an empty conversation without an ID, so the first
+// save would land under an empty _id. Creating the document up front keeps each session
+// under its own ID and records who owns it.
+func ensureConversation(ctx context.Context, repo odm.OdmCollectionInterface[db.ConversationModel], sessionId, userId string) {
+	if sessionId == "" {
+		return
+	}
+
+	exists, err := async.Await(repo.Exists(ctx, sessionId))
+	if err != nil || exists {
+		return
+	}
+
+	_, err = async.Await(repo.Save(ctx, db.ConversationModel{SessionID: sessionId, UserID: userId}))
+	if err != nil {
+		logger.Error("Failed to create conversation", zap.String("sessionId", sessionId), zap.Error(err))
+	}
+}
+
+// sessionCaseContext returns the case details attached to the session, if any.
+func sessionCaseContext(ctx context.Context, repo odm.OdmCollectionInterface[db.ConversationModel], sessionId string) *db.CaseContext {
+	if sessionId == "" {
+		return nil
+	}
+
+	conversation, err := async.Await(repo.FindOneByID(ctx, sessionId))
+	if err != nil || conversation == nil {
+		return nil
+	}
+	return conversation.CaseContext
+}
+
+type agentModels struct {
+	// registry names of the models, used to attribute token usage
+	name             string
+	miniName         string
+	toolSelectorName string
+
+	big          llm.LLMClient
+	mini         llm.LLMClient
+	toolSelector llm.LLMClient
+}
+
+// selectModels picks the answering model from the requested name, honouring the
+// tenant's and the user's model allowlists, plus the agent's helper models. Offline
+// tenants only ever get local models; there is no fallback to a hosted one.
+func (s *AgentService) selectModels(ctx context.Context, tenant, userId string, tenantConfig *db.TenantConfigModel, agentConfig db.AgentConfigModel, requested string) (agentModels, error) {
+	var userModels []string
+	if login, err := async.Await(odm.CollectionOf[db.LoginModel](s.mongo, tenant).FindOneByID(ctx, userId)); err == nil && login != nil {
+		userModels = login.AllowedModels
+	}
+
+	if requested == "" {
+		requested = agentConfig.BigModel
+	}
+
+	var (
+		models agentModels
+		err    error
+	)
+
+	if tenantConfig.OfflineMode {
+		models.name, models.big, err = s.models.ResolveOffline(requested, tenantConfig.AllowedModels, userModels)
+		if err != nil {
+			return models, err
+		}
+
+		offline := s.models.Offline()
+		models.miniName = localOr(s.models, agentConfig.MiniModel, offline.MiniModel)
+		if models.mini, err = s.models.Client(models.miniName, tenantConfig.AllowedModels, userModels); err != nil {
+			return models, err
+		}
+		models.toolSelectorName = localOr(s.models, agentConfig.ToolSelectorModel, offline.ToolSelector)
+		models.toolSelector, err = s.models.Client(models.toolSelectorName, tenantConfig.AllowedModels, userModels)
+		return models, err
+	}
+
+	models.name, models.big, err = s.models.Resolve(requested, tenantConfig.AllowedModels, userModels)
+	if err != nil {
+		return models, err
+	}
+	if models.miniName, models.mini, err = s.configuredModel(agentConfig.MiniModel, tenantConfig.AllowedModels, userModels); err != nil {
+		return models, err
+	}
+	models.toolSelectorName, models.toolSelector, err = s.configuredModel(agentConfig.ToolSelectorModel, tenantConfig.AllowedModels, userModels)
+	return models, err
+}
+
+func localOr(registry *llmrouter.Registry, name, fallback string) string {
+	if registry.IsLocal(name) {
+		return name
+	}
+	return fallback
+}
+
+// configuredModel returns the client for a model named in the agent config, falling back
+// to the registry default when that model cannot be used. Its fallbacks are limited by
+// the allowlists.
+func (s *AgentService) configuredModel(name string, allowlists ...[]string) (string, llm.LLMClient, error) {
+	client, err := s.models.Client(name, allowlists...)
+	if err == nil {
+		return name, client, nil
+	}
+
+	logger.Error("Configured model unavailable, using default", zap.String("model", name), zap.Error(err))
+	return s.models.Resolve("", allowlists...)
+}
+
+func (s *AgentService) recordAnswerProvenance(ctx context.Context, tenant, sessionId string, corpusVersion int64) {
+	if sessionId == "" {
+		return
+	}
+
+	_, err := s.mongo.Database(tenant).Collection(db.ConversationModel{}.CollectionName()).UpdateOne(ctx,
+		bson.M{"_id": sessionId},
+		bson.M{
+			"$push": bson.M{"answerProvenance": db.AnswerProvenance{
+				CorpusVersion: corpusVersion,
+				AnsweredOn:    time.Now().Unix(),
+			}},
+			// agent-boot's saves leave updatedOn alone; the history list is ordered by it
+			"$set": bson.M{"updatedOn": time.Now().Unix()},
+		},
+	)
+	if err != nil {
+		logger.Error("Failed to record answer provenance", zap.String("sessionId", sessionId), zap.Error(err))
+	}
+}
+
+// nameConversation titles a session from its first question and answer. A session
+// that already has a title, such as an imported one, keeps it.
+func (s *AgentService) nameConversation(ctx context.Context, model llm.LLMClient, tenant, sessionId, question, answer string) {
+	if sessionId == "" {
+		return
+	}
+
+	title, err := titles.Generate(ctx, model, question, answer)
+	if err != nil {
+		logger.Error("Failed to generate conversation title", zap.String("tenant", tenant), zap.Error(err))
+		title = titles.FromQuestion(question)
+	}
+
+	_, err = s.mongo.Database(tenant).Collection(db.ConversationModel{}.CollectionName()).UpdateOne(ctx,
+		bson.M{"_id": sessionId, "title": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"title": title}},
+	)
+	if err != nil {
+		logger.Error("Failed to save conversation title", zap.String("sessionId", sessionId), zap.Error(err))
+	}
+}
+
+// tenantSystemPrompt is the agent config's base prompt followed by the tenant's own
+// prompt. A template that no longer renders is left out rather than failing the turn.
+func tenantSystemPrompt(tenant string, config db.AgentConfigModel) string {
+	if config.PromptTemplate == "" {
+		return config.SystemPrompt
+	}
+
+	rendered, err := prompts.RenderPromptTemplate(config.PromptTemplate, prompts.PromptVariables(tenant, config.PromptVariables, time.Now()))
+	if err != nil {
+		logger.Error("Failed to render tenant prompt", zap.String("tenant", tenant), zap.Error(err))
+		return config.SystemPrompt
+	}
+	return prompts.ComposeSystemPrompt(config.SystemPrompt, rendered)
+}
+
+func stringParam(value any) string {
+	s, _ := value.(string)
+	return strings.TrimSpace(s)
+}
+
+// stringSliceParam reads a string-array tool argument. Models sometimes send a single
+// string instead of a one-element array.
+func stringSliceParam(value any) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case string:
+		return []string{v}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAnswerCacheSimilarity = 0.97
+	answerCacheCandidates        = 20
+)
+
+// AnswerCache serves a stored answer when a physician asks a question that is nearly
+// identical to an earlier one, at the same corpus version and with the same agent
+// config. Looking up costs one embedding; retrieval is left to the agent. Entries
+// expire after the configured TTL and are dropped whenever the tenant's corpus changes.
+type AnswerCache struct {
+	mongo      odm.MongoClient
+	ttl        time.Duration
+	similarity float64
+}
+
+func ProvideAnswerCache(mongo odm.MongoClient, ccfg *appconfig.AppConfig) *AnswerCache {
+	c := &AnswerCache{
+		mongo:      mongo,
+		ttl:        time.Duration(ccfg.AnswerCacheTTLMinutes) * time.Minute,
+		similarity: ccfg.AnswerCacheSimilarity,
+	}
+	if c.similarity <= 0 || c.similarity > 1 {
+		c.similarity = defaultAnswerCacheSimilarity
+	}
+	return c
+}
+
+// Enabled reports whether answers are cached at all; a zero TTL turns the cache off.
+func (c *AnswerCache) Enabled() bool {
+	return c.ttl > 0
+}
+
+type answerCacheKey struct {
+	question      string // normalized
+	embedding     []float32
+	model         string
+	corpusVersion int64
+	configHash    string   // of the agent config and system prompt the answer is generated with
+	accessGroups  []string // of the user asking; answers may cite their restricted documents
+}
+
+// Key embeds the normalized question.
+func (c *AnswerCache) Key(ctx context.Context, question string, embedder embed.Embedder, model string, corpusVersion int64, configHash string, accessGroups []string) (answerCacheKey, error) {
+	key := answerCacheKey{
+		question:      normalizeQuestion(question),
+		model:         model,
+		corpusVersion: corpusVersion,
+		configHash:    configHash,
+		accessGroups:  db.NormalizeAccessGroups(accessGroups),
+	}
+
+	vector, err := async.Await(embedder.GetEmbedding(ctx, key.question, embedding.WithTask("retrieval.query")))
+	if err != nil {
+		return key, err
+	}
+	key.embedding = vector
+	return key, nil
+}
+
+// agentConfigHash identifies the agent config and tenant system prompt an answer is
+// generated with, so editing either stops earlier answers from being served.
+func agentConfigHash(tenant string, config db.AgentConfigModel) string {
+	encoded, _ := json.Marshal(config)
+	hash, _ := odm.HashedKey(string(encoded), tenantSystemPrompt(tenant, config))
+	return hash
+}
+
+// Lookup returns the closest unexpired entry for key, if it is similar enough.
+func (c *AnswerCache) Lookup(ctx context.Context, tenant string, key answerCacheKey) (*db.AnswerCacheModel, bool) {
+	best, err := c.closest(ctx, odm.CollectionOf[db.AnswerCacheModel](c.mongo, tenant), key, time.Now())
+	if err != nil {
+		logger.Error("Failed to read answer cache", zap.String("tenant", tenant), zap.Error(err))
+		return nil, false
+	}
+	if best == nil {
+		return nil, false
+	}
+
+	_, updateErr := c.mongo.Database(tenant).Collection(db.AnswerCacheModel{}.CollectionName()).UpdateOne(ctx,
+		bson.M{"_id": best.ID}, bson.M{"$inc": bson.M{"hits": 1}})
+	if updateErr != nil {
+		logger.Error("Failed to count answer cache hit", zap.String("tenant", tenant), zap.Error(updateErr))
+	}
+	return best, true
+}
+
+// closest returns the candidate most similar to key's question among the entries
+// unexpired at now that were answered for the same access groups, or nil when none is
+// at least c.similarity.
+func (c *AnswerCache) closest(ctx context.Context, repo odm.OdmCollectionInterface[db.AnswerCacheModel], key answerCacheKey, now time.Time) (*db.AnswerCacheModel, error) {
+	filter := bson.M{
+		"configHash":    key.configHash,
+		"model":         key.model,
+		"corpusVersion": key.corpusVersion,
+		"accessGroups":  bson.M{"$exists": false},
+		"expiresAt":     bson.M{"$gt": now},
+	}
+	if len(key.accessGroups) > 0 {
+		filter["accessGroups"] = key.accessGroups
+	}
+	candidates, err := async.Await(repo.Find(ctx, filter, bson.D{{Key: "createdOn", Value: -1}}, answerCacheCandidates, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		best      *db.AnswerCacheModel
+		bestScore = c.similarity
+	)
+	for i := range candidates {
+		embedding, ok := candidates[i].QuestionEmbedding.Float32OK()
+		if !ok {
+			continue
+		}
+		if score := cosineSimilarity(key.embedding, embedding); score >= bestScore {
+			best, bestScore = &candidates[i], score
+		}
+	}
+	return best, nil
+}
+
+// Store saves answer under key, replacing an earlier answer to the same question. The
+// corpus may have changed while the agent retrieved; an answer is only stored if the
+// corpus is still at the key's version.
+func (c *AnswerCache) Store(ctx context.Context, tenant string, key answerCacheKey, answer string) {
+	current, err := db.CurrentCorpusVersion(ctx, c.mongo, tenant)
+	if err != nil {
+		logger.Error("Failed to read corpus version", zap.String("tenant", tenant), zap.Error(err))
+		return
+	}
+	if err := c.store(ctx, odm.CollectionOf[db.AnswerCacheModel](c.mongo, tenant), key, answer, current, time.Now()); err != nil {
+		logger.Error("Failed to store cached answer", zap.String("tenant", tenant), zap.Error(err))
+	}
+}
+
+// store saves answer under key unless the corpus has moved on from key's version to
+// current.
+func (c *AnswerCache) store(ctx context.Context, repo odm.OdmCollectionInterface[db.AnswerCacheModel], key answerCacheKey, answer string, current int64, now time.Time) error {
+	if current != key.corpusVersion {
+		return nil
+	}
+
+	id, _ := odm.HashedKey(key.configHash, key.model, strconv.FormatInt(key.corpusVersion, 10), key.question, strings.Join(key.accessGroups, ","))
+	_, err := async.Await(repo.Save(ctx, db.AnswerCacheModel{
+		ID:                id,
+		ConfigHash:        key.configHash,
+		Question:          key.question,
+		QuestionEmbedding: bson.NewVector(key.embedding),
+		Model:             key.model,
+		CorpusVersion:     key.corpusVersion,
+		AccessGroups:      key.accessGroups,
+		Answer:            answer,
+		CreatedOn:         now.Unix(),
+		ExpiresAt:         now.Add(c.ttl),
+	}))
+	return err
+}
+
+// normalizeQuestion lowercases the question and drops punctuation and extra spaces, so
+// "Remedy for fear of death?" and "remedy for fear of death" share an entry.
+func normalizeQuestion(question string) string {
+	words := strings.FieldsFunc(strings.ToLower(question), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
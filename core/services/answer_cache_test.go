@@ -0,0 +1,140 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var cacheNow = time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+
+func testAnswerCache() *AnswerCache {
+	return ProvideAnswerCache(nil, &appconfig.AppConfig{AnswerCacheTTLMinutes: 60, AnswerCacheSimilarity: 0.97})
+}
+
+func cacheKey(embedding []float32, accessGroups ...string) answerCacheKey {
+	return answerCacheKey{
+		question:      "remedy for sudden fright",
+		embedding:     embedding,
+		model:         "gpt-4o",
+		corpusVersion: 3,
+		configHash:    "config",
+		accessGroups:  db.NormalizeAccessGroups(accessGroups),
+	}
+}
+
+func TestAnswerCacheSimilarity(t *testing.T) {
+	c := testAnswerCache()
+	repo := odmtest.NewCollection[db.AnswerCacheModel]()
+	require.NoError(t, c.store(t.Context(), repo, cacheKey([]float32{1, 0}), "Aconite.", 3, cacheNow))
+
+	for _, tc := range []struct {
+		name      string
+		embedding []float32
+		hit       bool
+	}{
+		{name: "Same", embedding: []float32{1, 0}, hit: true},
+		{name: "AboveThreshold", embedding: []float32{1, 0.2}, hit: true}, // 0.981
+		{name: "BelowThreshold", embedding: []float32{1, 0.3}},            // 0.958
+		{name: "Orthogonal", embedding: []float32{0, 1}},
+		{name: "OtherDimensions", embedding: []float32{1, 0, 0}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			best, err := c.closest(t.Context(), repo, cacheKey(tc.embedding), cacheNow)
+			require.NoError(t, err)
+			if !tc.hit {
+				assert.Nil(t, best)
+				return
+			}
+			require.NotNil(t, best)
+			assert.Equal(t, "Aconite.", best.Answer)
+		})
+	}
+}
+
+func TestAnswerCachePicksClosest(t *testing.T) {
+	c := testAnswerCache()
+	repo := odmtest.NewCollection[db.AnswerCacheModel]()
+	far, near := cacheKey([]float32{1, 0.2}), cacheKey([]float32{1, 0.05})
+	far.question, near.question = "remedy for fright", "remedy for a sudden fright"
+	require.NoError(t, c.store(t.Context(), repo, far, "Far.", 3, cacheNow))
+	require.NoError(t, c.store(t.Context(), repo, near, "Near.", 3, cacheNow))
+
+	best, err := c.closest(t.Context(), repo, cacheKey([]float32{1, 0}), cacheNow)
+	require.NoError(t, err)
+	require.NotNil(t, best)
+	assert.Equal(t, "Near.", best.Answer)
+}
+
+func TestAnswerCacheLookupFilters(t *testing.T) {
+	c := testAnswerCache()
+	repo := odmtest.NewCollection[db.AnswerCacheModel]()
+	require.NoError(t, c.store(t.Context(), repo, cacheKey([]float32{1, 0}), "Public.", 3, cacheNow))
+	require.NoError(t, c.store(t.Context(), repo, cacheKey([]float32{1, 0}, "oncology", "cardiology"), "Restricted.", 3, cacheNow))
+
+	for _, tc := range []struct {
+		name string
+		key  func(answerCacheKey) answerCacheKey
+		now  time.Time
+		want string
+	}{
+		{name: "NoGroupsSeesPublic", key: func(k answerCacheKey) answerCacheKey { return k }, now: cacheNow, want: "Public."},
+		{name: "SameGroupsInAnyOrder", key: func(k answerCacheKey) answerCacheKey {
+			k.accessGroups = db.NormalizeAccessGroups([]string{"cardiology", "oncology"})
+			return k
+		}, now: cacheNow, want: "Restricted."},
+		{name: "FewerGroups", key: func(k answerCacheKey) answerCacheKey {
+			k.accessGroups = []string{"oncology"}
+			return k
+		}, now: cacheNow},
+		{name: "OtherGroups", key: func(k answerCacheKey) answerCacheKey {
+			k.accessGroups = []string{"pediatrics"}
+			return k
+		}, now: cacheNow},
+		{name: "OtherModel", key: func(k answerCacheKey) answerCacheKey {
+			k.model = "claude"
+			return k
+		}, now: cacheNow},
+		{name: "OtherCorpusVersion", key: func(k answerCacheKey) answerCacheKey {
+			k.corpusVersion = 4
+			return k
+		}, now: cacheNow},
+		{name: "OtherConfig", key: func(k answerCacheKey) answerCacheKey {
+			k.configHash = "edited"
+			return k
+		}, now: cacheNow},
+		{name: "Expired", key: func(k answerCacheKey) answerCacheKey { return k }, now: cacheNow.Add(time.Hour)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			best, err := c.closest(t.Context(), repo, tc.key(cacheKey([]float32{1, 0})), tc.now)
+			require.NoError(t, err)
+			if tc.want == "" {
+				assert.Nil(t, best)
+				return
+			}
+			require.NotNil(t, best)
+			assert.Equal(t, tc.want, best.Answer)
+		})
+	}
+}
+
+func TestAnswerCacheStoreSkipsChangedCorpus(t *testing.T) {
+	c := testAnswerCache()
+	repo := odmtest.NewCollection[db.AnswerCacheModel]()
+
+	// the corpus moved on to version 4 while the agent answered at version 3
+	require.NoError(t, c.store(t.Context(), repo, cacheKey([]float32{1, 0}), "Stale.", 4, cacheNow))
+	assert.Empty(t, repo.Docs())
+
+	require.NoError(t, c.store(t.Context(), repo, cacheKey([]float32{1, 0}), "Aconite.", 3, cacheNow))
+	require.NoError(t, c.store(t.Context(), repo, cacheKey([]float32{1, 0}), "Aconite 30C.", 3, cacheNow))
+	docs := repo.Docs()
+	require.Len(t, docs, 1, "a second answer to the same question replaces the first")
+	assert.Equal(t, "Aconite 30C.", docs[0].Answer)
+	assert.Equal(t, cacheNow.Add(time.Hour), docs[0].ExpiresAt.UTC())
+}
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"context"
+	"maps"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/medicine-rag/core/lang"
+	"github.com/ollama/ollama/api"
+)
+
+// englishQueries translates search queries the tool selector wrote in the user's
+// language before they run, since the corpus is English.
+func englishQueries(handler func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk, model llm.LLMClient) func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+	return func(ctx context.Context, params api.ToolCallFunctionArguments) <-chan *schema.ToolResultChunk {
+		if query, ok := params["query"].(string); ok {
+			params = maps.Clone(params)
+			params["query"] = lang.ToEnglish(ctx, model, query)
+		}
+		return handler(ctx, params)
+	}
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/zap"
+)
+
+// sourceHistory is what the corpus holds for one source document.
+type sourceHistory struct {
+	URI           string `bson:"_id"`
+	LiveChunks    int64  `bson:"liveChunks"`
+	LatestAdded   int64  `bson:"latestAdded"`   // newest corpus version among its live chunks
+	LatestRetired int64  `bson:"latestRetired"` // newest corpus version that retired one of its chunks
+}
+
+// stampAnswerVersions records on each answer the corpus version it was generated from.
+// Provenance is appended once per answer, while agent-boot trims the oldest messages, so
+// the two are matched up from the most recent answer backwards.
+func stampAnswerVersions(transcript *pb.ConversationTranscript, provenance []db.AnswerProvenance) {
+	next := len(provenance) - 1
+	for i := len(transcript.Messages) - 1; i >= 0 && next >= 0; i-- {
+		if msg := transcript.Messages[i]; msg.Role == "assistant" {
+			msg.CorpusVersion = provenance[next].CorpusVersion
+			next--
+		}
+	}
+}
+
+// markSourceChanges flags the cited sources of each answer that have been re-ingested or
+// removed since the answer was generated. Answers without a recorded corpus version are
+// left alone, as are attributions that never named a corpus document, such as a
+// repertory. Failing to check is not an error: the transcript is served unmarked.
+func markSourceChanges(ctx context.Context, mongo odm.MongoClient, tenant string, transcript *pb.ConversationTranscript) {
+	var uris []string
+	for _, msg := range transcript.Messages {
+		if msg.CorpusVersion == 0 {
+			continue
+		}
+		for _, source := range msg.Sources {
+			uris = append(uris, sourceURIs(source.Attribution)...)
+		}
+	}
+	if len(uris) == 0 {
+		return
+	}
+
+	histories, err := loadSourceHistories(ctx, mongo, tenant, uris)
+	if err != nil {
+		logger.Error("Failed to check sources for changes", zap.String("tenant", tenant), zap.Error(err))
+		return
+	}
+
+	for _, msg := range transcript.Messages {
+		if msg.CorpusVersion == 0 {
+			continue
+		}
+		for i, source := range msg.Sources {
+			if change := sourceChange(histories, source.Attribution, msg.CorpusVersion); change != nil {
+				change.SourceIndex = int32(i)
+				msg.SourceChanges = append(msg.SourceChanges, change)
+			}
+		}
+	}
+}
+
+// sourceChange reports the latest change to the documents behind an attribution after
+// the given corpus version, or nil if they are unchanged. A remedy profile drawn from
+// several documents counts as removed only once all of them are gone.
+func sourceChange(histories map[string]sourceHistory, attribution string, since int64) *pb.SourceChange {
+	var (
+		change  *pb.SourceChange
+		removed = true
+	)
+	for _, uri := range sourceURIs(attribution) {
+		history, ok := histories[uri]
+		if !ok {
+			continue
+		}
+
+		if history.LiveChunks > 0 {
+			removed = false
+			if history.LatestAdded > since && (change == nil || history.LatestAdded > change.ChangedInVersion) {
+				change = &pb.SourceChange{ChangedInVersion: history.LatestAdded}
+			}
+		} else if history.LatestRetired > since && (change == nil || history.LatestRetired > change.ChangedInVersion) {
+			change = &pb.SourceChange{ChangedInVersion: history.LatestRetired}
+		}
+	}
+
+	if change != nil {
+		change.Removed = removed
+	}
+	return change
+}
+
+func loadSourceHistories(ctx context.Context, client odm.MongoClient, tenant string, uris []string) (map[string]sourceHistory, error) {
+	retired := bson.M{"$gt": bson.A{"$retiredVersion", 0}}
+	cursor, err := client.Database(tenant).Collection(db.ChunkModel{}.CollectionName()).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"sourceUri": bson.M{"$in": uris}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":           "$sourceUri",
+			"liveChunks":    bson.M{"$sum": bson.M{"$cond": bson.A{retired, 0, 1}}},
+			"latestAdded":   bson.M{"$max": bson.M{"$cond": bson.A{retired, 0, "$corpusVersion"}}},
+			"latestRetired": bson.M{"$max": "$retiredVersion"},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []sourceHistory
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	histories := make(map[string]sourceHistory, len(groups))
+	for _, history := range groups {
+		histories[history.URI] = history
+	}
+	return histories, nil
+}
+
+// sourceURIs splits an attribution into the documents it names. Search results carry a
+// single source URI; remedy profiles list every document they were drawn from.
+func sourceURIs(attribution string) []string {
+	var uris []string
+	for _, uri := range strings.Split(attribution, ", ") {
+		if uri = strings.TrimSpace(uri); uri != "" {
+			uris = append(uris, uri)
+		}
+	}
+	return uris
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/auth"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/apikeys"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// How long a key is served from memory before it is re-read, i.e. how quickly a
+// rotation or revocation on another instance takes effect here.
+const apiKeyReloadInterval = 30 * time.Second
+
+// How often a key's lastUsedOn is written while it is in use.
+const apiKeyLastUsedInterval = time.Minute
+
+// ApiKeyGuard checks calls made with tokens exchanged for an API key: the key must still
+// be active, grant the method's scope and be within its rate limit. Calls made with a
+// login token pass through untouched. It runs after the auth interceptor, which has
+// already verified the token.
+type ApiKeyGuard struct {
+	mongo   odm.MongoClient
+	limiter *apikeys.Limiter
+
+	mu   sync.Mutex
+	keys map[string]apiKeyEntry // by tenant/keyId
+}
+
+type apiKeyEntry struct {
+	key        *db.ApiKeyModel // nil when the key does not exist
+	loadedAt   time.Time
+	lastUsedAt time.Time
+}
+
+func ProvideApiKeyGuard(mongo odm.MongoClient) *ApiKeyGuard {
+	return &ApiKeyGuard{
+		mongo:   mongo,
+		limiter: apikeys.NewLimiter(),
+		keys:    make(map[string]apiKeyEntry),
+	}
+}
+
+func (g *ApiKeyGuard) Unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := g.check(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (g *ApiKeyGuard) Stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := g.check(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// Forget drops a key from memory so a rotation or revocation applies on this instance at once.
+func (g *ApiKeyGuard) Forget(tenant, keyID string) {
+	g.mu.Lock()
+	delete(g.keys, tenant+"/"+keyID)
+	g.mu.Unlock()
+	g.limiter.Forget(keyID)
+}
+
+func (g *ApiKeyGuard) check(ctx context.Context, fullMethod string) error {
+	userType := auth.GetUserType(ctx)
+	if !apikeys.IsKeyToken(userType) {
+		return nil
+	}
+
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	keyID, ok := apikeys.KeyIDFromUserID(userId)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "Invalid API key token")
+	}
+
+	key, err := g.key(ctx, tenant, keyID)
+	if err != nil {
+		logger.Error("Failed to load API key", zap.String("tenant", tenant), zap.String("keyId", keyID), zap.Error(err))
+		return status.Error(codes.Unavailable, "Failed to check API key")
+	}
+	if key == nil || key.RevokedOn > 0 || !apikeys.IsTokenFor(userType, key.SecretHash) {
+		return status.Error(codes.Unauthenticated, "API key is revoked or was rotated")
+	}
+
+	scope := apikeys.ScopeForMethod(fullMethod)
+	if scope == "" || !slices.Contains(key.Scopes, scope) {
+		g.record(tenant, keyID, true)
+		if scope == "" {
+			return status.Error(codes.PermissionDenied, "This method cannot be called with an API key")
+		}
+		return status.Error(codes.PermissionDenied, "API key is missing the "+scope+" scope")
+	}
+
+	if !g.limiter.Allow(keyID, key.RequestsPerMinute, time.Now()) {
+		g.record(tenant, keyID, true)
+		return status.Error(codes.ResourceExhausted,
+			"API key is limited to "+strconv.Itoa(int(key.RequestsPerMinute))+" requests per minute")
+	}
+
+	g.record(tenant, keyID, false)
+	return nil
+}
+
+// key returns the tenant's key, or nil if it does not exist. If a reload fails the
+// previously loaded key keeps being served.
+func (g *ApiKeyGuard) key(ctx context.Context, tenant, keyID string) (*db.ApiKeyModel, error) {
+	cacheKey := tenant + "/" + keyID
+
+	g.mu.Lock()
+	entry, ok := g.keys[cacheKey]
+	g.mu.Unlock()
+
+	if ok && time.Since(entry.loadedAt) < apiKeyReloadInterval {
+		return entry.key, nil
+	}
+
+	key, err := loadApiKey(ctx, g.mongo, tenant, keyID)
+	if err != nil {
+		if ok {
+			return entry.key, nil
+		}
+		return nil, err
+	}
+
+	g.mu.Lock()
+	entry = g.keys[cacheKey]
+	entry.key, entry.loadedAt = key, time.Now()
+	g.keys[cacheKey] = entry
+	g.mu.Unlock()
+
+	return key, nil
+}
+
+// record counts a request against the key's usage for today, in the background so the
+// call is not held up. lastUsedOn is only written once a minute.
+func (g *ApiKeyGuard) record(tenant, keyID string, rejected bool) {
+	now := time.Now().UTC()
+
+	touch := false
+	if !rejected {
+		g.mu.Lock()
+		if entry, ok := g.keys[tenant+"/"+keyID]; ok && now.Sub(entry.lastUsedAt) >= apiKeyLastUsedInterval {
+			entry.lastUsedAt = now
+			g.keys[tenant+"/"+keyID] = entry
+			touch = true
+		}
+		g.mu.Unlock()
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		counter := "requests"
+		if rejected {
+			counter = "rejected"
+		}
+		day := now.Format(time.DateOnly)
+		_, err := g.mongo.Database(tenant).Collection(db.ApiKeyUsageModel{}.CollectionName()).UpdateOne(ctx,
+			bson.M{"_id": keyID + ":" + day},
+			bson.M{
+				"$inc":         bson.M{counter: 1},
+				"$setOnInsert": bson.M{"keyId": keyID, "day": day},
+			},
+			options.UpdateOne().SetUpsert(true),
+		)
+		if err != nil {
+			logger.Error("Failed to record API key usage", zap.String("tenant", tenant), zap.String("keyId", keyID), zap.Error(err))
+		}
+
+		if touch {
+			_, err = g.mongo.Database(tenant).Collection(db.ApiKeyModel{}.CollectionName()).UpdateOne(ctx,
+				bson.M{"_id": keyID}, bson.M{"$max": bson.M{"lastUsedOn": now.Unix()}})
+			if err != nil {
+				logger.Error("Failed to update API key last use", zap.String("tenant", tenant), zap.String("keyId", keyID), zap.Error(err))
+			}
+		}
+	}()
+}
+
+// loadApiKey returns the tenant's key, or nil if it does not exist.
+func loadApiKey(ctx context.Context, mongo odm.MongoClient, tenant, keyID string) (*db.ApiKeyModel, error) {
+	repo := odm.CollectionOf[db.ApiKeyModel](mongo, tenant)
+	exists, err := async.Await(repo.Exists(ctx, keyID))
+	if err != nil || !exists {
+		return nil, err
+	}
+	return async.Await(repo.FindOneByID(ctx, keyID))
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/auth"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/apikeys"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Usage is summed over this many days, today included.
+const apiKeyUsageDays = 30
+
+type ApiKeyService struct {
+	pb.UnimplementedApiKeysServer
+	mongo odm.MongoClient
+	guard *ApiKeyGuard
+}
+
+func ProvideApiKeyService(mongo odm.MongoClient, guard *ApiKeyGuard) *ApiKeyService {
+	return &ApiKeyService{
+		mongo: mongo,
+		guard: guard,
+	}
+}
+
+func (s *ApiKeyService) ListApiKeys(ctx context.Context, req *pb.ListApiKeysRequest) (*pb.ListApiKeysResponse, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	if err := s.requireTenantAdmin(ctx, tenant, userId); err != nil {
+		return nil, err
+	}
+
+	repo := odm.CollectionOf[db.ApiKeyModel](s.mongo, tenant)
+	keys, err := async.Await(repo.Find(ctx, bson.M{}, bson.D{{Key: "createdOn", Value: -1}}, 2*apikeys.MaxKeysPerTenant, 0))
+	if err != nil {
+		logger.Error("Failed to list API keys", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to list API keys")
+	}
+
+	now := time.Now().UTC()
+	today := now.Format(time.DateOnly)
+	usageRepo := odm.CollectionOf[db.ApiKeyUsageModel](s.mongo, tenant)
+	usage, err := async.Await(usageRepo.Find(ctx,
+		bson.M{"day": bson.M{"$gte": now.AddDate(0, 0, 1-apiKeyUsageDays).Format(time.DateOnly)}}, nil, 0, 0))
+	if err != nil {
+		logger.Error("Failed to load API key usage", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load API key usage")
+	}
+
+	resp := &pb.ListApiKeysResponse{MaxRequestsPerMinute: apikeys.MaxRequestsPerMinute}
+	for _, name := range apikeys.ScopeNames() {
+		resp.AvailableScopes = append(resp.AvailableScopes, &pb.ApiKeyScope{Name: name, Description: apikeys.Scopes[name].Description})
+	}
+	for _, key := range keys {
+		keyProto := apiKeyProto(key)
+		for _, day := range usage {
+			if day.KeyID != key.KeyID {
+				continue
+			}
+			keyProto.RequestsLast30Days += day.Requests
+			keyProto.RejectedLast30Days += day.Rejected
+			if day.Day == today {
+				keyProto.RequestsToday = day.Requests
+			}
+		}
+		resp.Keys = append(resp.Keys, keyProto)
+	}
+
+	return resp, nil
+}
+
+func (s *ApiKeyService) CreateApiKey(ctx context.Context, req *pb.CreateApiKeyRequest) (*pb.IssuedApiKey, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	if err := s.requireTenantAdmin(ctx, tenant, userId); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	if len([]rune(name)) > apikeys.MaxNameLength {
+		return nil, status.Errorf(codes.InvalidArgument, "name must be at most %d characters", apikeys.MaxNameLength)
+	}
+	scopes, err := apikeys.ValidateScopes(req.Scopes)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	perMinute, err := apikeys.RequestsPerMinute(req.RequestsPerMinute)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	repo := odm.CollectionOf[db.ApiKeyModel](s.mongo, tenant)
+	active, err := async.Await(repo.Count(ctx, bson.M{"revokedOn": bson.M{"$exists": false}}))
+	if err != nil {
+		logger.Error("Failed to count API keys", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to create API key")
+	}
+	if active >= apikeys.MaxKeysPerTenant {
+		return nil, status.Errorf(codes.FailedPrecondition, "at most %d API keys may be active; revoke one first", apikeys.MaxKeysPerTenant)
+	}
+
+	secret, keyID, secretHash, err := apikeys.Generate()
+	if err != nil {
+		logger.Error("Failed to generate API key", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to create API key")
+	}
+
+	key := db.ApiKeyModel{
+		KeyID:             keyID,
+		Name:              name,
+		SecretHash:        secretHash,
+		Scopes:            scopes,
+		RequestsPerMinute: perMinute,
+		CreatedBy:         userId,
+		CreatedOn:         time.Now().Unix(),
+	}
+	if _, err := async.Await(repo.Save(ctx, key)); err != nil {
+		logger.Error("Failed to save API key", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to create API key")
+	}
+
+	logger.Info("Created API key", zap.String("tenant", tenant), zap.String("keyId", keyID), zap.String("createdBy", userId),
+		zap.Strings("scopes", scopes))
+	return &pb.IssuedApiKey{Key: apiKeyProto(key), ApiKey: secret}, nil
+}
+
+func (s *ApiKeyService) RotateApiKey(ctx context.Context, req *pb.RotateApiKeyRequest) (*pb.IssuedApiKey, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	key, err := s.activeKey(ctx, tenant, userId, req.KeyId)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, secretHash, err := apikeys.Rotate(key.KeyID)
+	if err != nil {
+		logger.Error("Failed to generate API key", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to rotate API key")
+	}
+	key.SecretHash = secretHash
+	key.RotatedOn = time.Now().Unix()
+	if _, err := async.Await(odm.CollectionOf[db.ApiKeyModel](s.mongo, tenant).Save(ctx, *key)); err != nil {
+		logger.Error("Failed to save API key", zap.String("tenant", tenant), zap.String("keyId", key.KeyID), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to rotate API key")
+	}
+	s.guard.Forget(tenant, key.KeyID)
+
+	logger.Info("Rotated API key", zap.String("tenant", tenant), zap.String("keyId", key.KeyID), zap.String("rotatedBy", userId))
+	return &pb.IssuedApiKey{Key: apiKeyProto(*key), ApiKey: secret}, nil
+}
+
+func (s *ApiKeyService) RevokeApiKey(ctx context.Context, req *pb.RevokeApiKeyRequest) (*pb.ApiKey, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	key, err := s.activeKey(ctx, tenant, userId, req.KeyId)
+	if err != nil {
+		return nil, err
+	}
+
+	key.RevokedOn = time.Now().Unix()
+	if _, err := async.Await(odm.CollectionOf[db.ApiKeyModel](s.mongo, tenant).Save(ctx, *key)); err != nil {
+		logger.Error("Failed to save API key", zap.String("tenant", tenant), zap.String("keyId", key.KeyID), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to revoke API key")
+	}
+	s.guard.Forget(tenant, key.KeyID)
+
+	logger.Info("Revoked API key", zap.String("tenant", tenant), zap.String("keyId", key.KeyID), zap.String("revokedBy", userId))
+	return apiKeyProto(*key), nil
+}
+
+// activeKey loads a key an admin may rotate or revoke.
+func (s *ApiKeyService) activeKey(ctx context.Context, tenant, userId, keyID string) (*db.ApiKeyModel, error) {
+	if err := s.requireTenantAdmin(ctx, tenant, userId); err != nil {
+		return nil, err
+	}
+	if keyID == "" {
+		return nil, status.Error(codes.InvalidArgument, "keyId is required")
+	}
+
+	key, err := loadApiKey(ctx, s.mongo, tenant, keyID)
+	if err != nil {
+		logger.Error("Failed to load API key", zap.String("tenant", tenant), zap.String("keyId", keyID), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load API key")
+	}
+	if key == nil {
+		return nil, status.Error(codes.NotFound, "API key not found")
+	}
+	if key.RevokedOn > 0 {
+		return nil, status.Error(codes.FailedPrecondition, "API key is revoked")
+	}
+	return key, nil
+}
+
+// requireTenantAdmin lets through users an operator has made tenant admin. API keys can
+// never manage keys, whatever their creator's role.
+func (s *ApiKeyService) requireTenantAdmin(ctx context.Context, tenant, userId string) error {
+	if apikeys.IsKeyToken(auth.GetUserType(ctx)) {
+		return status.Error(codes.PermissionDenied, "API keys cannot manage API keys")
+	}
+
+	isAdmin, err := isTenantAdmin(ctx, s.mongo, tenant, userId)
+	if err != nil {
+		logger.Error("Failed to load user", zap.String("tenant", tenant), zap.String("userId", userId), zap.Error(err))
+		return status.Error(codes.Internal, "Failed to load user")
+	}
+	if isAdmin {
+		return nil
+	}
+	return status.Error(codes.PermissionDenied, "Only tenant admins can manage API keys")
+}
+
+// isTenantAdmin reports whether userId is a login an operator has made tenant admin.
+func isTenantAdmin(ctx context.Context, mongo odm.MongoClient, tenant, userId string) (bool, error) {
+	repo := odm.CollectionOf[db.LoginModel](mongo, tenant)
+	exists, err := async.Await(repo.Exists(ctx, userId))
+	if err != nil || !exists {
+		return false, err
+	}
+
+	login, err := async.Await(repo.FindOneByID(ctx, userId))
+	if err != nil {
+		return false, err
+	}
+	return login.Role == db.RoleAdmin, nil
+}
+
+func apiKeyProto(key db.ApiKeyModel) *pb.ApiKey {
+	return &pb.ApiKey{
+		KeyId:             key.KeyID,
+		Name:              key.Name,
+		Display:           apikeys.Display(key.KeyID),
+		Scopes:            key.Scopes,
+		RequestsPerMinute: key.RequestsPerMinute,
+		CreatedOn:         key.CreatedOn,
+		RotatedOn:         key.RotatedOn,
+		RevokedOn:         key.RevokedOn,
+		LastUsedOn:        key.LastUsedOn,
+	}
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"github.com/SaiNageswarS/agent-boot/agentboot"
+	"github.com/SaiNageswarS/agent-boot/schema"
+)
+
+// completionReporter passes every chunk through to the client and lets the service
+// annotate the final StreamComplete before it is sent. agent-boot builds that chunk
+// itself, so this is the only place to attach per-answer metadata.
+type completionReporter struct {
+	agentboot.ProgressReporter
+	onComplete []func(*schema.StreamComplete)
+	failed     bool
+	failure    string
+}
+
+func newCompletionReporter(inner agentboot.ProgressReporter, onComplete ...func(*schema.StreamComplete)) *completionReporter {
+	return &completionReporter{
+		ProgressReporter: inner,
+		onComplete:       onComplete,
+	}
+}
+
+func (r *completionReporter) Send(event *schema.AgentStreamChunk) error {
+	if streamErr, ok := event.ChunkType.(*schema.AgentStreamChunk_Error); ok {
+		r.failed = true
+		if streamErr.Error != nil {
+			r.failure = streamErr.Error.ErrorMessage
+		}
+	}
+	if complete, ok := event.ChunkType.(*schema.AgentStreamChunk_Complete); ok && complete.Complete != nil {
+		if complete.Complete.Metadata == nil {
+			complete.Complete.Metadata = make(map[string]string)
+		}
+		for _, fn := range r.onComplete {
+			fn(complete.Complete)
+		}
+	}
+
+	return r.ProgressReporter.Send(event)
+}
+
+// Failed reports whether an error was streamed. agent-boot reports inference failures
+// as stream events and still completes, so this is the only way to tell.
+func (r *completionReporter) Failed() bool {
+	return r.failed
+}
+
+// Failure is the message of the last error streamed.
+func (r *completionReporter) Failure() string {
+	return r.failure
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/guardrails"
+	"go.uber.org/zap"
+)
+
+// contentPolicy builds the tenant's stop sequences and banned-content rules. Rules with
+// invalid patterns are skipped so the others still apply.
+func contentPolicy(tenant string, config db.AgentConfigModel) *guardrails.ContentPolicy {
+	rules := make([]guardrails.ContentRule, 0, len(config.ContentRules))
+	for _, rule := range config.ContentRules {
+		rules = append(rules, guardrails.ContentRule{
+			Name:        rule.Name,
+			Pattern:     rule.Pattern,
+			Action:      guardrails.ContentAction(rule.Action),
+			Replacement: rule.Replacement,
+		})
+	}
+
+	policy, errs := guardrails.NewContentPolicy(config.StopSequences, rules)
+	for _, err := range errs {
+		logger.Error("Skipping content rule", zap.String("tenant", tenant), zap.Error(err))
+	}
+	return policy
+}
+
+// disclaimerPolicy builds the disclaimers for the tenant's jurisdiction.
+func disclaimerPolicy(tenantConfig *db.TenantConfigModel, config db.AgentConfigModel) *guardrails.DisclaimerPolicy {
+	disclaimers := make([]guardrails.Disclaimer, 0, len(config.Disclaimers))
+	for _, disclaimer := range config.Disclaimers {
+		disclaimers = append(disclaimers, guardrails.Disclaimer{
+			Name:          disclaimer.Name,
+			Text:          disclaimer.Text,
+			Category:      guardrails.AnswerCategory(strings.ToLower(strings.TrimSpace(disclaimer.Category))),
+			Jurisdictions: disclaimer.Jurisdictions,
+			Position:      guardrails.DisclaimerPosition(strings.ToLower(strings.TrimSpace(disclaimer.Position))),
+		})
+	}
+	return guardrails.NewDisclaimerPolicy(tenantConfig.Jurisdiction, disclaimers)
+}
+
+// recordContentViolations keeps each banned-content match for review.
+func recordContentViolations(ctx context.Context, mongo odm.MongoClient, tenant, sessionId, userId, question string, violations []guardrails.ContentViolation) {
+	now := time.Now()
+	repo := odm.CollectionOf[db.ContentViolationModel](mongo, tenant)
+	for i, violation := range violations {
+		logger.Info("Answer matched banned content",
+			zap.String("tenant", tenant), zap.String("sessionId", sessionId),
+			zap.String("rule", violation.Rule), zap.String("action", string(violation.Action)))
+
+		violationId, _ := odm.HashedKey(tenant, userId, sessionId, strconv.FormatInt(now.UnixNano(), 10), strconv.Itoa(i))
+		_, err := async.Await(repo.Save(ctx, db.ContentViolationModel{
+			ViolationID: violationId,
+			SessionID:   sessionId,
+			UserID:      userId,
+			Rule:        violation.Rule,
+			Match:       violation.Match,
+			Action:      string(violation.Action),
+			Question:    question,
+			CreatedOn:   now.Unix(),
+		}))
+		if err != nil {
+			logger.Error("Failed to save content violation", zap.String("tenant", tenant), zap.String("rule", violation.Rule), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/medicine-rag/core/titles"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+)
+
+const maxImportedConversations = 5000
+
+// importedConversation is one conversation parsed from an external export.
+type importedConversation struct {
+	Title     string
+	CreatedOn int64
+	Messages  []llm.Message
+}
+
+// title is the export's title, or the first question when the export has none.
+func (c importedConversation) title() string {
+	if title := titles.FromQuestion(c.Title); title != "" {
+		return title
+	}
+	for _, message := range c.Messages {
+		if message.Role == "user" {
+			return titles.FromQuestion(message.Content)
+		}
+	}
+	return ""
+}
+
+// parseConversationExport turns an external chat export into conversations. Only user
+// and assistant turns are kept; system prompts, tool calls and attachments are dropped.
+func parseConversationExport(format pb.ImportFormat, content []byte) ([]importedConversation, error) {
+	if format == pb.ImportFormat_IMPORT_FORMAT_AUTO {
+		format = detectImportFormat(content)
+	}
+
+	switch format {
+	case pb.ImportFormat_IMPORT_FORMAT_CHATGPT:
+		return parseChatGPTExport(content)
+	case pb.ImportFormat_IMPORT_FORMAT_CLAUDE:
+		return parseClaudeExport(content)
+	case pb.ImportFormat_IMPORT_FORMAT_MESSAGES:
+		return parseMessagesExport(content)
+	case pb.ImportFormat_IMPORT_FORMAT_MARKDOWN:
+		return parseMarkdownExport(content)
+	default:
+		return nil, errors.New("unsupported import format")
+	}
+}
+
+func detectImportFormat(content []byte) pb.ImportFormat {
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) == 0 || (trimmed[0] != '[' && trimmed[0] != '{') {
+		return pb.ImportFormat_IMPORT_FORMAT_MARKDOWN
+	}
+
+	switch {
+	case bytes.Contains(trimmed, []byte(`"mapping"`)):
+		return pb.ImportFormat_IMPORT_FORMAT_CHATGPT
+	case bytes.Contains(trimmed, []byte(`"chat_messages"`)):
+		return pb.ImportFormat_IMPORT_FORMAT_CLAUDE
+	default:
+		return pb.ImportFormat_IMPORT_FORMAT_MESSAGES
+	}
+}
+
+// normalizeRole maps the role names used by other assistants onto user/assistant.
+func normalizeRole(role string) string {
+	switch strings.ToLower(strings.TrimSpace(role)) {
+	case "user", "human", "you", "me", "patient", "doctor", "physician":
+		return "user"
+	case "assistant", "ai", "bot", "model", "chatgpt", "gpt", "claude", "gemini", "copilot":
+		return "assistant"
+	default:
+		return ""
+	}
+}
+
+func appendTurn(messages []llm.Message, role, content string) []llm.Message {
+	role, content = normalizeRole(role), strings.TrimSpace(content)
+	if role == "" || content == "" {
+		return messages
+	}
+	return append(messages, llm.Message{Role: role, Content: content})
+}
+
+// ChatGPT exports store each conversation as a tree of nodes (edits create branches).
+// The visible thread is the path from current_node back to the root.
+func parseChatGPTExport(content []byte) ([]importedConversation, error) {
+	var export []struct {
+		Title       string  `json:"title"`
+		CreateTime  float64 `json:"create_time"`
+		CurrentNode string  `json:"current_node"`
+		Mapping     map[string]struct {
+			Parent  string `json:"parent"`
+			Message *struct {
+				Author struct {
+					Role string `json:"role"`
+				} `json:"author"`
+				Content struct {
+					Parts []any `json:"parts"`
+				} `json:"content"`
+			} `json:"message"`
+		} `json:"mapping"`
+	}
+	if err := json.Unmarshal(content, &export); err != nil {
+		return nil, errors.New("invalid ChatGPT export: " + err.Error())
+	}
+
+	conversations := make([]importedConversation, 0, len(export))
+	for _, c := range export {
+		var path []string
+		seen := make(map[string]bool)
+		for id := c.CurrentNode; id != "" && !seen[id]; id = c.Mapping[id].Parent {
+			seen[id] = true
+			path = append(path, id)
+		}
+
+		var messages []llm.Message
+		for i := len(path) - 1; i >= 0; i-- {
+			node := c.Mapping[path[i]]
+			if node.Message == nil {
+				continue
+			}
+
+			var text []string
+			for _, part := range node.Message.Content.Parts {
+				if s, ok := part.(string); ok {
+					text = append(text, s)
+				}
+			}
+			messages = appendTurn(messages, node.Message.Author.Role, strings.Join(text, "\n"))
+		}
+
+		conversations = append(conversations, importedConversation{
+			Title:     c.Title,
+			CreatedOn: int64(c.CreateTime),
+			Messages:  messages,
+		})
+	}
+	return conversations, nil
+}
+
+func parseClaudeExport(content []byte) ([]importedConversation, error) {
+	var export []struct {
+		Name         string `json:"name"`
+		CreatedAt    string `json:"created_at"`
+		ChatMessages []struct {
+			Sender    string `json:"sender"`
+			Text      string `json:"text"`
+			CreatedAt string `json:"created_at"`
+		} `json:"chat_messages"`
+	}
+	if err := json.Unmarshal(content, &export); err != nil {
+		return nil, errors.New("invalid Claude export: " + err.Error())
+	}
+
+	conversations := make([]importedConversation, 0, len(export))
+	for _, c := range export {
+		sort.SliceStable(c.ChatMessages, func(i, j int) bool {
+			return c.ChatMessages[i].CreatedAt < c.ChatMessages[j].CreatedAt
+		})
+
+		var messages []llm.Message
+		for _, m := range c.ChatMessages {
+			messages = appendTurn(messages, m.Sender, m.Text)
+		}
+
+		conversations = append(conversations, importedConversation{
+			Title:     c.Name,
+			CreatedOn: parseExportTime(c.CreatedAt),
+			Messages:  messages,
+		})
+	}
+	return conversations, nil
+}
+
+type exportedMessages struct {
+	Title     string `json:"title"`
+	CreatedOn int64  `json:"createdOn"`
+	Messages  []struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"messages"`
+}
+
+// The generic format accepts one conversation, an array of them, or a bare array of messages.
+func parseMessagesExport(content []byte) ([]importedConversation, error) {
+	trimmed := bytes.TrimSpace(content)
+
+	var exports []exportedMessages
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var single exportedMessages
+		if err := json.Unmarshal(trimmed, &single); err != nil {
+			return nil, errors.New("invalid messages export: " + err.Error())
+		}
+		exports = append(exports, single)
+	} else if err := json.Unmarshal(trimmed, &exports); err != nil || !hasMessages(exports) {
+		var bare exportedMessages
+		if err := json.Unmarshal(trimmed, &bare.Messages); err != nil {
+			return nil, errors.New("invalid messages export: " + err.Error())
+		}
+		exports = []exportedMessages{bare}
+	}
+
+	conversations := make([]importedConversation, 0, len(exports))
+	for _, e := range exports {
+		var messages []llm.Message
+		for _, m := range e.Messages {
+			messages = appendTurn(messages, m.Role, m.Content)
+		}
+		conversations = append(conversations, importedConversation{Title: e.Title, CreatedOn: e.CreatedOn, Messages: messages})
+	}
+	return conversations, nil
+}
+
+func hasMessages(exports []exportedMessages) bool {
+	for _, e := range exports {
+		if len(e.Messages) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// A turn starts at a line naming the speaker: "User:", "**Assistant:**", "## ChatGPT" and so on.
+var markdownTurn = regexp.MustCompile(`^(?:#{1,6}\s*)?(?:\*\*)?([A-Za-z]+)(?:\*\*)?\s*(?::\s*(?:\*\*)?\s*(.*))?$`)
+
+func parseMarkdownExport(content []byte) ([]importedConversation, error) {
+	var (
+		conversation importedConversation
+		role         string
+		body         strings.Builder
+	)
+
+	flush := func() {
+		conversation.Messages = appendTurn(conversation.Messages, role, body.String())
+		body.Reset()
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if conversation.Title == "" && role == "" && strings.HasPrefix(line, "# ") && normalizeRole(strings.TrimPrefix(line, "# ")) == "" {
+			conversation.Title = strings.TrimSpace(strings.TrimPrefix(line, "# "))
+			continue
+		}
+
+		if m := markdownTurn.FindStringSubmatch(strings.TrimSpace(line)); m != nil && normalizeRole(m[1]) != "" &&
+			(strings.Contains(line, ":") || strings.HasPrefix(strings.TrimSpace(line), "#")) {
+			flush()
+			role = m[1]
+			body.WriteString(m[2])
+			continue
+		}
+
+		if body.Len() > 0 {
+			body.WriteString("\n")
+		}
+		body.WriteString(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.New("invalid Markdown export: " + err.Error())
+	}
+	flush()
+
+	return []importedConversation{conversation}, nil
+}
+
+func parseExportTime(value string) int64 {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t.Unix()
+	}
+	return 0
+}
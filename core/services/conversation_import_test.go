@@ -0,0 +1,122 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChatGPTExport(t *testing.T) {
+	// the user edited their question, so the tree has two branches; current_node picks one
+	export := `[{
+		"title": "Remedies for fright",
+		"create_time": 1700000000.5,
+		"current_node": "answer",
+		"mapping": {
+			"root": {"parent": "", "message": null},
+			"system": {"parent": "root", "message": {"author": {"role": "system"}, "content": {"parts": ["You are helpful."]}}},
+			"draft": {"parent": "system", "message": {"author": {"role": "user"}, "content": {"parts": ["Remedy for fear?"]}}},
+			"question": {"parent": "system", "message": {"author": {"role": "user"}, "content": {"parts": ["Remedy for sudden fright?"]}}},
+			"answer": {"parent": "question", "message": {"author": {"role": "assistant"}, "content": {"parts": ["Aconite.", {"image": "x"}]}}}
+		}
+	}]`
+
+	conversations, err := parseConversationExport(pb.ImportFormat_IMPORT_FORMAT_AUTO, []byte(export))
+	require.NoError(t, err)
+	require.Len(t, conversations, 1)
+	assert.Equal(t, "Remedies for fright", conversations[0].Title)
+	assert.EqualValues(t, 1700000000, conversations[0].CreatedOn)
+	assert.Equal(t, []llm.Message{
+		{Role: "user", Content: "Remedy for sudden fright?"},
+		{Role: "assistant", Content: "Aconite."},
+	}, conversations[0].Messages)
+}
+
+func TestParseClaudeExport(t *testing.T) {
+	export := `[{
+		"name": "Restlessness at night",
+		"created_at": "2024-05-01T10:00:00Z",
+		"chat_messages": [
+			{"sender": "assistant", "text": "Arsenicum album.", "created_at": "2024-05-01T10:00:05Z"},
+			{"sender": "human", "text": "Remedy for restlessness after midnight?", "created_at": "2024-05-01T10:00:00Z"}
+		]
+	}]`
+
+	conversations, err := parseConversationExport(pb.ImportFormat_IMPORT_FORMAT_AUTO, []byte(export))
+	require.NoError(t, err)
+	require.Len(t, conversations, 1)
+	assert.Equal(t, "Restlessness at night", conversations[0].Title)
+	assert.EqualValues(t, 1714557600, conversations[0].CreatedOn)
+	assert.Equal(t, []llm.Message{
+		{Role: "user", Content: "Remedy for restlessness after midnight?"},
+		{Role: "assistant", Content: "Arsenicum album."},
+	}, conversations[0].Messages, "in the order they were sent")
+}
+
+func TestParseMessagesExport(t *testing.T) {
+	want := []llm.Message{{Role: "user", Content: "Bryonia modalities?"}, {Role: "assistant", Content: "Worse from motion."}}
+
+	for name, export := range map[string]string{
+		"Single": `{"title": "Bryonia", "createdOn": 42, "messages": [
+			{"role": "user", "content": "Bryonia modalities?"}, {"role": "tool", "content": "ignored"}, {"role": "assistant", "content": "Worse from motion."}]}`,
+		"Array": `[{"title": "Bryonia", "createdOn": 42, "messages": [
+			{"role": "user", "content": "Bryonia modalities?"}, {"role": "assistant", "content": "Worse from motion."}]}]`,
+		"Bare": `[{"role": "user", "content": "Bryonia modalities?"}, {"role": "bot", "content": "Worse from motion."}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			conversations, err := parseConversationExport(pb.ImportFormat_IMPORT_FORMAT_AUTO, []byte(export))
+			require.NoError(t, err)
+			require.Len(t, conversations, 1)
+			assert.Equal(t, want, conversations[0].Messages)
+			if name != "Bare" {
+				assert.Equal(t, "Bryonia", conversations[0].Title)
+				assert.EqualValues(t, 42, conversations[0].CreatedOn)
+			}
+		})
+	}
+
+	_, err := parseConversationExport(pb.ImportFormat_IMPORT_FORMAT_MESSAGES, []byte(`{"messages": 1}`))
+	assert.Error(t, err)
+}
+
+func TestParseMarkdownExport(t *testing.T) {
+	export := `# Grief after a loss
+
+**User:** Which remedy for silent grief?
+
+## Assistant
+Ignatia, especially with sighing.
+Natrum muriaticum when it is long-standing.
+
+ChatGPT: Anything else?
+`
+
+	conversations, err := parseConversationExport(pb.ImportFormat_IMPORT_FORMAT_AUTO, []byte(export))
+	require.NoError(t, err)
+	require.Len(t, conversations, 1)
+	assert.Equal(t, "Grief after a loss", conversations[0].Title)
+	assert.Equal(t, []llm.Message{
+		{Role: "user", Content: "Which remedy for silent grief?"},
+		{Role: "assistant", Content: "Ignatia, especially with sighing.\nNatrum muriaticum when it is long-standing."},
+		{Role: "assistant", Content: "Anything else?"},
+	}, conversations[0].Messages)
+}
+
+func TestParseMarkdownExportLineTooLong(t *testing.T) {
+	export := "User: " + strings.Repeat("a", 5*1024*1024)
+
+	_, err := parseConversationExport(pb.ImportFormat_IMPORT_FORMAT_MARKDOWN, []byte(export))
+	assert.Error(t, err, "a line the scanner can't read fails the import instead of truncating it")
+}
+
+func TestImportedConversationTitle(t *testing.T) {
+	titled := importedConversation{Title: "  Grief   after a loss ", Messages: []llm.Message{{Role: "user", Content: "Ignatia?"}}}
+	assert.Equal(t, "Grief after a loss", titled.title())
+
+	untitled := importedConversation{Messages: []llm.Message{{Role: "assistant", Content: "Hello."}, {Role: "user", Content: "Which remedy for silent grief?"}}}
+	assert.Equal(t, "Which remedy for silent grief?", untitled.title(), "titled with the first question")
+}
@@ -0,0 +1,373 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/auth"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/prompts"
+	"github.com/SaiNageswarS/medicine-rag/core/titles"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultShareTTL = 72 * time.Hour
+	maxShareTTL     = 30 * 24 * time.Hour
+)
+
+type ConversationService struct {
+	pb.UnimplementedConversationServer
+	mongo odm.MongoClient
+}
+
+func ProvideConversationService(mongo odm.MongoClient) *ConversationService {
+	return &ConversationService{
+		mongo: mongo,
+	}
+}
+
+// Shared transcripts are opened by people without an account, so only that RPC skips auth.
+func (s *ConversationService) AuthFuncOverride(ctx context.Context, fullMethodName string) (context.Context, error) {
+	if fullMethodName == pb.Conversation_GetSharedConversation_FullMethodName {
+		return ctx, nil
+	}
+
+	return auth.VerifyToken()(ctx)
+}
+
+func (s *ConversationService) ShareConversation(ctx context.Context, req *pb.ShareConversationRequest) (*pb.ShareConversationResponse, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	if req.SessionId == "" {
+		return nil, status.Error(codes.InvalidArgument, "sessionId is required")
+	}
+
+	tenantConfig, err := loadTenantConfig(ctx, s.mongo, tenant)
+	if err != nil {
+		logger.Error("Failed to load tenant config", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load tenant config")
+	}
+	if !tenantConfig.ShareLinksEnabled {
+		return nil, status.Error(codes.PermissionDenied, "Sharing is disabled for this tenant")
+	}
+
+	conversation, err := async.Await(odm.CollectionOf[db.ConversationModel](s.mongo, tenant).FindOneByID(ctx, req.SessionId))
+	if err != nil || conversation == nil || len(conversation.Messages) == 0 {
+		return nil, status.Error(codes.NotFound, "Conversation not found")
+	}
+	// a conversation saved before it recorded its owner has no one entitled to publish it
+	if conversation.UserID == "" || conversation.UserID != userId {
+		return nil, status.Error(codes.PermissionDenied, "Only the owner can share this conversation")
+	}
+
+	ttl := defaultShareTTL
+	if req.ExpiresInHours > 0 {
+		ttl = min(time.Duration(req.ExpiresInHours)*time.Hour, maxShareTTL)
+	}
+	expiresAt := time.Now().Add(ttl).Unix()
+
+	token, err := signShareToken(shareClaims{Tenant: tenant, SessionId: req.SessionId, ExpiresAt: expiresAt})
+	if err != nil {
+		logger.Error("Failed to sign share token", zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to create share link")
+	}
+
+	logger.Info("Conversation shared", zap.String("tenant", tenant), zap.String("sessionId", req.SessionId), zap.Int64("expiresAt", expiresAt))
+	return &pb.ShareConversationResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (s *ConversationService) GetSharedConversation(ctx context.Context, req *pb.GetSharedConversationRequest) (*pb.ConversationTranscript, error) {
+	claims, err := verifyShareToken(req.Token, time.Now())
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	// Disabling sharing for a tenant also revokes links that are already out.
+	tenantConfig, err := loadTenantConfig(ctx, s.mongo, claims.Tenant)
+	if err != nil || !tenantConfig.ShareLinksEnabled {
+		return nil, status.Error(codes.PermissionDenied, "Sharing is disabled for this tenant")
+	}
+
+	conversation, err := async.Await(odm.CollectionOf[db.ConversationModel](s.mongo, claims.Tenant).FindOneByID(ctx, claims.SessionId))
+	if err != nil || conversation == nil {
+		return nil, status.Error(codes.NotFound, "Conversation not found")
+	}
+
+	transcript := buildTranscript(conversation, tenantConfig)
+	transcript.ExpiresAt = claims.ExpiresAt
+	return transcript, nil
+}
+
+func (s *ConversationService) GetConversation(ctx context.Context, req *pb.GetConversationRequest) (*pb.ConversationTranscript, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	if req.SessionId == "" {
+		return nil, status.Error(codes.InvalidArgument, "sessionId is required")
+	}
+
+	conversation, err := async.Await(odm.CollectionOf[db.ConversationModel](s.mongo, tenant).FindOneByID(ctx, req.SessionId))
+	if err != nil || conversation == nil || len(conversation.Messages) == 0 {
+		return nil, status.Error(codes.NotFound, "Conversation not found")
+	}
+	if conversation.UserID != "" && conversation.UserID != userId {
+		return nil, status.Error(codes.PermissionDenied, "Only the owner can view this conversation")
+	}
+
+	tenantConfig, err := loadTenantConfig(ctx, s.mongo, tenant)
+	if err != nil {
+		logger.Error("Failed to load tenant config", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load tenant config")
+	}
+
+	transcript := buildTranscript(conversation, tenantConfig)
+	if conversation.CaseContext != nil {
+		transcript.CaseContext = caseContextProto(conversation.CaseContext)
+	}
+	markSourceChanges(ctx, s.mongo, tenant, transcript)
+	return transcript, nil
+}
+
+// ListConversations lists the caller's conversations for the history page. Sessions
+// created without a question being asked yet are left out.
+func (s *ConversationService) ListConversations(ctx context.Context, req *pb.ListConversationsRequest) (*pb.ListConversationsResponse, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+
+	limit := int64(req.Limit)
+	if limit <= 0 {
+		limit = 50
+	}
+	limit = min(limit, 200)
+
+	filter := bson.M{"userId": userId, "messages.0": bson.M{"$exists": true}}
+	if req.Before > 0 {
+		filter["updatedOn"] = bson.M{"$lt": req.Before}
+	}
+
+	// only the opening messages are read, to title conversations not yet named
+	collection := s.mongo.Database(tenant).Collection(db.ConversationModel{}.CollectionName())
+	cursor, err := collection.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "updatedOn", Value: -1}, {Key: "createdOn", Value: -1}}).
+		SetLimit(limit).
+		SetProjection(bson.M{"messages": bson.M{"$slice": 2}, "answerProvenance": 0, "caseContext": 0}))
+	var conversations []db.ConversationModel
+	if err == nil {
+		err = cursor.All(ctx, &conversations)
+	}
+	if err != nil {
+		logger.Error("Failed to list conversations", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to list conversations")
+	}
+
+	tenantConfig, err := loadTenantConfig(ctx, s.mongo, tenant)
+	if err != nil {
+		logger.Error("Failed to load tenant config", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load tenant config")
+	}
+
+	resp := &pb.ListConversationsResponse{Locale: tenantConfig.Locale, TimeZone: tenantConfig.TimeZone}
+	for _, conversation := range conversations {
+		resp.Conversations = append(resp.Conversations, &pb.ConversationSummary{
+			SessionId: conversation.SessionID,
+			Title:     conversationTitle(&conversation),
+			CreatedOn: conversation.CreatedOn,
+			UpdatedOn: max(conversation.UpdatedOn, conversation.CreatedOn),
+		})
+	}
+	return resp, nil
+}
+
+// conversationTitle is the generated title, or the first question for a conversation
+// that has none yet.
+func conversationTitle(conversation *db.ConversationModel) string {
+	if conversation.Title != "" {
+		return conversation.Title
+	}
+	for _, msg := range conversation.Messages {
+		if msg.Role == "user" && !msg.IsToolResult {
+			return titles.FromQuestion(msg.Content)
+		}
+	}
+	return ""
+}
+
+// SetSessionContext attaches case details to a session, which may not have started yet.
+// The agent adds them to the system prompt of every later turn.
+func (s *ConversationService) SetSessionContext(ctx context.Context, req *pb.SetSessionContextRequest) (*pb.CaseContext, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	if req.SessionId == "" {
+		return nil, status.Error(codes.InvalidArgument, "sessionId is required")
+	}
+	if _, err := s.ownedConversation(ctx, tenant, userId, req.SessionId); err != nil {
+		return nil, err
+	}
+
+	var caseContext *db.CaseContext
+	if req.CaseContext != nil {
+		caseContext = prompts.NormalizeCaseContext(db.CaseContext{
+			AgeYears:        req.CaseContext.AgeYears,
+			Sex:             req.CaseContext.Sex,
+			ChiefComplaints: req.CaseContext.ChiefComplaints,
+			History:         req.CaseContext.History,
+			Medications:     req.CaseContext.Medications,
+		})
+	}
+
+	update := bson.M{"$unset": bson.M{"caseContext": ""}}
+	if caseContext != nil {
+		if problems := prompts.ValidateCaseContext(*caseContext); len(problems) > 0 {
+			return nil, status.Error(codes.InvalidArgument, errors.Join(problems...).Error())
+		}
+		caseContext.UpdatedOn = time.Now().Unix()
+		// Attaching details before the first question creates the session for its owner.
+		update = bson.M{
+			"$set":         bson.M{"caseContext": caseContext},
+			"$setOnInsert": bson.M{"userId": userId, "createdOn": time.Now().Unix()},
+		}
+	}
+
+	_, err := s.mongo.Database(tenant).Collection(db.ConversationModel{}.CollectionName()).UpdateOne(ctx,
+		bson.M{"_id": req.SessionId}, update, options.UpdateOne().SetUpsert(caseContext != nil))
+	if err != nil {
+		logger.Error("Failed to save case context", zap.String("sessionId", req.SessionId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to save case context")
+	}
+
+	return caseContextProto(caseContext), nil
+}
+
+func (s *ConversationService) GetSessionContext(ctx context.Context, req *pb.GetSessionContextRequest) (*pb.CaseContext, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	if req.SessionId == "" {
+		return nil, status.Error(codes.InvalidArgument, "sessionId is required")
+	}
+
+	conversation, err := s.ownedConversation(ctx, tenant, userId, req.SessionId)
+	if err != nil {
+		return nil, err
+	}
+	if conversation == nil {
+		return &pb.CaseContext{}, nil
+	}
+	return caseContextProto(conversation.CaseContext), nil
+}
+
+// ownedConversation loads a session the caller may change: their own, or one that has
+// not started yet, in which case it returns nil.
+func (s *ConversationService) ownedConversation(ctx context.Context, tenant, userId, sessionId string) (*db.ConversationModel, error) {
+	repo := odm.CollectionOf[db.ConversationModel](s.mongo, tenant)
+	exists, err := async.Await(repo.Exists(ctx, sessionId))
+	if err != nil {
+		logger.Error("Failed to load conversation", zap.String("sessionId", sessionId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load conversation")
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	conversation, err := async.Await(repo.FindOneByID(ctx, sessionId))
+	if err != nil {
+		logger.Error("Failed to load conversation", zap.String("sessionId", sessionId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load conversation")
+	}
+	if conversation.UserID != "" && conversation.UserID != userId {
+		return nil, status.Error(codes.PermissionDenied, "Only the owner can change this conversation")
+	}
+	return conversation, nil
+}
+
+func caseContextProto(caseContext *db.CaseContext) *pb.CaseContext {
+	if caseContext == nil {
+		return &pb.CaseContext{}
+	}
+	return &pb.CaseContext{
+		AgeYears:        caseContext.AgeYears,
+		Sex:             caseContext.Sex,
+		ChiefComplaints: caseContext.ChiefComplaints,
+		History:         caseContext.History,
+		Medications:     caseContext.Medications,
+		UpdatedOn:       caseContext.UpdatedOn,
+	}
+}
+
+// ImportConversations stores conversations exported from another assistant as the
+// caller's own. Session IDs are derived from the content, so importing the same
+// export twice updates the earlier import instead of duplicating it.
+func (s *ConversationService) ImportConversations(ctx context.Context, req *pb.ImportConversationsRequest) (*pb.ImportConversationsResponse, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	if len(req.Content) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "content is required")
+	}
+
+	conversations, err := parseConversationExport(req.Format, req.Content)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if len(conversations) > maxImportedConversations {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d conversations can be imported at once", maxImportedConversations)
+	}
+
+	collection := s.mongo.Database(tenant).Collection(db.ConversationModel{}.CollectionName())
+	response := &pb.ImportConversationsResponse{}
+	for _, conversation := range conversations {
+		if len(conversation.Messages) == 0 {
+			response.Skipped++
+			continue
+		}
+
+		first := conversation.Messages[0]
+		sessionId, _ := odm.HashedKey("import", userId, conversation.Title, first.Role, first.Content, strconv.FormatInt(conversation.CreatedOn, 10))
+
+		createdOn := conversation.CreatedOn
+		if createdOn == 0 {
+			createdOn = time.Now().Unix()
+		}
+
+		// odm's Save stamps createdOn with the current time; imports keep the original date.
+		_, err := collection.UpdateOne(ctx,
+			bson.M{"_id": sessionId},
+			bson.M{"$set": db.ConversationModel{
+				SessionID: sessionId,
+				UserID:    userId,
+				Messages:  conversation.Messages,
+				Title:     conversation.title(),
+				CreatedOn: createdOn,
+				UpdatedOn: time.Now().Unix(),
+			}},
+			options.UpdateOne().SetUpsert(true),
+		)
+		if err != nil {
+			logger.Error("Failed to import conversation", zap.String("tenant", tenant), zap.Error(err))
+			return nil, status.Error(codes.Internal, "Failed to import conversations")
+		}
+		response.SessionIds = append(response.SessionIds, sessionId)
+	}
+
+	logger.Info("Conversations imported", zap.String("tenant", tenant), zap.Int("imported", len(response.SessionIds)), zap.Int32("skipped", response.Skipped))
+	return response, nil
+}
+
+// loadTenantConfig returns the tenant's config document, or defaults when none was saved.
+func loadTenantConfig(ctx context.Context, mongo odm.MongoClient, tenant string) (*db.TenantConfigModel, error) {
+	repo := odm.CollectionOf[db.TenantConfigModel](mongo, tenant)
+	exists, err := async.Await(repo.Exists(ctx, db.TenantConfigID))
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return &db.TenantConfigModel{ID: db.TenantConfigID}, nil
+	}
+
+	return async.Await(repo.FindOneByID(ctx, db.TenantConfigID))
+}
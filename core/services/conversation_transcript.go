@@ -0,0 +1,141 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/guardrails"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+)
+
+// buildTranscript turns a stored conversation into its visible dialogue. Tool results
+// are not part of the dialogue, but each answer is attributed to the tool results
+// retrieved for it, so transcripts can footnote their sources, and stamped with the
+// corpus version it was generated from.
+func buildTranscript(conversation *db.ConversationModel, tenantConfig *db.TenantConfigModel) *pb.ConversationTranscript {
+	transcript := &pb.ConversationTranscript{
+		SessionId: conversation.SessionID,
+		Locale:    tenantConfig.Locale,
+		TimeZone:  tenantConfig.TimeZone,
+		CreatedOn: conversation.CreatedOn,
+		Title:     conversationTitle(conversation),
+	}
+
+	var retrieved []storedSource // tool results since the last answer
+	for _, msg := range conversation.Messages {
+		if msg.IsToolResult {
+			retrieved = append(retrieved, parseStoredToolResults(msg.Content)...)
+			continue
+		}
+
+		message := &pb.TranscriptMessage{Role: msg.Role, Content: msg.Content}
+		if msg.Role == "assistant" {
+			message.Sources, message.Citations = citeSources(msg, retrieved)
+			retrieved = nil
+		}
+		transcript.Messages = append(transcript.Messages, message)
+	}
+	stampAnswerVersions(transcript, conversation.AnswerProvenance)
+	return transcript
+}
+
+// storedSource is a tool result as agent-boot stores it in the conversation.
+type storedSource struct {
+	title       string
+	attribution string
+	sentences   []string
+}
+
+// citeSources attributes the answer's sentences to the retrieved sources and keeps only
+// the sources cited, numbered in order of first citation.
+func citeSources(answer llm.Message, retrieved []storedSource) ([]*pb.TranscriptSource, []*pb.TranscriptCitation) {
+	if len(retrieved) == 0 {
+		return nil, nil
+	}
+
+	candidates := make([]guardrails.Source, len(retrieved))
+	for i, source := range retrieved {
+		candidates[i] = guardrails.Source{ID: strconv.Itoa(i), Title: source.title, Sentences: source.sentences}
+	}
+	_, checked := guardrails.EnforceCitations(answer.Content, candidates, guardrails.CitationOff)
+
+	var (
+		sources   []*pb.TranscriptSource
+		citations []*pb.TranscriptCitation
+		numbered  = make(map[int]int32) // retrieved index to index in sources
+	)
+	for _, citation := range checked {
+		if !citation.Supported {
+			continue
+		}
+
+		cited := &pb.TranscriptCitation{Sentence: citation.Sentence}
+		for _, id := range citation.SourceIDs {
+			i, _ := strconv.Atoi(id)
+			index, ok := numbered[i]
+			if !ok {
+				index = int32(len(sources))
+				numbered[i] = index
+				sources = append(sources, &pb.TranscriptSource{Title: retrieved[i].title, Attribution: retrieved[i].attribution})
+			}
+			cited.SourceIndexes = append(cited.SourceIndexes, index)
+		}
+		citations = append(citations, cited)
+	}
+	return sources, citations
+}
+
+var markdownEscape = regexp.MustCompile(`\\(.)`)
+
+// parseStoredToolResults reads back the markdown agent-boot writes for tool results:
+// a "### title" heading per result, its sentences, a metadata table and an
+// "_Attribution: ..._" line. Failed results are skipped.
+func parseStoredToolResults(content string) []storedSource {
+	var (
+		sources []storedSource
+		current *storedSource
+		failed  bool
+	)
+	flush := func() {
+		if current != nil && !failed && len(current.sentences) > 0 {
+			sources = append(sources, *current)
+		}
+		current, failed = nil, false
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "### "):
+			flush()
+			current = &storedSource{title: unescapeMarkdown(strings.TrimPrefix(line, "### "))}
+			continue
+		case line == "":
+			continue
+		}
+
+		if current == nil {
+			current = &storedSource{}
+		}
+		switch {
+		case strings.HasPrefix(line, "> **Error:**"):
+			failed = true
+		case strings.HasPrefix(line, "_Attribution: "):
+			current.attribution = unescapeMarkdown(strings.TrimSuffix(strings.TrimPrefix(line, "_Attribution: "), "_"))
+		case strings.HasPrefix(line, "|"), strings.HasPrefix(line, "_via "):
+			// metadata table and tool name
+		default:
+			current.sentences = append(current.sentences, unescapeMarkdown(strings.TrimPrefix(line, "- ")))
+		}
+	}
+	flush()
+	return sources
+}
+
+func unescapeMarkdown(text string) string {
+	text = markdownEscape.ReplaceAllString(text, "$1")
+	return strings.NewReplacer("&lt;", "<", "&gt;", ">").Replace(text)
+}
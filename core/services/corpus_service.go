@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/auth"
+	"github.com/SaiNageswarS/go-api-boot/cloud"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/notifications"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultCorpusVersionsLimit = 50
+	defaultCorpusPageSize      = 100
+	maxCorpusPageSize          = 500
+
+	// How often a watched tenant's corpus versions are polled. Each watching client
+	// polls on its own, so this bounds the load that open chat tabs put on Mongo.
+	corpusWatchInterval = 15 * time.Second
+	// versions read per poll; a larger backlog is reported over several updates
+	maxCorpusWatchVersions = 200
+)
+
+type CorpusService struct {
+	pb.UnimplementedCorpusServer
+	mongo odm.MongoClient
+	az    cloud.Cloud
+}
+
+func ProvideCorpusService(mongo odm.MongoClient, az cloud.Cloud) *CorpusService {
+	return &CorpusService{
+		mongo: mongo,
+		az:    az,
+	}
+}
+
+func (s *CorpusService) ListCorpusVersions(ctx context.Context, req *pb.ListCorpusVersionsRequest) (*pb.ListCorpusVersionsResponse, error) {
+	_, tenant := auth.GetUserIdAndTenant(ctx)
+
+	limit := int64(req.Limit)
+	if limit <= 0 {
+		limit = defaultCorpusVersionsLimit
+	}
+
+	versions, err := async.Await(odm.CollectionOf[db.CorpusVersionModel](s.mongo, tenant).
+		Find(ctx, bson.M{}, bson.D{{Key: "version", Value: -1}}, limit, 0))
+	if err != nil {
+		logger.Error("Failed to list corpus versions", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to list corpus versions")
+	}
+
+	resp := &pb.ListCorpusVersionsResponse{}
+	for _, v := range versions {
+		resp.Versions = append(resp.Versions, &pb.CorpusVersion{
+			Version:       v.Version,
+			SourceUri:     v.SourceURI,
+			AddedChunks:   int32(v.AddedChunks),
+			RetiredChunks: int32(v.RetiredChunks),
+			CreatedOn:     v.CreatedOn,
+			Deleted:       v.Deleted,
+		})
+	}
+	if len(versions) > 0 {
+		resp.CurrentVersion = versions[0].Version
+	}
+
+	return resp, nil
+}
+
+func (s *CorpusService) GetCorpusAtVersion(ctx context.Context, req *pb.GetCorpusAtVersionRequest) (*pb.GetCorpusAtVersionResponse, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	if req.Version < 0 || req.Offset < 0 {
+		return nil, status.Error(codes.InvalidArgument, "version and offset must not be negative")
+	}
+
+	pageSize := int64(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultCorpusPageSize
+	}
+	pageSize = min(pageSize, maxCorpusPageSize)
+
+	// chunks of documents the user may not retrieve are not listed either
+	filter := bson.M{"$and": bson.A{db.ChunksAtVersionFilter(req.Version), db.AccessFilter(userAccessGroups(ctx, s.mongo, tenant, userId))}}
+	if req.SourceUri != "" {
+		filter = bson.M{"$and": bson.A{filter, bson.M{"sourceUri": req.SourceUri}}}
+	}
+
+	chunkRepo := odm.CollectionOf[db.ChunkModel](s.mongo, tenant)
+	countTask := chunkRepo.Count(ctx, filter)
+	chunksTask := chunkRepo.Find(ctx, filter,
+		bson.D{{Key: "sourceUri", Value: 1}, {Key: "sectionIndex", Value: 1}, {Key: "windowIndex", Value: 1}},
+		pageSize, int64(req.Offset))
+
+	total, err := async.Await(countTask)
+	if err != nil {
+		logger.Error("Failed to count corpus chunks", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to read corpus")
+	}
+
+	chunks, err := async.Await(chunksTask)
+	if err != nil {
+		logger.Error("Failed to list corpus chunks", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to read corpus")
+	}
+
+	resp := &pb.GetCorpusAtVersionResponse{
+		Version:     req.Version,
+		TotalChunks: total,
+	}
+	for _, ch := range chunks {
+		resp.Chunks = append(resp.Chunks, &pb.CorpusChunk{
+			ChunkId:        ch.ChunkID,
+			Title:          ch.Title,
+			SectionPath:    ch.SectionPath,
+			SourceUri:      ch.SourceURI,
+			CorpusVersion:  ch.CorpusVersion,
+			RetiredVersion: ch.RetiredVersion,
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *CorpusService) GetFigure(ctx context.Context, req *pb.GetFigureRequest) (*pb.Figure, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	if req.FigureId == "" {
+		return nil, status.Error(codes.InvalidArgument, "figureId is required")
+	}
+
+	// figures of documents the user may not retrieve are not found
+	filter := bson.M{"$and": bson.A{bson.M{"_id": req.FigureId}, db.AccessFilter(userAccessGroups(ctx, s.mongo, tenant, userId))}}
+	figures, err := async.Await(odm.CollectionOf[db.FigureModel](s.mongo, tenant).Find(ctx, filter, nil, 1, 0))
+	if err != nil {
+		logger.Error("Failed to load figure", zap.String("tenant", tenant), zap.String("figureId", req.FigureId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load figure")
+	}
+	if len(figures) == 0 {
+		return nil, status.Error(codes.NotFound, "No figure with this figureId")
+	}
+
+	figure := figures[0]
+	resp := &pb.Figure{
+		FigureId:    figure.FigureID,
+		Label:       figure.Label,
+		Caption:     figure.Caption,
+		SourceUri:   figure.SourceURI,
+		Page:        int32(figure.Page),
+		SectionId:   figure.SectionID,
+		Title:       figure.Title,
+		Attribution: figure.Book,
+	}
+	if figure.ImagePath != "" {
+		resp.Image, err = downloadBlob(ctx, s.az, tenant, figure.ImagePath)
+		if err != nil {
+			logger.Error("Failed to download figure image", zap.String("tenant", tenant), zap.String("path", figure.ImagePath), zap.Error(err))
+			return nil, status.Error(codes.Internal, "Failed to load figure image")
+		}
+	}
+	return resp, nil
+}
+
+func (s *CorpusService) WatchCorpus(req *pb.WatchCorpusRequest, stream grpc.ServerStreamingServer[pb.CorpusUpdate]) error {
+	ctx := stream.Context()
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+
+	tenantConfig, err := loadTenantConfig(ctx, s.mongo, tenant)
+	if err != nil {
+		logger.Error("Failed to load tenant config", zap.String("tenant", tenant), zap.Error(err))
+		return status.Error(codes.Internal, "Failed to load tenant config")
+	}
+	if tenantConfig.DisableCorpusUpdateNotifications {
+		return status.Error(codes.FailedPrecondition, "Corpus update notifications are disabled")
+	}
+
+	prefs, err := loadNotificationPreferences(ctx, s.mongo, tenant, userId)
+	if err != nil {
+		logger.Error("Failed to load notification preferences", zap.String("tenant", tenant), zap.Error(err))
+		return status.Error(codes.Internal, "Failed to load notification preferences")
+	}
+	if !prefs.Allows(notifications.EventCorpusUpdate, notifications.ChannelInApp) {
+		return status.Error(codes.FailedPrecondition, "Corpus update notifications are turned off for this user")
+	}
+
+	since := req.SinceVersion
+	if since <= 0 {
+		if since, err = db.CurrentCorpusVersion(ctx, s.mongo, tenant); err != nil {
+			logger.Error("Failed to read corpus version", zap.String("tenant", tenant), zap.Error(err))
+			return status.Error(codes.Internal, "Failed to read corpus version")
+		}
+	}
+	if err := stream.Send(&pb.CorpusUpdate{Version: since}); err != nil {
+		return err
+	}
+	// sources of documents the user may not retrieve are not announced either
+	accessGroups := userAccessGroups(ctx, s.mongo, tenant, userId)
+
+	ticker := time.NewTicker(corpusWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		update, err := s.corpusUpdateSince(ctx, tenant, accessGroups, since)
+		if err != nil {
+			logger.Error("Failed to read corpus versions", zap.String("tenant", tenant), zap.Error(err))
+			continue
+		}
+		if update == nil {
+			continue
+		}
+
+		since = update.Version
+		// versions that only retired chunks change nothing a user would want to hear about
+		if len(update.NewSourceUris) == 0 && len(update.UpdatedSourceUris) == 0 {
+			continue
+		}
+		if err := stream.Send(update); err != nil {
+			return err
+		}
+	}
+}
+
+// corpusUpdateSince summarizes the versions published after since, or returns nil when
+// there are none. Only sources a user in accessGroups may retrieve are reported.
+func (s *CorpusService) corpusUpdateSince(ctx context.Context, tenant string, accessGroups []string, since int64) (*pb.CorpusUpdate, error) {
+	versions, err := async.Await(odm.CollectionOf[db.CorpusVersionModel](s.mongo, tenant).
+		Find(ctx, bson.M{"version": bson.M{"$gt": since}}, bson.D{{Key: "version", Value: 1}}, maxCorpusWatchVersions, 0))
+	if err != nil || len(versions) == 0 {
+		return nil, err
+	}
+
+	var sourceUris []string
+	for _, v := range versions {
+		if v.AddedChunks > 0 && v.SourceURI != "" && !slices.Contains(sourceUris, v.SourceURI) {
+			sourceUris = append(sourceUris, v.SourceURI)
+		}
+	}
+	var visible []string
+	if len(sourceUris) > 0 {
+		filter := bson.D{
+			{Key: "sourceUri", Value: bson.M{"$in": sourceUris}},
+			{Key: "$and", Value: bson.A{db.AccessFilter(accessGroups)}},
+		}
+		if err := odm.CollectionOf[db.ChunkModel](s.mongo, tenant).DistinctInto(ctx, "sourceUri", filter, &visible); err != nil {
+			return nil, err
+		}
+	}
+
+	update := &pb.CorpusUpdate{Version: versions[len(versions)-1].Version}
+	for _, v := range versions {
+		if v.AddedChunks == 0 || !slices.Contains(visible, v.SourceURI) {
+			continue
+		}
+		update.AddedChunks += int32(v.AddedChunks)
+
+		// a source re-ingested in the same batch it first appeared in is still new
+		if slices.Contains(update.NewSourceUris, v.SourceURI) || slices.Contains(update.UpdatedSourceUris, v.SourceURI) {
+			continue
+		}
+		if v.RetiredChunks > 0 {
+			update.UpdatedSourceUris = append(update.UpdatedSourceUris, v.SourceURI)
+		} else {
+			update.NewSourceUris = append(update.NewSourceUris, v.SourceURI)
+		}
+	}
+
+	update.Message = corpusUpdateMessage(len(update.NewSourceUris), len(update.UpdatedSourceUris))
+	return update, nil
+}
+
+func corpusUpdateMessage(added, updated int) string {
+	sources := func(n int, adjective string) string {
+		if n == 1 {
+			return "1 " + adjective + "source"
+		}
+		return fmt.Sprintf("%d %ssources", n, adjective)
+	}
+
+	switch {
+	case added > 0 && updated > 0:
+		return fmt.Sprintf("%s added to your library, %d updated", sources(added, "new "), updated)
+	case added > 0:
+		return sources(added, "new ") + " added to your library"
+	case updated > 0:
+		return sources(updated, "") + " updated in your library"
+	}
+	return ""
+}
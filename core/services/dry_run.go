@@ -0,0 +1,21 @@
+package services
+
+import (
+	"context"
+
+	"github.com/SaiNageswarS/agent-boot/memory"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+)
+
+// dryRunConversations loads a session's history for tool selection but drops the save
+// agent-boot makes at the end of the turn, so a dry run leaves the session as it was.
+type dryRunConversations struct {
+	odm.OdmCollectionInterface[memory.Conversation]
+}
+
+func (c dryRunConversations) Save(ctx context.Context, conversation memory.Conversation) <-chan async.Result[struct{}] {
+	return async.Go(func() (struct{}, error) {
+		return struct{}{}, nil
+	})
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"time"
+
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+)
+
+// defaultMaxIterations caps agent turns when neither the tenant nor the deployment does.
+const defaultMaxIterations = 10
+
+// agentTurns picks how many tool-calling turns an execution gets: what the client asked
+// for, or the agent config's turns, never more than the tenant's ceiling.
+func agentTurns(requested int32, agentConfig db.AgentConfigModel, tenantConfig *db.TenantConfigModel, ccfg *appconfig.AppConfig) int {
+	ceiling := defaultMaxIterations
+	switch {
+	case tenantConfig.MaxIterations > 0:
+		ceiling = tenantConfig.MaxIterations
+	case ccfg.MaxIterations > 0:
+		ceiling = ccfg.MaxIterations
+	}
+
+	turns := agentConfig.MaxTurns
+	if requested > 0 {
+		turns = int(requested)
+	}
+	return min(turns, ceiling)
+}
+
+// executionTimeout resolves the tenant's wall-clock budget per execution; zero is unlimited.
+func executionTimeout(tenantConfig *db.TenantConfigModel, ccfg *appconfig.AppConfig) time.Duration {
+	switch {
+	case tenantConfig.ExecutionTimeoutSeconds > 0:
+		return time.Duration(tenantConfig.ExecutionTimeoutSeconds) * time.Second
+	case tenantConfig.ExecutionTimeoutSeconds < 0:
+		return 0
+	default:
+		return time.Duration(max(ccfg.ExecutionTimeoutSeconds, 0)) * time.Second
+	}
+}
+
+// executionTokenBudget resolves the tenant's token budget per execution; zero is unlimited.
+func executionTokenBudget(tenantConfig *db.TenantConfigModel, ccfg *appconfig.AppConfig) int64 {
+	switch {
+	case tenantConfig.ExecutionTokenBudget > 0:
+		return tenantConfig.ExecutionTokenBudget
+	case tenantConfig.ExecutionTokenBudget < 0:
+		return 0
+	default:
+		return max(ccfg.ExecutionTokenBudget, 0)
+	}
+}
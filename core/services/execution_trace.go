@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/audit"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/llmrouter"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.uber.org/zap"
+)
+
+const (
+	traceCompleted  = "completed"
+	traceFailed     = "failed"
+	traceTerminated = "terminated"
+	traceCached     = "cached"
+	traceDryRun     = "dry_run"
+
+	traceBudgetExhausted = "budget_exhausted"
+)
+
+// saveExecutionTrace stores the audit trace of a run. A trace that cannot be saved is
+// logged; the user has already had their answer.
+func saveExecutionTrace(ctx context.Context, mongo odm.MongoClient, tenant string, started time.Time, trace db.ExecutionTraceModel, recorder *audit.Recorder, usage llmrouter.Usage) {
+	if recorder != nil {
+		trace.Steps, trace.Truncated = recorder.Steps()
+	}
+	if trace.Steps == nil {
+		trace.Steps = []db.TraceStep{}
+	}
+	trace.PromptTokens = usage.PromptTokens
+	trace.CompletionTokens = usage.CompletionTokens
+	trace.LatencyMs = time.Since(started).Milliseconds()
+	trace.StartedOn = started.Unix()
+	trace.CreatedOn = time.Now().Unix()
+
+	if _, err := async.Await(odm.CollectionOf[db.ExecutionTraceModel](mongo, tenant).Save(ctx, trace)); err != nil {
+		logger.Error("Failed to save execution trace", zap.String("tenant", tenant), zap.String("traceId", trace.TraceID), zap.Error(err))
+	}
+}
+
+func executionTraceProto(tenant string, trace db.ExecutionTraceModel, withSteps bool) *pb.ExecutionTrace {
+	out := &pb.ExecutionTrace{
+		TraceId:          trace.TraceID,
+		Tenant:           tenant,
+		SessionId:        trace.SessionID,
+		UserId:           trace.UserID,
+		Question:         trace.Question,
+		Model:            trace.Model,
+		CorpusVersion:    trace.CorpusVersion,
+		Outcome:          trace.Outcome,
+		Answer:           trace.Answer,
+		Error:            trace.Error,
+		Truncated:        trace.Truncated,
+		LatencyMs:        trace.LatencyMs,
+		PromptTokens:     trace.PromptTokens,
+		CompletionTokens: trace.CompletionTokens,
+		StartedOn:        trace.StartedOn,
+	}
+	if !withSteps {
+		return out
+	}
+
+	for _, step := range trace.Steps {
+		stepProto := &pb.TraceStep{
+			Kind:             step.Kind,
+			Name:             step.Name,
+			Model:            step.Model,
+			StartedMs:        step.StartedMs,
+			DurationMs:       step.DurationMs,
+			Error:            step.Error,
+			SystemPrompt:     step.SystemPrompt,
+			OfferedTools:     step.OfferedTools,
+			Response:         step.Response,
+			PromptTokens:     step.PromptTokens,
+			CompletionTokens: step.CompletionTokens,
+			TokensEstimated:  step.TokensEstimated,
+			Arguments:        step.Arguments,
+		}
+		for _, m := range step.Messages {
+			stepProto.Messages = append(stepProto.Messages, &pb.TraceMessage{Role: m.Role, Content: m.Content})
+		}
+		for _, call := range step.ToolCalls {
+			stepProto.ToolCalls = append(stepProto.ToolCalls, &pb.TraceToolCall{Name: call.Name, Arguments: call.Arguments})
+		}
+		for _, result := range step.Results {
+			stepProto.Results = append(stepProto.Results, &pb.TraceResult{
+				Title:       result.Title,
+				Attribution: result.Attribution,
+				Content:     result.Content,
+				Error:       result.Error,
+			})
+		}
+		out.Steps = append(out.Steps, stepProto)
+	}
+	return out
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/SaiNageswarS/agent-boot/agentboot"
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/medicine-rag/core/llmrouter"
+)
+
+const degradedCode = "degraded"
+
+// failoverNotice tells the client when a fallback model stood in for one of the
+// execution's models. Failovers happen inside model calls, so the notice is held until
+// the next chunk is streamed and sent just ahead of it.
+type failoverNotice struct {
+	agentboot.ProgressReporter
+
+	mu        sync.Mutex
+	fallbacks []string // model=fallback, in the order they happened
+	pending   []string
+}
+
+func newFailoverNotice(inner agentboot.ProgressReporter) *failoverNotice {
+	return &failoverNotice{ProgressReporter: inner}
+}
+
+func (n *failoverNotice) record(f llmrouter.Failover) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	pair := f.Model + "=" + f.Fallback
+	if slices.Contains(n.fallbacks, pair) {
+		return
+	}
+	n.fallbacks = append(n.fallbacks, pair)
+	n.pending = append(n.pending, f.Fallback)
+}
+
+func (n *failoverNotice) Send(event *schema.AgentStreamChunk) error {
+	n.mu.Lock()
+	pending := n.pending
+	n.pending = nil
+	n.mu.Unlock()
+
+	for _, fallback := range pending {
+		message := "One of the models is unavailable right now, so " + fallback + " stood in for it. The answer may differ in quality."
+		if err := n.ProgressReporter.Send(agentboot.NewStreamError(message, degradedCode)); err != nil {
+			return err
+		}
+	}
+	return n.ProgressReporter.Send(event)
+}
+
+// Fallbacks lists the failovers so far as model=fallback pairs, or "" if there were none.
+func (n *failoverNotice) Fallbacks() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return strings.Join(n.fallbacks, ",")
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SaiNageswarS/go-api-boot/auth"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	maxFeedbackCommentLength  = 2000
+	maxFeedbackQuestionLength = 8000
+	maxFeedbackAnswerLength   = 20000
+)
+
+type FeedbackService struct {
+	pb.UnimplementedFeedbackServer
+	mongo odm.MongoClient
+}
+
+func ProvideFeedbackService(mongo odm.MongoClient) *FeedbackService {
+	return &FeedbackService{
+		mongo: mongo,
+	}
+}
+
+func (s *FeedbackService) SubmitFeedback(ctx context.Context, req *pb.SubmitFeedbackRequest) (*pb.SubmitFeedbackResponse, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	if req.SessionId == "" || req.MessageId == "" {
+		return nil, status.Error(codes.InvalidArgument, "sessionId and messageId are required")
+	}
+
+	var rating string
+	switch req.Rating {
+	case pb.FeedbackRating_THUMBS_UP:
+		rating = "up"
+	case pb.FeedbackRating_THUMBS_DOWN:
+		rating = "down"
+	default:
+		return nil, status.Error(codes.InvalidArgument, "rating is required")
+	}
+
+	if utf8.RuneCountInString(req.Comment) > maxFeedbackCommentLength {
+		return nil, status.Error(codes.InvalidArgument, "comment is too long")
+	}
+	if utf8.RuneCountInString(req.Question) > maxFeedbackQuestionLength {
+		return nil, status.Error(codes.InvalidArgument, "question is too long")
+	}
+	if utf8.RuneCountInString(req.Answer) > maxFeedbackAnswerLength {
+		return nil, status.Error(codes.InvalidArgument, "answer is too long")
+	}
+
+	// Only the owner may rate answers of a session, as only they may read it.
+	conversation, err := async.Await(odm.CollectionOf[db.ConversationModel](s.mongo, tenant).FindOneByID(ctx, req.SessionId))
+	if err != nil || conversation == nil || len(conversation.Messages) == 0 {
+		return nil, status.Error(codes.NotFound, "Conversation not found")
+	}
+	if conversation.UserID != "" && conversation.UserID != userId {
+		return nil, status.Error(codes.PermissionDenied, "Only the owner can rate this conversation")
+	}
+
+	feedback := db.NewFeedbackModel(req.SessionId, req.MessageId)
+	feedback.UserID = userId
+	feedback.Rating = rating
+	feedback.Comment = req.Comment
+	feedback.Question = req.Question
+	feedback.Answer = req.Answer
+	feedback.CreatedOn = time.Now().Unix()
+
+	_, err = async.Await(odm.CollectionOf[db.FeedbackModel](s.mongo, tenant).Save(ctx, *feedback))
+	if err != nil {
+		logger.Error("Failed to save feedback", zap.String("sessionId", req.SessionId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to save feedback")
+	}
+
+	logger.Info("Feedback recorded", zap.String("sessionId", req.SessionId), zap.String("messageId", req.MessageId), zap.String("rating", rating))
+	return &pb.SubmitFeedbackResponse{FeedbackId: feedback.FeedbackID}, nil
+}
@@ -0,0 +1,635 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/auth"
+	"github.com/SaiNageswarS/go-api-boot/cloud"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
+	"github.com/SaiNageswarS/medicine-rag/core/events"
+	"github.com/SaiNageswarS/medicine-rag/core/ingest"
+	"github.com/SaiNageswarS/medicine-rag/core/tenancy"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Jobs run on the instance that accepted them; more than this many wait for a slot.
+	maxConcurrentIngestionJobs = 4
+	ingestionJobTimeout        = 30 * time.Minute
+)
+
+type IngestionService struct {
+	pb.UnimplementedIngestionServer
+	mongo      odm.MongoClient
+	az         cloud.Cloud
+	blobs      ingest.BlobDeleter
+	embedders  *embedding.Registry
+	limits     *tenancy.Limits
+	dispatcher *events.Dispatcher
+	slots      chan struct{}
+}
+
+func ProvideIngestionService(mongo odm.MongoClient, az cloud.Cloud, blobs ingest.BlobDeleter, embedders *embedding.Registry, limits *tenancy.Limits, dispatcher *events.Dispatcher) *IngestionService {
+	return &IngestionService{
+		mongo:      mongo,
+		az:         az,
+		blobs:      blobs,
+		embedders:  embedders,
+		limits:     limits,
+		dispatcher: dispatcher,
+		slots:      make(chan struct{}, maxConcurrentIngestionJobs),
+	}
+}
+
+func (s *IngestionService) IngestDocument(ctx context.Context, req *pb.IngestDocumentRequest) (*pb.IngestionJob, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	if err := s.requireTenantAdmin(ctx, tenant, userId); err != nil {
+		return nil, err
+	}
+
+	fileName, err := documentFileName(req.FileName, req.Content, req.StoragePath, req.DocumentType)
+	if err != nil {
+		return nil, err
+	}
+	accessGroups, err := accessGroupsParam(req.AccessGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	storagePath := req.StoragePath
+	if len(req.Content) > 0 {
+		storagePath = ingest.UploadsPrefix + fileName
+		if _, err := s.az.UploadBuffer(ctx, tenant, storagePath, req.Content); err != nil {
+			logger.Error("Failed to store uploaded document", zap.String("tenant", tenant), zap.String("path", storagePath), zap.Error(err))
+			return nil, status.Error(codes.Internal, "Failed to store document")
+		}
+	}
+
+	sourceUri := req.SourceUri
+	if sourceUri == "" {
+		sourceUri = storagePath
+	}
+
+	now := time.Now()
+	jobId, _ := odm.HashedKey(tenant, userId, storagePath, strconv.FormatInt(now.UnixNano(), 10))
+	job := &db.IngestionJobModel{
+		JobID:          jobId,
+		SourceURI:      sourceUri,
+		FileName:       fileName,
+		StoragePath:    storagePath,
+		DocumentType:   req.DocumentType,
+		AllowDuplicate: req.AllowDuplicate,
+		AccessGroups:   accessGroups,
+		Status:         db.IngestionJobQueued,
+		CreatedBy:      userId,
+		CreatedOn:      now.Unix(),
+		UpdatedOn:      now.Unix(),
+	}
+	if _, err := async.Await(odm.CollectionOf[db.IngestionJobModel](s.mongo, tenant).Save(ctx, *job)); err != nil {
+		logger.Error("Failed to save ingestion job", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to create ingestion job")
+	}
+
+	go s.run(context.WithoutCancel(ctx), tenant, job, req.Content)
+
+	return ingestionJobProto(*job), nil
+}
+
+func (s *IngestionService) PreviewIngestion(ctx context.Context, req *pb.PreviewIngestionRequest) (*pb.PreviewIngestionResponse, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	if err := s.requireTenantAdmin(ctx, tenant, userId); err != nil {
+		return nil, err
+	}
+
+	fileName, err := documentFileName(req.FileName, req.Content, req.StoragePath, req.DocumentType)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = 20
+	}
+	limit = min(limit, 200)
+
+	tenantConfig, err := loadTenantConfig(ctx, s.mongo, tenant)
+	if err != nil {
+		logger.Error("Failed to load tenant config", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load tenant config")
+	}
+
+	content := req.Content
+	if len(content) == 0 {
+		if content, err = s.download(ctx, tenant, req.StoragePath); err != nil {
+			logger.Error("Failed to read document", zap.String("tenant", tenant), zap.String("path", req.StoragePath), zap.Error(err))
+			return nil, status.Error(codes.NotFound, "Failed to read document from storage")
+		}
+	}
+
+	sourceUri := req.StoragePath
+	if sourceUri == "" {
+		sourceUri = ingest.UploadsPrefix + fileName
+	}
+	preview, err := ingest.PreviewDocument(ctx, fileName, sourceUri, content, tenantConfig.Chunking, ingest.PreviewOptions{
+		DocumentType:  req.DocumentType,
+		Strategy:      req.Strategy,
+		MaxTokens:     int(req.MaxTokens),
+		OverlapTokens: int(req.OverlapTokens),
+		MinQuality:    cmp.Or(req.MinQuality, tenantConfig.MinChunkQuality),
+
+		Deidentification: tenantConfig.Deidentification,
+	})
+	if err != nil {
+		// bad documents and bad settings alike are the caller's to fix
+		return nil, status.Error(codes.InvalidArgument, "Failed to chunk document: "+err.Error())
+	}
+
+	return previewProto(preview, limit), nil
+}
+
+func (s *IngestionService) GetIngestionJob(ctx context.Context, req *pb.GetIngestionJobRequest) (*pb.IngestionJob, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	if err := s.requireTenantAdmin(ctx, tenant, userId); err != nil {
+		return nil, err
+	}
+	if req.JobId == "" {
+		return nil, status.Error(codes.InvalidArgument, "jobId is required")
+	}
+
+	repo := odm.CollectionOf[db.IngestionJobModel](s.mongo, tenant)
+	exists, err := async.Await(repo.Exists(ctx, req.JobId))
+	var job *db.IngestionJobModel
+	if err == nil && exists {
+		job, err = async.Await(repo.FindOneByID(ctx, req.JobId))
+	}
+	if err != nil {
+		logger.Error("Failed to load ingestion job", zap.String("tenant", tenant), zap.String("jobId", req.JobId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load ingestion job")
+	}
+	if job == nil {
+		return nil, status.Error(codes.NotFound, "Ingestion job not found")
+	}
+
+	return ingestionJobProto(*job), nil
+}
+
+func (s *IngestionService) DeleteDocument(ctx context.Context, req *pb.DeleteDocumentRequest) (*pb.DeleteDocumentResponse, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	if err := s.requireTenantAdmin(ctx, tenant, userId); err != nil {
+		return nil, err
+	}
+	if req.SourceUri == "" {
+		return nil, status.Error(codes.InvalidArgument, "sourceUri is required")
+	}
+
+	// a job still running would publish the document's chunks again
+	if err := s.requireNotIngesting(ctx, tenant, req.SourceUri, "delete it"); err != nil {
+		return nil, err
+	}
+
+	deletion, err := ingest.DeleteDocument(ctx, s.mongo, s.blobs, tenant, req.SourceUri)
+	if errors.Is(err, ingest.ErrDocumentNotFound) {
+		return nil, status.Error(codes.NotFound, "Document not found")
+	}
+	if err != nil {
+		logger.Error("Failed to delete document", zap.String("tenant", tenant), zap.String("sourceUri", req.SourceUri), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to delete document")
+	}
+
+	now := time.Now()
+	eventId, _ := odm.HashedKey(tenant, db.AuditDocumentDeleted, req.SourceUri, strconv.FormatInt(now.UnixNano(), 10))
+	event := db.AuditEventModel{
+		EventID: eventId,
+		Action:  db.AuditDocumentDeleted,
+		UserID:  userId,
+		Target:  req.SourceUri,
+		Details: map[string]string{
+			"chunks":        strconv.Itoa(deletion.Chunks),
+			"liveChunks":    strconv.Itoa(deletion.LiveChunks),
+			"embeddings":    strconv.Itoa(deletion.Embeddings),
+			"tableRows":     strconv.Itoa(deletion.TableRows),
+			"figures":       strconv.Itoa(deletion.Figures),
+			"images":        strconv.Itoa(deletion.Images),
+			"uploads":       strconv.Itoa(deletion.Uploads),
+			"quarantined":   strconv.Itoa(deletion.Quarantined),
+			"duplicates":    strconv.Itoa(deletion.Duplicates),
+			"corpusVersion": strconv.FormatInt(deletion.CorpusVersion, 10),
+		},
+		CreatedOn: now.Unix(),
+	}
+	if _, err := async.Await(odm.CollectionOf[db.AuditEventModel](s.mongo, tenant).Save(ctx, event)); err != nil {
+		// the document is gone either way; the log line stands in for the event
+		logger.Error("Failed to save audit event", zap.String("tenant", tenant), zap.String("action", event.Action),
+			zap.String("userId", userId), zap.String("sourceUri", req.SourceUri), zap.Error(err))
+	}
+
+	return &pb.DeleteDocumentResponse{
+		SourceUri:          req.SourceUri,
+		DeletedChunks:      int32(deletion.Chunks),
+		DeletedEmbeddings:  int32(deletion.Embeddings),
+		DeletedTableRows:   int32(deletion.TableRows),
+		UnlinkedDuplicates: int32(deletion.Duplicates),
+		DeletedFigures:     int32(deletion.Figures),
+		DeletedQuarantined: int32(deletion.Quarantined),
+		DeletedImages:      int32(deletion.Images),
+		DeletedUploads:     int32(deletion.Uploads),
+		CorpusVersion:      deletion.CorpusVersion,
+	}, nil
+}
+
+func (s *IngestionService) ListQuarantinedChunks(ctx context.Context, req *pb.ListQuarantinedChunksRequest) (*pb.ListQuarantinedChunksResponse, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	if err := s.requireTenantAdmin(ctx, tenant, userId); err != nil {
+		return nil, err
+	}
+
+	limit := int64(req.Limit)
+	if limit <= 0 {
+		limit = 50
+	}
+	limit = min(limit, 500)
+	if req.Offset < 0 {
+		return nil, status.Error(codes.InvalidArgument, "offset cannot be negative")
+	}
+
+	filter := bson.M{"status": db.QuarantinePending}
+	switch req.Status {
+	case "", db.QuarantinePending:
+	case db.QuarantineApproved, db.QuarantineRejected:
+		filter["status"] = req.Status
+	default:
+		return nil, status.Error(codes.InvalidArgument, "status must be pending, approved or rejected")
+	}
+	if req.SourceUri != "" {
+		filter["sourceUri"] = req.SourceUri
+	}
+
+	repo := odm.CollectionOf[db.QuarantinedChunkModel](s.mongo, tenant)
+	chunks, err := async.Await(repo.Find(ctx, filter, bson.D{{Key: "createdOn", Value: 1}, {Key: "_id", Value: 1}}, limit, int64(req.Offset)))
+	if err != nil {
+		logger.Error("Failed to list quarantined chunks", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to list quarantined chunks")
+	}
+	total, err := async.Await(repo.Count(ctx, filter))
+	if err != nil {
+		logger.Error("Failed to count quarantined chunks", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to list quarantined chunks")
+	}
+
+	resp := &pb.ListQuarantinedChunksResponse{Total: int32(total)}
+	for _, chunk := range chunks {
+		resp.Chunks = append(resp.Chunks, quarantinedChunkProto(chunk))
+	}
+	return resp, nil
+}
+
+func (s *IngestionService) ReviewQuarantinedChunk(ctx context.Context, req *pb.ReviewQuarantinedChunkRequest) (*pb.QuarantinedChunk, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	if err := s.requireTenantAdmin(ctx, tenant, userId); err != nil {
+		return nil, err
+	}
+	if req.ChunkId == "" {
+		return nil, status.Error(codes.InvalidArgument, "chunkId is required")
+	}
+
+	repo := odm.CollectionOf[db.QuarantinedChunkModel](s.mongo, tenant)
+	exists, err := async.Await(repo.Exists(ctx, req.ChunkId))
+	if err != nil {
+		logger.Error("Failed to load quarantined chunk", zap.String("tenant", tenant), zap.String("chunkId", req.ChunkId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to review chunk")
+	}
+	if !exists {
+		return nil, status.Error(codes.NotFound, "Quarantined chunk not found")
+	}
+	quarantined, err := async.Await(repo.FindOneByID(ctx, req.ChunkId))
+	if err != nil {
+		logger.Error("Failed to load quarantined chunk", zap.String("tenant", tenant), zap.String("chunkId", req.ChunkId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to review chunk")
+	}
+	// a job still running would publish the document's chunks without this one
+	if err := s.requireNotIngesting(ctx, tenant, quarantined.SourceURI, "review its chunks"); err != nil {
+		return nil, err
+	}
+
+	pipeline, err := tenantPipeline(ctx, s.mongo, s.embedders, s.limits, tenant)
+	if err == nil {
+		quarantined, err = pipeline.ReviewQuarantined(ctx, tenant, req.ChunkId, req.Approve, userId)
+	}
+	if errors.Is(err, ingest.ErrQuarantinedChunkNotFound) {
+		return nil, status.Error(codes.NotFound, "Quarantined chunk not found")
+	}
+	if err != nil {
+		logger.Error("Failed to review quarantined chunk", zap.String("tenant", tenant), zap.String("chunkId", req.ChunkId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to review chunk")
+	}
+	return quarantinedChunkProto(*quarantined), nil
+}
+
+// requireNotIngesting fails with FAILED_PRECONDITION while a job is ingesting the
+// document, saying to do what once it is done.
+func (s *IngestionService) requireNotIngesting(ctx context.Context, tenant, sourceUri, what string) error {
+	running, err := async.Await(odm.CollectionOf[db.IngestionJobModel](s.mongo, tenant).Count(ctx, bson.M{
+		"sourceUri": sourceUri,
+		"status":    bson.M{"$nin": bson.A{db.IngestionJobDone, db.IngestionJobFailed}},
+	}))
+	if err != nil {
+		logger.Error("Failed to check ingestion jobs", zap.String("tenant", tenant), zap.String("sourceUri", sourceUri), zap.Error(err))
+		return status.Error(codes.Internal, "Failed to check ingestion jobs")
+	}
+	if running > 0 {
+		return status.Error(codes.FailedPrecondition, "Document is being ingested; "+what+" once its job is done")
+	}
+	return nil
+}
+
+// run ingests the job's document, recording each stage it reaches and telling the
+// tenant's event sinks when it starts and ends. content is nil for documents referenced
+// by storage path, which are downloaded first.
+func (s *IngestionService) run(ctx context.Context, tenant string, job *db.IngestionJobModel, content []byte) {
+	ctx, cancel := context.WithTimeout(ctx, ingestionJobTimeout)
+	defer cancel()
+
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+
+	s.dispatcher.Emit(ctx, events.New(events.JobStarted, tenant, ingestionJobEvent(*job)))
+	report, err := s.ingest(ctx, tenant, job, content)
+	job.Chunks = report.Chunks
+	job.Embedded = report.Embedded
+	job.Quarantined = report.Quarantined
+	job.Redacted = report.Redacted
+	job.FlaggedPages = report.Flagged[job.SourceURI]
+	job.DuplicateOf = report.DuplicateOf[job.SourceURI]
+	if err != nil {
+		logger.Error("Ingestion job failed", zap.String("tenant", tenant), zap.String("jobId", job.JobID),
+			zap.String("sourceUri", job.SourceURI), zap.Error(err))
+		s.saveJob(ctx, tenant, job, db.IngestionJobFailed, err)
+		s.dispatcher.Emit(ctx, events.New(events.JobFailed, tenant, ingestionJobEvent(*job)))
+		return
+	}
+
+	logger.Info("Ingestion job done", zap.String("tenant", tenant), zap.String("jobId", job.JobID),
+		zap.String("sourceUri", job.SourceURI), zap.Int("chunks", report.Chunks), zap.Int("embedded", report.Embedded))
+	s.saveJob(ctx, tenant, job, db.IngestionJobDone, nil)
+	s.dispatcher.Emit(ctx, events.New(events.JobCompleted, tenant, ingestionJobEvent(*job)))
+}
+
+func (s *IngestionService) ingest(ctx context.Context, tenant string, job *db.IngestionJobModel, content []byte) (ingest.Report, error) {
+	if content == nil {
+		s.saveJob(ctx, tenant, job, db.IngestionJobParsing, nil)
+
+		var err error
+		if content, err = s.download(ctx, tenant, job.StoragePath); err != nil {
+			return ingest.Report{}, err
+		}
+	}
+
+	pipeline, err := tenantPipeline(ctx, s.mongo, s.embedders, s.limits, tenant)
+	if err != nil {
+		return ingest.Report{}, err
+	}
+	pipeline.DocumentType = job.DocumentType
+	pipeline.AllowDuplicates = job.AllowDuplicate
+	pipeline.AccessGroups = job.AccessGroups
+	pipeline.Storage = s.az
+	pipeline.Indexed = s.dispatcher.IndexedHook(tenant, ingestionJobEvent(*job))
+	return pipeline.Ingest(ctx, tenant, job.SourceURI, job.FileName, content, func(stage string) {
+		if stage != job.Status {
+			s.saveJob(ctx, tenant, job, stage, nil)
+		}
+	})
+}
+
+// download reads a document in the tenant's storage bucket.
+func (s *IngestionService) download(ctx context.Context, tenant, storagePath string) ([]byte, error) {
+	content, err := downloadBlob(ctx, s.az, tenant, storagePath)
+	if err != nil {
+		return nil, errors.New("failed to download document: " + err.Error())
+	}
+	return content, nil
+}
+
+// downloadBlob reads a file in the tenant's storage bucket.
+func downloadBlob(ctx context.Context, az cloud.Cloud, tenant, path string) ([]byte, error) {
+	filePath, err := az.DownloadFile(ctx, tenant, path)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(filePath)
+	return os.ReadFile(filePath)
+}
+
+// documentFileName checks a request for a document: either its content or its storage
+// path, a supported file name and a known document type. The file name defaults to
+// the base of the storage path.
+func documentFileName(fileName string, content []byte, storagePath, documentType string) (string, error) {
+	if (len(content) == 0) == (storagePath == "") {
+		return "", status.Error(codes.InvalidArgument, "Either content or storagePath is required")
+	}
+	if fileName == "" {
+		fileName = storagePath
+	}
+	fileName = path.Base(strings.ReplaceAll(fileName, "\\", "/"))
+	if fileName == "." || fileName == "/" || fileName == ".." {
+		return "", status.Error(codes.InvalidArgument, "fileName is required")
+	}
+	if _, ok := ingest.DefaultConverters()[ingest.Extension(fileName)]; !ok {
+		return "", status.Error(codes.InvalidArgument, "Unsupported document type; expected a PDF, EPUB, DOCX, HTML or markdown file")
+	}
+
+	if documentType != "" && !ingest.IsDocumentType(documentType) {
+		return "", status.Error(codes.InvalidArgument, "documentType must be narrative, repertory or case-journal")
+	}
+	return fileName, nil
+}
+
+// tenantPipeline returns a pipeline embedding with the tenant's embedder, within the
+// tenant's embedding slots, that may switch the tenant to the multilingual embedder.
+// It fails when the tenant has vectors of another model.
+func tenantPipeline(ctx context.Context, mongo odm.MongoClient, embedders *embedding.Registry, limits *tenancy.Limits, tenant string) (*ingest.Pipeline, error) {
+	name, err := ingest.TenantEmbedderName(ctx, mongo, tenant)
+	if err != nil {
+		return nil, errors.New("failed to load tenant config: " + err.Error())
+	}
+	spec, embedder, err := embedders.Resolve(name)
+	if err != nil {
+		return nil, errors.New("failed to resolve tenant embedder: " + err.Error())
+	}
+	if err := ingest.CheckStoredVectors(ctx, mongo, tenant, spec); err != nil {
+		return nil, err
+	}
+	pipeline := ingest.NewPipeline(mongo, spec, limits.Embedder(tenant, embedder))
+	pipeline.Multilingual = multilingualEmbedder(embedders, limits, tenant)
+	return pipeline, nil
+}
+
+// multilingualEmbedder is the deployment's multilingual_embedder, within the tenant's
+// embedding slots; nil when there is none or it is unavailable.
+func multilingualEmbedder(embedders *embedding.Registry, limits *tenancy.Limits, tenant string) *ingest.NamedEmbedder {
+	name := embedders.MultilingualName()
+	if name == "" {
+		return nil
+	}
+	spec, embedder, err := embedders.Resolve(name)
+	if err != nil {
+		logger.Error("Multilingual embedder unavailable", zap.String("embedder", name), zap.Error(err))
+		return nil
+	}
+	return &ingest.NamedEmbedder{Name: name, Spec: spec, Embedder: limits.Embedder(tenant, embedder)}
+}
+
+// saveJob records the job's status. A job whose record can't be saved still runs, so
+// failing to save is logged, not returned.
+func (s *IngestionService) saveJob(ctx context.Context, tenant string, job *db.IngestionJobModel, jobStatus string, jobErr error) {
+	job.Status = jobStatus
+	job.Error = ""
+	if jobErr != nil {
+		job.Error = jobErr.Error()
+	}
+	job.UpdatedOn = time.Now().Unix()
+
+	// the job's own context may have timed out, and the failure must still be recorded
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	if _, err := async.Await(odm.CollectionOf[db.IngestionJobModel](s.mongo, tenant).Save(saveCtx, *job)); err != nil {
+		logger.Error("Failed to save ingestion job", zap.String("tenant", tenant), zap.String("jobId", job.JobID), zap.Error(err))
+	}
+}
+
+func (s *IngestionService) requireTenantAdmin(ctx context.Context, tenant, userId string) error {
+	isAdmin, err := isTenantAdmin(ctx, s.mongo, tenant, userId)
+	if err != nil {
+		logger.Error("Failed to load user", zap.String("tenant", tenant), zap.String("userId", userId), zap.Error(err))
+		return status.Error(codes.Internal, "Failed to load user")
+	}
+	if !isAdmin {
+		return status.Error(codes.PermissionDenied, "Only tenant admins can manage documents")
+	}
+	return nil
+}
+
+// ingestionJobEvent describes the job in events.
+func ingestionJobEvent(job db.IngestionJobModel) events.Job {
+	return events.Job{
+		ID:          job.JobID,
+		Kind:        events.JobIngestion,
+		Source:      job.SourceURI,
+		Status:      job.Status,
+		Error:       job.Error,
+		DuplicateOf: job.DuplicateOf,
+		Chunks:      job.Chunks,
+		Embedded:    job.Embedded,
+	}
+}
+
+func ingestionJobProto(job db.IngestionJobModel) *pb.IngestionJob {
+	return &pb.IngestionJob{
+		JobId:               job.JobID,
+		SourceUri:           job.SourceURI,
+		FileName:            job.FileName,
+		StoragePath:         job.StoragePath,
+		Status:              job.Status,
+		Error:               job.Error,
+		Chunks:              int32(job.Chunks),
+		EmbeddedChunks:      int32(job.Embedded),
+		QuarantinedChunks:   int32(job.Quarantined),
+		RedactedIdentifiers: int32(job.Redacted),
+		FlaggedPages:        flaggedPagesProto(job.FlaggedPages),
+		DocumentType:        job.DocumentType,
+		DuplicateOf:         job.DuplicateOf,
+		AccessGroups:        job.AccessGroups,
+		CreatedBy:           job.CreatedBy,
+		CreatedOn:           job.CreatedOn,
+		UpdatedOn:           job.UpdatedOn,
+	}
+}
+
+func quarantinedChunkProto(quarantined db.QuarantinedChunkModel) *pb.QuarantinedChunk {
+	chunk := quarantined.Chunk
+	return &pb.QuarantinedChunk{
+		ChunkId:       quarantined.ChunkID,
+		SourceUri:     quarantined.SourceURI,
+		Title:         chunk.Title,
+		SectionPath:   chunk.SectionPath,
+		PageStart:     int32(chunk.PageStart),
+		PageEnd:       int32(chunk.PageEnd),
+		Text:          strings.Join(chunk.Sentences, " "),
+		Quality:       chunk.Quality,
+		QualityIssues: chunk.QualityIssues,
+		Status:        quarantined.Status,
+		ReviewedBy:    quarantined.ReviewedBy,
+		ReviewedOn:    quarantined.ReviewedOn,
+		CreatedOn:     quarantined.CreatedOn,
+	}
+}
+
+func flaggedPagesProto(pages []int) []int32 {
+	var out []int32
+	for _, page := range pages {
+		out = append(out, int32(page))
+	}
+	return out
+}
+
+func previewProto(preview *ingest.Preview, limit int) *pb.PreviewIngestionResponse {
+	resp := &pb.PreviewIngestionResponse{
+		DocumentType:      preview.Params.DocumentType,
+		Strategy:          preview.Params.Strategy,
+		MaxTokens:         int32(preview.Params.MaxTokens),
+		OverlapTokens:     int32(preview.Params.OverlapTokens),
+		TotalChunks:       int32(len(preview.Chunks)),
+		TableRows:         int32(preview.TableRows),
+		Figures:           int32(preview.Figures),
+		EstimatedTokens:   int64(preview.TotalTokens),
+		FlaggedPages:      flaggedPagesProto(preview.Flagged),
+		Languages:         ingest.Languages(preview.Chunks),
+		QuarantinedChunks: int32(preview.Quarantined),
+		MinQuality:        preview.MinQuality,
+	}
+	if len(preview.Redacted) > 0 {
+		resp.Redacted = map[string]int32{}
+		for kind, n := range preview.Redacted {
+			resp.Redacted[kind] = int32(n)
+		}
+	}
+	if len(preview.Chunks) > 0 {
+		resp.Book = preview.Chunks[0].Book
+		resp.Author = preview.Chunks[0].Author
+		resp.PublicationYear = int32(preview.Chunks[0].PublicationYear)
+	}
+	for i, chunk := range preview.Chunks[:min(limit, len(preview.Chunks))] {
+		resp.Chunks = append(resp.Chunks, &pb.ChunkPreview{
+			SectionIndex:    int32(chunk.SectionIndex),
+			SectionPath:     chunk.SectionPath,
+			Chapter:         chunk.Chapter,
+			Section:         chunk.Section,
+			WindowIndex:     int32(chunk.WindowIndex),
+			PageStart:       int32(chunk.PageStart),
+			PageEnd:         int32(chunk.PageEnd),
+			EstimatedTokens: int32(preview.Tokens[i]),
+			NeedsReview:     chunk.NeedsReview,
+			Entities:        chunk.Entities,
+			Text:            strings.Join(chunk.Sentences, " "),
+			Language:        chunk.Language,
+			Quality:         chunk.Quality,
+			QualityIssues:   chunk.QualityIssues,
+			Quarantined:     chunk.Quality < preview.MinQuality,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+
+	"github.com/SaiNageswarS/go-api-boot/auth"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/mcp"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type InteractionService struct {
+	pb.UnimplementedInteractionsServer
+	mongo odm.MongoClient
+}
+
+func ProvideInteractionService(mongo odm.MongoClient) *InteractionService {
+	return &InteractionService{
+		mongo: mongo,
+	}
+}
+
+func (s *InteractionService) CheckInteractions(ctx context.Context, req *pb.CheckInteractionsRequest) (*pb.CheckInteractionsResponse, error) {
+	_, tenant := auth.GetUserIdAndTenant(ctx)
+	if len(req.Substances) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "substances are required")
+	}
+
+	checker := mcp.NewInteractionChecker(odm.CollectionOf[db.InteractionModel](s.mongo, tenant))
+	report, err := checker.Check(ctx, req.Substances)
+	if err != nil {
+		logger.Error("Failed to check interactions", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to check interactions")
+	}
+
+	resp := &pb.CheckInteractionsResponse{Unknown: report.Unknown}
+	for _, interaction := range report.Interactions {
+		resp.Interactions = append(resp.Interactions, &pb.Interaction{
+			Id:          interaction.InteractionID,
+			Kind:        interaction.Kind,
+			First:       &pb.InteractionSubstance{Name: interaction.Substances[0].Name, Type: interaction.Substances[0].Type},
+			Second:      &pb.InteractionSubstance{Name: interaction.Substances[1].Name, Type: interaction.Substances[1].Type},
+			Description: mcp.DescribeInteraction(interaction),
+			Note:        interaction.Note,
+			Source:      interaction.Source,
+		})
+	}
+	for _, pair := range report.NoRecord {
+		resp.NoRecord = append(resp.NoRecord, &pb.SubstancePair{First: pair[0], Second: pair[1]})
+	}
+
+	return resp, nil
+}
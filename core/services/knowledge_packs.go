@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"slices"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
+	"github.com/SaiNageswarS/medicine-rag/core/mcp"
+	"go.uber.org/zap"
+)
+
+// knowledgePacks opens the tenant's knowledge packs for search. Only the shared packs
+// the deployment registers are opened, so a tenant config can't name another tenant's
+// database. A pack's vectors are only comparable to the query's embedding when the pack
+// was embedded with the tenant's model, so other packs, and those whose config can't be
+// read, are searched on their text index alone.
+func (t *SearchTools) knowledgePacks(ctx context.Context, tenant string, tenantConfig *db.TenantConfigModel, embeddingSpec embedding.Spec) []mcp.KnowledgePack {
+	var packs []mcp.KnowledgePack
+	for _, name := range tenantConfig.KnowledgePacks {
+		if name == "" || name == tenant {
+			continue
+		}
+		if !slices.Contains(t.ccfg.KnowledgePacks, name) {
+			logger.Error("Tenant lists an unregistered knowledge pack, skipping it", zap.String("tenant", tenant), zap.String("pack", name))
+			continue
+		}
+
+		pack := mcp.KnowledgePack{
+			Name:    name,
+			Chunks:  odm.CollectionOf[db.ChunkModel](t.mongo, name),
+			Vectors: odm.CollectionOf[db.ChunkAnnModel](t.mongo, name),
+		}
+		packConfig, err := loadTenantConfig(ctx, t.mongo, name)
+		if err == nil {
+			var packSpec embedding.Spec
+			if packSpec, err = t.embedders.Spec(packConfig.Embedder); err == nil {
+				pack.LexicalOnly = packSpec.Model != embeddingSpec.Model || packSpec.Dimensions != embeddingSpec.Dimensions
+			}
+		}
+		if err != nil {
+			logger.Error("Failed to read knowledge pack's embedder, searching its text only", zap.String("tenant", tenant), zap.String("pack", name), zap.Error(err))
+			pack.LexicalOnly = true
+		}
+		packs = append(packs, pack)
+	}
+	return packs
+}
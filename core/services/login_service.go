@@ -8,6 +8,7 @@ import (
 	"github.com/SaiNageswarS/go-api-boot/logger"
 	"github.com/SaiNageswarS/go-api-boot/odm"
 	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/apikeys"
 	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
 	"github.com/SaiNageswarS/medicine-rag/core/db"
 	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
@@ -59,6 +60,35 @@ func (s *LoginService) Login(ctx context.Context, req *pb.LoginRequest) (*pb.Aut
 	}, nil
 }
 
+// ExchangeApiKey trades an API key for a JWT that acts as the key. The token stops
+// working once the key is revoked or rotated; see ApiKeyGuard.
+func (s *LoginService) ExchangeApiKey(ctx context.Context, req *pb.ExchangeApiKeyRequest) (*pb.AuthResponse, error) {
+	req.Tenant = strings.TrimSpace(req.Tenant)
+	keyID, secret, err := apikeys.Parse(req.ApiKey)
+	if req.Tenant == "" || err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid API key")
+	}
+
+	key, err := loadApiKey(ctx, s.mongo, req.Tenant, keyID)
+	if err != nil {
+		logger.Error("Failed to load API key", zap.String("tenant", req.Tenant), zap.String("keyId", keyID), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to check API key")
+	}
+	if key == nil || key.RevokedOn > 0 || !apikeys.Matches(key.SecretHash, secret) {
+		return nil, status.Error(codes.Unauthenticated, "Invalid API key")
+	}
+
+	jwtToken, err := auth.GetToken(req.Tenant, apikeys.UserID(keyID), apikeys.TokenUserType(key.SecretHash))
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Wrong claim")
+	}
+
+	return &pb.AuthResponse{
+		Jwt:      jwtToken,
+		UserType: apikeys.UserType,
+	}, nil
+}
+
 func (s *LoginService) SignUp(ctx context.Context, req *pb.SignUpRequest) (*pb.AuthResponse, error) {
 	if !s.ccfgg.SignUpAllowed || req.Email == "" || req.Password == "" {
 		return nil, status.Error(codes.PermissionDenied, "Sign up is not allowed")
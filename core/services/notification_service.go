@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/auth"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/notifications"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type NotificationService struct {
+	pb.UnimplementedNotificationsServer
+	mongo odm.MongoClient
+}
+
+func ProvideNotificationService(mongo odm.MongoClient) *NotificationService {
+	return &NotificationService{
+		mongo: mongo,
+	}
+}
+
+func (s *NotificationService) GetNotificationPreferences(ctx context.Context, req *pb.GetNotificationPreferencesRequest) (*pb.NotificationPreferences, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+
+	stored, err := loadStoredNotificationPreferences(ctx, s.mongo, tenant, userId)
+	if err != nil {
+		logger.Error("Failed to load notification preferences", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load notification preferences")
+	}
+
+	return s.preferencesProto(ctx, tenant, notifications.Resolve(stored)), nil
+}
+
+func (s *NotificationService) UpdateNotificationPreferences(ctx context.Context, req *pb.UpdateNotificationPreferencesRequest) (*pb.NotificationPreferences, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+
+	channels := make(map[string][]string, len(req.Events))
+	for _, event := range req.Events {
+		channels[event.Event] = append([]string{}, event.Channels...)
+	}
+	if problems := notifications.Validate(channels, req.DigestFrequency); len(problems) > 0 {
+		return nil, status.Error(codes.InvalidArgument, errors.Join(problems...).Error())
+	}
+
+	stored, err := loadStoredNotificationPreferences(ctx, s.mongo, tenant, userId)
+	if err != nil {
+		logger.Error("Failed to load notification preferences", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load notification preferences")
+	}
+	if stored == nil {
+		stored = &db.NotificationPreferencesModel{UserID: userId}
+	}
+	if stored.Channels == nil {
+		stored.Channels = make(map[string][]string, len(channels))
+	}
+	for event, eventChannels := range channels {
+		stored.Channels[event] = eventChannels
+	}
+	if req.DigestFrequency != "" {
+		stored.DigestFrequency = req.DigestFrequency
+	}
+	stored.UpdatedOn = time.Now().Unix()
+
+	if _, err := async.Await(odm.CollectionOf[db.NotificationPreferencesModel](s.mongo, tenant).Save(ctx, *stored)); err != nil {
+		logger.Error("Failed to save notification preferences", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to save notification preferences")
+	}
+
+	return s.preferencesProto(ctx, tenant, notifications.Resolve(stored)), nil
+}
+
+func (s *NotificationService) preferencesProto(ctx context.Context, tenant string, prefs notifications.Preferences) *pb.NotificationPreferences {
+	resp := &pb.NotificationPreferences{
+		DigestFrequency: prefs.DigestFrequency,
+		UpdatedOn:       prefs.UpdatedOn,
+	}
+	for _, event := range notifications.Events {
+		resp.Events = append(resp.Events, &pb.NotificationEventPreference{
+			Event:             event.Name,
+			Channels:          prefs.Channels[event.Name],
+			Description:       event.Description,
+			AvailableChannels: event.Channels,
+		})
+	}
+
+	if tenantConfig, err := loadTenantConfig(ctx, s.mongo, tenant); err == nil {
+		resp.CorpusUpdatesDisabledByTenant = tenantConfig.DisableCorpusUpdateNotifications
+	}
+	return resp
+}
+
+// loadNotificationPreferences returns the user's preferences with defaults filled in.
+// Subsystems call it before notifying a user.
+func loadNotificationPreferences(ctx context.Context, mongo odm.MongoClient, tenant, userId string) (notifications.Preferences, error) {
+	stored, err := loadStoredNotificationPreferences(ctx, mongo, tenant, userId)
+	if err != nil {
+		return notifications.Preferences{}, err
+	}
+	return notifications.Resolve(stored), nil
+}
+
+func loadStoredNotificationPreferences(ctx context.Context, mongo odm.MongoClient, tenant, userId string) (*db.NotificationPreferencesModel, error) {
+	repo := odm.CollectionOf[db.NotificationPreferencesModel](mongo, tenant)
+	exists, err := async.Await(repo.Exists(ctx, userId))
+	if err != nil || !exists {
+		return nil, err
+	}
+	return async.Await(repo.FindOneByID(ctx, userId))
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/mcp"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"github.com/yuin/goldmark"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPortalSearchLimit = 10
+	maxPortalSearchLimit     = 20
+	maxPortalQueryLength     = 200
+	maxPortalSnippetLength   = 320
+	maxPortalPages           = 200
+)
+
+// tenant names become database names, so anything unusual is rejected before touching Mongo.
+var portalTenantPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+type PortalService struct {
+	pb.UnimplementedPortalServer
+	mongo odm.MongoClient
+}
+
+func ProvidePortalService(mongo odm.MongoClient) *PortalService {
+	return &PortalService{
+		mongo: mongo,
+	}
+}
+
+// The portal is public; rate limiting is done by the web tier in front of it.
+func (s *PortalService) AuthFuncOverride(ctx context.Context, fullMethodName string) (context.Context, error) {
+	return ctx, nil
+}
+
+func (s *PortalService) ListPortalPages(ctx context.Context, req *pb.ListPortalPagesRequest) (*pb.ListPortalPagesResponse, error) {
+	tenantConfig, err := s.portalConfig(ctx, req.Tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := async.Await(odm.CollectionOf[db.PortalPageModel](s.mongo, req.Tenant).
+		Find(ctx, bson.M{"published": true}, bson.D{{Key: "title", Value: 1}}, maxPortalPages, 0))
+	if err != nil {
+		logger.Error("Failed to list portal pages", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to list pages")
+	}
+
+	resp := &pb.ListPortalPagesResponse{TenantTitle: tenantConfig.PublicPortalTitle}
+	for _, page := range pages {
+		resp.Pages = append(resp.Pages, &pb.PortalPageSummary{
+			Slug:    page.Slug,
+			Title:   page.Title,
+			Summary: page.Summary,
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *PortalService) GetPortalPage(ctx context.Context, req *pb.GetPortalPageRequest) (*pb.PortalPage, error) {
+	if _, err := s.portalConfig(ctx, req.Tenant); err != nil {
+		return nil, err
+	}
+
+	page, err := async.Await(odm.CollectionOf[db.PortalPageModel](s.mongo, req.Tenant).
+		FindOne(ctx, bson.M{"_id": req.Slug, "published": true}))
+	if err != nil || page == nil {
+		return nil, status.Error(codes.NotFound, "Page not found")
+	}
+
+	// goldmark escapes raw HTML by default, so curated content cannot inject markup.
+	var body bytes.Buffer
+	if err := goldmark.Convert([]byte(page.Body), &body); err != nil {
+		logger.Error("Failed to render portal page", zap.String("slug", page.Slug), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to render page")
+	}
+
+	return &pb.PortalPage{
+		Slug:      page.Slug,
+		Title:     page.Title,
+		Summary:   page.Summary,
+		BodyHtml:  body.String(),
+		Tags:      page.Tags,
+		UpdatedOn: page.UpdatedOn,
+	}, nil
+}
+
+func (s *PortalService) SearchPortal(ctx context.Context, req *pb.SearchPortalRequest) (*pb.SearchPortalResponse, error) {
+	tenantConfig, err := s.portalConfig(ctx, req.Tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	query := strings.TrimSpace(req.Query)
+	if query == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+	if len(query) > maxPortalQueryLength {
+		return nil, status.Error(codes.InvalidArgument, "query is too long")
+	}
+
+	// Nothing has been marked public yet.
+	if len(tenantConfig.PublicTags) == 0 {
+		return &pb.SearchPortalResponse{}, nil
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultPortalSearchLimit
+	}
+	limit = min(limit, maxPortalSearchLimit)
+
+	// documents with access groups stay private whatever their tags, and retired or
+	// excluded sources aren't served
+	clauses := bson.A{bson.M{"tags": bson.M{"$in": tenantConfig.PublicTags}}, db.AccessFilter(nil), db.LiveChunksFilter()}
+	exclusions := mcp.Exclusions{Documents: tenantConfig.ExcludedDocuments, Authors: tenantConfig.ExcludedAuthors}
+	if !exclusions.IsZero() {
+		clauses = append(clauses, exclusions.Filter())
+	}
+	filter := bson.M{"$and": clauses}
+	hits, err := async.Await(odm.CollectionOf[db.ChunkModel](s.mongo, req.Tenant).
+		TermSearch(ctx, query, odm.TermSearchParams{
+			IndexName: db.TextSearchIndexName,
+			Path:      db.TextSearchPaths,
+			Filter:    filter,
+			Limit:     limit,
+		}))
+	if err != nil {
+		logger.Error("Portal search failed", zap.String("tenant", req.Tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Search failed")
+	}
+
+	resp := &pb.SearchPortalResponse{}
+	for _, hit := range hits {
+		resp.Results = append(resp.Results, &pb.PortalSearchResult{
+			Title:       hit.Doc.Title,
+			SectionPath: hit.Doc.SectionPath,
+			Snippet:     portalSnippet(hit.Doc.Sentences),
+			SourceUri:   hit.Doc.SourceURI,
+		})
+	}
+
+	return resp, nil
+}
+
+// portalConfig returns the tenant config, or NotFound when the tenant has no public portal.
+// Disabled and unknown tenants look the same from outside.
+func (s *PortalService) portalConfig(ctx context.Context, tenant string) (*db.TenantConfigModel, error) {
+	if !portalTenantPattern.MatchString(tenant) {
+		return nil, status.Error(codes.NotFound, "Portal not found")
+	}
+
+	tenantConfig, err := loadTenantConfig(ctx, s.mongo, tenant)
+	if err != nil {
+		logger.Error("Failed to load tenant config", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load portal")
+	}
+	if !tenantConfig.PublicPortalEnabled {
+		return nil, status.Error(codes.NotFound, "Portal not found")
+	}
+
+	return tenantConfig, nil
+}
+
+func portalSnippet(sentences []string) string {
+	snippet := strings.Join(strings.Fields(strings.Join(sentences, " ")), " ")
+	if len(snippet) <= maxPortalSnippetLength {
+		return snippet
+	}
+
+	cut := strings.LastIndex(snippet[:maxPortalSnippetLength], " ")
+	if cut <= 0 {
+		cut = maxPortalSnippetLength
+	}
+	return strings.ToValidUTF8(snippet[:cut], "") + "…"
+}
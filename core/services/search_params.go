@@ -0,0 +1,64 @@
+package services
+
+import (
+	"strconv"
+
+	"github.com/SaiNageswarS/medicine-rag/core/mcp"
+	"github.com/ollama/ollama/api"
+)
+
+// debugMetadataKey is the GenerateAnswerRequest metadata entry that asks for the
+// database queries behind each search result, in its queries metadata entry.
+const debugMetadataKey = "debug"
+
+// debugRequested reports whether metadata asks for search diagnostics.
+func debugRequested(metadata map[string]string) bool {
+	requested, _ := strconv.ParseBool(metadata[debugMetadataKey])
+	return requested
+}
+
+// searchFilter reads the search tool's optional source filters. Years the model
+// writes in words or ranges are ignored rather than failing the search.
+func searchFilter(params api.ToolCallFunctionArguments) mcp.SearchFilter {
+	return mcp.SearchFilter{
+		Books:     stringSliceParam(params["books"]),
+		Authors:   stringSliceParam(params["authors"]),
+		Chapters:  stringSliceParam(params["chapters"]),
+		Languages: stringSliceParam(params["languages"]),
+		YearFrom:  intParam(params["published_from"]),
+		YearTo:    intParam(params["published_to"]),
+	}
+}
+
+// searchPage reads the page the search tool is asked for, so the agent can fetch
+// results after those it already has, or ask for fewer or more relevant ones. The
+// tenant's search limits bound it.
+func searchPage(params api.ToolCallFunctionArguments) mcp.SearchPage {
+	return mcp.SearchPage{
+		Offset:   intParam(params["offset"]),
+		Limit:    intParam(params["limit"]),
+		MinScore: floatParam(params["min_score"]),
+	}
+}
+
+func floatParam(value any) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(stringParam(v), 64)
+		return f
+	}
+	return 0
+}
+
+func intParam(value any) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case string:
+		year, _ := strconv.Atoi(stringParam(v))
+		return year
+	}
+	return 0
+}
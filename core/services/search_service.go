@@ -0,0 +1,199 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/go-api-boot/auth"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/mcp"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	maxSearchTopK        = 50
+	maxSearchQueryLength = 1000
+	defaultTableRows     = 10 // table rows returned when topK is zero
+)
+
+// engine scores copied from a search result's metadata into SearchResult.scores.
+var searchScoreKeys = []string{"textRank", "textScore", "vectorRank", "vectorScore", "rerankScore", "sourceBoost"}
+
+type SearchService struct {
+	pb.UnimplementedSearchServer
+	mongo       odm.MongoClient
+	searchTools *SearchTools
+}
+
+func ProvideSearchService(mongo odm.MongoClient, searchTools *SearchTools) *SearchService {
+	return &SearchService{
+		mongo:       mongo,
+		searchTools: searchTools,
+	}
+}
+
+func (s *SearchService) Search(ctx context.Context, req *pb.SearchRequest) (*pb.SearchResponse, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+
+	query := strings.TrimSpace(req.Query)
+	if query == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+	if len(query) > maxSearchQueryLength {
+		return nil, status.Error(codes.InvalidArgument, "query is too long")
+	}
+	if req.TopK < 0 || req.Offset < 0 {
+		return nil, status.Error(codes.InvalidArgument, "topK and offset cannot be negative")
+	}
+	if req.MinScore < 0 || req.MinScore > 1 {
+		return nil, status.Error(codes.InvalidArgument, "minScore must be between 0 and 1")
+	}
+
+	tenantConfig, err := loadTenantConfig(ctx, s.mongo, tenant)
+	if err != nil {
+		logger.Error("Failed to load tenant config", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load tenant config")
+	}
+
+	corpusVersion, err := db.CurrentCorpusVersion(ctx, s.mongo, tenant)
+	if err != nil {
+		logger.Error("Failed to read corpus version", zap.String("tenant", tenant), zap.Error(err))
+	}
+
+	accessGroups := userAccessGroups(ctx, s.mongo, tenant, userId)
+	opts := []mcp.SearchToolOption{mcp.WithAccessGroups(accessGroups)}
+	if req.Facets {
+		opts = append(opts, mcp.WithFacets())
+	}
+	if req.Figures {
+		opts = append(opts, mcp.WithFigures())
+	}
+	if req.PreferredLanguage != "" {
+		opts = append(opts, mcp.WithPreferredLanguage(req.PreferredLanguage))
+	}
+	search, _ := s.searchTools.Build(ctx, tenant, tenantConfig, corpusVersion, opts...)
+	filter := mcp.SearchFilter{
+		Books:     req.Books,
+		Authors:   req.Authors,
+		Chapters:  req.Chapters,
+		Languages: req.Languages,
+		YearFrom:  int(req.PublishedFrom),
+		YearTo:    int(req.PublishedTo),
+	}
+
+	resp := &pb.SearchResponse{}
+	var searchErr error
+	page := mcp.SearchPage{Offset: int(req.Offset), Limit: min(int(req.TopK), maxSearchTopK), MinScore: req.MinScore}
+	for result := range search.Run(ctx, query, filter, page) {
+		if result.Error != "" {
+			searchErr = errors.New(result.Error)
+			continue
+		}
+		resp.Results = append(resp.Results, searchResultProto(result))
+		resp.CorrectedQuery = result.Metadata["correctedQuery"]
+		resp.Partial = resp.Partial || result.Metadata["partial"] == "true"
+		if facets, ok := mcp.ParseFacets(result.Metadata); ok {
+			resp.Facets = searchFacetsProto(facets)
+		}
+	}
+	if searchErr != nil {
+		logger.Error("Search failed", zap.String("tenant", tenant), zap.Error(searchErr))
+		return nil, status.Error(codes.Internal, "Search failed")
+	}
+
+	// sections are sent as they are ready, not necessarily in rank order
+	slices.SortFunc(resp.Results, func(a, b *pb.SearchResult) int { return cmp.Compare(a.Rank, b.Rank) })
+
+	if req.TableRows {
+		limit := min(int(req.TopK), maxSearchTopK)
+		if limit == 0 {
+			limit = defaultTableRows
+		}
+		rows, err := mcp.NewTableTool(odm.CollectionOf[db.TableRowModel](s.mongo, tenant), accessGroups).Rows(ctx, query, limit)
+		if err != nil {
+			logger.Error("Table row search failed", zap.String("tenant", tenant), zap.Error(err))
+			return nil, status.Error(codes.Internal, "Search failed")
+		}
+		for _, row := range rows {
+			resp.TableRows = append(resp.TableRows, tableRowProto(row))
+		}
+	}
+	return resp, nil
+}
+
+func tableRowProto(row db.TableRowModel) *pb.TableRow {
+	return &pb.TableRow{
+		TableId:     row.TableID,
+		RowIndex:    int32(row.RowIndex),
+		Header:      row.Header,
+		Cells:       row.Cells,
+		SectionId:   row.SectionID,
+		ChunkId:     row.ChunkID,
+		Title:       row.Title,
+		Attribution: row.Book,
+		SourceUri:   row.SourceURI,
+	}
+}
+
+func searchResultProto(result *schema.ToolResultChunk) *pb.SearchResult {
+	rank, _ := strconv.Atoi(result.Metadata["rank"])
+	score, _ := strconv.ParseFloat(result.Metadata["fusedScore"], 64)
+
+	scores := make(map[string]string)
+	for _, key := range searchScoreKeys {
+		if value, ok := result.Metadata[key]; ok {
+			scores[key] = value
+		}
+	}
+
+	highlights, _ := mcp.ParseHighlights(result.Metadata)
+	marks := make([]*pb.Highlight, len(highlights))
+	for i, h := range highlights {
+		marks[i] = &pb.Highlight{Sentence: int32(h.Sentence), Start: int32(h.Start), End: int32(h.End), Match: h.Match}
+	}
+
+	figures, _ := mcp.ParseFigures(result.Metadata)
+	refs := make([]*pb.FigureRef, len(figures))
+	for i, f := range figures {
+		refs[i] = &pb.FigureRef{FigureId: f.FigureID, Label: f.Label, Caption: f.Caption}
+	}
+
+	return &pb.SearchResult{
+		SectionId:     result.Id,
+		Title:         result.Title,
+		Attribution:   result.Attribution,
+		Sentences:     result.Sentences,
+		Rank:          int32(rank),
+		Score:         score,
+		KnowledgePack: result.Metadata["knowledgePack"],
+		Scores:        scores,
+		Highlights:    marks,
+		Figures:       refs,
+		Language:      result.Metadata["language"],
+	}
+}
+
+func searchFacetsProto(facets mcp.Facets) *pb.SearchFacets {
+	counts := func(values map[string]int) map[string]int32 {
+		out := make(map[string]int32, len(values))
+		for value, count := range values {
+			out[value] = int32(count)
+		}
+		return out
+	}
+	return &pb.SearchFacets{
+		Books:    counts(facets.Books),
+		Authors:  counts(facets.Authors),
+		Chapters: counts(facets.Chapters),
+	}
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/apikeys"
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
+	"github.com/SaiNageswarS/medicine-rag/core/mcp"
+	"github.com/SaiNageswarS/medicine-rag/core/tenancy"
+	"go.uber.org/zap"
+)
+
+// SearchTools builds tenants' hybrid search with the deployment's and the tenant's
+// search settings, for the agent and the Search API alike.
+type SearchTools struct {
+	mongo           odm.MongoClient
+	embedders       *embedding.Registry
+	limits          *tenancy.Limits
+	exact           *mcp.ExactVectorIndex
+	queryEmbeddings *mcp.EmbeddingCache
+	reranker        *mcp.Reranker
+	synonyms        *mcp.SynonymIndex
+	spelling        *mcp.SpellingIndex
+	ccfg            *appconfig.AppConfig
+}
+
+func ProvideSearchTools(mongo odm.MongoClient, embedders *embedding.Registry, limits *tenancy.Limits, exact *mcp.ExactVectorIndex, queryEmbeddings *mcp.EmbeddingCache, reranker *mcp.Reranker, synonyms *mcp.SynonymIndex, spelling *mcp.SpellingIndex, ccfg *appconfig.AppConfig) *SearchTools {
+	return &SearchTools{
+		mongo:           mongo,
+		embedders:       embedders,
+		limits:          limits,
+		exact:           exact,
+		queryEmbeddings: queryEmbeddings,
+		reranker:        reranker,
+		synonyms:        synonyms,
+		spelling:        spelling,
+		ccfg:            ccfg,
+	}
+}
+
+// Build returns the tenant's search tool, with opts applied after the tenant's settings,
+// and the rate-limited embedder it embeds queries with. The embedder is nil when the
+// tenant's is unavailable.
+func (t *SearchTools) Build(ctx context.Context, tenant string, tenantConfig *db.TenantConfigModel, corpusVersion int64, opts ...mcp.SearchToolOption) (*mcp.SearchTool, embed.Embedder) {
+	chunkRepository := odm.CollectionOf[db.ChunkModel](t.mongo, tenant)
+	vectorRepository := odm.CollectionOf[db.ChunkAnnModel](t.mongo, tenant)
+
+	searchOptions := []mcp.SearchToolOption{mcp.WithExactScan(t.exact, tenant),
+		mcp.WithFusionWeights(fusionWeights(tenantConfig, t.ccfg)), mcp.WithSearchLimits(searchLimits(tenantConfig, t.ccfg)), mcp.WithReranker(t.reranker),
+		mcp.WithTimeBudget(time.Duration(t.ccfg.SearchBudgetMs) * time.Millisecond), mcp.WithEntityBoost(t.ccfg.EntityBoost),
+		mcp.WithSourceBoost(sourceBoost(tenantConfig, t.ccfg)),
+		mcp.WithSynonyms(t.synonyms.Dictionary(ctx, tenant, odm.CollectionOf[db.SynonymModel](t.mongo, tenant))),
+		mcp.WithSpellingCorrection(t.spelling.Vocabulary(ctx, tenant, corpusVersion, chunkRepository)),
+		mcp.WithContextExpansion(mcp.ContextExpansion{Mode: mcp.ContextMode(t.ccfg.SearchContext), Sentences: t.ccfg.SearchContextSentences}),
+		mcp.WithExclusions(mcp.Exclusions{Documents: tenantConfig.ExcludedDocuments, Authors: tenantConfig.ExcludedAuthors}),
+		mcp.WithDeduplication()}
+	// Without the embedder the tenant's vectors were made with, vector search would compare
+	// vectors of different models, so search runs on the text index alone.
+	embeddingSpec, embedder, err := t.embedders.Resolve(tenantConfig.Embedder)
+	if err != nil {
+		logger.Error("Embedder unavailable, searching text only", zap.String("tenant", tenant), zap.String("embedder", tenantConfig.Embedder), zap.Error(err))
+	}
+	if tenantConfig.OfflineMode || embedder == nil {
+		searchOptions = append(searchOptions, mcp.WithLexicalOnly())
+	} else {
+		searchOptions = append(searchOptions, mcp.WithEmbeddingCache(t.queryEmbeddings, tenant, embeddingSpec.Model, odm.CollectionOf[db.QueryEmbeddingModel](t.mongo, tenant)))
+	}
+	if packs := t.knowledgePacks(ctx, tenant, tenantConfig, embeddingSpec); len(packs) > 0 {
+		searchOptions = append(searchOptions, mcp.WithKnowledgePacks(packs...))
+	}
+
+	if embedder != nil {
+		embedder = t.limits.Embedder(tenant, embedder)
+	}
+
+	return mcp.NewSearchTool(chunkRepository, vectorRepository, embedder, append(searchOptions, opts...)...), embedder
+}
+
+// userAccessGroups returns the access groups of the user a call's token was issued to,
+// from their login. API keys have no login and are in no group, so they only retrieve
+// untagged documents, as does a user whose login can't be read.
+func userAccessGroups(ctx context.Context, mongo odm.MongoClient, tenant, userId string) []string {
+	if _, isKey := apikeys.KeyIDFromUserID(userId); isKey {
+		return nil
+	}
+	login, err := async.Await(odm.CollectionOf[db.LoginModel](mongo, tenant).FindOneByID(ctx, userId))
+	if err != nil || login == nil {
+		logger.Error("Failed to load user's access groups", zap.String("tenant", tenant), zap.String("userId", userId), zap.Error(err))
+		return nil
+	}
+	return login.AccessGroups
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/mcp"
+)
+
+// fusionWeights resolves the tenant's hybrid search weights, falling back to the
+// deployment's for each weight the tenant leaves at zero.
+func fusionWeights(tenantConfig *db.TenantConfigModel, ccfg *appconfig.AppConfig) mcp.FusionWeights {
+	return mcp.FusionWeights{
+		Text:   searchSetting(tenantConfig.TextSearchWeight, ccfg.TextSearchWeight),
+		Vector: searchSetting(tenantConfig.VectorSearchWeight, ccfg.VectorSearchWeight),
+	}
+}
+
+// searchLimits resolves how much the tenant's searches rank and send, falling back to
+// the deployment's for each limit the tenant leaves at zero.
+func searchLimits(tenantConfig *db.TenantConfigModel, ccfg *appconfig.AppConfig) mcp.SearchLimits {
+	return mcp.SearchLimits{
+		TopK:     searchSetting(tenantConfig.SearchTopK, ccfg.SearchTopK),
+		Depth:    searchSetting(tenantConfig.SearchDepth, ccfg.SearchDepth),
+		MaxDepth: searchSetting(tenantConfig.MaxSearchDepth, ccfg.MaxSearchDepth),
+		MinScore: searchSetting(tenantConfig.MinSearchScore, ccfg.MinSearchScore),
+	}
+}
+
+// sourceBoost resolves how much the tenant's searches favour newer editions and its
+// preferred sources, falling back to the deployment's for each boost the tenant leaves
+// at zero.
+func sourceBoost(tenantConfig *db.TenantConfigModel, ccfg *appconfig.AppConfig) mcp.SourceBoost {
+	return mcp.SourceBoost{
+		Recency:            searchSetting(tenantConfig.RecencyBoost, ccfg.RecencyBoost),
+		Preferred:          searchSetting(tenantConfig.PreferredSourceBoost, ccfg.PreferredSourceBoost),
+		PreferredDocuments: tenantConfig.PreferredDocuments,
+		PreferredAuthors:   tenantConfig.PreferredAuthors,
+	}
+}
+
+// searchSetting is the tenant's value when positive, zero when negative, and the
+// deployment's otherwise.
+func searchSetting[T int | float64](tenant, deployment T) T {
+	switch {
+	case tenant > 0:
+		return tenant
+	case tenant < 0:
+		return 0
+	default:
+		return max(deployment, 0)
+	}
+}
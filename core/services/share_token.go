@@ -0,0 +1,89 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+// Share tokens are deliberately not JWTs: the auth interceptor accepts any JWT signed with
+// ACCESS-SECRET, so a share token must never parse as one. The HMAC key is derived from the
+// same secret with a purpose prefix.
+const shareTokenPurpose = "medicine-rag/share-link"
+
+var errInvalidShareToken = errors.New("invalid share token")
+
+type shareClaims struct {
+	Tenant    string `json:"t"`
+	SessionId string `json:"s"`
+	ExpiresAt int64  `json:"e"`
+}
+
+func signShareToken(claims shareClaims) (string, error) {
+	key, err := shareTokenKey()
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(shareTokenMac(key, encoded)), nil
+}
+
+func verifyShareToken(token string, now time.Time) (shareClaims, error) {
+	var claims shareClaims
+
+	key, err := shareTokenKey()
+	if err != nil {
+		return claims, err
+	}
+
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return claims, errInvalidShareToken
+	}
+
+	gotMac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(gotMac, shareTokenMac(key, encoded)) {
+		return claims, errInvalidShareToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return claims, errInvalidShareToken
+	}
+
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, errInvalidShareToken
+	}
+
+	if now.Unix() > claims.ExpiresAt {
+		return claims, errors.New("share link expired")
+	}
+
+	return claims, nil
+}
+
+func shareTokenKey() ([]byte, error) {
+	secret := os.Getenv("ACCESS-SECRET")
+	if secret == "" {
+		return nil, errors.New("ACCESS-SECRET is not set in environment")
+	}
+
+	return []byte(shareTokenPurpose + ":" + secret), nil
+}
+
+func shareTokenMac(key []byte, encoded string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
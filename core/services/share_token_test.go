@@ -0,0 +1,68 @@
+package services
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShareToken(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	claims := shareClaims{Tenant: "clinic", SessionId: "session-1", ExpiresAt: now.Add(time.Hour).Unix()}
+
+	t.Setenv("ACCESS-SECRET", "secret")
+	token, err := signShareToken(claims)
+	require.NoError(t, err)
+	payload, mac, _ := strings.Cut(token, ".")
+
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"t":"clinic","s":"session-2","e":1700003600}`))
+	flipped := []byte(mac)
+	flipped[0] ^= 1
+
+	for _, tc := range []struct {
+		name   string
+		token  string
+		secret string
+		now    time.Time
+		valid  bool
+	}{
+		{name: "RoundTrip", token: token, secret: "secret", now: now, valid: true},
+		{name: "TamperedPayload", token: forged + "." + mac, secret: "secret", now: now},
+		{name: "TamperedMac", token: payload + "." + string(flipped), secret: "secret", now: now},
+		{name: "MissingMac", token: payload, secret: "secret", now: now},
+		{name: "OtherSecret", token: token, secret: "rotated", now: now},
+		{name: "Expired", token: token, secret: "secret", now: now.Add(time.Hour + time.Second)},
+		{name: "MissingSecret", token: token, secret: "", now: now},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("ACCESS-SECRET", tc.secret)
+
+			got, err := verifyShareToken(tc.token, tc.now)
+			if !tc.valid {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, claims, got)
+		})
+	}
+}
+
+func TestSignShareTokenWithoutSecret(t *testing.T) {
+	t.Setenv("ACCESS-SECRET", "")
+
+	_, err := signShareToken(shareClaims{Tenant: "clinic", SessionId: "session-1"})
+	assert.Error(t, err)
+}
+
+func TestShareTokenIsNotAJWT(t *testing.T) {
+	t.Setenv("ACCESS-SECRET", "secret")
+
+	token, err := signShareToken(shareClaims{Tenant: "clinic", SessionId: "session-1"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(token, "."), "the auth interceptor must never accept it")
+}
@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/cloud"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
+	"github.com/SaiNageswarS/medicine-rag/core/events"
+	"github.com/SaiNageswarS/medicine-rag/core/ingest"
+	"github.com/SaiNageswarS/medicine-rag/core/tenancy"
+	"github.com/robfig/cron/v3"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.uber.org/zap"
+)
+
+const (
+	// Cron schedules name minutes, so they are checked once a minute.
+	syncCheckInterval = time.Minute
+	// A run still marked running after this long died with its instance, and no longer
+	// holds off the next.
+	syncRunTimeout = 6 * time.Hour
+	// Syncs run on the instance that claimed them; more than this many wait for a slot.
+	maxConcurrentSyncs = 2
+)
+
+// ErrSyncRunning is returned when starting a sync of a source that is already syncing.
+var ErrSyncRunning = errors.New("source is already syncing")
+
+// SourceScheduler re-scans the sources in tenants' configs on their cron schedules and
+// ingests the documents that are new or changed; see ingest.Pipeline.Run. Every
+// instance runs one, and each scheduled run is claimed by a single instance.
+type SourceScheduler struct {
+	mongo        odm.MongoClient
+	az           cloud.Cloud
+	embedders    *embedding.Registry
+	limits       *tenancy.Limits
+	dispatcher   *events.Dispatcher
+	azureAccount string
+	slots        chan struct{}
+
+	mu   sync.Mutex
+	next map[string]nextSync // by tenant and source name
+}
+
+type nextSync struct {
+	schedule string
+	at       time.Time // zero for a schedule that doesn't parse
+}
+
+func ProvideSourceScheduler(ccfg *appconfig.AppConfig, mongo odm.MongoClient, az cloud.Cloud, embedders *embedding.Registry, limits *tenancy.Limits, dispatcher *events.Dispatcher) *SourceScheduler {
+	return &SourceScheduler{
+		mongo:        mongo,
+		az:           az,
+		embedders:    embedders,
+		limits:       limits,
+		dispatcher:   dispatcher,
+		azureAccount: ccfg.AzureStorageAccount,
+		slots:        make(chan struct{}, maxConcurrentSyncs),
+		next:         map[string]nextSync{},
+	}
+}
+
+// Run checks the schedules every minute until ctx is done.
+func (s *SourceScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(syncCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.check(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check starts the syncs that came due by now. A source's first check only schedules
+// it, so a restart doesn't sync every source at once.
+func (s *SourceScheduler) check(ctx context.Context, now time.Time) {
+	tenants, err := s.mongo.Database("admin").Client().ListDatabaseNames(ctx,
+		bson.M{"name": bson.M{"$nin": bson.A{"admin", "local", "config"}}})
+	if err != nil {
+		logger.Error("Failed to list tenants for source sync", zap.Error(err))
+		return
+	}
+
+	for _, tenant := range tenants {
+		sources, err := s.scheduledSources(ctx, tenant)
+		if err != nil {
+			logger.Error("Failed to load scheduled sources", zap.String("tenant", tenant), zap.Error(err))
+			continue
+		}
+
+		for _, source := range sources {
+			scheduledFor, due := s.due(tenant, source, now)
+			if !due {
+				continue
+			}
+			_, err := s.Start(ctx, tenant, source, db.SyncTriggerSchedule, scheduledFor)
+			if err != nil && !errors.Is(err, ErrSyncRunning) {
+				logger.Error("Failed to start source sync", zap.String("tenant", tenant),
+					zap.String("source", source.Name), zap.Error(err))
+			}
+		}
+	}
+}
+
+// scheduledSources returns the tenant's sources that have a schedule.
+func (s *SourceScheduler) scheduledSources(ctx context.Context, tenant string) ([]db.SourceConfig, error) {
+	var config db.TenantConfigModel
+	err := s.mongo.Database(tenant).Collection(config.CollectionName()).
+		FindOne(ctx, bson.M{"_id": db.TenantConfigID, "sources.schedule": bson.M{"$exists": true}}).
+		Decode(&config)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []db.SourceConfig
+	for _, source := range config.Sources {
+		if source.Schedule != "" {
+			sources = append(sources, source)
+		}
+	}
+	return sources, nil
+}
+
+// due reports whether the source's next scheduled time has passed, and which time it
+// was, then moves on to the time after now. Times missed while no instance was
+// running are not caught up on; the next sync ingests what they would have.
+func (s *SourceScheduler) due(tenant string, source db.SourceConfig, now time.Time) (time.Time, bool) {
+	key := tenant + "/" + source.Name
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, seen := s.next[key]
+	if seen && next.schedule == source.Schedule {
+		if next.at.IsZero() || now.Before(next.at) {
+			return time.Time{}, false
+		}
+	}
+
+	schedule, err := cron.ParseStandard(source.Schedule)
+	if err != nil {
+		logger.Error("Invalid source schedule", zap.String("tenant", tenant), zap.String("source", source.Name),
+			zap.String("schedule", source.Schedule), zap.Error(err))
+		s.next[key] = nextSync{schedule: source.Schedule}
+		return time.Time{}, false
+	}
+	s.next[key] = nextSync{schedule: source.Schedule, at: schedule.Next(now)}
+
+	// a new or changed schedule counts from now
+	if !seen || next.schedule != source.Schedule {
+		return time.Time{}, false
+	}
+	return next.at, true
+}
+
+// Start records a sync run of the tenant's source and performs it in the background.
+// A scheduled run's id is derived from its scheduled time, so when several instances
+// start the same one, all but the first get ErrSyncRunning, as does any start while
+// the source has a run going.
+func (s *SourceScheduler) Start(ctx context.Context, tenant string, source db.SourceConfig, trigger string, scheduledFor time.Time) (*db.SyncRunModel, error) {
+	now := time.Now()
+	runs := s.mongo.Database(tenant).Collection(db.SyncRunModel{}.CollectionName())
+
+	running, err := runs.CountDocuments(ctx, bson.M{
+		"source":    source.Name,
+		"status":    db.SyncRunRunning,
+		"startedOn": bson.M{"$gt": now.Add(-syncRunTimeout).Unix()},
+	})
+	if err != nil {
+		return nil, errors.New("failed to check running syncs: " + err.Error())
+	}
+	if running > 0 {
+		return nil, ErrSyncRunning
+	}
+
+	run := &db.SyncRunModel{
+		Source:    source.Name,
+		Kind:      source.Kind,
+		Trigger:   trigger,
+		Status:    db.SyncRunRunning,
+		StartedOn: now.Unix(),
+	}
+	if trigger == db.SyncTriggerSchedule {
+		run.ScheduledFor = scheduledFor.Unix()
+		run.RunID, _ = odm.HashedKey(source.Name, strconv.FormatInt(run.ScheduledFor, 10))
+	} else {
+		run.RunID, _ = odm.HashedKey(source.Name, strconv.FormatInt(now.UnixNano(), 10))
+	}
+
+	if _, err := runs.InsertOne(ctx, run); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrSyncRunning
+		}
+		return nil, errors.New("failed to record sync run: " + err.Error())
+	}
+
+	logger.Info("Source sync started", zap.String("tenant", tenant), zap.String("source", source.Name),
+		zap.String("runId", run.RunID), zap.String("trigger", trigger))
+
+	started := *run
+	go s.run(context.WithoutCancel(ctx), tenant, source, run)
+	return &started, nil
+}
+
+// run syncs the source and records how the run went, telling the tenant's event sinks
+// when it starts and ends.
+func (s *SourceScheduler) run(ctx context.Context, tenant string, source db.SourceConfig, run *db.SyncRunModel) {
+	ctx, cancel := context.WithTimeout(ctx, syncRunTimeout)
+	defer cancel()
+
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+
+	s.dispatcher.Emit(ctx, events.New(events.JobStarted, tenant, syncRunEvent(*run)))
+	report, err := s.sync(ctx, tenant, source, run)
+	run.Ingested = report.Ingested
+	run.Resumed = report.Resumed
+	run.Unchanged = report.Unchanged
+	run.Duplicates = report.Duplicates
+	run.Unsupported = report.Unsupported
+	run.Failed = report.Failed
+	run.Chunks = report.Chunks
+	run.Embedded = report.Embedded
+	run.Quarantined = report.Quarantined
+	run.Redacted = report.Redacted
+	run.FinishedOn = time.Now().Unix()
+	run.Status = db.SyncRunDone
+	if err != nil {
+		run.Status = db.SyncRunFailed
+		run.Error = err.Error()
+		logger.Error("Source sync failed", zap.String("tenant", tenant), zap.String("source", source.Name),
+			zap.String("runId", run.RunID), zap.Error(err))
+	} else {
+		logger.Info("Source sync done", zap.String("tenant", tenant), zap.String("source", source.Name),
+			zap.String("runId", run.RunID), zap.Int("ingested", report.Ingested), zap.Int("failed", report.Failed))
+	}
+
+	saveCtx, cancelSave := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancelSave()
+	if _, err := async.Await(odm.CollectionOf[db.SyncRunModel](s.mongo, tenant).Save(saveCtx, *run)); err != nil {
+		logger.Error("Failed to save sync run", zap.String("tenant", tenant), zap.String("runId", run.RunID), zap.Error(err))
+	}
+
+	eventType := events.JobCompleted
+	if run.Status == db.SyncRunFailed {
+		eventType = events.JobFailed
+	}
+	s.dispatcher.Emit(ctx, events.New(eventType, tenant, syncRunEvent(*run)))
+}
+
+func (s *SourceScheduler) sync(ctx context.Context, tenant string, source db.SourceConfig, run *db.SyncRunModel) (ingest.Report, error) {
+	pipeline, err := tenantPipeline(ctx, s.mongo, s.embedders, s.limits, tenant)
+	if err != nil {
+		return ingest.Report{}, err
+	}
+	pipeline.AccessGroups = source.AccessGroups
+	pipeline.Storage = s.az
+	pipeline.Indexed = s.dispatcher.IndexedHook(tenant, syncRunEvent(*run))
+	documents, err := ingest.OpenSource(ctx, source, s.azureAccount)
+	if err != nil {
+		return ingest.Report{}, errors.New("failed to open source: " + err.Error())
+	}
+	return pipeline.Run(ctx, tenant, documents)
+}
+
+// syncRunEvent describes the run in events.
+func syncRunEvent(run db.SyncRunModel) events.Job {
+	return events.Job{
+		ID:       run.RunID,
+		Kind:     events.JobSync,
+		Source:   run.Source,
+		Status:   run.Status,
+		Error:    run.Error,
+		Ingested: run.Ingested + run.Resumed,
+		Failed:   run.Failed,
+		Chunks:   run.Chunks,
+		Embedded: run.Embedded,
+	}
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SaiNageswarS/agent-boot/agentboot"
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/medicine-rag/core/followups"
+)
+
+const terminatedCode = "terminated"
+
+var errTerminatedByOperator = errors.New("terminated by operator")
+
+// StreamRegistry tracks the agent runs in flight so operators can find runs stuck on a
+// hung provider connection and end them. Runs are kept in memory, so each instance only
+// knows its own streams.
+type StreamRegistry struct {
+	mu   sync.Mutex
+	runs map[string]*activeRun
+}
+
+func ProvideStreamRegistry() *StreamRegistry {
+	return &StreamRegistry{runs: make(map[string]*activeRun)}
+}
+
+type activeRun struct {
+	id        string
+	tenant    string
+	userId    string
+	sessionId string
+	started   time.Time
+	cancel    context.CancelCauseFunc
+
+	terminated atomic.Bool
+	// held while an event is forwarded, so that nothing reaches the client after the
+	// termination error
+	sendMu sync.Mutex
+
+	mu           sync.Mutex
+	model        string
+	stage        string
+	stageDetail  string
+	stageUpdated time.Time
+}
+
+// runSnapshot is a point-in-time copy of an active run.
+type runSnapshot struct {
+	ID           string
+	Tenant       string
+	UserID       string
+	SessionID    string
+	Model        string
+	Started      time.Time
+	Stage        string
+	StageDetail  string
+	StageUpdated time.Time
+}
+
+// Start registers a run. The returned context is cancelled when an operator terminates
+// the run; finish must be called once the run is over.
+func (r *StreamRegistry) Start(ctx context.Context, tenant, userId, sessionId string) (context.Context, *activeRun) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	now := time.Now()
+	id, _ := odm.HashedKey(tenant, userId, sessionId, strconv.FormatInt(now.UnixNano(), 10))
+	run := &activeRun{
+		id:           id,
+		tenant:       tenant,
+		userId:       userId,
+		sessionId:    sessionId,
+		started:      now,
+		cancel:       cancel,
+		stage:        "starting",
+		stageUpdated: now,
+	}
+
+	r.mu.Lock()
+	r.runs[id] = run
+	r.mu.Unlock()
+	return ctx, run
+}
+
+func (r *StreamRegistry) finish(run *activeRun) {
+	r.mu.Lock()
+	delete(r.runs, run.id)
+	r.mu.Unlock()
+	run.cancel(nil)
+}
+
+// List returns the active runs, oldest first, optionally restricted to one tenant.
+func (r *StreamRegistry) List(tenant string) []runSnapshot {
+	r.mu.Lock()
+	runs := make([]*activeRun, 0, len(r.runs))
+	for _, run := range r.runs {
+		if tenant == "" || run.tenant == tenant {
+			runs = append(runs, run)
+		}
+	}
+	r.mu.Unlock()
+
+	snapshots := make([]runSnapshot, 0, len(runs))
+	for _, run := range runs {
+		snapshots = append(snapshots, run.snapshot())
+	}
+	slices.SortFunc(snapshots, func(a, b runSnapshot) int { return a.Started.Compare(b.Started) })
+	return snapshots
+}
+
+// Terminate cancels a run. Its provider calls are aborted and the client is sent a
+// StreamError instead of the rest of the answer. It reports whether the run existed.
+func (r *StreamRegistry) Terminate(id string) (runSnapshot, bool) {
+	r.mu.Lock()
+	run, ok := r.runs[id]
+	r.mu.Unlock()
+	if !ok {
+		return runSnapshot{}, false
+	}
+
+	run.terminated.Store(true)
+	run.cancel(errTerminatedByOperator)
+	return run.snapshot(), true
+}
+
+func (run *activeRun) setModel(model string) {
+	run.mu.Lock()
+	run.model = model
+	run.mu.Unlock()
+}
+
+func (run *activeRun) setStage(stage, detail string) {
+	run.mu.Lock()
+	run.stage, run.stageDetail, run.stageUpdated = stage, detail, time.Now()
+	run.mu.Unlock()
+}
+
+func (run *activeRun) Terminated() bool {
+	return run.terminated.Load()
+}
+
+func (run *activeRun) snapshot() runSnapshot {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	return runSnapshot{
+		ID:           run.id,
+		Tenant:       run.tenant,
+		UserID:       run.userId,
+		SessionID:    run.sessionId,
+		Model:        run.model,
+		Started:      run.started,
+		Stage:        run.stage,
+		StageDetail:  run.stageDetail,
+		StageUpdated: run.stageUpdated,
+	}
+}
+
+// terminate sends the client the termination error. Events the agent sends afterwards,
+// while it winds down, are dropped.
+func (run *activeRun) terminate(inner agentboot.ProgressReporter) error {
+	run.sendMu.Lock()
+	defer run.sendMu.Unlock()
+	return inner.Send(agentboot.NewStreamError("This answer was stopped by an operator. Please try again.", terminatedCode))
+}
+
+// runReporter records the stage of a run from the events it streams.
+type runReporter struct {
+	agentboot.ProgressReporter
+	run *activeRun
+}
+
+func (r *runReporter) Send(event *schema.AgentStreamChunk) error {
+	switch chunk := event.ChunkType.(type) {
+	case *schema.AgentStreamChunk_ProgressUpdateChunk:
+		if chunk.ProgressUpdateChunk != nil {
+			r.run.setStage(chunk.ProgressUpdateChunk.Stage.String(), chunk.ProgressUpdateChunk.Message)
+		}
+	case *schema.AgentStreamChunk_ToolResultChunk:
+		// follow-up suggestions come after the run has completed
+		if chunk.ToolResultChunk != nil && chunk.ToolResultChunk.ToolName != followups.ToolName {
+			r.run.setStage("tool_result", chunk.ToolResultChunk.Title)
+		}
+	case *schema.AgentStreamChunk_Answer:
+		r.run.setStage("answering", "")
+	case *schema.AgentStreamChunk_Error:
+		if chunk.Error != nil {
+			r.run.setStage("failed", chunk.Error.ErrorCode)
+		}
+	case *schema.AgentStreamChunk_Complete:
+		r.run.setStage("completed", "")
+	}
+
+	r.run.sendMu.Lock()
+	defer r.run.sendMu.Unlock()
+	if r.run.Terminated() {
+		return nil
+	}
+	return r.ProgressReporter.Send(event)
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/auth"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/go-api-boot/odm"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/llmrouter"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultUsagePageSize = 100
+	maxUsagePageSize     = 500
+
+	quotaExceededCode = "quota_exceeded"
+)
+
+var usageMonthPattern = regexp.MustCompile(`^\d{4}-(0[1-9]|1[0-2])$`)
+
+type UsageService struct {
+	pb.UnimplementedUsageServer
+	mongo odm.MongoClient
+	ccfg  *appconfig.AppConfig
+}
+
+func ProvideUsageService(mongo odm.MongoClient, ccfg *appconfig.AppConfig) *UsageService {
+	return &UsageService{
+		mongo: mongo,
+		ccfg:  ccfg,
+	}
+}
+
+func (s *UsageService) GetUsage(ctx context.Context, req *pb.GetUsageRequest) (*pb.GetUsageResponse, error) {
+	_, tenant := auth.GetUserIdAndTenant(ctx)
+	month, err := usageMonth(req.Month)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	tenantConfig, err := loadTenantConfig(ctx, s.mongo, tenant)
+	if err != nil {
+		logger.Error("Failed to load tenant config", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to load tenant config")
+	}
+
+	cursor, err := s.mongo.Database(tenant).Collection(db.UsageModel{}.CollectionName()).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"month": month}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":              bson.M{"provider": "$provider", "model": "$model"},
+			"promptTokens":     bson.M{"$sum": "$promptTokens"},
+			"completionTokens": bson.M{"$sum": "$completionTokens"},
+			"estimated":        bson.M{"$max": "$estimated"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id.provider", Value: 1}, {Key: "_id.model", Value: 1}}}},
+	})
+	if err != nil {
+		logger.Error("Failed to aggregate usage", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to read usage")
+	}
+
+	var groups []struct {
+		ID struct {
+			Provider string `bson:"provider"`
+			Model    string `bson:"model"`
+		} `bson:"_id"`
+		PromptTokens     int64 `bson:"promptTokens"`
+		CompletionTokens int64 `bson:"completionTokens"`
+		Estimated        bool  `bson:"estimated"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		logger.Error("Failed to read usage", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to read usage")
+	}
+
+	resp := &pb.GetUsageResponse{
+		Month: month,
+		Quota: monthlyTokenQuota(tenantConfig, s.ccfg),
+	}
+	for _, g := range groups {
+		resp.PromptTokens += g.PromptTokens
+		resp.CompletionTokens += g.CompletionTokens
+		resp.Models = append(resp.Models, &pb.ModelUsage{
+			Provider:         g.ID.Provider,
+			Model:            g.ID.Model,
+			PromptTokens:     g.PromptTokens,
+			CompletionTokens: g.CompletionTokens,
+			Estimated:        g.Estimated,
+		})
+	}
+	resp.TotalTokens = resp.PromptTokens + resp.CompletionTokens
+
+	return resp, nil
+}
+
+func (s *UsageService) ListUsage(ctx context.Context, req *pb.ListUsageRequest) (*pb.ListUsageResponse, error) {
+	userId, tenant := auth.GetUserIdAndTenant(ctx)
+	month, err := usageMonth(req.Month)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if req.Offset < 0 {
+		return nil, status.Error(codes.InvalidArgument, "offset must not be negative")
+	}
+
+	pageSize := int64(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultUsagePageSize
+	}
+	pageSize = min(pageSize, maxUsagePageSize)
+
+	filter := bson.M{"month": month}
+	if req.OnlyMine {
+		filter["userId"] = userId
+	}
+
+	records, err := async.Await(odm.CollectionOf[db.UsageModel](s.mongo, tenant).
+		Find(ctx, filter, bson.D{{Key: "createdOn", Value: -1}}, pageSize, int64(req.Offset)))
+	if err != nil {
+		logger.Error("Failed to list usage", zap.String("tenant", tenant), zap.Error(err))
+		return nil, status.Error(codes.Internal, "Failed to list usage")
+	}
+
+	resp := &pb.ListUsageResponse{}
+	for _, r := range records {
+		resp.Records = append(resp.Records, &pb.UsageRecord{
+			ExecutionId:      r.ExecutionID,
+			SessionId:        r.SessionID,
+			UserId:           r.UserID,
+			Provider:         r.Provider,
+			Model:            r.Model,
+			PromptTokens:     r.PromptTokens,
+			CompletionTokens: r.CompletionTokens,
+			Estimated:        r.Estimated,
+			CreatedOn:        r.CreatedOn,
+		})
+	}
+	return resp, nil
+}
+
+func usageMonth(requested string) (string, error) {
+	if requested == "" {
+		return db.UsageMonth(time.Now()), nil
+	}
+	if !usageMonthPattern.MatchString(requested) {
+		return "", errors.New("month must be formatted as YYYY-MM")
+	}
+	return requested, nil
+}
+
+// monthlyTokenQuota returns the tenant's quota, or 0 when it is unlimited.
+func monthlyTokenQuota(tenantConfig *db.TenantConfigModel, ccfg *appconfig.AppConfig) int64 {
+	switch {
+	case tenantConfig.MonthlyTokenQuota > 0:
+		return tenantConfig.MonthlyTokenQuota
+	case tenantConfig.MonthlyTokenQuota < 0:
+		return 0
+	default:
+		return max(ccfg.MonthlyTokenQuota, 0)
+	}
+}
+
+// quotaExceeded reports whether the tenant has used up this month's quota. The check
+// runs before an execution starts, so the execution that crosses the quota still
+// finishes and the overshoot is bounded by a single answer.
+func quotaExceeded(ctx context.Context, mongo odm.MongoClient, tenant string, quota int64) (bool, error) {
+	return monthQuotaExceeded(ctx, odm.CollectionOf[db.UsageTotalModel](mongo, tenant), quota, time.Now())
+}
+
+// monthQuotaExceeded checks the total of the month now falls in, so usage of earlier
+// months never counts.
+func monthQuotaExceeded(ctx context.Context, repo odm.OdmCollectionInterface[db.UsageTotalModel], quota int64, now time.Time) (bool, error) {
+	if quota == 0 {
+		return false, nil
+	}
+
+	month := db.UsageMonth(now)
+	exists, err := async.Await(repo.Exists(ctx, month))
+	if err != nil || !exists {
+		return false, err
+	}
+
+	total, err := async.Await(repo.FindOneByID(ctx, month))
+	if err != nil {
+		return false, err
+	}
+	return total.TotalTokens() >= quota, nil
+}
+
+// recordUsage stores one record per model the execution used and adds the tokens to
+// the tenant's monthly total.
+func recordUsage(ctx context.Context, mongo odm.MongoClient, tenant, sessionId, userId string, meter *llmrouter.Meter) {
+	usage := meter.Usage()
+	if len(usage) == 0 {
+		return
+	}
+
+	now := time.Now()
+	month := db.UsageMonth(now)
+	executionId, _ := odm.HashedKey(tenant, userId, sessionId, strconv.FormatInt(now.UnixNano(), 10))
+
+	repo := odm.CollectionOf[db.UsageModel](mongo, tenant)
+	var total llmrouter.Usage
+	for key, u := range usage {
+		usageId, _ := odm.HashedKey(executionId, key.Provider, key.Model)
+		_, err := async.Await(repo.Save(ctx, db.UsageModel{
+			UsageID:          usageId,
+			ExecutionID:      executionId,
+			SessionID:        sessionId,
+			UserID:           userId,
+			Month:            month,
+			Provider:         key.Provider,
+			Model:            key.Model,
+			PromptTokens:     u.PromptTokens,
+			CompletionTokens: u.CompletionTokens,
+			Estimated:        u.Estimated,
+			CreatedOn:        now.Unix(),
+		}))
+		if err != nil {
+			logger.Error("Failed to save usage", zap.String("tenant", tenant), zap.String("model", key.Model), zap.Error(err))
+		}
+
+		total.PromptTokens += u.PromptTokens
+		total.CompletionTokens += u.CompletionTokens
+	}
+
+	_, err := mongo.Database(tenant).Collection(db.UsageTotalModel{}.CollectionName()).UpdateOne(ctx,
+		bson.M{"_id": month},
+		bson.M{
+			"$inc": bson.M{"promptTokens": total.PromptTokens, "completionTokens": total.CompletionTokens},
+			"$set": bson.M{"updatedOn": now.Unix()},
+		},
+		options.UpdateOne().SetUpsert(true),
+	)
+	if err != nil {
+		logger.Error("Failed to update usage total", zap.String("tenant", tenant), zap.String("month", month), zap.Error(err))
+	}
+}
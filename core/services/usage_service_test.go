@@ -0,0 +1,66 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/odmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonthlyTokenQuota(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		tenant   int64
+		deployed int64
+		want     int64
+	}{
+		{name: "AppDefault", deployed: 1000, want: 1000},
+		{name: "TenantOverride", tenant: 5000, deployed: 1000, want: 5000},
+		{name: "TenantOverrideWithoutDefault", tenant: 5000, want: 5000},
+		{name: "TenantUnlimited", tenant: -1, deployed: 1000, want: 0},
+		{name: "Unlimited"},
+		{name: "NegativeAppDefault", deployed: -1, want: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			quota := monthlyTokenQuota(&db.TenantConfigModel{MonthlyTokenQuota: tc.tenant}, &appconfig.AppConfig{MonthlyTokenQuota: tc.deployed})
+			assert.Equal(t, tc.want, quota)
+		})
+	}
+}
+
+func TestMonthQuotaExceeded(t *testing.T) {
+	// June's total is over any quota below; the check must only look at the month now falls in.
+	repo := odmtest.NewCollection(
+		db.UsageTotalModel{Month: "2025-06", PromptTokens: 9000, CompletionTokens: 1000},
+		db.UsageTotalModel{Month: "2025-07", PromptTokens: 600, CompletionTokens: 400},
+	)
+	lastSecondOfJune := time.Date(2025, 6, 30, 23, 59, 59, 0, time.UTC)
+	firstSecondOfJuly := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	firstSecondOfAugust := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name  string
+		quota int64
+		now   time.Time
+		want  bool
+	}{
+		{name: "Unlimited", quota: 0, now: lastSecondOfJune},
+		{name: "OverInJune", quota: 5000, now: lastSecondOfJune, want: true},
+		{name: "NewMonthStartsAfresh", quota: 5000, now: firstSecondOfJuly},
+		{name: "AtQuota", quota: 1000, now: firstSecondOfJuly, want: true},
+		{name: "UnderQuota", quota: 1001, now: firstSecondOfJuly},
+		{name: "NoUsageYet", quota: 1, now: firstSecondOfAugust},
+		// Months are UTC, so 1 July 01:00 in Berlin is still June.
+		{name: "MonthIsUTC", quota: 5000, now: time.Date(2025, 7, 1, 1, 0, 0, 0, time.FixedZone("CEST", 2*60*60)), want: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			exceeded, err := monthQuotaExceeded(t.Context(), repo, tc.quota, tc.now)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, exceeded)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package structured
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Request metadata keys. GenerateAnswerRequest belongs to agent-boot, so the response
+// format travels in its metadata like the model name does.
+const (
+	// FormatKey selects the format: "text" (the default), "json_schema", or the name of a
+	// built-in format such as "remedy".
+	FormatKey = "response_format"
+	// SchemaKey holds the JSON schema when FormatKey is "json_schema".
+	SchemaKey = "response_schema"
+)
+
+const remedySchema = `{
+	"type": "object",
+	"properties": {
+		"remedy": {"type": "string", "minLength": 1, "description": "name of the remedy"},
+		"potency": {"type": "string", "description": "recommended potency, e.g. 30C; empty if the sources give none"},
+		"indications": {"type": "array", "items": {"type": "string"}, "description": "symptoms and conditions the remedy is indicated for"},
+		"sources": {"type": "array", "items": {"type": "string"}, "description": "titles of the search results the answer is based on"}
+	},
+	"required": ["remedy", "potency", "indications", "sources"],
+	"additionalProperties": false
+}`
+
+// builtinFormats are the schemas callers can request by name.
+var builtinFormats = map[string]string{
+	"remedy": remedySchema,
+}
+
+// Format is a requested answer structure.
+type Format struct {
+	Name   string
+	Schema *Schema
+	raw    string // the schema as shown to the model
+}
+
+// ParseFormat reads the format from request metadata. It returns nil for plain text
+// answers.
+func ParseFormat(metadata map[string]string) (*Format, error) {
+	name := strings.ToLower(strings.TrimSpace(metadata[FormatKey]))
+
+	var raw string
+	switch name {
+	case "", "text":
+		return nil, nil
+	case "json_schema":
+		raw = metadata[SchemaKey]
+		if strings.TrimSpace(raw) == "" {
+			return nil, errors.New(SchemaKey + " is required when " + FormatKey + " is json_schema")
+		}
+	default:
+		var ok bool
+		if raw, ok = builtinFormats[name]; !ok {
+			return nil, fmt.Errorf("unknown %s %q", FormatKey, name)
+		}
+	}
+
+	schema, err := ParseSchema(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(raw)); err != nil {
+		return nil, err
+	}
+	return &Format{Name: name, Schema: schema, raw: compact.String()}, nil
+}
+
+// Instruction is appended to the agent's system prompt.
+func (f *Format) Instruction() string {
+	return "Respond with a single JSON value and nothing else: no prose before or after it and no " +
+		"markdown code fences. The JSON must conform to this JSON schema:\n" + f.raw
+}
+
+// Extract parses the JSON value in a model's output, tolerating code fences and prose
+// around it.
+func Extract(output string) (any, error) {
+	text := strings.TrimSpace(output)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text[strings.IndexByte(text+"\n", '\n'):], "\n")
+		text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(text), &value); err == nil {
+		return value, nil
+	}
+
+	// fall back to the outermost object or array in the text
+	start := strings.IndexAny(text, "{[")
+	if start >= 0 {
+		closer := byte('}')
+		if text[start] == '[' {
+			closer = ']'
+		}
+		if end := strings.LastIndexByte(text, closer); end > start {
+			if err := json.Unmarshal([]byte(text[start:end+1]), &value); err == nil {
+				return value, nil
+			}
+		}
+	}
+	return nil, errors.New("$: the answer is not valid JSON")
+}
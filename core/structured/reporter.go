@@ -0,0 +1,150 @@
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/SaiNageswarS/agent-boot/agentboot"
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	"github.com/SaiNageswarS/medicine-rag/core/llmrouter"
+	"go.uber.org/zap"
+)
+
+const invalidOutputCode = "invalid_structured_output"
+
+// at most this many validation problems are reported to the model or the client
+const maxReportedProblems = 10
+
+const repairSystemPrompt = "You correct JSON documents so that they conform to a JSON schema. " +
+	"Keep the content of the document; change only what the schema requires. Reply with the JSON only."
+
+// Reporter holds a structured answer back until it is complete, validates it against
+// the requested schema and has the model repair it when it does not conform. The client
+// receives the answer once, as canonical JSON.
+type Reporter struct {
+	agentboot.ProgressReporter
+	ctx        context.Context
+	format     *Format
+	model      llm.LLMClient
+	maxRepairs int
+
+	mu     sync.Mutex
+	answer strings.Builder
+}
+
+func NewReporter(ctx context.Context, inner agentboot.ProgressReporter, format *Format, model llm.LLMClient, maxRepairs int) *Reporter {
+	return &Reporter{
+		ProgressReporter: inner,
+		ctx:              ctx,
+		format:           format,
+		model:            model,
+		maxRepairs:       max(maxRepairs, 0),
+	}
+}
+
+func (r *Reporter) Send(event *schema.AgentStreamChunk) error {
+	switch chunk := event.ChunkType.(type) {
+	case *schema.AgentStreamChunk_Answer:
+		if chunk.Answer != nil {
+			r.mu.Lock()
+			r.answer.WriteString(chunk.Answer.Content)
+			r.mu.Unlock()
+		}
+		return nil // sent once the answer has been validated
+
+	case *schema.AgentStreamChunk_Complete:
+		if chunk.Complete != nil {
+			return r.complete(chunk.Complete)
+		}
+	}
+
+	return r.ProgressReporter.Send(event)
+}
+
+func (r *Reporter) complete(complete *schema.StreamComplete) error {
+	r.mu.Lock()
+	output := complete.Answer
+	if output == "" {
+		output = r.answer.String()
+	}
+	r.mu.Unlock()
+
+	// Inference failed and the error has already been sent; there is nothing to validate.
+	if strings.TrimSpace(output) == "" {
+		return r.ProgressReporter.Send(agentboot.NewStreamComplete(complete))
+	}
+
+	value, problems := r.check(output)
+	repairs := 0
+	for len(problems) > 0 && repairs < r.maxRepairs {
+		repairs++
+		repaired, err := r.repair(output, problems)
+		if err != nil {
+			logger.Error("Failed to repair structured answer", zap.String("format", r.format.Name), zap.Error(err))
+			break
+		}
+		output = repaired
+		value, problems = r.check(output)
+	}
+
+	if complete.Metadata == nil {
+		complete.Metadata = make(map[string]string)
+	}
+	complete.Metadata["responseFormat"] = r.format.Name
+	complete.Metadata["schemaValid"] = strconv.FormatBool(len(problems) == 0)
+	complete.Metadata["repairAttempts"] = strconv.Itoa(repairs)
+
+	if len(problems) == 0 {
+		canonical, _ := json.MarshalIndent(value, "", "  ")
+		output = string(canonical)
+	} else {
+		complete.Metadata["schemaErrors"] = strings.Join(problems, "; ")
+		logger.Info("Structured answer does not conform to schema",
+			zap.String("format", r.format.Name), zap.Int("repairAttempts", repairs), zap.Strings("problems", problems))
+		if err := r.ProgressReporter.Send(agentboot.NewStreamError("The answer does not match the requested format.", invalidOutputCode)); err != nil {
+			return err
+		}
+	}
+
+	complete.Answer = output
+	if err := r.ProgressReporter.Send(agentboot.NewAnswerChunk(&schema.AnswerChunk{Content: output})); err != nil {
+		return err
+	}
+	return r.ProgressReporter.Send(agentboot.NewStreamComplete(complete))
+}
+
+func (r *Reporter) check(output string) (any, []string) {
+	value, err := Extract(output)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+
+	problems := r.format.Schema.Validate(value)
+	if len(problems) > maxReportedProblems {
+		problems = append(problems[:maxReportedProblems], fmt.Sprintf("and %d more", len(problems)-maxReportedProblems))
+	}
+	return value, problems
+}
+
+func (r *Reporter) repair(output string, problems []string) (string, error) {
+	prompt := fmt.Sprintf("JSON schema:\n%s\n\nDocument:\n%s\n\nProblems:\n- %s\n\nReturn the corrected document.",
+		r.format.raw, output, strings.Join(problems, "\n- "))
+
+	var repaired strings.Builder
+	err := r.model.GenerateInference(r.ctx,
+		[]llm.Message{{Role: "user", Content: prompt}},
+		func(chunk string) error {
+			repaired.WriteString(chunk)
+			return nil
+		},
+		llmrouter.WithSystemPrompt(repairSystemPrompt),
+		llmrouter.WithTemperature(0),
+	)
+	return repaired.String(), err
+}
@@ -0,0 +1,206 @@
+// Package structured lets callers ask for an answer as JSON conforming to a schema. The
+// model is told the schema up front; its output is validated on the server and sent back
+// to the model for repair when it does not conform.
+package structured
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Schema is the subset of JSON Schema that answers are validated against: type,
+// properties, required, additionalProperties (as a boolean), items, enum, minItems,
+// maxItems, minLength and maxLength. Other keywords are accepted and ignored, so a
+// schema written for a full validator still works, if less strictly.
+type Schema struct {
+	Type                 schemaType         `json:"type,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+}
+
+// schemaType is either a single type name or a list of them.
+type schemaType []string
+
+func (t *schemaType) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaType{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return errors.New("type must be a string or a list of strings")
+	}
+	*t = list
+	return nil
+}
+
+func (t schemaType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+var schemaTypes = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+
+// ParseSchema reads a JSON schema and checks that the keywords it relies on are well formed.
+func ParseSchema(raw string) (*Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal([]byte(raw), &schema); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	if err := schema.check("$"); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+func (s *Schema) check(path string) error {
+	for _, t := range s.Type {
+		if !slices.Contains(schemaTypes, t) {
+			return fmt.Errorf("%s: unknown type %q", path, t)
+		}
+	}
+	for name, property := range s.Properties {
+		if property == nil {
+			return fmt.Errorf("%s.%s: empty schema", path, name)
+		}
+		if err := property.check(path + "." + name); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.check(path + "[]")
+	}
+	return nil
+}
+
+// Validate returns a description of every way value, as decoded by encoding/json,
+// fails to conform to the schema. Paths are written as $.field[index].
+func (s *Schema) Validate(value any) []string {
+	var problems []string
+	s.validate("$", value, &problems)
+	return problems
+}
+
+func (s *Schema) validate(path string, value any, problems *[]string) {
+	if len(s.Type) > 0 && !slices.ContainsFunc(s.Type, func(t string) bool { return hasType(value, t) }) {
+		*problems = append(*problems, fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(s.Type, " or "), typeOf(value)))
+		return
+	}
+
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(allowed any) bool { return equalJSON(allowed, value) }) {
+		*problems = append(*problems, fmt.Sprintf("%s: must be one of %s", path, mustJSON(s.Enum)))
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: missing required field %q", path, name))
+			}
+		}
+
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := s.Properties[name]; ok {
+				property.validate(path+"."+name, v[name], problems)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				*problems = append(*problems, fmt.Sprintf("%s: unexpected field %q", path, name))
+			}
+		}
+
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			*problems = append(*problems, fmt.Sprintf("%s: must have at least %d items", path, *s.MinItems))
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			*problems = append(*problems, fmt.Sprintf("%s: must have at most %d items", path, *s.MaxItems))
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, problems)
+			}
+		}
+
+	case string:
+		length := len([]rune(v))
+		if s.MinLength != nil && length < *s.MinLength {
+			*problems = append(*problems, fmt.Sprintf("%s: must be at least %d characters", path, *s.MinLength))
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			*problems = append(*problems, fmt.Sprintf("%s: must be at most %d characters", path, *s.MaxLength))
+		}
+	}
+}
+
+func hasType(value any, t string) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == float64(int64(n))
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return false
+}
+
+func typeOf(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func equalJSON(a, b any) bool {
+	return mustJSON(a) == mustJSON(b)
+}
+
+func mustJSON(value any) string {
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
@@ -0,0 +1,119 @@
+package structured
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SaiNageswarS/agent-boot/agentboot"
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/agent-boot/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat(map[string]string{})
+	require.NoError(t, err)
+	assert.Nil(t, format)
+
+	format, err = ParseFormat(map[string]string{FormatKey: "remedy"})
+	require.NoError(t, err)
+	assert.Equal(t, "remedy", format.Name)
+	assert.Contains(t, format.Instruction(), `"required":["remedy","potency","indications","sources"]`)
+
+	_, err = ParseFormat(map[string]string{FormatKey: "json_schema"})
+	assert.Error(t, err, "json_schema needs a schema")
+
+	_, err = ParseFormat(map[string]string{FormatKey: "json_schema", SchemaKey: `{"type": "tuple"}`})
+	assert.ErrorContains(t, err, `unknown type "tuple"`)
+
+	_, err = ParseFormat(map[string]string{FormatKey: "yaml"})
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	format, err := ParseFormat(map[string]string{FormatKey: "remedy"})
+	require.NoError(t, err)
+
+	valid, err := Extract("```json\n{\"remedy\": \"Aconite\", \"potency\": \"30C\", \"indications\": [\"sudden fear\"], \"sources\": [\"Aconite\"]}\n```")
+	require.NoError(t, err)
+	assert.Empty(t, format.Schema.Validate(valid))
+
+	invalid, err := Extract(`Here you go: {"remedy": "", "potency": 30, "indications": "fear", "dose": "daily"}`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`$: missing required field "sources"`,
+		`$: unexpected field "dose"`,
+		"$.indications: expected array, got string",
+		"$.potency: expected string, got number",
+		"$.remedy: must be at least 1 characters",
+	}, format.Schema.Validate(invalid))
+
+	_, err = Extract("Aconite 30C is indicated.")
+	assert.Error(t, err)
+}
+
+type repairingModel struct {
+	llm.LLMClient
+	replies []string
+	prompts []string
+}
+
+func (m *repairingModel) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	m.prompts = append(m.prompts, messages[len(messages)-1].Content)
+	reply := m.replies[0]
+	m.replies = m.replies[1:]
+	return callback(reply)
+}
+
+type recordingReporter struct {
+	events []*schema.AgentStreamChunk
+}
+
+func (r *recordingReporter) Send(event *schema.AgentStreamChunk) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestReporter(t *testing.T) {
+	format, err := ParseFormat(map[string]string{FormatKey: "remedy"})
+	require.NoError(t, err)
+
+	generated := `{"remedy": "Aconite", "potency": "30C", "indications": "sudden fear"}`
+	send := func(reporter *Reporter) {
+		require.NoError(t, reporter.Send(agentboot.NewAnswerChunk(&schema.AnswerChunk{Content: generated})))
+		require.NoError(t, reporter.Send(agentboot.NewStreamComplete(&schema.StreamComplete{Answer: generated})))
+	}
+
+	t.Run("Repairs", func(t *testing.T) {
+		inner := &recordingReporter{}
+		model := &repairingModel{replies: []string{
+			`{"remedy": "Aconite", "potency": "30C", "indications": ["sudden fear"]}`,
+			`{"remedy": "Aconite", "potency": "30C", "indications": ["sudden fear"], "sources": ["Aconite"]}`,
+		}}
+		send(NewReporter(t.Context(), inner, format, model, 2))
+
+		require.Len(t, model.prompts, 2)
+		assert.Contains(t, model.prompts[0], "$.indications: expected array, got string")
+
+		// the raw answer chunk is held back: only the validated answer and completion
+		require.Len(t, inner.events, 2)
+		complete := inner.events[1].GetComplete()
+		assert.Equal(t, "true", complete.Metadata["schemaValid"])
+		assert.Equal(t, "2", complete.Metadata["repairAttempts"])
+		assert.JSONEq(t, `{"remedy": "Aconite", "potency": "30C", "indications": ["sudden fear"], "sources": ["Aconite"]}`, complete.Answer)
+		assert.Equal(t, complete.Answer, inner.events[0].GetAnswer().Content)
+	})
+
+	t.Run("GivesUp", func(t *testing.T) {
+		inner := &recordingReporter{}
+		send(NewReporter(t.Context(), inner, format, &repairingModel{}, 0))
+
+		require.Len(t, inner.events, 3)
+		assert.Equal(t, invalidOutputCode, inner.events[0].GetError().ErrorCode)
+		complete := inner.events[2].GetComplete()
+		assert.Equal(t, "false", complete.Metadata["schemaValid"])
+		assert.Contains(t, complete.Metadata["schemaErrors"], `missing required field "sources"`)
+		assert.Equal(t, generated, complete.Answer)
+	})
+}
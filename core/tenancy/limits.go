@@ -0,0 +1,137 @@
+package tenancy
+
+import (
+	"context"
+	"sync"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
+	"github.com/ollama/ollama/api"
+)
+
+const (
+	defaultLLMConcurrency   = 4
+	defaultEmbedConcurrency = 8
+)
+
+// Limits caps how many LLM and embedder calls each tenant may have in flight. Provider
+// rate limits are shared by every tenant on a deployment, so without a per-tenant cap a
+// single tenant's spike could take all of them and starve the rest.
+type Limits struct {
+	llmSlots   int
+	embedSlots int
+
+	mu    sync.Mutex
+	pools map[string]*tenantPool
+}
+
+type tenantPool struct {
+	llm   chan struct{}
+	embed chan struct{}
+}
+
+func ProvideLimits(ccfg *appconfig.AppConfig) *Limits {
+	l := &Limits{
+		llmSlots:   ccfg.TenantLLMConcurrency,
+		embedSlots: ccfg.TenantEmbedConcurrency,
+		pools:      make(map[string]*tenantPool),
+	}
+	if l.llmSlots <= 0 {
+		l.llmSlots = defaultLLMConcurrency
+	}
+	if l.embedSlots <= 0 {
+		l.embedSlots = defaultEmbedConcurrency
+	}
+	return l
+}
+
+// LLM wraps client so its calls take one of the tenant's LLM slots.
+func (l *Limits) LLM(tenant string, client llm.LLMClient) llm.LLMClient {
+	return &limitedLLMClient{LLMClient: client, slots: l.pool(tenant).llm}
+}
+
+// Embedder wraps embedder so its calls take one of the tenant's embedding slots. A
+// batch of texts embedded together takes one slot.
+func (l *Limits) Embedder(tenant string, embedder embed.Embedder) embed.Embedder {
+	return &limitedEmbedder{embedder: embedder, slots: l.pool(tenant).embed}
+}
+
+func (l *Limits) pool(tenant string) *tenantPool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	p, ok := l.pools[tenant]
+	if !ok {
+		p = &tenantPool{
+			llm:   make(chan struct{}, l.llmSlots),
+			embed: make(chan struct{}, l.embedSlots),
+		}
+		l.pools[tenant] = p
+	}
+	return p
+}
+
+// acquire waits for a free slot, giving up when ctx is done.
+func acquire(ctx context.Context, slots chan struct{}) (release func(), err error) {
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type limitedLLMClient struct {
+	llm.LLMClient
+	slots chan struct{}
+}
+
+func (c *limitedLLMClient) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	release, err := acquire(ctx, c.slots)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return c.LLMClient.GenerateInference(ctx, messages, callback, opts...)
+}
+
+func (c *limitedLLMClient) GenerateInferenceWithTools(ctx context.Context, messages []llm.Message, contentCallback func(chunk string) error, toolCallback func(toolCalls []api.ToolCall) error, opts ...llm.LLMOption) error {
+	release, err := acquire(ctx, c.slots)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return c.LLMClient.GenerateInferenceWithTools(ctx, messages, contentCallback, toolCallback, opts...)
+}
+
+type limitedEmbedder struct {
+	embedder embed.Embedder
+	slots    chan struct{}
+}
+
+func (e *limitedEmbedder) GetEmbedding(ctx context.Context, text string, opts ...embed.EmbedOption) <-chan async.Result[[]float32] {
+	return async.Go(func() ([]float32, error) {
+		release, err := acquire(ctx, e.slots)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		return async.Await(e.embedder.GetEmbedding(ctx, text, opts...))
+	})
+}
+
+func (e *limitedEmbedder) GetEmbeddings(ctx context.Context, texts []string, opts ...embed.EmbedOption) ([][]float32, error) {
+	release, err := acquire(ctx, e.slots)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return embedding.EmbedBatch(ctx, e.embedder, texts, opts...)
+}
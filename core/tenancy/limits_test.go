@@ -0,0 +1,158 @@
+package tenancy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/go-api-boot/embed"
+	"github.com/SaiNageswarS/go-collection-boot/async"
+	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const waitFor = 50 * time.Millisecond
+
+func TestProvideLimitsDefaults(t *testing.T) {
+	l := ProvideLimits(&appconfig.AppConfig{})
+	assert.Equal(t, defaultLLMConcurrency, l.llmSlots)
+	assert.Equal(t, defaultEmbedConcurrency, l.embedSlots)
+
+	l = ProvideLimits(&appconfig.AppConfig{TenantLLMConcurrency: 1, TenantEmbedConcurrency: 3})
+	assert.Equal(t, 1, l.llmSlots)
+	assert.Equal(t, 3, l.embedSlots)
+}
+
+func TestLimitsCapBlocks(t *testing.T) {
+	l := ProvideLimits(&appconfig.AppConfig{TenantLLMConcurrency: 2})
+	inner := newBlockingLLM()
+	client := l.LLM("clinic", inner)
+
+	done := make(chan error, 3)
+	for range 3 {
+		go func() { done <- client.GenerateInference(t.Context(), nil, nil) }()
+	}
+
+	inner.awaitStarted(t)
+	inner.awaitStarted(t)
+	inner.assertNotStarted(t, "the third call waits for a slot")
+
+	inner.release <- struct{}{}
+	require.NoError(t, <-done)
+	inner.awaitStarted(t)
+
+	inner.release <- struct{}{}
+	inner.release <- struct{}{}
+	require.NoError(t, <-done)
+	require.NoError(t, <-done)
+	assert.Empty(t, l.pool("clinic").llm, "every slot is returned")
+}
+
+func TestLimitsCancelledWaitReleases(t *testing.T) {
+	l := ProvideLimits(&appconfig.AppConfig{TenantLLMConcurrency: 1})
+	inner := newBlockingLLM()
+	client := l.LLM("clinic", inner)
+
+	done := make(chan error, 1)
+	go func() { done <- client.GenerateInference(t.Context(), nil, nil) }()
+	inner.awaitStarted(t)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	waiting := make(chan error, 1)
+	go func() { waiting <- client.GenerateInference(ctx, nil, nil) }()
+	inner.assertNotStarted(t, "the second call waits for the slot")
+
+	cancel()
+	select {
+	case err := <-waiting:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("a cancelled call kept waiting for a slot")
+	}
+
+	inner.release <- struct{}{}
+	require.NoError(t, <-done)
+	assert.Empty(t, l.pool("clinic").llm, "the cancelled call took no slot")
+	inner.assertNotStarted(t, "the cancelled call never reached the model")
+}
+
+func TestLimitsTenantsAreIsolated(t *testing.T) {
+	l := ProvideLimits(&appconfig.AppConfig{TenantLLMConcurrency: 1, TenantEmbedConcurrency: 1})
+	inner := newBlockingLLM()
+
+	done := make(chan error, 2)
+	go func() { done <- l.LLM("clinic", inner).GenerateInference(t.Context(), nil, nil) }()
+	inner.awaitStarted(t)
+
+	go func() { done <- l.LLM("hospital", inner).GenerateInference(t.Context(), nil, nil) }()
+	inner.awaitStarted(t)
+
+	inner.release <- struct{}{}
+	inner.release <- struct{}{}
+	require.NoError(t, <-done)
+	require.NoError(t, <-done)
+
+	// The embedding slots are counted apart from the LLM ones.
+	l.pool("clinic").llm <- struct{}{}
+	vectors, err := embedding.EmbedBatch(t.Context(), l.Embedder("clinic", &stubEmbedder{}), []string{"aconite", "belladonna"})
+	require.NoError(t, err)
+	assert.Len(t, vectors, 2)
+}
+
+func TestLimitedEmbedderCancelledWait(t *testing.T) {
+	l := ProvideLimits(&appconfig.AppConfig{TenantEmbedConcurrency: 1})
+	l.pool("clinic").embed <- struct{}{}
+
+	ctx, cancel := context.WithTimeout(t.Context(), waitFor)
+	defer cancel()
+	_, err := async.Await(l.Embedder("clinic", &stubEmbedder{}).GetEmbedding(ctx, "aconite"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// blockingLLM holds each call until it is released.
+type blockingLLM struct {
+	llm.LLMClient
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingLLM() *blockingLLM {
+	return &blockingLLM{started: make(chan struct{}, 8), release: make(chan struct{})}
+}
+
+func (c *blockingLLM) GenerateInference(ctx context.Context, _ []llm.Message, _ func(string) error, _ ...llm.LLMOption) error {
+	c.started <- struct{}{}
+	select {
+	case <-c.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *blockingLLM) awaitStarted(t *testing.T) {
+	t.Helper()
+	select {
+	case <-c.started:
+	case <-time.After(time.Second):
+		t.Fatal("a call did not start")
+	}
+}
+
+func (c *blockingLLM) assertNotStarted(t *testing.T, msg string) {
+	t.Helper()
+	select {
+	case <-c.started:
+		t.Fatal(msg)
+	case <-time.After(waitFor):
+	}
+}
+
+type stubEmbedder struct{}
+
+func (e *stubEmbedder) GetEmbedding(ctx context.Context, text string, opts ...embed.EmbedOption) <-chan async.Result[[]float32] {
+	return async.Go(func() ([]float32, error) { return []float32{1}, nil })
+}
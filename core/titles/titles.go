@@ -0,0 +1,94 @@
+// Package titles names conversations for the history list from their first question
+// and answer.
+package titles
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/SaiNageswarS/medicine-rag/core/llmrouter"
+)
+
+const (
+	// MaxLength bounds a title, in characters.
+	MaxLength = 60
+
+	// the prompt holds at most this many characters of the question and of the answer
+	maxQuestionLength = 500
+	maxAnswerLength   = 1000
+
+	generateTimeout = 15 * time.Second
+)
+
+const systemPrompt = "You name conversations between a physician and a homeopathy knowledge assistant for a history list. " +
+	"Reply with a title of at most six words in the language of the question, naming the remedies or symptoms discussed. " +
+	"No quotes, no trailing punctuation, nothing else."
+
+// Generate asks model for a title for a conversation that opened with question and
+// answer. It falls back to the question itself when the model's reply is unusable.
+func Generate(ctx context.Context, model llm.LLMClient, question, answer string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, generateTimeout)
+	defer cancel()
+
+	prompt := "Question:\n" + truncate(question, maxQuestionLength) +
+		"\n\nAnswer:\n" + truncate(answer, maxAnswerLength)
+
+	var reply strings.Builder
+	err := model.GenerateInference(ctx,
+		[]llm.Message{{Role: "user", Content: prompt}},
+		func(chunk string) error {
+			reply.WriteString(chunk)
+			return nil
+		},
+		llmrouter.WithSystemPrompt(systemPrompt),
+		llmrouter.WithTemperature(0.2),
+		llmrouter.WithMaxTokens(32),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if title := Clean(reply.String()); title != "" {
+		return title, nil
+	}
+	return FromQuestion(question), nil
+}
+
+var titlePrefix = regexp.MustCompile(`(?i)^\s*(?:title\s*:\s*|#+\s*)`)
+
+// Clean keeps the first line of a model's reply, without a "Title:" label, markdown,
+// quotes or trailing punctuation, cut to MaxLength.
+func Clean(reply string) string {
+	for _, line := range strings.Split(reply, "\n") {
+		line = titlePrefix.ReplaceAllString(strings.TrimSpace(line), "")
+		line = strings.Trim(line, "\"'“”‘’*_` ")
+		line = strings.TrimRight(line, ".!?。:;, ")
+		if line != "" {
+			return truncate(strings.Join(strings.Fields(line), " "), MaxLength)
+		}
+	}
+	return ""
+}
+
+// FromQuestion titles a conversation with its first question, for conversations the
+// model did not name.
+func FromQuestion(question string) string {
+	return truncate(strings.Join(strings.Fields(question), " "), MaxLength)
+}
+
+// truncate cuts s to length characters at a word boundary where there is one.
+func truncate(s string, length int) string {
+	if utf8.RuneCountInString(s) <= length {
+		return s
+	}
+	runes := []rune(s)[:length]
+	cut := string(runes)
+	if i := strings.LastIndex(cut, " "); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,;:") + "…"
+}
@@ -0,0 +1,46 @@
+package titles
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/SaiNageswarS/agent-boot/llm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type namingModel struct {
+	llm.LLMClient
+	reply  string
+	prompt string
+}
+
+func (m *namingModel) GenerateInference(ctx context.Context, messages []llm.Message, callback func(chunk string) error, opts ...llm.LLMOption) error {
+	m.prompt = messages[len(messages)-1].Content
+	return callback(m.reply)
+}
+
+func TestClean(t *testing.T) {
+	assert.Equal(t, "Arnica for bruising", Clean(`Title: "Arnica for bruising."`))
+	assert.Equal(t, "Remedies for night fears", Clean("\n## **Remedies for night fears**\nExtra line"))
+	assert.Empty(t, Clean(" \n\"\" "))
+
+	long := Clean(strings.Repeat("Arsenicum album restlessness ", 10))
+	assert.LessOrEqual(t, utf8.RuneCountInString(long), MaxLength+1)
+	assert.True(t, strings.HasSuffix(long, "…"))
+}
+
+func TestGenerate(t *testing.T) {
+	model := &namingModel{reply: "Arnica for bruising"}
+	title, err := Generate(t.Context(), model, "What helps bruising after a fall?", "Arnica 30C is indicated.")
+	require.NoError(t, err)
+	assert.Equal(t, "Arnica for bruising", title)
+	assert.Contains(t, model.prompt, "What helps bruising after a fall?")
+
+	model.reply = "..."
+	title, err = Generate(t.Context(), model, "What helps   bruising after a fall?", "Arnica.")
+	require.NoError(t, err)
+	assert.Equal(t, "What helps bruising after a fall?", title, "an unusable reply falls back to the question")
+}
@@ -11,14 +11,12 @@ import (
 	"github.com/SaiNageswarS/go-collection-boot/async"
 	"github.com/SaiNageswarS/go-collection-boot/linq"
 	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/entities"
+	"github.com/SaiNageswarS/medicine-rag/core/ingest"
 	"github.com/SaiNageswarS/medicine-rag/core/prompts"
-	"github.com/yuin/goldmark"
-	"github.com/yuin/goldmark/ast"
-	"github.com/yuin/goldmark/text"
 	"go.uber.org/zap"
 )
 
-const minSectionBytes = 4000    // Minimum bytes for a section to be considered valid
 const maxTitleInputBytes = 2500 // Maximum bytes for title generation input
 
 // ChunkMarkdown processes a markdown file, chunks it into sections, and uploads the chunks to Azure Blob Storage.
@@ -30,8 +28,11 @@ func (s *Activities) ChunkMarkdown(ctx context.Context, tenant, sourceUri, markd
 		return nil, errors.New("failed to download markdown file: " + err.Error())
 	}
 
+	// front matter names the source book, and would otherwise parse as a heading
+	source, md := ingest.ParseFrontMatter(md)
+
 	// parse sections in markdown
-	sections, err := parseMarkdownSections(ctx, md, minSectionBytes)
+	sections, err := ingest.ParseMarkdownSections(ctx, md, ingest.MinSectionBytes)
 	if err != nil {
 		return nil, err
 	}
@@ -45,31 +46,38 @@ func (s *Activities) ChunkMarkdown(ctx context.Context, tenant, sourceUri, markd
 		linq.FromSlice(ctx, sections),
 
 		// TRANSFORM each markdownSection → db.ChunkModel (with LLM call)
-		linq.Select(func(sec markdownSection) db.ChunkModel {
-			secHash, _ := odm.HashedKey(sec.body)
+		linq.Select(func(sec ingest.MarkdownSection) db.ChunkModel {
+			secHash, _ := odm.HashedKey(sec.Body)
 
 			// generate a concise title – any error handled below
-			titleBodyInputLen := min(len(sec.body), maxTitleInputBytes)
+			titleBodyInputLen := min(len(sec.Body), maxTitleInputBytes)
 
-			logger.Info("Generating section title", zap.String("sectionPath", strings.Join(sec.path, " | ")))
+			logger.Info("Generating section title", zap.String("sectionPath", strings.Join(sec.Path, " | ")))
 			title, _ := async.Await(prompts.GenerateSectionTitle(
 				ctx, sourceUri,
-				sec.path[len(sec.path)-1], sec.body[:titleBodyInputLen], s.ccfg.TitleGenModel,
+				sec.Path[len(sec.Path)-1], sec.Body[:titleBodyInputLen], s.ccfg.TitleGenModel,
 			))
 			if title == "" || len(title) > 100 {
-				title = sec.path[len(sec.path)-1]
+				title = sec.Path[len(sec.Path)-1]
 			} else {
-				logger.Info("Generated section title", zap.String("sectionPath", strings.Join(sec.path, " | ")), zap.String("title", title))
+				logger.Info("Generated section title", zap.String("sectionPath", strings.Join(sec.Path, " | ")), zap.String("title", title))
 			}
 
 			return db.ChunkModel{
 				ChunkID:      secHash,
-				SectionPath:  strings.Join(sec.path, " | "),
+				SectionPath:  strings.Join(sec.Path, " | "),
 				SectionIndex: len(allChunks) + 1, // running index
 				SectionID:    secHash,
 				Title:        title,
 				SourceURI:    sourceUri,
-				Sentences:    []string{sec.body},
+				Sentences:    []string{sec.Body},
+				Entities:     entities.ChunkTags(append([]string{title}, sec.Path...), sec.Body),
+				Chapter:      sec.Chapter(),
+				Section:      sec.Section(),
+
+				Book:            source.Book,
+				Author:          source.Author,
+				PublicationYear: source.Year,
 			}
 		}),
 
@@ -89,97 +97,3 @@ func (s *Activities) ChunkMarkdown(ctx context.Context, tenant, sourceUri, markd
 
 	return sectionChunkPaths, nil
 }
-
-func parseMarkdownSections(ctx context.Context, md []byte, minBytes int) ([]markdownSection, error) {
-	reader := text.NewReader(md)
-	root := goldmark.DefaultParser().Parse(reader)
-
-	type head struct {
-		start   int // byte offset of heading line start
-		lineEnd int // byte offset just *after* the end-of-line
-		level   int
-		title   string
-	}
-	var heads []head
-
-	// ── collect all headings with byte offsets ────────────────────────────
-	ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
-		if !entering {
-			return ast.WalkContinue, nil
-		}
-		if h, ok := n.(*ast.Heading); ok {
-			seg := h.Lines().At(0) // first (and only) line
-			// seg.Start .. seg.Stop covers the characters of the line
-			lineEnd := seg.Stop
-			// skip trailing CR/LF so body starts at the next content byte
-			for lineEnd < len(md) && (md[lineEnd] == '\n' || md[lineEnd] == '\r') {
-				lineEnd++
-			}
-			heads = append(heads, head{
-				start:   seg.Start,
-				lineEnd: lineEnd,
-				level:   h.Level,
-				title:   strings.TrimSpace(string(h.Text(md))),
-			})
-		}
-		return ast.WalkContinue, nil
-	})
-	if len(heads) == 0 {
-		return nil, errors.New("no headings found")
-	}
-
-	// ── slice raw markdown into sections (body = after heading) ───────────
-	var sections []markdownSection
-	var path []string
-	for i, h := range heads {
-		// update hierarchy
-		if len(path) >= h.level {
-			path = path[:h.level-1]
-		}
-		path = append(path, h.title)
-
-		start := h.lineEnd // <─ body starts *after* heading
-		end := len(md)
-		if i+1 < len(heads) {
-			end = heads[i+1].start
-		}
-
-		sections = append(sections, markdownSection{
-			path: append([]string(nil), path...), // copy
-			body: string(md[start:end]),
-		})
-	}
-
-	// ── merge small chunks ────────────────────────────────────────────────
-	if minBytes <= 0 {
-		return sections, nil
-	}
-	var merged []markdownSection
-	for _, s := range sections {
-		if len(s.body) < minBytes && len(merged) > 0 {
-			prev := &merged[len(merged)-1]
-			prev.body += "\n\n" + s.body
-			// Append the current section's path to the previous section's path
-			prev.path = append(prev.path, s.path...)
-			combinedPath, err := linq.Pipe2(
-				linq.FromSlice(ctx, prev.path),
-				linq.Distinct(func(a string) string { return a }), // Ensure unique paths
-				linq.ToSlice[string](),
-			)
-
-			if err != nil {
-				logger.Error("Failed to combine section paths", zap.Error(err))
-			} else {
-				prev.path = combinedPath
-			}
-		} else {
-			merged = append(merged, s)
-		}
-	}
-	return merged, nil
-}
-
-type markdownSection struct {
-	path []string // section path
-	body string   // section body
-}
@@ -3,15 +3,16 @@ package activities
 import (
 	"context"
 	"errors"
-	"strings"
 
-	"github.com/SaiNageswarS/go-api-boot/embed"
 	"github.com/SaiNageswarS/go-api-boot/logger"
 	"github.com/SaiNageswarS/go-api-boot/odm"
 	"github.com/SaiNageswarS/go-collection-boot/async"
 	"github.com/SaiNageswarS/go-collection-boot/linq"
 	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
+	"github.com/SaiNageswarS/medicine-rag/core/ingest"
 	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.temporal.io/sdk/temporal"
 	"go.uber.org/zap"
 )
 
@@ -80,6 +81,24 @@ func (s *Activities) GetChunksWithMissingEmbeddings(ctx context.Context, tenant,
 }
 
 func (s *Activities) EmbedChunks(ctx context.Context, tenant string, chunkIds []string) error {
+	name, err := s.tenantEmbedderName(ctx, tenant)
+	if err != nil {
+		return errors.New("failed to load tenant config: " + err.Error())
+	}
+	spec, embedder, err := s.embedders.Resolve(name)
+	if err != nil {
+		return errors.New("failed to resolve tenant embedder: " + err.Error())
+	}
+	embedder = s.limits.Embedder(tenant, embedder)
+
+	if err := ingest.CheckStoredVectors(ctx, s.mongo, tenant, spec); err != nil {
+		if errors.Is(err, ingest.ErrEmbeddingModelMismatch) {
+			return temporal.NewNonRetryableApplicationError(
+				"tenant has embeddings of another model than "+spec.Model+"; delete them before re-embedding", "EmbeddingModelMismatch", nil)
+		}
+		return err
+	}
+
 	// Download the chunk data
 	for idx, chunkId := range chunkIds {
 		chunkModel, err := async.Await(odm.CollectionOf[db.ChunkModel](s.mongo, tenant).FindOneByID(ctx, chunkId))
@@ -88,16 +107,18 @@ func (s *Activities) EmbedChunks(ctx context.Context, tenant string, chunkIds []
 		}
 
 		// Embed the chunk using the LLM client
-		embeddingText := chunkModel.SectionPath + "\n" + strings.Join(chunkModel.Sentences, "\n")
+		embeddingText := ingest.EmbeddingText(*chunkModel)
 
-		embeddings, err := async.Await(s.embedder.GetEmbedding(ctx, embeddingText, embed.WithTask("retrieval.passage")))
+		embeddings, err := async.Await(embedder.GetEmbedding(ctx, embeddingText, embedding.WithTask("retrieval.passage")))
 		if err != nil {
 			return errors.New("failed to embed chunk: " + err.Error())
 		}
 
 		chunkAnn := db.ChunkAnnModel{
-			ChunkID:   chunkModel.ChunkID,
-			Embedding: bson.NewVector(embeddings),
+			ChunkID:    chunkModel.ChunkID,
+			Embedding:  bson.NewVector(embeddings),
+			Model:      spec.Model,
+			Dimensions: spec.Dimensions,
 		}
 
 		_, err = async.Await(odm.CollectionOf[db.ChunkAnnModel](s.mongo, tenant).Save(ctx, chunkAnn))
@@ -112,3 +133,9 @@ func (s *Activities) EmbedChunks(ctx context.Context, tenant string, chunkIds []
 
 	return nil
 }
+
+// tenantEmbedderName reads the embedder chosen in the tenant's config, empty for the
+// default one.
+func (s *Activities) tenantEmbedderName(ctx context.Context, tenant string) (string, error) {
+	return ingest.TenantEmbedderName(ctx, s.mongo, tenant)
+}
@@ -9,8 +9,18 @@ import (
 )
 
 func (s *Activities) InitTenant(ctx context.Context, tenant string) error {
-	// Initialize DB.
-	if err := db.InitSearchCoreDB(ctx, s.mongo, tenant); err != nil {
+	// Initialize DB, with a vector index sized for the tenant's embedding model.
+	name, err := s.tenantEmbedderName(ctx, tenant)
+	if err != nil {
+		logger.Error("Failed to load tenant config", zap.String("tenant", tenant), zap.Error(err))
+		return err
+	}
+	spec, err := s.embedders.Spec(name)
+	if err != nil {
+		logger.Error("Failed to resolve tenant embedder", zap.String("tenant", tenant), zap.Error(err))
+		return err
+	}
+	if err := db.InitSearchCoreDB(ctx, s.mongo, tenant, spec.Dimensions); err != nil {
 		logger.Error("Failed to initialize search core DB", zap.String("tenant", tenant), zap.Error(err))
 		return err
 	}
@@ -5,13 +5,15 @@ import (
 	"encoding/json"
 	"errors"
 
-	"github.com/SaiNageswarS/go-api-boot/odm"
-	"github.com/SaiNageswarS/go-collection-boot/async"
 	"github.com/SaiNageswarS/medicine-rag/core/db"
+	"github.com/SaiNageswarS/medicine-rag/core/ingest"
 )
 
-func (s *Activities) SaveChunks(ctx context.Context, tenant string, chunkPaths []string) error {
+// SaveChunks saves the chunks of one source document as a new corpus version; see
+// ingest.Publish.
+func (s *Activities) SaveChunks(ctx context.Context, tenant, sourceUri string, chunkPaths []string) error {
 	// Download the chunk data
+	chunks := make([]db.ChunkModel, 0, len(chunkPaths))
 	for _, chunkPath := range chunkPaths {
 		chunkData, err := getBytes(s.az.DownloadFile(ctx, tenant, chunkPath))
 		if err != nil {
@@ -23,12 +25,8 @@ func (s *Activities) SaveChunks(ctx context.Context, tenant string, chunkPaths [
 		if err != nil {
 			return errors.New("failed to unmarshal chunk data: " + err.Error())
 		}
-
-		_, err = async.Await(odm.CollectionOf[db.ChunkModel](s.mongo, tenant).Save(ctx, chunkModel))
-		if err != nil {
-			return errors.New("failed to save chunk to database: " + err.Error())
-		}
+		chunks = append(chunks, chunkModel)
 	}
 
-	return nil
+	return ingest.Publish(ctx, s.mongo, tenant, sourceUri, chunks)
 }
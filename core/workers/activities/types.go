@@ -7,24 +7,27 @@ import (
 	"os"
 
 	"github.com/SaiNageswarS/go-api-boot/cloud"
-	"github.com/SaiNageswarS/go-api-boot/embed"
 	"github.com/SaiNageswarS/go-api-boot/odm"
 	"github.com/SaiNageswarS/medicine-rag/core/appconfig"
+	"github.com/SaiNageswarS/medicine-rag/core/embedding"
+	"github.com/SaiNageswarS/medicine-rag/core/tenancy"
 )
 
 type Activities struct {
-	ccfg     *appconfig.AppConfig
-	az       cloud.Cloud
-	embedder embed.Embedder
-	mongo    odm.MongoClient
+	ccfg      *appconfig.AppConfig
+	az        cloud.Cloud
+	embedders *embedding.Registry
+	mongo     odm.MongoClient
+	limits    *tenancy.Limits
 }
 
-func ProvideActivities(ccfg *appconfig.AppConfig, az cloud.Cloud, embedder embed.Embedder, mongo odm.MongoClient) *Activities {
+func ProvideActivities(ccfg *appconfig.AppConfig, az cloud.Cloud, embedders *embedding.Registry, mongo odm.MongoClient, limits *tenancy.Limits) *Activities {
 	return &Activities{
-		ccfg:     ccfg,
-		az:       az,
-		embedder: embedder,
-		mongo:    mongo,
+		ccfg:      ccfg,
+		az:        az,
+		embedders: embedders,
+		mongo:     mongo,
+		limits:    limits,
 	}
 }
 
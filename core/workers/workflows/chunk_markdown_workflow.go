@@ -40,7 +40,7 @@ func ChunkMarkdownWorkflow(ctx workflow.Context, input ChunkMarkdownWorkflowInpu
 	}
 
 	// Save chunks
-	err = workflow.ExecuteActivity(ctx, (*activities.Activities).SaveChunks, input.Tenant, windowChunkUrls).Get(ctx, nil)
+	err = workflow.ExecuteActivity(ctx, (*activities.Activities).SaveChunks, input.Tenant, input.SourceUri, windowChunkUrls).Get(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -1,4 +1,4 @@
-from dataclasses import dataclass
+from dataclasses import dataclass, fields
 from typing import List, Dict
 from typing import Optional
 import orjson
@@ -18,6 +18,10 @@ class Chunk:
     windowIndex: int    # 0-based window order within section
     tags: Optional[List[str]] = None  # Optional tags for the chunk
     abbrevations: Optional[Dict[str, str]] = None  # Optional abbreviations mapping
+    book: Optional[str] = None  # Source book, from the markdown front matter
+    author: Optional[str] = None  # Author of the source book
+    publicationYear: Optional[int] = None  # Year the source book was published
+    paragraphs: Optional[List[int]] = None  # Paragraph of each sentence within the section
 
     def to_json_bytes(self) -> bytes:
         return orjson.dumps(
@@ -40,4 +44,6 @@ def parse_section_chunk_file(file_path: str) -> Chunk:
 
     with open(file_path, "r", encoding="utf-8") as f:
         section_dict = json.load(f)
-        return Chunk(**section_dict)
+        # fields the core service adds that windowing doesn't need are dropped
+        known = {field.name for field in fields(Chunk)}
+        return Chunk(**{k: v for k, v in section_dict.items() if k in known})
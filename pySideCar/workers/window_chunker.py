@@ -1,5 +1,6 @@
 import logging
 import gc
+import re
 from collections.abc import Iterator
 
 import spacy
@@ -46,7 +47,7 @@ class WindowChunker:
             len(section_chunk.sentences[0])
         )
         
-        sentences = self._split_sentences(section_chunk.sentences[0])
+        sentences, paragraphs = self._split_paragraph_sentences(section_chunk.sentences[0])
         logger.info(
             f"Found {len(sentences)} sentences in section chunk: {section_chunk.chunkId}."
         )
@@ -72,6 +73,7 @@ class WindowChunker:
                 end_sent = start_sent + 1
 
             window_sentences = sentences[start_sent:end_sent]
+            window_paragraphs = paragraphs[start_sent:end_sent]
 
             # Create a new Chunk object for the window
             yield Chunk(
@@ -81,10 +83,14 @@ class WindowChunker:
                 title=section_chunk.title,
                 sourceUri=section_chunk.sourceUri,
                 sentences=window_sentences,
+                paragraphs=window_paragraphs,
                 sectionId=section_chunk.sectionId,
                 windowIndex=w_idx,
                 prevChunkId="",
                 nextChunkId="",
+                book=section_chunk.book,
+                author=section_chunk.author,
+                publicationYear=section_chunk.publicationYear,
             )
 
             w_idx += 1
@@ -117,6 +123,29 @@ class WindowChunker:
         """
         return len(self.encoding.encode(text)) if text else 0
 
+    def _split_paragraph_sentences(self, text: str) -> tuple[list[str], list[int]]:
+        """
+        Splits a text into sentences, numbering the paragraph each one is in.
+        Paragraphs are separated by blank lines, and no sentence spans two.
+
+        Args:
+            text (str): The text to split into sentences.
+
+        Returns:
+            tuple[list[str], list[int]]: The sentences, and the 0-based paragraph
+            of each.
+        """
+        sentences, paragraphs = [], []
+        paragraph = 0
+        for block in re.split(r"\n\s*\n", text):
+            block_sentences = [s for s in self._split_sentences(block) if s]
+            if not block_sentences:
+                continue
+            sentences.extend(block_sentences)
+            paragraphs.extend([paragraph] * len(block_sentences))
+            paragraph += 1
+        return sentences, paragraphs
+
     def _split_sentences(self, text: str) -> list[str]:
         """
         Splits a text into sentences using the spaCy sentencizer.
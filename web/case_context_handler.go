@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/status"
+)
+
+type caseContextJSON struct {
+	AgeYears        int32    `json:"ageYears"`
+	Sex             string   `json:"sex"`
+	ChiefComplaints []string `json:"chiefComplaints"`
+	History         string   `json:"history"`
+	Medications     []string `json:"medications"`
+	UpdatedOn       int64    `json:"updatedOn"`
+}
+
+// SessionContextHandler reads (GET) and replaces (PUT) the case details attached to a
+// session. A PUT with no details clears them.
+func (h *PageHandler) SessionContextHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "PUT" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.isAuthenticated(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionId := r.PathValue("id")
+	if sessionId == "" {
+		http.Error(w, "Session id is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(h.authContext(r), 10*time.Second)
+	defer cancel()
+
+	var (
+		caseContext *pb.CaseContext
+		err         error
+	)
+	if r.Method == "PUT" {
+		var reqData caseContextJSON
+		if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		caseContext, err = h.conversationClient.SetSessionContext(ctx, &pb.SetSessionContextRequest{
+			SessionId: sessionId,
+			CaseContext: &pb.CaseContext{
+				AgeYears:        reqData.AgeYears,
+				Sex:             reqData.Sex,
+				ChiefComplaints: reqData.ChiefComplaints,
+				History:         reqData.History,
+				Medications:     reqData.Medications,
+			},
+		})
+	} else {
+		caseContext, err = h.conversationClient.GetSessionContext(ctx, &pb.GetSessionContextRequest{SessionId: sessionId})
+	}
+	if err != nil {
+		logger.Error("Failed to access case context", zap.String("sessionId", sessionId), zap.Error(err))
+		http.Error(w, status.Convert(err).Message(), httpStatusFromGrpc(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(caseContextJSON{
+		AgeYears:        caseContext.AgeYears,
+		Sex:             caseContext.Sex,
+		ChiefComplaints: caseContext.ChiefComplaints,
+		History:         caseContext.History,
+		Medications:     caseContext.Medications,
+		UpdatedOn:       caseContext.UpdatedOn,
+	})
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CorpusEventsHandler is an EventSource endpoint that tells a chat client when newly
+// indexed documents become searchable in its tenant's library. Each event carries the
+// corpus version as its SSE id, so a reconnecting EventSource resumes from Last-Event-ID
+// without missing updates.
+func (h *PageHandler) CorpusEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.isAuthenticated(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	since, _ := strconv.ParseInt(r.Header.Get("Last-Event-ID"), 10, 64)
+
+	ctx := h.authContext(r)
+	stream, err := h.corpusClient.WatchCorpus(ctx, &pb.WatchCorpusRequest{SinceVersion: since})
+	if err != nil {
+		logger.Error("Failed to watch corpus", zap.Error(err))
+		http.Error(w, status.Convert(err).Message(), httpStatusFromGrpc(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable Nginx buffering
+	flusher.Flush()
+
+	received := make(chan *pb.CorpusUpdate)
+	recvErr := make(chan error, 1)
+	go func() {
+		defer close(received)
+		for {
+			update, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case received <- update:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			h.extendSSEWriteDeadline(w)
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case update, ok := <-received:
+			if !ok {
+				// The client reconnects on its own unless told that notifications are off.
+				if err := <-recvErr; status.Code(err) == codes.FailedPrecondition {
+					h.sendCorpusEvent(w, 0, map[string]interface{}{"type": "disabled"})
+					flusher.Flush()
+				}
+				return
+			}
+
+			event := map[string]interface{}{"type": "connected"}
+			if update.Message != "" {
+				event = map[string]interface{}{
+					"type":              "corpus",
+					"message":           update.Message,
+					"newSourceUris":     update.NewSourceUris,
+					"updatedSourceUris": update.UpdatedSourceUris,
+				}
+			}
+			h.sendCorpusEvent(w, update.Version, event)
+			flusher.Flush()
+		}
+	}
+}
+
+func (h *PageHandler) sendCorpusEvent(w http.ResponseWriter, version int64, data interface{}) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		logger.Error("Failed to marshal SSE data", zap.Error(err))
+		return
+	}
+
+	h.extendSSEWriteDeadline(w)
+	if version > 0 {
+		fmt.Fprintf(w, "id: %d\n", version)
+	}
+	fmt.Fprintf(w, "data: %s\n\n", jsonData)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type apiKeyRow struct {
+	*pb.ApiKey
+	CreatedOn  string
+	LastUsedOn string
+	RevokedOn  string
+}
+
+type apiKeyScopeOption struct {
+	*pb.ApiKeyScope
+	Checked bool
+}
+
+// DeveloperSettingsHandler lets tenant admins manage the tenant's API keys. Creating or
+// rotating a key renders the page with the new key, the only time it is shown.
+func (h *PageHandler) DeveloperSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.isAuthenticated(r) {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(h.authContext(r), 10*time.Second)
+	defer cancel()
+
+	var (
+		issued  *pb.IssuedApiKey
+		formErr string
+		form    = &pb.CreateApiKeyRequest{}
+	)
+	if r.Method == "POST" {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Invalid form", http.StatusBadRequest)
+			return
+		}
+
+		var err error
+		keyId := r.PostForm.Get("keyId")
+		switch r.PostForm.Get("action") {
+		case "create":
+			form.Name = r.PostForm.Get("name")
+			form.Scopes = r.PostForm["scope"]
+			perMinute, _ := strconv.Atoi(r.PostForm.Get("requestsPerMinute"))
+			form.RequestsPerMinute = int32(perMinute)
+			issued, err = h.apiKeysClient.CreateApiKey(ctx, form)
+		case "rotate":
+			issued, err = h.apiKeysClient.RotateApiKey(ctx, &pb.RotateApiKeyRequest{KeyId: keyId})
+		case "revoke":
+			if _, err = h.apiKeysClient.RevokeApiKey(ctx, &pb.RevokeApiKeyRequest{KeyId: keyId}); err == nil {
+				http.Redirect(w, r, "/settings/developer", http.StatusSeeOther)
+				return
+			}
+		default:
+			http.Error(w, "Unknown action", http.StatusBadRequest)
+			return
+		}
+
+		switch status.Code(err) {
+		case codes.OK:
+			form = &pb.CreateApiKeyRequest{}
+		case codes.InvalidArgument, codes.FailedPrecondition, codes.NotFound:
+			formErr = status.Convert(err).Message()
+		default:
+			logger.Error("Failed to change API key", zap.String("action", r.PostForm.Get("action")), zap.Error(err))
+			http.Error(w, status.Convert(err).Message(), httpStatusFromGrpc(err))
+			return
+		}
+	}
+
+	keys, err := h.apiKeysClient.ListApiKeys(ctx, &pb.ListApiKeysRequest{})
+	if err != nil {
+		logger.Error("Failed to list API keys", zap.Error(err))
+		http.Error(w, status.Convert(err).Message(), httpStatusFromGrpc(err))
+		return
+	}
+
+	data := struct {
+		Keys                 []apiKeyRow
+		Scopes               []apiKeyScopeOption
+		MaxRequestsPerMinute int32
+		Form                 *pb.CreateApiKeyRequest
+		Issued               *pb.IssuedApiKey
+		Error                string
+	}{
+		MaxRequestsPerMinute: keys.MaxRequestsPerMinute,
+		Form:                 form,
+		Issued:               issued,
+		Error:                formErr,
+	}
+	for _, key := range keys.Keys {
+		row := apiKeyRow{ApiKey: key, CreatedOn: formatDay(key.CreatedOn), LastUsedOn: "Never"}
+		if key.LastUsedOn > 0 {
+			row.LastUsedOn = formatDay(key.LastUsedOn)
+		}
+		if key.RevokedOn > 0 {
+			row.RevokedOn = formatDay(key.RevokedOn)
+		}
+		data.Keys = append(data.Keys, row)
+	}
+	for _, scope := range keys.AvailableScopes {
+		data.Scopes = append(data.Scopes, apiKeyScopeOption{ApiKeyScope: scope, Checked: slices.Contains(form.Scopes, scope.Name)})
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := h.templates["developer"].Execute(w, data); err != nil {
+		logger.Error("Failed to execute developer template", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func formatDay(unix int64) string {
+	return localeFor("").Date(time.Unix(unix, 0).UTC())
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/status"
+)
+
+// FeedbackHandler records a thumbs up/down rating and optional comment for an answer.
+func (h *PageHandler) FeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.isAuthenticated(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var reqData struct {
+		SessionId string `json:"sessionId"`
+		MessageId string `json:"messageId"`
+		Rating    string `json:"rating"`
+		Comment   string `json:"comment"`
+		Question  string `json:"question"`
+		Answer    string `json:"answer"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var rating pb.FeedbackRating
+	switch reqData.Rating {
+	case "up":
+		rating = pb.FeedbackRating_THUMBS_UP
+	case "down":
+		rating = pb.FeedbackRating_THUMBS_DOWN
+	default:
+		http.Error(w, "Rating must be up or down", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(h.authContext(r), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.feedbackClient.SubmitFeedback(ctx, &pb.SubmitFeedbackRequest{
+		SessionId: reqData.SessionId,
+		MessageId: reqData.MessageId,
+		Rating:    rating,
+		Comment:   reqData.Comment,
+		Question:  reqData.Question,
+		Answer:    reqData.Answer,
+	})
+	if err != nil {
+		logger.Error("Failed to submit feedback", zap.String("sessionId", reqData.SessionId), zap.Error(err))
+		http.Error(w, status.Convert(err).Message(), httpStatusFromGrpc(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"feedbackId": resp.FeedbackId,
+	})
+}
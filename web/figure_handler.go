@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/status"
+)
+
+// FigureHandler serves the image of a figure search results refer to, for the signed-in
+// user; see Corpus/GetFigure.
+func (h *PageHandler) FigureHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.isAuthenticated(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(h.authContext(r), 15*time.Second)
+	defer cancel()
+
+	figure, err := h.corpusClient.GetFigure(ctx, &pb.GetFigureRequest{FigureId: r.PathValue("id")})
+	if err != nil {
+		logger.Error("Failed to get figure", zap.String("figureId", r.PathValue("id")), zap.Error(err))
+		http.Error(w, status.Convert(err).Message(), httpStatusFromGrpc(err))
+		return
+	}
+	if len(figure.Image) == 0 {
+		http.Error(w, "Figure has no image", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	// figures may be restricted to some of the tenant's users
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.Write(figure.Image)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+// Retries transient UNAVAILABLE errors (core restarting, pod rescheduled) with
+// exponential backoff. Streams are only retried before the first response arrives.
+const coreServiceConfig = `{
+	"methodConfig": [{
+		"name": [{}],
+		"retryPolicy": {
+			"maxAttempts": 4,
+			"initialBackoff": "0.2s",
+			"maxBackoff": "2s",
+			"backoffMultiplier": 2.0,
+			"retryableStatusCodes": ["UNAVAILABLE"]
+		}
+	}]
+}`
+
+// coreDialOptions builds the dial options for core from the environment:
+//
+//	GRPC_TLS                    "true" to use TLS (implied by any of the files below)
+//	GRPC_TLS_CA_FILE            PEM bundle used to verify core; system roots when empty
+//	GRPC_TLS_CERT_FILE/KEY_FILE client certificate for mTLS
+//	GRPC_TLS_SERVER_NAME        overrides the name verified against core's certificate
+//	GRPC_KEEPALIVE_TIME         ping interval on idle connections (default 30s)
+//	GRPC_KEEPALIVE_TIMEOUT      how long to wait for a ping ack (default 5s)
+func coreDialOptions() ([]grpc.DialOption, error) {
+	creds, err := coreTransportCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	keepaliveTime, err := envDuration("GRPC_KEEPALIVE_TIME", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	keepaliveTimeout, err := envDuration("GRPC_KEEPALIVE_TIMEOUT", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	return []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		// core rejects pings more often than every 10s, so keep Time above that.
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                max(keepaliveTime, 10*time.Second),
+			Timeout:             keepaliveTimeout,
+			PermitWithoutStream: true,
+		}),
+		grpc.WithDefaultServiceConfig(coreServiceConfig),
+		// Matches core's 20MB limit for large answers.
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(20 * 1024 * 1024)),
+	}, nil
+}
+
+func coreTransportCredentials() (credentials.TransportCredentials, error) {
+	caFile := os.Getenv("GRPC_TLS_CA_FILE")
+	certFile := os.Getenv("GRPC_TLS_CERT_FILE")
+	keyFile := os.Getenv("GRPC_TLS_KEY_FILE")
+
+	useTLS, _ := strconv.ParseBool(os.Getenv("GRPC_TLS"))
+	if !useTLS && caFile == "" && certFile == "" && keyFile == "" {
+		return insecure.NewCredentials(), nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: os.Getenv("GRPC_TLS_SERVER_NAME"),
+	}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.New("failed to read GRPC_TLS_CA_FILE: " + err.Error())
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in GRPC_TLS_CA_FILE")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, errors.New("GRPC_TLS_CERT_FILE and GRPC_TLS_KEY_FILE must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.New("failed to load client certificate: " + err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+func envDuration(name string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.New("invalid " + name + ": " + err.Error())
+	}
+	return d, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+// Templates the web pod cannot serve pages without.
+var requiredTemplates = []string{"login", "chat", "shared", "print", "notifications", "portal", "developer"}
+
+// HealthzHandler reports that the process is alive. It never checks dependencies,
+// so a core outage doesn't get web pods restarted.
+func (h *PageHandler) HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok"))
+}
+
+// ReadyzHandler reports whether this pod can serve traffic: templates are loaded
+// and the gRPC connection to core is up.
+func (h *PageHandler) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{
+		"templates": "ok",
+		"core":      "ok",
+	}
+	ready := true
+
+	for _, name := range requiredTemplates {
+		if h.templates[name] == nil {
+			checks["templates"] = "missing " + name
+			ready = false
+			break
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+	if state := h.waitForCoreConnection(ctx); state != connectivity.Ready {
+		checks["core"] = state.String()
+		ready = false
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ready":  ready,
+		"checks": checks,
+	})
+}
+
+// waitForCoreConnection kicks an idle connection and waits until it is ready or ctx expires.
+func (h *PageHandler) waitForCoreConnection(ctx context.Context) connectivity.State {
+	state := h.conn.GetState()
+	for state != connectivity.Ready {
+		if state == connectivity.Idle {
+			h.conn.Connect()
+		}
+		if !h.conn.WaitForStateChange(ctx, state) {
+			break
+		}
+		state = h.conn.GetState()
+	}
+	return state
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/status"
+)
+
+const historyPageSize = 50
+
+type historyRow struct {
+	SessionId string
+	Title     string
+	UpdatedOn string
+}
+
+// HistoryHandler lists the user's past conversations by title, most recent first. Each
+// opens in the printable view; "before" pages back through older ones.
+func (h *PageHandler) HistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.isAuthenticated(r) {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	before, _ := strconv.ParseInt(r.URL.Query().Get("before"), 10, 64)
+
+	ctx, cancel := context.WithTimeout(h.authContext(r), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.conversationClient.ListConversations(ctx, &pb.ListConversationsRequest{Limit: historyPageSize, Before: before})
+	if err != nil {
+		logger.Error("Failed to list conversations", zap.Error(err))
+		http.Error(w, status.Convert(err).Message(), httpStatusFromGrpc(err))
+		return
+	}
+
+	locale := localeFor(resp.Locale)
+	zone := timeZone(resp.TimeZone)
+
+	data := struct {
+		Lang          string
+		Conversations []historyRow
+		OlderBefore   int64
+	}{
+		Lang: locale.tag,
+	}
+	for _, conversation := range resp.Conversations {
+		row := historyRow{SessionId: conversation.SessionId, Title: conversation.Title}
+		if row.Title == "" {
+			row.Title = "Untitled conversation"
+		}
+		if conversation.UpdatedOn > 0 {
+			row.UpdatedOn = locale.DateTime(time.Unix(conversation.UpdatedOn, 0), zone)
+		}
+		data.Conversations = append(data.Conversations, row)
+	}
+	// a full page may have older conversations behind it
+	if n := len(resp.Conversations); n == historyPageSize {
+		data.OlderBefore = resp.Conversations[n-1].UpdatedOn
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := h.templates["history"].Execute(w, data); err != nil {
+		logger.Error("Failed to execute history template", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
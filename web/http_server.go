@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Server-wide timeouts. WriteTimeout covers ordinary pages; SSE handlers push their
+// write deadline forward on every event, so long agent streams are not cut off.
+const (
+	readHeaderTimeout = 10 * time.Second
+	readTimeout       = 30 * time.Second
+	writeTimeout      = 30 * time.Second
+	idleTimeout       = 120 * time.Second
+
+	http2MaxConcurrentStreams = 250
+	http2PingTimeout          = 15 * time.Second
+	http2SendPingTimeout      = 30 * time.Second
+)
+
+// newHTTPServer configures protocols and timeouts from the environment:
+//
+//	WEB_TLS_CERT_FILE/KEY_FILE  serve HTTPS (HTTP/2 is negotiated via ALPN)
+//	WEB_H2C                     "true" to accept cleartext HTTP/2 (h2c); only enable
+//	                            behind a trusted proxy that terminates TLS
+func newHTTPServer(addr string, handler http.Handler) *http.Server {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	if h2c, _ := strconv.ParseBool(os.Getenv("WEB_H2C")); h2c {
+		protocols.SetUnencryptedHTTP2(true)
+	}
+
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		Protocols:         protocols,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		HTTP2: &http.HTTP2Config{
+			MaxConcurrentStreams: http2MaxConcurrentStreams,
+			// Pings detect dead peers on idle HTTP/2 connections, including SSE streams
+			// that are waiting on a slow agent turn.
+			SendPingTimeout: http2SendPingTimeout,
+			PingTimeout:     http2PingTimeout,
+		},
+	}
+}
+
+// listenAndServe serves HTTPS when a certificate is configured, plain HTTP otherwise.
+func listenAndServe(server *http.Server) error {
+	certFile := os.Getenv("WEB_TLS_CERT_FILE")
+	keyFile := os.Getenv("WEB_TLS_KEY_FILE")
+	if certFile != "" && keyFile != "" {
+		return server.ListenAndServeTLS(certFile, keyFile)
+	}
+	return server.ListenAndServe()
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // tenant time zones must resolve in minimal containers too
+)
+
+// localeFormat describes how dates and numbers are written for one locale. Exports are
+// rendered on the server, so the tenant's locale decides the format rather than the
+// viewer's browser.
+type localeFormat struct {
+	tag      string // BCP 47, also used as the page's lang attribute
+	months   [12]string
+	date     string // {day}, {month} and {year} placeholders
+	dateTime string // {date} and {time} placeholders
+	hour12   bool
+	decimal  string
+	group    string
+	indian   bool // groups as 12,34,567 instead of 1,234,567
+}
+
+var englishMonths = [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+
+var localeFormats = map[string]localeFormat{
+	"en-US": {months: englishMonths, date: "{month} {day}, {year}", dateTime: "{date}, {time}", hour12: true, decimal: ".", group: ","},
+	"en-GB": {months: englishMonths, date: "{day} {month} {year}", dateTime: "{date}, {time}", decimal: ".", group: ","},
+	"en-IN": {months: englishMonths, date: "{day} {month} {year}", dateTime: "{date}, {time}", hour12: true, decimal: ".", group: ",", indian: true},
+	"hi-IN": {
+		months: [12]string{"जनवरी", "फ़रवरी", "मार्च", "अप्रैल", "मई", "जून", "जुलाई", "अगस्त", "सितंबर", "अक्तूबर", "नवंबर", "दिसंबर"},
+		date:   "{day} {month} {year}", dateTime: "{date}, {time}", hour12: true, decimal: ".", group: ",", indian: true,
+	},
+	"de-DE": {
+		months: [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		date:   "{day}. {month} {year}", dateTime: "{date}, {time}", decimal: ",", group: ".",
+	},
+	"fr-FR": {
+		months: [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		date:   "{day} {month} {year}", dateTime: "{date} à {time}", decimal: ",", group: " ",
+	},
+	"es-ES": {
+		months: [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		date:   "{day} de {month} de {year}", dateTime: "{date}, {time}", decimal: ",", group: ".",
+	},
+	"it-IT": {
+		months: [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		date:   "{day} {month} {year}", dateTime: "{date}, {time}", decimal: ",", group: ".",
+	},
+	"pt-BR": {
+		months: [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		date:   "{day} de {month} de {year}", dateTime: "{date}, {time}", decimal: ",", group: ".",
+	},
+	"nl-NL": {
+		months: [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		date:   "{day} {month} {year}", dateTime: "{date} {time}", decimal: ",", group: ".",
+	},
+}
+
+// localeFor returns the format for a BCP 47 tag such as "de-DE" or "de". An unknown
+// region falls back to another region of the same language, and an unknown language
+// to en-US.
+func localeFor(tag string) localeFormat {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	language, _, _ := strings.Cut(tag, "-")
+
+	best := "en-US"
+	for candidate := range localeFormats {
+		if strings.EqualFold(candidate, tag) {
+			best = candidate
+			break
+		}
+		// prefer a stable choice among regions of the same language
+		if prefix, _, _ := strings.Cut(candidate, "-"); strings.EqualFold(prefix, language) && (best == "en-US" || candidate < best) {
+			best = candidate
+		}
+	}
+
+	format := localeFormats[best]
+	format.tag = best
+	return format
+}
+
+// Date writes t as a long date, e.g. "2. Januar 2026".
+func (l localeFormat) Date(t time.Time) string {
+	return strings.NewReplacer(
+		"{day}", strconv.Itoa(t.Day()),
+		"{month}", l.months[t.Month()-1],
+		"{year}", strconv.Itoa(t.Year()),
+	).Replace(l.date)
+}
+
+// DateTime writes t in the given time zone, with the zone's abbreviation.
+func (l localeFormat) DateTime(t time.Time, zone *time.Location) string {
+	t = t.In(zone)
+
+	clock := t.Format("15:04")
+	if l.hour12 {
+		clock = t.Format("3:04 PM")
+		if l.tag != "en-US" {
+			clock = strings.ToLower(clock)
+		}
+	}
+	return strings.NewReplacer("{date}", l.Date(t), "{time}", clock+" "+t.Format("MST")).Replace(l.dateTime)
+}
+
+// Number writes v with the given number of decimals and the locale's separators.
+func (l localeFormat) Number(v float64, decimals int) string {
+	text := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	integer, fraction, _ := strings.Cut(text, ".")
+
+	var grouped strings.Builder
+	if v < 0 {
+		grouped.WriteByte('-')
+	}
+	for i, digit := range integer {
+		remaining := len(integer) - i
+		if i > 0 && (remaining%3 == 0 && (!l.indian || remaining == 3) || l.indian && remaining > 3 && remaining%2 == 1) {
+			grouped.WriteString(l.group)
+		}
+		grouped.WriteRune(digit)
+	}
+	if fraction != "" {
+		grouped.WriteString(l.decimal + fraction)
+	}
+	return grouped.String()
+}
+
+// Doses such as "2.5 ml" or "10000 IU". Potencies such as "30C" are names, not
+// quantities, and are left alone.
+var dosePattern = regexp.MustCompile(`(?i)\b(\d+(?:\.\d+)?)(\s?(?:mg|mcg|µg|ug|gm|g|ml|iu|units?|drops?|tablets?|tabs?|pills?|globules?|pellets?|capsules?)\b)`)
+
+// Dosages rewrites the doses in answer text with the locale's separators, leaving the
+// rest of the text as written.
+func (l localeFormat) Dosages(text string) string {
+	return dosePattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := dosePattern.FindStringSubmatch(match)
+		amount, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return match
+		}
+		decimals := 0
+		if _, fraction, ok := strings.Cut(parts[1], "."); ok {
+			decimals = len(fraction)
+		}
+		return l.Number(amount, decimals) + parts[2]
+	})
+}
+
+// timeZone loads an IANA zone name, falling back to UTC.
+func timeZone(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	zone, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return zone
+}
@@ -9,8 +9,8 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 func main() {
@@ -25,29 +25,70 @@ func main() {
 	}
 
 	// Create gRPC connection
-	conn, err := grpc.NewClient(grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	dialOptions, err := coreDialOptions()
+	if err != nil {
+		logger.Fatal("Invalid gRPC client configuration", zap.Error(err))
+	}
+
+	conn, err := grpc.NewClient(grpcAddr, dialOptions...)
 	if err != nil {
 		logger.Fatal("Failed to connect to gRPC server", zap.Error(err))
 	}
 	defer conn.Close()
 
+	idle, err := loadIdlePolicy()
+	if err != nil {
+		logger.Fatal("Invalid idle logout configuration", zap.Error(err))
+	}
+
+	publicURL, err := loadPublicURL()
+	if err != nil {
+		logger.Fatal("Invalid public URL configuration", zap.Error(err))
+	}
+
 	// Create page handler with gRPC connection
-	pageHandler := ProvidePageHandler(conn)
+	pageHandler := ProvidePageHandler(conn, idle, publicURL)
 
 	// Set up HTTP routes
 	mux := http.NewServeMux()
 
+	// Kubernetes probes
+	mux.HandleFunc("/healthz", pageHandler.HealthzHandler)
+	mux.HandleFunc("/readyz", pageHandler.ReadyzHandler)
+
 	// Page routes
 	mux.HandleFunc("/", pageHandler.RootHandler)
 	mux.HandleFunc("/login", pageHandler.LoginPageHandler)
 	mux.HandleFunc("/chat", pageHandler.ChatPageHandler)
 	mux.HandleFunc("/logout", pageHandler.LogoutHandler)
+	mux.HandleFunc("/shared/{token}", pageHandler.SharedConversationHandler)
+	mux.HandleFunc("/conversation/{id}/print", pageHandler.PrintConversationHandler)
+	mux.HandleFunc("/history", pageHandler.HistoryHandler)
+	mux.HandleFunc("/settings/notifications", pageHandler.NotificationSettingsHandler)
+	mux.HandleFunc("/settings/developer", pageHandler.DeveloperSettingsHandler)
+	mux.HandleFunc("/robots.txt", pageHandler.RobotsHandler)
+
+	// Public portal: unauthenticated, so every route is rate limited per client IP.
+	trustedProxies, err := loadTrustedProxies()
+	if err != nil {
+		logger.Fatal("Invalid trusted proxy configuration", zap.Error(err))
+	}
+	portalLimiter := newIPRateLimiter(rate.Every(time.Second), 30, trustedProxies)
+	mux.HandleFunc("/portal/{tenant}", portalLimiter.Middleware(pageHandler.PortalIndexHandler))
+	mux.HandleFunc("/portal/{tenant}/pages/{slug}", portalLimiter.Middleware(pageHandler.PortalPageHandler))
+	mux.HandleFunc("/portal/{tenant}/search", portalLimiter.Middleware(pageHandler.PortalSearchHandler))
 
 	// Static files
 	mux.HandleFunc("/static/", pageHandler.StaticHandler)
 
 	// API routes for AJAX calls
 	mux.HandleFunc("/api/agent/stream", pageHandler.AgentStreamHandler)
+	mux.HandleFunc("/api/session/keepalive", pageHandler.SessionKeepAliveHandler)
+	mux.HandleFunc("/api/session/{id}/share", pageHandler.ShareSessionHandler)
+	mux.HandleFunc("/api/session/{id}/context", pageHandler.SessionContextHandler)
+	mux.HandleFunc("/api/feedback", pageHandler.FeedbackHandler)
+	mux.HandleFunc("/api/figures/{id}", pageHandler.FigureHandler)
+	mux.HandleFunc("/api/corpus/events", pageHandler.CorpusEventsHandler)
 
 	// Create HTTP server
 	port := os.Getenv("PORT")
@@ -55,15 +96,12 @@ func main() {
 		port = "3000"
 	}
 
-	server := &http.Server{
-		Addr:    ":" + port,
-		Handler: mux,
-	}
+	server := newHTTPServer(":"+port, pageHandler.IdleSessionMiddleware(mux))
 
 	// Start server in a goroutine
 	go func() {
 		logger.Info("Starting web server", zap.String("port", port), zap.String("grpc_addr", grpcAddr))
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := listenAndServe(server); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("Failed to start server", zap.Error(err))
 		}
 	}()
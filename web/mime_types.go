@@ -0,0 +1,41 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// Extensions that Go's built-in table lacks and that the host's mime.types
+// may not have either (distroless and scratch images ship none).
+var staticMimeTypes = map[string]string{
+	".js":          "text/javascript; charset=utf-8",
+	".mjs":         "text/javascript; charset=utf-8",
+	".map":         "application/json",
+	".json":        "application/json",
+	".woff":        "font/woff",
+	".woff2":       "font/woff2",
+	".ttf":         "font/ttf",
+	".otf":         "font/otf",
+	".eot":         "application/vnd.ms-fontobject",
+	".ico":         "image/x-icon",
+	".wasm":        "application/wasm",
+	".svg":         "image/svg+xml",
+	".webmanifest": "application/manifest+json",
+}
+
+func init() {
+	for ext, typ := range staticMimeTypes {
+		_ = mime.AddExtensionType(ext, typ)
+	}
+}
+
+// staticContentType picks the Content-Type by extension, falling back to sniffing
+// the first bytes for unknown extensions.
+func staticContentType(path string, content []byte) string {
+	if contentType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path))); contentType != "" {
+		return contentType
+	}
+	return http.DetectContentType(content)
+}
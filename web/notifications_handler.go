@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/status"
+)
+
+var channelLabels = map[string]string{
+	"in_app":  "In app",
+	"email":   "Email",
+	"push":    "Push",
+	"webhook": "Webhook",
+}
+
+type notificationChannelOption struct {
+	Name    string
+	Label   string
+	Enabled bool
+}
+
+type notificationEventRow struct {
+	Event       string
+	Description string
+	Channels    []notificationChannelOption
+}
+
+// NotificationSettingsHandler shows and saves the user's notification preferences. The
+// form posts every event it lists, so clearing all of an event's boxes turns it off.
+func (h *PageHandler) NotificationSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.isAuthenticated(r) {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(h.authContext(r), 10*time.Second)
+	defer cancel()
+
+	var (
+		prefs *pb.NotificationPreferences
+		err   error
+	)
+	if r.Method == "POST" {
+		if err = r.ParseForm(); err != nil {
+			http.Error(w, "Invalid form", http.StatusBadRequest)
+			return
+		}
+
+		req := &pb.UpdateNotificationPreferencesRequest{DigestFrequency: r.PostForm.Get("digestFrequency")}
+		for _, event := range r.PostForm["event"] {
+			req.Events = append(req.Events, &pb.NotificationEventPreference{
+				Event:    event,
+				Channels: r.PostForm["channel:"+event],
+			})
+		}
+		if prefs, err = h.notificationsClient.UpdateNotificationPreferences(ctx, req); err == nil {
+			http.Redirect(w, r, "/settings/notifications?saved=1", http.StatusSeeOther)
+			return
+		}
+	} else {
+		prefs, err = h.notificationsClient.GetNotificationPreferences(ctx, &pb.GetNotificationPreferencesRequest{})
+	}
+	if err != nil {
+		logger.Error("Failed to handle notification preferences", zap.String("method", r.Method), zap.Error(err))
+		http.Error(w, status.Convert(err).Message(), httpStatusFromGrpc(err))
+		return
+	}
+
+	data := struct {
+		Events                        []notificationEventRow
+		DigestFrequency               string
+		DigestFrequencies             []string
+		CorpusUpdatesDisabledByTenant bool
+		Saved                         bool
+	}{
+		DigestFrequency:               prefs.DigestFrequency,
+		DigestFrequencies:             []string{"off", "daily", "weekly"},
+		CorpusUpdatesDisabledByTenant: prefs.CorpusUpdatesDisabledByTenant,
+		Saved:                         r.URL.Query().Get("saved") == "1",
+	}
+	for _, event := range prefs.Events {
+		row := notificationEventRow{Event: event.Event, Description: event.Description}
+		for _, channel := range event.AvailableChannels {
+			label := channelLabels[channel]
+			if label == "" {
+				label = channel
+			}
+			row.Channels = append(row.Channels, notificationChannelOption{
+				Name:    channel,
+				Label:   label,
+				Enabled: slices.Contains(event.Channels, channel),
+			})
+		}
+		data.Events = append(data.Events, row)
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := h.templates["notifications"].Execute(w, data); err != nil {
+		logger.Error("Failed to execute notifications template", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
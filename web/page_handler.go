@@ -26,17 +26,40 @@ var viewsFS embed.FS
 //go:embed static/*.js static/*.css
 var staticFS embed.FS
 
+const (
+	sseHeartbeatInterval = 15 * time.Second
+	sseWriteTimeout      = 30 * time.Second
+)
+
 type PageHandler struct {
-	templates   map[string]*template.Template
-	loginClient pb.LoginClient
-	agentClient schema.AgentClient
+	conn                *grpc.ClientConn
+	templates           map[string]*template.Template
+	loginClient         pb.LoginClient
+	agentClient         schema.AgentClient
+	conversationClient  pb.ConversationClient
+	feedbackClient      pb.FeedbackClient
+	portalClient        pb.PortalClient
+	corpusClient        pb.CorpusClient
+	notificationsClient pb.NotificationsClient
+	apiKeysClient       pb.ApiKeysClient
+	idle                idlePolicy
+	publicURL           string
 }
 
-func ProvidePageHandler(conn *grpc.ClientConn) *PageHandler {
+func ProvidePageHandler(conn *grpc.ClientConn, idle idlePolicy, publicURL string) *PageHandler {
 	handler := &PageHandler{
-		templates:   make(map[string]*template.Template),
-		loginClient: pb.NewLoginClient(conn),
-		agentClient: schema.NewAgentClient(conn),
+		conn:                conn,
+		templates:           make(map[string]*template.Template),
+		loginClient:         pb.NewLoginClient(conn),
+		agentClient:         schema.NewAgentClient(conn),
+		conversationClient:  pb.NewConversationClient(conn),
+		feedbackClient:      pb.NewFeedbackClient(conn),
+		portalClient:        pb.NewPortalClient(conn),
+		corpusClient:        pb.NewCorpusClient(conn),
+		notificationsClient: pb.NewNotificationsClient(conn),
+		apiKeysClient:       pb.NewApiKeysClient(conn),
+		idle:                idle,
+		publicURL:           publicURL,
 	}
 	handler.loadTemplates()
 	return handler
@@ -56,6 +79,42 @@ func (h *PageHandler) loadTemplates() {
 		return
 	}
 
+	sharedTemplate, err := viewsFS.ReadFile("views/shared.html")
+	if err != nil {
+		logger.Error("Failed to read shared template", zap.Error(err))
+		return
+	}
+
+	printTemplate, err := viewsFS.ReadFile("views/print.html")
+	if err != nil {
+		logger.Error("Failed to read print template", zap.Error(err))
+		return
+	}
+
+	notificationsTemplate, err := viewsFS.ReadFile("views/notifications.html")
+	if err != nil {
+		logger.Error("Failed to read notifications template", zap.Error(err))
+		return
+	}
+
+	portalTemplate, err := viewsFS.ReadFile("views/portal.html")
+	if err != nil {
+		logger.Error("Failed to read portal template", zap.Error(err))
+		return
+	}
+
+	developerTemplate, err := viewsFS.ReadFile("views/developer.html")
+	if err != nil {
+		logger.Error("Failed to read developer template", zap.Error(err))
+		return
+	}
+
+	historyTemplate, err := viewsFS.ReadFile("views/history.html")
+	if err != nil {
+		logger.Error("Failed to read history template", zap.Error(err))
+		return
+	}
+
 	h.templates["login"], err = template.New("login").Parse(string(loginTemplate))
 	if err != nil {
 		logger.Error("Failed to parse login template", zap.Error(err))
@@ -66,6 +125,36 @@ func (h *PageHandler) loadTemplates() {
 		logger.Error("Failed to parse chat template", zap.Error(err))
 	}
 
+	h.templates["shared"], err = template.New("shared").Parse(string(sharedTemplate))
+	if err != nil {
+		logger.Error("Failed to parse shared template", zap.Error(err))
+	}
+
+	h.templates["print"], err = template.New("print").Parse(string(printTemplate))
+	if err != nil {
+		logger.Error("Failed to parse print template", zap.Error(err))
+	}
+
+	h.templates["notifications"], err = template.New("notifications").Parse(string(notificationsTemplate))
+	if err != nil {
+		logger.Error("Failed to parse notifications template", zap.Error(err))
+	}
+
+	h.templates["portal"], err = template.New("portal").Parse(string(portalTemplate))
+	if err != nil {
+		logger.Error("Failed to parse portal template", zap.Error(err))
+	}
+
+	h.templates["developer"], err = template.New("developer").Parse(string(developerTemplate))
+	if err != nil {
+		logger.Error("Failed to parse developer template", zap.Error(err))
+	}
+
+	h.templates["history"], err = template.New("history").Parse(string(historyTemplate))
+	if err != nil {
+		logger.Error("Failed to parse history template", zap.Error(err))
+	}
+
 	logger.Info("Embedded templates loaded successfully")
 }
 
@@ -80,11 +169,15 @@ func (h *PageHandler) LoginPageHandler(w http.ResponseWriter, r *http.Request) {
 
 		data := struct {
 			Error  string
+			Notice string
 			Email  string
 			Tenant string
 		}{
 			Tenant: "default", // Default tenant
 		}
+		if r.URL.Query().Get("reason") == "idle" {
+			data.Notice = "You were signed out after a period of inactivity."
+		}
 
 		w.Header().Set("Content-Type", "text/html")
 		if err := h.templates["login"].Execute(w, data); err != nil {
@@ -144,17 +237,13 @@ func (h *PageHandler) RootHandler(w http.ResponseWriter, r *http.Request) {
 
 // LogoutHandler handles logout
 func (h *PageHandler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
-	// Clear the authentication cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "auth_token",
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		HttpOnly: true,
-		Secure:   false, // Set to true in production with HTTPS
-		SameSite: http.SameSiteLaxMode,
-	})
+	h.clearSessionCookies(w)
 
+	// the chat page logs out idle users itself; let the login page say why
+	if r.URL.Query().Get("reason") == "idle" {
+		http.Redirect(w, r, "/login?reason=idle", http.StatusFound)
+		return
+	}
 	http.Redirect(w, r, "/login", http.StatusFound)
 }
 
@@ -165,6 +254,7 @@ func (h *PageHandler) handleLogin(w http.ResponseWriter, r *http.Request) {
 
 	data := struct {
 		Error  string
+		Notice string
 		Email  string
 		Tenant string
 	}{
@@ -203,7 +293,7 @@ func (h *PageHandler) handleLogin(w http.ResponseWriter, r *http.Request) {
 		Name:     "auth_token",
 		Value:    resp.Jwt,
 		Path:     "/",
-		MaxAge:   86400, // 24 hours
+		MaxAge:   int(sessionLifetime.Seconds()),
 		HttpOnly: true,
 		Secure:   false, // Set to true in production with HTTPS
 		SameSite: http.SameSiteLaxMode,
@@ -214,7 +304,7 @@ func (h *PageHandler) handleLogin(w http.ResponseWriter, r *http.Request) {
 		Name:     "user_email",
 		Value:    email,
 		Path:     "/",
-		MaxAge:   86400, // 24 hours
+		MaxAge:   int(sessionLifetime.Seconds()),
 		HttpOnly: false, // Allow JS access for UI
 		Secure:   false, // Set to true in production with HTTPS
 		SameSite: http.SameSiteLaxMode,
@@ -224,7 +314,7 @@ func (h *PageHandler) handleLogin(w http.ResponseWriter, r *http.Request) {
 		Name:     "user_tenant",
 		Value:    tenant,
 		Path:     "/",
-		MaxAge:   86400, // 24 hours
+		MaxAge:   int(sessionLifetime.Seconds()),
 		HttpOnly: false, // Allow JS access for UI
 		Secure:   false, // Set to true in production with HTTPS
 		SameSite: http.SameSiteLaxMode,
@@ -234,12 +324,17 @@ func (h *PageHandler) handleLogin(w http.ResponseWriter, r *http.Request) {
 		Name:     "user_type",
 		Value:    resp.UserType,
 		Path:     "/",
-		MaxAge:   86400, // 24 hours
+		MaxAge:   int(sessionLifetime.Seconds()),
 		HttpOnly: false, // Allow JS access for UI
 		Secure:   false, // Set to true in production with HTTPS
 		SameSite: http.SameSiteLaxMode,
 	})
 
+	if h.idle.enabled() {
+		now := time.Now()
+		h.setSessionActivity(w, sessionActivity{loginAt: now, lastAt: now})
+	}
+
 	logger.Info("User logged in successfully", zap.String("email", email), zap.String("tenant", tenant))
 	http.Redirect(w, r, "/chat", http.StatusFound)
 }
@@ -305,17 +400,8 @@ func (h *PageHandler) StaticHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Set content type based on file extension
-	contentType := "text/plain"
-	if strings.HasSuffix(path, ".js") {
-		contentType = "application/javascript"
-	} else if strings.HasSuffix(path, ".css") {
-		contentType = "text/css"
-	} else if strings.HasSuffix(path, ".html") {
-		contentType = "text/html"
-	}
-
-	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Type", staticContentType(path, content))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
 	w.Header().Set("Cache-Control", "public, max-age=3600") // Cache for 1 hour
 	w.Write(content)
 }
@@ -340,6 +426,10 @@ func (h *PageHandler) AgentStreamHandler(w http.ResponseWriter, r *http.Request)
 		Text      string `json:"text"`
 		SessionId string `json:"sessionId"`
 		Model     string `json:"model"`
+		// BCP 47 answer language; empty answers in the question's language
+		Language string `json:"language"`
+		// runs the tools and returns the plan instead of an answer
+		Explain bool `json:"explain"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
@@ -390,6 +480,12 @@ func (h *PageHandler) AgentStreamHandler(w http.ResponseWriter, r *http.Request)
 			"sessionId": reqData.SessionId,
 		},
 	}
+	if reqData.Language != "" {
+		agentReq.Metadata["language"] = reqData.Language
+	}
+	if reqData.Explain {
+		agentReq.Metadata["explain"] = "true"
+	}
 
 	// Call the streaming gRPC service
 	stream, err := h.agentClient.Execute(ctx, agentReq)
@@ -414,6 +510,30 @@ func (h *PageHandler) AgentStreamHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Receive in the background so heartbeats keep flowing while the agent is busy.
+	type recvResult struct {
+		chunk *schema.AgentStreamChunk
+		err   error
+	}
+	received := make(chan recvResult)
+	go func() {
+		defer close(received)
+		for {
+			chunk, err := stream.Recv()
+			select {
+			case received <- recvResult{chunk, err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
 	chunkCount := 0
 	for {
 		select {
@@ -421,8 +541,17 @@ func (h *PageHandler) AgentStreamHandler(w http.ResponseWriter, r *http.Request)
 			// Client disconnected
 			logger.Info("Client disconnected from stream", zap.Int("chunks_received", chunkCount))
 			return
-		default:
-			chunk, err := stream.Recv()
+		case <-heartbeat.C:
+			// SSE comment line; ignored by the client but keeps proxies from
+			// closing a connection that looks idle.
+			h.extendSSEWriteDeadline(w)
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case res, ok := <-received:
+			if !ok {
+				return
+			}
+			chunk, err := res.chunk, res.err
 			if err != nil {
 				if err.Error() == "EOF" || status.Code(err) == codes.Canceled {
 					// Stream ended normally
@@ -438,8 +567,18 @@ func (h *PageHandler) AgentStreamHandler(w http.ResponseWriter, r *http.Request)
 				return
 			}
 
+			// answers stream token by token, so chunks are logged at debug level only
 			chunkCount++
-			logger.Info("Received chunk from gRPC stream", zap.Int("chunk_number", chunkCount))
+
+			// Suggested follow-up questions arrive as a pseudo tool result after completion;
+			// the browser gets them as an event of their own.
+			if result := chunk.GetToolResultChunk(); result != nil && result.ToolName == followUpToolName {
+				h.sendSSEData(w, map[string]interface{}{
+					"type":      "followUps",
+					"questions": result.Sentences,
+				})
+				continue
+			}
 
 			// Convert chunk to JSON and send as SSE
 			chunkData := map[string]interface{}{
@@ -448,6 +587,12 @@ func (h *PageHandler) AgentStreamHandler(w http.ResponseWriter, r *http.Request)
 			}
 
 			h.sendSSEData(w, chunkData)
+			if complete := chunk.GetComplete(); complete != nil {
+				h.sendSSEData(w, map[string]interface{}{
+					"type": "meta",
+					"meta": answerMeta(complete),
+				})
+			}
 			flusher.Flush()
 
 			logger.Debug("Sent SSE chunk to client", zap.Int("chunk_number", chunkCount))
@@ -455,6 +600,26 @@ func (h *PageHandler) AgentStreamHandler(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// followUpToolName marks the tool result carrying suggested follow-up questions; it
+// matches core's followups.ToolName.
+const followUpToolName = "follow_up_questions"
+
+// answerMetaKeys are the completion metadata entries shown in the footer under each
+// answer. Anything else the agent attaches stays in the chunk itself.
+var answerMetaKeys = []string{"model", "miniModel", "toolSelectorModel", "corpusVersion", "toolCalls", "latencyMs", "tokens", "cached", "language", "confidence", "disclaimers", "explain", "budgetExhausted", "fallbackModels", "traceId"}
+
+// answerMeta describes how an answer was produced: the models involved, the corpus
+// version it was retrieved from, how many tool calls it took and how long it took.
+func answerMeta(complete *schema.StreamComplete) map[string]string {
+	meta := make(map[string]string, len(answerMetaKeys))
+	for _, key := range answerMetaKeys {
+		if value, ok := complete.Metadata[key]; ok && value != "" {
+			meta[key] = value
+		}
+	}
+	return meta
+}
+
 // Helper function to send SSE data
 func (h *PageHandler) sendSSEData(w http.ResponseWriter, data interface{}) {
 	jsonData, err := json.Marshal(data)
@@ -463,6 +628,8 @@ func (h *PageHandler) sendSSEData(w http.ResponseWriter, data interface{}) {
 		return
 	}
 
+	h.extendSSEWriteDeadline(w)
+
 	fmt.Fprintf(w, "data: %s\n\n", string(jsonData))
 }
 
@@ -479,6 +646,12 @@ func (h *PageHandler) sendSSEError(w http.ResponseWriter, message string) {
 	}
 }
 
+// extendSSEWriteDeadline gives the next SSE write a fresh deadline, overriding the
+// server-wide WriteTimeout that would otherwise end long streams.
+func (h *PageHandler) extendSSEWriteDeadline(w http.ResponseWriter) {
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(sseWriteTimeout))
+}
+
 func (h *PageHandler) getAuthToken(r *http.Request) string {
 	cookie, err := r.Cookie("auth_token")
 	if err != nil {
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.uber.org/zap"
+)
+
+// Curated pages change rarely, so shared caches may keep them for a few minutes.
+const portalPageCacheControl = "public, max-age=300"
+
+// robotsTxt keeps crawlers on the public portal pages only. Search results are
+// query-dependent and are excluded along with the authenticated app.
+const robotsTxt = `User-agent: *
+Allow: /portal/
+Disallow: /portal/*/search
+Disallow: /
+`
+
+type portalPageData struct {
+	Tenant      string
+	TenantTitle string
+	Pages       []*pb.PortalPageSummary
+	Page        *pb.PortalPage
+	Body        template.HTML
+	Query       string
+	Results     []*pb.PortalSearchResult
+	Searched    bool
+}
+
+// PortalIndexHandler lists a tenant's curated remedy pages.
+func (h *PageHandler) PortalIndexHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tenant := r.PathValue("tenant")
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.portalClient.ListPortalPages(ctx, &pb.ListPortalPagesRequest{Tenant: tenant})
+	if err != nil {
+		h.portalError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", portalPageCacheControl)
+	h.renderPortal(w, portalPageData{
+		Tenant:      tenant,
+		TenantTitle: portalTitle(resp.TenantTitle, tenant),
+		Pages:       resp.Pages,
+	})
+}
+
+// PortalPageHandler renders a single curated page.
+func (h *PageHandler) PortalPageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tenant := r.PathValue("tenant")
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	page, err := h.portalClient.GetPortalPage(ctx, &pb.GetPortalPageRequest{
+		Tenant: tenant,
+		Slug:   r.PathValue("slug"),
+	})
+	if err != nil {
+		h.portalError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", portalPageCacheControl)
+	h.renderPortal(w, portalPageData{
+		Tenant:      tenant,
+		TenantTitle: portalTitle("", tenant),
+		Page:        page,
+		// bodyHtml is rendered by core with raw HTML escaped.
+		Body: template.HTML(page.BodyHtml),
+	})
+}
+
+// PortalSearchHandler runs a plain text search over the tenant's public corpus.
+func (h *PageHandler) PortalSearchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tenant := r.PathValue("tenant")
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	data := portalPageData{
+		Tenant:      tenant,
+		TenantTitle: portalTitle("", tenant),
+		Query:       query,
+	}
+
+	if query != "" {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		resp, err := h.portalClient.SearchPortal(ctx, &pb.SearchPortalRequest{Tenant: tenant, Query: query})
+		if err != nil {
+			h.portalError(w, err)
+			return
+		}
+		data.Results = resp.Results
+		data.Searched = true
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	h.renderPortal(w, data)
+}
+
+// RobotsHandler serves the crawler policy for the whole site.
+func (h *PageHandler) RobotsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write([]byte(robotsTxt))
+}
+
+func (h *PageHandler) renderPortal(w http.ResponseWriter, data portalPageData) {
+	w.Header().Set("Content-Type", "text/html")
+	if err := h.templates["portal"].Execute(w, data); err != nil {
+		logger.Error("Failed to execute portal template", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func (h *PageHandler) portalError(w http.ResponseWriter, err error) {
+	code := httpStatusFromGrpc(err)
+	if code >= http.StatusInternalServerError {
+		logger.Error("Portal request failed", zap.Error(err))
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	http.Error(w, http.StatusText(code), code)
+}
+
+func portalTitle(title, tenant string) string {
+	if title != "" {
+		return title
+	}
+	return tenant
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/status"
+)
+
+// printFootnote is a source cited by an answer in the printed conversation.
+type printFootnote struct {
+	Number      int    `json:"number"`
+	Title       string `json:"title"`
+	Attribution string `json:"attribution"`
+}
+
+// printSourceChange is a footnoted source that was re-ingested or removed after the
+// answer citing it was generated.
+type printSourceChange struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	Removed bool   `json:"removed"`
+}
+
+type printMessage struct {
+	Role      string          `json:"role"`
+	Content   string          `json:"content"`
+	Footnotes []printFootnote `json:"footnotes"`
+
+	// Answers whose sources changed since carry the question, so it can be asked again.
+	SourceChanges []printSourceChange `json:"sourceChanges,omitempty"`
+	Question      string              `json:"question,omitempty"`
+}
+
+// PrintConversationHandler renders the user's own conversation for a physical case file:
+// no chat controls, print styles, and the sources of each answer as numbered footnotes.
+func (h *PageHandler) PrintConversationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.isAuthenticated(r) {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	sessionId := r.PathValue("id")
+	if sessionId == "" {
+		http.Error(w, "Session id is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(h.authContext(r), 10*time.Second)
+	defer cancel()
+
+	transcript, err := h.conversationClient.GetConversation(ctx, &pb.GetConversationRequest{SessionId: sessionId})
+	if err != nil {
+		logger.Error("Failed to load conversation for printing", zap.String("sessionId", sessionId), zap.Error(err))
+		http.Error(w, status.Convert(err).Message(), httpStatusFromGrpc(err))
+		return
+	}
+
+	locale := localeFor(transcript.Locale)
+	zone := timeZone(transcript.TimeZone)
+
+	// footnotes are numbered continuously across the conversation
+	next := 1
+	question := ""
+	messages := make([]printMessage, 0, len(transcript.Messages))
+	for _, msg := range transcript.Messages {
+		if msg.Role != "assistant" {
+			question = msg.Content
+			messages = append(messages, printMessage{Role: msg.Role, Content: msg.Content})
+			continue
+		}
+
+		content, footnotes := footnoteCitations(msg, next)
+		next += len(footnotes)
+		message := printMessage{Role: msg.Role, Content: locale.Dosages(content), Footnotes: footnotes}
+		if changes := changedFootnotes(msg, footnotes); len(changes) > 0 {
+			message.SourceChanges = changes
+			message.Question = question
+		}
+		messages = append(messages, message)
+	}
+
+	data := struct {
+		Lang      string
+		SessionId string
+		StartedOn string
+		PrintedOn string
+		Case      *pb.CaseContext
+		Messages  []printMessage
+	}{
+		Lang:      locale.tag,
+		SessionId: transcript.SessionId,
+		PrintedOn: locale.DateTime(time.Now(), zone),
+		Case:      transcript.CaseContext,
+		Messages:  messages,
+	}
+	if transcript.CreatedOn > 0 {
+		data.StartedOn = locale.DateTime(time.Unix(transcript.CreatedOn, 0), zone)
+	}
+
+	// Printed conversations contain clinical details; keep them out of caches and indexes.
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	if err := h.templates["print"].Execute(w, data); err != nil {
+		logger.Error("Failed to execute print template", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// changedFootnotes lists the footnoted sources of an answer that have changed since it
+// was generated.
+func changedFootnotes(msg *pb.TranscriptMessage, footnotes []printFootnote) []printSourceChange {
+	var changes []printSourceChange
+	for _, change := range msg.SourceChanges {
+		if int(change.SourceIndex) >= len(footnotes) {
+			continue
+		}
+		footnote := footnotes[change.SourceIndex]
+		changes = append(changes, printSourceChange{Number: footnote.Number, Title: footnote.Title, Removed: change.Removed})
+	}
+	return changes
+}
+
+// footnoteCitations marks each cited sentence of an answer with the numbers of its
+// sources, starting at first, and returns the footnotes in the order they are numbered.
+func footnoteCitations(msg *pb.TranscriptMessage, first int) (string, []printFootnote) {
+	footnotes := make([]printFootnote, len(msg.Sources))
+	for i, source := range msg.Sources {
+		footnotes[i] = printFootnote{Number: first + i, Title: source.Title, Attribution: source.Attribution}
+	}
+
+	var (
+		content strings.Builder
+		rest    = msg.Content
+	)
+	for _, citation := range msg.Citations {
+		at := strings.Index(rest, citation.Sentence)
+		if at < 0 || len(citation.SourceIndexes) == 0 {
+			continue
+		}
+		end := at + len(citation.Sentence)
+
+		numbers := make([]string, 0, len(citation.SourceIndexes))
+		for _, index := range citation.SourceIndexes {
+			if int(index) < len(footnotes) {
+				numbers = append(numbers, strconv.Itoa(footnotes[index].Number))
+			}
+		}
+		content.WriteString(rest[:end])
+		content.WriteString(`<sup class="footnote-ref">[` + strings.Join(numbers, ", ") + `]</sup>`)
+		rest = rest[end:]
+	}
+	content.WriteString(rest)
+	return content.String(), footnotes
+}
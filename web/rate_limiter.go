@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ipRateLimiter hands out a token bucket per client IP. Idle buckets are
+// dropped periodically so the map doesn't grow with every visitor.
+type ipRateLimiter struct {
+	mu       sync.Mutex
+	limiters map[string]*visitor
+	limit    rate.Limit
+	burst    int
+	trusted  []*net.IPNet
+}
+
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// newIPRateLimiter keys buckets on the connecting address. When that address
+// is one of the trusted proxies, the key is the right-most X-Forwarded-For hop
+// that isn't.
+func newIPRateLimiter(limit rate.Limit, burst int, trusted []*net.IPNet) *ipRateLimiter {
+	l := &ipRateLimiter{
+		limiters: make(map[string]*visitor),
+		limit:    limit,
+		burst:    burst,
+		trusted:  trusted,
+	}
+	go l.cleanup(10 * time.Minute)
+	return l
+}
+
+func (l *ipRateLimiter) Allow(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	v, ok := l.limiters[ip]
+	if !ok {
+		v = &visitor{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.limiters[ip] = v
+	}
+	v.lastSeen = time.Now()
+	return v.limiter.Allow()
+}
+
+// Middleware rejects requests over the limit with 429.
+func (l *ipRateLimiter) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !l.Allow(l.clientIP(r)) {
+			w.Header().Set("Retry-After", "60")
+			w.Header().Set("Cache-Control", "no-store")
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (l *ipRateLimiter) cleanup(idle time.Duration) {
+	ticker := time.NewTicker(idle)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		l.mu.Lock()
+		for ip, v := range l.limiters {
+			if time.Since(v.lastSeen) > idle {
+				delete(l.limiters, ip)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// clientIP walks X-Forwarded-For from the right, past the trusted proxies.
+// Hops left of the first untrusted one are set by the client and ignored.
+func (l *ipRateLimiter) clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !l.isTrusted(ip) {
+		return ip
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+		if !l.isTrusted(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+func (l *ipRateLimiter) isTrusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range l.trusted {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadTrustedProxies parses WEB_TRUSTED_PROXIES, a comma-separated list of
+// IPs or CIDRs of the proxies in front of the web server.
+func loadTrustedProxies() ([]*net.IPNet, error) {
+	var trusted []*net.IPNet
+	for _, entry := range strings.Split(os.Getenv("WEB_TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, errors.New("invalid WEB_TRUSTED_PROXIES entry: " + entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			trusted = append(trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, errors.New("invalid WEB_TRUSTED_PROXIES entry: " + err.Error())
+		}
+		trusted = append(trusted, n)
+	}
+	return trusted, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestClientIP(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		trusted    string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{name: "NoProxies", remoteAddr: "203.0.113.7:5000", forwarded: "198.51.100.1", want: "203.0.113.7"},
+		{name: "UntrustedPeer", trusted: "10.0.0.0/8", remoteAddr: "203.0.113.7:5000", forwarded: "198.51.100.1", want: "203.0.113.7"},
+		{name: "TrustedProxy", trusted: "10.0.0.0/8", remoteAddr: "10.0.0.2:5000", forwarded: "198.51.100.1", want: "198.51.100.1"},
+		{name: "SpoofedLeftmost", trusted: "10.0.0.0/8", remoteAddr: "10.0.0.2:5000", forwarded: "1.2.3.4, 198.51.100.1", want: "198.51.100.1"},
+		{name: "TrustedChain", trusted: "10.0.0.0/8, 192.0.2.10", remoteAddr: "10.0.0.2:5000", forwarded: "1.2.3.4, 198.51.100.1, 192.0.2.10, 10.1.1.1", want: "198.51.100.1"},
+		{name: "OnlyProxies", trusted: "10.0.0.0/8", remoteAddr: "10.0.0.2:5000", forwarded: "10.0.0.3", want: "10.0.0.3"},
+		{name: "NoHeader", trusted: "10.0.0.0/8", remoteAddr: "10.0.0.2:5000", want: "10.0.0.2"},
+		{name: "MalformedHop", trusted: "10.0.0.0/8", remoteAddr: "10.0.0.2:5000", forwarded: "198.51.100.1, not-an-ip", want: "10.0.0.2"},
+		{name: "RemoteAddrWithoutPort", remoteAddr: "203.0.113.7", want: "203.0.113.7"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("WEB_TRUSTED_PROXIES", tc.trusted)
+			trusted, err := loadTrustedProxies()
+			require.NoError(t, err)
+
+			r := httptest.NewRequest("GET", "/portal/clinic", nil)
+			r.RemoteAddr = tc.remoteAddr
+			if tc.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+
+			l := &ipRateLimiter{trusted: trusted}
+			assert.Equal(t, tc.want, l.clientIP(r))
+		})
+	}
+}
+
+func TestLoadTrustedProxies(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		entries string
+		want    []string
+		invalid bool
+	}{
+		{name: "Empty"},
+		{name: "IPsAndCIDRs", entries: " 10.0.0.0/8 , 192.0.2.10,,::1", want: []string{"10.0.0.0/8", "192.0.2.10/32", "::1/128"}},
+		{name: "MalformedIP", entries: "10.0.0.256", invalid: true},
+		{name: "MalformedCIDR", entries: "10.0.0.0/33", invalid: true},
+		{name: "Hostname", entries: "proxy.internal", invalid: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("WEB_TRUSTED_PROXIES", tc.entries)
+
+			trusted, err := loadTrustedProxies()
+			if tc.invalid {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var got []string
+			for _, n := range trusted {
+				got = append(got, n.String())
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestIsTrusted(t *testing.T) {
+	_, n, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+	l := &ipRateLimiter{trusted: []*net.IPNet{n}}
+
+	assert.True(t, l.isTrusted("10.1.2.3"))
+	assert.False(t, l.isTrusted("11.1.2.3"))
+	assert.False(t, l.isTrusted("garbage"))
+}
+
+func TestIPRateLimiterBuckets(t *testing.T) {
+	l := newIPRateLimiter(rate.Every(20*time.Millisecond), 2, nil)
+
+	assert.True(t, l.Allow("203.0.113.7"))
+	assert.True(t, l.Allow("203.0.113.7"))
+	assert.False(t, l.Allow("203.0.113.7"), "the burst is spent")
+	assert.True(t, l.Allow("198.51.100.1"), "each client has its own bucket")
+
+	time.Sleep(30 * time.Millisecond)
+	assert.True(t, l.Allow("203.0.113.7"), "a token is refilled")
+}
+
+func TestIPRateLimiterMiddleware(t *testing.T) {
+	l := newIPRateLimiter(rate.Every(time.Hour), 1, nil)
+	handler := l.Middleware(func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/portal/clinic", nil)
+		r.RemoteAddr = "203.0.113.7:5000"
+		handler(w, r)
+		assert.Equal(t, want, w.Code)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sessionLifetime is how long a login lasts, idle or not. It is the lifetime of the
+// auth cookie, since the JWT issued by core does not expire on its own.
+const sessionLifetime = 24 * time.Hour
+
+// The activity cookie holds "<login unix>.<last activity unix>". It is only set when an
+// idle timeout is configured.
+const activityCookie = "session_activity"
+
+// idlePolicy signs users out of shared terminals after a period without activity:
+//
+//	WEB_IDLE_LOGOUT_AFTER  idle duration after which the session ends, e.g. "15m";
+//	                       unset or zero disables idle logout
+//	WEB_IDLE_WARN_AFTER    idle duration after which the chat page warns, e.g. "13m";
+//	                       defaults to two minutes before logout
+type idlePolicy struct {
+	warnAfter   time.Duration
+	logoutAfter time.Duration
+}
+
+func loadIdlePolicy() (idlePolicy, error) {
+	logoutAfter, err := envDuration("WEB_IDLE_LOGOUT_AFTER", 0)
+	if err != nil {
+		return idlePolicy{}, err
+	}
+	if logoutAfter <= 0 {
+		return idlePolicy{}, nil
+	}
+	if logoutAfter >= sessionLifetime {
+		return idlePolicy{}, errors.New("WEB_IDLE_LOGOUT_AFTER must be shorter than the " + sessionLifetime.String() + " session lifetime")
+	}
+
+	warnAfter, err := envDuration("WEB_IDLE_WARN_AFTER", max(logoutAfter-2*time.Minute, logoutAfter/2))
+	if err != nil {
+		return idlePolicy{}, err
+	}
+	if warnAfter <= 0 || warnAfter >= logoutAfter {
+		return idlePolicy{}, errors.New("WEB_IDLE_WARN_AFTER must be positive and shorter than WEB_IDLE_LOGOUT_AFTER")
+	}
+	return idlePolicy{warnAfter: warnAfter, logoutAfter: logoutAfter}, nil
+}
+
+func (p idlePolicy) enabled() bool {
+	return p.logoutAfter > 0
+}
+
+// sessionActivity is when the user logged in and when they were last active.
+type sessionActivity struct {
+	loginAt time.Time
+	lastAt  time.Time
+}
+
+func readSessionActivity(r *http.Request) (sessionActivity, bool) {
+	cookie, err := r.Cookie(activityCookie)
+	if err != nil {
+		return sessionActivity{}, false
+	}
+	login, last, ok := strings.Cut(cookie.Value, ".")
+	if !ok {
+		return sessionActivity{}, false
+	}
+	loginUnix, err1 := strconv.ParseInt(login, 10, 64)
+	lastUnix, err2 := strconv.ParseInt(last, 10, 64)
+	if err1 != nil || err2 != nil {
+		return sessionActivity{}, false
+	}
+	return sessionActivity{loginAt: time.Unix(loginUnix, 0), lastAt: time.Unix(lastUnix, 0)}, true
+}
+
+// logoutAt is when the session ends if the user stays idle: after the idle timeout, but
+// never later than the login itself expires.
+func (a sessionActivity) logoutAt(policy idlePolicy) time.Time {
+	idle := a.lastAt.Add(policy.logoutAfter)
+	if expires := a.loginAt.Add(sessionLifetime); expires.Before(idle) {
+		return expires
+	}
+	return idle
+}
+
+func (h *PageHandler) setSessionActivity(w http.ResponseWriter, activity sessionActivity) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     activityCookie,
+		Value:    strconv.FormatInt(activity.loginAt.Unix(), 10) + "." + strconv.FormatInt(activity.lastAt.Unix(), 10),
+		Path:     "/",
+		MaxAge:   int(h.idle.logoutAfter.Seconds()),
+		HttpOnly: true,
+		Secure:   false, // Set to true in production with HTTPS
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// passiveRequest reports requests the browser makes without the user doing anything,
+// which must not keep an idle session alive.
+func passiveRequest(r *http.Request) bool {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/static/"),
+		r.URL.Path == "/api/corpus/events",
+		r.URL.Path == "/healthz",
+		r.URL.Path == "/readyz":
+		return true
+	}
+	return false
+}
+
+// IdleSessionMiddleware ends sessions that have been idle longer than the configured
+// timeout and records activity on every other signed-in request. Enforcement happens
+// here rather than only in the browser, so a closed or frozen tab still logs out.
+func (h *PageHandler) IdleSessionMiddleware(next http.Handler) http.Handler {
+	if !h.idle.enabled() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.isAuthenticated(r) || passiveRequest(r) || r.URL.Path == "/logout" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		now := time.Now()
+		activity, ok := readSessionActivity(r)
+		if !ok {
+			// signed in before idle logout was enabled
+			activity = sessionActivity{loginAt: now, lastAt: now}
+		}
+		if !now.Before(activity.logoutAt(h.idle)) {
+			h.clearSessionCookies(w)
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				http.Error(w, "Session expired", http.StatusUnauthorized)
+			} else {
+				http.Redirect(w, r, "/login?reason=idle", http.StatusFound)
+			}
+			return
+		}
+
+		activity.lastAt = now
+		h.setSessionActivity(w, activity)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SessionKeepAliveHandler records activity from the chat page, which only calls it
+// while the user is interacting, and tells the page when to warn and when the session
+// ends.
+func (h *PageHandler) SessionKeepAliveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.isAuthenticated(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !h.idle.enabled() {
+		json.NewEncoder(w).Encode(map[string]interface{}{"enabled": false})
+		return
+	}
+
+	// The middleware has just recorded this request as activity.
+	now := time.Now()
+	activity, ok := readSessionActivity(r)
+	if !ok {
+		activity = sessionActivity{loginAt: now}
+	}
+	activity.lastAt = now
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled":     true,
+		"warnAfter":   int64(h.idle.warnAfter.Seconds()),
+		"logoutAfter": int64(h.idle.logoutAfter.Seconds()),
+		"logoutAt":    activity.logoutAt(h.idle).Unix(),
+		"expiresAt":   activity.loginAt.Add(sessionLifetime).Unix(),
+	})
+}
+
+// clearSessionCookies signs the browser out.
+func (h *PageHandler) clearSessionCookies(w http.ResponseWriter) {
+	for _, name := range []string{"auth_token", activityCookie} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+			Secure:   false, // Set to true in production with HTTPS
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+}
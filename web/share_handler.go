@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/SaiNageswarS/go-api-boot/logger"
+	pb "github.com/SaiNageswarS/medicine-rag/proto/generated"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ShareSessionHandler creates a signed, expiring read-only link for a session.
+func (h *PageHandler) ShareSessionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.isAuthenticated(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionId := r.PathValue("id")
+	if sessionId == "" {
+		http.Error(w, "Session id is required", http.StatusBadRequest)
+		return
+	}
+
+	var reqData struct {
+		ExpiresInHours int32 `json:"expiresInHours"`
+	}
+	// Body is optional; an empty body keeps the server default expiry.
+	_ = json.NewDecoder(r.Body).Decode(&reqData)
+
+	ctx, cancel := context.WithTimeout(h.authContext(r), 10*time.Second)
+	defer cancel()
+
+	resp, err := h.conversationClient.ShareConversation(ctx, &pb.ShareConversationRequest{
+		SessionId:      sessionId,
+		ExpiresInHours: reqData.ExpiresInHours,
+	})
+	if err != nil {
+		logger.Error("Failed to share conversation", zap.String("sessionId", sessionId), zap.Error(err))
+		http.Error(w, status.Convert(err).Message(), httpStatusFromGrpc(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		// without a configured public URL the link is relative, for the browser to resolve
+		"url":       h.publicURL + "/shared/" + url.PathEscape(resp.Token),
+		"expiresAt": resp.ExpiresAt,
+	})
+}
+
+// SharedConversationHandler renders a read-only transcript without requiring login.
+func (h *PageHandler) SharedConversationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	transcript, err := h.conversationClient.GetSharedConversation(ctx, &pb.GetSharedConversationRequest{
+		Token: r.PathValue("token"),
+	})
+	if err != nil {
+		logger.Error("Failed to load shared conversation", zap.Error(err))
+		http.Error(w, "This link is invalid or has expired", httpStatusFromGrpc(err))
+		return
+	}
+
+	locale := localeFor(transcript.Locale)
+
+	type message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	messages := make([]message, 0, len(transcript.Messages))
+	for _, msg := range transcript.Messages {
+		content := msg.Content
+		if msg.Role == "assistant" {
+			content = locale.Dosages(content)
+		}
+		messages = append(messages, message{Role: msg.Role, Content: content})
+	}
+
+	data := struct {
+		Lang      string
+		Messages  []message
+		ExpiresAt string
+	}{
+		Lang:      locale.tag,
+		Messages:  messages,
+		ExpiresAt: locale.DateTime(time.Unix(transcript.ExpiresAt, 0), timeZone(transcript.TimeZone)),
+	}
+
+	// Shared transcripts can contain clinical details; keep them out of caches and indexes.
+	w.Header().Set("Content-Type", "text/html")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	if err := h.templates["shared"].Execute(w, data); err != nil {
+		logger.Error("Failed to execute shared template", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// authContext forwards the user's JWT cookie to core as gRPC metadata.
+func (h *PageHandler) authContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if authToken := h.getAuthToken(r); authToken != "" {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(map[string]string{
+			"authorization": "Bearer " + authToken,
+		}))
+	}
+	return ctx
+}
+
+// loadPublicURL parses WEB_PUBLIC_URL, the address users reach the web server at, such
+// as https://chat.example.org. Share links are built from it rather than from request
+// headers, which the caller controls.
+func loadPublicURL() (string, error) {
+	raw := strings.TrimSpace(os.Getenv("WEB_PUBLIC_URL"))
+	if raw == "" {
+		return "", nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", errors.New("WEB_PUBLIC_URL must be an absolute http or https URL: " + raw)
+	}
+	return strings.TrimSuffix(u.String(), "/"), nil
+}
+
+func httpStatusFromGrpc(err error) int {
+	switch status.Code(err) {
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -9,6 +9,9 @@ const userData = {
 let messageCount = 0;
 let isLoading = false;
 
+// Question and final answer per assistant message, sent along with feedback.
+const answerContext = {};
+
 // Configure marked for medical content with proper newline handling
 marked.setOptions({
     breaks: true,       // Convert '\n' in paragraphs into <br>
@@ -60,6 +63,122 @@ function startNewSession() {
     handleInputChange();
 }
 
+function printSession() {
+    if (messageCount === 0) {
+        alert('Ask a question before printing this conversation.');
+        return;
+    }
+
+    window.open('/conversation/' + encodeURIComponent(userData.sessionId) + '/print', '_blank', 'noopener');
+}
+
+function toggleCasePanel() {
+    const panel = document.getElementById('case-panel');
+    panel.classList.toggle('hidden');
+    if (!panel.classList.contains('hidden')) {
+        loadCaseContext();
+    }
+}
+
+function caseContextUrl() {
+    return '/api/session/' + encodeURIComponent(userData.sessionId) + '/context';
+}
+
+function caseLines(id) {
+    return document.getElementById(id).value.split('\n').map(function (line) {
+        return line.trim();
+    }).filter(Boolean);
+}
+
+function showCaseContext(caseContext) {
+    document.getElementById('case-age').value = caseContext.ageYears || '';
+    document.getElementById('case-sex').value = caseContext.sex || '';
+    document.getElementById('case-complaints').value = (caseContext.chiefComplaints || []).join('\n');
+    document.getElementById('case-history').value = caseContext.history || '';
+    document.getElementById('case-medications').value = (caseContext.medications || []).join('\n');
+    document.getElementById('case-badge').classList.toggle('hidden', !caseContext.updatedOn);
+    document.getElementById('case-status').textContent = caseContext.updatedOn
+        ? 'Attached to this session. The assistant takes these details into account in every answer.'
+        : 'No case details attached.';
+}
+
+async function loadCaseContext() {
+    try {
+        const response = await fetch(caseContextUrl());
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        showCaseContext(await response.json());
+    } catch (error) {
+        console.error('Loading case details failed:', error);
+        document.getElementById('case-status').textContent = 'Could not load case details: ' + error.message;
+    }
+}
+
+async function putCaseContext(caseContext) {
+    try {
+        const response = await fetch(caseContextUrl(), {
+            method: 'PUT',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify(caseContext)
+        });
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+        showCaseContext(await response.json());
+    } catch (error) {
+        console.error('Saving case details failed:', error);
+        document.getElementById('case-status').textContent = 'Could not save case details: ' + error.message;
+    }
+}
+
+function saveCaseContext(event) {
+    event.preventDefault();
+    putCaseContext({
+        ageYears: parseInt(document.getElementById('case-age').value, 10) || 0,
+        sex: document.getElementById('case-sex').value,
+        chiefComplaints: caseLines('case-complaints'),
+        history: document.getElementById('case-history').value.trim(),
+        medications: caseLines('case-medications')
+    });
+}
+
+function clearCaseContext() {
+    putCaseContext({});
+}
+
+async function shareSession() {
+    if (messageCount === 0) {
+        alert('Ask a question before sharing this conversation.');
+        return;
+    }
+
+    try {
+        const response = await fetch('/api/session/' + encodeURIComponent(userData.sessionId) + '/share', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({})
+        });
+
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+
+        const share = await response.json();
+        const link = new URL(share.url, window.location.origin).href;
+        const expires = new Date(share.expiresAt * 1000).toLocaleString();
+        try {
+            await navigator.clipboard.writeText(link);
+            alert('Read-only link copied to clipboard. It expires on ' + expires + '.');
+        } catch (clipboardError) {
+            prompt('Read-only link (expires on ' + expires + '):', link);
+        }
+    } catch (error) {
+        console.error('Share failed:', error);
+        alert('Could not share conversation: ' + error.message);
+    }
+}
+
 function handleInputChange() {
     const messageInput = document.getElementById('message-input');
     const sendButton = document.getElementById('send-button');
@@ -93,6 +212,7 @@ async function sendMessage() {
 
     // Add assistant message placeholder
     const assistantMessageId = addAssistantMessage('', true);
+    answerContext[assistantMessageId] = { question: messageText, answer: '' };
 
     messageCount++;
     document.getElementById('message-count').textContent = messageCount;
@@ -151,8 +271,19 @@ function addAssistantMessage(content, isStreaming) {
                 '</div>' +
                 '<div class="flex items-center justify-between mt-3 pt-2 border-t border-gray-100">' +
                     '<span class="inline-flex items-center gap-1 px-2 py-1 rounded-full text-xs font-medium bg-purple-100 text-purple-800">Claude</span>' +
-                    '<span class="text-xs text-gray-400">' + new Date().toLocaleTimeString() + '</span>' +
+                    '<div class="flex items-center gap-2">' +
+                        '<div id="feedback-' + messageId + '" class="hidden flex items-center gap-1">' +
+                            '<button type="button" id="feedback-up-' + messageId + '" onclick="submitFeedback(' + messageId + ', \'up\')" class="p-1 rounded text-gray-400 hover:text-green-600 hover:bg-green-50 transition-colors" title="Helpful">👍</button>' +
+                            '<button type="button" id="feedback-down-' + messageId + '" onclick="submitFeedback(' + messageId + ', \'down\')" class="p-1 rounded text-gray-400 hover:text-red-600 hover:bg-red-50 transition-colors" title="Not helpful">👎</button>' +
+                        '</div>' +
+                        '<span class="text-xs text-gray-400">' + new Date().toLocaleTimeString() + '</span>' +
+                    '</div>' +
                 '</div>' +
+                '<div id="feedback-comment-' + messageId + '" class="hidden mt-2 flex gap-2">' +
+                    '<input type="text" id="feedback-comment-input-' + messageId + '" maxlength="2000" placeholder="Tell us more (optional)" class="flex-1 px-3 py-1.5 text-sm border border-gray-300 rounded-lg focus:outline-none focus:ring-2 focus:ring-blue-500">' +
+                    '<button type="button" onclick="submitFeedbackComment(' + messageId + ')" class="px-3 py-1.5 text-sm bg-blue-600 hover:bg-blue-700 text-white rounded-lg transition-colors">Send</button>' +
+                '</div>' +
+                '<div id="meta-' + messageId + '" class="hidden mt-2"></div>' +
             '</div>' +
         '</div>';
 
@@ -187,6 +318,9 @@ function addToolResult(messageId, toolResult) {
     const sentences = toolResult.sentences || [];
     const fullContent = sentences.join('\n'); 
     const preview = sentences.slice(0, 2).join(' ') + (sentences.length > 2 ? '...' : '');
+    const metadata = Object.assign({}, toolResult.metadata);
+    const figures = parseFigures(metadata.figures);
+    delete metadata.figures;
     
     toolDiv.innerHTML = 
         '<div class="bg-blue-50 border-l-4 border-blue-400">' +
@@ -207,10 +341,11 @@ function addToolResult(messageId, toolResult) {
             '</button>' +
             '<div id="' + toolId + '-content" class="hidden border-t border-blue-200 p-3 bg-white transition-all duration-300">' +
                 '<div class="prose prose-sm max-w-none">' + renderMarkdown(fullContent) + '</div>' +
+                renderFigures(figures) +
                 (toolResult.metadata ? 
                     '<div class="mt-3 pt-3 border-t text-xs text-gray-600">' +
                         '<div class="grid grid-cols-2 gap-2">' +
-                            Object.entries(toolResult.metadata).map(([key, value]) => 
+                            Object.entries(metadata).map(([key, value]) => 
                                 '<div><span class="font-medium">' + escapeHtml(key) + ':</span> ' + escapeHtml(String(value)) + '</div>'
                             ).join('') +
                         '</div>' +
@@ -223,6 +358,33 @@ function addToolResult(messageId, toolResult) {
     // Don't auto-scroll when adding tool results to avoid interrupting user reading
 }
 
+// Figures come as JSON in a search result's metadata when the tenant turns them on.
+function parseFigures(json) {
+    if (!json) return [];
+    try {
+        return JSON.parse(json) || [];
+    } catch (e) {
+        console.warn('Invalid figures metadata:', e);
+        return [];
+    }
+}
+
+// Each figure shows its image, served by /api/figures, above its caption. Figures
+// without an image keep only the caption.
+function renderFigures(figures) {
+    if (!figures.length) return '';
+    return '<div class="mt-3 grid grid-cols-2 gap-3">' +
+        figures.map(figure =>
+            '<figure class="border border-gray-200 rounded p-2 bg-gray-50">' +
+                '<a href="/api/figures/' + encodeURIComponent(figure.figureId) + '" target="_blank" rel="noopener">' +
+                    '<img src="/api/figures/' + encodeURIComponent(figure.figureId) + '" alt="' + escapeHtml(figure.label || '') + '" loading="lazy" class="max-h-48 mx-auto" onerror="this.parentElement.remove()">' +
+                '</a>' +
+                '<figcaption class="mt-1 text-xs text-gray-700">' + escapeHtml(figure.caption || figure.label || '') + '</figcaption>' +
+            '</figure>'
+        ).join('') +
+    '</div>';
+}
+
 function toggleToolResult(toolId, event) {
     // Prevent any default button behavior and event bubbling
     if (event) {
@@ -256,7 +418,165 @@ function toggleToolResult(toolId, event) {
     }, 0);
 }
 
+function enableFeedback(messageId, answer) {
+    if (!answerContext[messageId] || !answer) return;
+    answerContext[messageId].answer = answer;
+
+    const feedbackEl = document.getElementById('feedback-' + messageId);
+    if (feedbackEl) {
+        feedbackEl.classList.remove('hidden');
+    }
+}
+
+async function submitFeedback(messageId, rating, comment) {
+    const context = answerContext[messageId];
+    if (!context) return;
+    context.rating = rating;
+
+    const upButton = document.getElementById('feedback-up-' + messageId);
+    const downButton = document.getElementById('feedback-down-' + messageId);
+    upButton.classList.toggle('text-green-600', rating === 'up');
+    downButton.classList.toggle('text-red-600', rating === 'down');
+
+    try {
+        const response = await fetch('/api/feedback', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({
+                sessionId: userData.sessionId,
+                messageId: String(messageId),
+                rating: rating,
+                comment: comment || '',
+                question: context.question,
+                answer: context.answer
+            })
+        });
+
+        if (!response.ok) {
+            throw new Error(await response.text());
+        }
+
+        const commentEl = document.getElementById('feedback-comment-' + messageId);
+        if (comment) {
+            commentEl.innerHTML = '<span class="text-xs text-gray-500">Thanks for the feedback.</span>';
+        } else if (commentEl) {
+            commentEl.classList.remove('hidden');
+        }
+    } catch (error) {
+        console.error('Feedback failed:', error);
+        alert('Could not send feedback: ' + error.message);
+    }
+}
+
+function submitFeedbackComment(messageId) {
+    const context = answerContext[messageId];
+    const input = document.getElementById('feedback-comment-input-' + messageId);
+    if (!context || !context.rating || !input || !input.value.trim()) return;
+
+    submitFeedback(messageId, context.rating, input.value.trim());
+}
+
+const explainCommand = '/explain ';
+
+const answerMetaLabels = {
+    model: 'Answer model',
+    miniModel: 'Summary model',
+    toolSelectorModel: 'Tool selector',
+    corpusVersion: 'Corpus version',
+    toolCalls: 'Tool calls',
+    latencyMs: 'Latency',
+    tokens: 'Tokens',
+    cached: 'Cached',
+    budgetExhausted: 'Budget exhausted',
+    fallbackModels: 'Fallback models',
+    language: 'Language',
+    confidence: 'Confidence',
+    disclaimers: 'Disclaimers',
+    explain: 'Dry run',
+    traceId: 'Trace ID'
+};
+
+// Shows how an answer was produced, for users and for support.
+function renderAnswerMeta(messageId, meta) {
+    const metaEl = document.getElementById('meta-' + messageId);
+    if (!metaEl || !meta) return;
+
+    const rows = Object.keys(answerMetaLabels)
+        .filter(key => meta[key])
+        .map(key => {
+            const value = key === 'latencyMs' ? (Number(meta[key]) / 1000).toFixed(1) + ' s' : meta[key];
+            return '<div><span class="font-medium">' + answerMetaLabels[key] + ':</span> ' + escapeHtml(String(value)) + '</div>';
+        });
+    if (rows.length === 0) return;
+
+    metaEl.innerHTML =
+        '<details class="text-xs text-gray-500">' +
+            '<summary class="cursor-pointer select-none hover:text-gray-700">How this answer was produced</summary>' +
+            '<div class="mt-2 grid grid-cols-2 gap-1">' + rows.join('') + '</div>' +
+        '</details>';
+    metaEl.classList.remove('hidden');
+}
+
+const answerNoticeCodes = ['budget_exhausted', 'degraded'];
+
+// Warns above an answer that it may be incomplete or of lower quality.
+function showAnswerNotice(messageId, message) {
+    const contentElement = document.getElementById('content-' + messageId);
+    if (!contentElement || !message) return;
+
+    const notice = document.createElement('div');
+    notice.className = 'mb-3 px-3 py-2 rounded-lg border border-amber-200 bg-amber-50 text-amber-800 text-sm';
+    notice.textContent = '⏱️ ' + message;
+    contentElement.parentNode.insertBefore(notice, contentElement);
+}
+
+// Shows suggested follow-up questions under an answer; clicking one asks it.
+function renderFollowUps(messageId, questions) {
+    const metaEl = document.getElementById('meta-' + messageId);
+    if (!metaEl || !questions || questions.length === 0) return;
+
+    const chips = document.createElement('div');
+    chips.className = 'mt-3 flex flex-wrap gap-2';
+    questions.forEach(question => {
+        const chip = document.createElement('button');
+        chip.type = 'button';
+        chip.className = 'px-3 py-1.5 rounded-full border border-blue-200 bg-blue-50 text-blue-700 text-xs hover:bg-blue-100 transition-colors text-left';
+        chip.textContent = question;
+        chip.onclick = () => askFollowUp(question);
+        chips.appendChild(chip);
+    });
+    metaEl.parentNode.insertBefore(chips, metaEl);
+    scrollToBottom();
+}
+
+function askFollowUp(question) {
+    if (isLoading) return;
+    document.getElementById('message-input').value = question;
+    handleInputChange();
+    sendMessage();
+}
+
+// Token deltas arrive faster than markdown can be re-rendered, so the answer is
+// redrawn at most once per frame with the text received so far.
+const pendingAnswerRenders = {};
+
+function scheduleAnswerRender(messageId, currentAnswer) {
+    if (pendingAnswerRenders[messageId]) return;
+    pendingAnswerRenders[messageId] = requestAnimationFrame(() => {
+        delete pendingAnswerRenders[messageId];
+        updateAssistantMessage(messageId, currentAnswer(), true, false);
+    });
+}
+
+function cancelAnswerRender(messageId) {
+    if (!pendingAnswerRenders[messageId]) return;
+    cancelAnimationFrame(pendingAnswerRenders[messageId]);
+    delete pendingAnswerRenders[messageId];
+}
+
 function updateAssistantMessage(messageId, content, isStreaming, hasError) {
+    if (!isStreaming) cancelAnswerRender(messageId);
+
     const contentElement = document.getElementById('content-' + messageId);
     if (contentElement && content) {
         contentElement.innerHTML = renderMarkdown(content);
@@ -281,6 +601,12 @@ function updateAssistantMessage(messageId, content, isStreaming, hasError) {
 // Enhanced SSE handling with real-time updates
 async function callAgentStreaming(text, messageId) {
     let fullAnswer = '';
+
+    // "/explain <question>" runs the tools without generating an answer, to check retrieval
+    const explain = text.startsWith(explainCommand);
+    if (explain) {
+        text = text.slice(explainCommand.length).trim();
+    }
     
     try {
         console.log('Starting agent streaming request:', text);
@@ -293,7 +619,8 @@ async function callAgentStreaming(text, messageId) {
             body: JSON.stringify({
                 text: text,
                 sessionId: userData.sessionId,
-                model: 'claude'
+                model: 'claude',
+                explain: explain
             })
         });
 
@@ -354,24 +681,47 @@ async function callAgentStreaming(text, messageId) {
                                     addToolResult(messageId, toolResult);
                                 }
                                 
-                                // Handle answer content
+                                // Answer chunks are deltas, down to single tokens; the completion
+                                // carries the final answer
                                 if (chunkType.Answer) {
-                                    const answer = chunkType.Answer;
-                                    console.log('Answer chunk received');
-                                    fullAnswer = answer.content; // Use the full content, not append
-                                    updateAssistantMessage(messageId, fullAnswer, true, false);
+                                    fullAnswer += chunkType.Answer.content || '';
+                                    scheduleAnswerRender(messageId, () => fullAnswer);
                                 }
                                 
+                                // Handle errors reported inside the stream, e.g. an exhausted token quota
+                                if (chunkType.Error) {
+                                    const streamError = chunkType.Error;
+                                    console.warn('Stream error:', streamError.error_code, streamError.error_message);
+                                    // notices about an answer that still follows: a fallback model stood in,
+                                    // or the execution ran out of time or tokens
+                                    if (answerNoticeCodes.includes(streamError.error_code)) {
+                                        showAnswerNotice(messageId, streamError.error_message);
+                                        continue;
+                                    }
+                                    updateProgress(messageId, '');
+                                    updateAssistantMessage(messageId, streamError.error_message, false, true);
+                                    break;
+                                }
+
                                 // Handle completion
                                 if (chunkType.Complete) {
                                     const complete = chunkType.Complete;
                                     console.log('Stream completed:', complete.processingTime + 'ms');
                                     updateProgress(messageId, ''); // Clear progress
                                     updateAssistantMessage(messageId, complete.answer || fullAnswer, false, false);
-                                    break;
+                                    enableFeedback(messageId, complete.answer || fullAnswer);
                                 }
                             }
                         }
+
+                        // Sent right after completion with the models, corpus version, tool calls and latency
+                        if (parsed.type === 'meta') {
+                            renderAnswerMeta(messageId, parsed.meta);
+                        }
+
+                        if (parsed.type === 'followUps') {
+                            renderFollowUps(messageId, parsed.questions);
+                        }
                         
                         if (parsed.type === 'error') {
                             throw new Error(parsed.message);
@@ -408,8 +758,160 @@ function escapeHtml(text) {
     return div.innerHTML;
 }
 
+// Tells the user when newly indexed documents become searchable. EventSource reconnects
+// by itself and resumes from the last corpus version it saw.
+function watchCorpusUpdates() {
+    if (!window.EventSource) return;
+
+    const events = new EventSource('/api/corpus/events');
+    events.onmessage = function(e) {
+        let event;
+        try {
+            event = JSON.parse(e.data);
+        } catch (parseError) {
+            console.warn('Failed to parse corpus event:', e.data, parseError);
+            return;
+        }
+
+        if (event.type === 'disabled') {
+            events.close();
+        } else if (event.type === 'corpus' && event.message) {
+            showNotice(event.message);
+        }
+    };
+}
+
+function showNotice(message) {
+    const notice = document.createElement('div');
+    notice.className = 'fixed bottom-24 right-4 z-50 flex items-center gap-3 px-4 py-3 rounded-lg shadow-lg bg-blue-600 text-white text-sm';
+    notice.innerHTML =
+        '<span>📚 ' + escapeHtml(message) + '</span>' +
+        '<button type="button" class="text-blue-100 hover:text-white" title="Dismiss">✕</button>';
+    notice.querySelector('button').onclick = () => notice.remove();
+
+    document.body.appendChild(notice);
+    setTimeout(() => notice.remove(), 10000);
+}
+
+// Signs the user out of shared terminals after a period of inactivity. The server enforces
+// the timeout; this warns beforehand and reports activity so the session stays alive while
+// the user is working. Tabs share the deadline through localStorage.
+const idleSession = {
+    warnAfter: 0,
+    logoutAfter: 0,
+    expiresAt: 0,
+    lastPing: 0,
+    banner: null
+};
+
+async function keepSessionAlive() {
+    idleSession.lastPing = Date.now();
+    try {
+        const response = await fetch('/api/session/keepalive', { method: 'POST' });
+        if (response.status === 401) {
+            window.location.href = '/login?reason=idle';
+            return;
+        }
+        const session = await response.json();
+        if (!session.enabled) return;
+
+        idleSession.warnAfter = session.warnAfter;
+        idleSession.logoutAfter = session.logoutAfter;
+        idleSession.expiresAt = session.expiresAt;
+        localStorage.setItem('idleLogoutAt', String(session.logoutAt));
+    } catch (error) {
+        console.warn('Session keep-alive failed:', error);
+    }
+}
+
+function recordActivity() {
+    // the warning stays up until the user chooses to stay signed in
+    if (idleSession.logoutAfter === 0 || idleSession.banner) return;
+    if (Date.now() - idleSession.lastPing > 30000) {
+        keepSessionAlive();
+    }
+}
+
+function checkIdleSession() {
+    if (idleSession.logoutAfter === 0) return;
+
+    const now = Date.now() / 1000;
+    const logoutAt = Number(localStorage.getItem('idleLogoutAt')) || 0;
+    if (logoutAt === 0) return;
+
+    if (now >= logoutAt) {
+        window.location.href = '/logout?reason=idle';
+        return;
+    }
+
+    const warnAt = logoutAt - (idleSession.logoutAfter - idleSession.warnAfter);
+    if (now >= warnAt) {
+        showIdleWarning(logoutAt, logoutAt >= idleSession.expiresAt);
+    } else {
+        hideIdleWarning();
+    }
+}
+
+function showIdleWarning(logoutAt, expiring) {
+    if (!idleSession.banner) {
+        const banner = document.createElement('div');
+        banner.className = 'fixed top-4 left-1/2 -translate-x-1/2 z-50 flex items-center gap-3 px-4 py-3 rounded-lg shadow-lg bg-yellow-100 border border-yellow-300 text-yellow-900 text-sm';
+        banner.innerHTML = '<span></span><button type="button" class="px-3 py-1 rounded bg-yellow-600 text-white hover:bg-yellow-700"></button>';
+        document.body.appendChild(banner);
+        idleSession.banner = banner;
+    }
+
+    const remaining = Math.max(0, Math.round(logoutAt - Date.now() / 1000));
+    const countdown = Math.floor(remaining / 60) + ':' + String(remaining % 60).padStart(2, '0');
+    const button = idleSession.banner.querySelector('button');
+    if (expiring) {
+        // the login itself expires; activity cannot extend it
+        idleSession.banner.querySelector('span').textContent = 'Your session ends in ' + countdown + '. Sign in again to continue.';
+        button.textContent = 'Sign out now';
+        button.onclick = () => { window.location.href = '/logout'; };
+    } else {
+        idleSession.banner.querySelector('span').textContent = 'You will be signed out in ' + countdown + ' due to inactivity.';
+        button.textContent = 'Stay signed in';
+        button.onclick = () => { hideIdleWarning(); keepSessionAlive(); };
+    }
+}
+
+function hideIdleWarning() {
+    if (idleSession.banner) {
+        idleSession.banner.remove();
+        idleSession.banner = null;
+    }
+}
+
+function watchIdleSession() {
+    ['mousedown', 'keydown', 'scroll', 'touchstart'].forEach(function(type) {
+        document.addEventListener(type, recordActivity, { passive: true, capture: true });
+    });
+    keepSessionAlive();
+    setInterval(checkIdleSession, 1000);
+}
+
+// Asks the question passed as ?ask=, e.g. from an answer whose sources have changed.
+// The parameter is dropped from the address so that reloading does not ask again.
+function askFromUrl() {
+    const params = new URLSearchParams(window.location.search);
+    const question = (params.get('ask') || '').trim();
+    if (!question) return;
+
+    params.delete('ask');
+    const query = params.toString();
+    history.replaceState(null, '', window.location.pathname + (query ? '?' + query : ''));
+
+    document.getElementById('message-input').value = question;
+    handleInputChange();
+    sendMessage();
+}
+
 // Initialize
 document.addEventListener('DOMContentLoaded', function() {
     console.log('Chat initialized with user:', userData.user);
     handleInputChange();
+    watchCorpusUpdates();
+    watchIdleSession();
+    askFromUrl();
 });
\ No newline at end of file